@@ -276,6 +276,19 @@ func (dc *DomainsCertificates) getCertificateForDomain(domainToFind string) (*Do
 	return nil, false
 }
 
+func (dc *DomainsCertificates) tlsCertificates() []*tls.Certificate {
+	dc.lock.RLock()
+	defer dc.lock.RUnlock()
+
+	var certs []*tls.Certificate
+	for _, domainsCertificate := range dc.Certs {
+		if domainsCertificate.tlsCert != nil {
+			certs = append(certs, domainsCertificate.tlsCert)
+		}
+	}
+	return certs
+}
+
 func (dc *DomainsCertificates) exists(domainToFind types.Domain) (*DomainsCertificate, bool) {
 	dc.lock.RLock()
 	defer dc.lock.RUnlock()
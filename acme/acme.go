@@ -53,6 +53,7 @@ type ACME struct {
 	DNSChallenge          *acmeprovider.DNSChallenge  `description:"Activate DNS-01 Challenge"`
 	HTTPChallenge         *acmeprovider.HTTPChallenge `description:"Activate HTTP-01 Challenge"`
 	TLSChallenge          *acmeprovider.TLSChallenge  `description:"Activate TLS-ALPN-01 Challenge"`
+	EAB                   *acmeprovider.EAB           `description:"External Account Binding to use."`
 	DNSProvider           string                      `description:"(Deprecated) Activate DNS-01 Challenge"`                                                                    // Deprecated
 	DelayDontCheckDNS     flaeg.Duration              `description:"(Deprecated) Assume DNS propagates after a delay in seconds rather than finding and querying nameservers."` // Deprecated
 	ACMELogging           bool                        `description:"Enable debug logging of ACME actions."`
@@ -222,7 +223,7 @@ func (a *ACME) leadershipListener(elected bool) error {
 			// New users will need to register; be sure to save it
 			log.Debug("Register...")
 
-			reg, err := a.client.Register(true)
+			reg, err := acmeprovider.Register(a.client, a.EAB)
 			if err != nil {
 				return err
 			}
@@ -285,6 +286,20 @@ func (a *ACME) getCertificate(clientHello *tls.ClientHelloInfo) (*tls.Certificat
 	return nil, nil
 }
 
+// GetCertificates returns the certificates of the ACME account.
+func (a *ACME) GetCertificates() []*tls.Certificate {
+	if a.store == nil {
+		return nil
+	}
+
+	account, ok := a.store.Get().(*Account)
+	if !ok || account == nil {
+		return nil
+	}
+
+	return account.DomainsCertificate.tlsCertificates()
+}
+
 func (a *ACME) retrieveCertificates() {
 	a.jobs.In() <- func() {
 		log.Info("Retrieving ACME certificates...")
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
 
 	"github.com/containous/mux"
+	"github.com/containous/traefik/healthcheck"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/safe"
@@ -20,12 +25,21 @@ type Handler struct {
 	Dashboard             bool   `description:"Activate dashboard" export:"true"`
 	Debug                 bool   `export:"true"`
 	CurrentConfigurations *safe.Safe
-	Statistics            *types.Statistics          `description:"Enable more detailed statistics" export:"true"`
-	Stats                 *thoas_stats.Stats         `json:"-"`
-	StatsRecorder         *middlewares.StatsRecorder `json:"-"`
-	DashboardAssets       *assetfs.AssetFS           `json:"-"`
+	Statistics            *types.Statistics                 `description:"Enable more detailed statistics" export:"true"`
+	Stats                 *thoas_stats.Stats                `json:"-"`
+	StatsRecorder         *middlewares.StatsRecorder        `json:"-"`
+	DashboardAssets       *assetfs.AssetFS                  `json:"-"`
+	HealthCheck           *healthcheck.HealthCheck          `json:"-"`
+	BackendRampOverrides  *middlewares.BackendRampOverrides `json:"-"`
+	// ConfigurationValidator validates a dynamic configuration without applying it.
+	ConfigurationValidator func(*types.Configuration) []types.ConfigurationError `json:"-"`
+	// MiddlewareChains holds the types.MiddlewareChains of the frontends, as built by the server.
+	MiddlewareChains *safe.Safe `json:"-"`
 }
 
+// maxValidateBodyBytes is the maximum size of the configurations posted to be validated.
+const maxValidateBodyBytes = 10 * 1024 * 1024
+
 var (
 	templatesRenderer = render.New(render.Options{
 		Directory: "nowhere",
@@ -49,10 +63,24 @@ func (p Handler) AddRoutes(router *mux.Router) {
 	router.Methods(http.MethodGet).Path("/api/providers/{provider}/frontends/{frontend}").HandlerFunc(p.getFrontendHandler)
 	router.Methods(http.MethodGet).Path("/api/providers/{provider}/frontends/{frontend}/routes").HandlerFunc(p.getRoutesHandler)
 	router.Methods(http.MethodGet).Path("/api/providers/{provider}/frontends/{frontend}/routes/{route}").HandlerFunc(p.getRouteHandler)
+	router.Methods(http.MethodGet).Path("/api/providers/{provider}/frontends/{frontend}/middlewares").HandlerFunc(p.getMiddlewaresHandler)
+
+	// backend ramps switchover routes
+	router.Methods(http.MethodGet).Path("/api/backendramps/overrides").HandlerFunc(p.getBackendRampOverridesHandler)
+	router.Methods(http.MethodGet).Path("/api/providers/{provider}/frontends/{frontend}/backendramp/override").HandlerFunc(p.getBackendRampOverrideHandler)
+	router.Methods(http.MethodPut).Path("/api/providers/{provider}/frontends/{frontend}/backendramp/override").HandlerFunc(p.putBackendRampOverrideHandler)
+	router.Methods(http.MethodDelete).Path("/api/providers/{provider}/frontends/{frontend}/backendramp/override").HandlerFunc(p.deleteBackendRampOverrideHandler)
+
+	// dynamic configuration validation route
+	router.Methods(http.MethodPost).Path("/api/validate").HandlerFunc(p.postValidateHandler)
 
 	// health route
 	router.Methods(http.MethodGet).Path("/health").HandlerFunc(p.getHealthHandler)
 
+	// backends health routes
+	router.Methods(http.MethodGet).Path("/api/health/backends").HandlerFunc(p.getBackendsHealthHandler)
+	router.Methods(http.MethodGet).Path("/api/health/backends/{backend}").HandlerFunc(p.getBackendHealthHandler)
+
 	version.Handler{}.AddRoutes(router)
 
 	if p.Dashboard {
@@ -233,6 +261,159 @@ func (p Handler) getRouteHandler(response http.ResponseWriter, request *http.Req
 	http.NotFound(response, request)
 }
 
+// getMiddlewaresHandler returns the middleware chains of a frontend, by entry point, in their execution order.
+func (p Handler) getMiddlewaresHandler(response http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+	providerID := getProviderIDFromVars(vars)
+	frontendID := vars["frontend"]
+
+	if p.MiddlewareChains != nil {
+		middlewareChains, _ := p.MiddlewareChains.Get().(types.MiddlewareChains)
+		if chains, ok := middlewareChains[providerID][frontendID]; ok {
+			err := templatesRenderer.JSON(response, http.StatusOK, chains)
+			if err != nil {
+				log.Error(err)
+			}
+			return
+		}
+	}
+	http.NotFound(response, request)
+}
+
+// backendRampOverride is the variant a backend ramp is switched over to,
+// backend for the frontend backend, or ramp for the ramp backend.
+type backendRampOverride struct {
+	Variant string `json:"variant"`
+}
+
+func (p Handler) getBackendRampOverridesHandler(response http.ResponseWriter, request *http.Request) {
+	overrides := make(map[string]map[string]string)
+	if p.BackendRampOverrides != nil {
+		overrides = p.BackendRampOverrides.All()
+	}
+
+	err := templatesRenderer.JSON(response, http.StatusOK, overrides)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (p Handler) getBackendRampOverrideHandler(response http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+	providerID := getProviderIDFromVars(vars)
+	frontendID := vars["frontend"]
+
+	if p.BackendRampOverrides != nil {
+		if variant := p.BackendRampOverrides.Get(providerID, frontendID); len(variant) > 0 {
+			err := templatesRenderer.JSON(response, http.StatusOK, backendRampOverride{Variant: variant})
+			if err != nil {
+				log.Error(err)
+			}
+			return
+		}
+	}
+	http.NotFound(response, request)
+}
+
+func (p Handler) putBackendRampOverrideHandler(response http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+	providerID := getProviderIDFromVars(vars)
+	frontendID := vars["frontend"]
+
+	if !p.hasBackendRamp(providerID, frontendID) {
+		http.NotFound(response, request)
+		return
+	}
+
+	override := backendRampOverride{}
+	if err := json.NewDecoder(request.Body).Decode(&override); err != nil {
+		http.Error(response, fmt.Sprintf("invalid backend ramp override: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := p.BackendRampOverrides.Set(providerID, frontendID, override.Variant); err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Infof("Backend ramp of frontend %s of provider %s switched over to %s", frontendID, providerID, override.Variant)
+
+	err := templatesRenderer.JSON(response, http.StatusOK, override)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+func (p Handler) deleteBackendRampOverrideHandler(response http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+	providerID := getProviderIDFromVars(vars)
+	frontendID := vars["frontend"]
+
+	if !p.hasBackendRamp(providerID, frontendID) {
+		http.NotFound(response, request)
+		return
+	}
+
+	p.BackendRampOverrides.Delete(providerID, frontendID)
+	log.Infof("Backend ramp of frontend %s of provider %s reset", frontendID, providerID)
+
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// hasBackendRamp returns whether a frontend of the current configuration of a provider has a backend ramp.
+func (p Handler) hasBackendRamp(providerID, frontendID string) bool {
+	if p.BackendRampOverrides == nil {
+		return false
+	}
+
+	currentConfigurations := p.CurrentConfigurations.Get().(types.Configurations)
+	if provider, ok := currentConfigurations[providerID]; ok {
+		if frontend, ok := provider.Frontends[frontendID]; ok {
+			return frontend.BackendRamp != nil
+		}
+	}
+	return false
+}
+
+type validationResponse struct {
+	Valid  bool                       `json:"valid"`
+	Errors []types.ConfigurationError `json:"errors,omitempty"`
+}
+
+// postValidateHandler validates the dynamic configuration of the request body, in the JSON format of the providers configurations,
+// without applying it: it responds 200 when the configuration is valid, 400 when it can't be parsed, and 422 with its errors otherwise.
+func (p Handler) postValidateHandler(response http.ResponseWriter, request *http.Request) {
+	if p.ConfigurationValidator == nil {
+		http.NotFound(response, request)
+		return
+	}
+
+	config := &types.Configuration{}
+	body := http.MaxBytesReader(response, request.Body, maxValidateBodyBytes)
+	if err := json.NewDecoder(body).Decode(config); err != nil {
+		err = templatesRenderer.JSON(response, http.StatusBadRequest, validationResponse{
+			Errors: []types.ConfigurationError{{Message: fmt.Sprintf("invalid configuration: %v", err)}},
+		})
+		if err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	validation := validationResponse{Errors: p.ConfigurationValidator(config)}
+	if len(validation.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	} else {
+		validation.Valid = true
+	}
+
+	err := templatesRenderer.JSON(response, status, validation)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
 // healthResponse combines data returned by thoas/stats with statistics (if
 // they are enabled).
 type healthResponse struct {
@@ -250,3 +431,54 @@ func (p *Handler) getHealthHandler(response http.ResponseWriter, request *http.R
 		log.Error(err)
 	}
 }
+
+func (p Handler) getBackendsHealthHandler(response http.ResponseWriter, request *http.Request) {
+	health := make(map[string]*healthcheck.BackendHealth)
+	if p.HealthCheck != nil {
+		health = p.HealthCheck.BackendsHealth()
+	}
+
+	renderBackendsHealth(response, request, health)
+}
+
+func (p Handler) getBackendHealthHandler(response http.ResponseWriter, request *http.Request) {
+	backendID := mux.Vars(request)["backend"]
+
+	if p.HealthCheck != nil {
+		if backend, ok := p.HealthCheck.BackendsHealth()[backendID]; ok {
+			renderBackendsHealth(response, request, map[string]*healthcheck.BackendHealth{backendID: backend})
+			return
+		}
+	}
+	http.NotFound(response, request)
+}
+
+// renderBackendsHealth renders the backends health in JSON,
+// or in text with the format=text query parameter: one "<backend> <url> <weight>" line per healthy server.
+func renderBackendsHealth(response http.ResponseWriter, request *http.Request, health map[string]*healthcheck.BackendHealth) {
+	if request.URL.Query().Get("format") != "text" {
+		err := templatesRenderer.JSON(response, http.StatusOK, health)
+		if err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	var names []string
+	for name := range health {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		for _, server := range health[name].Servers {
+			fmt.Fprintf(&buf, "%s %s %d\n", name, server.URL, server.Weight)
+		}
+	}
+
+	err := templatesRenderer.Text(response, http.StatusOK, buf.String())
+	if err != nil {
+		log.Error(err)
+	}
+}
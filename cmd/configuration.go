@@ -44,8 +44,9 @@ func NewTraefikDefaultPointersConfiguration() *TraefikConfiguration {
 	var defaultDocker docker.Provider
 	defaultDocker.Watch = true
 	defaultDocker.ExposedByDefault = true
-	defaultDocker.Endpoint = "unix:///var/run/docker.sock"
+	defaultDocker.Endpoint = docker.DefaultEndpoint
 	defaultDocker.SwarmMode = false
+	defaultDocker.RefreshSeconds = 15
 
 	// default File
 	var defaultFile file.Provider
@@ -0,0 +1,88 @@
+package dumpconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/cmd"
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/server"
+	"github.com/ghodss/yaml"
+)
+
+// Configuration holds the traefik configuration and the options of the dump-config command
+type Configuration struct {
+	cmd.TraefikConfiguration `mapstructure:",squash"`
+	Provider                 string         `description:"Dump the configuration of this provider only"`
+	Format                   string         `description:"Output format of the configurations (json or yaml)"`
+	Timeout                  parse.Duration `description:"Maximum duration to wait for the configurations of the providers"`
+}
+
+// NewConfiguration creates a Configuration with default values
+func NewConfiguration() *Configuration {
+	return &Configuration{
+		TraefikConfiguration: *cmd.NewTraefikConfiguration(),
+		Format:               "json",
+		Timeout:              parse.Duration(10 * time.Second),
+	}
+}
+
+// NewDefaultPointersConfiguration creates a Configuration with pointers default values
+func NewDefaultPointersConfiguration() *Configuration {
+	return &Configuration{
+		TraefikConfiguration: *cmd.NewTraefikDefaultPointersConfiguration(),
+	}
+}
+
+// NewCmd builds a new DumpConfig command
+func NewCmd(dumpConfiguration *Configuration, dumpPointersConfiguration *Configuration) *flaeg.Command {
+	return &flaeg.Command{
+		Name:                  "dump-config",
+		Description:           `Dump the dynamic configuration provided by the providers, as loaded by traefik. Traefik will not start.`,
+		Config:                dumpConfiguration,
+		DefaultPointersConfig: dumpPointersConfiguration,
+		Run:                   runCmd(dumpConfiguration),
+		Metadata: map[string]string{
+			"parseAllSources": "true",
+		},
+	}
+}
+
+func runCmd(dump *Configuration) func() error {
+	return func() error {
+		globalConfiguration := &dump.GlobalConfiguration
+		globalConfiguration.SetEffectiveConfiguration(dump.ConfigFile)
+
+		format := strings.ToLower(dump.Format)
+		if format != "json" && format != "yaml" {
+			return fmt.Errorf("unsupported format %q, expected json or yaml", dump.Format)
+		}
+
+		providerAggregator := configuration.NewProviderAggregator(globalConfiguration)
+		configurations, err := server.DumpConfigurations(*globalConfiguration, providerAggregator, dump.Provider, time.Duration(dump.Timeout))
+		if err != nil {
+			return err
+		}
+
+		if len(dump.Provider) > 0 && configurations[dump.Provider] == nil {
+			return fmt.Errorf("no configuration provided by the provider %s", dump.Provider)
+		}
+
+		var output []byte
+		if format == "yaml" {
+			output, err = yaml.Marshal(configurations)
+		} else {
+			output, err = json.MarshalIndent(configurations, "", "  ")
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(output))
+		return nil
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/containous/traefik/autogen/genstatic"
 	"github.com/containous/traefik/cmd"
 	"github.com/containous/traefik/cmd/bug"
+	"github.com/containous/traefik/cmd/dumpconfig"
 	"github.com/containous/traefik/cmd/healthcheck"
 	"github.com/containous/traefik/cmd/storeconfig"
 	cmdVersion "github.com/containous/traefik/cmd/version"
@@ -25,6 +26,10 @@ import (
 	"github.com/containous/traefik/configuration/router"
 	"github.com/containous/traefik/job"
 	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/log/otlp"
+	"github.com/containous/traefik/middlewares/tracing"
+	"github.com/containous/traefik/ping"
+	acmeprovider "github.com/containous/traefik/provider/acme"
 	"github.com/containous/traefik/provider/ecs"
 	"github.com/containous/traefik/provider/kubernetes"
 	"github.com/containous/traefik/safe"
@@ -61,6 +66,10 @@ Complete documentation is available at https://traefik.io`,
 	// storeconfig Command init
 	storeConfigCmd := storeconfig.NewCmd(traefikConfiguration, traefikPointersConfiguration)
 
+	// dump-config Command init
+	dumpConfiguration := dumpconfig.NewConfiguration()
+	dumpConfigCmd := dumpconfig.NewCmd(dumpConfiguration, dumpconfig.NewDefaultPointersConfiguration())
+
 	// init flaeg source
 	f := flaeg.New(traefikCmd, os.Args[1:])
 	// add custom parsers
@@ -72,16 +81,23 @@ Complete documentation is available at https://traefik.io`,
 	f.AddParser(reflect.TypeOf(ecs.Clusters{}), &ecs.Clusters{})
 	f.AddParser(reflect.TypeOf([]types.Domain{}), &types.Domains{})
 	f.AddParser(reflect.TypeOf(types.DNSResolvers{}), &types.DNSResolvers{})
+	f.AddParser(reflect.TypeOf(acmeprovider.ChallengeDomains{}), &acmeprovider.ChallengeDomains{})
 	f.AddParser(reflect.TypeOf(types.Buckets{}), &types.Buckets{})
 	f.AddParser(reflect.TypeOf(types.StatusCodes{}), &types.StatusCodes{})
+	f.AddParser(reflect.TypeOf(types.KafkaBrokers{}), &types.KafkaBrokers{})
 	f.AddParser(reflect.TypeOf(types.FieldNames{}), &types.FieldNames{})
+	f.AddParser(reflect.TypeOf(types.StatusSamplingRatios{}), &types.StatusSamplingRatios{})
 	f.AddParser(reflect.TypeOf(types.FieldHeaderNames{}), &types.FieldHeaderNames{})
+	f.AddParser(reflect.TypeOf(types.OTLPHeaders{}), &types.OTLPHeaders{})
+	f.AddParser(reflect.TypeOf(tracing.HeaderTags{}), &tracing.HeaderTags{})
+	f.AddParser(reflect.TypeOf(ping.Frontends{}), &ping.Frontends{})
 
 	// add commands
 	f.AddCommand(cmdVersion.NewCmd())
 	f.AddCommand(bug.NewCmd(traefikConfiguration, traefikPointersConfiguration))
 	f.AddCommand(storeConfigCmd)
 	f.AddCommand(healthcheck.NewCmd(traefikConfiguration, traefikPointersConfiguration))
+	f.AddCommand(dumpConfigCmd)
 
 	usedCmd, err := f.GetCommand()
 	if err != nil {
@@ -97,10 +113,17 @@ Complete documentation is available at https://traefik.io`,
 		os.Exit(1)
 	}
 
+	// the dump-config command has its own configuration, which embeds the traefik one:
+	// it is the root of the sources when it is used, to read the TOML file too.
+	rootCmd, rootConfiguration := traefikCmd, traefikConfiguration
+	if usedCmd == dumpConfigCmd {
+		rootCmd, rootConfiguration = dumpConfigCmd, &dumpConfiguration.TraefikConfiguration
+	}
+
 	// staert init
-	s := staert.NewStaert(traefikCmd)
+	s := staert.NewStaert(rootCmd)
 	// init TOML source
-	toml := staert.NewTomlSource("traefik", []string{traefikConfiguration.ConfigFile, "/etc/traefik/", "$HOME/.traefik/", "."})
+	toml := staert.NewTomlSource("traefik", []string{rootConfiguration.ConfigFile, "/etc/traefik/", "$HOME/.traefik/", "."})
 
 	// add sources to staert
 	s.AddSource(toml)
@@ -110,7 +133,7 @@ Complete documentation is available at https://traefik.io`,
 		os.Exit(1)
 	}
 
-	traefikConfiguration.ConfigFile = toml.ConfigFileUsed()
+	rootConfiguration.ConfigFile = toml.ConfigFileUsed()
 
 	kv, err := storeconfig.CreateKvSource(traefikConfiguration)
 	if err != nil {
@@ -166,6 +189,8 @@ func runCmd(globalConfiguration *configuration.GlobalConfiguration, configFile s
 	globalConfiguration.SetEffectiveConfiguration(configFile)
 	globalConfiguration.ValidateConfiguration()
 
+	configureLogsExport(globalConfiguration)
+
 	log.Infof("Traefik version %s built on %s", version.Version, version.BuildDate)
 
 	jsonConf, err := json.Marshal(globalConfiguration)
@@ -212,7 +237,7 @@ func runCmd(globalConfiguration *configuration.GlobalConfiguration, configFile s
 			}
 
 			// TLS ALPN 01
-			if acmeProvider.TLSChallenge != nil && acmeProvider.HTTPChallenge == nil && acmeProvider.DNSChallenge == nil {
+			if acmeProvider.TLSChallenge != nil {
 				entryPoint.TLSALPNGetter = acmeProvider.GetTLSALPNCertificate
 			}
 
@@ -332,6 +357,23 @@ func configureLogging(globalConfiguration *configuration.GlobalConfiguration) {
 	}
 }
 
+// configureLogsExport exports the Traefik logs to an OpenTelemetry collector.
+// It is configured once the effective configuration is known, to get the tracing service name.
+func configureLogsExport(globalConfiguration *configuration.GlobalConfiguration) {
+	if globalConfiguration.TraefikLog == nil || globalConfiguration.TraefikLog.OTLP == nil {
+		return
+	}
+
+	exporter, err := otlp.NewExporter("traefik", globalConfiguration.TraefikLog.OTLP)
+	if err != nil {
+		log.Errorf("Unable to export the Traefik logs: %v", err)
+		return
+	}
+
+	log.AddHook(otlp.NewHook(exporter))
+	logrus.RegisterExitHandler(exporter.Close)
+}
+
 func checkNewVersion() {
 	ticker := time.Tick(24 * time.Hour)
 	safe.Go(func() {
@@ -50,6 +50,12 @@ const (
 	// DefaultDialTimeout when connecting to a backend server.
 	DefaultDialTimeout = 30 * time.Second
 
+	// DefaultMaxRetryBodyBytes is the default maximum size of the request body kept to retry the refused requests.
+	DefaultMaxRetryBodyBytes = 1024 * 1024
+
+	// DefaultDNSRetryInterval is the default interval before retrying a failed resolution of a backend server host name.
+	DefaultDNSRetryInterval = 100 * time.Millisecond
+
 	// DefaultIdleTimeout before closing an idle connection.
 	DefaultIdleTimeout = 180 * time.Second
 
@@ -85,6 +91,8 @@ type GlobalConfiguration struct {
 	HealthCheck               *HealthCheckConfig      `description:"Health check parameters" export:"true"`
 	RespondingTimeouts        *RespondingTimeouts     `description:"Timeouts for incoming requests to the Traefik instance" export:"true"`
 	ForwardingTimeouts        *ForwardingTimeouts     `description:"Timeouts for requests forwarded to the backend servers" export:"true"`
+	ForwardingHTTP2           *ForwardingHTTP2        `description:"HTTP/2 settings for requests forwarded to the backend servers" export:"true"`
+	ForwardingDNS             *ForwardingDNS          `description:"Resolution settings of the host names of the backend servers" export:"true"`
 	KeepTrailingSlash         bool                    `description:"Do not remove trailing slash." export:"true"` // Deprecated
 	Docker                    *docker.Provider        `description:"Enable Docker backend with default settings" export:"true"`
 	File                      *file.Provider          `description:"Enable File backend with default settings" export:"true"`
@@ -178,6 +186,21 @@ func (gc *GlobalConfiguration) SetEffectiveConfiguration(configFile string) {
 
 	gc.initACMEProvider()
 	gc.initTracing()
+	gc.initOTLPLogs()
+}
+
+// initOTLPLogs sets the service name of the exported logs to the one of the tracing, so that they can be correlated.
+func (gc *GlobalConfiguration) initOTLPLogs() {
+	if gc.Tracing == nil || len(gc.Tracing.ServiceName) == 0 {
+		return
+	}
+
+	if gc.AccessLog != nil && gc.AccessLog.OTLP != nil && len(gc.AccessLog.OTLP.ServiceName) == 0 {
+		gc.AccessLog.OTLP.ServiceName = gc.Tracing.ServiceName
+	}
+	if gc.TraefikLog != nil && gc.TraefikLog.OTLP != nil && len(gc.TraefikLog.OTLP.ServiceName) == 0 {
+		gc.TraefikLog.OTLP.ServiceName = gc.Tracing.ServiceName
+	}
 }
 
 func (gc *GlobalConfiguration) initTracing() {
@@ -247,17 +270,19 @@ func (gc *GlobalConfiguration) initACMEProvider() {
 	if gc.ACME != nil {
 		gc.ACME.CAServer = getSafeACMECAServer(gc.ACME.CAServer)
 
-		if gc.ACME.DNSChallenge != nil && gc.ACME.HTTPChallenge != nil {
+		// Several challenges can be used at the same time when they declare their domains.
+
+		if gc.ACME.DNSChallenge != nil && gc.ACME.HTTPChallenge != nil && len(gc.ACME.DNSChallenge.Domains) == 0 && len(gc.ACME.HTTPChallenge.Domains) == 0 {
 			log.Warn("Unable to use DNS challenge and HTTP challenge at the same time. Fallback to DNS challenge.")
 			gc.ACME.HTTPChallenge = nil
 		}
 
-		if gc.ACME.DNSChallenge != nil && gc.ACME.TLSChallenge != nil {
+		if gc.ACME.DNSChallenge != nil && gc.ACME.TLSChallenge != nil && len(gc.ACME.DNSChallenge.Domains) == 0 && len(gc.ACME.TLSChallenge.Domains) == 0 {
 			log.Warn("Unable to use DNS challenge and TLS challenge at the same time. Fallback to DNS challenge.")
 			gc.ACME.TLSChallenge = nil
 		}
 
-		if gc.ACME.HTTPChallenge != nil && gc.ACME.TLSChallenge != nil {
+		if gc.ACME.HTTPChallenge != nil && gc.ACME.TLSChallenge != nil && len(gc.ACME.HTTPChallenge.Domains) == 0 && len(gc.ACME.TLSChallenge.Domains) == 0 {
 			log.Warn("Unable to use HTTP challenge and TLS challenge at the same time. Fallback to TLS challenge.")
 			gc.ACME.HTTPChallenge = nil
 		}
@@ -305,6 +330,7 @@ func (gc *GlobalConfiguration) InitACMEProvider() (*acmeprovider.Provider, error
 				HTTPChallenge: gc.ACME.HTTPChallenge,
 				DNSChallenge:  gc.ACME.DNSChallenge,
 				TLSChallenge:  gc.ACME.TLSChallenge,
+				EAB:           gc.ACME.EAB,
 				Domains:       gc.ACME.Domains,
 				ACMELogging:   gc.ACME.ACMELogging,
 				CAServer:      gc.ACME.CAServer,
@@ -394,7 +420,13 @@ func (dep *DefaultEntryPoints) Type() string {
 
 // Retry contains request retry config
 type Retry struct {
-	Attempts int `description:"Number of attempts" export:"true"`
+	Attempts            int            `description:"Number of attempts" export:"true"`
+	ResponseHeader      string         `description:"Retry the requests without body when the backend response has this header" export:"true"`
+	ResponseHeaderValue string         `description:"Retry on the response header only when it has this value (any value when empty)" export:"true"`
+	InitialInterval     parse.Duration `description:"Delay before the first retry (no delay when zero)" export:"true"`
+	MaxInterval         parse.Duration `description:"Maximum delay between two attempts (no maximum when zero)" export:"true"`
+	Multiplier          float64        `description:"Factor applied to the delay after each retry" export:"true"`
+	Jitter              bool           `description:"Randomize the delay between zero and its computed value" export:"true"`
 }
 
 // HealthCheckConfig contains health check configuration parameters.
@@ -416,6 +448,20 @@ type ForwardingTimeouts struct {
 	ResponseHeaderTimeout parse.Duration `description:"The amount of time to wait for a server's response headers after fully writing the request (including its body, if any). If zero, no timeout exists" export:"true"`
 }
 
+// ForwardingHTTP2 contains the HTTP/2 configuration for forwarding requests to the backend servers.
+type ForwardingHTTP2 struct {
+	RetryRefusedRequests bool  `description:"Retry on a new connection the requests refused by a backend server (GOAWAY or REFUSED_STREAM), including the ones with a body" export:"true"`
+	MaxRetryBodyBytes    int64 `description:"Maximum size of the request body kept to retry the refused requests. Defaults to 1MB" export:"true"`
+}
+
+// ForwardingDNS contains the resolution settings of the host names of the backend servers,
+// to ride out the transient failures of the name servers.
+type ForwardingDNS struct {
+	Retries       int            `description:"Number of retries of a failed resolution of a backend server host name, with an exponential backoff" export:"true"`
+	RetryInterval parse.Duration `description:"Interval before the first retry of a failed resolution, doubled for each retry. Defaults to 100ms" export:"true"`
+	GracePeriod   parse.Duration `description:"Duration during which the last known addresses of a backend server host name are used while its resolution fails" export:"true"`
+}
+
 // LifeCycle contains configurations relevant to the lifecycle (such as the
 // shutdown phase) of Traefik.
 type LifeCycle struct {
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/tls"
 	"github.com/containous/traefik/types"
@@ -12,24 +13,85 @@ import (
 
 // EntryPoint holds an entry point configuration of the reverse proxy (ip, port, TLS...)
 type EntryPoint struct {
-	Address          string
-	TLS              *tls.TLS          `export:"true"`
-	Redirect         *types.Redirect   `export:"true"`
-	Auth             *types.Auth       `export:"true"`
-	WhiteList        *types.WhiteList  `export:"true"`
-	Compress         *Compress         `export:"true"`
-	ProxyProtocol    *ProxyProtocol    `export:"true"`
-	ForwardedHeaders *ForwardedHeaders `export:"true"`
-	ClientIPStrategy *types.IPStrategy `export:"true"`
+	Address           string
+	TLS               *tls.TLS           `export:"true"`
+	Redirect          *types.Redirect    `export:"true"`
+	Auth              *types.Auth        `export:"true"`
+	WhiteList         *types.WhiteList   `export:"true"`
+	Compress          *Compress          `export:"true"`
+	ProxyProtocol     *ProxyProtocol     `export:"true"`
+	ForwardedHeaders  *ForwardedHeaders  `export:"true"`
+	ClientIPStrategy  *types.IPStrategy  `export:"true"`
+	HTTP2             *HTTP2             `export:"true"`
+	MissingHost       *MissingHost       `export:"true"`
+	UserAgentClassify *UserAgentClassify `export:"true"`
+	ConnectionAge     *ConnectionAge     `export:"true"`
+	RequestID         *RequestID         `export:"true"`
+	Transport         *Transport         `export:"true"`
+	// StripHostTrailingDot routes the hosts and server names ending with a dot as the ones without it.
+	StripHostTrailingDot bool `export:"true"`
 }
 
 // Compress contains compress configuration
-type Compress struct{}
+type Compress struct {
+	Encodings []string `description:"Compression encodings, by order of preference (zstd, br, gzip)" export:"true"`
+}
+
+// HTTP2 contains HTTP/2 specific configuration
+type HTTP2 struct {
+	MaxConcurrentStreamsPerConn int64 `description:"Maximum number of concurrent streams a single client connection can have in flight" export:"true"`
+	DisableCoalescing           bool  `description:"Reject with a 421 Misdirected Request the requests for another host than the TLS server name of the connection" export:"true"`
+}
+
+// Transport defines the TCP options of the client connections of an entry point
+type Transport struct {
+	KeepAlive      *TCPKeepAlive  `description:"TCP keep-alive of the client connections" export:"true"`
+	MaxConnections int            `description:"Maximum number of simultaneous client connections" export:"true"`
+	QueueTimeout   parse.Duration `description:"Maximum time a client connection waits for a free slot, when MaxConnections is reached, before it is closed" export:"true"`
+	ReusePort      bool           `description:"Set SO_REUSEPORT and SO_REUSEADDR on the listener, so that several processes can listen on the same port" export:"true"`
+}
+
+// TCPKeepAlive defines the TCP keep-alive probes of the client connections.
+// Interval and Count are only supported on Linux, and default to the settings of the system.
+type TCPKeepAlive struct {
+	Idle     parse.Duration `description:"Idle time of a connection before the first keep-alive probe (3m by default)" export:"true"`
+	Interval parse.Duration `description:"Time between the keep-alive probes" export:"true"`
+	Count    int            `description:"Number of unanswered keep-alive probes before the connection is closed" export:"true"`
+}
+
+// ConnectionAge defines the maximum age of the client connections,
+// past which they are closed after the current response so that the clients reconnect
+type ConnectionAge struct {
+	MaxAge parse.Duration `description:"Maximum age of the client connections" export:"true"`
+}
+
+// RequestID defines the header carrying the ID of the requests, generated when missing,
+// and forwarded to the backends, returned to the clients, and logged in the access logs and the traces
+type RequestID struct {
+	HeaderName string `description:"Header carrying the request ID" export:"true"`
+	Generator  string `description:"Generator of the missing request IDs (uuid or ulid)" export:"true"`
+}
+
+// UserAgentClassify defines how the requests are classified (crawler, bot, browser or unknown) from their User-Agent header
+type UserAgentClassify struct {
+	HeaderName   string   `description:"Header set with the class of the user agent" export:"true"`
+	Crawlers     []string `description:"User agent patterns of the crawlers" export:"true"`
+	Bots         []string `description:"User agent patterns of the bots" export:"true"`
+	Browsers     []string `description:"User agent patterns of the browsers" export:"true"`
+	PatternsFile string   `description:"File of user agent patterns, reloaded when modified" export:"true"`
+}
+
+// MissingHost defines how requests without Host header (e.g. HTTP/1.0) are handled
+type MissingHost struct {
+	DefaultHost string `description:"Host used to route requests without Host header" export:"true"`
+	Reject      bool   `description:"Reject requests without Host header with a 400 Bad Request" export:"true"`
+}
 
 // ProxyProtocol contains Proxy-Protocol configuration
 type ProxyProtocol struct {
 	Insecure   bool `export:"true"`
 	TrustedIPs []string
+	TLVHeaders map[string]string `description:"Headers set from the TLVs of the PROXY protocol v2 header, by TLV name" export:"true"`
 }
 
 // ForwardedHeaders Trust client forwarding headers
@@ -69,8 +131,11 @@ func (ep *EntryPoints) Set(value string) error {
 	result := parseEntryPointsConfiguration(value)
 
 	var compress *Compress
-	if len(result["compress"]) > 0 {
+	if len(result["compress"]) > 0 || len(result["compress_encodings"]) > 0 {
 		compress = &Compress{}
+		if v, ok := result["compress_encodings"]; ok {
+			compress.Encodings = strings.Split(v, ",")
+		}
 	}
 
 	configTLS, err := makeEntryPointTLS(result)
@@ -79,20 +144,139 @@ func (ep *EntryPoints) Set(value string) error {
 	}
 
 	(*ep)[result["name"]] = &EntryPoint{
-		Address:          result["address"],
-		TLS:              configTLS,
-		Auth:             makeEntryPointAuth(result),
-		Redirect:         makeEntryPointRedirect(result),
-		Compress:         compress,
-		WhiteList:        makeWhiteList(result),
-		ProxyProtocol:    makeEntryPointProxyProtocol(result),
-		ForwardedHeaders: makeEntryPointForwardedHeaders(result),
-		ClientIPStrategy: makeIPStrategy("clientipstrategy", result),
+		Address:           result["address"],
+		TLS:               configTLS,
+		Auth:              makeEntryPointAuth(result),
+		Redirect:          makeEntryPointRedirect(result),
+		Compress:          compress,
+		WhiteList:         makeWhiteList(result),
+		ProxyProtocol:     makeEntryPointProxyProtocol(result),
+		ForwardedHeaders:  makeEntryPointForwardedHeaders(result),
+		ClientIPStrategy:  makeIPStrategy("clientipstrategy", result),
+		HTTP2:             makeEntryPointHTTP2(result),
+		MissingHost:       makeEntryPointMissingHost(result),
+		UserAgentClassify: makeEntryPointUserAgentClassify(result),
+		ConnectionAge:     makeEntryPointConnectionAge(result),
+		RequestID:         makeEntryPointRequestID(result),
+		Transport:         makeEntryPointTransport(result),
+
+		StripHostTrailingDot: toBool(result, "striphosttrailingdot"),
 	}
 
 	return nil
 }
 
+func makeEntryPointHTTP2(result map[string]string) *HTTP2 {
+	maxStreams := toInt(result, "http2_maxconcurrentstreamsperconn")
+	disableCoalescing := toBool(result, "http2_disablecoalescing")
+	if maxStreams <= 0 && !disableCoalescing {
+		return nil
+	}
+
+	http2 := &HTTP2{DisableCoalescing: disableCoalescing}
+	if maxStreams > 0 {
+		http2.MaxConcurrentStreamsPerConn = int64(maxStreams)
+	}
+	return http2
+}
+
+func makeEntryPointMissingHost(result map[string]string) *MissingHost {
+	defaultHost := result["missinghost_defaulthost"]
+	reject := toBool(result, "missinghost_reject")
+	if len(defaultHost) == 0 && !reject {
+		return nil
+	}
+
+	return &MissingHost{
+		DefaultHost: defaultHost,
+		Reject:      reject,
+	}
+}
+
+func makeEntryPointUserAgentClassify(result map[string]string) *UserAgentClassify {
+	_, enabled := result["useragentclassify"]
+	headerName := result["useragentclassify_headername"]
+	patternsFile := result["useragentclassify_patternsfile"]
+	if !enabled && len(headerName) == 0 && len(patternsFile) == 0 {
+		return nil
+	}
+
+	return &UserAgentClassify{
+		HeaderName:   headerName,
+		PatternsFile: patternsFile,
+	}
+}
+
+func makeEntryPointConnectionAge(result map[string]string) *ConnectionAge {
+	v, ok := result["connectionage_maxage"]
+	if !ok {
+		return nil
+	}
+
+	connectionAge := &ConnectionAge{}
+	if err := connectionAge.MaxAge.Set(v); err != nil {
+		log.Errorf("Invalid connection max age %q: %v", v, err)
+		return nil
+	}
+	return connectionAge
+}
+
+func makeEntryPointTransport(result map[string]string) *Transport {
+	if len(result["transport_keepalive_idle"]) == 0 && len(result["transport_keepalive_interval"]) == 0 &&
+		len(result["transport_keepalive_count"]) == 0 && len(result["transport_maxconnections"]) == 0 &&
+		len(result["transport_queuetimeout"]) == 0 && len(result["transport_reuseport"]) == 0 {
+		return nil
+	}
+
+	transport := &Transport{
+		MaxConnections: toInt(result, "transport_maxconnections"),
+		ReusePort:      toBool(result, "transport_reuseport"),
+	}
+
+	if v, ok := result["transport_queuetimeout"]; ok {
+		if err := transport.QueueTimeout.Set(v); err != nil {
+			log.Errorf("Invalid transport queue timeout %q: %v", v, err)
+		}
+	}
+
+	if len(result["transport_keepalive_idle"]) > 0 || len(result["transport_keepalive_interval"]) > 0 ||
+		len(result["transport_keepalive_count"]) > 0 {
+		keepAlive := &TCPKeepAlive{
+			Count: toInt(result, "transport_keepalive_count"),
+		}
+
+		if v, ok := result["transport_keepalive_idle"]; ok {
+			if err := keepAlive.Idle.Set(v); err != nil {
+				log.Errorf("Invalid transport keep-alive idle %q: %v", v, err)
+			}
+		}
+
+		if v, ok := result["transport_keepalive_interval"]; ok {
+			if err := keepAlive.Interval.Set(v); err != nil {
+				log.Errorf("Invalid transport keep-alive interval %q: %v", v, err)
+			}
+		}
+
+		transport.KeepAlive = keepAlive
+	}
+
+	return transport
+}
+
+func makeEntryPointRequestID(result map[string]string) *RequestID {
+	_, enabled := result["requestid"]
+	headerName := result["requestid_headername"]
+	generator := result["requestid_generator"]
+	if !enabled && len(headerName) == 0 && len(generator) == 0 {
+		return nil
+	}
+
+	return &RequestID{
+		HeaderName: headerName,
+		Generator:  generator,
+	}
+}
+
 func makeWhiteList(result map[string]string) *types.WhiteList {
 	if rawRange, ok := result["whitelist_sourcerange"]; ok {
 		return &types.WhiteList{
@@ -164,14 +348,62 @@ func makeEntryPointAuth(result map[string]string) *types.Auth {
 			TrustForwardHeader:  toBool(result, "auth_forward_trustforwardheader"),
 			AuthResponseHeaders: authResponseHeaders,
 		}
+
+		_, hasKey := result["auth_forward_cache_key"]
+		_, hasTTL := result["auth_forward_cache_ttl"]
+		_, hasMaxEntries := result["auth_forward_cache_maxentries"]
+		if hasKey || hasTTL || hasMaxEntries {
+			forward.Cache = &types.ForwardCache{
+				Key:        result["auth_forward_cache_key"],
+				MaxEntries: toInt(result, "auth_forward_cache_maxentries"),
+			}
+
+			if v, ok := result["auth_forward_cache_ttl"]; ok {
+				if err := forward.Cache.TTL.Set(v); err != nil {
+					log.Errorf("Invalid forward auth cache TTL %q: %v", v, err)
+				}
+			}
+		}
+	}
+
+	var jwt *types.JWT
+	if jwksURL, ok := result["auth_jwt_jwksurl"]; ok {
+		jwt = &types.JWT{
+			JWKSURL:        jwksURL,
+			Issuer:         result["auth_jwt_issuer"],
+			ForwardHeaders: toBool(result, "auth_jwt_forwardheaders"),
+		}
+
+		if v, ok := result["auth_jwt_audiences"]; ok {
+			jwt.Audiences = strings.Split(v, ",")
+		}
+
+		if v, ok := result["auth_jwt_claimsheaders"]; ok {
+			jwt.ClaimsHeaders = make(map[string]string)
+			for _, mapping := range strings.Split(v, ",") {
+				parts := strings.SplitN(mapping, ":", 2)
+				if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+					log.Errorf("Invalid JWT claims header mapping %q, expected claim:header", mapping)
+					continue
+				}
+				jwt.ClaimsHeaders[parts[0]] = parts[1]
+			}
+		}
+
+		if v, ok := result["auth_jwt_refreshinterval"]; ok {
+			if err := jwt.RefreshInterval.Set(v); err != nil {
+				log.Errorf("Invalid JWT refresh interval %q: %v", v, err)
+			}
+		}
 	}
 
 	var auth *types.Auth
-	if basic != nil || digest != nil || forward != nil {
+	if basic != nil || digest != nil || forward != nil || jwt != nil {
 		auth = &types.Auth{
 			Basic:       basic,
 			Digest:      digest,
 			Forward:     forward,
+			JWT:         jwt,
 			HeaderField: result["auth_headerfield"],
 		}
 	}
@@ -190,6 +422,18 @@ func makeEntryPointProxyProtocol(result map[string]string) *ProxyProtocol {
 		if len(ppTrustedIPs) > 0 {
 			proxyProtocol.TrustedIPs = strings.Split(ppTrustedIPs, ",")
 		}
+
+		if v, ok := result["proxyprotocol_tlvheaders"]; ok {
+			proxyProtocol.TLVHeaders = make(map[string]string)
+			for _, mapping := range strings.Split(v, ",") {
+				parts := strings.SplitN(mapping, ":", 2)
+				if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+					log.Errorf("Invalid PROXY protocol TLV header mapping %q, expected tlv:header", mapping)
+					continue
+				}
+				proxyProtocol.TLVHeaders[parts[0]] = parts[1]
+			}
+		}
 	}
 
 	if proxyProtocol != nil && proxyProtocol.Insecure {
@@ -258,6 +502,36 @@ func makeEntryPointTLS(result map[string]string) (*tls.TLS, error) {
 			}
 		}
 
+		if len(result["ca_clientauthtype"]) > 0 {
+			configTLS.ClientCA.ClientAuthType = result["ca_clientauthtype"]
+		}
+
+		if len(result["ca_crl_files"]) > 0 || len(result["ca_crl_urls"]) > 0 {
+			crl := &tls.CRL{
+				FailOpen: toBool(result, "ca_crl_failopen"),
+			}
+
+			if len(result["ca_crl_files"]) > 0 {
+				files := tls.FilesOrContents{}
+				files.Set(result["ca_crl_files"])
+				crl.Files = files
+			}
+
+			if len(result["ca_crl_urls"]) > 0 {
+				crl.URLs = strings.Split(result["ca_crl_urls"], ",")
+			}
+
+			if len(result["ca_crl_refreshinterval"]) > 0 {
+				var refreshInterval parse.Duration
+				if err := refreshInterval.Set(result["ca_crl_refreshinterval"]); err != nil {
+					return nil, err
+				}
+				crl.RefreshInterval = refreshInterval
+			}
+
+			configTLS.ClientCA.CRL = crl
+		}
+
 		if len(result["tls_minversion"]) > 0 {
 			configTLS.MinVersion = result["tls_minversion"]
 		}
@@ -270,6 +544,52 @@ func makeEntryPointTLS(result map[string]string) (*tls.TLS, error) {
 			configTLS.SniStrict = toBool(result, "tls_snistrict")
 		}
 
+		if len(result["tls_disableocspstapling"]) > 0 {
+			configTLS.DisableOCSPStapling = toBool(result, "tls_disableocspstapling")
+		}
+
+		if len(result["tls_sessiontickets_rotation"]) > 0 || len(result["tls_sessiontickets_keyfile"]) > 0 {
+			sessionTickets := &tls.SessionTickets{
+				KeyFile: result["tls_sessiontickets_keyfile"],
+			}
+
+			if len(result["tls_sessiontickets_rotation"]) > 0 {
+				var rotation parse.Duration
+				if err := rotation.Set(result["tls_sessiontickets_rotation"]); err != nil {
+					return nil, err
+				}
+				sessionTickets.Rotation = rotation
+			}
+
+			configTLS.SessionTickets = sessionTickets
+		}
+
+		if len(result["tls_handshakes_maxconcurrent"]) > 0 || len(result["tls_handshakes_rate"]) > 0 ||
+			len(result["tls_handshakes_burst"]) > 0 || len(result["tls_handshakes_queuetimeout"]) > 0 {
+			handshakes := &tls.Handshakes{
+				MaxConcurrent: toInt(result, "tls_handshakes_maxconcurrent"),
+				Burst:         toInt(result, "tls_handshakes_burst"),
+			}
+
+			if len(result["tls_handshakes_rate"]) > 0 {
+				handshakeRate, err := strconv.ParseFloat(result["tls_handshakes_rate"], 64)
+				if err != nil {
+					return nil, err
+				}
+				handshakes.Rate = handshakeRate
+			}
+
+			if len(result["tls_handshakes_queuetimeout"]) > 0 {
+				var queueTimeout parse.Duration
+				if err := queueTimeout.Set(result["tls_handshakes_queuetimeout"]); err != nil {
+					return nil, err
+				}
+				handshakes.QueueTimeout = queueTimeout
+			}
+
+			configTLS.Handshakes = handshakes
+		}
+
 		if len(result["tls_defaultcertificate_cert"]) > 0 && len(result["tls_defaultcertificate_key"]) > 0 {
 			configTLS.DefaultCertificate = &tls.Certificate{
 				CertFile: tls.FileOrContent(result["tls_defaultcertificate_cert"]),
@@ -2,7 +2,9 @@ package configuration
 
 import (
 	"testing"
+	"time"
 
+	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/tls"
 	"github.com/containous/traefik/types"
 	"github.com/stretchr/testify/assert"
@@ -479,6 +481,18 @@ func TestEntryPoints_Set(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:                   "ProxyProtocol TLVHeaders",
+			expression:             "Name:foo ProxyProtocol.TrustedIPs:10.0.0.3/24 ProxyProtocol.TLVHeaders:aws_vpce_id:X-Amzn-Vpce-Id,0xE0:X-Tenant",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				ProxyProtocol: &ProxyProtocol{
+					TrustedIPs: []string{"10.0.0.3/24"},
+					TLVHeaders: map[string]string{"aws_vpce_id": "X-Amzn-Vpce-Id", "0xE0": "X-Tenant"},
+				},
+			},
+		},
 		{
 			name:                   "compress on",
 			expression:             "Name:foo Compress:on",
@@ -497,6 +511,257 @@ func TestEntryPoints_Set(t *testing.T) {
 				ForwardedHeaders: &ForwardedHeaders{},
 			},
 		},
+		{
+			name:                   "compress encodings",
+			expression:             "Name:foo Compress.Encodings:br,gzip",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				Compress:         &Compress{Encodings: []string{"br", "gzip"}},
+				ForwardedHeaders: &ForwardedHeaders{},
+			},
+		},
+		{
+			name:                   "HTTP2 max concurrent streams per connection",
+			expression:             "Name:foo HTTP2.MaxConcurrentStreamsPerConn:10",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				HTTP2:            &HTTP2{MaxConcurrentStreamsPerConn: 10},
+			},
+		},
+		{
+			name:                   "HTTP2 disable coalescing",
+			expression:             "Name:foo HTTP2.DisableCoalescing:true",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				HTTP2:            &HTTP2{DisableCoalescing: true},
+			},
+		},
+		{
+			name:                   "missing host default host",
+			expression:             "Name:foo MissingHost.DefaultHost:example.com",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				MissingHost:      &MissingHost{DefaultHost: "example.com"},
+			},
+		},
+		{
+			name:                   "missing host reject",
+			expression:             "Name:foo MissingHost.Reject:true",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				MissingHost:      &MissingHost{Reject: true},
+			},
+		},
+		{
+			name:                   "user agent classify",
+			expression:             "Name:foo UserAgentClassify",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders:  &ForwardedHeaders{},
+				UserAgentClassify: &UserAgentClassify{},
+			},
+		},
+		{
+			name:                   "user agent classify header name and patterns file",
+			expression:             "Name:foo UserAgentClassify.HeaderName:X-UA-Class UserAgentClassify.PatternsFile:/etc/traefik/ua.txt",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				UserAgentClassify: &UserAgentClassify{
+					HeaderName:   "X-UA-Class",
+					PatternsFile: "/etc/traefik/ua.txt",
+				},
+			},
+		},
+		{
+			name:                   "connection max age",
+			expression:             "Name:foo ConnectionAge.MaxAge:10m",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				ConnectionAge:    &ConnectionAge{MaxAge: parse.Duration(10 * time.Minute)},
+			},
+		},
+		{
+			name:                   "transport",
+			expression:             "Name:foo Transport.KeepAlive.Idle:1m Transport.KeepAlive.Interval:10s Transport.KeepAlive.Count:3 Transport.MaxConnections:1000 Transport.QueueTimeout:2s Transport.ReusePort:true",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				Transport: &Transport{
+					KeepAlive: &TCPKeepAlive{
+						Idle:     parse.Duration(time.Minute),
+						Interval: parse.Duration(10 * time.Second),
+						Count:    3,
+					},
+					MaxConnections: 1000,
+					QueueTimeout:   parse.Duration(2 * time.Second),
+					ReusePort:      true,
+				},
+			},
+		},
+		{
+			name:                   "transport max connections",
+			expression:             "Name:foo Transport.MaxConnections:1000",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				Transport:        &Transport{MaxConnections: 1000},
+			},
+		},
+		{
+			name:                   "TLS client auth type",
+			expression:             "Name:foo TLS CA.ClientAuthType:RequestClientCert",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				TLS: &tls.TLS{
+					Certificates: tls.Certificates{},
+					ClientCA:     tls.ClientCA{ClientAuthType: "RequestClientCert"},
+				},
+			},
+		},
+		{
+			name:                   "TLS client CRL",
+			expression:             "Name:foo TLS CA:ca.crt CA.CRL.Files:ca.crl,ca2.crl CA.CRL.URLs:http://ca.example.com/ca.crl CA.CRL.RefreshInterval:10m CA.CRL.FailOpen:true",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				TLS: &tls.TLS{
+					Certificates: tls.Certificates{},
+					ClientCA: tls.ClientCA{
+						Files: tls.FilesOrContents{"ca.crt"},
+						CRL: &tls.CRL{
+							Files:           tls.FilesOrContents{"ca.crl", "ca2.crl"},
+							URLs:            []string{"http://ca.example.com/ca.crl"},
+							RefreshInterval: parse.Duration(10 * time.Minute),
+							FailOpen:        true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:                   "TLS OCSP stapling disabled",
+			expression:             "Name:foo TLS TLS.DisableOCSPStapling:true",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				TLS: &tls.TLS{
+					Certificates:        tls.Certificates{},
+					DisableOCSPStapling: true,
+				},
+			},
+		},
+		{
+			name:                   "TLS session tickets",
+			expression:             "Name:foo TLS TLS.SessionTickets.Rotation:1h TLS.SessionTickets.KeyFile:/etc/traefik/tickets.key",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				TLS: &tls.TLS{
+					Certificates: tls.Certificates{},
+					SessionTickets: &tls.SessionTickets{
+						Rotation: parse.Duration(time.Hour),
+						KeyFile:  "/etc/traefik/tickets.key",
+					},
+				},
+			},
+		},
+		{
+			name:                   "TLS handshake limits",
+			expression:             "Name:foo TLS TLS.Handshakes.MaxConcurrent:64 TLS.Handshakes.Rate:0.5 TLS.Handshakes.Burst:2 TLS.Handshakes.QueueTimeout:2s",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				TLS: &tls.TLS{
+					Certificates: tls.Certificates{},
+					Handshakes: &tls.Handshakes{
+						MaxConcurrent: 64,
+						Rate:          0.5,
+						Burst:         2,
+						QueueTimeout:  parse.Duration(2 * time.Second),
+					},
+				},
+			},
+		},
+		{
+			name:                   "request ID",
+			expression:             "Name:foo RequestID",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				RequestID:        &RequestID{},
+			},
+		},
+		{
+			name:                   "request ID header name and generator",
+			expression:             "Name:foo RequestID.HeaderName:X-Correlation-Id RequestID.Generator:ulid",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				RequestID:        &RequestID{HeaderName: "X-Correlation-Id", Generator: "ulid"},
+			},
+		},
+		{
+			name:                   "strip host trailing dot",
+			expression:             "Name:foo StripHostTrailingDot:true",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders:     &ForwardedHeaders{},
+				StripHostTrailingDot: true,
+			},
+		},
+		{
+			name: "auth JWT",
+			expression: "Name:foo " +
+				"Auth.JWT.JWKSURL:https://auth.example.com/.well-known/jwks.json " +
+				"Auth.JWT.Issuer:https://auth.example.com/ " +
+				"Auth.JWT.Audiences:api,web " +
+				"Auth.JWT.ClaimsHeaders:sub:X-User,email:X-User-Email " +
+				"Auth.JWT.ForwardHeaders:true " +
+				"Auth.JWT.RefreshInterval:10m",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				Auth: &types.Auth{
+					JWT: &types.JWT{
+						JWKSURL:         "https://auth.example.com/.well-known/jwks.json",
+						Issuer:          "https://auth.example.com/",
+						Audiences:       []string{"api", "web"},
+						ClaimsHeaders:   map[string]string{"sub": "X-User", "email": "X-User-Email"},
+						ForwardHeaders:  true,
+						RefreshInterval: parse.Duration(10 * time.Minute),
+					},
+				},
+			},
+		},
+		{
+			name: "auth forward cache",
+			expression: "Name:foo " +
+				"Auth.Forward.Address:https://authserver.com/auth " +
+				"Auth.Forward.Cache.Key:X-Api-Key " +
+				"Auth.Forward.Cache.TTL:30s " +
+				"Auth.Forward.Cache.MaxEntries:500",
+			expectedEntryPointName: "foo",
+			expectedEntryPoint: &EntryPoint{
+				ForwardedHeaders: &ForwardedHeaders{},
+				Auth: &types.Auth{
+					Forward: &types.Forward{
+						Address: "https://authserver.com/auth",
+						Cache: &types.ForwardCache{
+							Key:        "X-Api-Key",
+							TTL:        parse.Duration(30 * time.Second),
+							MaxEntries: 500,
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range testCases {
@@ -88,6 +88,11 @@ func (p *ProviderAggregator) AddProvider(provider provider.Provider) error {
 	return nil
 }
 
+// Len returns the number of the providers
+func (p ProviderAggregator) Len() int {
+	return len(p.providers)
+}
+
 // Init the provider
 func (p ProviderAggregator) Init(_ types.Constraints) error {
 	return nil
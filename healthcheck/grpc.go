@@ -0,0 +1,83 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// checkGRPCHealth calls the Check method of the gRPC health checking protocol on the server,
+// and returns a non-nil error, with the gRPC status, if the server is not serving.
+// The https servers are dialed with TLS, the others (http, h2c) without.
+func checkGRPCHealth(serverURL *url.URL, backend *BackendConfig) error {
+	scheme := serverURL.Scheme
+	if len(backend.Scheme) > 0 {
+		scheme = backend.Scheme
+	}
+
+	port := serverURL.Port()
+	if backend.Port != 0 {
+		port = strconv.Itoa(backend.Port)
+	}
+	if len(port) == 0 {
+		port = "80"
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+
+	opts := []grpc.DialOption{grpc.WithBlock()}
+	if scheme == "https" {
+		tlsConfig := &tls.Config{}
+		if backend.TLSConfig != nil {
+			tlsConfig = backend.TLSConfig.Clone()
+		}
+		if len(tlsConfig.ServerName) == 0 && len(backend.Hostname) > 0 {
+			tlsConfig.ServerName = backend.Hostname
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	if len(backend.Hostname) > 0 {
+		opts = append(opts, grpc.WithAuthority(backend.Hostname))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if backend.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), backend.Timeout)
+	}
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, net.JoinHostPort(serverURL.Hostname(), port), opts...)
+	if err != nil {
+		return fmt.Errorf("gRPC connection failed: %s", err)
+	}
+	defer conn.Close()
+
+	if len(backend.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(backend.Headers))
+	}
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: backend.Service})
+	if err != nil {
+		st, _ := status.FromError(err)
+		return fmt.Errorf("gRPC health check failed with status %s: %s", st.Code(), st.Message())
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("received gRPC health status: %s", resp.GetStatus())
+	}
+
+	return nil
+}
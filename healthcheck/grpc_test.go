@@ -0,0 +1,124 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type healthServer struct {
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+	header   string
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md["x-custom"]) > 0 {
+		h.header = md["x-custom"][0]
+	}
+
+	st, ok := h.statuses[req.Service]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return &healthpb.HealthCheckResponse{Status: st}, nil
+}
+
+func TestCheckGRPCHealth(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	health := &healthServer{statuses: map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":            healthpb.HealthCheckResponse_SERVING,
+		"foo.Serving": healthpb.HealthCheckResponse_SERVING,
+		"foo.Down":    healthpb.HealthCheckResponse_NOT_SERVING,
+	}}
+	healthpb.RegisterHealthServer(server, health)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc          string
+		serverURL     string
+		service       string
+		port          int
+		expectedError string
+	}{
+		{
+			desc:      "server serving",
+			serverURL: "h2c://127.0.0.1:" + port,
+		},
+		{
+			desc:      "service serving",
+			serverURL: "http://127.0.0.1:" + port,
+			service:   "foo.Serving",
+		},
+		{
+			desc:          "service not serving",
+			serverURL:     "h2c://127.0.0.1:" + port,
+			service:       "foo.Down",
+			expectedError: "received gRPC health status: NOT_SERVING",
+		},
+		{
+			desc:          "unknown service",
+			serverURL:     "h2c://127.0.0.1:" + port,
+			service:       "foo.Unknown",
+			expectedError: "gRPC health check failed with status NotFound: unknown service",
+		},
+		{
+			desc:      "health check port",
+			serverURL: "h2c://127.0.0.1:1",
+			port:      listener.Addr().(*net.TCPAddr).Port,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			serverURL, err := url.Parse(test.serverURL)
+			require.NoError(t, err)
+
+			backend := NewBackendConfig(Options{
+				Mode:    ModeGRPC,
+				Service: test.service,
+				Port:    test.port,
+				Timeout: time.Second,
+				Headers: map[string]string{"X-Custom": "foo"},
+			}, "backend")
+
+			err = checkHealth(serverURL, backend)
+			if len(test.expectedError) > 0 {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "foo", health.header)
+		})
+	}
+}
+
+func TestCheckGRPCHealthUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	backend := NewBackendConfig(Options{Mode: ModeGRPC, Timeout: 100 * time.Millisecond}, "backend")
+
+	err = checkHealth(&url.URL{Scheme: "h2c", Host: addr}, backend)
+	assert.Error(t, err)
+}
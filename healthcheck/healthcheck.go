@@ -1,11 +1,17 @@
 package healthcheck
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -33,7 +39,18 @@ type metricsRegistry interface {
 	BackendServerUpGauge() metrics.Gauge
 }
 
+// maxBodyBytes is the maximum number of bytes of the response body read to check the expected body.
+const maxBodyBytes = 64 * 1024
+
+// Health check modes.
+const (
+	ModeHTTP = "http"
+	ModeGRPC = "grpc"
+)
+
 // Options are the public health check options.
+// In the gRPC mode, the Check method of the gRPC health checking protocol is called for the service,
+// with the TLS configuration for the https servers, instead of requesting the path.
 type Options struct {
 	Headers   map[string]string
 	Hostname  string
@@ -44,9 +61,25 @@ type Options struct {
 	Interval  time.Duration
 	Timeout   time.Duration
 	LB        BalancerHandler
+	Mode      string
+	Service   string
+	TLSConfig *tls.Config
+
+	// In the HTTP mode, the response must also contain the expected body and match the regex,
+	// within its first bytes, and have the expected header values.
+	ExpectedBody      string
+	ExpectedBodyRegex *regexp.Regexp
+	ExpectedHeaders   map[string]string
+
+	// Removed, when set, returns whether a disabled server was removed from the backend,
+	// in which case it is no longer checked, nor returned to the load balancer.
+	Removed func(u *url.URL) bool
 }
 
 func (opt Options) String() string {
+	if opt.Mode == ModeGRPC {
+		return fmt.Sprintf("[Mode: %s Hostname: %s Headers: %v Service: %s Port: %d Interval: %s Timeout: %s]", opt.Mode, opt.Hostname, opt.Headers, opt.Service, opt.Port, opt.Interval, opt.Timeout)
+	}
 	return fmt.Sprintf("[Hostname: %s Headers: %v Path: %s Port: %d Interval: %s Timeout: %s]", opt.Hostname, opt.Headers, opt.Path, opt.Port, opt.Interval, opt.Timeout)
 }
 
@@ -54,10 +87,59 @@ func (opt Options) String() string {
 type BackendConfig struct {
 	Options
 	name           string
+	lock           sync.RWMutex
 	disabledURLs   []*url.URL
 	requestTimeout time.Duration
 }
 
+// ServerHealth is the health view of a backend server.
+type ServerHealth struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+// BackendHealth is the health view of a backend:
+// the healthy servers, which are in the load balancer, and the disabled ones.
+type BackendHealth struct {
+	Servers         []ServerHealth `json:"servers"`
+	DisabledServers []string       `json:"disabledServers,omitempty"`
+}
+
+// serverWeighter is implemented by the load balancers exposing the weights of their servers.
+type serverWeighter interface {
+	ServerWeight(u *url.URL) (int, bool)
+}
+
+// Health returns the health view of the backend.
+// The weights are only known for the load balancers exposing them (i.e. not for drr).
+func (b *BackendConfig) Health() *BackendHealth {
+	health := &BackendHealth{Servers: []ServerHealth{}}
+
+	weighter, _ := b.LB.(serverWeighter)
+	for _, u := range b.LB.Servers() {
+		server := ServerHealth{URL: u.String()}
+		if weighter != nil {
+			if weight, ok := weighter.ServerWeight(u); ok {
+				server.Weight = weight
+			}
+		}
+		health.Servers = append(health.Servers, server)
+	}
+
+	b.lock.RLock()
+	for _, u := range b.disabledURLs {
+		health.DisabledServers = append(health.DisabledServers, u.String())
+	}
+	b.lock.RUnlock()
+
+	sort.Slice(health.Servers, func(i, j int) bool {
+		return health.Servers[i].URL < health.Servers[j].URL
+	})
+	sort.Strings(health.DisabledServers)
+
+	return health
+}
+
 func (b *BackendConfig) newRequest(serverURL *url.URL) (*http.Request, error) {
 	u := &url.URL{}
 	*u = *serverURL
@@ -92,11 +174,15 @@ type HealthCheck struct {
 	Backends map[string]*BackendConfig
 	metrics  metricsRegistry
 	cancel   context.CancelFunc
+	lock     sync.RWMutex
 }
 
 // SetBackendsConfiguration set backends configuration
 func (hc *HealthCheck) SetBackendsConfiguration(parentCtx context.Context, backends map[string]*BackendConfig) {
+	hc.lock.Lock()
 	hc.Backends = backends
+	hc.lock.Unlock()
+
 	if hc.cancel != nil {
 		hc.cancel()
 	}
@@ -132,6 +218,11 @@ func (hc *HealthCheck) checkBackend(backend *BackendConfig) {
 	enabledURLs := backend.LB.Servers()
 	var newDisabledURLs []*url.URL
 	for _, disableURL := range backend.disabledURLs {
+		if backend.Removed != nil && backend.Removed(disableURL) {
+			log.Debugf("Health check stopped for the removed server. Backend: %q URL: %q", backend.name, disableURL.String())
+			continue
+		}
+
 		serverUpMetricValue := float64(0)
 		if err := checkHealth(disableURL, backend); err == nil {
 			log.Warnf("Health check up: Returning to server list. Backend: %q URL: %q", backend.name, disableURL.String())
@@ -146,7 +237,9 @@ func (hc *HealthCheck) checkBackend(backend *BackendConfig) {
 		labelValues := []string{"backend", backend.name, "url", disableURL.String()}
 		hc.metrics.BackendServerUpGauge().With(labelValues...).Set(serverUpMetricValue)
 	}
+	backend.lock.Lock()
 	backend.disabledURLs = newDisabledURLs
+	backend.lock.Unlock()
 
 	for _, enableURL := range enabledURLs {
 		serverUpMetricValue := float64(1)
@@ -155,7 +248,9 @@ func (hc *HealthCheck) checkBackend(backend *BackendConfig) {
 			if err := backend.LB.RemoveServer(enableURL); err != nil {
 				log.Error(err)
 			}
+			backend.lock.Lock()
 			backend.disabledURLs = append(backend.disabledURLs, enableURL)
+			backend.lock.Unlock()
 			serverUpMetricValue = 0
 		}
 		labelValues := []string{"backend", backend.name, "url", enableURL.String()}
@@ -163,6 +258,30 @@ func (hc *HealthCheck) checkBackend(backend *BackendConfig) {
 	}
 }
 
+// BackendsHealth returns the health view of the health checked backends, by backend name.
+// When a backend is used by several frontends, and therefore has several load balancers,
+// the view of the first one is returned.
+func (hc *HealthCheck) BackendsHealth() map[string]*BackendHealth {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	var keys []string
+	for key := range hc.Backends {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	health := make(map[string]*BackendHealth)
+	for _, key := range keys {
+		backend := hc.Backends[key]
+		if _, ok := health[backend.name]; !ok {
+			health[backend.name] = backend.Health()
+		}
+	}
+
+	return health
+}
+
 // GetHealthCheck returns the health check which is guaranteed to be a singleton.
 func GetHealthCheck(metrics metricsRegistry) *HealthCheck {
 	once.Do(func() {
@@ -189,6 +308,10 @@ func NewBackendConfig(options Options, backendName string) *BackendConfig {
 // checkHealth returns a nil error in case it was successful and otherwise
 // a non-nil error with a meaningful description why the health check failed.
 func checkHealth(serverURL *url.URL, backend *BackendConfig) error {
+	if backend.Mode == ModeGRPC {
+		return checkGRPCHealth(serverURL, backend)
+	}
+
 	req, err := backend.newRequest(serverURL)
 	if err != nil {
 		return fmt.Errorf("failed to create HTTP request: %s", err)
@@ -212,5 +335,33 @@ func checkHealth(serverURL *url.URL, backend *BackendConfig) error {
 		return fmt.Errorf("received error status code: %v", resp.StatusCode)
 	}
 
+	return backend.checkResponse(resp)
+}
+
+// checkResponse checks the expected headers and body of the health check response.
+func (b *BackendConfig) checkResponse(resp *http.Response) error {
+	for name, expected := range b.ExpectedHeaders {
+		if value := resp.Header.Get(name); value != expected {
+			return fmt.Errorf("received header %s %q, expected %q", name, value, expected)
+		}
+	}
+
+	if len(b.ExpectedBody) == 0 && b.ExpectedBodyRegex == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read the response body: %s", err)
+	}
+
+	if len(b.ExpectedBody) > 0 && !bytes.Contains(body, []byte(b.ExpectedBody)) {
+		return fmt.Errorf("received body without %q", b.ExpectedBody)
+	}
+
+	if b.ExpectedBodyRegex != nil && !b.ExpectedBodyRegex.Match(body) {
+		return fmt.Errorf("received body not matching %q", b.ExpectedBodyRegex)
+	}
+
 	return nil
 }
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -298,6 +300,142 @@ func TestAddHeadersAndHost(t *testing.T) {
 	}
 }
 
+func TestCheckHealthExpectedResponse(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		options     Options
+		expectedErr bool
+	}{
+		{
+			desc:    "expected body",
+			options: Options{ExpectedBody: `"status": "up"`},
+		},
+		{
+			desc:        "unexpected body",
+			options:     Options{ExpectedBody: `"database": "up"`},
+			expectedErr: true,
+		},
+		{
+			desc:    "matching body regex",
+			options: Options{ExpectedBodyRegex: regexp.MustCompile(`"status":\s*"up"`)},
+		},
+		{
+			desc:        "not matching body regex",
+			options:     Options{ExpectedBodyRegex: regexp.MustCompile(`"database":\s*"up"`)},
+			expectedErr: true,
+		},
+		{
+			desc:        "body beyond the read limit",
+			options:     Options{ExpectedBody: "beyond the limit"},
+			expectedErr: true,
+		},
+		{
+			desc:    "expected header",
+			options: Options{ExpectedHeaders: map[string]string{"X-Health": "up"}},
+		},
+		{
+			desc:        "unexpected header",
+			options:     Options{ExpectedHeaders: map[string]string{"X-Health": "down"}},
+			expectedErr: true,
+		},
+		{
+			desc:        "missing header",
+			options:     Options{ExpectedHeaders: map[string]string{"X-Foo": "bar"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("X-Health", "up")
+				rw.Write([]byte(`{"status": "up", "database": "down"}`))
+				rw.Write([]byte(strings.Repeat(" ", maxBodyBytes)))
+				rw.Write([]byte("beyond the limit"))
+			}))
+			defer ts.Close()
+
+			test.options.Path = "/health"
+			test.options.Timeout = time.Second
+			backend := NewBackendConfig(test.options, "backendName")
+
+			err := checkHealth(testhelpers.MustParseURL(ts.URL), backend)
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckBackendRemovedServer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer ts.Close()
+
+	serverURL := testhelpers.MustParseURL(ts.URL)
+
+	lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}}
+	backend := NewBackendConfig(Options{
+		Path:     "/path",
+		Interval: healthCheckInterval,
+		Timeout:  healthCheckTimeout,
+		LB:       lb,
+		Removed: func(u *url.URL) bool {
+			return u.String() == serverURL.String()
+		},
+	}, "backendName")
+	backend.disabledURLs = []*url.URL{serverURL}
+
+	check := newHealthCheck(testhelpers.NewCollectingHealthCheckMetrics())
+	check.checkBackend(backend)
+
+	// The healthy server is not returned to the load balancer, nor checked anymore.
+	assert.Equal(t, 0, lb.numUpsertedServers)
+	assert.Empty(t, backend.disabledURLs)
+}
+
+func TestBackendsHealth(t *testing.T) {
+	rr, err := roundrobin.New(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	require.NoError(t, err)
+
+	require.NoError(t, rr.UpsertServer(testhelpers.MustParseURL("http://10.0.0.2:80"), roundrobin.Weight(3)))
+	require.NoError(t, rr.UpsertServer(testhelpers.MustParseURL("http://10.0.0.1:80"), roundrobin.Weight(1)))
+
+	backend := NewBackendConfig(Options{LB: rr}, "backend1")
+	backend.disabledURLs = []*url.URL{testhelpers.MustParseURL("http://10.0.0.3:80")}
+
+	otherLB := &testLoadBalancer{RWMutex: &sync.RWMutex{}}
+	require.NoError(t, otherLB.UpsertServer(testhelpers.MustParseURL("http://10.0.1.1:80")))
+
+	hc := newHealthCheck(nil)
+	hc.Backends = map[string]*BackendConfig{
+		"httpprovider1frontend1": backend,
+		"httpprovider1frontend2": NewBackendConfig(Options{LB: otherLB}, "backend1"),
+		"httpprovider1frontend3": NewBackendConfig(Options{LB: otherLB}, "backend2"),
+	}
+
+	expected := map[string]*BackendHealth{
+		"backend1": {
+			Servers: []ServerHealth{
+				{URL: "http://10.0.0.1:80", Weight: 1},
+				{URL: "http://10.0.0.2:80", Weight: 3},
+			},
+			DisabledServers: []string{"http://10.0.0.3:80"},
+		},
+		"backend2": {
+			Servers: []ServerHealth{
+				{URL: "http://10.0.1.1:80"},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, hc.BackendsHealth())
+}
+
 type testLoadBalancer struct {
 	// RWMutex needed due to parallel test execution: Both the system-under-test
 	// and the test assertions reference the counters.
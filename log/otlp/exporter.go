@@ -0,0 +1,218 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBatchSize     = 512
+	defaultQueueSize     = 2048
+	defaultFlushInterval = 5 * time.Second
+	defaultTimeout       = 10 * time.Second
+
+	// exporterField marks the entries logged by the exporters, so that they are not exported.
+	exporterField = "otlpExporter"
+)
+
+// Record is a log record to export.
+type Record struct {
+	Time       time.Time
+	Level      logrus.Level
+	Body       string
+	Attributes map[string]interface{}
+	// TraceID and SpanID are the hexadecimal identifiers of the trace and the span the record belongs to, if any.
+	TraceID string
+	SpanID  string
+}
+
+// Exporter sends the log records in batches to the OTLP/HTTP logs endpoint of an OpenTelemetry collector.
+// Exporting a record never blocks: the records are dropped when the queue is full,
+// and the batches are dropped when the collector cannot be reached.
+type Exporter struct {
+	name          string
+	endpoint      string
+	headers       map[string]string
+	serviceName   string
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+
+	queue   chan Record
+	dropped uint64
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewExporter creates a new Exporter, the name is the name of the instrumentation scope of the exported records.
+func NewExporter(name string, config *types.OTLPLogs) (*Exporter, error) {
+	if len(config.Endpoint) == 0 {
+		return nil, errors.New("the OTLP endpoint is required")
+	}
+	endpoint, err := url.ParseRequestURI(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %v", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: the scheme must be http or https", config.Endpoint)
+	}
+	if config.BatchSize < 0 || config.QueueSize < 0 || config.FlushInterval < 0 || config.Timeout < 0 {
+		return nil, errors.New("the OTLP batch size, queue size, flush interval and timeout must be positive")
+	}
+
+	serviceName := config.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = "traefik"
+	}
+
+	batchSize := config.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+
+	queueSize := config.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
+	}
+
+	flushInterval := time.Duration(config.FlushInterval)
+	if flushInterval == 0 {
+		flushInterval = defaultFlushInterval
+	}
+
+	timeout := time.Duration(config.Timeout)
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	exporter := &Exporter{
+		name:          name,
+		endpoint:      config.Endpoint,
+		headers:       config.Headers,
+		serviceName:   serviceName,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		client:        &http.Client{Timeout: timeout},
+		queue:         make(chan Record, queueSize),
+		done:          make(chan struct{}),
+	}
+
+	exporter.wg.Add(1)
+	go exporter.run()
+
+	return exporter, nil
+}
+
+// Export queues a record to export, or drops it when the queue is full.
+func (e *Exporter) Export(record Record) {
+	select {
+	case e.queue <- record:
+	default:
+		atomic.AddUint64(&e.dropped, 1)
+	}
+}
+
+// Close exports the queued records, and stops the exporter.
+func (e *Exporter) Close() {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+	e.wg.Wait()
+}
+
+func (e *Exporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, e.batchSize)
+	for {
+		select {
+		case record := <-e.queue:
+			batch = append(batch, record)
+			if len(batch) < e.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-e.done:
+			for {
+				select {
+				case record := <-e.queue:
+					batch = append(batch, record)
+					if len(batch) == e.batchSize {
+						e.flush(batch)
+						batch = batch[:0]
+					}
+					continue
+				default:
+				}
+				e.flush(batch)
+				return
+			}
+		}
+
+		e.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (e *Exporter) flush(batch []Record) {
+	if dropped := atomic.SwapUint64(&e.dropped, 0); dropped > 0 {
+		log.WithField(exporterField, e.name).Warnf("OTLP %s exporter queue full: %d log records dropped", e.name, dropped)
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := e.send(batch); err != nil {
+		log.WithField(exporterField, e.name).Errorf("Error exporting %d log records to %s: %v", len(batch), e.endpoint, err)
+	}
+}
+
+func (e *Exporter) send(batch []Record) error {
+	body, err := json.Marshal(e.newLogsData(batch))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The body is drained so that the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
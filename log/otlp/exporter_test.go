@@ -0,0 +1,259 @@
+package otlp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collector records the export requests it receives.
+type collector struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests []*http.Request
+	batches  []logsData
+}
+
+func newCollector(t *testing.T, status int) *collector {
+	t.Helper()
+
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var data logsData
+		if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.lock.Lock()
+		c.requests = append(c.requests, req)
+		c.batches = append(c.batches, data)
+		c.lock.Unlock()
+
+		rw.WriteHeader(status)
+	}))
+
+	return c
+}
+
+func (c *collector) received() ([]*http.Request, []logsData) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*http.Request(nil), c.requests...), append([]logsData(nil), c.batches...)
+}
+
+func TestExporter(t *testing.T) {
+	c := newCollector(t, http.StatusOK)
+	defer c.Close()
+
+	exporter, err := NewExporter("traefik.test", &types.OTLPLogs{
+		Endpoint:      c.URL + "/v1/logs",
+		Headers:       types.OTLPHeaders{"Authorization": "Bearer token"},
+		ServiceName:   "edge",
+		BatchSize:     2,
+		FlushInterval: parse.Duration(time.Hour),
+	})
+	require.NoError(t, err)
+
+	now := time.Unix(1, 5)
+	exporter.Export(Record{
+		Time:  now,
+		Level: logrus.WarnLevel,
+		Body:  "first",
+		Attributes: map[string]interface{}{
+			"string":   "value",
+			"int":      42,
+			"duration": time.Millisecond,
+			"float":    0.5,
+			"bool":     true,
+			"error":    errors.New("boom"),
+		},
+		TraceID: "0af7651916cd43dd8448eb211c80319c",
+		SpanID:  "b7ad6b7169203331",
+	})
+	exporter.Export(Record{Time: now, Level: logrus.InfoLevel, Body: "second"})
+	// The last record is exported on close.
+	exporter.Export(Record{Time: now, Level: logrus.ErrorLevel, Body: "third"})
+	exporter.Close()
+
+	requests, batches := c.received()
+	require.Len(t, requests, 2)
+
+	assert.Equal(t, "/v1/logs", requests[0].URL.Path)
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", requests[0].Header.Get("Authorization"))
+
+	require.Len(t, batches[0].ResourceLogs, 1)
+	resourceLogs := batches[0].ResourceLogs[0]
+	require.Len(t, resourceLogs.Resource.Attributes, 1)
+	assert.Equal(t, "service.name", resourceLogs.Resource.Attributes[0].Key)
+	assert.Equal(t, "edge", *resourceLogs.Resource.Attributes[0].Value.StringValue)
+
+	require.Len(t, resourceLogs.ScopeLogs, 1)
+	assert.Equal(t, "traefik.test", resourceLogs.ScopeLogs[0].Scope.Name)
+
+	records := resourceLogs.ScopeLogs[0].LogRecords
+	require.Len(t, records, 2)
+
+	record := records[0]
+	assert.Equal(t, "1000000005", record.TimeUnixNano)
+	assert.Equal(t, 13, record.SeverityNumber)
+	assert.Equal(t, "WARNING", record.SeverityText)
+	assert.Equal(t, "first", *record.Body.StringValue)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", record.TraceID)
+	assert.Equal(t, "b7ad6b7169203331", record.SpanID)
+
+	attributes := map[string]anyValue{}
+	for _, attribute := range record.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	assert.Equal(t, "value", *attributes["string"].StringValue)
+	assert.Equal(t, "42", *attributes["int"].IntValue)
+	assert.Equal(t, "1000000", *attributes["duration"].IntValue)
+	assert.Equal(t, 0.5, *attributes["float"].DoubleValue)
+	assert.True(t, *attributes["bool"].BoolValue)
+	assert.Equal(t, "boom", *attributes["error"].StringValue)
+
+	assert.Equal(t, "second", *records[1].Body.StringValue)
+	assert.Empty(t, records[1].TraceID)
+
+	require.Len(t, batches[1].ResourceLogs[0].ScopeLogs[0].LogRecords, 1)
+	assert.Equal(t, "third", *batches[1].ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.StringValue)
+}
+
+func TestExporterFlushInterval(t *testing.T) {
+	c := newCollector(t, http.StatusOK)
+	defer c.Close()
+
+	exporter, err := NewExporter("traefik.test", &types.OTLPLogs{
+		Endpoint:      c.URL,
+		FlushInterval: parse.Duration(10 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	defer exporter.Close()
+
+	exporter.Export(Record{Time: time.Now(), Level: logrus.InfoLevel, Body: "message"})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		requests, _ := c.received()
+		if len(requests) == 1 {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "the records must be exported at the flush interval")
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExporterDoesNotBlock(t *testing.T) {
+	var lock sync.Mutex
+	lock.Lock()
+
+	// The collector doesn't answer until the end of the test.
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	exporter, err := NewExporter("traefik.test", &types.OTLPLogs{
+		Endpoint:      server.URL,
+		BatchSize:     1,
+		QueueSize:     1,
+		FlushInterval: parse.Duration(time.Hour),
+	})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			exporter.Export(Record{Time: time.Now(), Level: logrus.InfoLevel, Body: "message"})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the export blocked")
+	}
+
+	lock.Unlock()
+	exporter.Close()
+}
+
+func TestNewExporterFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.OTLPLogs
+	}{
+		{
+			desc:   "no endpoint",
+			config: &types.OTLPLogs{},
+		},
+		{
+			desc:   "invalid endpoint",
+			config: &types.OTLPLogs{Endpoint: "collector:4318"},
+		},
+		{
+			desc:   "negative batch size",
+			config: &types.OTLPLogs{Endpoint: "http://collector:4318/v1/logs", BatchSize: -1},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewExporter("traefik.test", test.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHook(t *testing.T) {
+	c := newCollector(t, http.StatusOK)
+	defer c.Close()
+
+	exporter, err := NewExporter("traefik", &types.OTLPLogs{Endpoint: c.URL, FlushInterval: parse.Duration(time.Hour)})
+	require.NoError(t, err)
+
+	logger := logrus.New()
+	logger.Out = &nopWriter{}
+	logger.AddHook(NewHook(exporter))
+
+	logger.WithField("providerName", "docker").Info("Configuration received")
+	// The entries of the exporters are not exported.
+	logger.WithField(exporterField, "traefik").Error("Error exporting")
+	exporter.Close()
+
+	_, batches := c.received()
+	require.Len(t, batches, 1)
+
+	records := batches[0].ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 1)
+	assert.Equal(t, "Configuration received", *records[0].Body.StringValue)
+	assert.Equal(t, 9, records[0].SeverityNumber)
+	require.Len(t, records[0].Attributes, 1)
+	assert.Equal(t, "providerName", records[0].Attributes[0].Key)
+	assert.Equal(t, "docker", *records[0].Attributes[0].Value.StringValue)
+}
+
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
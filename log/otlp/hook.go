@@ -0,0 +1,44 @@
+package otlp
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus hook exporting the log entries.
+type Hook struct {
+	exporter *Exporter
+}
+
+// NewHook creates a new Hook.
+func NewHook(exporter *Exporter) *Hook {
+	return &Hook{exporter: exporter}
+}
+
+// Levels returns all the levels, the level of the entries is filtered by the logger.
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire exports an entry, except the ones logged by the exporters.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[exporterField]; ok {
+		return nil
+	}
+
+	var attributes map[string]interface{}
+	if len(entry.Data) > 0 {
+		attributes = make(map[string]interface{}, len(entry.Data))
+		for key, value := range entry.Data {
+			attributes[key] = value
+		}
+	}
+
+	h.exporter.Export(Record{
+		Time:       entry.Time,
+		Level:      entry.Level,
+		Body:       entry.Message,
+		Attributes: attributes,
+	})
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package otlp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The JSON encoding of the OTLP logs data, see https://github.com/open-telemetry/opentelemetry-proto.
+
+type logsData struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *Exporter) newLogsData(batch []Record) logsData {
+	observed := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	records := make([]logRecord, 0, len(batch))
+	for _, record := range batch {
+		records = append(records, logRecord{
+			TimeUnixNano:         strconv.FormatInt(record.Time.UnixNano(), 10),
+			ObservedTimeUnixNano: observed,
+			SeverityNumber:       severityNumber(record.Level),
+			SeverityText:         strings.ToUpper(record.Level.String()),
+			Body:                 stringValue(record.Body),
+			Attributes:           newAttributes(record.Attributes),
+			TraceID:              record.TraceID,
+			SpanID:               record.SpanID,
+		})
+	}
+
+	return logsData{
+		ResourceLogs: []resourceLogs{{
+			Resource: resource{
+				Attributes: []keyValue{{Key: "service.name", Value: stringValue(e.serviceName)}},
+			},
+			ScopeLogs: []scopeLogs{{
+				Scope:      scope{Name: e.name},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+// severityNumber returns the OpenTelemetry severity number of a level.
+func severityNumber(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 21
+	case logrus.ErrorLevel:
+		return 17
+	case logrus.WarnLevel:
+		return 13
+	case logrus.InfoLevel:
+		return 9
+	default:
+		return 5
+	}
+}
+
+func newAttributes(fields map[string]interface{}) []keyValue {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	attributes := make([]keyValue, 0, len(fields))
+	for key, value := range fields {
+		attributes = append(attributes, keyValue{Key: key, Value: newAnyValue(value)})
+	}
+
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].Key < attributes[j].Key
+	})
+
+	return attributes
+}
+
+func newAnyValue(value interface{}) anyValue {
+	switch v := value.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case uint64:
+		return intValue(int64(v))
+	case float32:
+		f := float64(v)
+		return anyValue{DoubleValue: &f}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case time.Duration:
+		return intValue(int64(v))
+	case time.Time:
+		return stringValue(v.Format(time.RFC3339Nano))
+	case error:
+		return stringValue(v.Error())
+	default:
+		return stringValue(fmt.Sprint(v))
+	}
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+func intValue(i int64) anyValue {
+	s := strconv.FormatInt(i, 10)
+	return anyValue{IntValue: &s}
+}
@@ -1,36 +1,70 @@
 package metrics
 
 import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics"
 	"github.com/go-kit/kit/metrics/dogstatsd"
+	"github.com/go-kit/kit/util/conn"
 )
 
-var datadogClient = dogstatsd.New("traefik.", kitlog.LoggerFunc(func(keyvals ...interface{}) error {
+var datadogLogger = kitlog.LoggerFunc(func(keyvals ...interface{}) error {
 	log.Info(keyvals)
 	return nil
-}))
+})
+
+var datadogClient = dogstatsd.New("traefik.", datadogLogger)
+
+// datadogCertificatesExpiry holds the expiry of the served certificates, written after the metrics of the client:
+// the client writes the last value of its gauges until the process ends,
+// whereas the expiry of the certificates no longer served is removed when the certificates are updated.
+var datadogCertificatesExpiry = newDatadogGauge("traefik.", ddEntrypointTLSCertificateExpiryName)
 
 var datadogTicker *time.Ticker
 
 // Metric names consistent with https://github.com/DataDog/integrations-extras/pull/64
 const (
-	ddMetricsBackendReqsName      = "backend.request.total"
-	ddMetricsBackendLatencyName   = "backend.request.duration"
-	ddRetriesTotalName            = "backend.retries.total"
-	ddConfigReloadsName           = "config.reload.total"
-	ddConfigReloadsFailureTagName = "failure"
-	ddLastConfigReloadSuccessName = "config.reload.lastSuccessTimestamp"
-	ddLastConfigReloadFailureName = "config.reload.lastFailureTimestamp"
-	ddEntrypointReqsName          = "entrypoint.request.total"
-	ddEntrypointReqDurationName   = "entrypoint.request.duration"
-	ddEntrypointOpenConnsName     = "entrypoint.connections.open"
-	ddOpenConnsName               = "backend.connections.open"
-	ddServerUpName                = "backend.server.up"
+	ddMetricsBackendReqsName             = "backend.request.total"
+	ddMetricsBackendLatencyName          = "backend.request.duration"
+	ddRetriesTotalName                   = "backend.retries.total"
+	ddConfigReloadsName                  = "config.reload.total"
+	ddConfigReloadsFailureTagName        = "failure"
+	ddLastConfigReloadSuccessName        = "config.reload.lastSuccessTimestamp"
+	ddLastConfigReloadFailureName        = "config.reload.lastFailureTimestamp"
+	ddEntrypointReqsName                 = "entrypoint.request.total"
+	ddEntrypointReqDurationName          = "entrypoint.request.duration"
+	ddEntrypointOpenConnsName            = "entrypoint.connections.open"
+	ddEntrypointRejectedStreamsName      = "entrypoint.streams.rejected.total"
+	ddOpenConnsName                      = "backend.connections.open"
+	ddServerUpName                       = "backend.server.up"
+	ddConnWaitName                       = "backend.connections.wait"
+	ddConnWaitingName                    = "backend.connections.waiting"
+	ddResponseHeaderViolationsName       = "backend.response.header.violations.total"
+	ddSLOComplianceName                  = "backend.slo.compliance"
+	ddClientShareName                    = "backend.client.share"
+	ddServerEjectionsName                = "backend.server.ejections.total"
+	ddVersionReqsName                    = "backend.version.request.total"
+	ddTLSPinFailuresName                 = "backend.tls.pin.failures.total"
+	ddRequestHeaderViolationsName        = "backend.request.header.violations.total"
+	ddMirrorFailuresName                 = "backend.mirror.failures.total"
+	ddRampWeightName                     = "backend.ramp.weight"
+	ddEntrypointLimitedTLSHandshakesName = "entrypoint.tls.handshakes.limited.total"
+	ddBackendConcurrencyLimitName        = "backend.concurrency.limit"
+	ddEntrypointTCPConnsName             = "entrypoint.tcp.connections"
+	ddEntrypointMaxTCPConnsName          = "entrypoint.tcp.connections.max"
+	ddEntrypointRejectedTCPConnsName     = "entrypoint.tcp.connections.rejected.total"
+	ddEntrypointTLSHandshakesName        = "entrypoint.tls.handshakes.total"
+	ddEntrypointTLSHandshakeErrorsName   = "entrypoint.tls.handshakes.errors.total"
+	ddEntrypointTLSCertificateExpiryName = "entrypoint.tls.certificate.expiry.seconds"
 )
 
 // RegisterDatadog registers the metrics pusher if this didn't happen yet and creates a datadog Registry instance.
@@ -40,19 +74,39 @@ func RegisterDatadog(config *types.Datadog) Registry {
 	}
 
 	registry := &standardRegistry{
-		enabled:                        true,
-		configReloadsCounter:           datadogClient.NewCounter(ddConfigReloadsName, 1.0),
-		configReloadsFailureCounter:    datadogClient.NewCounter(ddConfigReloadsName, 1.0).With(ddConfigReloadsFailureTagName, "true"),
-		lastConfigReloadSuccessGauge:   datadogClient.NewGauge(ddLastConfigReloadSuccessName),
-		lastConfigReloadFailureGauge:   datadogClient.NewGauge(ddLastConfigReloadFailureName),
-		entrypointReqsCounter:          datadogClient.NewCounter(ddEntrypointReqsName, 1.0),
-		entrypointReqDurationHistogram: datadogClient.NewHistogram(ddEntrypointReqDurationName, 1.0),
-		entrypointOpenConnsGauge:       datadogClient.NewGauge(ddEntrypointOpenConnsName),
-		backendReqsCounter:             datadogClient.NewCounter(ddMetricsBackendReqsName, 1.0),
-		backendReqDurationHistogram:    datadogClient.NewHistogram(ddMetricsBackendLatencyName, 1.0),
-		backendRetriesCounter:          datadogClient.NewCounter(ddRetriesTotalName, 1.0),
-		backendOpenConnsGauge:          datadogClient.NewGauge(ddOpenConnsName),
-		backendServerUpGauge:           datadogClient.NewGauge(ddServerUpName),
+		enabled:                                true,
+		configReloadsCounter:                   datadogClient.NewCounter(ddConfigReloadsName, 1.0),
+		configReloadsFailureCounter:            datadogClient.NewCounter(ddConfigReloadsName, 1.0).With(ddConfigReloadsFailureTagName, "true"),
+		lastConfigReloadSuccessGauge:           datadogClient.NewGauge(ddLastConfigReloadSuccessName),
+		lastConfigReloadFailureGauge:           datadogClient.NewGauge(ddLastConfigReloadFailureName),
+		entrypointReqsCounter:                  datadogClient.NewCounter(ddEntrypointReqsName, 1.0),
+		entrypointReqDurationHistogram:         datadogClient.NewHistogram(ddEntrypointReqDurationName, 1.0),
+		entrypointOpenConnsGauge:               datadogClient.NewGauge(ddEntrypointOpenConnsName),
+		entrypointRejectedStreamsCounter:       datadogClient.NewCounter(ddEntrypointRejectedStreamsName, 1.0),
+		backendReqsCounter:                     datadogClient.NewCounter(ddMetricsBackendReqsName, 1.0),
+		backendReqDurationHistogram:            datadogClient.NewHistogram(ddMetricsBackendLatencyName, 1.0),
+		backendRetriesCounter:                  datadogClient.NewCounter(ddRetriesTotalName, 1.0),
+		backendOpenConnsGauge:                  datadogClient.NewGauge(ddOpenConnsName),
+		backendServerUpGauge:                   datadogClient.NewGauge(ddServerUpName),
+		backendConnWaitHistogram:               datadogClient.NewHistogram(ddConnWaitName, 1.0),
+		backendConnWaitingGauge:                datadogClient.NewGauge(ddConnWaitingName),
+		backendResponseHeaderViolationsCounter: datadogClient.NewCounter(ddResponseHeaderViolationsName, 1.0),
+		backendSLOComplianceGauge:              datadogClient.NewGauge(ddSLOComplianceName),
+		backendClientShareGauge:                datadogClient.NewGauge(ddClientShareName),
+		backendServerEjectionsCounter:          datadogClient.NewCounter(ddServerEjectionsName, 1.0),
+		backendVersionReqsCounter:              datadogClient.NewCounter(ddVersionReqsName, 1.0),
+		backendTLSPinFailuresCounter:           datadogClient.NewCounter(ddTLSPinFailuresName, 1.0),
+		backendRequestHeaderViolationsCounter:  datadogClient.NewCounter(ddRequestHeaderViolationsName, 1.0),
+		backendMirrorFailuresCounter:           datadogClient.NewCounter(ddMirrorFailuresName, 1.0),
+		backendRampWeightGauge:                 datadogClient.NewGauge(ddRampWeightName),
+		entrypointLimitedTLSHandshakesCounter:  datadogClient.NewCounter(ddEntrypointLimitedTLSHandshakesName, 1.0),
+		backendConcurrencyLimitGauge:           datadogClient.NewGauge(ddBackendConcurrencyLimitName),
+		entrypointTCPConnsGauge:                datadogClient.NewGauge(ddEntrypointTCPConnsName),
+		entrypointMaxTCPConnsGauge:             datadogClient.NewGauge(ddEntrypointMaxTCPConnsName),
+		entrypointRejectedTCPConnsCounter:      datadogClient.NewCounter(ddEntrypointRejectedTCPConnsName, 1.0),
+		entrypointTLSHandshakesCounter:         datadogClient.NewCounter(ddEntrypointTLSHandshakesName, 1.0),
+		entrypointTLSHandshakeErrorsCounter:    datadogClient.NewCounter(ddEntrypointTLSHandshakeErrorsName, 1.0),
+		entrypointTLSCertificateExpiryGauge:    datadogCertificatesExpiry,
 	}
 
 	return registry
@@ -72,7 +126,15 @@ func initDatadogClient(config *types.Datadog) *time.Ticker {
 	report := time.NewTicker(pushInterval)
 
 	safe.Go(func() {
-		datadogClient.SendLoop(report.C, "udp", address)
+		w := conn.NewDefaultManager("udp", address, datadogLogger)
+		for range report.C {
+			if _, err := datadogClient.WriteTo(w); err != nil {
+				datadogLogger.Log("during", "WriteTo", "err", err)
+			}
+			if _, err := datadogCertificatesExpiry.WriteTo(w); err != nil {
+				datadogLogger.Log("during", "WriteTo", "err", err)
+			}
+		}
 	})
 
 	return report
@@ -85,3 +147,106 @@ func StopDatadog() {
 	}
 	datadogTicker = nil
 }
+
+// datadogGauge is a DataDog gauge whose values, by label values, can be removed.
+type datadogGauge struct {
+	prefix      string
+	name        string
+	labelValues []string
+	values      *datadogGaugeValues
+}
+
+type datadogGaugeValues struct {
+	lock   sync.Mutex
+	values map[string]datadogGaugeValue
+}
+
+type datadogGaugeValue struct {
+	labelValues []string
+	value       float64
+}
+
+func newDatadogGauge(prefix, name string) *datadogGauge {
+	return &datadogGauge{
+		prefix: prefix,
+		name:   name,
+		values: &datadogGaugeValues{values: make(map[string]datadogGaugeValue)},
+	}
+}
+
+// With implements metrics.Gauge.
+func (g *datadogGauge) With(labelValues ...string) metrics.Gauge {
+	lvs := make([]string, 0, len(g.labelValues)+len(labelValues))
+	lvs = append(lvs, g.labelValues...)
+	lvs = append(lvs, labelValues...)
+
+	return &datadogGauge{prefix: g.prefix, name: g.name, labelValues: lvs, values: g.values}
+}
+
+// Set implements metrics.Gauge.
+func (g *datadogGauge) Set(value float64) {
+	g.values.lock.Lock()
+	defer g.values.lock.Unlock()
+
+	g.values.values[datadogTags(g.labelValues)] = datadogGaugeValue{labelValues: g.labelValues, value: value}
+}
+
+// Add implements metrics.Gauge.
+func (g *datadogGauge) Add(delta float64) {
+	g.values.lock.Lock()
+	defer g.values.lock.Unlock()
+
+	tags := datadogTags(g.labelValues)
+	g.values.values[tags] = datadogGaugeValue{labelValues: g.labelValues, value: g.values.values[tags].value + delta}
+}
+
+// removeIf removes the values whose label values match.
+func (g *datadogGauge) removeIf(match func(labels map[string]string) bool) {
+	g.values.lock.Lock()
+	defer g.values.lock.Unlock()
+
+	for tags, value := range g.values.values {
+		labels := make(map[string]string)
+		for i := 0; i+1 < len(value.labelValues); i += 2 {
+			labels[value.labelValues[i]] = value.labelValues[i+1]
+		}
+
+		if match(labels) {
+			delete(g.values.values, tags)
+		}
+	}
+}
+
+// WriteTo writes the values of the gauge, in the DogStatsD format.
+func (g *datadogGauge) WriteTo(w io.Writer) (int64, error) {
+	g.values.lock.Lock()
+	var lines []string
+	for tags, value := range g.values.values {
+		lines = append(lines, fmt.Sprintf("%s%s:%f|g%s\n", g.prefix, g.name, value.value, tags))
+	}
+	g.values.lock.Unlock()
+
+	sort.Strings(lines)
+
+	var count int64
+	for _, line := range lines {
+		n, err := io.WriteString(w, line)
+		count += int64(n)
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func datadogTags(labelValues []string) string {
+	if len(labelValues) == 0 {
+		return ""
+	}
+
+	var pairs []string
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		pairs = append(pairs, labelValues[i]+":"+labelValues[i+1])
+	}
+	return "|#" + strings.Join(pairs, ",")
+}
@@ -1,12 +1,15 @@
 package metrics
 
 import (
+	"bytes"
 	"net/http"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stvp/go-udp-testing"
 )
 
@@ -50,3 +53,20 @@ func TestDatadog(t *testing.T) {
 		datadogRegistry.BackendServerUpGauge().With("backend", "test", "url", "http://127.0.0.1", "one", "two").Set(1)
 	})
 }
+
+func TestDatadogCertificatesExpiry(t *testing.T) {
+	gauge := newDatadogGauge("traefik.", ddEntrypointTLSCertificateExpiryName)
+	gauge.With("entrypoint", "https", "domain", "example.com").Set(3600)
+	gauge.With("entrypoint", "https", "domain", "example.org").Set(7200)
+
+	// The expiry of the certificates which are not served anymore is removed.
+	gauge.removeIf(func(labels map[string]string) bool {
+		return labels["domain"] != "example.org"
+	})
+
+	buf := &bytes.Buffer{}
+	_, err := gauge.WriteTo(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "traefik.entrypoint.tls.certificate.expiry.seconds:7200.000000|g|#entrypoint:https,domain:example.org\n", buf.String())
+}
@@ -25,18 +25,38 @@ type influxDBWriter struct {
 var influxDBTicker *time.Ticker
 
 const (
-	influxDBMetricsBackendReqsName      = "traefik.backend.requests.total"
-	influxDBMetricsBackendLatencyName   = "traefik.backend.request.duration"
-	influxDBRetriesTotalName            = "traefik.backend.retries.total"
-	influxDBConfigReloadsName           = "traefik.config.reload.total"
-	influxDBConfigReloadsFailureName    = influxDBConfigReloadsName + ".failure"
-	influxDBLastConfigReloadSuccessName = "traefik.config.reload.lastSuccessTimestamp"
-	influxDBLastConfigReloadFailureName = "traefik.config.reload.lastFailureTimestamp"
-	influxDBEntrypointReqsName          = "traefik.entrypoint.requests.total"
-	influxDBEntrypointReqDurationName   = "traefik.entrypoint.request.duration"
-	influxDBEntrypointOpenConnsName     = "traefik.entrypoint.connections.open"
-	influxDBOpenConnsName               = "traefik.backend.connections.open"
-	influxDBServerUpName                = "traefik.backend.server.up"
+	influxDBMetricsBackendReqsName             = "traefik.backend.requests.total"
+	influxDBMetricsBackendLatencyName          = "traefik.backend.request.duration"
+	influxDBRetriesTotalName                   = "traefik.backend.retries.total"
+	influxDBConfigReloadsName                  = "traefik.config.reload.total"
+	influxDBConfigReloadsFailureName           = influxDBConfigReloadsName + ".failure"
+	influxDBLastConfigReloadSuccessName        = "traefik.config.reload.lastSuccessTimestamp"
+	influxDBLastConfigReloadFailureName        = "traefik.config.reload.lastFailureTimestamp"
+	influxDBEntrypointReqsName                 = "traefik.entrypoint.requests.total"
+	influxDBEntrypointReqDurationName          = "traefik.entrypoint.request.duration"
+	influxDBEntrypointOpenConnsName            = "traefik.entrypoint.connections.open"
+	influxDBEntrypointRejectedStreamsName      = "traefik.entrypoint.streams.rejected.total"
+	influxDBOpenConnsName                      = "traefik.backend.connections.open"
+	influxDBServerUpName                       = "traefik.backend.server.up"
+	influxDBConnWaitName                       = "traefik.backend.connections.wait"
+	influxDBConnWaitingName                    = "traefik.backend.connections.waiting"
+	influxDBResponseHeaderViolationsName       = "traefik.backend.response.header.violations.total"
+	influxDBSLOComplianceName                  = "traefik.backend.slo.compliance"
+	influxDBClientShareName                    = "traefik.backend.client.share"
+	influxDBServerEjectionsName                = "traefik.backend.server.ejections.total"
+	influxDBVersionReqsName                    = "traefik.backend.version.requests.total"
+	influxDBTLSPinFailuresName                 = "traefik.backend.tls.pin.failures.total"
+	influxDBRequestHeaderViolationsName        = "traefik.backend.request.header.violations.total"
+	influxDBMirrorFailuresName                 = "traefik.backend.mirror.failures.total"
+	influxDBRampWeightName                     = "traefik.backend.ramp.weight"
+	influxDBEntrypointLimitedTLSHandshakesName = "traefik.entrypoint.tls.handshakes.limited.total"
+	influxDBBackendConcurrencyLimitName        = "traefik.backend.concurrency.limit"
+	influxDBEntrypointTCPConnsName             = "traefik.entrypoint.tcp.connections"
+	influxDBEntrypointMaxTCPConnsName          = "traefik.entrypoint.tcp.connections.max"
+	influxDBEntrypointRejectedTCPConnsName     = "traefik.entrypoint.tcp.connections.rejected.total"
+	influxDBEntrypointTLSHandshakesName        = "traefik.entrypoint.tls.handshakes.total"
+	influxDBEntrypointTLSHandshakeErrorsName   = "traefik.entrypoint.tls.handshakes.errors.total"
+	influxDBEntrypointTLSCertificateExpiryName = "traefik.entrypoint.tls.certificate.expiry.seconds"
 )
 
 // RegisterInfluxDB registers the metrics pusher if this didn't happen yet and creates a InfluxDB Registry instance.
@@ -49,19 +69,39 @@ func RegisterInfluxDB(config *types.InfluxDB) Registry {
 	}
 
 	return &standardRegistry{
-		enabled:                        true,
-		configReloadsCounter:           influxDBClient.NewCounter(influxDBConfigReloadsName),
-		configReloadsFailureCounter:    influxDBClient.NewCounter(influxDBConfigReloadsFailureName),
-		lastConfigReloadSuccessGauge:   influxDBClient.NewGauge(influxDBLastConfigReloadSuccessName),
-		lastConfigReloadFailureGauge:   influxDBClient.NewGauge(influxDBLastConfigReloadFailureName),
-		entrypointReqsCounter:          influxDBClient.NewCounter(influxDBEntrypointReqsName),
-		entrypointReqDurationHistogram: influxDBClient.NewHistogram(influxDBEntrypointReqDurationName),
-		entrypointOpenConnsGauge:       influxDBClient.NewGauge(influxDBEntrypointOpenConnsName),
-		backendReqsCounter:             influxDBClient.NewCounter(influxDBMetricsBackendReqsName),
-		backendReqDurationHistogram:    influxDBClient.NewHistogram(influxDBMetricsBackendLatencyName),
-		backendRetriesCounter:          influxDBClient.NewCounter(influxDBRetriesTotalName),
-		backendOpenConnsGauge:          influxDBClient.NewGauge(influxDBOpenConnsName),
-		backendServerUpGauge:           influxDBClient.NewGauge(influxDBServerUpName),
+		enabled:                                true,
+		configReloadsCounter:                   influxDBClient.NewCounter(influxDBConfigReloadsName),
+		configReloadsFailureCounter:            influxDBClient.NewCounter(influxDBConfigReloadsFailureName),
+		lastConfigReloadSuccessGauge:           influxDBClient.NewGauge(influxDBLastConfigReloadSuccessName),
+		lastConfigReloadFailureGauge:           influxDBClient.NewGauge(influxDBLastConfigReloadFailureName),
+		entrypointReqsCounter:                  influxDBClient.NewCounter(influxDBEntrypointReqsName),
+		entrypointReqDurationHistogram:         influxDBClient.NewHistogram(influxDBEntrypointReqDurationName),
+		entrypointOpenConnsGauge:               influxDBClient.NewGauge(influxDBEntrypointOpenConnsName),
+		entrypointRejectedStreamsCounter:       influxDBClient.NewCounter(influxDBEntrypointRejectedStreamsName),
+		backendReqsCounter:                     influxDBClient.NewCounter(influxDBMetricsBackendReqsName),
+		backendReqDurationHistogram:            influxDBClient.NewHistogram(influxDBMetricsBackendLatencyName),
+		backendRetriesCounter:                  influxDBClient.NewCounter(influxDBRetriesTotalName),
+		backendOpenConnsGauge:                  influxDBClient.NewGauge(influxDBOpenConnsName),
+		backendServerUpGauge:                   influxDBClient.NewGauge(influxDBServerUpName),
+		backendConnWaitHistogram:               influxDBClient.NewHistogram(influxDBConnWaitName),
+		backendConnWaitingGauge:                influxDBClient.NewGauge(influxDBConnWaitingName),
+		backendResponseHeaderViolationsCounter: influxDBClient.NewCounter(influxDBResponseHeaderViolationsName),
+		backendSLOComplianceGauge:              influxDBClient.NewGauge(influxDBSLOComplianceName),
+		backendClientShareGauge:                influxDBClient.NewGauge(influxDBClientShareName),
+		backendServerEjectionsCounter:          influxDBClient.NewCounter(influxDBServerEjectionsName),
+		backendVersionReqsCounter:              influxDBClient.NewCounter(influxDBVersionReqsName),
+		backendTLSPinFailuresCounter:           influxDBClient.NewCounter(influxDBTLSPinFailuresName),
+		backendRequestHeaderViolationsCounter:  influxDBClient.NewCounter(influxDBRequestHeaderViolationsName),
+		backendMirrorFailuresCounter:           influxDBClient.NewCounter(influxDBMirrorFailuresName),
+		backendRampWeightGauge:                 influxDBClient.NewGauge(influxDBRampWeightName),
+		entrypointLimitedTLSHandshakesCounter:  influxDBClient.NewCounter(influxDBEntrypointLimitedTLSHandshakesName),
+		backendConcurrencyLimitGauge:           influxDBClient.NewGauge(influxDBBackendConcurrencyLimitName),
+		entrypointTCPConnsGauge:                influxDBClient.NewGauge(influxDBEntrypointTCPConnsName),
+		entrypointMaxTCPConnsGauge:             influxDBClient.NewGauge(influxDBEntrypointMaxTCPConnsName),
+		entrypointRejectedTCPConnsCounter:      influxDBClient.NewCounter(influxDBEntrypointRejectedTCPConnsName),
+		entrypointTLSHandshakesCounter:         influxDBClient.NewCounter(influxDBEntrypointTLSHandshakesName),
+		entrypointTLSHandshakeErrorsCounter:    influxDBClient.NewCounter(influxDBEntrypointTLSHandshakeErrorsName),
+		entrypointTLSCertificateExpiryGauge:    influxDBClient.NewGauge(influxDBEntrypointTLSCertificateExpiryName),
 	}
 }
 
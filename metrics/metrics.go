@@ -20,6 +20,7 @@ type Registry interface {
 	EntrypointReqsCounter() metrics.Counter
 	EntrypointReqDurationHistogram() metrics.Histogram
 	EntrypointOpenConnsGauge() metrics.Gauge
+	EntrypointRejectedStreamsCounter() metrics.Counter
 
 	// backend metrics
 	BackendReqsCounter() metrics.Counter
@@ -27,6 +28,25 @@ type Registry interface {
 	BackendOpenConnsGauge() metrics.Gauge
 	BackendRetriesCounter() metrics.Counter
 	BackendServerUpGauge() metrics.Gauge
+	BackendConnWaitHistogram() metrics.Histogram
+	BackendConnWaitingGauge() metrics.Gauge
+	BackendResponseHeaderViolationsCounter() metrics.Counter
+	BackendSLOComplianceGauge() metrics.Gauge
+	BackendClientShareGauge() metrics.Gauge
+	BackendServerEjectionsCounter() metrics.Counter
+	BackendVersionReqsCounter() metrics.Counter
+	BackendTLSPinFailuresCounter() metrics.Counter
+	BackendRequestHeaderViolationsCounter() metrics.Counter
+	BackendMirrorFailuresCounter() metrics.Counter
+	BackendRampWeightGauge() metrics.Gauge
+	EntrypointLimitedTLSHandshakesCounter() metrics.Counter
+	BackendConcurrencyLimitGauge() metrics.Gauge
+	EntrypointTCPConnsGauge() metrics.Gauge
+	EntrypointMaxTCPConnsGauge() metrics.Gauge
+	EntrypointRejectedTCPConnsCounter() metrics.Counter
+	EntrypointTLSHandshakesCounter() metrics.Counter
+	EntrypointTLSHandshakeErrorsCounter() metrics.Counter
+	EntrypointTLSCertificateExpiryGauge() metrics.Gauge
 }
 
 // NewVoidRegistry is a noop implementation of metrics.Registry.
@@ -46,11 +66,31 @@ func NewMultiRegistry(registries []Registry) Registry {
 	var entrypointReqsCounter []metrics.Counter
 	var entrypointReqDurationHistogram []metrics.Histogram
 	var entrypointOpenConnsGauge []metrics.Gauge
+	var entrypointRejectedStreamsCounter []metrics.Counter
 	var backendReqsCounter []metrics.Counter
 	var backendReqDurationHistogram []metrics.Histogram
 	var backendOpenConnsGauge []metrics.Gauge
 	var backendRetriesCounter []metrics.Counter
 	var backendServerUpGauge []metrics.Gauge
+	var backendConnWaitHistogram []metrics.Histogram
+	var backendConnWaitingGauge []metrics.Gauge
+	var backendResponseHeaderViolationsCounter []metrics.Counter
+	var backendSLOComplianceGauge []metrics.Gauge
+	var backendClientShareGauge []metrics.Gauge
+	var backendServerEjectionsCounter []metrics.Counter
+	var backendVersionReqsCounter []metrics.Counter
+	var backendTLSPinFailuresCounter []metrics.Counter
+	var backendRequestHeaderViolationsCounter []metrics.Counter
+	var backendMirrorFailuresCounter []metrics.Counter
+	var backendRampWeightGauge []metrics.Gauge
+	var entrypointLimitedTLSHandshakesCounter []metrics.Counter
+	var backendConcurrencyLimitGauge []metrics.Gauge
+	var entrypointTCPConnsGauge []metrics.Gauge
+	var entrypointMaxTCPConnsGauge []metrics.Gauge
+	var entrypointRejectedTCPConnsCounter []metrics.Counter
+	var entrypointTLSHandshakesCounter []metrics.Counter
+	var entrypointTLSHandshakeErrorsCounter []metrics.Counter
+	var entrypointTLSCertificateExpiryGauge []metrics.Gauge
 
 	for _, r := range registries {
 		if r.ConfigReloadsCounter() != nil {
@@ -74,6 +114,9 @@ func NewMultiRegistry(registries []Registry) Registry {
 		if r.EntrypointOpenConnsGauge() != nil {
 			entrypointOpenConnsGauge = append(entrypointOpenConnsGauge, r.EntrypointOpenConnsGauge())
 		}
+		if r.EntrypointRejectedStreamsCounter() != nil {
+			entrypointRejectedStreamsCounter = append(entrypointRejectedStreamsCounter, r.EntrypointRejectedStreamsCounter())
+		}
 		if r.BackendReqsCounter() != nil {
 			backendReqsCounter = append(backendReqsCounter, r.BackendReqsCounter())
 		}
@@ -89,39 +132,136 @@ func NewMultiRegistry(registries []Registry) Registry {
 		if r.BackendServerUpGauge() != nil {
 			backendServerUpGauge = append(backendServerUpGauge, r.BackendServerUpGauge())
 		}
+		if r.BackendConnWaitHistogram() != nil {
+			backendConnWaitHistogram = append(backendConnWaitHistogram, r.BackendConnWaitHistogram())
+		}
+		if r.BackendConnWaitingGauge() != nil {
+			backendConnWaitingGauge = append(backendConnWaitingGauge, r.BackendConnWaitingGauge())
+		}
+		if r.BackendResponseHeaderViolationsCounter() != nil {
+			backendResponseHeaderViolationsCounter = append(backendResponseHeaderViolationsCounter, r.BackendResponseHeaderViolationsCounter())
+		}
+		if r.BackendSLOComplianceGauge() != nil {
+			backendSLOComplianceGauge = append(backendSLOComplianceGauge, r.BackendSLOComplianceGauge())
+		}
+		if r.BackendClientShareGauge() != nil {
+			backendClientShareGauge = append(backendClientShareGauge, r.BackendClientShareGauge())
+		}
+		if r.BackendServerEjectionsCounter() != nil {
+			backendServerEjectionsCounter = append(backendServerEjectionsCounter, r.BackendServerEjectionsCounter())
+		}
+		if r.BackendVersionReqsCounter() != nil {
+			backendVersionReqsCounter = append(backendVersionReqsCounter, r.BackendVersionReqsCounter())
+		}
+		if r.BackendTLSPinFailuresCounter() != nil {
+			backendTLSPinFailuresCounter = append(backendTLSPinFailuresCounter, r.BackendTLSPinFailuresCounter())
+		}
+		if r.BackendRequestHeaderViolationsCounter() != nil {
+			backendRequestHeaderViolationsCounter = append(backendRequestHeaderViolationsCounter, r.BackendRequestHeaderViolationsCounter())
+		}
+		if r.BackendMirrorFailuresCounter() != nil {
+			backendMirrorFailuresCounter = append(backendMirrorFailuresCounter, r.BackendMirrorFailuresCounter())
+		}
+		if r.BackendRampWeightGauge() != nil {
+			backendRampWeightGauge = append(backendRampWeightGauge, r.BackendRampWeightGauge())
+		}
+		if r.EntrypointLimitedTLSHandshakesCounter() != nil {
+			entrypointLimitedTLSHandshakesCounter = append(entrypointLimitedTLSHandshakesCounter, r.EntrypointLimitedTLSHandshakesCounter())
+		}
+		if r.BackendConcurrencyLimitGauge() != nil {
+			backendConcurrencyLimitGauge = append(backendConcurrencyLimitGauge, r.BackendConcurrencyLimitGauge())
+		}
+		if r.EntrypointTCPConnsGauge() != nil {
+			entrypointTCPConnsGauge = append(entrypointTCPConnsGauge, r.EntrypointTCPConnsGauge())
+		}
+		if r.EntrypointMaxTCPConnsGauge() != nil {
+			entrypointMaxTCPConnsGauge = append(entrypointMaxTCPConnsGauge, r.EntrypointMaxTCPConnsGauge())
+		}
+		if r.EntrypointRejectedTCPConnsCounter() != nil {
+			entrypointRejectedTCPConnsCounter = append(entrypointRejectedTCPConnsCounter, r.EntrypointRejectedTCPConnsCounter())
+		}
+		if r.EntrypointTLSHandshakesCounter() != nil {
+			entrypointTLSHandshakesCounter = append(entrypointTLSHandshakesCounter, r.EntrypointTLSHandshakesCounter())
+		}
+		if r.EntrypointTLSHandshakeErrorsCounter() != nil {
+			entrypointTLSHandshakeErrorsCounter = append(entrypointTLSHandshakeErrorsCounter, r.EntrypointTLSHandshakeErrorsCounter())
+		}
+		if r.EntrypointTLSCertificateExpiryGauge() != nil {
+			entrypointTLSCertificateExpiryGauge = append(entrypointTLSCertificateExpiryGauge, r.EntrypointTLSCertificateExpiryGauge())
+		}
 	}
 
 	return &standardRegistry{
-		enabled:                        len(registries) > 0,
-		configReloadsCounter:           multi.NewCounter(configReloadsCounter...),
-		configReloadsFailureCounter:    multi.NewCounter(configReloadsFailureCounter...),
-		lastConfigReloadSuccessGauge:   multi.NewGauge(lastConfigReloadSuccessGauge...),
-		lastConfigReloadFailureGauge:   multi.NewGauge(lastConfigReloadFailureGauge...),
-		entrypointReqsCounter:          multi.NewCounter(entrypointReqsCounter...),
-		entrypointReqDurationHistogram: multi.NewHistogram(entrypointReqDurationHistogram...),
-		entrypointOpenConnsGauge:       multi.NewGauge(entrypointOpenConnsGauge...),
-		backendReqsCounter:             multi.NewCounter(backendReqsCounter...),
-		backendReqDurationHistogram:    multi.NewHistogram(backendReqDurationHistogram...),
-		backendOpenConnsGauge:          multi.NewGauge(backendOpenConnsGauge...),
-		backendRetriesCounter:          multi.NewCounter(backendRetriesCounter...),
-		backendServerUpGauge:           multi.NewGauge(backendServerUpGauge...),
+		enabled:                                len(registries) > 0,
+		configReloadsCounter:                   multi.NewCounter(configReloadsCounter...),
+		configReloadsFailureCounter:            multi.NewCounter(configReloadsFailureCounter...),
+		lastConfigReloadSuccessGauge:           multi.NewGauge(lastConfigReloadSuccessGauge...),
+		lastConfigReloadFailureGauge:           multi.NewGauge(lastConfigReloadFailureGauge...),
+		entrypointReqsCounter:                  multi.NewCounter(entrypointReqsCounter...),
+		entrypointReqDurationHistogram:         multi.NewHistogram(entrypointReqDurationHistogram...),
+		entrypointOpenConnsGauge:               multi.NewGauge(entrypointOpenConnsGauge...),
+		entrypointRejectedStreamsCounter:       multi.NewCounter(entrypointRejectedStreamsCounter...),
+		backendReqsCounter:                     multi.NewCounter(backendReqsCounter...),
+		backendReqDurationHistogram:            multi.NewHistogram(backendReqDurationHistogram...),
+		backendOpenConnsGauge:                  multi.NewGauge(backendOpenConnsGauge...),
+		backendRetriesCounter:                  multi.NewCounter(backendRetriesCounter...),
+		backendServerUpGauge:                   multi.NewGauge(backendServerUpGauge...),
+		backendConnWaitHistogram:               multi.NewHistogram(backendConnWaitHistogram...),
+		backendConnWaitingGauge:                multi.NewGauge(backendConnWaitingGauge...),
+		backendResponseHeaderViolationsCounter: multi.NewCounter(backendResponseHeaderViolationsCounter...),
+		backendSLOComplianceGauge:              multi.NewGauge(backendSLOComplianceGauge...),
+		backendClientShareGauge:                multi.NewGauge(backendClientShareGauge...),
+		backendServerEjectionsCounter:          multi.NewCounter(backendServerEjectionsCounter...),
+		backendVersionReqsCounter:              multi.NewCounter(backendVersionReqsCounter...),
+		backendTLSPinFailuresCounter:           multi.NewCounter(backendTLSPinFailuresCounter...),
+		backendRequestHeaderViolationsCounter:  multi.NewCounter(backendRequestHeaderViolationsCounter...),
+		backendMirrorFailuresCounter:           multi.NewCounter(backendMirrorFailuresCounter...),
+		backendRampWeightGauge:                 multi.NewGauge(backendRampWeightGauge...),
+		entrypointLimitedTLSHandshakesCounter:  multi.NewCounter(entrypointLimitedTLSHandshakesCounter...),
+		backendConcurrencyLimitGauge:           multi.NewGauge(backendConcurrencyLimitGauge...),
+		entrypointTCPConnsGauge:                multi.NewGauge(entrypointTCPConnsGauge...),
+		entrypointMaxTCPConnsGauge:             multi.NewGauge(entrypointMaxTCPConnsGauge...),
+		entrypointRejectedTCPConnsCounter:      multi.NewCounter(entrypointRejectedTCPConnsCounter...),
+		entrypointTLSHandshakesCounter:         multi.NewCounter(entrypointTLSHandshakesCounter...),
+		entrypointTLSHandshakeErrorsCounter:    multi.NewCounter(entrypointTLSHandshakeErrorsCounter...),
+		entrypointTLSCertificateExpiryGauge:    multi.NewGauge(entrypointTLSCertificateExpiryGauge...),
 	}
 }
 
 type standardRegistry struct {
-	enabled                        bool
-	configReloadsCounter           metrics.Counter
-	configReloadsFailureCounter    metrics.Counter
-	lastConfigReloadSuccessGauge   metrics.Gauge
-	lastConfigReloadFailureGauge   metrics.Gauge
-	entrypointReqsCounter          metrics.Counter
-	entrypointReqDurationHistogram metrics.Histogram
-	entrypointOpenConnsGauge       metrics.Gauge
-	backendReqsCounter             metrics.Counter
-	backendReqDurationHistogram    metrics.Histogram
-	backendOpenConnsGauge          metrics.Gauge
-	backendRetriesCounter          metrics.Counter
-	backendServerUpGauge           metrics.Gauge
+	enabled                                bool
+	configReloadsCounter                   metrics.Counter
+	configReloadsFailureCounter            metrics.Counter
+	lastConfigReloadSuccessGauge           metrics.Gauge
+	lastConfigReloadFailureGauge           metrics.Gauge
+	entrypointReqsCounter                  metrics.Counter
+	entrypointReqDurationHistogram         metrics.Histogram
+	entrypointOpenConnsGauge               metrics.Gauge
+	entrypointRejectedStreamsCounter       metrics.Counter
+	backendReqsCounter                     metrics.Counter
+	backendReqDurationHistogram            metrics.Histogram
+	backendOpenConnsGauge                  metrics.Gauge
+	backendRetriesCounter                  metrics.Counter
+	backendServerUpGauge                   metrics.Gauge
+	backendConnWaitHistogram               metrics.Histogram
+	backendConnWaitingGauge                metrics.Gauge
+	backendResponseHeaderViolationsCounter metrics.Counter
+	backendSLOComplianceGauge              metrics.Gauge
+	backendClientShareGauge                metrics.Gauge
+	backendServerEjectionsCounter          metrics.Counter
+	backendVersionReqsCounter              metrics.Counter
+	backendTLSPinFailuresCounter           metrics.Counter
+	backendRequestHeaderViolationsCounter  metrics.Counter
+	backendMirrorFailuresCounter           metrics.Counter
+	backendRampWeightGauge                 metrics.Gauge
+	entrypointLimitedTLSHandshakesCounter  metrics.Counter
+	backendConcurrencyLimitGauge           metrics.Gauge
+	entrypointTCPConnsGauge                metrics.Gauge
+	entrypointMaxTCPConnsGauge             metrics.Gauge
+	entrypointRejectedTCPConnsCounter      metrics.Counter
+	entrypointTLSHandshakesCounter         metrics.Counter
+	entrypointTLSHandshakeErrorsCounter    metrics.Counter
+	entrypointTLSCertificateExpiryGauge    metrics.Gauge
 }
 
 func (r *standardRegistry) IsEnabled() bool {
@@ -156,6 +296,10 @@ func (r *standardRegistry) EntrypointOpenConnsGauge() metrics.Gauge {
 	return r.entrypointOpenConnsGauge
 }
 
+func (r *standardRegistry) EntrypointRejectedStreamsCounter() metrics.Counter {
+	return r.entrypointRejectedStreamsCounter
+}
+
 func (r *standardRegistry) BackendReqsCounter() metrics.Counter {
 	return r.backendReqsCounter
 }
@@ -175,3 +319,79 @@ func (r *standardRegistry) BackendRetriesCounter() metrics.Counter {
 func (r *standardRegistry) BackendServerUpGauge() metrics.Gauge {
 	return r.backendServerUpGauge
 }
+
+func (r *standardRegistry) BackendConnWaitHistogram() metrics.Histogram {
+	return r.backendConnWaitHistogram
+}
+
+func (r *standardRegistry) BackendConnWaitingGauge() metrics.Gauge {
+	return r.backendConnWaitingGauge
+}
+
+func (r *standardRegistry) BackendResponseHeaderViolationsCounter() metrics.Counter {
+	return r.backendResponseHeaderViolationsCounter
+}
+
+func (r *standardRegistry) BackendSLOComplianceGauge() metrics.Gauge {
+	return r.backendSLOComplianceGauge
+}
+
+func (r *standardRegistry) BackendClientShareGauge() metrics.Gauge {
+	return r.backendClientShareGauge
+}
+
+func (r *standardRegistry) BackendServerEjectionsCounter() metrics.Counter {
+	return r.backendServerEjectionsCounter
+}
+
+func (r *standardRegistry) BackendVersionReqsCounter() metrics.Counter {
+	return r.backendVersionReqsCounter
+}
+
+func (r *standardRegistry) BackendTLSPinFailuresCounter() metrics.Counter {
+	return r.backendTLSPinFailuresCounter
+}
+
+func (r *standardRegistry) BackendRequestHeaderViolationsCounter() metrics.Counter {
+	return r.backendRequestHeaderViolationsCounter
+}
+
+func (r *standardRegistry) BackendMirrorFailuresCounter() metrics.Counter {
+	return r.backendMirrorFailuresCounter
+}
+
+func (r *standardRegistry) BackendRampWeightGauge() metrics.Gauge {
+	return r.backendRampWeightGauge
+}
+
+func (r *standardRegistry) EntrypointLimitedTLSHandshakesCounter() metrics.Counter {
+	return r.entrypointLimitedTLSHandshakesCounter
+}
+
+func (r *standardRegistry) BackendConcurrencyLimitGauge() metrics.Gauge {
+	return r.backendConcurrencyLimitGauge
+}
+
+func (r *standardRegistry) EntrypointTCPConnsGauge() metrics.Gauge {
+	return r.entrypointTCPConnsGauge
+}
+
+func (r *standardRegistry) EntrypointMaxTCPConnsGauge() metrics.Gauge {
+	return r.entrypointMaxTCPConnsGauge
+}
+
+func (r *standardRegistry) EntrypointRejectedTCPConnsCounter() metrics.Counter {
+	return r.entrypointRejectedTCPConnsCounter
+}
+
+func (r *standardRegistry) EntrypointTLSHandshakesCounter() metrics.Counter {
+	return r.entrypointTLSHandshakesCounter
+}
+
+func (r *standardRegistry) EntrypointTLSHandshakeErrorsCounter() metrics.Counter {
+	return r.entrypointTLSHandshakeErrorsCounter
+}
+
+func (r *standardRegistry) EntrypointTLSCertificateExpiryGauge() metrics.Gauge {
+	return r.entrypointTLSCertificateExpiryGauge
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"math"
 	"net/http"
 	"sort"
 	"strings"
@@ -27,22 +28,75 @@ const (
 	configLastReloadFailureName    = metricConfigPrefix + "last_reload_failure"
 
 	// entrypoint
-	metricEntryPointPrefix    = MetricNamePrefix + "entrypoint_"
-	entrypointReqsTotalName   = metricEntryPointPrefix + "requests_total"
-	entrypointReqDurationName = metricEntryPointPrefix + "request_duration_seconds"
-	entrypointOpenConnsName   = metricEntryPointPrefix + "open_connections"
+	metricEntryPointPrefix             = MetricNamePrefix + "entrypoint_"
+	entrypointReqsTotalName            = metricEntryPointPrefix + "requests_total"
+	entrypointReqDurationName          = metricEntryPointPrefix + "request_duration_seconds"
+	entrypointOpenConnsName            = metricEntryPointPrefix + "open_connections"
+	entrypointRejectedStreamsName      = metricEntryPointPrefix + "rejected_streams_total"
+	entrypointLimitedTLSHandshakesName = metricEntryPointPrefix + "limited_tls_handshakes_total"
+	entrypointTCPConnsName             = metricEntryPointPrefix + "tcp_connections"
+	entrypointMaxTCPConnsName          = metricEntryPointPrefix + "max_tcp_connections"
+	entrypointRejectedTCPConnsName     = metricEntryPointPrefix + "rejected_tcp_connections_total"
+	entrypointTLSHandshakesName        = metricEntryPointPrefix + "tls_handshakes_total"
+	entrypointTLSHandshakeErrorsName   = metricEntryPointPrefix + "tls_handshake_errors_total"
+	entrypointTLSCertificateExpiryName = metricEntryPointPrefix + "tls_certificate_expiry_seconds"
 
 	// backend level.
 
 	// MetricBackendPrefix prefix of all backend metric names
-	MetricBackendPrefix     = MetricNamePrefix + "backend_"
-	backendReqsTotalName    = MetricBackendPrefix + "requests_total"
-	backendReqDurationName  = MetricBackendPrefix + "request_duration_seconds"
-	backendOpenConnsName    = MetricBackendPrefix + "open_connections"
-	backendRetriesTotalName = MetricBackendPrefix + "retries_total"
-	backendServerUpName     = MetricBackendPrefix + "server_up"
+	MetricBackendPrefix                     = MetricNamePrefix + "backend_"
+	backendReqsTotalName                    = MetricBackendPrefix + "requests_total"
+	backendReqDurationName                  = MetricBackendPrefix + "request_duration_seconds"
+	backendOpenConnsName                    = MetricBackendPrefix + "open_connections"
+	backendRetriesTotalName                 = MetricBackendPrefix + "retries_total"
+	backendServerUpName                     = MetricBackendPrefix + "server_up"
+	backendConnWaitName                     = MetricBackendPrefix + "connection_wait_seconds"
+	backendConnWaitingName                  = MetricBackendPrefix + "connections_waiting"
+	backendResponseHeaderViolationsName     = MetricBackendPrefix + "response_header_violations_total"
+	backendSLOComplianceName                = MetricBackendPrefix + "slo_compliance_ratio"
+	backendClientShareName                  = MetricBackendPrefix + "client_share_ratio"
+	backendServerEjectionsTotalName         = MetricBackendPrefix + "server_ejections_total"
+	backendVersionReqsTotalName             = MetricBackendPrefix + "version_requests_total"
+	backendTLSPinFailuresTotalName          = MetricBackendPrefix + "tls_pin_failures_total"
+	backendRequestHeaderViolationsTotalName = MetricBackendPrefix + "request_header_violations_total"
+	backendMirrorFailuresTotalName          = MetricBackendPrefix + "mirror_failures_total"
+	backendRampWeightName                   = MetricBackendPrefix + "ramp_weight_ratio"
+	backendConcurrencyLimitName             = MetricBackendPrefix + "concurrency_limit"
 )
 
+// defaultDurationBuckets are the default buckets of the request duration histograms.
+var defaultDurationBuckets = []float64{0.1, 0.3, 1.2, 5.0}
+
+// connWaitBuckets are the buckets of the connection wait histogram,
+// getting a connection usually takes far less time than processing a request.
+var connWaitBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0}
+
+// durationBuckets returns the configured buckets of the request duration histograms sorted and without duplicates,
+// as Prometheus panics on the buckets which are not strictly increasing, or the default buckets when none is configured.
+func durationBuckets(buckets types.Buckets) []float64 {
+	var sorted []float64
+	for _, bucket := range buckets {
+		if !math.IsNaN(bucket) {
+			sorted = append(sorted, bucket)
+		}
+	}
+
+	if len(sorted) == 0 {
+		return defaultDurationBuckets
+	}
+
+	sort.Float64s(sorted)
+
+	result := sorted[:1]
+	for _, bucket := range sorted[1:] {
+		if bucket != result[len(result)-1] {
+			result = append(result, bucket)
+		}
+	}
+
+	return result
+}
+
 // promState holds all metric state internally and acts as the only Collector we register for Prometheus.
 //
 // This enables control to remove metrics that belong to outdated configuration.
@@ -78,10 +132,7 @@ func RegisterPrometheus(config *types.Prometheus) Registry {
 }
 
 func initStandardRegistry(config *types.Prometheus) Registry {
-	buckets := []float64{0.1, 0.3, 1.2, 5.0}
-	if config.Buckets != nil {
-		buckets = config.Buckets
-	}
+	buckets := durationBuckets(config.Buckets)
 
 	safe.Go(func() {
 		promState.ListenValueUpdates()
@@ -117,6 +168,10 @@ func initStandardRegistry(config *types.Prometheus) Registry {
 		Name: entrypointOpenConnsName,
 		Help: "How many open connections exist on an entrypoint, partitioned by method and protocol.",
 	}, []string{"method", "protocol", "entrypoint"})
+	entrypointRejectedStreams := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: entrypointRejectedStreamsName,
+		Help: "How many HTTP/2 streams were rejected on an entrypoint because a client connection exceeded its concurrent streams limit.",
+	}, []string{"entrypoint"})
 
 	backendReqs := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
 		Name: backendReqsTotalName,
@@ -139,6 +194,83 @@ func initStandardRegistry(config *types.Prometheus) Registry {
 		Name: backendServerUpName,
 		Help: "Backend server is up, described by gauge value of 0 or 1.",
 	}, []string{"backend", "url"})
+	backendConnWait := newHistogramFrom(promState.collectors, stdprometheus.HistogramOpts{
+		Name:    backendConnWaitName,
+		Help:    "How long requests waited to get a connection to a backend server.",
+		Buckets: connWaitBuckets,
+	}, []string{"backend"})
+	backendConnWaiting := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: backendConnWaitingName,
+		Help: "How many requests are waiting to get a connection to a backend server.",
+	}, []string{"backend"})
+	backendResponseHeaderViolations := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: backendResponseHeaderViolationsName,
+		Help: "How many backend responses violated a response header rule, partitioned by header.",
+	}, []string{"backend", "header"})
+	backendSLOCompliance := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: backendSLOComplianceName,
+		Help: "Ratio of the recent backend responses within the latency SLO of the circuit breaker.",
+	}, []string{"backend"})
+	backendClientShare := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: backendClientShareName,
+		Help: "Share of the capacity of a backend used by the in-flight requests of a client, partitioned by client.",
+	}, []string{"backend", "client"})
+	backendServerEjections := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: backendServerEjectionsTotalName,
+		Help: "How many times a backend server was ejected by the outlier detection.",
+	}, []string{"backend", "url"})
+	backendVersionReqs := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: backendVersionReqsTotalName,
+		Help: "How many HTTP requests processed on a backend, partitioned by backend version and status code.",
+	}, []string{"backend", "version", "code"})
+	backendTLSPinFailures := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: backendTLSPinFailuresTotalName,
+		Help: "How many TLS handshakes with a backend failed because of a public key pin mismatch.",
+	}, []string{"backend"})
+	backendRequestHeaderViolations := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: backendRequestHeaderViolationsTotalName,
+		Help: "How many requests had a malformed header, partitioned by backend.",
+	}, []string{"backend"})
+	backendMirrorFailures := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: backendMirrorFailuresTotalName,
+		Help: "How many mirrored requests failed to be published, partitioned by backend.",
+	}, []string{"backend"})
+	backendRampWeight := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: backendRampWeightName,
+		Help: "Share of the requests forwarded to a backend by the ramp of a frontend migrating to it.",
+	}, []string{"frontend", "backend"})
+	entrypointLimitedTLSHandshakes := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: entrypointLimitedTLSHandshakesName,
+		Help: "How many TLS handshakes were queued or rejected by the handshake limits, partitioned by entrypoint and result.",
+	}, []string{"entrypoint", "result"})
+	backendConcurrencyLimit := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: backendConcurrencyLimitName,
+		Help: "Current adaptive concurrency limit of the in-flight requests of a backend.",
+	}, []string{"backend"})
+	entrypointTCPConnections := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: entrypointTCPConnsName,
+		Help: "How many TCP connections are currently open on an entrypoint with a connection limit.",
+	}, []string{"entrypoint"})
+	entrypointMaxTCPConnections := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: entrypointMaxTCPConnsName,
+		Help: "Maximum number of simultaneous TCP connections of an entrypoint.",
+	}, []string{"entrypoint"})
+	entrypointRejectedTCPConnections := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: entrypointRejectedTCPConnsName,
+		Help: "How many TCP connections were closed by the connection limit of an entrypoint.",
+	}, []string{"entrypoint"})
+	entrypointTLSHandshakes := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: entrypointTLSHandshakesName,
+		Help: "How many TLS handshakes were completed, partitioned by entrypoint, TLS version and whether the session was resumed.",
+	}, []string{"entrypoint", "tls_version", "resumed"})
+	entrypointTLSHandshakeErrors := newCounterFrom(promState.collectors, stdprometheus.CounterOpts{
+		Name: entrypointTLSHandshakeErrorsName,
+		Help: "How many TLS connections were closed before completing their handshake, partitioned by entrypoint.",
+	}, []string{"entrypoint"})
+	entrypointTLSCertificateExpiry := newGaugeFrom(promState.collectors, stdprometheus.GaugeOpts{
+		Name: entrypointTLSCertificateExpiryName,
+		Help: "How many seconds remain until a certificate served by an entrypoint expires, partitioned by entrypoint and domain.",
+	}, []string{"entrypoint", "domain"})
 
 	promState.describers = []func(chan<- *stdprometheus.Desc){
 		configReloads.cv.Describe,
@@ -148,27 +280,67 @@ func initStandardRegistry(config *types.Prometheus) Registry {
 		entrypointReqs.cv.Describe,
 		entrypointReqDurations.hv.Describe,
 		entrypointOpenConns.gv.Describe,
+		entrypointRejectedStreams.cv.Describe,
 		backendReqs.cv.Describe,
 		backendReqDurations.hv.Describe,
 		backendOpenConns.gv.Describe,
 		backendRetries.cv.Describe,
 		backendServerUp.gv.Describe,
+		backendConnWait.hv.Describe,
+		backendConnWaiting.gv.Describe,
+		backendResponseHeaderViolations.cv.Describe,
+		backendSLOCompliance.gv.Describe,
+		backendClientShare.gv.Describe,
+		backendServerEjections.cv.Describe,
+		backendVersionReqs.cv.Describe,
+		backendTLSPinFailures.cv.Describe,
+		backendRequestHeaderViolations.cv.Describe,
+		backendMirrorFailures.cv.Describe,
+		backendRampWeight.gv.Describe,
+		entrypointLimitedTLSHandshakes.cv.Describe,
+		backendConcurrencyLimit.gv.Describe,
+		entrypointTCPConnections.gv.Describe,
+		entrypointMaxTCPConnections.gv.Describe,
+		entrypointRejectedTCPConnections.cv.Describe,
+		entrypointTLSHandshakes.cv.Describe,
+		entrypointTLSHandshakeErrors.cv.Describe,
+		entrypointTLSCertificateExpiry.gv.Describe,
 	}
 
 	return &standardRegistry{
-		enabled:                        true,
-		configReloadsCounter:           configReloads,
-		configReloadsFailureCounter:    configReloadsFailures,
-		lastConfigReloadSuccessGauge:   lastConfigReloadSuccess,
-		lastConfigReloadFailureGauge:   lastConfigReloadFailure,
-		entrypointReqsCounter:          entrypointReqs,
-		entrypointReqDurationHistogram: entrypointReqDurations,
-		entrypointOpenConnsGauge:       entrypointOpenConns,
-		backendReqsCounter:             backendReqs,
-		backendReqDurationHistogram:    backendReqDurations,
-		backendOpenConnsGauge:          backendOpenConns,
-		backendRetriesCounter:          backendRetries,
-		backendServerUpGauge:           backendServerUp,
+		enabled:                                true,
+		configReloadsCounter:                   configReloads,
+		configReloadsFailureCounter:            configReloadsFailures,
+		lastConfigReloadSuccessGauge:           lastConfigReloadSuccess,
+		lastConfigReloadFailureGauge:           lastConfigReloadFailure,
+		entrypointReqsCounter:                  entrypointReqs,
+		entrypointReqDurationHistogram:         entrypointReqDurations,
+		entrypointOpenConnsGauge:               entrypointOpenConns,
+		entrypointRejectedStreamsCounter:       entrypointRejectedStreams,
+		backendReqsCounter:                     backendReqs,
+		backendReqDurationHistogram:            backendReqDurations,
+		backendOpenConnsGauge:                  backendOpenConns,
+		backendRetriesCounter:                  backendRetries,
+		backendServerUpGauge:                   backendServerUp,
+		backendConnWaitHistogram:               backendConnWait,
+		backendConnWaitingGauge:                backendConnWaiting,
+		backendResponseHeaderViolationsCounter: backendResponseHeaderViolations,
+		backendSLOComplianceGauge:              backendSLOCompliance,
+		backendClientShareGauge:                backendClientShare,
+		backendServerEjectionsCounter:          backendServerEjections,
+		backendVersionReqsCounter:              backendVersionReqs,
+		backendTLSPinFailuresCounter:           backendTLSPinFailures,
+		backendRequestHeaderViolationsCounter:  backendRequestHeaderViolations,
+		backendMirrorFailuresCounter:           backendMirrorFailures,
+		backendRampWeightGauge:                 backendRampWeight,
+		entrypointLimitedTLSHandshakesCounter:  entrypointLimitedTLSHandshakes,
+		backendConcurrencyLimitGauge:           backendConcurrencyLimit,
+		entrypointTCPConnsGauge:                entrypointTCPConnections,
+		entrypointMaxTCPConnsGauge:             entrypointMaxTCPConnections,
+		entrypointRejectedTCPConnsCounter:      entrypointRejectedTCPConnections,
+		entrypointTLSHandshakesCounter:         entrypointTLSHandshakes,
+		entrypointTLSHandshakeErrorsCounter:    entrypointTLSHandshakeErrors,
+		entrypointTLSCertificateExpiryGauge:    entrypointTLSCertificateExpiry,
 	}
 }
 
@@ -207,10 +379,30 @@ func OnConfigurationUpdate(configurations types.Configurations) {
 	promState.SetDynamicConfig(dynamicConfig)
 }
 
+// OnCertificatesUpdate receives the domains of the certificates served by each entrypoint,
+// so that the expiry of the certificates which are not served anymore is removed.
+// StatsD and InfluxDB only send the gauges set since their last push, the expiry of these certificates is not set anymore.
+func OnCertificatesUpdate(certificates map[string][]string) {
+	domains := make(map[string]map[string]bool)
+	for entrypointName, entrypointDomains := range certificates {
+		domains[entrypointName] = make(map[string]bool)
+		for _, domain := range entrypointDomains {
+			domains[entrypointName][domain] = true
+		}
+	}
+
+	promState.SetCertificates(domains)
+
+	datadogCertificatesExpiry.removeIf(func(labels map[string]string) bool {
+		return !domains[labels["entrypoint"]][labels["domain"]]
+	})
+}
+
 func newPrometheusState() *prometheusState {
 	return &prometheusState{
 		collectors:    make(chan *collector),
 		dynamicConfig: newDynamicConfig(),
+		certificates:  make(map[string]map[string]bool),
 		state:         make(map[string]*collector),
 	}
 }
@@ -221,6 +413,7 @@ type prometheusState struct {
 
 	mtx           sync.Mutex
 	dynamicConfig *dynamicConfig
+	certificates  map[string]map[string]bool
 	state         map[string]*collector
 }
 
@@ -230,6 +423,12 @@ func (ps *prometheusState) SetDynamicConfig(dynamicConfig *dynamicConfig) {
 	ps.dynamicConfig = dynamicConfig
 }
 
+func (ps *prometheusState) SetCertificates(certificates map[string]map[string]bool) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	ps.certificates = certificates
+}
+
 func (ps *prometheusState) ListenValueUpdates() {
 	for collector := range ps.collectors {
 		ps.mtx.Lock()
@@ -288,6 +487,10 @@ func (ps *prometheusState) isOutdated(collector *collector) bool {
 		}
 	}
 
+	if domain, ok := labels["domain"]; ok && !ps.certificates[labels["entrypoint"]][domain] {
+		return true
+	}
+
 	return false
 }
 
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"testing"
@@ -94,6 +95,7 @@ func (ps *prometheusState) reset() {
 	ps.collectors = make(chan *collector)
 	ps.describers = []func(ch chan<- *prometheus.Desc){}
 	ps.dynamicConfig = newDynamicConfig()
+	ps.certificates = make(map[string]map[string]bool)
 	ps.state = make(map[string]*collector)
 }
 
@@ -125,6 +127,10 @@ func TestPrometheus(t *testing.T) {
 		EntrypointOpenConnsGauge().
 		With("method", http.MethodGet, "protocol", "http", "entrypoint", "http").
 		Set(1)
+	prometheusRegistry.
+		EntrypointRejectedStreamsCounter().
+		With("entrypoint", "http").
+		Add(1)
 
 	prometheusRegistry.
 		BackendReqsCounter().
@@ -146,6 +152,82 @@ func TestPrometheus(t *testing.T) {
 		BackendServerUpGauge().
 		With("backend", "backend1", "url", "http://127.0.0.10:80").
 		Set(1)
+	prometheusRegistry.
+		BackendConnWaitHistogram().
+		With("backend", "backend1").
+		Observe(0.002)
+	prometheusRegistry.
+		BackendConnWaitingGauge().
+		With("backend", "backend1").
+		Set(1)
+	prometheusRegistry.
+		BackendResponseHeaderViolationsCounter().
+		With("backend", "backend1", "header", "Content-Type").
+		Add(1)
+	prometheusRegistry.
+		BackendSLOComplianceGauge().
+		With("backend", "backend1").
+		Set(1)
+	prometheusRegistry.
+		BackendClientShareGauge().
+		With("backend", "backend1", "client", "10.0.0.1").
+		Set(1)
+	prometheusRegistry.
+		BackendServerEjectionsCounter().
+		With("backend", "backend1", "url", "http://127.0.0.10:80").
+		Add(1)
+	prometheusRegistry.
+		BackendVersionReqsCounter().
+		With("backend", "backend1", "version", "1.2.0", "code", strconv.Itoa(http.StatusOK)).
+		Add(1)
+	prometheusRegistry.
+		BackendTLSPinFailuresCounter().
+		With("backend", "backend1").
+		Add(1)
+	prometheusRegistry.
+		BackendRequestHeaderViolationsCounter().
+		With("backend", "backend1").
+		Add(1)
+	prometheusRegistry.
+		BackendMirrorFailuresCounter().
+		With("backend", "backend1").
+		Add(1)
+	prometheusRegistry.
+		BackendRampWeightGauge().
+		With("frontend", "frontend1", "backend", "backend1").
+		Set(1)
+	prometheusRegistry.
+		EntrypointLimitedTLSHandshakesCounter().
+		With("entrypoint", "https", "result", "rejected").
+		Add(1)
+	prometheusRegistry.
+		BackendConcurrencyLimitGauge().
+		With("backend", "backend1").
+		Set(1)
+	prometheusRegistry.
+		EntrypointTCPConnsGauge().
+		With("entrypoint", "http").
+		Set(1)
+	prometheusRegistry.
+		EntrypointMaxTCPConnsGauge().
+		With("entrypoint", "http").
+		Set(1)
+	prometheusRegistry.
+		EntrypointRejectedTCPConnsCounter().
+		With("entrypoint", "http").
+		Add(1)
+	prometheusRegistry.
+		EntrypointTLSHandshakesCounter().
+		With("entrypoint", "https", "tls_version", "1.2", "resumed", "false").
+		Add(1)
+	prometheusRegistry.
+		EntrypointTLSHandshakeErrorsCounter().
+		With("entrypoint", "https").
+		Add(1)
+	prometheusRegistry.
+		EntrypointTLSCertificateExpiryGauge().
+		With("entrypoint", "https", "domain", "example.com").
+		Set(1)
 
 	delayForTrackingCompletion()
 
@@ -201,6 +283,13 @@ func TestPrometheus(t *testing.T) {
 			},
 			assert: buildGaugeAssert(t, entrypointOpenConnsName, 1),
 		},
+		{
+			name: entrypointRejectedStreamsName,
+			labels: map[string]string{
+				"entrypoint": "http",
+			},
+			assert: buildCounterAssert(t, entrypointRejectedStreamsName, 1),
+		},
 		{
 			name: backendReqsTotalName,
 			labels: map[string]string{
@@ -245,6 +334,149 @@ func TestPrometheus(t *testing.T) {
 			},
 			assert: buildGaugeAssert(t, backendServerUpName, 1),
 		},
+		{
+			name: backendConnWaitName,
+			labels: map[string]string{
+				"backend": "backend1",
+			},
+			assert: buildHistogramAssert(t, backendConnWaitName, 1),
+		},
+		{
+			name: backendConnWaitingName,
+			labels: map[string]string{
+				"backend": "backend1",
+			},
+			assert: buildGaugeAssert(t, backendConnWaitingName, 1),
+		},
+		{
+			name: backendResponseHeaderViolationsName,
+			labels: map[string]string{
+				"backend": "backend1",
+				"header":  "Content-Type",
+			},
+			assert: buildCounterAssert(t, backendResponseHeaderViolationsName, 1),
+		},
+		{
+			name: backendSLOComplianceName,
+			labels: map[string]string{
+				"backend": "backend1",
+			},
+			assert: buildGaugeAssert(t, backendSLOComplianceName, 1),
+		},
+		{
+			name: backendClientShareName,
+			labels: map[string]string{
+				"backend": "backend1",
+				"client":  "10.0.0.1",
+			},
+			assert: buildGaugeAssert(t, backendClientShareName, 1),
+		},
+		{
+			name: backendServerEjectionsTotalName,
+			labels: map[string]string{
+				"backend": "backend1",
+				"url":     "http://127.0.0.10:80",
+			},
+			assert: buildCounterAssert(t, backendServerEjectionsTotalName, 1),
+		},
+		{
+			name: backendVersionReqsTotalName,
+			labels: map[string]string{
+				"backend": "backend1",
+				"version": "1.2.0",
+				"code":    "200",
+			},
+			assert: buildCounterAssert(t, backendVersionReqsTotalName, 1),
+		},
+		{
+			name: backendTLSPinFailuresTotalName,
+			labels: map[string]string{
+				"backend": "backend1",
+			},
+			assert: buildCounterAssert(t, backendTLSPinFailuresTotalName, 1),
+		},
+		{
+			name: backendRequestHeaderViolationsTotalName,
+			labels: map[string]string{
+				"backend": "backend1",
+			},
+			assert: buildCounterAssert(t, backendRequestHeaderViolationsTotalName, 1),
+		},
+		{
+			name: backendMirrorFailuresTotalName,
+			labels: map[string]string{
+				"backend": "backend1",
+			},
+			assert: buildCounterAssert(t, backendMirrorFailuresTotalName, 1),
+		},
+		{
+			name: backendRampWeightName,
+			labels: map[string]string{
+				"frontend": "frontend1",
+				"backend":  "backend1",
+			},
+			assert: buildGaugeAssert(t, backendRampWeightName, 1),
+		},
+		{
+			name: entrypointLimitedTLSHandshakesName,
+			labels: map[string]string{
+				"entrypoint": "https",
+				"result":     "rejected",
+			},
+			assert: buildCounterAssert(t, entrypointLimitedTLSHandshakesName, 1),
+		},
+		{
+			name: backendConcurrencyLimitName,
+			labels: map[string]string{
+				"backend": "backend1",
+			},
+			assert: buildGaugeAssert(t, backendConcurrencyLimitName, 1),
+		},
+		{
+			name: entrypointTCPConnsName,
+			labels: map[string]string{
+				"entrypoint": "http",
+			},
+			assert: buildGaugeAssert(t, entrypointTCPConnsName, 1),
+		},
+		{
+			name: entrypointMaxTCPConnsName,
+			labels: map[string]string{
+				"entrypoint": "http",
+			},
+			assert: buildGaugeAssert(t, entrypointMaxTCPConnsName, 1),
+		},
+		{
+			name: entrypointRejectedTCPConnsName,
+			labels: map[string]string{
+				"entrypoint": "http",
+			},
+			assert: buildCounterAssert(t, entrypointRejectedTCPConnsName, 1),
+		},
+		{
+			name: entrypointTLSHandshakesName,
+			labels: map[string]string{
+				"entrypoint":  "https",
+				"tls_version": "1.2",
+				"resumed":     "false",
+			},
+			assert: buildCounterAssert(t, entrypointTLSHandshakesName, 1),
+		},
+		{
+			name: entrypointTLSHandshakeErrorsName,
+			labels: map[string]string{
+				"entrypoint": "https",
+			},
+			assert: buildCounterAssert(t, entrypointTLSHandshakeErrorsName, 1),
+		},
+		{
+			name: entrypointTLSCertificateExpiryName,
+			labels: map[string]string{
+				"entrypoint": "https",
+				"domain":     "example.com",
+			},
+			assert: buildGaugeAssert(t, entrypointTLSCertificateExpiryName, 1),
+		},
 	}
 
 	for _, test := range tests {
@@ -265,6 +497,45 @@ func TestPrometheus(t *testing.T) {
 	}
 }
 
+func TestPrometheusBuckets(t *testing.T) {
+	// Reset state of global promState.
+	defer promState.reset()
+
+	// Unsorted and duplicated buckets would make Prometheus panic.
+	prometheusRegistry := RegisterPrometheus(&types.Prometheus{Buckets: types.Buckets{0.01, 0.005, 0.01, 0.1}})
+	defer prometheus.Unregister(promState)
+
+	labelNamesValues := []string{
+		"code", strconv.Itoa(http.StatusOK),
+		"method", http.MethodGet,
+		"protocol", "http",
+	}
+	prometheusRegistry.
+		EntrypointReqDurationHistogram().
+		With(append(labelNamesValues, "entrypoint", "http")...).
+		Observe(0.007)
+	prometheusRegistry.
+		BackendReqDurationHistogram().
+		With(append(labelNamesValues, "backend", "backend1")...).
+		Observe(0.007)
+
+	delayForTrackingCompletion()
+
+	families := mustScrape()
+	for _, name := range []string{entrypointReqDurationName, backendReqDurationName} {
+		family := findMetricFamily(name, families)
+		if !assert.NotNil(t, family, name) || !assert.Len(t, family.Metric, 1, name) {
+			continue
+		}
+
+		var upperBounds []float64
+		for _, bucket := range family.Metric[0].Histogram.Bucket {
+			upperBounds = append(upperBounds, bucket.GetUpperBound())
+		}
+		assert.Equal(t, []float64{0.005, 0.01, 0.1}, upperBounds, name)
+	}
+}
+
 func TestPrometheusMetricRemoval(t *testing.T) {
 	// Reset state of global promState.
 	defer promState.reset()
@@ -317,6 +588,39 @@ func TestPrometheusMetricRemoval(t *testing.T) {
 	assertMetricsExist(t, mustScrape(), entrypointReqsTotalName)
 }
 
+func TestPrometheusCertificateExpiryRemoval(t *testing.T) {
+	// Reset state of global promState.
+	defer promState.reset()
+
+	prometheusRegistry := RegisterPrometheus(&types.Prometheus{})
+	defer prometheus.Unregister(promState)
+
+	configurations := make(types.Configurations)
+	configurations["providerName"] = th.BuildConfiguration(
+		th.WithFrontends(
+			th.WithFrontend("backend1", th.WithEntryPoints("https")),
+		),
+	)
+	OnConfigurationUpdate(configurations)
+	OnCertificatesUpdate(map[string][]string{"https": {"example.com"}})
+
+	prometheusRegistry.
+		EntrypointTLSCertificateExpiryGauge().
+		With("entrypoint", "https", "domain", "example.com").
+		Set(3600)
+
+	delayForTrackingCompletion()
+
+	assertMetricsExist(t, mustScrape(), entrypointTLSCertificateExpiryName)
+	assertMetricsExist(t, mustScrape(), entrypointTLSCertificateExpiryName)
+
+	// The expiry of the certificates which are not served anymore is removed after the next scrape.
+	OnCertificatesUpdate(map[string][]string{"https": {"example.org"}})
+
+	assertMetricsExist(t, mustScrape(), entrypointTLSCertificateExpiryName)
+	assertMetricsAbsent(t, mustScrape(), entrypointTLSCertificateExpiryName)
+}
+
 func TestPrometheusRemovedMetricsReset(t *testing.T) {
 	// Reset state of global promState.
 	defer promState.reset()
@@ -493,3 +797,45 @@ func buildTimestampAssert(t *testing.T, metricName string) func(family *dto.Metr
 		}
 	}
 }
+
+func TestDurationBuckets(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		buckets  types.Buckets
+		expected []float64
+	}{
+		{
+			desc:     "no buckets",
+			expected: defaultDurationBuckets,
+		},
+		{
+			desc:     "empty buckets",
+			buckets:  types.Buckets{},
+			expected: defaultDurationBuckets,
+		},
+		{
+			desc:     "sorted buckets",
+			buckets:  types.Buckets{0.005, 0.01, 0.025},
+			expected: []float64{0.005, 0.01, 0.025},
+		},
+		{
+			desc:     "unsorted and duplicated buckets",
+			buckets:  types.Buckets{0.025, 0.005, 0.01, 0.005},
+			expected: []float64{0.005, 0.01, 0.025},
+		},
+		{
+			desc:     "NaN bucket",
+			buckets:  types.Buckets{math.NaN(), 0.1},
+			expected: []float64{0.1},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, durationBuckets(test.buckets))
+		})
+	}
+}
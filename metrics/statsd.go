@@ -18,18 +18,38 @@ var statsdClient = statsd.New("traefik.", kitlog.LoggerFunc(func(keyvals ...inte
 var statsdTicker *time.Ticker
 
 const (
-	statsdMetricsBackendReqsName      = "backend.request.total"
-	statsdMetricsBackendLatencyName   = "backend.request.duration"
-	statsdRetriesTotalName            = "backend.retries.total"
-	statsdConfigReloadsName           = "config.reload.total"
-	statsdConfigReloadsFailureName    = statsdConfigReloadsName + ".failure"
-	statsdLastConfigReloadSuccessName = "config.reload.lastSuccessTimestamp"
-	statsdLastConfigReloadFailureName = "config.reload.lastFailureTimestamp"
-	statsdEntrypointReqsName          = "entrypoint.request.total"
-	statsdEntrypointReqDurationName   = "entrypoint.request.duration"
-	statsdEntrypointOpenConnsName     = "entrypoint.connections.open"
-	statsdOpenConnsName               = "backend.connections.open"
-	statsdServerUpName                = "backend.server.up"
+	statsdMetricsBackendReqsName             = "backend.request.total"
+	statsdMetricsBackendLatencyName          = "backend.request.duration"
+	statsdRetriesTotalName                   = "backend.retries.total"
+	statsdConfigReloadsName                  = "config.reload.total"
+	statsdConfigReloadsFailureName           = statsdConfigReloadsName + ".failure"
+	statsdLastConfigReloadSuccessName        = "config.reload.lastSuccessTimestamp"
+	statsdLastConfigReloadFailureName        = "config.reload.lastFailureTimestamp"
+	statsdEntrypointReqsName                 = "entrypoint.request.total"
+	statsdEntrypointReqDurationName          = "entrypoint.request.duration"
+	statsdEntrypointOpenConnsName            = "entrypoint.connections.open"
+	statsdEntrypointRejectedStreamsName      = "entrypoint.streams.rejected.total"
+	statsdOpenConnsName                      = "backend.connections.open"
+	statsdServerUpName                       = "backend.server.up"
+	statsdConnWaitName                       = "backend.connections.wait"
+	statsdConnWaitingName                    = "backend.connections.waiting"
+	statsdResponseHeaderViolationsName       = "backend.response.header.violations.total"
+	statsdSLOComplianceName                  = "backend.slo.compliance"
+	statsdClientShareName                    = "backend.client.share"
+	statsdServerEjectionsName                = "backend.server.ejections.total"
+	statsdVersionReqsName                    = "backend.version.request.total"
+	statsdTLSPinFailuresName                 = "backend.tls.pin.failures.total"
+	statsdRequestHeaderViolationsName        = "backend.request.header.violations.total"
+	statsdMirrorFailuresName                 = "backend.mirror.failures.total"
+	statsdRampWeightName                     = "backend.ramp.weight"
+	statsdEntrypointLimitedTLSHandshakesName = "entrypoint.tls.handshakes.limited.total"
+	statsdBackendConcurrencyLimitName        = "backend.concurrency.limit"
+	statsdEntrypointTCPConnsName             = "entrypoint.tcp.connections"
+	statsdEntrypointMaxTCPConnsName          = "entrypoint.tcp.connections.max"
+	statsdEntrypointRejectedTCPConnsName     = "entrypoint.tcp.connections.rejected.total"
+	statsdEntrypointTLSHandshakesName        = "entrypoint.tls.handshakes.total"
+	statsdEntrypointTLSHandshakeErrorsName   = "entrypoint.tls.handshakes.errors.total"
+	statsdEntrypointTLSCertificateExpiryName = "entrypoint.tls.certificate.expiry.seconds"
 )
 
 // RegisterStatsd registers the metrics pusher if this didn't happen yet and creates a statsd Registry instance.
@@ -39,19 +59,39 @@ func RegisterStatsd(config *types.Statsd) Registry {
 	}
 
 	return &standardRegistry{
-		enabled:                        true,
-		configReloadsCounter:           statsdClient.NewCounter(statsdConfigReloadsName, 1.0),
-		configReloadsFailureCounter:    statsdClient.NewCounter(statsdConfigReloadsFailureName, 1.0),
-		lastConfigReloadSuccessGauge:   statsdClient.NewGauge(statsdLastConfigReloadSuccessName),
-		lastConfigReloadFailureGauge:   statsdClient.NewGauge(statsdLastConfigReloadFailureName),
-		entrypointReqsCounter:          statsdClient.NewCounter(statsdEntrypointReqsName, 1.0),
-		entrypointReqDurationHistogram: statsdClient.NewTiming(statsdEntrypointReqDurationName, 1.0),
-		entrypointOpenConnsGauge:       statsdClient.NewGauge(statsdEntrypointOpenConnsName),
-		backendReqsCounter:             statsdClient.NewCounter(statsdMetricsBackendReqsName, 1.0),
-		backendReqDurationHistogram:    statsdClient.NewTiming(statsdMetricsBackendLatencyName, 1.0),
-		backendRetriesCounter:          statsdClient.NewCounter(statsdRetriesTotalName, 1.0),
-		backendOpenConnsGauge:          statsdClient.NewGauge(statsdOpenConnsName),
-		backendServerUpGauge:           statsdClient.NewGauge(statsdServerUpName),
+		enabled:                                true,
+		configReloadsCounter:                   statsdClient.NewCounter(statsdConfigReloadsName, 1.0),
+		configReloadsFailureCounter:            statsdClient.NewCounter(statsdConfigReloadsFailureName, 1.0),
+		lastConfigReloadSuccessGauge:           statsdClient.NewGauge(statsdLastConfigReloadSuccessName),
+		lastConfigReloadFailureGauge:           statsdClient.NewGauge(statsdLastConfigReloadFailureName),
+		entrypointReqsCounter:                  statsdClient.NewCounter(statsdEntrypointReqsName, 1.0),
+		entrypointReqDurationHistogram:         statsdClient.NewTiming(statsdEntrypointReqDurationName, 1.0),
+		entrypointOpenConnsGauge:               statsdClient.NewGauge(statsdEntrypointOpenConnsName),
+		entrypointRejectedStreamsCounter:       statsdClient.NewCounter(statsdEntrypointRejectedStreamsName, 1.0),
+		backendReqsCounter:                     statsdClient.NewCounter(statsdMetricsBackendReqsName, 1.0),
+		backendReqDurationHistogram:            statsdClient.NewTiming(statsdMetricsBackendLatencyName, 1.0),
+		backendRetriesCounter:                  statsdClient.NewCounter(statsdRetriesTotalName, 1.0),
+		backendOpenConnsGauge:                  statsdClient.NewGauge(statsdOpenConnsName),
+		backendServerUpGauge:                   statsdClient.NewGauge(statsdServerUpName),
+		backendConnWaitHistogram:               statsdClient.NewTiming(statsdConnWaitName, 1.0),
+		backendConnWaitingGauge:                statsdClient.NewGauge(statsdConnWaitingName),
+		backendResponseHeaderViolationsCounter: statsdClient.NewCounter(statsdResponseHeaderViolationsName, 1.0),
+		backendSLOComplianceGauge:              statsdClient.NewGauge(statsdSLOComplianceName),
+		backendClientShareGauge:                statsdClient.NewGauge(statsdClientShareName),
+		backendServerEjectionsCounter:          statsdClient.NewCounter(statsdServerEjectionsName, 1.0),
+		backendVersionReqsCounter:              statsdClient.NewCounter(statsdVersionReqsName, 1.0),
+		backendTLSPinFailuresCounter:           statsdClient.NewCounter(statsdTLSPinFailuresName, 1.0),
+		backendRequestHeaderViolationsCounter:  statsdClient.NewCounter(statsdRequestHeaderViolationsName, 1.0),
+		backendMirrorFailuresCounter:           statsdClient.NewCounter(statsdMirrorFailuresName, 1.0),
+		backendRampWeightGauge:                 statsdClient.NewGauge(statsdRampWeightName),
+		entrypointLimitedTLSHandshakesCounter:  statsdClient.NewCounter(statsdEntrypointLimitedTLSHandshakesName, 1.0),
+		backendConcurrencyLimitGauge:           statsdClient.NewGauge(statsdBackendConcurrencyLimitName),
+		entrypointTCPConnsGauge:                statsdClient.NewGauge(statsdEntrypointTCPConnsName),
+		entrypointMaxTCPConnsGauge:             statsdClient.NewGauge(statsdEntrypointMaxTCPConnsName),
+		entrypointRejectedTCPConnsCounter:      statsdClient.NewCounter(statsdEntrypointRejectedTCPConnsName, 1.0),
+		entrypointTLSHandshakesCounter:         statsdClient.NewCounter(statsdEntrypointTLSHandshakesName, 1.0),
+		entrypointTLSHandshakeErrorsCounter:    statsdClient.NewCounter(statsdEntrypointTLSHandshakeErrorsName, 1.0),
+		entrypointTLSCertificateExpiryGauge:    statsdClient.NewGauge(statsdEntrypointTLSCertificateExpiryName),
 	}
 }
 
@@ -0,0 +1,159 @@
+package accesslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/sirupsen/logrus"
+)
+
+// baggageTemplateKey is the key of the members of the baggage of the request in the data of the extra fields templates.
+const baggageTemplateKey = "Baggage"
+
+// headerFieldPrefixes are the prefixes of the fields of the headers.
+var headerFieldPrefixes = []string{"request_", "origin_", "downstream_"}
+
+// fieldsMapper renames the fields of the JSON access log entries, and adds the extra fields to them.
+type fieldsMapper struct {
+	renames map[string]string
+	extra   map[string]*template.Template
+	static  map[string]string
+}
+
+// newFieldsMapper creates a new fieldsMapper, and sets the names of the default fields of the JSON formatter.
+// The renamed fields must be known, and the extra fields values which are not static are parsed as templates,
+// executed with the fields of the entries, before they are renamed.
+func newFieldsMapper(config *types.AccessLogFields, formatter *logrus.JSONFormatter) (*fieldsMapper, error) {
+	mapper := &fieldsMapper{
+		renames: make(map[string]string),
+		extra:   make(map[string]*template.Template),
+		static:  make(map[string]string),
+	}
+
+	names := make(map[string]string)
+	for field, name := range config.Rename {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("empty new name of the field %s", field)
+		}
+
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("the fields %s and %s are both renamed %s", other, field, name)
+		}
+		names[name] = field
+
+		switch field {
+		case logrus.FieldKeyTime, logrus.FieldKeyLevel, logrus.FieldKeyMsg:
+			setFieldMap(formatter, field, name)
+		default:
+			if !isKnownField(field) {
+				return nil, fmt.Errorf("unknown access log field %s", field)
+			}
+			mapper.renames[field] = name
+		}
+	}
+
+	for name := range names {
+		_, coreField := allCoreKeys[name]
+		if _, renamed := config.Rename[name]; coreField && !renamed {
+			return nil, fmt.Errorf("the field %s is renamed %s, which is the name of another field", names[name], name)
+		}
+	}
+
+	for name, value := range config.Extra {
+		if len(name) == 0 {
+			return nil, errors.New("empty name of an extra field")
+		}
+
+		if !strings.Contains(value, "{{") {
+			mapper.static[name] = value
+			continue
+		}
+
+		tmpl, err := template.New(name).Funcs(template.FuncMap{"env": os.Getenv}).Option("missingkey=zero").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of the extra field %s: %v", name, err)
+		}
+		mapper.extra[name] = tmpl
+	}
+
+	return mapper, nil
+}
+
+// setFieldMap sets the name of a default field of the JSON formatter.
+func setFieldMap(formatter *logrus.JSONFormatter, key string, name string) {
+	if formatter.FieldMap == nil {
+		formatter.FieldMap = logrus.FieldMap{}
+	}
+
+	switch key {
+	case logrus.FieldKeyTime:
+		formatter.FieldMap[logrus.FieldKeyTime] = name
+	case logrus.FieldKeyLevel:
+		formatter.FieldMap[logrus.FieldKeyLevel] = name
+	case logrus.FieldKeyMsg:
+		formatter.FieldMap[logrus.FieldKeyMsg] = name
+	}
+}
+
+// isKnownField returns true if the field is a core field, or the field of a header.
+func isKnownField(field string) bool {
+	if _, ok := allCoreKeys[field]; ok {
+		return true
+	}
+
+	for _, prefix := range headerFieldPrefixes {
+		if strings.HasPrefix(field, prefix) && len(field) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the fields renamed, with the extra fields, which don't replace the fields of the entry.
+// The templates of the extra fields are also executed with the members of the baggage of the request, as .Baggage.
+func (m *fieldsMapper) apply(fields logrus.Fields, baggage map[string]string) logrus.Fields {
+	mapped := make(logrus.Fields, len(fields)+len(m.static)+len(m.extra))
+	for field, value := range fields {
+		if name, ok := m.renames[field]; ok {
+			mapped[name] = value
+		} else {
+			mapped[field] = value
+		}
+	}
+
+	for name, value := range m.static {
+		if _, ok := mapped[name]; !ok {
+			mapped[name] = value
+		}
+	}
+
+	var data map[string]interface{}
+	if len(m.extra) > 0 {
+		data = make(map[string]interface{}, len(fields)+1)
+		for field, value := range fields {
+			data[field] = value
+		}
+		data[baggageTemplateKey] = baggage
+	}
+
+	for name, tmpl := range m.extra {
+		if _, ok := mapped[name]; ok {
+			continue
+		}
+
+		var value bytes.Buffer
+		if err := tmpl.Execute(&value, data); err != nil {
+			log.Debugf("Error executing the template of the access log extra field %s: %v", name, err)
+			continue
+		}
+		mapped[name] = value.String()
+	}
+
+	return mapped
+}
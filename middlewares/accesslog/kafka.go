@@ -0,0 +1,146 @@
+package accesslog
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/kafka"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+const (
+	defaultKafkaBufferSize    = 1024
+	defaultKafkaFlushInterval = 500 * time.Millisecond
+	kafkaDropsReportInterval  = 10 * time.Second
+)
+
+// newKafkaProducer connects a Kafka producer, it is replaced in the tests.
+var newKafkaProducer kafka.ConnectFunc = sarama.NewAsyncProducer
+
+// kafkaWriter publishes each access log line written to it as a message of a Kafka topic.
+// The lines are dropped, and counted, while the producer is not connected to the brokers or is overloaded,
+// so that the requests are never stalled by Kafka.
+type kafkaWriter struct {
+	dropped uint64
+
+	topic    string
+	brokers  []string
+	config   *sarama.Config
+	producer *kafka.Producer
+
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+func newKafkaWriter(config *types.AccessLogKafka) (*kafkaWriter, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("no Kafka brokers")
+	}
+
+	if len(config.Topic) == 0 {
+		return nil, errors.New("no Kafka topic")
+	}
+
+	if config.BufferSize < 0 || config.FlushInterval < 0 {
+		return nil, errors.New("the Kafka buffer size and flush interval must be positive")
+	}
+
+	saramaConfig := kafka.NewConfig()
+
+	saramaConfig.ChannelBufferSize = defaultKafkaBufferSize
+	if config.BufferSize > 0 {
+		saramaConfig.ChannelBufferSize = config.BufferSize
+	}
+
+	saramaConfig.Producer.Flush.Frequency = defaultKafkaFlushInterval
+	if config.FlushInterval > 0 {
+		saramaConfig.Producer.Flush.Frequency = time.Duration(config.FlushInterval)
+	}
+
+	if config.SASL != nil {
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.User = config.SASL.User
+		saramaConfig.Net.SASL.Password = config.SASL.Password
+	}
+
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.CreateTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	if err := saramaConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &kafkaWriter{
+		topic:   config.Topic,
+		brokers: config.Brokers,
+		config:  saramaConfig,
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// start connects the producer in the background, and reports the dropped lines.
+func (w *kafkaWriter) start() {
+	w.producer = kafka.NewProducer("access log", w.brokers, w.config, newKafkaProducer, func(*sarama.ProducerMessage) {
+		atomic.AddUint64(&w.dropped, 1)
+	})
+
+	w.wg.Add(1)
+	safe.Go(func() {
+		defer w.wg.Done()
+		w.reportDrops()
+	})
+}
+
+func (w *kafkaWriter) reportDrops() {
+	ticker := time.NewTicker(kafkaDropsReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			w.reportDropped()
+			return
+		case <-ticker.C:
+			w.reportDropped()
+		}
+	}
+}
+
+func (w *kafkaWriter) reportDropped() {
+	if dropped := atomic.SwapUint64(&w.dropped, 0); dropped > 0 {
+		log.Warnf("%d access log lines could not be published to the Kafka topic %s", dropped, w.topic)
+	}
+}
+
+// Write publishes the line without waiting, dropping it if the producer is not connected or is overloaded.
+func (w *kafkaWriter) Write(p []byte) (int, error) {
+	w.producer.Publish(&sarama.ProducerMessage{
+		Topic: w.topic,
+		Value: sarama.ByteEncoder(bytes.TrimSuffix(append([]byte(nil), p...), []byte("\n"))),
+	})
+
+	return len(p), nil
+}
+
+// Close publishes the buffered lines, closes the producer, and reports the dropped lines.
+func (w *kafkaWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.producer.Close()
+		close(w.stop)
+		w.wg.Wait()
+	})
+	return nil
+}
@@ -0,0 +1,178 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProducer struct {
+	input  chan *sarama.ProducerMessage
+	errors chan *sarama.ProducerError
+}
+
+func newFakeProducer(size int) *fakeProducer {
+	return &fakeProducer{
+		input:  make(chan *sarama.ProducerMessage, size),
+		errors: make(chan *sarama.ProducerError, size),
+	}
+}
+
+func (f *fakeProducer) AsyncClose()                               { close(f.errors) }
+func (f *fakeProducer) Close() error                              { f.AsyncClose(); return nil }
+func (f *fakeProducer) Input() chan<- *sarama.ProducerMessage     { return f.input }
+func (f *fakeProducer) Successes() <-chan *sarama.ProducerMessage { return nil }
+func (f *fakeProducer) Errors() <-chan *sarama.ProducerError      { return f.errors }
+
+// withKafkaProducer makes the Kafka outputs connect the producer, or fail to connect when it is nil,
+// and returns the function restoring the connection to the brokers.
+func withKafkaProducer(producer *fakeProducer) func() {
+	connect := newKafkaProducer
+
+	newKafkaProducer = func(brokers []string, config *sarama.Config) (sarama.AsyncProducer, error) {
+		if producer == nil {
+			return nil, errors.New("no brokers available")
+		}
+		return producer, nil
+	}
+
+	return func() { newKafkaProducer = connect }
+}
+
+// waitKafkaConnected waits for the Kafka output of the handler to be connected.
+func waitKafkaConnected(t *testing.T, logHandler *LogHandler) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !logHandler.kafka.producer.Connected() {
+		require.True(t, time.Now().Before(deadline), "the Kafka output must be connected")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLoggerKafka(t *testing.T) {
+	fake := newFakeProducer(10)
+	defer withKafkaProducer(fake)()
+
+	logHandler, err := NewLogHandler(&types.AccessLog{
+		Format: JSONFormat,
+		Kafka:  &types.AccessLogKafka{Brokers: []string{"broker1:9092"}, Topic: "access-logs"},
+		Fields: &types.AccessLogFields{
+			DefaultMode: types.AccessLogKeep,
+			Names:       types.FieldNames{ClientHost: types.AccessLogDrop},
+		},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, logHandler.file)
+	waitKafkaConnected(t, logHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+	logHandler.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	require.NoError(t, logHandler.Close())
+
+	require.Len(t, fake.input, 1)
+	message := <-fake.input
+	assert.Equal(t, "access-logs", message.Topic)
+
+	value, err := message.Value.Encode()
+	require.NoError(t, err)
+
+	// Each message is a line of the access log, with its fields filtered.
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(value, &line))
+	assert.Equal(t, "/foo", line[RequestPath])
+	assert.Equal(t, float64(http.StatusTeapot), line[DownstreamStatus])
+	assert.NotContains(t, line, ClientHost)
+	assert.NotEqual(t, byte('\n'), value[len(value)-1])
+}
+
+func TestLoggerKafkaDrops(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		producer *fakeProducer
+		expected uint64
+	}{
+		{
+			desc:     "not connected",
+			expected: 3,
+		},
+		{
+			desc:     "overloaded",
+			producer: newFakeProducer(1),
+			expected: 2,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			defer withKafkaProducer(test.producer)()
+
+			logHandler, err := NewLogHandler(&types.AccessLog{
+				Format: CommonFormat,
+				Kafka:  &types.AccessLogKafka{Brokers: []string{"broker1:9092"}, Topic: "access-logs"},
+			})
+			require.NoError(t, err)
+			defer logHandler.Close()
+
+			if test.producer != nil {
+				waitKafkaConnected(t, logHandler)
+			}
+
+			// The requests are never stalled.
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+				logHandler.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+					rw.WriteHeader(http.StatusOK)
+				})
+			}
+
+			assert.Equal(t, test.expected, atomic.LoadUint64(&logHandler.kafka.dropped))
+		})
+	}
+}
+
+func TestNewLogHandlerInvalidKafka(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.AccessLogKafka
+	}{
+		{
+			desc:   "no brokers",
+			config: &types.AccessLogKafka{Topic: "access-logs"},
+		},
+		{
+			desc:   "no topic",
+			config: &types.AccessLogKafka{Brokers: []string{"broker1:9092"}},
+		},
+		{
+			desc:   "negative buffer size",
+			config: &types.AccessLogKafka{Brokers: []string{"broker1:9092"}, Topic: "access-logs", BufferSize: -1},
+		},
+		{
+			desc:   "SASL without user",
+			config: &types.AccessLogKafka{Brokers: []string{"broker1:9092"}, Topic: "access-logs", SASL: &types.KafkaSASL{}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewLogHandler(&types.AccessLog{Format: CommonFormat, Kafka: test.config})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -66,6 +66,10 @@ const (
 	Overhead = "Overhead"
 	// RetryAttempts is the map key used for the amount of attempts the request was retried.
 	RetryAttempts = "RetryAttempts"
+	// RequestID is the map key used for the ID of the request, received from the client or generated.
+	RequestID = "RequestID"
+	// BackendVersion is the map key used for the version of the backend, from the configured header of its response.
+	BackendVersion = "BackendVersion"
 )
 
 // These are written out in the default case when no config is provided to specify keys of interest.
@@ -106,6 +110,8 @@ func init() {
 	allCoreKeys[StartLocal] = struct{}{}
 	allCoreKeys[Overhead] = struct{}{}
 	allCoreKeys[RetryAttempts] = struct{}{}
+	allCoreKeys[RequestID] = struct{}{}
+	allCoreKeys[BackendVersion] = struct{}{}
 }
 
 // CoreLogData holds the fields computed from the request/response.
@@ -117,4 +123,6 @@ type LogData struct {
 	Request            http.Header
 	OriginResponse     http.Header
 	DownstreamResponse http.Header
+	// Baggage holds the members of the W3C baggage of the request, by key.
+	Baggage map[string]string
 }
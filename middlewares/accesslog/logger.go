@@ -2,18 +2,26 @@ package accesslog
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/log/otlp"
+	"github.com/containous/traefik/middlewares/requestid"
+	"github.com/containous/traefik/middlewares/sampling"
+	"github.com/containous/traefik/middlewares/tracing"
 	"github.com/containous/traefik/types"
 	"github.com/sirupsen/logrus"
 )
@@ -45,13 +53,56 @@ type LogHandler struct {
 	file           *os.File
 	mu             sync.Mutex
 	httpCodeRanges types.HTTPCodeRanges
+	statusRatios   []statusSamplingRatio
+	samplingRate   float64
+	alwaysCodes    types.HTTPCodeRanges
 	logHandlerChan chan logHandlerParams
 	wg             sync.WaitGroup
+	exporter       *otlp.Exporter
+	kafka          *kafkaWriter
+	fieldsMapper   *fieldsMapper
+}
+
+// statusSamplingRatio is the sampling ratio of a status code range.
+type statusSamplingRatio struct {
+	codes [2]int
+	ratio float64
 }
 
 // NewLogHandler creates a new LogHandler
 func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
+	statusRatios, err := newStatusSamplingRatios(config.StatusSamplingRatios)
+	if err != nil {
+		return nil, err
+	}
+
+	samplingRate := config.SamplingRatio
+	var alwaysCodes types.HTTPCodeRanges
+	if config.Sampling != nil {
+		if config.SamplingRatio > 0 {
+			return nil, errors.New("the access log samplingRatio and sampling.rate options are mutually exclusive")
+		}
+		samplingRate = config.Sampling.Rate
+
+		alwaysCodes, err = types.NewHTTPCodeRanges(config.Sampling.AlwaysStatusCodes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access log sampling status codes: %v", err)
+		}
+	}
+
+	var kafka *kafkaWriter
+	if config.Kafka != nil {
+		kafka, err = newKafkaWriter(config.Kafka)
+		if err != nil {
+			return nil, fmt.Errorf("error creating access log Kafka output: %v", err)
+		}
+	}
+
+	// The access logs are written to stdout, unless they are written to a file or published to Kafka.
 	file := os.Stdout
+	if kafka != nil {
+		file = nil
+	}
 	if len(config.FilePath) > 0 {
 		f, err := openAccessLogFile(config.FilePath)
 		if err != nil {
@@ -59,21 +110,32 @@ func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
 		}
 		file = f
 	}
+
 	logHandlerChan := make(chan logHandlerParams, config.BufferingSize)
 
 	var formatter logrus.Formatter
+	var mapper *fieldsMapper
 
 	switch config.Format {
 	case CommonFormat:
+		if config.Fields != nil && (len(config.Fields.Rename) > 0 || len(config.Fields.Extra) > 0) {
+			return nil, errors.New("the access log fields can only be renamed, and extra fields added, in the json format")
+		}
 		formatter = new(CommonLogFormatter)
 	case JSONFormat:
-		formatter = new(logrus.JSONFormatter)
+		jsonFormatter := new(logrus.JSONFormatter)
+		if config.Fields != nil && (len(config.Fields.Rename) > 0 || len(config.Fields.Extra) > 0) {
+			mapper, err = newFieldsMapper(config.Fields, jsonFormatter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid access log fields: %v", err)
+			}
+		}
+		formatter = jsonFormatter
 	default:
 		return nil, fmt.Errorf("unsupported access log format: %s", config.Format)
 	}
 
 	logger := &logrus.Logger{
-		Out:       file,
 		Formatter: formatter,
 		Hooks:     make(logrus.LevelHooks),
 		Level:     logrus.InfoLevel,
@@ -83,7 +145,12 @@ func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
 		config:         config,
 		logger:         logger,
 		file:           file,
+		kafka:          kafka,
+		statusRatios:   statusRatios,
+		samplingRate:   samplingRate,
+		alwaysCodes:    alwaysCodes,
 		logHandlerChan: logHandlerChan,
+		fieldsMapper:   mapper,
 	}
 
 	if config.Filters != nil {
@@ -94,6 +161,19 @@ func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
 		}
 	}
 
+	logger.Out = logHandler.output()
+	if kafka != nil {
+		kafka.start()
+	}
+
+	if config.OTLP != nil {
+		exporter, err := otlp.NewExporter("traefik.accesslog", config.OTLP)
+		if err != nil {
+			return nil, fmt.Errorf("error creating access log OTLP exporter: %v", err)
+		}
+		logHandler.exporter = exporter
+	}
+
 	if config.BufferingSize > 0 {
 		logHandler.wg.Add(1)
 		go func() {
@@ -107,6 +187,31 @@ func NewLogHandler(config *types.AccessLog) (*LogHandler, error) {
 	return logHandler, nil
 }
 
+// newStatusSamplingRatios parses the sampling ratios per status code range,
+// sorted from the narrowest range to the widest one, so that the most specific range wins.
+func newStatusSamplingRatios(ratios types.StatusSamplingRatios) ([]statusSamplingRatio, error) {
+	var statusRatios []statusSamplingRatio
+	for block, ratio := range ratios {
+		codeRanges, err := types.NewHTTPCodeRanges([]string{block})
+		if err != nil {
+			return nil, fmt.Errorf("invalid access log sampling status code range %q: %v", block, err)
+		}
+
+		statusRatios = append(statusRatios, statusSamplingRatio{codes: codeRanges[0], ratio: ratio})
+	}
+
+	sort.Slice(statusRatios, func(i, j int) bool {
+		wi := statusRatios[i].codes[1] - statusRatios[i].codes[0]
+		wj := statusRatios[j].codes[1] - statusRatios[j].codes[0]
+		if wi != wj {
+			return wi < wj
+		}
+		return statusRatios[i].codes[0] < statusRatios[j].codes[0]
+	})
+
+	return statusRatios, nil
+}
+
 func openAccessLogFile(filePath string) (*os.File, error) {
 	dir := filepath.Dir(filePath)
 
@@ -168,6 +273,10 @@ func (l *LogHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request, next h
 	core[RequestPath] = urlCopyString
 	core[RequestProtocol] = req.Proto
 
+	if id, ok := requestid.GetID(req.Context()); ok {
+		core[RequestID] = id
+	}
+
 	core[ClientAddr] = req.RemoteAddr
 	core[ClientHost], core[ClientPort] = silentSplitHostPort(req.RemoteAddr)
 
@@ -179,9 +288,14 @@ func (l *LogHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request, next h
 
 	next.ServeHTTP(crw, reqWithDataTable)
 
+	if !l.isSampled(req, crw.Status(), time.Now().UTC().Sub(core[StartUTC].(time.Time))) {
+		return
+	}
+
 	core[ClientUsername] = formatUsernameForLog(core[ClientUsername])
 
 	logDataTable.DownstreamResponse = crw.Header()
+	logDataTable.Baggage = tracing.GetBaggage(req.Context()).Members()
 
 	if l.config.BufferingSize > 0 {
 		l.logHandlerChan <- logHandlerParams{
@@ -198,6 +312,15 @@ func (l *LogHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request, next h
 func (l *LogHandler) Close() error {
 	close(l.logHandlerChan)
 	l.wg.Wait()
+	if l.exporter != nil {
+		l.exporter.Close()
+	}
+	if l.kafka != nil {
+		l.kafka.Close()
+	}
+	if l.file == nil {
+		return nil
+	}
 	return l.file.Close()
 }
 
@@ -218,10 +341,22 @@ func (l *LogHandler) Rotate() error {
 	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	l.logger.Out = l.file
+	l.logger.Out = l.output()
 	return nil
 }
 
+// output returns the writer of the access log lines: the file, and the Kafka topic if any.
+func (l *LogHandler) output() io.Writer {
+	switch {
+	case l.kafka == nil:
+		return l.file
+	case l.file == nil:
+		return l.kafka
+	default:
+		return io.MultiWriter(l.file, l.kafka)
+	}
+}
+
 func silentSplitHostPort(value string) (host string, port string) {
 	host, port, err := net.SplitHostPort(value)
 	if err != nil {
@@ -284,12 +419,42 @@ func (l *LogHandler) logTheRoundTrip(logDataTable *LogData, crr *captureRequestR
 		l.redactHeaders(logDataTable.OriginResponse, fields, "origin_")
 		l.redactHeaders(logDataTable.DownstreamResponse, fields, "downstream_")
 
+		if l.fieldsMapper != nil {
+			fields = l.fieldsMapper.apply(fields, logDataTable.Baggage)
+		}
+
+		if l.exporter != nil {
+			l.export(logDataTable, fields)
+		}
+
 		l.mu.Lock()
 		defer l.mu.Unlock()
 		l.logger.WithFields(fields).Println()
 	}
 }
 
+// export exports the access log entry, with the trace context of the request.
+func (l *LogHandler) export(logDataTable *LogData, fields logrus.Fields) {
+	entry := &logrus.Entry{Logger: l.logger, Data: fields, Time: time.Now(), Level: logrus.InfoLevel}
+
+	line, err := l.logger.Formatter.Format(entry)
+	if err != nil {
+		log.Debugf("Error formatting the exported access log entry: %v", err)
+		return
+	}
+
+	traceContext := sampling.GetTraceContext(logDataTable.Request)
+
+	l.exporter.Export(otlp.Record{
+		Time:       entry.Time,
+		Level:      entry.Level,
+		Body:       strings.TrimSuffix(string(line), "\n"),
+		Attributes: fields,
+		TraceID:    traceContext.TraceID,
+		SpanID:     traceContext.SpanID,
+	})
+}
+
 func (l *LogHandler) redactHeaders(headers http.Header, fields logrus.Fields, prefix string) {
 	for k := range headers {
 		v := l.config.Fields.KeepHeader(k)
@@ -301,6 +466,45 @@ func (l *LogHandler) redactHeaders(headers http.Header, fields logrus.Fields, pr
 	}
 }
 
+// isSampled returns true if the request is always logged,
+// or else if it is sampled with the ratio of its status code range, or with the global sampling ratio when no range matches.
+// Unlike the global ratio, a status code range with a ratio of 0 is never logged.
+func (l *LogHandler) isSampled(req *http.Request, statusCode int, duration time.Duration) bool {
+	if l.isAlwaysSampled(req.Header, statusCode, duration) {
+		return true
+	}
+
+	for _, statusRatio := range l.statusRatios {
+		if statusCode < statusRatio.codes[0] || statusCode > statusRatio.codes[1] {
+			continue
+		}
+
+		if statusRatio.ratio <= 0 {
+			return false
+		}
+		return sampling.IsSampled(req.Context(), statusRatio.ratio)
+	}
+
+	return sampling.IsSampled(req.Context(), l.samplingRate)
+}
+
+// isAlwaysSampled returns true if the request matches the always rules of the sampling, or if it is traced.
+func (l *LogHandler) isAlwaysSampled(header http.Header, statusCode int, duration time.Duration) bool {
+	if l.config.Sampling == nil {
+		return false
+	}
+
+	if l.alwaysCodes.Contains(statusCode) {
+		return true
+	}
+
+	if l.config.Sampling.MinDuration > 0 && duration >= time.Duration(l.config.Sampling.MinDuration) {
+		return true
+	}
+
+	return sampling.GetTraceContext(header).Sampled
+}
+
 func (l *LogHandler) keepAccessLog(statusCode, retryAttempts int, duration time.Duration) bool {
 	if l.config.Filters == nil {
 		// no filters were specified
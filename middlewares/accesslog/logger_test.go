@@ -16,6 +16,9 @@ import (
 
 	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/requestid"
+	"github.com/containous/traefik/middlewares/sampling"
+	"github.com/containous/traefik/middlewares/tracing"
 	"github.com/containous/traefik/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -148,6 +151,406 @@ func TestAsyncLoggerCLF(t *testing.T) {
 	assertValidLogData(t, expectedLog, logData)
 }
 
+func TestLoggerSampling(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set("X-B3-TraceId", "4bf92f3577b34da6")
+
+	var value float64
+	sampling.NewHandler().ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		value, _ = sampling.GetValue(r.Context())
+		req = r
+	})
+	require.True(t, value > 0.01 && value < 0.99, "unexpected sampling value %f", value)
+
+	testCases := []struct {
+		desc     string
+		ratio    float64
+		expected bool
+	}{
+		{
+			desc:     "sampled",
+			ratio:    value + 0.01,
+			expected: true,
+		},
+		{
+			desc:  "not sampled",
+			ratio: value - 0.01,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			tmpDir := createTempDir(t, CommonFormat)
+			defer os.RemoveAll(tmpDir)
+
+			logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+			logger, err := NewLogHandler(&types.AccessLog{FilePath: logFilePath, Format: CommonFormat, SamplingRatio: test.ratio})
+			require.NoError(t, err)
+
+			logger.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			})
+			require.NoError(t, logger.Close())
+
+			logData, err := ioutil.ReadFile(logFilePath)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, len(logData) > 0)
+		})
+	}
+}
+
+func TestLoggerRequestID(t *testing.T) {
+	tmpDir := createTempDir(t, JSONFormat)
+	defer os.RemoveAll(tmpDir)
+
+	logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+	logger, err := NewLogHandler(&types.AccessLog{FilePath: logFilePath, Format: JSONFormat})
+	require.NoError(t, err)
+
+	requestID, err := requestid.NewHandler("", "")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set(requestid.DefaultHeaderName, "d4c3b2a1")
+
+	requestID.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		logger.ServeHTTP(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+	})
+	require.NoError(t, logger.Close())
+
+	logData, err := ioutil.ReadFile(logFilePath)
+	require.NoError(t, err)
+
+	jsonData := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(logData, &jsonData))
+
+	assert.Equal(t, "d4c3b2a1", jsonData[RequestID])
+}
+
+func TestLoggerOTLP(t *testing.T) {
+	tmpDir := createTempDir(t, JSONFormat)
+	defer os.RemoveAll(tmpDir)
+
+	var bodies [][]byte
+	collector := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bodies = append(bodies, body)
+	}))
+	defer collector.Close()
+
+	logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+	logger, err := NewLogHandler(&types.AccessLog{
+		FilePath: logFilePath,
+		Format:   JSONFormat,
+		OTLP:     &types.OTLPLogs{Endpoint: collector.URL, ServiceName: "edge"},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+	req.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	logger.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	require.NoError(t, logger.Close())
+
+	require.Len(t, bodies, 1)
+
+	var data struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value struct{ StringValue string }
+				}
+			}
+			ScopeLogs []struct {
+				LogRecords []struct {
+					Body       struct{ StringValue string }
+					Attributes []struct {
+						Key   string
+						Value struct{ IntValue string }
+					}
+					TraceID string
+					SpanID  string
+				}
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(bodies[0], &data))
+
+	require.Len(t, data.ResourceLogs, 1)
+	assert.Equal(t, "edge", data.ResourceLogs[0].Resource.Attributes[0].Value.StringValue)
+
+	records := data.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 1)
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", records[0].TraceID)
+	assert.Equal(t, "b7ad6b7169203331", records[0].SpanID)
+
+	// The body is the access log line.
+	logData, err := ioutil.ReadFile(logFilePath)
+	require.NoError(t, err)
+
+	var line, body map[string]interface{}
+	require.NoError(t, json.Unmarshal(logData, &line))
+	require.NoError(t, json.Unmarshal([]byte(records[0].Body.StringValue), &body))
+	assert.Equal(t, line[RequestPath], body[RequestPath])
+	assert.Equal(t, float64(http.StatusTeapot), body[DownstreamStatus])
+
+	var status string
+	for _, attribute := range records[0].Attributes {
+		if attribute.Key == DownstreamStatus {
+			status = attribute.Value.IntValue
+		}
+	}
+	assert.Equal(t, "418", status)
+}
+
+func TestNewLogHandlerInvalidOTLP(t *testing.T) {
+	_, err := NewLogHandler(&types.AccessLog{Format: CommonFormat, OTLP: &types.OTLPLogs{}})
+	assert.Error(t, err)
+}
+
+func TestLoggerStatusSampling(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set("X-B3-TraceId", "4bf92f3577b34da6")
+
+	var value float64
+	sampling.NewHandler().ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		value, _ = sampling.GetValue(r.Context())
+		req = r
+	})
+	require.True(t, value > 0.01 && value < 0.99, "unexpected sampling value %f", value)
+
+	ratios := types.StatusSamplingRatios{
+		"500-599": 1,
+		"400-499": 0,
+		"404":     1,
+		"200-299": value - 0.01,
+		"201":     value + 0.01,
+	}
+
+	testCases := []struct {
+		desc          string
+		statusCode    int
+		samplingRatio float64
+		expected      bool
+	}{
+		{
+			desc:       "always logged",
+			statusCode: http.StatusBadGateway,
+			expected:   true,
+		},
+		{
+			desc:       "never logged",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			desc:       "narrowest range wins",
+			statusCode: http.StatusNotFound,
+			expected:   true,
+		},
+		{
+			desc:       "not sampled",
+			statusCode: http.StatusOK,
+		},
+		{
+			desc:       "sampled",
+			statusCode: http.StatusCreated,
+			expected:   true,
+		},
+		{
+			desc:          "no matching range uses the sampling ratio",
+			statusCode:    http.StatusFound,
+			samplingRatio: value - 0.01,
+		},
+		{
+			desc:       "no matching range and no sampling ratio",
+			statusCode: http.StatusFound,
+			expected:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			tmpDir := createTempDir(t, CommonFormat)
+			defer os.RemoveAll(tmpDir)
+
+			logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+			logger, err := NewLogHandler(&types.AccessLog{
+				FilePath:             logFilePath,
+				Format:               CommonFormat,
+				SamplingRatio:        test.samplingRatio,
+				StatusSamplingRatios: ratios,
+			})
+			require.NoError(t, err)
+
+			logger.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(test.statusCode)
+			})
+			require.NoError(t, logger.Close())
+
+			logData, err := ioutil.ReadFile(logFilePath)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, len(logData) > 0)
+		})
+	}
+}
+
+func TestLoggerSamplingRules(t *testing.T) {
+	newRequest := func(headers map[string]string) (*http.Request, float64) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.Header.Set(requestid.DefaultHeaderName, "d4c3b2a1")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		requestID, err := requestid.NewHandler("", "")
+		require.NoError(t, err)
+
+		var value float64
+		requestID.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+			sampling.NewHandler().ServeHTTP(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+				value, _ = sampling.GetValue(r.Context())
+				req = r
+			})
+		})
+		return req, value
+	}
+
+	_, value := newRequest(nil)
+	require.True(t, value > 0.01 && value < 0.99, "unexpected sampling value %f", value)
+
+	testCases := []struct {
+		desc       string
+		headers    map[string]string
+		statusCode int
+		delay      time.Duration
+		rate       float64
+		expected   bool
+	}{
+		{
+			desc:       "sampled",
+			statusCode: http.StatusOK,
+			rate:       value + 0.01,
+			expected:   true,
+		},
+		{
+			desc:       "not sampled",
+			statusCode: http.StatusOK,
+			rate:       value - 0.01,
+		},
+		{
+			desc:       "always logged status code",
+			statusCode: http.StatusBadGateway,
+			rate:       value - 0.01,
+			expected:   true,
+		},
+		{
+			desc:       "always logged slow request",
+			statusCode: http.StatusOK,
+			delay:      20 * time.Millisecond,
+			rate:       value - 0.01,
+			expected:   true,
+		},
+		{
+			desc:       "always logged traced request",
+			headers:    map[string]string{"X-B3-TraceId": "4bf92f3577b34da6", "X-B3-Sampled": "1"},
+			statusCode: http.StatusOK,
+			rate:       0.000001,
+			expected:   true,
+		},
+		{
+			desc:       "no rate",
+			statusCode: http.StatusOK,
+			expected:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			tmpDir := createTempDir(t, CommonFormat)
+			defer os.RemoveAll(tmpDir)
+
+			logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+			logger, err := NewLogHandler(&types.AccessLog{
+				FilePath: logFilePath,
+				Format:   CommonFormat,
+				Sampling: &types.AccessLogSampling{
+					Rate:              test.rate,
+					AlwaysStatusCodes: types.StatusCodes{"500-599"},
+					MinDuration:       parse.Duration(10 * time.Millisecond),
+				},
+			})
+			require.NoError(t, err)
+
+			req, _ := newRequest(test.headers)
+			logger.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				time.Sleep(test.delay)
+				rw.WriteHeader(test.statusCode)
+			})
+			require.NoError(t, logger.Close())
+
+			logData, err := ioutil.ReadFile(logFilePath)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, len(logData) > 0)
+		})
+	}
+}
+
+func TestNewLogHandlerInvalidSampling(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.AccessLog
+	}{
+		{
+			desc: "samplingRatio and sampling.rate",
+			config: &types.AccessLog{
+				Format:        CommonFormat,
+				SamplingRatio: 0.1,
+				Sampling:      &types.AccessLogSampling{Rate: 0.1},
+			},
+		},
+		{
+			desc: "invalid status codes",
+			config: &types.AccessLog{
+				Format:   CommonFormat,
+				Sampling: &types.AccessLogSampling{AlwaysStatusCodes: types.StatusCodes{"5xx"}},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewLogHandler(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewLogHandlerInvalidStatusSamplingRatios(t *testing.T) {
+	_, err := NewLogHandler(&types.AccessLog{
+		Format:               CommonFormat,
+		StatusSamplingRatios: types.StatusSamplingRatios{"5xx": 1},
+	})
+	assert.Error(t, err)
+}
+
 func assertString(exp string) func(t *testing.T, actual interface{}) {
 	return func(t *testing.T, actual interface{}) {
 		t.Helper()
@@ -306,6 +709,47 @@ func TestLoggerJSON(t *testing.T) {
 				RequestRefererHeader: assertString(testReferer),
 			},
 		},
+		{
+			desc: "renamed fields and extra fields",
+			config: &types.AccessLog{
+				FilePath: "",
+				Format:   JSONFormat,
+				Fields: &types.AccessLogFields{
+					DefaultMode: "drop",
+					Names: types.FieldNames{
+						ClientHost:  "keep",
+						RequestHost: "keep",
+					},
+					Headers: &types.FieldHeaders{
+						DefaultMode: "drop",
+						Names: types.FieldHeaderNames{
+							"User-Agent": "keep",
+						},
+					},
+					Rename: types.FieldNames{
+						ClientHost:             "client.ip",
+						RequestUserAgentHeader: "user_agent.original",
+						"time":                 "@timestamp",
+						"level":                "log.level",
+					},
+					Extra: types.FieldNames{
+						"environment": "production",
+						"url.full":    "http://{{ .RequestHost }}/",
+						RequestHost:   "replaced",
+					},
+				},
+			},
+			expected: map[string]func(t *testing.T, value interface{}){
+				"client.ip":           assertString(testHostname),
+				RequestHost:           assertString(testHostname),
+				"user_agent.original": assertString(testUserAgent),
+				"environment":         assertString("production"),
+				"url.full":            assertString("http://" + testHostname + "/"),
+				"log.level":           assertString("info"),
+				"msg":                 assertString(""),
+				"@timestamp":          assertNotEqual(""),
+			},
+		},
 	}
 
 	for _, test := range testCases {
@@ -337,6 +781,99 @@ func TestLoggerJSON(t *testing.T) {
 	}
 }
 
+func TestNewLogHandlerInvalidFields(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		format string
+		fields *types.AccessLogFields
+	}{
+		{
+			desc:   "unknown field",
+			format: JSONFormat,
+			fields: &types.AccessLogFields{Rename: types.FieldNames{"ClientHots": "client.ip"}},
+		},
+		{
+			desc:   "empty new name",
+			format: JSONFormat,
+			fields: &types.AccessLogFields{Rename: types.FieldNames{ClientHost: ""}},
+		},
+		{
+			desc:   "fields renamed alike",
+			format: JSONFormat,
+			fields: &types.AccessLogFields{Rename: types.FieldNames{ClientHost: "client", ClientAddr: "client"}},
+		},
+		{
+			desc:   "field renamed as another field",
+			format: JSONFormat,
+			fields: &types.AccessLogFields{Rename: types.FieldNames{ClientHost: RequestHost}},
+		},
+		{
+			desc:   "invalid extra field template",
+			format: JSONFormat,
+			fields: &types.AccessLogFields{Extra: types.FieldNames{"url.full": "{{ .RequestHost"}},
+		},
+		{
+			desc:   "common format",
+			format: CommonFormat,
+			fields: &types.AccessLogFields{Extra: types.FieldNames{"environment": "production"}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewLogHandler(&types.AccessLog{Format: test.format, Fields: test.fields})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoggerJSONBaggage(t *testing.T) {
+	tmpDir := createTempDir(t, JSONFormat)
+	defer os.RemoveAll(tmpDir)
+
+	logFilePath := filepath.Join(tmpDir, logFileNameSuffix)
+
+	logger, err := NewLogHandler(&types.AccessLog{
+		FilePath: logFilePath,
+		Format:   JSONFormat,
+		Fields: &types.AccessLogFields{
+			DefaultMode: "drop",
+			Extra: types.FieldNames{
+				"tenant.id": "{{ .Baggage.tenant }}",
+				"user.name": `{{ index .Baggage "user" }}`,
+				"missing":   "{{ .Baggage.missing }}",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	baggage := tracing.NewBaggage()
+	baggage.Merge([]string{"tenant=acme"})
+
+	req := httptest.NewRequest(http.MethodGet, "http://"+testHostname+testPath, nil)
+	req = req.WithContext(tracing.WithBaggage(req.Context(), baggage))
+
+	logger.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		// The members added by the next middlewares are logged too.
+		tracing.GetBaggage(r.Context()).Set("user", "alice")
+		rw.WriteHeader(testStatus)
+	})
+	require.NoError(t, logger.Close())
+
+	logData, err := ioutil.ReadFile(logFilePath)
+	require.NoError(t, err)
+
+	jsonData := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(logData, &jsonData))
+
+	assert.Equal(t, "acme", jsonData["tenant.id"])
+	assert.Equal(t, "alice", jsonData["user.name"])
+	assert.Equal(t, "", jsonData["missing"])
+}
+
 func TestNewLogHandlerOutputStdout(t *testing.T) {
 	testCases := []struct {
 		desc        string
@@ -0,0 +1,14 @@
+package accesslog
+
+import (
+	"net/http"
+)
+
+// SaveBackendVersion is an implementation of BackendVersionListener that stores BackendVersion in the LogDataTable.
+type SaveBackendVersion struct{}
+
+// BackendVersion implements the BackendVersionListener interface and will be called for each backend response with a version.
+func (s *SaveBackendVersion) BackendVersion(req *http.Request, version string) {
+	table := GetLogDataTable(req)
+	table.Core[BackendVersion] = version
+}
@@ -0,0 +1,218 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/mailgun/timetools"
+)
+
+const (
+	defaultAdaptiveInitialLimit  = 20
+	defaultAdaptiveMinLimit      = 1
+	defaultAdaptiveMaxLimit      = 1000
+	defaultAdaptiveBackoffRatio  = 0.9
+	defaultAdaptiveProbeInterval = 30 * time.Second
+)
+
+var (
+	adaptiveConcurrencyStores     = make(map[string]*adaptiveConcurrencyStore)
+	adaptiveConcurrencyStoresLock sync.Mutex
+)
+
+// AdaptiveConcurrency limits the in-flight requests of a backend, with a limit adjusted the way TCP Vegas adjusts
+// its congestion window: the latency of a response, compared to the minimum latency of the backend,
+// estimates the number of requests queued by the backend.
+// The limit grows while few requests are queued, and shrinks when more get queued, or when the backend returns
+// server errors. The requests beyond the limit are rejected.
+// The minimum latency is measured again every probe interval, to follow the lasting changes of the backend.
+// The limit and the in-flight requests are shared by the adaptive concurrencies of a backend,
+// so that they are kept across the configuration reloads, and the frontends of the backend share its limit.
+type AdaptiveConcurrency struct {
+	next          http.Handler
+	minLimit      float64
+	maxLimit      float64
+	backoffRatio  float64
+	probeInterval time.Duration
+	statusCode    int
+	limitGauge    gokitmetrics.Gauge
+	clock         timetools.TimeProvider
+	store         *adaptiveConcurrencyStore
+}
+
+// adaptiveConcurrencyStore holds the limit and the in-flight requests of a backend.
+type adaptiveConcurrencyStore struct {
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	minRTT   time.Duration
+	probeAt  time.Time
+}
+
+func getAdaptiveConcurrencyStore(backendName string) *adaptiveConcurrencyStore {
+	adaptiveConcurrencyStoresLock.Lock()
+	defer adaptiveConcurrencyStoresLock.Unlock()
+
+	s, ok := adaptiveConcurrencyStores[backendName]
+	if !ok {
+		s = &adaptiveConcurrencyStore{}
+		adaptiveConcurrencyStores[backendName] = s
+	}
+	return s
+}
+
+// NewAdaptiveConcurrency creates a new AdaptiveConcurrency for the given backend.
+// The limit gauge is optional.
+func NewAdaptiveConcurrency(backendName string, next http.Handler, config *types.AdaptiveConcurrency, limitGauge gokitmetrics.Gauge) (*AdaptiveConcurrency, error) {
+	return newAdaptiveConcurrency(backendName, next, config, limitGauge, &timetools.RealTime{})
+}
+
+func newAdaptiveConcurrency(backendName string, next http.Handler, config *types.AdaptiveConcurrency, limitGauge gokitmetrics.Gauge, clock timetools.TimeProvider) (*AdaptiveConcurrency, error) {
+	ac := &AdaptiveConcurrency{
+		next:          next,
+		minLimit:      defaultAdaptiveMinLimit,
+		maxLimit:      defaultAdaptiveMaxLimit,
+		backoffRatio:  defaultAdaptiveBackoffRatio,
+		probeInterval: defaultAdaptiveProbeInterval,
+		statusCode:    http.StatusServiceUnavailable,
+		limitGauge:    limitGauge,
+		clock:         clock,
+	}
+
+	if config.MinLimit < 0 || config.MaxLimit < 0 || config.InitialLimit < 0 || config.ProbeInterval < 0 {
+		return nil, errors.New("the adaptive concurrency limits and probe interval must be positive")
+	}
+
+	if config.MinLimit > 0 {
+		ac.minLimit = float64(config.MinLimit)
+	}
+	if config.MaxLimit > 0 {
+		ac.maxLimit = float64(config.MaxLimit)
+	}
+	if ac.minLimit > ac.maxLimit {
+		return nil, fmt.Errorf("the minimum limit %v must be lower than the maximum limit %v", ac.minLimit, ac.maxLimit)
+	}
+
+	initialLimit := float64(defaultAdaptiveInitialLimit)
+	if config.InitialLimit > 0 {
+		initialLimit = float64(config.InitialLimit)
+	}
+
+	if config.BackoffRatio != 0 {
+		if config.BackoffRatio <= 0 || config.BackoffRatio >= 1 {
+			return nil, fmt.Errorf("invalid backoff ratio %v, must be in ]0, 1[", config.BackoffRatio)
+		}
+		ac.backoffRatio = config.BackoffRatio
+	}
+
+	if config.ProbeInterval > 0 {
+		ac.probeInterval = time.Duration(config.ProbeInterval)
+	}
+
+	switch config.StatusCode {
+	case 0:
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		ac.statusCode = config.StatusCode
+	default:
+		return nil, fmt.Errorf("invalid status code %d, must be %d or %d", config.StatusCode, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	}
+
+	ac.store = getAdaptiveConcurrencyStore(backendName)
+
+	ac.store.mu.Lock()
+	defer ac.store.mu.Unlock()
+
+	// The limit of the previous configuration is kept, within the new minimum and maximum limits.
+	if ac.store.limit > 0 {
+		ac.setLimit(ac.store.limit)
+	} else {
+		ac.setLimit(initialLimit)
+	}
+
+	return ac, nil
+}
+
+func (ac *AdaptiveConcurrency) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	inFlight, ok := ac.acquire()
+	if !ok {
+		log.Debugf("Adaptive concurrency limit reached, rejecting the request")
+		http.Error(rw, http.StatusText(ac.statusCode), ac.statusCode)
+		return
+	}
+
+	start := ac.clock.UtcNow()
+	recorder := &responseRecorder{rw, http.StatusOK}
+	ac.next.ServeHTTP(recorder, req)
+
+	ac.release(inFlight, ac.clock.UtcNow().Sub(start), recorder.statusCode >= http.StatusInternalServerError)
+}
+
+// acquire returns the number of in-flight requests including the new one, if the limit allows it.
+func (ac *AdaptiveConcurrency) acquire() (int, bool) {
+	ac.store.mu.Lock()
+	defer ac.store.mu.Unlock()
+
+	if float64(ac.store.inFlight) >= math.Floor(ac.store.limit) {
+		return ac.store.inFlight, false
+	}
+
+	ac.store.inFlight++
+	return ac.store.inFlight, true
+}
+
+// release adjusts the limit with the latency of a request, served with the given number of in-flight requests.
+func (ac *AdaptiveConcurrency) release(inFlight int, rtt time.Duration, failed bool) {
+	ac.store.mu.Lock()
+	defer ac.store.mu.Unlock()
+
+	ac.store.inFlight--
+
+	if failed {
+		ac.setLimit(ac.store.limit * ac.backoffRatio)
+		return
+	}
+
+	if now := ac.clock.UtcNow(); !now.Before(ac.store.probeAt) {
+		ac.store.minRTT = 0
+		ac.store.probeAt = now.Add(ac.probeInterval)
+	}
+
+	if ac.store.minRTT == 0 || rtt < ac.store.minRTT {
+		ac.store.minRTT = rtt
+	}
+
+	// The limit doesn't grow when it is mostly unused, so that it stays meaningful after an idle period.
+	if float64(inFlight)*2 < ac.store.limit {
+		return
+	}
+
+	var queued float64
+	if rtt > 0 {
+		queued = ac.store.limit * (1 - float64(ac.store.minRTT)/float64(rtt))
+	}
+
+	step := math.Max(1, math.Log10(ac.store.limit))
+	switch {
+	case queued <= 3*step:
+		ac.setLimit(ac.store.limit + step)
+	case queued >= 6*step:
+		ac.setLimit(ac.store.limit - step)
+	}
+}
+
+// setLimit sets the limit, within the minimum and maximum limits.
+// It must be called with the lock of the store held.
+func (ac *AdaptiveConcurrency) setLimit(limit float64) {
+	ac.store.limit = math.Min(ac.maxLimit, math.Max(ac.minLimit, limit))
+
+	if ac.limitGauge != nil {
+		ac.limitGauge.Set(math.Floor(ac.store.limit))
+	}
+}
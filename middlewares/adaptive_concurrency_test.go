@@ -0,0 +1,236 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/mailgun/timetools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetAdaptiveConcurrencyStore forgets the limit of the backend, for the tests to start from the initial limit.
+func resetAdaptiveConcurrencyStore(backendName string) {
+	adaptiveConcurrencyStoresLock.Lock()
+	defer adaptiveConcurrencyStoresLock.Unlock()
+
+	delete(adaptiveConcurrencyStores, backendName)
+}
+
+func TestNewAdaptiveConcurrency(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        *types.AdaptiveConcurrency
+		expectedLimit float64
+		expectedErr   bool
+	}{
+		{
+			desc:          "defaults",
+			config:        &types.AdaptiveConcurrency{},
+			expectedLimit: defaultAdaptiveInitialLimit,
+		},
+		{
+			desc:          "initial limit above the maximum",
+			config:        &types.AdaptiveConcurrency{InitialLimit: 50, MaxLimit: 10},
+			expectedLimit: 10,
+		},
+		{
+			desc:        "negative limit",
+			config:      &types.AdaptiveConcurrency{MinLimit: -1},
+			expectedErr: true,
+		},
+		{
+			desc:        "minimum above the maximum",
+			config:      &types.AdaptiveConcurrency{MinLimit: 20, MaxLimit: 10},
+			expectedErr: true,
+		},
+		{
+			desc:        "backoff ratio of 1",
+			config:      &types.AdaptiveConcurrency{BackoffRatio: 1},
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid status code",
+			config:      &types.AdaptiveConcurrency{StatusCode: http.StatusInternalServerError},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			resetAdaptiveConcurrencyStore(t.Name())
+
+			ac, err := NewAdaptiveConcurrency(t.Name(), http.NotFoundHandler(), test.config, nil)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedLimit, ac.store.limit)
+		})
+	}
+}
+
+func TestAdaptiveConcurrencyLimit(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		config        *types.AdaptiveConcurrency
+		inFlight      int
+		rtts          []time.Duration
+		failed        bool
+		expectedLimit float64
+	}{
+		{
+			desc:          "stable latency",
+			config:        &types.AdaptiveConcurrency{InitialLimit: 10},
+			inFlight:      10,
+			rtts:          []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+			expectedLimit: 13.1221,
+		},
+		{
+			desc:          "stable latency up to the maximum",
+			config:        &types.AdaptiveConcurrency{InitialLimit: 10, MaxLimit: 11},
+			inFlight:      10,
+			rtts:          []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+			expectedLimit: 11,
+		},
+		{
+			desc:          "rising latency",
+			config:        &types.AdaptiveConcurrency{InitialLimit: 10},
+			inFlight:      10,
+			rtts:          []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+			expectedLimit: 8.9586,
+		},
+		{
+			desc:          "server errors",
+			config:        &types.AdaptiveConcurrency{InitialLimit: 10},
+			inFlight:      10,
+			rtts:          []time.Duration{10 * time.Millisecond, 10 * time.Millisecond},
+			failed:        true,
+			expectedLimit: 8.1,
+		},
+		{
+			desc:          "unused limit",
+			config:        &types.AdaptiveConcurrency{InitialLimit: 10},
+			inFlight:      2,
+			rtts:          []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+			expectedLimit: 10,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			clock := &timetools.FreezedTime{CurrentTime: time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)}
+			resetAdaptiveConcurrencyStore(t.Name())
+
+			ac, err := newAdaptiveConcurrency(t.Name(), http.NotFoundHandler(), test.config, nil, clock)
+			require.NoError(t, err)
+
+			for _, rtt := range test.rtts {
+				ac.store.inFlight = test.inFlight
+				ac.release(test.inFlight, rtt, test.failed)
+			}
+
+			assert.InDelta(t, test.expectedLimit, ac.store.limit, 0.0001)
+		})
+	}
+}
+
+func TestAdaptiveConcurrencyProbe(t *testing.T) {
+	clock := &timetools.FreezedTime{CurrentTime: time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)}
+	resetAdaptiveConcurrencyStore(t.Name())
+
+	ac, err := newAdaptiveConcurrency(t.Name(), http.NotFoundHandler(), &types.AdaptiveConcurrency{}, nil, clock)
+	require.NoError(t, err)
+
+	ac.release(1, 10*time.Millisecond, false)
+	ac.release(1, 50*time.Millisecond, false)
+	assert.Equal(t, 10*time.Millisecond, ac.store.minRTT)
+
+	// The minimum latency is measured again.
+	clock.CurrentTime = clock.CurrentTime.Add(defaultAdaptiveProbeInterval)
+	ac.release(1, 50*time.Millisecond, false)
+	assert.Equal(t, 50*time.Millisecond, ac.store.minRTT)
+}
+
+func TestAdaptiveConcurrencyServeHTTP(t *testing.T) {
+	gauge := &testhelpers.CollectingGauge{}
+
+	served := make(chan struct{})
+	done := make(chan struct{})
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		served <- struct{}{}
+		<-done
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+
+	resetAdaptiveConcurrencyStore(t.Name())
+
+	ac, err := NewAdaptiveConcurrency(t.Name(), next, &types.AdaptiveConcurrency{InitialLimit: 2, MinLimit: 1, StatusCode: http.StatusTooManyRequests}, gauge)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), gauge.GaugeValue)
+
+	// The limit is reached by two requests in flight.
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			recorder := httptest.NewRecorder()
+			ac.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+			codes <- recorder.Code
+		}()
+		<-served
+	}
+
+	recorder := httptest.NewRecorder()
+	ac.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	close(done)
+	assert.Equal(t, http.StatusBadGateway, <-codes)
+	assert.Equal(t, http.StatusBadGateway, <-codes)
+
+	// The server errors back off the limit.
+	assert.Equal(t, 0, ac.store.inFlight)
+	assert.InDelta(t, 1.62, ac.store.limit, 0.0001)
+	assert.Equal(t, float64(1), gauge.GaugeValue)
+}
+
+func TestAdaptiveConcurrencyReload(t *testing.T) {
+	resetAdaptiveConcurrencyStore(t.Name())
+
+	clock := &timetools.FreezedTime{CurrentTime: time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)}
+	ac, err := newAdaptiveConcurrency(t.Name(), http.NotFoundHandler(), &types.AdaptiveConcurrency{InitialLimit: 10}, nil, clock)
+	require.NoError(t, err)
+
+	acquired, ok := ac.acquire()
+	require.True(t, ok)
+	ac.release(acquired, 10*time.Millisecond, true)
+	assert.InDelta(t, 9, ac.store.limit, 0.0001)
+
+	_, ok = ac.acquire()
+	require.True(t, ok)
+
+	// The adaptive concurrency of the reloaded configuration keeps the limit and the in-flight requests.
+	gauge := &testhelpers.CollectingGauge{}
+	reloaded, err := newAdaptiveConcurrency(t.Name(), http.NotFoundHandler(), &types.AdaptiveConcurrency{InitialLimit: 10}, gauge, clock)
+	require.NoError(t, err)
+	assert.InDelta(t, 9, reloaded.store.limit, 0.0001)
+	assert.Equal(t, 1, reloaded.store.inFlight)
+	assert.Equal(t, float64(9), gauge.GaugeValue)
+
+	// The kept limit is bounded by the new maximum limit.
+	reloaded, err = newAdaptiveConcurrency(t.Name(), http.NotFoundHandler(), &types.AdaptiveConcurrency{MaxLimit: 5}, nil, clock)
+	require.NoError(t, err)
+	assert.InDelta(t, 5, reloaded.store.limit, 0.0001)
+}
@@ -14,7 +14,7 @@ import (
 	"github.com/urfave/negroni"
 )
 
-// Authenticator is a middleware that provides HTTP basic and digest authentication
+// Authenticator is a middleware that provides HTTP basic, digest and JWT authentication
 type Authenticator struct {
 	handler negroni.Handler
 	users   map[string]string
@@ -64,9 +64,26 @@ func NewAuthenticator(authConfig *types.Auth, tracingMiddleware *tracing.Tracing
 		tracingAuth.name = "Auth Digest"
 		tracingAuth.clientSpanKind = false
 	} else if authConfig.Forward != nil {
-		tracingAuth.handler = createAuthForwardHandler(authConfig)
+		var cache *forwardAuthCache
+		if authConfig.Forward.Cache != nil {
+			cache, err = newForwardAuthCache(authConfig.Forward.Cache)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tracingAuth.handler = createAuthForwardHandler(authConfig, cache)
 		tracingAuth.name = "Auth Forward"
 		tracingAuth.clientSpanKind = true
+	} else if authConfig.JWT != nil {
+		validator, err := newJWTValidator(authConfig.JWT)
+		if err != nil {
+			return nil, err
+		}
+
+		tracingAuth.handler = createAuthJWTHandler(validator, authConfig)
+		tracingAuth.name = "Auth JWT"
+		tracingAuth.clientSpanKind = false
 	}
 
 	if tracingMiddleware != nil {
@@ -77,9 +94,9 @@ func NewAuthenticator(authConfig *types.Auth, tracingMiddleware *tracing.Tracing
 	return authenticator, nil
 }
 
-func createAuthForwardHandler(authConfig *types.Auth) negroni.HandlerFunc {
+func createAuthForwardHandler(authConfig *types.Auth, cache *forwardAuthCache) negroni.HandlerFunc {
 	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-		Forward(authConfig.Forward, w, r, next)
+		forwardWithCache(authConfig.Forward, cache, w, r, next)
 	})
 }
 func createAuthDigestHandler(digestAuth *goauth.DigestAuth, authConfig *types.Auth) negroni.HandlerFunc {
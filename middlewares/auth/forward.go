@@ -18,8 +18,49 @@ const (
 	xForwardedMethod = "X-Forwarded-Method"
 )
 
+// forwardAuthResponse holds the decision of the authentication server.
+type forwardAuthResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
 // Forward the authentication to a external server
 func Forward(config *types.Forward, w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	forwardWithCache(config, nil, w, r, next)
+}
+
+// forwardWithCache forwards the authentication to a external server,
+// unless the response for the principal of the request is cached.
+func forwardWithCache(config *types.Forward, cache *forwardAuthCache, w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var cacheKey string
+	if cache != nil {
+		cacheKey = r.Header.Get(cache.key)
+	}
+
+	if len(cacheKey) > 0 {
+		if authResponse, ok := cache.get(cacheKey); ok {
+			log.Debugf("Using the cached response of %s", config.Address)
+			serveForwardAuthResponse(config, authResponse, w, r, next)
+			return
+		}
+	}
+
+	authResponse, ok := callForwardAuth(config, w, r)
+	if !ok {
+		return
+	}
+
+	// The server errors are not decisions, and are never cached.
+	if len(cacheKey) > 0 && authResponse.statusCode < http.StatusInternalServerError {
+		cache.add(cacheKey, authResponse)
+	}
+
+	serveForwardAuthResponse(config, authResponse, w, r, next)
+}
+
+// callForwardAuth calls the authentication server, and answers with a 500 if it fails.
+func callForwardAuth(config *types.Forward, w http.ResponseWriter, r *http.Request) (*forwardAuthResponse, bool) {
 	// Ensure our request client does not follow redirects
 	httpClient := http.Client{
 		CheckRedirect: func(r *http.Request, via []*http.Request) error {
@@ -32,7 +73,7 @@ func Forward(config *types.Forward, w http.ResponseWriter, r *http.Request, next
 		if err != nil {
 			tracing.SetErrorAndDebugLog(r, "Unable to configure TLS to call %s. Cause %s", config.Address, err)
 			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return nil, false
 		}
 
 		httpClient.Transport = &http.Transport{
@@ -45,7 +86,7 @@ func Forward(config *types.Forward, w http.ResponseWriter, r *http.Request, next
 	if err != nil {
 		tracing.SetErrorAndDebugLog(r, "Error calling %s. Cause %s", config.Address, err)
 		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, false
 	}
 
 	writeHeader(r, forwardReq, config.TrustForwardHeader)
@@ -56,56 +97,80 @@ func Forward(config *types.Forward, w http.ResponseWriter, r *http.Request, next
 	if forwardErr != nil {
 		tracing.SetErrorAndDebugLog(r, "Error calling %s. Cause: %s", config.Address, forwardErr)
 		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, false
 	}
 
 	body, readError := ioutil.ReadAll(forwardResponse.Body)
 	if readError != nil {
 		tracing.SetErrorAndDebugLog(r, "Error reading body %s. Cause: %s", config.Address, readError)
 		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, false
 	}
 	defer forwardResponse.Body.Close()
 
-	// Pass the forward response's body and selected headers if it
-	// didn't return a response within the range of [200, 300).
-	if forwardResponse.StatusCode < http.StatusOK || forwardResponse.StatusCode >= http.StatusMultipleChoices {
-		log.Debugf("Remote error %s. StatusCode: %d", config.Address, forwardResponse.StatusCode)
+	authResponse := &forwardAuthResponse{
+		statusCode: forwardResponse.StatusCode,
+		header:     make(http.Header),
+	}
 
-		utils.CopyHeaders(w.Header(), forwardResponse.Header)
-		utils.RemoveHeaders(w.Header(), forward.HopHeaders...)
+	if isForwardAuthSuccess(forwardResponse.StatusCode) {
+		for _, headerName := range config.AuthResponseHeaders {
+			authResponse.header.Set(headerName, forwardResponse.Header.Get(headerName))
+		}
+		return authResponse, true
+	}
 
-		// Grab the location header, if any.
-		redirectURL, err := forwardResponse.Location()
+	log.Debugf("Remote error %s. StatusCode: %d", config.Address, forwardResponse.StatusCode)
 
-		if err != nil {
-			if err != http.ErrNoLocation {
-				tracing.SetErrorAndDebugLog(r, "Error reading response location header %s. Cause: %s", config.Address, err)
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-		} else if redirectURL.String() != "" {
-			// Set the location in our response if one was sent back.
-			w.Header().Set("Location", redirectURL.String())
+	utils.CopyHeaders(authResponse.header, forwardResponse.Header)
+	utils.RemoveHeaders(authResponse.header, forward.HopHeaders...)
+	authResponse.body = body
+
+	// Grab the location header, if any.
+	redirectURL, err := forwardResponse.Location()
+
+	if err != nil {
+		if err != http.ErrNoLocation {
+			tracing.SetErrorAndDebugLog(r, "Error reading response location header %s. Cause: %s", config.Address, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return nil, false
 		}
+	} else if redirectURL.String() != "" {
+		// Set the location in our response if one was sent back.
+		authResponse.header.Set("Location", redirectURL.String())
+	}
 
-		tracing.LogResponseCode(tracing.GetSpan(r), forwardResponse.StatusCode)
-		w.WriteHeader(forwardResponse.StatusCode)
+	return authResponse, true
+}
 
-		if _, err = w.Write(body); err != nil {
+// serveForwardAuthResponse passes the request to the next handler with the selected headers
+// of the authentication server response if it is within the range of [200, 300),
+// or answers with the authentication server response otherwise.
+func serveForwardAuthResponse(config *types.Forward, authResponse *forwardAuthResponse, w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !isForwardAuthSuccess(authResponse.statusCode) {
+		utils.CopyHeaders(w.Header(), authResponse.header)
+
+		tracing.LogResponseCode(tracing.GetSpan(r), authResponse.statusCode)
+		w.WriteHeader(authResponse.statusCode)
+
+		if _, err := w.Write(authResponse.body); err != nil {
 			log.Error(err)
 		}
 		return
 	}
 
 	for _, headerName := range config.AuthResponseHeaders {
-		r.Header.Set(headerName, forwardResponse.Header.Get(headerName))
+		r.Header.Set(headerName, authResponse.header.Get(headerName))
 	}
 
 	r.RequestURI = r.URL.RequestURI()
 	next(w, r)
 }
 
+func isForwardAuthSuccess(statusCode int) bool {
+	return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+}
+
 func writeHeader(req *http.Request, forwardReq *http.Request, trustForwardHeader bool) {
 	utils.CopyHeaders(forwardReq.Header, req.Header)
 	utils.RemoveHeaders(forwardReq.Header, forward.HopHeaders...)
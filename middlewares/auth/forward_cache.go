@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/containous/traefik/types"
+	"github.com/hashicorp/golang-lru"
+)
+
+const (
+	defaultForwardCacheTTL        = time.Minute
+	defaultForwardCacheMaxEntries = 1000
+)
+
+// forwardAuthCache caches the responses of the authentication server, per value of the key header.
+// The least recently used responses are evicted when the cache is full.
+type forwardAuthCache struct {
+	key     string
+	ttl     time.Duration
+	entries *lru.Cache
+}
+
+type forwardAuthCacheEntry struct {
+	response   *forwardAuthResponse
+	expiration time.Time
+}
+
+func newForwardAuthCache(config *types.ForwardCache) (*forwardAuthCache, error) {
+	key := config.Key
+	if len(key) == 0 {
+		key = authorizationHeader
+	}
+
+	ttl := time.Duration(config.TTL)
+	if ttl <= 0 {
+		ttl = defaultForwardCacheTTL
+	}
+
+	maxEntries := config.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultForwardCacheMaxEntries
+	}
+
+	entries, err := lru.New(maxEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &forwardAuthCache{
+		key:     http.CanonicalHeaderKey(key),
+		ttl:     ttl,
+		entries: entries,
+	}, nil
+}
+
+func (c *forwardAuthCache) get(key string) (*forwardAuthResponse, bool) {
+	value, ok := c.entries.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	entry := value.(*forwardAuthCacheEntry)
+	if time.Now().After(entry.expiration) {
+		c.entries.Remove(key)
+		return nil, false
+	}
+
+	return entry.response, true
+}
+
+func (c *forwardAuthCache) add(key string, response *forwardAuthResponse) {
+	c.entries.Add(key, &forwardAuthCacheEntry{
+		response:   response,
+		expiration: time.Now().Add(c.ttl),
+	})
+}
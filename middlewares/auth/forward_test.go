@@ -5,8 +5,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/middlewares/tracing"
 	"github.com/containous/traefik/testhelpers"
 	"github.com/containous/traefik/types"
@@ -390,3 +393,122 @@ func Test_writeHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestForwardAuthCache(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") != "Bearer good" {
+			w.Header().Set("X-Reason", "bad token")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-Auth-User", "user@example.com")
+		w.Header().Set("X-Auth-Secret", "secret")
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		desc           string
+		authorization  []string
+		expectedCalls  int32
+		expectedCode   int
+		expectedBody   string
+		expectedReason string
+	}{
+		{
+			desc:          "cached success",
+			authorization: []string{"Bearer good", "Bearer good", "Bearer good"},
+			expectedCalls: 1,
+			expectedCode:  http.StatusOK,
+			expectedBody:  "user@example.com\n",
+		},
+		{
+			desc:           "cached denial",
+			authorization:  []string{"Bearer bad", "Bearer bad"},
+			expectedCalls:  1,
+			expectedCode:   http.StatusForbidden,
+			expectedBody:   "Forbidden\n",
+			expectedReason: "bad token",
+		},
+		{
+			desc:           "requests without key are not cached",
+			authorization:  []string{"", ""},
+			expectedCalls:  2,
+			expectedCode:   http.StatusForbidden,
+			expectedBody:   "Forbidden\n",
+			expectedReason: "bad token",
+		},
+		{
+			desc:          "least recently used entry is evicted",
+			authorization: []string{"Bearer good", "Bearer bad", "Bearer good"},
+			expectedCalls: 3,
+			expectedCode:  http.StatusOK,
+			expectedBody:  "user@example.com\n",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+
+			maxEntries := 10
+			if test.expectedCalls == int32(len(test.authorization)) {
+				maxEntries = 1
+			}
+
+			middleware, err := NewAuthenticator(&types.Auth{
+				Forward: &types.Forward{
+					Address:             server.URL,
+					AuthResponseHeaders: []string{"X-Auth-User"},
+					Cache: &types.ForwardCache{
+						TTL:        parse.Duration(time.Minute),
+						MaxEntries: maxEntries,
+					},
+				},
+			}, &tracing.Tracing{})
+			require.NoError(t, err)
+
+			n := negroni.New(middleware)
+			n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintln(w, r.Header.Get("X-Auth-User"))
+			}))
+
+			var recorder *httptest.ResponseRecorder
+			for _, authorization := range test.authorization {
+				req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil)
+				if len(authorization) > 0 {
+					req.Header.Set("Authorization", authorization)
+				}
+
+				recorder = httptest.NewRecorder()
+				n.ServeHTTP(recorder, req)
+			}
+
+			assert.Equal(t, test.expectedCalls, atomic.LoadInt32(&calls))
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedBody, recorder.Body.String())
+			assert.Equal(t, test.expectedReason, recorder.Header().Get("X-Reason"))
+		})
+	}
+}
+
+func TestForwardAuthCacheExpiration(t *testing.T) {
+	cache, err := newForwardAuthCache(&types.ForwardCache{Key: "x-api-key"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "X-Api-Key", cache.key)
+	assert.Equal(t, defaultForwardCacheTTL, cache.ttl)
+
+	cache.add("key", &forwardAuthResponse{statusCode: http.StatusOK})
+
+	_, ok := cache.get("key")
+	assert.True(t, ok)
+
+	cache.ttl = -time.Second
+	cache.add("key", &forwardAuthResponse{statusCode: http.StatusOK})
+
+	_, ok = cache.get("key")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.entries.Len())
+}
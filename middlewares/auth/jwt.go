@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/accesslog"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/urfave/negroni"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	defaultJWKSRefreshInterval = time.Hour
+	// minJWKSRefreshInterval limits the refreshes of the key set, when the endpoint is down or when unknown keys are used.
+	minJWKSRefreshInterval = 10 * time.Second
+	jwksTimeout            = 10 * time.Second
+)
+
+// jwtSigningMethods are the accepted signing methods, which all rely on the public keys of the key set.
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// jwtValidator validates the bearer tokens against a JSON Web Key Set.
+type jwtValidator struct {
+	config *types.JWT
+	keys   *jwksCache
+	parser *jwt.Parser
+}
+
+func newJWTValidator(config *types.JWT) (*jwtValidator, error) {
+	if len(config.JWKSURL) == 0 {
+		return nil, errors.New("error creating JWT Authenticator: jwksUrl is empty")
+	}
+
+	if _, err := url.ParseRequestURI(config.JWKSURL); err != nil {
+		return nil, fmt.Errorf("error creating JWT Authenticator: invalid jwksUrl: %v", err)
+	}
+
+	refreshInterval := time.Duration(config.RefreshInterval)
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+
+	return &jwtValidator{
+		config: config,
+		keys: &jwksCache{
+			url:             config.JWKSURL,
+			client:          &http.Client{Timeout: jwksTimeout},
+			refreshInterval: refreshInterval,
+		},
+		parser: &jwt.Parser{ValidMethods: jwtSigningMethods},
+	}, nil
+}
+
+// validate returns the claims of the token if it is valid.
+func (v *jwtValidator) validate(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("token has no valid expiration time")
+	}
+
+	if len(v.config.Issuer) > 0 && !claims.VerifyIssuer(v.config.Issuer, true) {
+		return nil, errors.New("invalid issuer")
+	}
+
+	if !verifyAudience(claims, v.config.Audiences) {
+		return nil, errors.New("invalid audience")
+	}
+
+	return claims, nil
+}
+
+func (v *jwtValidator) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if key := v.keys.lookup(kid, false); key != nil {
+		return key, nil
+	}
+
+	// The keys may have been rotated since the last refresh.
+	if key := v.keys.lookup(kid, true); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func verifyAudience(claims jwt.MapClaims, audiences []string) bool {
+	if len(audiences) == 0 {
+		return true
+	}
+
+	var tokenAudiences []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		tokenAudiences = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				tokenAudiences = append(tokenAudiences, s)
+			}
+		}
+	}
+
+	for _, audience := range audiences {
+		for _, tokenAudience := range tokenAudiences {
+			if audience == tokenAudience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jwksCache holds the last good JSON Web Key Set, and refreshes it periodically.
+// The key set is fetched outside of the lock, by a single refresh at a time,
+// while the last good key set is served.
+type jwksCache struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+
+	lock        sync.Mutex
+	keys        *jose.JSONWebKeySet
+	lastRefresh time.Time
+	lastAttempt time.Time
+	// refreshing is closed when the refresh in progress, if any, is done.
+	refreshing chan struct{}
+}
+
+// lookup returns the public key with the given ID, refreshing the key set if it is stale or if forced.
+// It waits for the refresh only without key set, or if forced, as the key may have been rotated.
+func (c *jwksCache) lookup(kid string, force bool) interface{} {
+	c.lock.Lock()
+	stale := force || c.keys == nil || time.Since(c.lastRefresh) >= c.refreshInterval
+	if stale && c.refreshing == nil && time.Since(c.lastAttempt) >= minJWKSRefreshInterval {
+		c.startRefresh()
+	}
+	refreshing := c.refreshing
+	wait := refreshing != nil && (force || c.keys == nil)
+	c.lock.Unlock()
+
+	if wait {
+		<-refreshing
+	}
+
+	c.lock.Lock()
+	keys := c.keys
+	c.lock.Unlock()
+
+	// Without key ID, the key set must be unambiguous.
+	if keys == nil || len(kid) == 0 && len(keys.Keys) != 1 {
+		return nil
+	}
+
+	for _, key := range keys.Keys {
+		if len(kid) > 0 && key.KeyID != kid {
+			continue
+		}
+		if len(key.Use) > 0 && key.Use != "sig" {
+			continue
+		}
+		if public := key.Public(); public.Key != nil {
+			return public.Key
+		}
+	}
+	return nil
+}
+
+// startRefresh fetches the key set in the background, and keeps the last good one on failure.
+// It must be called with the lock held.
+func (c *jwksCache) startRefresh() {
+	attempt := time.Now()
+	refreshing := make(chan struct{})
+
+	c.lastAttempt = attempt
+	c.refreshing = refreshing
+
+	safe.Go(func() {
+		defer close(refreshing)
+
+		keys, err := c.fetch()
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		c.refreshing = nil
+		if err != nil {
+			log.Errorf("Unable to refresh the JSON Web Key Set from %s: %v", c.url, err)
+			return
+		}
+
+		c.keys = keys
+		c.lastRefresh = attempt
+	})
+}
+
+func (c *jwksCache) fetch() (*jose.JSONWebKeySet, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	keys := &jose.JSONWebKeySet{}
+	if err := json.NewDecoder(resp.Body).Decode(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func createAuthJWTHandler(validator *jwtValidator, authConfig *types.Auth) negroni.HandlerFunc {
+	return negroni.HandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		tokenString, ok := bearerToken(r)
+		if !ok {
+			log.Debugf("JWT auth failed: no bearer token")
+			requireJWTAuth(w, "")
+			return
+		}
+
+		claims, err := validator.validate(tokenString)
+		if err != nil {
+			log.Debugf("JWT auth failed: %v", err)
+			requireJWTAuth(w, "invalid_token")
+			return
+		}
+		log.Debugf("JWT auth succeeded")
+
+		if subject, ok := claims["sub"].(string); ok {
+			// set username in request context
+			r = accesslog.WithUserName(r, subject)
+
+			if authConfig.HeaderField != "" {
+				r.Header[authConfig.HeaderField] = []string{subject}
+			}
+		}
+
+		for claim, header := range authConfig.JWT.ClaimsHeaders {
+			// Never trust the values sent by the client.
+			r.Header.Del(header)
+			if value, ok := claims[claim]; ok {
+				r.Header.Set(header, claimValue(value))
+			}
+		}
+
+		if !authConfig.JWT.ForwardHeaders {
+			log.Debugf("Remove the Authorization header from the JWT auth")
+			r.Header.Del(authorizationHeader)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get(authorizationHeader), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(parts[1])
+	return token, len(token) > 0
+}
+
+func requireJWTAuth(w http.ResponseWriter, authError string) {
+	challenge := `Bearer realm="traefik"`
+	if len(authError) > 0 {
+		challenge += fmt.Sprintf(", error=%q", authError)
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+func claimValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
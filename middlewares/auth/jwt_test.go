@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/middlewares/tracing"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func newJWKSServer(t *testing.T, key *rsa.PrivateKey, kid string) (*httptest.Server, *int32) {
+	data, err := json.Marshal(jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: kid, Algorithm: "RS256", Use: "sig"}},
+	})
+	require.NoError(t, err)
+
+	var down int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write(data)
+	}))
+
+	return server, &down
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	tokenString, err := token.SignedString(key)
+	require.NoError(t, err)
+	return tokenString
+}
+
+func TestJWTAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksServer, _ := newJWKSServer(t, key, "key1")
+	defer jwksServer.Close()
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	testCases := []struct {
+		desc              string
+		authorization     string
+		expectedCode      int
+		expectedChallenge string
+		expectedHeaders   map[string]string
+	}{
+		{
+			desc: "valid token",
+			authorization: "Bearer " + signToken(t, key, "key1", jwt.MapClaims{
+				"iss": "https://auth.example.com/", "aud": "api", "exp": exp, "sub": "user1", "admin": true,
+			}),
+			expectedCode: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"X-User":        "user1",
+				"X-Admin":       "true",
+				"X-Email":       "",
+				"Authorization": "",
+			},
+		},
+		{
+			desc: "valid token with several audiences",
+			authorization: "Bearer " + signToken(t, key, "key1", jwt.MapClaims{
+				"iss": "https://auth.example.com/", "aud": []string{"web", "api"}, "exp": exp, "sub": "user1",
+			}),
+			expectedCode:    http.StatusOK,
+			expectedHeaders: map[string]string{"X-User": "user1"},
+		},
+		{
+			desc:              "missing token",
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik"`,
+		},
+		{
+			desc:              "not a bearer token",
+			authorization:     "Basic dGVzdDp0ZXN0",
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik"`,
+		},
+		{
+			desc:              "malformed token",
+			authorization:     "Bearer foo.bar.baz",
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik", error="invalid_token"`,
+		},
+		{
+			desc: "expired token",
+			authorization: "Bearer " + signToken(t, key, "key1", jwt.MapClaims{
+				"iss": "https://auth.example.com/", "aud": "api", "exp": time.Now().Add(-time.Minute).Unix(),
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik", error="invalid_token"`,
+		},
+		{
+			desc: "token without expiration",
+			authorization: "Bearer " + signToken(t, key, "key1", jwt.MapClaims{
+				"iss": "https://auth.example.com/", "aud": "api",
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik", error="invalid_token"`,
+		},
+		{
+			desc: "wrong issuer",
+			authorization: "Bearer " + signToken(t, key, "key1", jwt.MapClaims{
+				"iss": "https://evil.example.com/", "aud": "api", "exp": exp,
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik", error="invalid_token"`,
+		},
+		{
+			desc: "wrong audience",
+			authorization: "Bearer " + signToken(t, key, "key1", jwt.MapClaims{
+				"iss": "https://auth.example.com/", "aud": "web", "exp": exp,
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik", error="invalid_token"`,
+		},
+		{
+			desc: "unknown key",
+			authorization: "Bearer " + signToken(t, key, "key2", jwt.MapClaims{
+				"iss": "https://auth.example.com/", "aud": "api", "exp": exp,
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik", error="invalid_token"`,
+		},
+		{
+			desc: "invalid signature",
+			authorization: "Bearer " + signToken(t, otherKey, "key1", jwt.MapClaims{
+				"iss": "https://auth.example.com/", "aud": "api", "exp": exp,
+			}),
+			expectedCode:      http.StatusUnauthorized,
+			expectedChallenge: `Bearer realm="traefik", error="invalid_token"`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			authMiddleware, err := NewAuthenticator(&types.Auth{
+				JWT: &types.JWT{
+					JWKSURL:   jwksServer.URL,
+					Issuer:    "https://auth.example.com/",
+					Audiences: []string{"api"},
+					ClaimsHeaders: map[string]string{
+						"sub":   "X-User",
+						"admin": "X-Admin",
+						"email": "X-Email",
+					},
+				},
+			}, &tracing.Tracing{})
+			require.NoError(t, err)
+
+			var forwarded *http.Request
+			n := negroni.New(authMiddleware)
+			n.UseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = r
+			}))
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil)
+			req.Header.Set("X-Email", "spoofed@example.com")
+			if len(test.authorization) > 0 {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			n.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedChallenge, recorder.Header().Get("WWW-Authenticate"))
+
+			if test.expectedCode != http.StatusOK {
+				assert.Nil(t, forwarded)
+				return
+			}
+
+			require.NotNil(t, forwarded)
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, forwarded.Header.Get(name), name)
+			}
+		})
+	}
+}
+
+func TestJWTAuthKeySetUnavailable(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksServer, down := newJWKSServer(t, key, "key1")
+	defer jwksServer.Close()
+
+	validator, err := newJWTValidator(&types.JWT{JWKSURL: jwksServer.URL})
+	require.NoError(t, err)
+
+	token := signToken(t, key, "key1", jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+
+	_, err = validator.validate(token)
+	require.NoError(t, err)
+
+	// The key set is stale and the endpoint is down, the last good key set is used.
+	atomic.StoreInt32(down, 1)
+	validator.keys.lastRefresh = time.Now().Add(-2 * defaultJWKSRefreshInterval)
+	validator.keys.lastAttempt = validator.keys.lastRefresh
+
+	_, err = validator.validate(token)
+	require.NoError(t, err)
+
+	waitJWKSRefresh(validator.keys)
+	assert.True(t, validator.keys.lastAttempt.After(validator.keys.lastRefresh))
+}
+
+func TestJWTAuthKeySetRefreshNotBlocking(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "key1", Algorithm: "RS256", Use: "sig"}},
+	})
+	require.NoError(t, err)
+
+	var slow int32
+	release := make(chan struct{})
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.LoadInt32(&slow) == 1 {
+			<-release
+		}
+		rw.Write(data)
+	}))
+	defer jwksServer.Close()
+
+	validator, err := newJWTValidator(&types.JWT{JWKSURL: jwksServer.URL})
+	require.NoError(t, err)
+
+	token := signToken(t, key, "key1", jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()})
+
+	_, err = validator.validate(token)
+	require.NoError(t, err)
+
+	// The key set is stale and the endpoint is slow, the last good key set is served during the refresh.
+	atomic.StoreInt32(&slow, 1)
+	validator.keys.lock.Lock()
+	validator.keys.lastRefresh = time.Now().Add(-2 * defaultJWKSRefreshInterval)
+	validator.keys.lastAttempt = validator.keys.lastRefresh
+	validator.keys.lock.Unlock()
+
+	for i := 0; i < 2; i++ {
+		_, err = validator.validate(token)
+		require.NoError(t, err)
+	}
+
+	close(release)
+	waitJWKSRefresh(validator.keys)
+
+	validator.keys.lock.Lock()
+	defer validator.keys.lock.Unlock()
+	assert.Equal(t, validator.keys.lastAttempt, validator.keys.lastRefresh)
+}
+
+// waitJWKSRefresh waits for the refresh in progress of the key set, if any.
+func waitJWKSRefresh(cache *jwksCache) {
+	cache.lock.Lock()
+	refreshing := cache.refreshing
+	cache.lock.Unlock()
+
+	if refreshing != nil {
+		<-refreshing
+	}
+}
+
+func TestJWTAuthNoKeySet(t *testing.T) {
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer jwksServer.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	validator, err := newJWTValidator(&types.JWT{JWKSURL: jwksServer.URL})
+	require.NoError(t, err)
+
+	_, err = validator.validate(signToken(t, key, "key1", jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}))
+	assert.Error(t, err)
+}
+
+func TestNewJWTValidatorFail(t *testing.T) {
+	_, err := NewAuthenticator(&types.Auth{JWT: &types.JWT{}}, &tracing.Tracing{})
+	assert.Error(t, err)
+
+	_, err = NewAuthenticator(&types.Auth{JWT: &types.JWT{JWKSURL: "not a url"}}, &tracing.Tracing{})
+	assert.Error(t, err)
+}
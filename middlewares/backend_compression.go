@@ -0,0 +1,312 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Backend compression policies.
+const (
+	BackendCompressionRequire = "require"
+	BackendCompressionForbid  = "forbid"
+)
+
+// defaultMaxDecodedBodyBytes is the default maximum size of the decoded bodies of the responses.
+const defaultMaxDecodedBodyBytes = 10 * 1024 * 1024
+
+var errDecodedBodyTooLarge = errors.New("decoded body too large")
+
+var decoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	EncodingZstd: func(r io.Reader) (io.ReadCloser, error) {
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	},
+	EncodingBrotli: func(r io.Reader) (io.ReadCloser, error) {
+		return ioutil.NopCloser(brotli.NewReader(r)), nil
+	},
+	EncodingGzip: func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(r)
+	},
+}
+
+// BackendCompression enforces the compression policy of the backend responses of a frontend.
+// With the require policy, the responses without content encoding are compressed, if the client accepts it.
+// With the forbid policy, the encoded responses are decoded, so that the entry point compression applies consistently:
+// the decoded bodies are buffered, up to their maximum size, not to be decompression bombs.
+// The responses violating the policy are logged.
+type BackendCompression struct {
+	frontendName        string
+	policy              string
+	compress            *Compress
+	maxDecodedBodyBytes int64
+}
+
+// NewBackendCompression creates a new BackendCompression middleware.
+func NewBackendCompression(frontendName string, config *types.BackendCompression) (*BackendCompression, error) {
+	policy := strings.ToLower(config.Policy)
+	if policy != BackendCompressionRequire && policy != BackendCompressionForbid {
+		return nil, fmt.Errorf("invalid backend compression policy %q, must be %s or %s", config.Policy, BackendCompressionRequire, BackendCompressionForbid)
+	}
+
+	compress, err := NewCompress(config.Encodings)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.MaxDecodedBodyBytes < 0 {
+		return nil, fmt.Errorf("invalid maxDecodedBodyBytes %d, must be positive", config.MaxDecodedBodyBytes)
+	}
+
+	maxDecodedBodyBytes := config.MaxDecodedBodyBytes
+	if maxDecodedBodyBytes == 0 {
+		maxDecodedBodyBytes = defaultMaxDecodedBodyBytes
+	}
+
+	return &BackendCompression{
+		frontendName:        frontendName,
+		policy:              policy,
+		compress:            compress,
+		maxDecodedBodyBytes: maxDecodedBodyBytes,
+	}, nil
+}
+
+func (b *BackendCompression) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc") {
+		next.ServeHTTP(rw, req)
+		return
+	}
+
+	bw := &backendCompressionResponseWriter{
+		ResponseWriter: rw,
+		policy:         b,
+		req:            req,
+	}
+	defer func() {
+		if err := bw.close(); err != nil {
+			log.Debugf("Error while closing the backend compression writer of frontend %s: %v", b.frontendName, err)
+		}
+	}()
+
+	if _, ok := rw.(http.CloseNotifier); ok {
+		next.ServeHTTP(backendCompressionResponseWriterWithCloseNotify{bw}, req)
+	} else {
+		next.ServeHTTP(bw, req)
+	}
+}
+
+// backendCompressionResponseWriter applies the policy once the response headers are written:
+// it passes the body through, compresses it, or decodes it.
+type backendCompressionResponseWriter struct {
+	http.ResponseWriter
+	policy *BackendCompression
+	req    *http.Request
+
+	wroteHeader bool
+	writer      io.Writer
+	compressor  *compressResponseWriter
+	pipe        *io.PipeWriter
+	decoded     chan struct{}
+	code        int
+	decodedBody bytes.Buffer
+	decodeErr   error
+}
+
+type backendCompressionResponseWriterWithCloseNotify struct {
+	*backendCompressionResponseWriter
+}
+
+func (w backendCompressionResponseWriterWithCloseNotify) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *backendCompressionResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	if code < http.StatusOK && code != http.StatusSwitchingProtocols {
+		// The informational responses are followed by the final one.
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	w.writer = w.ResponseWriter
+
+	if !bodyAllowed(w.req, code) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(w.Header().Get("Content-Encoding")))
+
+	switch w.policy.policy {
+	case BackendCompressionRequire:
+		if len(encoding) > 0 && encoding != "identity" {
+			break
+		}
+
+		log.Warnf("Compression policy violation on frontend %s: uncompressed response for %s", w.policy.frontendName, w.req.URL.Path)
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		selected := w.policy.compress.selectEncoding(w.req.Header.Get("Accept-Encoding"))
+		if len(selected) == 0 {
+			break
+		}
+
+		w.Header().Del("Content-Encoding")
+		w.compressor = &compressResponseWriter{ResponseWriter: w.ResponseWriter, encoding: selected}
+		w.compressor.WriteHeader(code)
+		w.writer = w.compressor
+		return
+	case BackendCompressionForbid:
+		if len(encoding) == 0 || encoding == "identity" {
+			break
+		}
+
+		log.Warnf("Compression policy violation on frontend %s: %s encoded response for %s", w.policy.frontendName, encoding, w.req.URL.Path)
+
+		decode, ok := decoders[encoding]
+		if !ok {
+			log.Debugf("Unsupported content encoding %q, the response of frontend %s is not decoded", encoding, w.policy.frontendName)
+			break
+		}
+
+		// The headers are written with the decoded body.
+		w.Header().Del("Content-Encoding")
+		w.Header().Del("Content-Length")
+		w.code = code
+		w.startDecoding(decode)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// startDecoding decodes the body written to the pipe into the decoded body, up to its maximum size.
+func (w *backendCompressionResponseWriter) startDecoding(decode func(io.Reader) (io.ReadCloser, error)) {
+	pr, pw := io.Pipe()
+	w.pipe = pw
+	w.writer = pw
+	w.decoded = make(chan struct{})
+
+	go func() {
+		defer close(w.decoded)
+
+		reader, err := decode(pr)
+		if err == nil {
+			// One byte more than the maximum size, to tell a larger body apart.
+			var n int64
+			n, err = io.Copy(&w.decodedBody, io.LimitReader(reader, w.policy.maxDecodedBodyBytes+1))
+			if err == nil && n > w.policy.maxDecodedBodyBytes {
+				err = errDecodedBodyTooLarge
+			}
+			reader.Close()
+		}
+		w.decodeErr = err
+
+		// Unblocks the writes of the remaining body, if any.
+		pr.CloseWithError(err)
+	}()
+}
+
+// writeDecoded writes the response with the decoded body, or a 413 when the decoded body is too large.
+func (w *backendCompressionResponseWriter) writeDecoded() {
+	if w.decodeErr == errDecodedBodyTooLarge {
+		log.Debugf("The decoded response of frontend %s for %s is larger than %d bytes", w.policy.frontendName, w.req.URL.Path, w.policy.maxDecodedBodyBytes)
+
+		for name := range w.Header() {
+			w.Header().Del(name)
+		}
+		http.Error(w.ResponseWriter, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if w.decodeErr != nil {
+		log.Debugf("Error while decoding the response of frontend %s: %v", w.policy.frontendName, w.decodeErr)
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(w.decodedBody.Len()))
+	w.ResponseWriter.WriteHeader(w.code)
+
+	if _, err := w.ResponseWriter.Write(w.decodedBody.Bytes()); err != nil {
+		log.Debugf("Error while writing the decoded response of frontend %s: %v", w.policy.frontendName, err)
+	}
+}
+
+func (w *backendCompressionResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.writer.Write(b)
+}
+
+// Flush flushes the compressor, or the underlying ResponseWriter when the body is passed through.
+// It is a no-op while the body is decoded.
+func (w *backendCompressionResponseWriter) Flush() {
+	if w.compressor != nil {
+		w.compressor.Flush()
+		return
+	}
+
+	if w.pipe != nil {
+		return
+	}
+
+	if fw, ok := w.ResponseWriter.(http.Flusher); ok {
+		fw.Flush()
+	}
+}
+
+// Hijack hijacks the connection of the underlying ResponseWriter.
+func (w *backendCompressionResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+}
+
+func (w *backendCompressionResponseWriter) close() error {
+	if w.compressor != nil {
+		return w.compressor.Close()
+	}
+
+	if w.pipe != nil {
+		err := w.pipe.Close()
+		<-w.decoded
+		w.writeDecoded()
+		return err
+	}
+
+	return nil
+}
+
+// bodyAllowed returns whether the response to the request can have a body.
+func bodyAllowed(req *http.Request, code int) bool {
+	if req.Method == http.MethodHead {
+		return false
+	}
+
+	return code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified
+}
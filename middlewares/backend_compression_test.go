@@ -0,0 +1,297 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBackendCompression(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		config      *types.BackendCompression
+		expectedErr bool
+	}{
+		{
+			desc:   "require",
+			config: &types.BackendCompression{Policy: "require"},
+		},
+		{
+			desc:   "forbid",
+			config: &types.BackendCompression{Policy: "Forbid"},
+		},
+		{
+			desc:        "invalid policy",
+			config:      &types.BackendCompression{Policy: "foo"},
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid encoding",
+			config:      &types.BackendCompression{Policy: "require", Encodings: []string{"foo"}},
+			expectedErr: true,
+		},
+		{
+			desc:        "negative max decoded body bytes",
+			config:      &types.BackendCompression{Policy: "forbid", MaxDecodedBodyBytes: -1},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewBackendCompression("frontend", test.config)
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBackendCompressionRequire(t *testing.T) {
+	body := []byte(strings.Repeat(`{"traefik":"compress"}`, 100))
+	compressed := gzipBytes(t, body)
+
+	testCases := []struct {
+		desc             string
+		acceptEncoding   string
+		backendEncoding  string
+		backendBody      []byte
+		expectedEncoding string
+		expectedBody     []byte
+	}{
+		{
+			desc:             "compressed by the backend",
+			acceptEncoding:   "gzip",
+			backendEncoding:  "gzip",
+			backendBody:      compressed,
+			expectedEncoding: "gzip",
+			expectedBody:     compressed,
+		},
+		{
+			desc:             "compressed by Traefik",
+			acceptEncoding:   "gzip",
+			backendBody:      body,
+			expectedEncoding: "gzip",
+			expectedBody:     compressed,
+		},
+		{
+			desc:         "compression not accepted",
+			backendBody:  body,
+			expectedBody: body,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			backendCompression, err := NewBackendCompression("frontend", &types.BackendCompression{Policy: BackendCompressionRequire, Encodings: []string{EncodingGzip}})
+			require.NoError(t, err)
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil)
+			if len(test.acceptEncoding) > 0 {
+				req.Header.Set(acceptEncodingHeader, test.acceptEncoding)
+			}
+
+			rw := httptest.NewRecorder()
+			backendCompression.ServeHTTP(rw, req, func(rw http.ResponseWriter, r *http.Request) {
+				if len(test.backendEncoding) > 0 {
+					rw.Header().Set(contentEncodingHeader, test.backendEncoding)
+				}
+				rw.Header().Set("Content-Length", strconv.Itoa(len(test.backendBody)))
+				rw.WriteHeader(http.StatusOK)
+				rw.Write(test.backendBody)
+			})
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, test.expectedEncoding, rw.Header().Get(contentEncodingHeader))
+			assert.Equal(t, test.expectedBody, rw.Body.Bytes())
+		})
+	}
+}
+
+func TestBackendCompressionForbid(t *testing.T) {
+	body := []byte(strings.Repeat(`{"traefik":"compress"}`, 100))
+
+	var zstdBody bytes.Buffer
+	zstdWriter, err := zstd.NewWriter(&zstdBody)
+	require.NoError(t, err)
+	zstdWriter.Write(body)
+	require.NoError(t, zstdWriter.Close())
+
+	var brotliBody bytes.Buffer
+	brotliWriter := brotli.NewWriter(&brotliBody)
+	brotliWriter.Write(body)
+	require.NoError(t, brotliWriter.Close())
+
+	testCases := []struct {
+		desc             string
+		backendEncoding  string
+		backendBody      []byte
+		expectedEncoding string
+		expectedBody     []byte
+	}{
+		{
+			desc:         "uncompressed by the backend",
+			backendBody:  body,
+			expectedBody: body,
+		},
+		{
+			desc:            "gzip",
+			backendEncoding: "gzip",
+			backendBody:     gzipBytes(t, body),
+			expectedBody:    body,
+		},
+		{
+			desc:            "zstd",
+			backendEncoding: "zstd",
+			backendBody:     zstdBody.Bytes(),
+			expectedBody:    body,
+		},
+		{
+			desc:            "brotli",
+			backendEncoding: "br",
+			backendBody:     brotliBody.Bytes(),
+			expectedBody:    body,
+		},
+		{
+			desc:             "unsupported encoding",
+			backendEncoding:  "compress",
+			backendBody:      []byte("foo"),
+			expectedEncoding: "compress",
+			expectedBody:     []byte("foo"),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			backendCompression, err := NewBackendCompression("frontend", &types.BackendCompression{Policy: BackendCompressionForbid})
+			require.NoError(t, err)
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil)
+			req.Header.Set(acceptEncodingHeader, "gzip, br, zstd")
+
+			rw := httptest.NewRecorder()
+			backendCompression.ServeHTTP(rw, req, func(rw http.ResponseWriter, r *http.Request) {
+				if len(test.backendEncoding) > 0 {
+					rw.Header().Set(contentEncodingHeader, test.backendEncoding)
+				}
+				rw.Header().Set("Content-Length", strconv.Itoa(len(test.backendBody)))
+				// Written in two parts, to decode a streamed body.
+				rw.Write(test.backendBody[:len(test.backendBody)/2])
+				rw.Write(test.backendBody[len(test.backendBody)/2:])
+			})
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, test.expectedEncoding, rw.Header().Get(contentEncodingHeader))
+			assert.Equal(t, test.expectedBody, rw.Body.Bytes())
+
+			if len(test.backendEncoding) > 0 && len(test.expectedEncoding) == 0 {
+				assert.Equal(t, strconv.Itoa(len(test.expectedBody)), rw.Header().Get("Content-Length"))
+			}
+		})
+	}
+}
+
+func TestBackendCompressionForbidMaxDecodedBodyBytes(t *testing.T) {
+	body := []byte(strings.Repeat("a", 1024))
+
+	testCases := []struct {
+		desc                string
+		maxDecodedBodyBytes int64
+		expectedCode        int
+		expectedBody        []byte
+	}{
+		{
+			desc:                "decoded body of the maximum size",
+			maxDecodedBodyBytes: 1024,
+			expectedCode:        http.StatusOK,
+			expectedBody:        body,
+		},
+		{
+			desc:                "decoded body too large",
+			maxDecodedBodyBytes: 1023,
+			expectedCode:        http.StatusRequestEntityTooLarge,
+			expectedBody:        []byte("Request Entity Too Large\n"),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			backendCompression, err := NewBackendCompression("frontend", &types.BackendCompression{
+				Policy:              BackendCompressionForbid,
+				MaxDecodedBodyBytes: test.maxDecodedBodyBytes,
+			})
+			require.NoError(t, err)
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil)
+
+			rw := httptest.NewRecorder()
+			backendCompression.ServeHTTP(rw, req, func(rw http.ResponseWriter, r *http.Request) {
+				rw.Header().Set(contentEncodingHeader, "gzip")
+				rw.Header().Set("X-Backend", "backend")
+				rw.Write(gzipBytes(t, body))
+			})
+
+			assert.Equal(t, test.expectedCode, rw.Code)
+			assert.Equal(t, test.expectedBody, rw.Body.Bytes())
+			assert.Empty(t, rw.Header().Get(contentEncodingHeader))
+			if test.expectedCode != http.StatusOK {
+				assert.Empty(t, rw.Header().Get("X-Backend"))
+			}
+		})
+	}
+}
+
+func TestBackendCompressionNoBody(t *testing.T) {
+	backendCompression, err := NewBackendCompression("frontend", &types.BackendCompression{Policy: BackendCompressionForbid})
+	require.NoError(t, err)
+
+	req := testhelpers.MustNewRequest(http.MethodHead, "http://localhost", nil)
+
+	rw := httptest.NewRecorder()
+	backendCompression.ServeHTTP(rw, req, func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set(contentEncodingHeader, "gzip")
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	assert.Equal(t, "gzip", rw.Header().Get(contentEncodingHeader))
+}
+
+func gzipBytes(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err := writer.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	compressed, err := ioutil.ReadAll(&buf)
+	require.NoError(t, err)
+	return compressed
+}
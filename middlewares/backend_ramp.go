@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/mailgun/timetools"
+)
+
+// BackendRamp forwards a growing share of the requests to the ramp backend, and the others to the next handler.
+// The share is interpolated linearly between the start and the end of the ramp, and evaluated for each request,
+// so no reload is needed during the migration.
+// Its ramp handler is set once all the backends are built.
+// The ramp can be switched over to one of its backends with BackendRampOverrides.
+type BackendRamp struct {
+	backendName  string
+	start        time.Time
+	end          time.Time
+	weightGauge  gokitmetrics.Gauge
+	clock        timetools.TimeProvider
+	handler      http.Handler
+	overrides    *BackendRampOverrides
+	providerName string
+	frontendName string
+}
+
+// NewBackendRamp creates a new BackendRamp for the frontend.
+// The prefix of the backend name identifies the handler of the backend given to PostLoad.
+// The weight gauge, labelled with the frontend and the backend, is optional.
+func NewBackendRamp(config *types.BackendRamp, frontendName string, backendNamePrefix string, weightGauge gokitmetrics.Gauge) (*BackendRamp, error) {
+	return newBackendRamp(config, frontendName, backendNamePrefix, weightGauge, &timetools.RealTime{})
+}
+
+func newBackendRamp(config *types.BackendRamp, frontendName string, backendNamePrefix string, weightGauge gokitmetrics.Gauge, clock timetools.TimeProvider) (*BackendRamp, error) {
+	if len(config.Backend) == 0 {
+		return nil, errors.New("no backend")
+	}
+
+	start, err := time.Parse(time.RFC3339, config.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start %q, must be a RFC 3339 time", config.Start)
+	}
+
+	end, err := time.Parse(time.RFC3339, config.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end %q, must be a RFC 3339 time", config.End)
+	}
+
+	if !end.After(start) {
+		return nil, fmt.Errorf("the end %s must be after the start %s", config.End, config.Start)
+	}
+
+	ramp := &BackendRamp{
+		backendName:  backendNamePrefix + config.Backend,
+		start:        start,
+		end:          end,
+		clock:        clock,
+		frontendName: frontendName,
+	}
+
+	if weightGauge != nil {
+		ramp.weightGauge = weightGauge.With("frontend", frontendName, "backend", config.Backend)
+	}
+
+	return ramp, nil
+}
+
+// PostLoad sets the handler of the ramp backend, from the handlers by backend name.
+// Without it, the share of the requests of the ramp backend gets 503 responses.
+func (b *BackendRamp) PostLoad(handlers map[string]http.Handler) error {
+	handler, ok := handlers[b.backendName]
+	if !ok {
+		return fmt.Errorf("ramp backend %s not found", b.backendName)
+	}
+
+	b.handler = handler
+	return nil
+}
+
+// UseOverrides makes the ramp switch over to the variant of its frontend in the overrides, if any.
+func (b *BackendRamp) UseOverrides(overrides *BackendRampOverrides, providerName string) {
+	b.overrides = overrides
+	b.providerName = providerName
+}
+
+func (b *BackendRamp) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	weight := b.weight()
+
+	if b.weightGauge != nil {
+		b.weightGauge.Set(weight)
+	}
+
+	if weight < 1 && rand.Float64() >= weight {
+		next.ServeHTTP(rw, req)
+		return
+	}
+
+	if b.handler == nil {
+		log.Debugf("Backend of ramp %s not found", b.backendName)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		if _, err := rw.Write([]byte(http.StatusText(http.StatusServiceUnavailable))); err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	b.handler.ServeHTTP(rw, req)
+}
+
+// weight returns the current share of the requests of the ramp backend, between 0 and 1.
+func (b *BackendRamp) weight() float64 {
+	if b.overrides != nil {
+		switch b.overrides.Get(b.providerName, b.frontendName) {
+		case BackendRampVariantBackend:
+			return 0
+		case BackendRampVariantRamp:
+			return 1
+		}
+	}
+
+	now := b.clock.UtcNow()
+
+	switch {
+	case now.Before(b.start):
+		return 0
+	case !now.Before(b.end):
+		return 1
+	default:
+		return float64(now.Sub(b.start)) / float64(b.end.Sub(b.start))
+	}
+}
@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"fmt"
+	"sync"
+)
+
+// The backends a backend ramp can be switched over to.
+const (
+	// BackendRampVariantBackend forwards all the requests to the frontend backend.
+	BackendRampVariantBackend = "backend"
+	// BackendRampVariantRamp forwards all the requests to the ramp backend.
+	BackendRampVariantRamp = "ramp"
+)
+
+// BackendRampOverrides holds the backend ramps switched over to one of their backends (blue/green switchover),
+// by provider and frontend, until they are reset or the configuration of their provider is reloaded.
+type BackendRampOverrides struct {
+	lock     sync.RWMutex
+	variants map[string]map[string]string
+}
+
+// NewBackendRampOverrides creates a new BackendRampOverrides.
+func NewBackendRampOverrides() *BackendRampOverrides {
+	return &BackendRampOverrides{variants: make(map[string]map[string]string)}
+}
+
+// Set switches the backend ramp of a frontend over to a variant.
+func (o *BackendRampOverrides) Set(providerName, frontendName, variant string) error {
+	if variant != BackendRampVariantBackend && variant != BackendRampVariantRamp {
+		return fmt.Errorf("invalid variant %q, expected %q or %q", variant, BackendRampVariantBackend, BackendRampVariantRamp)
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.variants[providerName] == nil {
+		o.variants[providerName] = make(map[string]string)
+	}
+	o.variants[providerName][frontendName] = variant
+	return nil
+}
+
+// Get returns the variant the backend ramp of a frontend is switched over to, if any.
+func (o *BackendRampOverrides) Get(providerName, frontendName string) string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return o.variants[providerName][frontendName]
+}
+
+// Delete resets the backend ramp of a frontend.
+func (o *BackendRampOverrides) Delete(providerName, frontendName string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.variants[providerName], frontendName)
+	if len(o.variants[providerName]) == 0 {
+		delete(o.variants, providerName)
+	}
+}
+
+// Reset resets the backend ramps of the frontends of a provider.
+func (o *BackendRampOverrides) Reset(providerName string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.variants, providerName)
+}
+
+// All returns a copy of the variants, by provider and frontend.
+func (o *BackendRampOverrides) All() map[string]map[string]string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	variants := make(map[string]map[string]string, len(o.variants))
+	for providerName, frontends := range o.variants {
+		variants[providerName] = make(map[string]string, len(frontends))
+		for frontendName, variant := range frontends {
+			variants[providerName][frontendName] = variant
+		}
+	}
+	return variants
+}
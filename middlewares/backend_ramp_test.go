@@ -0,0 +1,199 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/mailgun/timetools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBackendRampInvalid(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.BackendRamp
+	}{
+		{
+			desc:   "no backend",
+			config: &types.BackendRamp{Start: "2018-10-01T00:00:00Z", End: "2018-10-08T00:00:00Z"},
+		},
+		{
+			desc:   "invalid start",
+			config: &types.BackendRamp{Backend: "backend2", Start: "2018-10-01", End: "2018-10-08T00:00:00Z"},
+		},
+		{
+			desc:   "invalid end",
+			config: &types.BackendRamp{Backend: "backend2", Start: "2018-10-01T00:00:00Z"},
+		},
+		{
+			desc:   "end before start",
+			config: &types.BackendRamp{Backend: "backend2", Start: "2018-10-08T00:00:00Z", End: "2018-10-01T00:00:00Z"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewBackendRamp(test.config, "frontend1", "", nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBackendRamp(t *testing.T) {
+	config := &types.BackendRamp{
+		Backend: "new",
+		Start:   "2018-10-01T00:00:00+02:00",
+		End:     "2018-10-08T00:00:00+02:00",
+	}
+
+	testCases := []struct {
+		desc             string
+		now              time.Time
+		expectedWeight   float64
+		expectedBackends []string
+	}{
+		{
+			desc:             "before the start",
+			now:              time.Date(2018, 9, 30, 21, 59, 0, 0, time.UTC),
+			expectedWeight:   0,
+			expectedBackends: []string{"old"},
+		},
+		{
+			desc:             "middle of the ramp",
+			now:              time.Date(2018, 10, 4, 10, 0, 0, 0, time.UTC),
+			expectedWeight:   0.5,
+			expectedBackends: []string{"old", "new"},
+		},
+		{
+			desc:             "end of the ramp",
+			now:              time.Date(2018, 10, 7, 22, 0, 0, 0, time.UTC),
+			expectedWeight:   1,
+			expectedBackends: []string{"new"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			gauge := &testhelpers.CollectingGauge{}
+			ramp, err := newBackendRamp(config, "frontend1", "http", gauge, &timetools.FreezedTime{CurrentTime: test.now})
+			require.NoError(t, err)
+
+			err = ramp.PostLoad(map[string]http.Handler{"httpnew": backendNameHandler("new")})
+			require.NoError(t, err)
+
+			backends := make(map[string]int)
+			for i := 0; i < 1000; i++ {
+				rw := httptest.NewRecorder()
+				ramp.ServeHTTP(rw, testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil), backendNameHandler("old"))
+				backends[rw.Body.String()]++
+			}
+
+			assert.Len(t, backends, len(test.expectedBackends))
+			for _, backend := range test.expectedBackends {
+				assert.InDelta(t, 1000/len(test.expectedBackends), backends[backend], 100, backend)
+			}
+
+			assert.Equal(t, test.expectedWeight, gauge.GaugeValue)
+			assert.Equal(t, []string{"frontend", "frontend1", "backend", "new"}, gauge.LastLabelValues)
+		})
+	}
+}
+
+func TestBackendRampMissingBackend(t *testing.T) {
+	config := &types.BackendRamp{
+		Backend: "missing",
+		Start:   "2018-10-01T00:00:00Z",
+		End:     "2018-10-08T00:00:00Z",
+	}
+
+	ramp, err := newBackendRamp(config, "frontend1", "", nil, &timetools.FreezedTime{CurrentTime: time.Date(2018, 10, 9, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+
+	err = ramp.PostLoad(map[string]http.Handler{})
+	assert.Error(t, err)
+
+	rw := httptest.NewRecorder()
+	ramp.ServeHTTP(rw, testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil), backendNameHandler("old"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+}
+
+func TestBackendRampOverrides(t *testing.T) {
+	config := &types.BackendRamp{
+		Backend: "new",
+		Start:   "2018-10-01T00:00:00Z",
+		End:     "2018-10-08T00:00:00Z",
+	}
+
+	gauge := &testhelpers.CollectingGauge{}
+	ramp, err := newBackendRamp(config, "frontend1", "http", gauge, &timetools.FreezedTime{CurrentTime: time.Date(2018, 10, 4, 12, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+
+	err = ramp.PostLoad(map[string]http.Handler{"httpnew": backendNameHandler("new")})
+	require.NoError(t, err)
+
+	overrides := NewBackendRampOverrides()
+	ramp.UseOverrides(overrides, "file")
+
+	serve := func() string {
+		rw := httptest.NewRecorder()
+		ramp.ServeHTTP(rw, testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil), backendNameHandler("old"))
+		return rw.Body.String()
+	}
+
+	require.NoError(t, overrides.Set("file", "frontend1", BackendRampVariantRamp))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, "new", serve())
+	}
+	assert.Equal(t, float64(1), gauge.GaugeValue)
+
+	require.NoError(t, overrides.Set("file", "frontend1", BackendRampVariantBackend))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, "old", serve())
+	}
+	assert.Equal(t, float64(0), gauge.GaugeValue)
+
+	// The overrides of the other frontends are ignored.
+	require.NoError(t, overrides.Set("file", "frontend2", BackendRampVariantRamp))
+	overrides.Delete("file", "frontend1")
+	serve()
+	assert.Equal(t, 0.5, gauge.GaugeValue)
+}
+
+func TestBackendRampOverridesVariants(t *testing.T) {
+	overrides := NewBackendRampOverrides()
+
+	assert.Error(t, overrides.Set("file", "frontend1", "green"))
+	assert.Empty(t, overrides.Get("file", "frontend1"))
+
+	require.NoError(t, overrides.Set("file", "frontend1", BackendRampVariantRamp))
+	require.NoError(t, overrides.Set("file", "frontend2", BackendRampVariantBackend))
+	require.NoError(t, overrides.Set("docker", "frontend1", BackendRampVariantBackend))
+
+	all := overrides.All()
+	assert.Equal(t, map[string]map[string]string{
+		"file":   {"frontend1": BackendRampVariantRamp, "frontend2": BackendRampVariantBackend},
+		"docker": {"frontend1": BackendRampVariantBackend},
+	}, all)
+
+	// The copy is not changed by the overrides.
+	overrides.Reset("file")
+	assert.Len(t, all["file"], 2)
+
+	assert.Empty(t, overrides.Get("file", "frontend1"))
+	assert.Equal(t, BackendRampVariantBackend, overrides.Get("docker", "frontend1"))
+
+	overrides.Delete("docker", "frontend1")
+	assert.Empty(t, overrides.All())
+}
@@ -0,0 +1,212 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/mailgun/timetools"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// BackendSchedule forwards the requests to the backend of the first matching time window, by window name,
+// or to the next handler outside of the windows.
+// The windows are recurring, and evaluated for each request, so no reload is needed when a window starts or ends.
+// Its window handlers are set once all the backends are built.
+type BackendSchedule struct {
+	location *time.Location
+	windows  []*scheduleWindow
+	clock    timetools.TimeProvider
+}
+
+type scheduleWindow struct {
+	name        string
+	backendName string
+	days        map[time.Weekday]bool
+	start       int
+	end         int
+	handler     http.Handler
+}
+
+// NewBackendSchedule creates a new BackendSchedule.
+// The prefix of the backend names identifies the handlers of the backends given to PostLoad.
+func NewBackendSchedule(config *types.BackendSchedule, backendNamePrefix string) (*BackendSchedule, error) {
+	return newBackendSchedule(config, backendNamePrefix, &timetools.RealTime{})
+}
+
+func newBackendSchedule(config *types.BackendSchedule, backendNamePrefix string, clock timetools.TimeProvider) (*BackendSchedule, error) {
+	location := time.Local
+	if len(config.Timezone) > 0 {
+		var err error
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %v", config.Timezone, err)
+		}
+	}
+
+	schedule := &BackendSchedule{location: location, clock: clock}
+
+	for name, config := range config.Windows {
+		if config == nil {
+			continue
+		}
+
+		window, err := newScheduleWindow(name, config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule window %s: %v", name, err)
+		}
+
+		window.backendName = backendNamePrefix + config.Backend
+		schedule.windows = append(schedule.windows, window)
+	}
+
+	sort.Slice(schedule.windows, func(i, j int) bool {
+		return schedule.windows[i].name < schedule.windows[j].name
+	})
+
+	return schedule, nil
+}
+
+func newScheduleWindow(name string, config *types.ScheduleWindow) (*scheduleWindow, error) {
+	if len(config.Backend) == 0 {
+		return nil, errors.New("no backend")
+	}
+
+	start, err := parseTimeOfDay(config.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := parseTimeOfDay(config.End)
+	if err != nil {
+		return nil, err
+	}
+
+	window := &scheduleWindow{
+		name:  name,
+		days:  make(map[time.Weekday]bool),
+		start: start,
+		end:   end,
+	}
+
+	for _, day := range config.Days {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		window.days[weekday] = true
+	}
+
+	if len(window.days) == 0 {
+		for _, weekday := range weekdays {
+			window.days[weekday] = true
+		}
+	}
+
+	return window, nil
+}
+
+// parseTimeOfDay returns the minutes since midnight of a HH:MM time, 0 if empty.
+func parseTimeOfDay(value string) (int, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, must be HH:MM", value)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseWeekday parses a day, abbreviated (mon) or not (monday).
+func parseWeekday(day string) (time.Weekday, bool) {
+	day = strings.ToLower(strings.TrimSpace(day))
+	if len(day) < 3 {
+		return 0, false
+	}
+
+	weekday, ok := weekdays[day[:3]]
+	if !ok || (len(day) > 3 && day != strings.ToLower(weekday.String())) {
+		return 0, false
+	}
+
+	return weekday, true
+}
+
+// PostLoad sets the handlers of the window backends, from the handlers by backend name.
+// The windows of the missing backends get 503 responses.
+func (b *BackendSchedule) PostLoad(handlers map[string]http.Handler) error {
+	var missing []string
+	for _, window := range b.windows {
+		handler, ok := handlers[window.backendName]
+		if !ok {
+			missing = append(missing, window.backendName)
+			continue
+		}
+		window.handler = handler
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("schedule backends %s not found", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (b *BackendSchedule) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	window := b.currentWindow()
+	if window == nil {
+		next.ServeHTTP(rw, req)
+		return
+	}
+
+	if window.handler == nil {
+		log.Debugf("Backend of schedule window %s not found", window.name)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		if _, err := rw.Write([]byte(http.StatusText(http.StatusServiceUnavailable))); err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	window.handler.ServeHTTP(rw, req)
+}
+
+func (b *BackendSchedule) currentWindow() *scheduleWindow {
+	now := b.clock.UtcNow().In(b.location)
+	minute := now.Hour()*60 + now.Minute()
+	weekday := now.Weekday()
+
+	for _, window := range b.windows {
+		if window.contains(weekday, minute) {
+			return window
+		}
+	}
+	return nil
+}
+
+// contains returns whether the window contains the minute of the day.
+// A window ending the next day is checked from its start on its days, and until its end on the following days.
+func (w *scheduleWindow) contains(weekday time.Weekday, minute int) bool {
+	if w.start < w.end {
+		return w.days[weekday] && minute >= w.start && minute < w.end
+	}
+
+	previous := (weekday + 6) % 7
+	return (w.days[weekday] && minute >= w.start) || (w.days[previous] && minute < w.end)
+}
@@ -0,0 +1,147 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/mailgun/timetools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBackendScheduleInvalid(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.BackendSchedule
+	}{
+		{
+			desc:   "invalid time zone",
+			config: &types.BackendSchedule{Timezone: "Foo/Bar"},
+		},
+		{
+			desc: "no backend",
+			config: &types.BackendSchedule{Windows: map[string]*types.ScheduleWindow{
+				"night": {Start: "22:00"},
+			}},
+		},
+		{
+			desc: "invalid start",
+			config: &types.BackendSchedule{Windows: map[string]*types.ScheduleWindow{
+				"night": {Backend: "backend2", Start: "10pm"},
+			}},
+		},
+		{
+			desc: "invalid day",
+			config: &types.BackendSchedule{Windows: map[string]*types.ScheduleWindow{
+				"night": {Backend: "backend2", Days: []string{"mondays"}},
+			}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewBackendSchedule(test.config, "")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBackendSchedule(t *testing.T) {
+	config := &types.BackendSchedule{
+		Timezone: "Europe/Paris",
+		Windows: map[string]*types.ScheduleWindow{
+			"a-night":   {Backend: "night", Start: "22:00", End: "06:00"},
+			"b-weekend": {Backend: "weekend", Days: []string{"sat", "Sunday"}},
+		},
+	}
+
+	testCases := []struct {
+		desc            string
+		now             time.Time
+		expectedBackend string
+	}{
+		{
+			desc:            "outside of the windows",
+			now:             time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+			expectedBackend: "default",
+		},
+		{
+			desc:            "start of the night in the time zone",
+			now:             time.Date(2018, 1, 1, 21, 0, 0, 0, time.UTC),
+			expectedBackend: "night",
+		},
+		{
+			desc:            "night after midnight",
+			now:             time.Date(2018, 1, 2, 4, 59, 0, 0, time.UTC),
+			expectedBackend: "night",
+		},
+		{
+			desc:            "end of the night",
+			now:             time.Date(2018, 1, 2, 5, 0, 0, 0, time.UTC),
+			expectedBackend: "default",
+		},
+		{
+			desc:            "weekend",
+			now:             time.Date(2018, 1, 6, 12, 0, 0, 0, time.UTC),
+			expectedBackend: "weekend",
+		},
+		{
+			desc:            "night of the weekend, first window by name",
+			now:             time.Date(2018, 1, 7, 23, 0, 0, 0, time.UTC),
+			expectedBackend: "night",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			schedule, err := newBackendSchedule(config, "http", &timetools.FreezedTime{CurrentTime: test.now})
+			require.NoError(t, err)
+
+			err = schedule.PostLoad(map[string]http.Handler{
+				"httpnight":   backendNameHandler("night"),
+				"httpweekend": backendNameHandler("weekend"),
+			})
+			require.NoError(t, err)
+
+			rw := httptest.NewRecorder()
+			schedule.ServeHTTP(rw, testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil), backendNameHandler("default"))
+
+			assert.Equal(t, test.expectedBackend, rw.Body.String())
+		})
+	}
+}
+
+func TestBackendScheduleMissingBackend(t *testing.T) {
+	config := &types.BackendSchedule{
+		Windows: map[string]*types.ScheduleWindow{
+			"always": {Backend: "missing"},
+		},
+	}
+
+	schedule, err := NewBackendSchedule(config, "")
+	require.NoError(t, err)
+
+	err = schedule.PostLoad(map[string]http.Handler{})
+	assert.Error(t, err)
+
+	rw := httptest.NewRecorder()
+	schedule.ServeHTTP(rw, testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil), backendNameHandler("default"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+}
+
+func backendNameHandler(name string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(name))
+	}
+}
@@ -0,0 +1,82 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// Version labels of the backend versions outside of the allowed ones.
+const (
+	backendVersionOther = "other"
+	backendVersionNone  = "none"
+)
+
+// BackendVersionListener is notified of the version of the backend responses.
+type BackendVersionListener interface {
+	// BackendVersion is called with the request forwarded to the backend, when its response has a version.
+	BackendVersion(req *http.Request, version string)
+}
+
+// BackendVersionRecorder records the version of the backend from a header of its responses,
+// to its listener (e.g. the access log) and, for the allowed versions only to bound the cardinality, in the metrics.
+type BackendVersionRecorder struct {
+	backendName   string
+	header        string
+	listener      BackendVersionListener
+	versions      map[string]bool
+	versionsCount gokitmetrics.Counter
+}
+
+// NewBackendVersionRecorder creates a new BackendVersionRecorder.
+// The listener is optional, and the counter is only used if versions are allowed.
+func NewBackendVersionRecorder(backendName string, config *types.BackendVersion, listener BackendVersionListener, versionsCount gokitmetrics.Counter) (*BackendVersionRecorder, error) {
+	if len(config.Header) == 0 {
+		return nil, errors.New("missing header")
+	}
+
+	recorder := &BackendVersionRecorder{
+		backendName: backendName,
+		header:      http.CanonicalHeaderKey(config.Header),
+		listener:    listener,
+	}
+
+	if len(config.MetricValues) > 0 && versionsCount != nil {
+		recorder.versionsCount = versionsCount
+		recorder.versions = make(map[string]bool)
+		for _, version := range config.MetricValues {
+			recorder.versions[version] = true
+		}
+	}
+
+	return recorder, nil
+}
+
+// ModifyResponseHeaders records the version of the backend response.
+func (b *BackendVersionRecorder) ModifyResponseHeaders(res *http.Response) error {
+	version := res.Header.Get(b.header)
+
+	if b.listener != nil && res.Request != nil && len(version) > 0 {
+		b.listener.BackendVersion(res.Request, version)
+	}
+
+	if b.versionsCount != nil {
+		b.versionsCount.With("backend", b.backendName, "version", b.versionLabel(version), "code", strconv.Itoa(res.StatusCode)).Add(1)
+	}
+
+	return nil
+}
+
+func (b *BackendVersionRecorder) versionLabel(version string) string {
+	switch {
+	case len(version) == 0:
+		return backendVersionNone
+	case b.versions[version]:
+		return version
+	default:
+		return backendVersionOther
+	}
+}
@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type versionListener struct {
+	version string
+}
+
+func (l *versionListener) BackendVersion(req *http.Request, version string) {
+	l.version = version
+}
+
+func TestNewBackendVersionRecorderMissingHeader(t *testing.T) {
+	_, err := NewBackendVersionRecorder("backend1", &types.BackendVersion{}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestBackendVersionRecorder(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		metricValues    []string
+		version         string
+		expectedVersion string
+		expectedLabels  []string
+	}{
+		{
+			desc:            "allowed version",
+			metricValues:    []string{"1.2.0", "1.3.0"},
+			version:         "1.3.0",
+			expectedVersion: "1.3.0",
+			expectedLabels:  []string{"backend", "backend1", "version", "1.3.0", "code", "200"},
+		},
+		{
+			desc:            "unknown version",
+			metricValues:    []string{"1.2.0", "1.3.0"},
+			version:         "1.4.0-rc1",
+			expectedVersion: "1.4.0-rc1",
+			expectedLabels:  []string{"backend", "backend1", "version", "other", "code", "200"},
+		},
+		{
+			desc:           "no version",
+			metricValues:   []string{"1.2.0"},
+			expectedLabels: []string{"backend", "backend1", "version", "none", "code", "200"},
+		},
+		{
+			desc:            "no metric",
+			version:         "1.3.0",
+			expectedVersion: "1.3.0",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			listener := &versionListener{}
+			counter := &testhelpers.CollectingCounter{}
+
+			recorder, err := NewBackendVersionRecorder("backend1", &types.BackendVersion{Header: "x-app-version", MetricValues: test.metricValues}, listener, counter)
+			require.NoError(t, err)
+
+			res := &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     make(http.Header),
+				Request:    testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil),
+			}
+			if len(test.version) > 0 {
+				res.Header.Set("X-App-Version", test.version)
+			}
+
+			require.NoError(t, recorder.ModifyResponseHeaders(res))
+
+			assert.Equal(t, test.expectedVersion, listener.version)
+			assert.Equal(t, test.expectedLabels, counter.LastLabelValues)
+		})
+	}
+}
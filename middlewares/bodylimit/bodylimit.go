@@ -0,0 +1,166 @@
+package bodylimit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/containous/traefik/types"
+)
+
+// ErrBodyTooLarge is returned when reading a request body exceeding the limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// BodyLimit is a middleware rejecting the requests whose body exceeds a maximum size, without buffering it:
+// the body is streamed to the next handler, and a 413 response is returned as soon as the limit is crossed.
+type BodyLimit struct {
+	maxBytes           int64
+	trustContentLength bool
+}
+
+// New creates a new BodyLimit.
+func New(config *types.BodyLimit) (*BodyLimit, error) {
+	if config.MaxBytes <= 0 {
+		return nil, fmt.Errorf("invalid body limit maxBytes %d, must be greater than 0", config.MaxBytes)
+	}
+
+	return &BodyLimit{
+		maxBytes:           config.MaxBytes,
+		trustContentLength: config.TrustContentLength,
+	}, nil
+}
+
+func (b *BodyLimit) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if b.trustContentLength && req.ContentLength > b.maxBytes {
+		rejectRequest(rw)
+		return
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		next(rw, req)
+		return
+	}
+
+	body := &limitedBody{ReadCloser: req.Body, remaining: b.maxBytes}
+	req.Body = body
+
+	lrw := &limitResponseWriter{ResponseWriter: rw, body: body}
+	if _, ok := rw.(http.CloseNotifier); ok {
+		next(limitResponseWriterWithCloseNotify{lrw}, req)
+	} else {
+		next(lrw, req)
+	}
+
+	if !lrw.wroteHeader && body.isExceeded() {
+		rejectRequest(rw)
+	}
+}
+
+func rejectRequest(rw http.ResponseWriter) {
+	// The rest of the body is not read, the connection can't be reused.
+	rw.Header().Set("Connection", "close")
+	http.Error(rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+}
+
+// limitedBody counts the bytes read from the request body, and fails once the limit is crossed.
+// It may be read by the transport while the response is written, hence the atomic exceeded flag.
+type limitedBody struct {
+	io.ReadCloser
+
+	remaining int64
+	exceeded  int32
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.isExceeded() {
+		return 0, ErrBodyTooLarge
+	}
+
+	// Read one more byte than allowed, to detect the bodies exceeding the limit.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.ReadCloser.Read(p)
+	if int64(n) > l.remaining {
+		n = int(l.remaining)
+		l.remaining = 0
+		atomic.StoreInt32(&l.exceeded, 1)
+		return n, ErrBodyTooLarge
+	}
+
+	l.remaining -= int64(n)
+	return n, err
+}
+
+func (l *limitedBody) isExceeded() bool {
+	return atomic.LoadInt32(&l.exceeded) == 1
+}
+
+// limitResponseWriter replaces the response of the next handler with a 413 response
+// when the body limit was crossed before the response headers are written.
+type limitResponseWriter struct {
+	http.ResponseWriter
+	body *limitedBody
+
+	wroteHeader bool
+	rejected    bool
+}
+
+type limitResponseWriterWithCloseNotify struct {
+	*limitResponseWriter
+}
+
+func (w limitResponseWriterWithCloseNotify) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *limitResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.body.isExceeded() {
+		w.rejected = true
+		for name := range w.Header() {
+			w.Header().Del(name)
+		}
+		rejectRequest(w.ResponseWriter)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *limitResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.rejected {
+		// The response of the next handler is discarded.
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client.
+func (w *limitResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the connection.
+func (w *limitResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+}
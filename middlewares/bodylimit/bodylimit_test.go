@@ -0,0 +1,175 @@
+package bodylimit
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimit(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		body               string
+		chunked            bool
+		trustContentLength bool
+		expectedCode       int
+		expectedBody       string
+		expectedNextCalled bool
+	}{
+		{
+			desc:               "body under the limit",
+			body:               "0123456789",
+			expectedCode:       http.StatusOK,
+			expectedBody:       "0123456789",
+			expectedNextCalled: true,
+		},
+		{
+			desc:               "chunked body under the limit",
+			body:               "0123456789",
+			chunked:            true,
+			expectedCode:       http.StatusOK,
+			expectedBody:       "0123456789",
+			expectedNextCalled: true,
+		},
+		{
+			desc:               "body over the limit",
+			body:               "0123456789a",
+			expectedCode:       http.StatusRequestEntityTooLarge,
+			expectedBody:       "Request Entity Too Large\n",
+			expectedNextCalled: true,
+		},
+		{
+			desc:               "chunked body over the limit",
+			body:               "0123456789a",
+			chunked:            true,
+			expectedCode:       http.StatusRequestEntityTooLarge,
+			expectedBody:       "Request Entity Too Large\n",
+			expectedNextCalled: true,
+		},
+		{
+			desc:               "body over the limit with trusted content length",
+			body:               "0123456789a",
+			trustContentLength: true,
+			expectedCode:       http.StatusRequestEntityTooLarge,
+			expectedBody:       "Request Entity Too Large\n",
+		},
+		{
+			desc:               "chunked body over the limit with trusted content length",
+			body:               "0123456789a",
+			chunked:            true,
+			trustContentLength: true,
+			expectedCode:       http.StatusRequestEntityTooLarge,
+			expectedBody:       "Request Entity Too Large\n",
+			expectedNextCalled: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			bodyLimit, err := New(&types.BodyLimit{MaxBytes: 10, TrustContentLength: test.trustContentLength})
+			require.NoError(t, err)
+
+			var body io.Reader = strings.NewReader(test.body)
+			if test.chunked {
+				// Hide the length of the body.
+				body = ioutil.NopCloser(body)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "http://localhost", body)
+			if test.chunked {
+				req.ContentLength = -1
+			}
+
+			var nextCalled bool
+			recorder := httptest.NewRecorder()
+			bodyLimit.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+
+				data, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					assert.Equal(t, ErrBodyTooLarge, err)
+					http.Error(rw, err.Error(), http.StatusBadGateway)
+					return
+				}
+
+				rw.Write(data)
+			})
+
+			assert.Equal(t, test.expectedNextCalled, nextCalled)
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedBody, recorder.Body.String())
+			if test.expectedCode == http.StatusRequestEntityTooLarge {
+				assert.Equal(t, "close", recorder.Header().Get("Connection"))
+			}
+		})
+	}
+}
+
+func TestBodyLimitStreaming(t *testing.T) {
+	bodyLimit, err := New(&types.BodyLimit{MaxBytes: 10})
+	require.NoError(t, err)
+
+	reader, writer := io.Pipe()
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", reader)
+	req.ContentLength = -1
+
+	go func() {
+		// The body is sent in chunks, the limit is crossed by the second one.
+		for _, chunk := range []string{"01234", "56789a", "never read"} {
+			if _, err := writer.Write([]byte(chunk)); err != nil {
+				return
+			}
+		}
+		writer.Close()
+	}()
+
+	var read []byte
+	recorder := httptest.NewRecorder()
+	bodyLimit.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 5)
+		for {
+			n, err := r.Body.Read(buf)
+			read = append(read, buf[:n]...)
+			if err != nil {
+				assert.Equal(t, ErrBodyTooLarge, err)
+				break
+			}
+		}
+		reader.Close()
+	})
+
+	assert.Equal(t, "0123456789", string(read))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+}
+
+func TestBodyLimitResponseStarted(t *testing.T) {
+	bodyLimit, err := New(&types.BodyLimit{MaxBytes: 10})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader("0123456789a"))
+
+	recorder := httptest.NewRecorder()
+	bodyLimit.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+		// The response is written before reading the body.
+		rw.WriteHeader(http.StatusAccepted)
+		_, err := ioutil.ReadAll(r.Body)
+		assert.Equal(t, ErrBodyTooLarge, err)
+	})
+
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+}
+
+func TestNewBodyLimitFail(t *testing.T) {
+	_, err := New(&types.BodyLimit{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,212 @@
+package middlewares
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/log"
+)
+
+// DefaultStreamContentTypes are the content types of the streaming responses, which are not buffered by default.
+var DefaultStreamContentTypes = []string{"text/event-stream", "application/grpc"}
+
+type streamKey struct{}
+
+// ContentTypeBuffering selects, by content type, the responses buffered by the buffering handler,
+// the other ones being streamed through to the client.
+// The decision is made once the response headers are written, so a route can serve both kinds of responses.
+type ContentTypeBuffering struct {
+	buffering          http.Handler
+	bufferContentTypes []string
+	streamContentTypes []string
+}
+
+// NewContentTypeBuffering creates a new ContentTypeBuffering.
+// The buffering is built around the next handler with newBuffering.
+// The responses of the stream content types, the default ones if empty, are streamed,
+// as well as the ones not in the buffer content types, if any.
+func NewContentTypeBuffering(next http.Handler, bufferContentTypes []string, streamContentTypes []string,
+	newBuffering func(http.Handler) (http.Handler, error)) (*ContentTypeBuffering, error) {
+	if len(streamContentTypes) == 0 {
+		streamContentTypes = DefaultStreamContentTypes
+	}
+
+	c := &ContentTypeBuffering{
+		bufferContentTypes: normalizeContentTypes(bufferContentTypes),
+		streamContentTypes: normalizeContentTypes(streamContentTypes),
+	}
+
+	buffering, err := newBuffering(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		stream, ok := req.Context().Value(streamKey{}).(*streamResponseWriter)
+		if !ok {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		next.ServeHTTP(&contentTypeResponseWriter{ResponseWriter: rw, stream: stream, buffering: c}, req)
+	}))
+	if err != nil {
+		return nil, err
+	}
+	c.buffering = buffering
+
+	return c, nil
+}
+
+func (c *ContentTypeBuffering) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	stream := &streamResponseWriter{ResponseWriter: rw}
+	c.buffering.ServeHTTP(stream, req.WithContext(context.WithValue(req.Context(), streamKey{}, stream)))
+}
+
+func (c *ContentTypeBuffering) isStreamed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	if matchContentType(c.streamContentTypes, mediaType) {
+		return true
+	}
+
+	return len(c.bufferContentTypes) > 0 && !matchContentType(c.bufferContentTypes, mediaType)
+}
+
+func normalizeContentTypes(contentTypes []string) []string {
+	var normalized []string
+	for _, contentType := range contentTypes {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(contentType)))
+	}
+	return normalized
+}
+
+// matchContentType returns whether the media type is one of the content types,
+// with its structured syntax suffix (e.g. application/grpc+proto), or matches a type wildcard (e.g. text/*).
+func matchContentType(contentTypes []string, mediaType string) bool {
+	if len(mediaType) == 0 {
+		return false
+	}
+
+	for _, contentType := range contentTypes {
+		if mediaType == contentType || strings.HasPrefix(mediaType, contentType+"+") {
+			return true
+		}
+
+		if strings.HasSuffix(contentType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(contentType, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamResponseWriter is the response writer given to the buffering.
+// Once a response is streamed, it ignores the response written by the buffering.
+type streamResponseWriter struct {
+	http.ResponseWriter
+	streamed bool
+}
+
+func (w *streamResponseWriter) WriteHeader(code int) {
+	if w.streamed {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *streamResponseWriter) Write(b []byte) (int, error) {
+	if w.streamed {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *streamResponseWriter) Flush() {
+	if fw, ok := w.ResponseWriter.(http.Flusher); ok {
+		fw.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives at most a single value (true) when the client connection has gone away.
+func (w *streamResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(<-chan bool)
+}
+
+// Hijack hijacks the connection of the underlying ResponseWriter.
+func (w *streamResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+}
+
+// contentTypeResponseWriter is the response writer given to the next handler by the buffering,
+// which writes the streamed responses to the client instead of the buffering.
+type contentTypeResponseWriter struct {
+	http.ResponseWriter
+	stream    *streamResponseWriter
+	buffering *ContentTypeBuffering
+
+	wroteHeader bool
+}
+
+func (w *contentTypeResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	if code >= http.StatusOK && w.buffering.isStreamed(w.Header().Get("Content-Type")) {
+		log.Debugf("Streaming the %s response through the buffering", w.Header().Get("Content-Type"))
+
+		for name, values := range w.Header() {
+			w.stream.Header()[name] = values
+		}
+		w.stream.ResponseWriter.WriteHeader(code)
+		w.stream.streamed = true
+	} else {
+		w.ResponseWriter.WriteHeader(code)
+	}
+
+	// The informational responses are followed by the final one.
+	w.wroteHeader = code >= http.StatusOK
+}
+
+func (w *contentTypeResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.stream.streamed {
+		return w.ResponseWriter.Write(b)
+	}
+
+	n, err := w.stream.ResponseWriter.Write(b)
+	w.stream.Flush()
+	return n, err
+}
+
+// Flush flushes the streamed response, it is a no-op while the response is buffered.
+func (w *contentTypeResponseWriter) Flush() {
+	if w.stream.streamed {
+		w.stream.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives at most a single value (true) when the client connection has gone away.
+func (w *contentTypeResponseWriter) CloseNotify() <-chan bool {
+	return w.stream.CloseNotify()
+}
+
+// Hijack hijacks the connection through the buffering, which stops handling the response.
+func (w *contentTypeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+}
@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/buffer"
+)
+
+func TestContentTypeBuffering(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		bufferContentTypes []string
+		streamContentTypes []string
+		contentType        string
+		expectedStreamed   bool
+	}{
+		{
+			desc:        "buffered by default",
+			contentType: "application/json",
+		},
+		{
+			desc:             "server-sent events streamed by default",
+			contentType:      "text/event-stream; charset=utf-8",
+			expectedStreamed: true,
+		},
+		{
+			desc:             "gRPC streamed by default",
+			contentType:      "application/grpc+proto",
+			expectedStreamed: true,
+		},
+		{
+			desc:               "stream content types",
+			streamContentTypes: []string{"text/*"},
+			contentType:        "text/plain",
+			expectedStreamed:   true,
+		},
+		{
+			desc:               "stream content types replacing the default ones",
+			streamContentTypes: []string{"text/plain"},
+			contentType:        "text/event-stream",
+		},
+		{
+			desc:               "buffer content types",
+			bufferContentTypes: []string{"application/json", "text/html"},
+			contentType:        "text/html",
+		},
+		{
+			desc:               "not in the buffer content types",
+			bufferContentTypes: []string{"application/json", "text/html"},
+			contentType:        "application/octet-stream",
+			expectedStreamed:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// The response is streamed if the first part is received before the end of the handler.
+			var streamed bool
+			var rw *httptest.ResponseRecorder
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("first"))
+				streamed = rw.Body.String() == "first"
+				w.Write([]byte("second"))
+			})
+
+			buffering, err := NewContentTypeBuffering(next, test.bufferContentTypes, test.streamContentTypes, func(handler http.Handler) (http.Handler, error) {
+				return buffer.New(handler)
+			})
+			require.NoError(t, err)
+
+			rw = httptest.NewRecorder()
+			buffering.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+			assert.Equal(t, test.expectedStreamed, streamed)
+			assert.Equal(t, http.StatusCreated, rw.Code)
+			assert.Equal(t, test.contentType, rw.Header().Get("Content-Type"))
+			assert.Equal(t, "firstsecond", rw.Body.String())
+		})
+	}
+}
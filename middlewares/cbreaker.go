@@ -1,10 +1,14 @@
 package middlewares
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/middlewares/tracing"
+	"github.com/containous/traefik/types"
 	"github.com/vulcand/oxy/cbreaker"
 )
 
@@ -24,7 +28,12 @@ func NewCircuitBreaker(next http.Handler, expression string, options ...cbreaker
 
 // NewCircuitBreakerOptions returns a new CircuitBreakerOption
 func NewCircuitBreakerOptions(expression string) cbreaker.CircuitBreakerOption {
-	return cbreaker.Fallback(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return cbreaker.Fallback(newDefaultCircuitBreakerFallback(expression))
+}
+
+// newDefaultCircuitBreakerFallback returns the handler serving a 503 while the circuit breaker is open.
+func newDefaultCircuitBreakerFallback(expression string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tracing.LogEventf(r, "blocked by circuit-breaker (%q)", expression)
 
 		w.WriteHeader(http.StatusServiceUnavailable)
@@ -32,9 +41,88 @@ func NewCircuitBreakerOptions(expression string) cbreaker.CircuitBreakerOption {
 		if _, err := w.Write([]byte(http.StatusText(http.StatusServiceUnavailable))); err != nil {
 			log.Error(err)
 		}
-	}))
+	})
 }
 
 func (cb *CircuitBreaker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	cb.circuitBreaker.ServeHTTP(rw, r)
 }
+
+// CircuitBreakerFallback serves a custom response, or forwards the requests to a fallback backend,
+// while the circuit breaker is open.
+type CircuitBreakerFallback struct {
+	BackendName    string
+	expression     string
+	statusCode     int
+	headers        map[string]string
+	body           []byte
+	backendHandler http.Handler
+}
+
+// NewCircuitBreakerFallback creates a new CircuitBreakerFallback.
+// The backend name identifies the fallback backend handler given to PostLoad.
+func NewCircuitBreakerFallback(expression string, config *types.CircuitBreakerFallback, backendName string) (*CircuitBreakerFallback, error) {
+	if len(config.Body) > 0 && len(config.BodyFile) > 0 {
+		return nil, errors.New("body and bodyFile are mutually exclusive")
+	}
+
+	fallback := &CircuitBreakerFallback{
+		expression: expression,
+		statusCode: http.StatusServiceUnavailable,
+		headers:    make(map[string]string),
+		body:       []byte(config.Body),
+	}
+
+	if len(config.Backend) > 0 {
+		fallback.BackendName = backendName
+	}
+
+	if config.StatusCode != 0 {
+		if config.StatusCode < 100 || config.StatusCode > 599 {
+			return nil, fmt.Errorf("invalid status code %d", config.StatusCode)
+		}
+		fallback.statusCode = config.StatusCode
+	}
+
+	if len(config.BodyFile) > 0 {
+		body, err := ioutil.ReadFile(config.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the body file: %v", err)
+		}
+		fallback.body = body
+	}
+
+	for name, value := range config.Headers {
+		fallback.headers[name] = value
+	}
+
+	if len(config.ContentType) > 0 {
+		fallback.headers["Content-Type"] = config.ContentType
+	}
+
+	return fallback, nil
+}
+
+// PostLoad sets the handler of the fallback backend, once all the backends are built.
+func (f *CircuitBreakerFallback) PostLoad(backendHandler http.Handler) {
+	f.backendHandler = backendHandler
+}
+
+func (f *CircuitBreakerFallback) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	tracing.LogEventf(r, "blocked by circuit-breaker (%q)", f.expression)
+
+	if f.backendHandler != nil {
+		f.backendHandler.ServeHTTP(rw, r)
+		return
+	}
+
+	for name, value := range f.headers {
+		rw.Header().Set(name, value)
+	}
+
+	rw.WriteHeader(f.statusCode)
+
+	if _, err := rw.Write(f.body); err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,177 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/mailgun/timetools"
+	"github.com/vulcand/oxy/memmetrics"
+)
+
+const (
+	defaultSLOWindow           = 10 * time.Second
+	defaultSLOMinRequests      = 10
+	defaultSLOFallbackDuration = 10 * time.Second
+)
+
+// SLOCircuitBreaker opens the circuit when the ratio of the responses compliant with a latency SLO,
+// over a rolling window, drops below a threshold.
+// A response is compliant when it is not a server error and is served within the SLO latency,
+// so the slow but successful responses count against the circuit breaker.
+type SLOCircuitBreaker struct {
+	next             http.Handler
+	fallback         http.Handler
+	latency          time.Duration
+	minSuccessRatio  float64
+	minRequests      int64
+	fallbackDuration time.Duration
+	complianceGauge  gokitmetrics.Gauge
+	clock            timetools.TimeProvider
+
+	mu        sync.Mutex
+	total     *memmetrics.RollingCounter
+	compliant *memmetrics.RollingCounter
+	openUntil time.Time
+}
+
+// NewSLOCircuitBreaker creates a new SLOCircuitBreaker.
+// The fallback handler serves the requests while the circuit is open, and the compliance gauge is optional.
+func NewSLOCircuitBreaker(next http.Handler, config *types.CircuitBreakerSLO, fallback http.Handler, complianceGauge gokitmetrics.Gauge) (*SLOCircuitBreaker, error) {
+	return newSLOCircuitBreaker(next, config, fallback, complianceGauge, &timetools.RealTime{})
+}
+
+func newSLOCircuitBreaker(next http.Handler, config *types.CircuitBreakerSLO, fallback http.Handler, complianceGauge gokitmetrics.Gauge, clock timetools.TimeProvider) (*SLOCircuitBreaker, error) {
+	if config.Latency <= 0 {
+		return nil, errors.New("the SLO latency must be greater than 0")
+	}
+	if config.MinSuccessRatio <= 0 || config.MinSuccessRatio > 1 {
+		return nil, fmt.Errorf("invalid SLO minSuccessRatio %v, must be in ]0, 1]", config.MinSuccessRatio)
+	}
+
+	window := time.Duration(config.Window)
+	if window == 0 {
+		window = defaultSLOWindow
+	}
+	if window < time.Second {
+		return nil, fmt.Errorf("invalid SLO window %s, must be at least 1s", window)
+	}
+
+	cb := &SLOCircuitBreaker{
+		next:             next,
+		fallback:         fallback,
+		latency:          time.Duration(config.Latency),
+		minSuccessRatio:  config.MinSuccessRatio,
+		minRequests:      defaultSLOMinRequests,
+		fallbackDuration: defaultSLOFallbackDuration,
+		complianceGauge:  complianceGauge,
+		clock:            clock,
+	}
+
+	if config.MinRequests > 0 {
+		cb.minRequests = int64(config.MinRequests)
+	}
+
+	if config.FallbackDuration > 0 {
+		cb.fallbackDuration = time.Duration(config.FallbackDuration)
+	}
+
+	if cb.fallback == nil {
+		cb.fallback = newDefaultCircuitBreakerFallback(cb.String())
+	}
+
+	var err error
+	if cb.total, err = newSLOCounter(window, clock); err != nil {
+		return nil, err
+	}
+	if cb.compliant, err = newSLOCounter(window, clock); err != nil {
+		return nil, err
+	}
+
+	return cb, nil
+}
+
+// newSLOCounter creates a rolling counter over the window, with at most 10 buckets of 1s or more.
+func newSLOCounter(window time.Duration, clock timetools.TimeProvider) (*memmetrics.RollingCounter, error) {
+	buckets := int(window / time.Second)
+	resolution := time.Second
+	if buckets > 10 {
+		buckets = 10
+		resolution = window / 10
+	}
+	return memmetrics.NewCounter(buckets, resolution, memmetrics.CounterClock(clock))
+}
+
+func (cb *SLOCircuitBreaker) String() string {
+	return fmt.Sprintf("SLO(latency=%s, minSuccessRatio=%v)", cb.latency, cb.minSuccessRatio)
+}
+
+func (cb *SLOCircuitBreaker) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if cb.isOpen() {
+		cb.fallback.ServeHTTP(rw, req)
+		return
+	}
+
+	start := cb.clock.UtcNow()
+	recorder := &responseRecorder{rw, http.StatusOK}
+	cb.next.ServeHTTP(recorder, req)
+
+	compliant := recorder.statusCode < http.StatusInternalServerError && cb.clock.UtcNow().Sub(start) <= cb.latency
+	cb.record(compliant)
+}
+
+func (cb *SLOCircuitBreaker) isOpen() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.openUntil.IsZero() {
+		return false
+	}
+
+	if cb.clock.UtcNow().Before(cb.openUntil) {
+		return true
+	}
+
+	// The circuit is closed again, with a fresh window.
+	log.Debugf("Closing the circuit breaker %s", cb)
+	cb.openUntil = time.Time{}
+	cb.total.Reset()
+	cb.compliant.Reset()
+	cb.setCompliance(1)
+	return false
+}
+
+func (cb *SLOCircuitBreaker) record(compliant bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if !cb.openUntil.IsZero() {
+		// The circuit was opened while the request was served.
+		return
+	}
+
+	cb.total.Inc(1)
+	if compliant {
+		cb.compliant.Inc(1)
+	}
+
+	total := cb.total.Count()
+	ratio := float64(cb.compliant.Count()) / float64(total)
+	cb.setCompliance(ratio)
+
+	if total >= cb.minRequests && ratio < cb.minSuccessRatio {
+		log.Debugf("Opening the circuit breaker %s, compliance ratio %v over %d requests", cb, ratio, total)
+		cb.openUntil = cb.clock.UtcNow().Add(cb.fallbackDuration)
+	}
+}
+
+func (cb *SLOCircuitBreaker) setCompliance(ratio float64) {
+	if cb.complianceGauge != nil {
+		cb.complianceGauge.Set(ratio)
+	}
+}
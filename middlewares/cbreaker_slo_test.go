@@ -0,0 +1,163 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/types"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/mailgun/timetools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSLOCircuitBreaker(t *testing.T) {
+	clock := &timetools.FreezedTime{CurrentTime: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	var duration time.Duration
+	var statusCode int
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		clock.Sleep(duration)
+		rw.WriteHeader(statusCode)
+	})
+
+	config := &types.CircuitBreakerSLO{
+		Latency:          parse.Duration(100 * time.Millisecond),
+		MinSuccessRatio:  0.5,
+		Window:           parse.Duration(10 * time.Second),
+		MinRequests:      4,
+		FallbackDuration: parse.Duration(5 * time.Second),
+	}
+
+	gauge := generic.NewGauge("slo")
+	cb, err := newSLOCircuitBreaker(next, config, nil, gauge, clock)
+	require.NoError(t, err)
+
+	serve := func(d time.Duration, code int) int {
+		duration, statusCode = d, code
+		recorder := httptest.NewRecorder()
+		cb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		return recorder.Code
+	}
+
+	// Fast successes and client errors are compliant.
+	assert.Equal(t, http.StatusOK, serve(10*time.Millisecond, http.StatusOK))
+	assert.Equal(t, http.StatusNotFound, serve(10*time.Millisecond, http.StatusNotFound))
+	assert.Equal(t, float64(1), gauge.Value())
+
+	// Slow successes and server errors are not.
+	assert.Equal(t, http.StatusOK, serve(200*time.Millisecond, http.StatusOK))
+	assert.Equal(t, 2.0/3.0, gauge.Value())
+	assert.Equal(t, http.StatusBadGateway, serve(10*time.Millisecond, http.StatusBadGateway))
+	assert.Equal(t, 0.5, gauge.Value())
+
+	// The ratio drops below the threshold once the minimum of requests is reached.
+	assert.Equal(t, http.StatusOK, serve(200*time.Millisecond, http.StatusOK))
+	assert.Equal(t, 0.4, gauge.Value())
+
+	// The circuit is open for the fallback duration.
+	assert.Equal(t, http.StatusServiceUnavailable, serve(10*time.Millisecond, http.StatusOK))
+	clock.Sleep(4 * time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, serve(10*time.Millisecond, http.StatusOK))
+
+	// Then it is closed again, with a fresh window.
+	clock.Sleep(time.Second)
+	assert.Equal(t, http.StatusOK, serve(200*time.Millisecond, http.StatusOK))
+	assert.Equal(t, float64(0), gauge.Value())
+	assert.Equal(t, http.StatusOK, serve(200*time.Millisecond, http.StatusOK))
+}
+
+func TestSLOCircuitBreakerWindow(t *testing.T) {
+	clock := &timetools.FreezedTime{CurrentTime: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+
+	config := &types.CircuitBreakerSLO{
+		Latency:         parse.Duration(100 * time.Millisecond),
+		MinSuccessRatio: 0.9,
+		Window:          parse.Duration(2 * time.Second),
+		MinRequests:     2,
+	}
+
+	cb, err := newSLOCircuitBreaker(next, config, nil, nil, clock)
+	require.NoError(t, err)
+
+	serve := func() int {
+		recorder := httptest.NewRecorder()
+		cb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		return recorder.Code
+	}
+
+	// The failures are out of the window before the minimum of requests is reached.
+	assert.Equal(t, http.StatusInternalServerError, serve())
+	clock.Sleep(3 * time.Second)
+	assert.Equal(t, http.StatusInternalServerError, serve())
+	assert.Equal(t, http.StatusInternalServerError, serve())
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+}
+
+func TestSLOCircuitBreakerFallback(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	})
+
+	fallback, err := NewCircuitBreakerFallback("", &types.CircuitBreakerFallback{StatusCode: http.StatusTooManyRequests}, "")
+	require.NoError(t, err)
+
+	config := &types.CircuitBreakerSLO{
+		Latency:         parse.Duration(time.Second),
+		MinSuccessRatio: 1,
+		MinRequests:     1,
+	}
+
+	cb, err := NewSLOCircuitBreaker(next, config, fallback, nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	cb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	cb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestNewSLOCircuitBreakerFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.CircuitBreakerSLO
+	}{
+		{
+			desc:   "no latency",
+			config: &types.CircuitBreakerSLO{MinSuccessRatio: 0.9},
+		},
+		{
+			desc:   "no ratio",
+			config: &types.CircuitBreakerSLO{Latency: parse.Duration(time.Second)},
+		},
+		{
+			desc:   "ratio greater than 1",
+			config: &types.CircuitBreakerSLO{Latency: parse.Duration(time.Second), MinSuccessRatio: 1.5},
+		},
+		{
+			desc:   "window shorter than a second",
+			config: &types.CircuitBreakerSLO{Latency: parse.Duration(time.Second), MinSuccessRatio: 0.9, Window: parse.Duration(time.Millisecond)},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewSLOCircuitBreaker(http.NotFoundHandler(), test.config, nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
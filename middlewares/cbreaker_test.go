@@ -0,0 +1,134 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCircuitBreakerFallback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traefik-cbreaker")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bodyFile := filepath.Join(dir, "maintenance.json")
+	err = ioutil.WriteFile(bodyFile, []byte(`{"error":"maintenance"}`), 0644)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc            string
+		config          *types.CircuitBreakerFallback
+		expectedError   bool
+		expectedCode    int
+		expectedHeaders map[string]string
+		expectedBody    string
+	}{
+		{
+			desc:         "empty fallback",
+			config:       &types.CircuitBreakerFallback{},
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc: "static response",
+			config: &types.CircuitBreakerFallback{
+				StatusCode:  http.StatusTooManyRequests,
+				ContentType: "application/json",
+				Body:        `{"error":"unavailable"}`,
+				Headers:     map[string]string{"Retry-After": "10"},
+			},
+			expectedCode:    http.StatusTooManyRequests,
+			expectedHeaders: map[string]string{"Content-Type": "application/json", "Retry-After": "10"},
+			expectedBody:    `{"error":"unavailable"}`,
+		},
+		{
+			desc: "body file",
+			config: &types.CircuitBreakerFallback{
+				ContentType: "application/json",
+				BodyFile:    bodyFile,
+			},
+			expectedCode:    http.StatusServiceUnavailable,
+			expectedHeaders: map[string]string{"Content-Type": "application/json"},
+			expectedBody:    `{"error":"maintenance"}`,
+		},
+		{
+			desc: "missing body file",
+			config: &types.CircuitBreakerFallback{
+				BodyFile: filepath.Join(dir, "missing.json"),
+			},
+			expectedError: true,
+		},
+		{
+			desc: "body and body file",
+			config: &types.CircuitBreakerFallback{
+				Body:     "unavailable",
+				BodyFile: bodyFile,
+			},
+			expectedError: true,
+		},
+		{
+			desc: "invalid status code",
+			config: &types.CircuitBreakerFallback{
+				StatusCode: 1000,
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			fallback, err := NewCircuitBreakerFallback("NetworkErrorRatio() > 0.5", test.config, "httpfilemaintenance")
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Empty(t, fallback.BackendName)
+
+			recorder := httptest.NewRecorder()
+			fallback.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedBody, recorder.Body.String())
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, recorder.Header().Get(name))
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerFallbackBackend(t *testing.T) {
+	fallback, err := NewCircuitBreakerFallback("NetworkErrorRatio() > 0.5", &types.CircuitBreakerFallback{
+		Backend: "maintenance",
+		Body:    "unavailable",
+	}, "httpfilemaintenance")
+	require.NoError(t, err)
+
+	assert.Equal(t, "httpfilemaintenance", fallback.BackendName)
+
+	// Without backend handler, the static response is served.
+	recorder := httptest.NewRecorder()
+	fallback.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "unavailable", recorder.Body.String())
+
+	fallback.PostLoad(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("maintenance page"))
+	}))
+
+	recorder = httptest.NewRecorder()
+	fallback.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "maintenance page", recorder.Body.String())
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// ClientCertStatusHeader is the request header set with the status of the client certificate of the TLS connection.
+const ClientCertStatusHeader = "X-Forwarded-Tls-Client-Cert-Status"
+
+// Client certificate statuses.
+const (
+	ClientCertNone      = "none"
+	ClientCertPresented = "presented"
+	ClientCertVerified  = "verified"
+)
+
+// ClientCertStatus is a middleware that sets the status of the client certificate in a request header,
+// replacing the value sent by the client, so that the frontend rules and the backends can tell
+// whether a certificate was presented, and verified against the CA files of the entrypoint.
+type ClientCertStatus struct{}
+
+func (c *ClientCertStatus) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	r.Header.Set(ClientCertStatusHeader, GetClientCertStatus(r.TLS))
+	next(rw, r)
+}
+
+// GetClientCertStatus returns the status of the client certificate of the TLS connection.
+// The certificates are only verified with the VerifyClientCertIfGiven and RequireAndVerifyClientCert client authentication types.
+func GetClientCertStatus(state *tls.ConnectionState) string {
+	switch {
+	case state == nil || len(state.PeerCertificates) == 0:
+		return ClientCertNone
+	case len(state.VerifiedChains) > 0:
+		return ClientCertVerified
+	default:
+		return ClientCertPresented
+	}
+}
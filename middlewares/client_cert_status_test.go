@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCertStatus(t *testing.T) {
+	cert := &x509.Certificate{}
+
+	testCases := []struct {
+		desc           string
+		state          *tls.ConnectionState
+		header         string
+		expectedStatus string
+	}{
+		{
+			desc:           "no TLS",
+			expectedStatus: ClientCertNone,
+		},
+		{
+			desc:           "no client certificate",
+			state:          &tls.ConnectionState{},
+			expectedStatus: ClientCertNone,
+		},
+		{
+			desc:           "client certificate not verified",
+			state:          &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			expectedStatus: ClientCertPresented,
+		},
+		{
+			desc: "client certificate verified",
+			state: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+				VerifiedChains:   [][]*x509.Certificate{{cert}},
+			},
+			expectedStatus: ClientCertVerified,
+		},
+		{
+			desc:           "status sent by the client",
+			state:          &tls.ConnectionState{},
+			header:         ClientCertVerified,
+			expectedStatus: ClientCertNone,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "https://localhost", nil)
+			req.TLS = test.state
+			if len(test.header) > 0 {
+				req.Header.Set(ClientCertStatusHeader, test.header)
+			}
+
+			var status string
+			(&ClientCertStatus{}).ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				status = r.Header.Get(ClientCertStatusHeader)
+			})
+
+			assert.Equal(t, test.expectedStatus, status)
+		})
+	}
+}
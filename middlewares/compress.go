@@ -1,33 +1,281 @@
 package middlewares
 
 import (
+	"bufio"
 	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
-	"github.com/NYTimes/gziphandler"
+	"github.com/andybalholm/brotli"
 	"github.com/containous/traefik/log"
+	"github.com/klauspost/compress/zstd"
 )
 
+// Compression encodings.
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// DefaultCompressEncodings are the encodings used when none is configured, by order of preference.
+var DefaultCompressEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// compressMinSize is the minimum size of the responses to compress.
+const compressMinSize = 512
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingZstd: {
+		New: func() interface{} {
+			// NewWriter only returns an error on invalid options.
+			w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+			return w
+		},
+	},
+	EncodingBrotli: {
+		New: func() interface{} {
+			return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+		},
+	},
+	EncodingGzip: {
+		New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+			return w
+		},
+	},
+}
+
 // Compress is a middleware that allows to compress the response
-type Compress struct{}
+type Compress struct {
+	encodings []string
+}
+
+// NewCompress creates a new Compress middleware, which uses the given encodings by order of preference.
+func NewCompress(encodings []string) (*Compress, error) {
+	for _, encoding := range encodings {
+		if _, ok := encoderPools[encoding]; !ok {
+			return nil, fmt.Errorf("unsupported compression encoding %q", encoding)
+		}
+	}
+
+	return &Compress{encodings: encodings}, nil
+}
 
 // ServeHTTP is a function used by Negroni
 func (c *Compress) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	contentType := r.Header.Get("Content-Type")
 	if strings.HasPrefix(contentType, "application/grpc") {
 		next.ServeHTTP(rw, r)
+		return
+	}
+
+	rw.Header().Add("Vary", "Accept-Encoding")
+
+	encoding := c.selectEncoding(r.Header.Get("Accept-Encoding"))
+	if len(encoding) == 0 {
+		next.ServeHTTP(rw, r)
+		return
+	}
+
+	cw := &compressResponseWriter{
+		ResponseWriter: rw,
+		encoding:       encoding,
+	}
+	defer func() {
+		if err := cw.Close(); err != nil {
+			log.Debugf("Error while closing the %s compression writer: %v", encoding, err)
+		}
+	}()
+
+	if _, ok := rw.(http.CloseNotifier); ok {
+		next.ServeHTTP(compressResponseWriterWithCloseNotify{cw}, r)
 	} else {
-		gzipHandler(next).ServeHTTP(rw, r)
+		next.ServeHTTP(cw, r)
+	}
+}
+
+// selectEncoding returns the accepted encoding with the highest quality value,
+// using the preference order to break the ties, or an empty string if none is accepted.
+func (c *Compress) selectEncoding(acceptEncoding string) string {
+	encodings := c.encodings
+	if len(encodings) == 0 {
+		encodings = DefaultCompressEncodings
+	}
+
+	accepted := parseAcceptEncoding(acceptEncoding)
+
+	var selected string
+	var selectedQuality float64
+	for _, encoding := range encodings {
+		quality, ok := accepted[encoding]
+		if !ok {
+			quality = accepted["*"]
+		}
+
+		if quality > selectedQuality {
+			selected = encoding
+			selectedQuality = quality
+		}
+	}
+
+	return selected
+}
+
+// parseAcceptEncoding returns the quality value of each coding of an Accept-Encoding header.
+// The invalid codings are ignored.
+func parseAcceptEncoding(acceptEncoding string) map[string]float64 {
+	codings := make(map[string]float64)
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if len(coding) == 0 {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil || q < 0 || q > 1 {
+				quality = -1
+				break
+			}
+			quality = q
+		}
+
+		if quality >= 0 {
+			codings[coding] = quality
+		}
+	}
+
+	return codings
+}
+
+// compressResponseWriter compresses the response with the selected encoding,
+// once the body reaches the minimum size and if it is not already encoded.
+type compressResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	encoder  encoder
+
+	code int
+	buf  []byte
+}
+
+type compressResponseWriterWithCloseNotify struct {
+	*compressResponseWriter
+}
+
+func (w compressResponseWriterWithCloseNotify) CloseNotify() <-chan bool {
+	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// WriteHeader saves the status code until the compression starts or the writer is closed.
+func (w *compressResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if _, ok := w.Header()["Content-Type"]; !ok {
+		// Infer it from the uncompressed body.
+		w.Header().Set("Content-Type", http.DetectContentType(b))
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+
+	if len(w.buf) >= compressMinSize && len(w.Header().Get("Content-Encoding")) == 0 {
+		if err := w.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+func (w *compressResponseWriter) startCompression() error {
+	w.Header().Set("Content-Encoding", w.encoding)
+	// The length of the compressed body is unknown.
+	w.Header().Del("Content-Length")
+
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+
+	w.encoder = encoderPools[w.encoding].Get().(encoder)
+	w.encoder.Reset(w.ResponseWriter)
+
+	n, err := w.encoder.Write(w.buf)
+	if err == nil && n < len(w.buf) {
+		err = io.ErrShortWrite
+	}
+
+	w.buf = nil
+	return err
+}
+
+// Close writes the buffered response if the compression has not started, or closes the encoder.
+func (w *compressResponseWriter) Close() error {
+	if w.encoder == nil {
+		if w.code != 0 {
+			w.ResponseWriter.WriteHeader(w.code)
+		}
+		if w.buf != nil {
+			if _, err := w.ResponseWriter.Write(w.buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := w.encoder.Close()
+	w.encoder.Reset(nil)
+	encoderPools[w.encoding].Put(w.encoder)
+	w.encoder = nil
+	return err
+}
+
+// Flush flushes the encoder and the underlying ResponseWriter.
+// It is a no-op until the compression starts.
+func (w *compressResponseWriter) Flush() {
+	if w.encoder == nil {
+		return
+	}
+
+	if err := w.encoder.Flush(); err != nil {
+		log.Debugf("Error while flushing the %s compression writer: %v", w.encoding, err)
+	}
+
+	if fw, ok := w.ResponseWriter.(http.Flusher); ok {
+		fw.Flush()
 	}
 }
 
-func gzipHandler(h http.Handler) http.Handler {
-	wrapper, err := gziphandler.GzipHandlerWithOpts(
-		gziphandler.CompressionLevel(gzip.DefaultCompression),
-		gziphandler.MinSize(gziphandler.DefaultMinSize))
-	if err != nil {
-		log.Error(err)
+// Hijack hijacks the connection of the underlying ResponseWriter.
+func (w *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
 	}
-	return wrapper(h)
+	return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
 }
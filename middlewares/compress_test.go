@@ -1,13 +1,19 @@
 package middlewares
 
 import (
+	"compress/gzip"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/NYTimes/gziphandler"
+	"github.com/andybalholm/brotli"
 	"github.com/containous/traefik/testhelpers"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/negroni"
@@ -239,6 +245,143 @@ func TestIntegrationShouldCompress(t *testing.T) {
 	}
 }
 
+func TestNewCompress(t *testing.T) {
+	_, err := NewCompress([]string{EncodingBrotli, EncodingGzip})
+	assert.NoError(t, err)
+
+	_, err = NewCompress([]string{"deflate"})
+	assert.Error(t, err)
+}
+
+func TestCompressSelectEncoding(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		encodings        []string
+		acceptEncoding   string
+		expectedEncoding string
+	}{
+		{
+			desc:             "preference order breaks the ties",
+			acceptEncoding:   "gzip, deflate, br, zstd",
+			expectedEncoding: EncodingZstd,
+		},
+		{
+			desc:             "configured preference order",
+			encodings:        []string{EncodingGzip, EncodingBrotli},
+			acceptEncoding:   "gzip, deflate, br, zstd",
+			expectedEncoding: EncodingGzip,
+		},
+		{
+			desc:             "highest quality value wins",
+			acceptEncoding:   "zstd;q=0.5, br;q=0.8, gzip;q=0.9",
+			expectedEncoding: EncodingGzip,
+		},
+		{
+			desc:             "encoding refused with a zero quality value",
+			acceptEncoding:   "zstd;q=0, br",
+			expectedEncoding: EncodingBrotli,
+		},
+		{
+			desc:             "wildcard",
+			encodings:        []string{EncodingBrotli, EncodingGzip},
+			acceptEncoding:   "*",
+			expectedEncoding: EncodingBrotli,
+		},
+		{
+			desc:             "wildcard with refused encoding",
+			acceptEncoding:   "zstd;q=0, *;q=0.5",
+			expectedEncoding: EncodingBrotli,
+		},
+		{
+			desc:             "case insensitive",
+			acceptEncoding:   "GZIP",
+			expectedEncoding: EncodingGzip,
+		},
+		{
+			desc:             "invalid quality value",
+			acceptEncoding:   "br;q=foo, gzip",
+			expectedEncoding: EncodingGzip,
+		},
+		{
+			desc:           "no supported encoding",
+			acceptEncoding: "deflate, identity",
+		},
+		{
+			desc: "no Accept-Encoding header",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			comp, err := NewCompress(test.encodings)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedEncoding, comp.selectEncoding(test.acceptEncoding))
+		})
+	}
+}
+
+func TestCompressEncodings(t *testing.T) {
+	testCases := []struct {
+		encoding string
+		decode   func(r io.Reader) (io.Reader, error)
+	}{
+		{
+			encoding: EncodingZstd,
+			decode: func(r io.Reader) (io.Reader, error) {
+				return zstd.NewReader(r)
+			},
+		},
+		{
+			encoding: EncodingBrotli,
+			decode: func(r io.Reader) (io.Reader, error) {
+				return brotli.NewReader(r), nil
+			},
+		},
+		{
+			encoding: EncodingGzip,
+			decode: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.encoding, func(t *testing.T) {
+			t.Parallel()
+
+			body := []byte(strings.Repeat(`{"traefik":"compress"}`, 100))
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost", nil)
+			req.Header.Add(acceptEncodingHeader, test.encoding)
+
+			rw := httptest.NewRecorder()
+			comp := &Compress{}
+			comp.ServeHTTP(rw, req, func(rw http.ResponseWriter, r *http.Request) {
+				rw.Header().Set(contentTypeHeader, "application/json")
+				rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+				rw.Write(body)
+			})
+
+			assert.Equal(t, test.encoding, rw.Header().Get(contentEncodingHeader))
+			assert.Equal(t, acceptEncodingHeader, rw.Header().Get(varyHeader))
+			assert.Empty(t, rw.Header().Get("Content-Length"))
+			assert.True(t, rw.Body.Len() < len(body))
+
+			reader, err := test.decode(rw.Body)
+			require.NoError(t, err)
+
+			decoded, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, body, decoded)
+		})
+	}
+}
+
 func generateBytes(len int) []byte {
 	var value []byte
 	for i := 0; i < len; i++ {
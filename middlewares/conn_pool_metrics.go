@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// ConnPoolMetricsRoundTripper is a RoundTripper which reports how long the requests wait
+// for a connection of the backend connection pool, and how many requests are waiting.
+type ConnPoolMetricsRoundTripper struct {
+	next          http.RoundTripper
+	waitHistogram gokitmetrics.Histogram
+	waitingGauge  gokitmetrics.Gauge
+}
+
+// NewConnPoolMetricsRoundTripper creates a new ConnPoolMetricsRoundTripper.
+func NewConnPoolMetricsRoundTripper(next http.RoundTripper, waitHistogram gokitmetrics.Histogram, waitingGauge gokitmetrics.Gauge) *ConnPoolMetricsRoundTripper {
+	return &ConnPoolMetricsRoundTripper{
+		next:          next,
+		waitHistogram: waitHistogram,
+		waitingGauge:  waitingGauge,
+	}
+}
+
+// RoundTrip implements the RoundTripper interface.
+func (c *ConnPoolMetricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var lock sync.Mutex
+	var start time.Time
+	var waiting bool
+
+	// done stops the wait, which ends when a connection is obtained or when the request fails before.
+	done := func(observe bool) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if !waiting {
+			return
+		}
+		waiting = false
+
+		c.waitingGauge.Add(-1)
+		if observe {
+			c.waitHistogram.Observe(time.Since(start).Seconds())
+		}
+	}
+
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			start = time.Now()
+			if !waiting {
+				waiting = true
+				c.waitingGauge.Add(1)
+			}
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			done(true)
+		},
+	}
+
+	resp, err := c.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	done(false)
+
+	return resp, err
+}
@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type collectingHistogram struct {
+	observations []float64
+}
+
+func (h *collectingHistogram) With(labelValues ...string) gokitmetrics.Histogram {
+	return h
+}
+
+func (h *collectingHistogram) Observe(value float64) {
+	h.observations = append(h.observations, value)
+}
+
+func TestConnPoolMetricsRoundTripper(t *testing.T) {
+	testCases := []struct {
+		desc                 string
+		gotConn              bool
+		err                  error
+		expectedObservations int
+	}{
+		{
+			desc:                 "connection obtained",
+			gotConn:              true,
+			expectedObservations: 1,
+		},
+		{
+			desc:    "request failed while waiting for a connection",
+			err:     errors.New("dial error"),
+			gotConn: false,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			histogram := &collectingHistogram{}
+			gauge := generic.NewGauge("waiting")
+
+			next := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				trace := httptrace.ContextClientTrace(req.Context())
+				trace.GetConn("backend:80")
+				assert.Equal(t, float64(1), gauge.Value())
+
+				if !test.gotConn {
+					return nil, test.err
+				}
+
+				trace.GotConn(httptrace.GotConnInfo{})
+				assert.Equal(t, float64(0), gauge.Value())
+
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+
+			rt := NewConnPoolMetricsRoundTripper(next, histogram, gauge)
+
+			req := httptest.NewRequest(http.MethodGet, "http://backend", nil)
+			_, err := rt.RoundTrip(req)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, float64(0), gauge.Value())
+			assert.Len(t, histogram.observations, test.expectedObservations)
+		})
+	}
+}
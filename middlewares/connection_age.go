@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+)
+
+// ConnectionAge is a middleware that asks the clients to reconnect once their connection is older than a maximum age,
+// so that the long-lived connections are spread again across the instances after a scale up.
+// The Connection: close header is set on the next response, the connection is closed once it is written:
+// HTTP/1.x connections are closed after the response, and HTTP/2 connections are gracefully shut down (GOAWAY),
+// letting their in-flight streams complete.
+type ConnectionAge struct {
+	maxAge time.Duration
+	now    func() time.Time
+
+	lock  sync.Mutex
+	conns map[string]time.Time
+}
+
+// NewConnectionAge creates a new ConnectionAge.
+// ConnState must be registered as the connection state hook of the server, to track the connections.
+func NewConnectionAge(maxAge time.Duration) *ConnectionAge {
+	return &ConnectionAge{
+		maxAge: maxAge,
+		now:    time.Now,
+		conns:  make(map[string]time.Time),
+	}
+}
+
+// ConnState tracks the opening time of the connections.
+func (c *ConnectionAge) ConnState(conn net.Conn, state http.ConnState) {
+	// The remote address (ip:port) identifies the client connection, as in the requests.
+	connKey := conn.RemoteAddr().String()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch state {
+	case http.StateNew:
+		c.conns[connKey] = c.now()
+	case http.StateHijacked, http.StateClosed:
+		delete(c.conns, connKey)
+	}
+}
+
+func (c *ConnectionAge) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// The upgraded connections (e.g. websockets) are hijacked, they can't be closed after the response.
+	if !containsHeader(r, "Connection", "upgrade") && c.isExpired(r.RemoteAddr) {
+		log.Debugf("Closing the connection of %s, older than %s", r.RemoteAddr, c.maxAge)
+		rw.Header().Set("Connection", "close")
+	}
+
+	next(rw, r)
+}
+
+func (c *ConnectionAge) isExpired(connKey string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	openedAt, ok := c.conns[connKey]
+	return ok && c.now().Sub(openedAt) > c.maxAge
+}
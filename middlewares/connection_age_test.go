@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type remoteAddrConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c remoteAddrConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func TestConnectionAge(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		age             time.Duration
+		state           http.ConnState
+		headers         map[string]string
+		expectedClosing bool
+	}{
+		{
+			desc:  "young connection",
+			age:   time.Minute,
+			state: http.StateNew,
+		},
+		{
+			desc:            "old connection",
+			age:             time.Hour,
+			state:           http.StateNew,
+			expectedClosing: true,
+		},
+		{
+			desc:    "old upgraded connection",
+			age:     time.Hour,
+			state:   http.StateNew,
+			headers: map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket"},
+		},
+		{
+			desc:  "closed connection",
+			age:   time.Hour,
+			state: http.StateClosed,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			connectionAge := NewConnectionAge(10 * time.Minute)
+			connectionAge.now = func() time.Time { return start }
+
+			conn := remoteAddrConn{remoteAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 34567}}
+			connectionAge.ConnState(conn, http.StateNew)
+			if test.state != http.StateNew {
+				connectionAge.ConnState(conn, test.state)
+			}
+
+			connectionAge.now = func() time.Time { return start.Add(test.age) }
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			req.RemoteAddr = "10.0.0.1:34567"
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			var nextCalled bool
+			recorder := httptest.NewRecorder()
+			connectionAge.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+			})
+
+			assert.True(t, nextCalled)
+			if test.expectedClosing {
+				assert.Equal(t, "close", recorder.Header().Get("Connection"))
+			} else {
+				assert.Empty(t, recorder.Header().Get("Connection"))
+			}
+		})
+	}
+}
+
+func TestConnectionAgeOtherConnection(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	connectionAge := NewConnectionAge(10 * time.Minute)
+	connectionAge.now = func() time.Time { return start }
+	connectionAge.ConnState(remoteAddrConn{remoteAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 34567}}, http.StateNew)
+
+	connectionAge.now = func() time.Time { return start.Add(time.Hour) }
+	connectionAge.ConnState(remoteAddrConn{remoteAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 34568}}, http.StateNew)
+
+	// The new connection of the same client is not closed.
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.RemoteAddr = "10.0.0.1:34568"
+
+	recorder := httptest.NewRecorder()
+	connectionAge.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {})
+
+	assert.Empty(t, recorder.Header().Get("Connection"))
+}
@@ -0,0 +1,216 @@
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/types"
+)
+
+// The default methods allowed, which are the simple methods.
+var defaultAllowMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// CORS is a middleware handling the Cross-Origin Resource Sharing requests:
+// the preflight requests are answered directly, and the CORS headers are added to the actual requests.
+// The requests without an Origin header are not CORS requests, and are forwarded untouched.
+type CORS struct {
+	allowAllOrigins  bool
+	allowOrigins     map[string]bool
+	originPatterns   []*regexp.Regexp
+	allowMethods     []string
+	allowAllHeaders  bool
+	allowHeaders     map[string]bool
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// New creates a new CORS middleware.
+func New(config *types.CORS) (*CORS, error) {
+	c := &CORS{
+		allowOrigins:     make(map[string]bool),
+		allowMethods:     defaultAllowMethods,
+		allowHeaders:     make(map[string]bool),
+		exposeHeaders:    strings.Join(config.ExposeHeaders, ", "),
+		allowCredentials: config.AllowCredentials,
+	}
+
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+
+		switch {
+		case origin == "*":
+			c.allowAllOrigins = true
+		case strings.Contains(origin, "*"):
+			// The wildcard matches one or several labels of the host.
+			pattern := strings.Replace(regexp.QuoteMeta(origin), `\*`, `[a-z0-9-]+(\.[a-z0-9-]+)*`, -1)
+			c.originPatterns = append(c.originPatterns, regexp.MustCompile("^"+pattern+"$"))
+		default:
+			c.allowOrigins[origin] = true
+		}
+	}
+
+	for _, pattern := range config.AllowOriginsRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CORS origin regex %q: %v", pattern, err)
+		}
+		c.originPatterns = append(c.originPatterns, re)
+	}
+
+	if len(config.AllowMethods) > 0 {
+		c.allowMethods = nil
+		for _, method := range config.AllowMethods {
+			c.allowMethods = append(c.allowMethods, strings.ToUpper(method))
+		}
+	}
+
+	for _, header := range config.AllowHeaders {
+		if header == "*" {
+			c.allowAllHeaders = true
+			continue
+		}
+		c.allowHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	if config.MaxAge > 0 {
+		c.maxAge = strconv.FormatInt(config.MaxAge, 10)
+	}
+
+	return c, nil
+}
+
+func (c *CORS) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	origin := req.Header.Get("Origin")
+	if len(origin) == 0 {
+		next(rw, req)
+		return
+	}
+
+	if req.Method == http.MethodOptions && len(req.Header.Get("Access-Control-Request-Method")) > 0 {
+		c.handlePreflight(rw, req, origin)
+		return
+	}
+
+	if !c.allowAllOrigins || c.allowCredentials {
+		// The response depends on the origin.
+		rw.Header().Add("Vary", "Origin")
+	}
+
+	if c.isOriginAllowed(origin) {
+		c.addAllowOrigin(rw.Header(), origin)
+
+		if len(c.exposeHeaders) > 0 {
+			rw.Header().Set("Access-Control-Expose-Headers", c.exposeHeaders)
+		}
+	}
+
+	next(rw, req)
+}
+
+// ModifyResponseHeaders removes the CORS headers of the backend responses to CORS requests,
+// which would be duplicated with the ones set by the middleware.
+func (c *CORS) ModifyResponseHeaders(res *http.Response) error {
+	if res.Request == nil || len(res.Request.Header.Get("Origin")) == 0 {
+		return nil
+	}
+
+	res.Header.Del("Access-Control-Allow-Origin")
+	res.Header.Del("Access-Control-Allow-Credentials")
+	res.Header.Del("Access-Control-Expose-Headers")
+	return nil
+}
+
+// handlePreflight answers a preflight request, with the CORS headers only if the actual request is allowed.
+func (c *CORS) handlePreflight(rw http.ResponseWriter, req *http.Request, origin string) {
+	headers := rw.Header()
+	headers.Add("Vary", "Origin")
+	headers.Add("Vary", "Access-Control-Request-Method")
+	headers.Add("Vary", "Access-Control-Request-Headers")
+
+	method := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	requestHeaders := parseHeaderList(req.Header.Get("Access-Control-Request-Headers"))
+
+	if c.isOriginAllowed(origin) && c.isMethodAllowed(method) && c.areHeadersAllowed(requestHeaders) {
+		c.addAllowOrigin(headers, origin)
+
+		headers.Set("Access-Control-Allow-Methods", method)
+		if len(requestHeaders) > 0 {
+			headers.Set("Access-Control-Allow-Headers", strings.Join(requestHeaders, ", "))
+		}
+		if len(c.maxAge) > 0 {
+			headers.Set("Access-Control-Max-Age", c.maxAge)
+		}
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (c *CORS) addAllowOrigin(headers http.Header, origin string) {
+	if c.allowAllOrigins && !c.allowCredentials {
+		headers.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		// The credentials can't be allowed with the "*" origin, the origin is echoed instead.
+		headers.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if c.allowCredentials {
+		headers.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *CORS) isOriginAllowed(origin string) bool {
+	if c.allowAllOrigins {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if c.allowOrigins[origin] {
+		return true
+	}
+
+	for _, pattern := range c.originPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *CORS) isMethodAllowed(method string) bool {
+	for _, allowed := range c.allowMethods {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) areHeadersAllowed(headers []string) bool {
+	if c.allowAllHeaders {
+		return true
+	}
+
+	for _, header := range headers {
+		if !c.allowHeaders[header] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList parses a comma separated list of header names, in their canonical format.
+func parseHeaderList(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		header = strings.TrimSpace(header)
+		if len(header) > 0 {
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return headers
+}
@@ -0,0 +1,268 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	config := &types.CORS{
+		AllowOrigins:      []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginsRegex: []string{`^https://review-[0-9]+\.example\.net$`},
+		AllowMethods:      []string{"GET", "put"},
+		AllowHeaders:      []string{"X-Requested-With", "content-type"},
+		ExposeHeaders:     []string{"X-Request-Id", "X-Total-Count"},
+		MaxAge:            600,
+	}
+
+	testCases := []struct {
+		desc               string
+		config             *types.CORS
+		method             string
+		headers            map[string]string
+		expectedCode       int
+		expectedNextCalled bool
+		expectedHeaders    map[string]string
+		expectedVary       []string
+	}{
+		{
+			desc:               "not a CORS request",
+			config:             config,
+			method:             http.MethodGet,
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+		},
+		{
+			desc:               "not a preflight request",
+			config:             config,
+			method:             http.MethodOptions,
+			headers:            map[string]string{"Origin": "https://app.example.com"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://app.example.com",
+				"Access-Control-Expose-Headers": "X-Request-Id, X-Total-Count",
+			},
+			expectedVary: []string{"Origin"},
+		},
+		{
+			desc:               "allowed origin",
+			config:             config,
+			method:             http.MethodGet,
+			headers:            map[string]string{"Origin": "https://app.example.com"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://app.example.com",
+				"Access-Control-Expose-Headers": "X-Request-Id, X-Total-Count",
+			},
+			expectedVary: []string{"Origin"},
+		},
+		{
+			desc:               "allowed wildcard origin",
+			config:             config,
+			method:             http.MethodGet,
+			headers:            map[string]string{"Origin": "https://eu.app.example.org"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://eu.app.example.org",
+				"Access-Control-Expose-Headers": "X-Request-Id, X-Total-Count",
+			},
+			expectedVary: []string{"Origin"},
+		},
+		{
+			desc:               "wildcard does not match another domain",
+			config:             config,
+			method:             http.MethodGet,
+			headers:            map[string]string{"Origin": "https://example.org.evil.com"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedVary:       []string{"Origin"},
+		},
+		{
+			desc:               "allowed regex origin",
+			config:             config,
+			method:             http.MethodGet,
+			headers:            map[string]string{"Origin": "https://review-42.example.net"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://review-42.example.net",
+				"Access-Control-Expose-Headers": "X-Request-Id, X-Total-Count",
+			},
+			expectedVary: []string{"Origin"},
+		},
+		{
+			desc:               "disallowed origin",
+			config:             config,
+			method:             http.MethodGet,
+			headers:            map[string]string{"Origin": "https://evil.com"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedVary:       []string{"Origin"},
+		},
+		{
+			desc:   "preflight",
+			config: config,
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "content-type, x-requested-with",
+			},
+			expectedCode: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "PUT",
+				"Access-Control-Allow-Headers": "Content-Type, X-Requested-With",
+				"Access-Control-Max-Age":       "600",
+			},
+			expectedVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			desc:   "preflight with disallowed method",
+			config: config,
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			expectedCode: http.StatusNoContent,
+			expectedVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			desc:   "preflight with disallowed header",
+			config: config,
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "PUT",
+				"Access-Control-Request-Headers": "X-Secret",
+			},
+			expectedCode: http.StatusNoContent,
+			expectedVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			desc:   "preflight with disallowed origin",
+			config: config,
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://evil.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			expectedCode: http.StatusNoContent,
+			expectedVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			desc:   "preflight with default methods and any header",
+			config: &types.CORS{AllowOrigins: []string{"*"}, AllowHeaders: []string{"*"}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "X-Anything",
+			},
+			expectedCode: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "POST",
+				"Access-Control-Allow-Headers": "X-Anything",
+			},
+			expectedVary: []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			desc:               "any origin",
+			config:             &types.CORS{AllowOrigins: []string{"*"}},
+			method:             http.MethodGet,
+			headers:            map[string]string{"Origin": "https://app.example.com"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+		},
+		{
+			desc:               "any origin with credentials",
+			config:             &types.CORS{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			method:             http.MethodGet,
+			headers:            map[string]string{"Origin": "https://app.example.com"},
+			expectedCode:       http.StatusOK,
+			expectedNextCalled: true,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+			expectedVary: []string{"Origin"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			corsMiddleware, err := New(test.config)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(test.method, "http://localhost", nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			var nextCalled bool
+			recorder := httptest.NewRecorder()
+			corsMiddleware.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+			})
+
+			assert.Equal(t, test.expectedNextCalled, nextCalled)
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedVary, recorder.Header()["Vary"])
+
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, recorder.Header().Get(name), name)
+			}
+
+			// No other CORS header is set.
+			for name := range recorder.Header() {
+				if name != "Vary" {
+					assert.Contains(t, test.expectedHeaders, name)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSModifyResponseHeaders(t *testing.T) {
+	corsMiddleware, err := New(&types.CORS{AllowOrigins: []string{"https://app.example.com"}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	res := &http.Response{
+		Request: req,
+		Header: http.Header{
+			"Access-Control-Allow-Origin": {"*"},
+			"Content-Type":                {"text/plain"},
+		},
+	}
+
+	// Not a CORS request, the headers of the backend are kept.
+	require.NoError(t, corsMiddleware.ModifyResponseHeaders(res))
+	assert.Equal(t, "*", res.Header.Get("Access-Control-Allow-Origin"))
+
+	req.Header.Set("Origin", "https://app.example.com")
+	require.NoError(t, corsMiddleware.ModifyResponseHeaders(res))
+	assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "text/plain", res.Header.Get("Content-Type"))
+}
+
+func TestNewCORSFail(t *testing.T) {
+	_, err := New(&types.CORS{AllowOriginsRegex: []string{"("}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,705 @@
+package extproc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/tracing"
+	"github.com/containous/traefik/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+//go:generate protoc --go_out=plugins=grpc:. extproc.proto
+
+const (
+	defaultTimeout      = time.Second
+	defaultMaxBodyBytes = 1024 * 1024
+	chunkSize           = 32 * 1024
+)
+
+var (
+	errBodyTooLarge = errors.New("body too large to be processed")
+	errNotConnected = errors.New("no connection to the processor")
+)
+
+// clientError is an error reading the request body, which is not a failure of the processor.
+type clientError struct {
+	error
+}
+
+// ExternalProcessor is a middleware delegating the transformation of the requests, and optionally of the responses,
+// to an external gRPC processing service: the headers and the bodies are streamed to the processor,
+// which answers with the mutations to apply, or with an immediate response.
+type ExternalProcessor struct {
+	address          string
+	clientTLS        *types.ClientTLS
+	conns            *Conns
+	client           ExternalProcessorClient
+	timeout          time.Duration
+	failOpen         bool
+	sendRequestBody  bool
+	processResponse  bool
+	sendResponseBody bool
+	maxBodyBytes     int64
+}
+
+// New creates a new ExternalProcessor, using the shared connection to its processor, got once the configuration is loaded.
+func New(config *types.ExternalProcessor, conns *Conns) (*ExternalProcessor, error) {
+	if len(config.Address) == 0 {
+		return nil, errors.New("the processor address is required")
+	}
+
+	if config.Timeout < 0 {
+		return nil, fmt.Errorf("invalid timeout %s, must be positive", time.Duration(config.Timeout))
+	}
+
+	if config.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("invalid maxBodyBytes %d, must be positive", config.MaxBodyBytes)
+	}
+
+	if config.SendResponseBody && !config.ProcessResponse {
+		return nil, errors.New("sendResponseBody requires processResponse")
+	}
+
+	if config.TLS != nil {
+		if _, err := config.TLS.CreateTLSConfig(); err != nil {
+			return nil, fmt.Errorf("unable to create the processor TLS configuration: %v", err)
+		}
+	}
+
+	p := &ExternalProcessor{
+		address:          config.Address,
+		clientTLS:        config.TLS,
+		conns:            conns,
+		timeout:          defaultTimeout,
+		failOpen:         config.FailOpen,
+		sendRequestBody:  config.SendRequestBody,
+		processResponse:  config.ProcessResponse,
+		sendResponseBody: config.SendResponseBody,
+		maxBodyBytes:     defaultMaxBodyBytes,
+	}
+
+	if config.Timeout > 0 {
+		p.timeout = time.Duration(config.Timeout)
+	}
+	if config.MaxBodyBytes > 0 {
+		p.maxBodyBytes = config.MaxBodyBytes
+	}
+
+	return p, nil
+}
+
+// Conns shares the connections to the processors, so that they outlive the configuration reloads.
+// The connections no longer used by the processors of the loaded configuration are closed by Sweep.
+type Conns struct {
+	lock  sync.Mutex
+	conns map[string]*grpc.ClientConn
+	used  map[string]bool
+}
+
+// NewConns creates a new Conns.
+func NewConns() *Conns {
+	return &Conns{
+		conns: make(map[string]*grpc.ClientConn),
+		used:  make(map[string]bool),
+	}
+}
+
+// Close closes the connections.
+func (c *Conns) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, key)
+	}
+}
+
+// Sweep closes the connections which weren't got since the previous sweep, once a configuration is loaded.
+func (c *Conns) Sweep() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for key, conn := range c.conns {
+		if c.used[key] {
+			continue
+		}
+
+		conn.Close()
+		delete(c.conns, key)
+	}
+	c.used = make(map[string]bool)
+}
+
+// get returns the connection to the processor, shared by the middlewares using the same processor.
+func (c *Conns) get(address string, clientTLS *types.ClientTLS) (*grpc.ClientConn, error) {
+	key := address
+	if clientTLS != nil {
+		key = fmt.Sprintf("%s|%+v", address, *clientTLS)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if conn, ok := c.conns[key]; ok {
+		c.used[key] = true
+		return conn, nil
+	}
+
+	opt := grpc.WithInsecure()
+	if clientTLS != nil {
+		tlsConfig, err := clientTLS.CreateTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the processor TLS configuration: %v", err)
+		}
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+
+	// The dial doesn't block, the connection is established by the first request.
+	conn, err := grpc.Dial(address, opt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to the processor %s: %v", address, err)
+	}
+
+	c.conns[key] = conn
+	c.used[key] = true
+	return conn, nil
+}
+
+// PostLoad gets the connection to the processor.
+func (p *ExternalProcessor) PostLoad(_ map[string]http.Handler) error {
+	conn, err := p.conns.get(p.address, p.clientTLS)
+	if err != nil {
+		return err
+	}
+
+	p.client = NewExternalProcessorClient(conn)
+	return nil
+}
+
+func (p *ExternalProcessor) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if p.client == nil {
+		p.handleRequestFailure(rw, req, next, errNotConnected)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	var stream ExternalProcessor_ProcessClient
+	err := p.withTimeout(cancel, func() (err error) {
+		// Waits for the connection to be ready, within the timeout, while the processor restarts.
+		stream, err = p.client.Process(ctx, grpc.FailFast(false))
+		return err
+	})
+	if err != nil {
+		p.handleRequestFailure(rw, req, next, err)
+		return
+	}
+	defer stream.CloseSend()
+
+	mutated, immediate, err := p.processRequest(cancel, stream, req)
+	if err != nil {
+		p.handleRequestFailure(rw, req, next, err)
+		return
+	}
+
+	if immediate != nil {
+		tracing.LogEventf(req, "request %s - immediate response from the processor", req.URL)
+		writeImmediateResponse(rw, immediate)
+		return
+	}
+	req = mutated
+
+	// The upgraded connections are hijacked, their response can't be processed.
+	if !p.processResponse || isUpgradeRequest(req) {
+		next(rw, req)
+		return
+	}
+
+	if !p.sendResponseBody {
+		hrw := &responseHeadersWriter{
+			ResponseWriter: rw,
+			process: func(code int) (*ImmediateResponse, int, error) {
+				return p.processResponseHeaders(cancel, stream, code, rw.Header(), true)
+			},
+			failOpen: p.failOpen,
+		}
+		next(hrw, req)
+
+		if !hrw.wroteHeader {
+			hrw.WriteHeader(http.StatusOK)
+		}
+		return
+	}
+
+	brw := newBufferedResponseWriter(rw, p.maxBodyBytes, p.failOpen)
+	next(brw, req)
+
+	if brw.passThrough {
+		log.Debugf("Response to %s too large to be processed, forwarded as is", req.URL)
+		return
+	}
+
+	if brw.overflow {
+		tracing.SetErrorAndDebugLog(req, "request %s - response too large to be processed", req.URL)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	immediate, code, body, err := p.processBufferedResponse(cancel, stream, brw)
+	if err != nil {
+		if !p.failOpen {
+			tracing.SetErrorAndDebugLog(req, "request %s - error processing the response: %v", req.URL, err)
+			writeFailure(rw, err)
+			return
+		}
+
+		log.Errorf("Error processing the response to %s, forwarded as is: %v", req.URL, err)
+		brw.writeOriginal()
+		return
+	}
+
+	if immediate != nil {
+		writeImmediateResponse(rw, immediate)
+		return
+	}
+
+	copyHeader(rw.Header(), brw.header)
+	rw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	rw.WriteHeader(code)
+
+	if _, err := rw.Write(body); err != nil {
+		log.Error(err)
+	}
+}
+
+func (p *ExternalProcessor) handleRequestFailure(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc, err error) {
+	if _, ok := err.(clientError); ok {
+		tracing.SetErrorAndDebugLog(req, "request %s - unable to read the body: %v", req.URL, err)
+		http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if !p.failOpen {
+		tracing.SetErrorAndDebugLog(req, "request %s - error processing the request: %v", req.URL, err)
+		writeFailure(rw, err)
+		return
+	}
+
+	log.Errorf("Error processing the request %s, forwarded as is: %v", req.URL, err)
+	next(rw, req)
+}
+
+func writeFailure(rw http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if err == errBodyTooLarge {
+		code = http.StatusRequestEntityTooLarge
+	}
+
+	http.Error(rw, http.StatusText(code), code)
+}
+
+// withTimeout calls fn, and cancels the processing stream once the timeout is reached.
+func (p *ExternalProcessor) withTimeout(cancel context.CancelFunc, fn func() error) error {
+	timer := time.AfterFunc(p.timeout, cancel)
+
+	err := fn()
+	if !timer.Stop() {
+		return fmt.Errorf("processor timed out after %s", p.timeout)
+	}
+
+	return err
+}
+
+func (p *ExternalProcessor) send(cancel context.CancelFunc, stream ExternalProcessor_ProcessClient, message *ProcessingRequest) error {
+	return p.withTimeout(cancel, func() error {
+		return stream.Send(message)
+	})
+}
+
+func (p *ExternalProcessor) recv(cancel context.CancelFunc, stream ExternalProcessor_ProcessClient) (*ProcessingResponse, error) {
+	var resp *ProcessingResponse
+	err := p.withTimeout(cancel, func() (err error) {
+		resp, err = stream.Recv()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ImmediateResponse != nil {
+		if code := resp.ImmediateResponse.Status; code != 0 && (code < 100 || code > 599) {
+			return nil, fmt.Errorf("invalid immediate response status %d", code)
+		}
+	}
+
+	return resp, nil
+}
+
+// processRequest sends the request to the processor, and returns a copy of the request with the mutations applied.
+// On error, the request is left as is, with its body readable from its start.
+func (p *ExternalProcessor) processRequest(cancel context.CancelFunc, stream ExternalProcessor_ProcessClient, req *http.Request) (*http.Request, *ImmediateResponse, error) {
+	hasBody := p.sendRequestBody && req.Body != nil && req.Body != http.NoBody
+
+	headers := &HttpHeaders{
+		Headers:     requestHeaders(req),
+		EndOfStream: !hasBody,
+	}
+	if err := p.send(cancel, stream, &ProcessingRequest{RequestHeaders: headers}); err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := p.recv(cancel, stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.ImmediateResponse != nil {
+		return nil, resp.ImmediateResponse, nil
+	}
+	if resp.RequestHeaders == nil {
+		return nil, nil, errors.New("unexpected answer to the request headers")
+	}
+
+	// The mutations are applied to a copy, the original request is forwarded if the processing fails.
+	mutated := cloneRequest(req)
+	if err := mutateRequest(mutated, resp.RequestHeaders.HeaderMutation); err != nil {
+		return nil, nil, err
+	}
+
+	if !hasBody {
+		return mutated, nil, nil
+	}
+
+	body := &bytes.Buffer{}
+	err = p.streamBody(cancel, stream, req.Body, body, func(chunk *HttpBody) *ProcessingRequest {
+		return &ProcessingRequest{RequestBody: chunk}
+	})
+	if err == nil {
+		resp, err = p.recv(cancel, stream)
+	}
+	if err == nil && resp.ImmediateResponse == nil && resp.RequestBody == nil {
+		err = errors.New("unexpected answer to the request body")
+	}
+	if err == nil && resp.RequestBody != nil {
+		err = mutateRequest(mutated, resp.RequestBody.HeaderMutation)
+	}
+	if err != nil {
+		req.Body = &readCloser{Reader: io.MultiReader(body, req.Body), Closer: req.Body}
+		return nil, nil, err
+	}
+
+	if resp.ImmediateResponse != nil {
+		return nil, resp.ImmediateResponse, nil
+	}
+
+	data := body.Bytes()
+	if mutation := resp.RequestBody.BodyMutation; mutation != nil && mutation.Replace {
+		data = mutation.Body
+	}
+
+	req.Body.Close()
+	mutated.Body = ioutil.NopCloser(bytes.NewReader(data))
+	mutated.ContentLength = int64(len(data))
+	mutated.TransferEncoding = nil
+	mutated.Header.Del("Content-Length")
+
+	return mutated, nil, nil
+}
+
+func (p *ExternalProcessor) processResponseHeaders(cancel context.CancelFunc, stream ExternalProcessor_ProcessClient, code int, header http.Header, endOfStream bool) (*ImmediateResponse, int, error) {
+	headers := &HttpHeaders{
+		Headers:     responseHeaders(code, header),
+		EndOfStream: endOfStream,
+	}
+	if err := p.send(cancel, stream, &ProcessingRequest{ResponseHeaders: headers}); err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := p.recv(cancel, stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.ImmediateResponse != nil {
+		return resp.ImmediateResponse, 0, nil
+	}
+	if resp.ResponseHeaders == nil {
+		return nil, 0, errors.New("unexpected answer to the response headers")
+	}
+
+	code, err = mutateResponse(code, header, resp.ResponseHeaders.HeaderMutation)
+	return nil, code, err
+}
+
+// processBufferedResponse sends the buffered response to the processor,
+// and applies the mutations to the buffered headers.
+func (p *ExternalProcessor) processBufferedResponse(cancel context.CancelFunc, stream ExternalProcessor_ProcessClient, brw *bufferedResponseWriter) (*ImmediateResponse, int, []byte, error) {
+	// The mutations are applied to a copy, the original response is written if the processing fails.
+	header := make(http.Header)
+	copyHeader(header, brw.header)
+
+	hasBody := brw.body.Len() > 0
+
+	immediate, code, err := p.processResponseHeaders(cancel, stream, brw.statusCode(), header, !hasBody)
+	if err != nil || immediate != nil {
+		return immediate, 0, nil, err
+	}
+
+	if !hasBody {
+		brw.header = header
+		return nil, code, nil, nil
+	}
+
+	err = p.streamBody(cancel, stream, bytes.NewReader(brw.body.Bytes()), nil, func(chunk *HttpBody) *ProcessingRequest {
+		return &ProcessingRequest{ResponseBody: chunk}
+	})
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	resp, err := p.recv(cancel, stream)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if resp.ImmediateResponse != nil {
+		return resp.ImmediateResponse, 0, nil, nil
+	}
+	if resp.ResponseBody == nil {
+		return nil, 0, nil, errors.New("unexpected answer to the response body")
+	}
+
+	code, err = mutateResponse(code, header, resp.ResponseBody.HeaderMutation)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	body := brw.body.Bytes()
+	if mutation := resp.ResponseBody.BodyMutation; mutation != nil && mutation.Replace {
+		body = mutation.Body
+	}
+
+	brw.header = header
+	return nil, code, body, nil
+}
+
+// streamBody sends the body to the processor in chunks, followed by the end of stream, and copies it to dst if not nil.
+// It fails once the body exceeds maxBodyBytes.
+func (p *ExternalProcessor) streamBody(cancel context.CancelFunc, stream ExternalProcessor_ProcessClient, src io.Reader, dst io.Writer, message func(*HttpBody) *ProcessingRequest) error {
+	chunk := make([]byte, chunkSize)
+
+	var sent int64
+	for {
+		n, err := src.Read(chunk)
+		if n > 0 {
+			// The chunk is copied first, for the body to be forwarded as is when failing open.
+			if dst != nil {
+				dst.Write(chunk[:n])
+			}
+
+			sent += int64(n)
+			if sent > p.maxBodyBytes {
+				return errBodyTooLarge
+			}
+
+			// The message is marshaled by Send, the chunk can be reused afterwards.
+			if errSend := p.send(cancel, stream, message(&HttpBody{Body: chunk[:n]})); errSend != nil {
+				return errSend
+			}
+		}
+
+		if err == io.EOF {
+			return p.send(cancel, stream, message(&HttpBody{EndOfStream: true}))
+		}
+		if err != nil {
+			return clientError{err}
+		}
+	}
+}
+
+func requestHeaders(req *http.Request) []*HeaderValue {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	headers := []*HeaderValue{
+		{Key: ":method", Value: req.Method},
+		{Key: ":scheme", Value: scheme},
+		{Key: ":authority", Value: req.Host},
+		{Key: ":path", Value: req.URL.RequestURI()},
+	}
+
+	return append(headers, toHeaderValues(req.Header)...)
+}
+
+func responseHeaders(code int, header http.Header) []*HeaderValue {
+	headers := []*HeaderValue{
+		{Key: ":status", Value: strconv.Itoa(code)},
+	}
+
+	return append(headers, toHeaderValues(header)...)
+}
+
+func toHeaderValues(header http.Header) []*HeaderValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers []*HeaderValue
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, &HeaderValue{Key: name, Value: value})
+		}
+	}
+
+	return headers
+}
+
+// mutateHeader removes, then sets the headers of the mutation.
+// The values of the pseudo headers are given to setPseudo.
+func mutateHeader(header http.Header, mutation *HeaderMutation, setPseudo func(name, value string) error) error {
+	for _, name := range mutation.GetRemoveHeaders() {
+		if !strings.HasPrefix(name, ":") {
+			header.Del(name)
+		}
+	}
+
+	values := make(http.Header)
+	for _, h := range mutation.GetSetHeaders() {
+		if strings.HasPrefix(h.Key, ":") {
+			if err := setPseudo(h.Key, h.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		values.Add(h.Key, h.Value)
+	}
+
+	for name, value := range values {
+		header[name] = value
+	}
+
+	return nil
+}
+
+func mutateRequest(req *http.Request, mutation *HeaderMutation) error {
+	return mutateHeader(req.Header, mutation, func(name, value string) error {
+		switch name {
+		case ":method":
+			req.Method = value
+		case ":authority":
+			req.Host = value
+		case ":path":
+			u, err := url.ParseRequestURI(value)
+			if err != nil {
+				return fmt.Errorf("invalid :path %q: %v", value, err)
+			}
+
+			req.URL.Path = u.Path
+			req.URL.RawPath = u.RawPath
+			req.URL.RawQuery = u.RawQuery
+			req.RequestURI = value
+		default:
+			return fmt.Errorf("unsupported request pseudo header %s", name)
+		}
+
+		return nil
+	})
+}
+
+func mutateResponse(code int, header http.Header, mutation *HeaderMutation) (int, error) {
+	err := mutateHeader(header, mutation, func(name, value string) error {
+		if name != ":status" {
+			return fmt.Errorf("unsupported response pseudo header %s", name)
+		}
+
+		status, err := strconv.Atoi(value)
+		if err != nil || status < 100 || status > 599 {
+			return fmt.Errorf("invalid :status %q", value)
+		}
+
+		code = status
+		return nil
+	})
+
+	return code, err
+}
+
+func writeImmediateResponse(rw http.ResponseWriter, resp *ImmediateResponse) {
+	resetHeader(rw.Header())
+
+	for _, h := range resp.Headers {
+		rw.Header().Add(h.Key, h.Value)
+	}
+
+	code := http.StatusOK
+	if resp.Status != 0 {
+		code = int(resp.Status)
+	}
+
+	rw.Header().Set("Content-Length", strconv.Itoa(len(resp.Body)))
+	rw.WriteHeader(code)
+
+	if _, err := rw.Write(resp.Body); err != nil {
+		log.Error(err)
+	}
+}
+
+// cloneRequest returns a shallow copy of the request, with its own headers and URL.
+func cloneRequest(req *http.Request) *http.Request {
+	clone := req.WithContext(req.Context())
+
+	clone.Header = make(http.Header, len(req.Header))
+	copyHeader(clone.Header, req.Header)
+
+	u := *req.URL
+	clone.URL = &u
+
+	return clone
+}
+
+func resetHeader(header http.Header) {
+	for name := range header {
+		delete(header, name)
+	}
+}
+
+// copyHeader replaces the dst headers with the src ones.
+func copyHeader(dst, src http.Header) {
+	resetHeader(dst)
+
+	for name, values := range src {
+		dst[name] = append([]string(nil), values...)
+	}
+}
+
+func isUpgradeRequest(req *http.Request) bool {
+	for _, value := range strings.Split(req.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(value), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,514 @@
+package extproc
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// testProcessor answers the headers messages, and the bodies once their last chunk is received, with handle.
+// The processing fails when handle returns nil.
+type testProcessor struct {
+	handle func(*ProcessingRequest) *ProcessingResponse
+}
+
+func (s *testProcessor) Process(stream ExternalProcessor_ProcessServer) error {
+	var body []byte
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if chunk := req.GetRequestBody(); chunk != nil {
+			body = append(body, chunk.Body...)
+			if !chunk.EndOfStream {
+				continue
+			}
+			req = &ProcessingRequest{RequestBody: &HttpBody{Body: body, EndOfStream: true}}
+			body = nil
+		}
+
+		if chunk := req.GetResponseBody(); chunk != nil {
+			body = append(body, chunk.Body...)
+			if !chunk.EndOfStream {
+				continue
+			}
+			req = &ProcessingRequest{ResponseBody: &HttpBody{Body: body, EndOfStream: true}}
+			body = nil
+		}
+
+		resp := s.handle(req)
+		if resp == nil {
+			return errors.New("processing failed")
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func startProcessor(t *testing.T, handle func(*ProcessingRequest) *ProcessingResponse) (string, *Conns, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	RegisterExternalProcessorServer(server, &testProcessor{handle: handle})
+	go server.Serve(listener)
+
+	conns := NewConns()
+
+	return listener.Addr().String(), conns, func() {
+		server.Stop()
+		conns.Close()
+	}
+}
+
+// passThrough answers each message without mutation.
+func passThrough(req *ProcessingRequest) *ProcessingResponse {
+	switch {
+	case req.RequestHeaders != nil:
+		return &ProcessingResponse{RequestHeaders: &CommonResponse{}}
+	case req.RequestBody != nil:
+		return &ProcessingResponse{RequestBody: &CommonResponse{}}
+	case req.ResponseHeaders != nil:
+		return &ProcessingResponse{ResponseHeaders: &CommonResponse{}}
+	default:
+		return &ProcessingResponse{ResponseBody: &CommonResponse{}}
+	}
+}
+
+func TestExternalProcessor(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		config             types.ExternalProcessor
+		handle             func(*ProcessingRequest) *ProcessingResponse
+		body               string
+		responseBody       string
+		expectedNextCalled bool
+		expectedURI        string
+		expectedHeaders    map[string]string
+		expectedBody       string
+		expectedCode       int
+		expectedRespHeader map[string]string
+		expectedRespBody   string
+	}{
+		{
+			desc: "request headers mutation",
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				if req.RequestHeaders == nil {
+					return nil
+				}
+				return &ProcessingResponse{RequestHeaders: &CommonResponse{
+					HeaderMutation: &HeaderMutation{
+						SetHeaders: []*HeaderValue{
+							{Key: ":path", Value: "/rewritten?q=1"},
+							{Key: "X-Added", Value: "added"},
+						},
+						RemoveHeaders: []string{"X-Removed"},
+					},
+				}}
+			},
+			expectedNextCalled: true,
+			expectedURI:        "/rewritten?q=1",
+			expectedHeaders:    map[string]string{"X-Added": "added", "X-Removed": ""},
+			expectedCode:       http.StatusOK,
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:   "request body replaced",
+			config: types.ExternalProcessor{SendRequestBody: true},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				if req.RequestBody != nil {
+					return &ProcessingResponse{RequestBody: &CommonResponse{
+						BodyMutation: &BodyMutation{Body: []byte(strings.ToUpper(string(req.RequestBody.Body))), Replace: true},
+					}}
+				}
+				return passThrough(req)
+			},
+			body:               "payload",
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedBody:       "PAYLOAD",
+			expectedCode:       http.StatusOK,
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:               "request body forwarded as is",
+			config:             types.ExternalProcessor{SendRequestBody: true},
+			handle:             passThrough,
+			body:               strings.Repeat("a", 3*chunkSize),
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedBody:       strings.Repeat("a", 3*chunkSize),
+			expectedCode:       http.StatusOK,
+			expectedRespBody:   "backend",
+		},
+		{
+			desc: "immediate response",
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				return &ProcessingResponse{ImmediateResponse: &ImmediateResponse{
+					Status:  http.StatusForbidden,
+					Headers: []*HeaderValue{{Key: "X-Reason", Value: "denied"}},
+					Body:    []byte("denied"),
+				}}
+			},
+			expectedCode:       http.StatusForbidden,
+			expectedRespHeader: map[string]string{"X-Reason": "denied"},
+			expectedRespBody:   "denied",
+		},
+		{
+			desc:   "response headers mutation",
+			config: types.ExternalProcessor{ProcessResponse: true},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				if req.ResponseHeaders != nil {
+					return &ProcessingResponse{ResponseHeaders: &CommonResponse{
+						HeaderMutation: &HeaderMutation{
+							SetHeaders:    []*HeaderValue{{Key: ":status", Value: "202"}, {Key: "X-Processed", Value: "true"}},
+							RemoveHeaders: []string{"X-Backend"},
+						},
+					}}
+				}
+				return passThrough(req)
+			},
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedCode:       http.StatusAccepted,
+			expectedRespHeader: map[string]string{"X-Processed": "true", "X-Backend": ""},
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:   "response body replaced",
+			config: types.ExternalProcessor{ProcessResponse: true, SendResponseBody: true},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				if req.ResponseBody != nil {
+					return &ProcessingResponse{ResponseBody: &CommonResponse{
+						HeaderMutation: &HeaderMutation{SetHeaders: []*HeaderValue{{Key: "X-Processed", Value: "true"}}},
+						BodyMutation:   &BodyMutation{Body: []byte("transformed " + string(req.ResponseBody.Body)), Replace: true},
+					}}
+				}
+				return passThrough(req)
+			},
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedCode:       http.StatusOK,
+			expectedRespHeader: map[string]string{"X-Processed": "true", "X-Backend": "backend", "Content-Length": "19"},
+			expectedRespBody:   "transformed backend",
+		},
+		{
+			desc:   "response immediate response",
+			config: types.ExternalProcessor{ProcessResponse: true, SendResponseBody: true},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				if req.ResponseBody != nil {
+					return &ProcessingResponse{ImmediateResponse: &ImmediateResponse{Status: http.StatusBadGateway, Body: []byte("rejected")}}
+				}
+				return passThrough(req)
+			},
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedCode:       http.StatusBadGateway,
+			expectedRespHeader: map[string]string{"X-Backend": ""},
+			expectedRespBody:   "rejected",
+		},
+		{
+			desc:             "failure closed",
+			handle:           func(req *ProcessingRequest) *ProcessingResponse { return nil },
+			expectedCode:     http.StatusInternalServerError,
+			expectedRespBody: "Internal Server Error\n",
+		},
+		{
+			desc:               "failure open",
+			config:             types.ExternalProcessor{FailOpen: true, SendRequestBody: true},
+			handle:             func(req *ProcessingRequest) *ProcessingResponse { return nil },
+			body:               "payload",
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedHeaders:    map[string]string{"X-Removed": "removed"},
+			expectedBody:       "payload",
+			expectedCode:       http.StatusOK,
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:   "failure open after the request headers mutation",
+			config: types.ExternalProcessor{FailOpen: true, SendRequestBody: true},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				if req.RequestHeaders == nil {
+					return nil
+				}
+				return &ProcessingResponse{RequestHeaders: &CommonResponse{
+					HeaderMutation: &HeaderMutation{
+						SetHeaders:    []*HeaderValue{{Key: ":path", Value: "/rewritten"}, {Key: "X-Added", Value: "added"}},
+						RemoveHeaders: []string{"X-Removed"},
+					},
+				}}
+			},
+			body:               "payload",
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedHeaders:    map[string]string{"X-Added": "", "X-Removed": "removed"},
+			expectedBody:       "payload",
+			expectedCode:       http.StatusOK,
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:   "failure open on an invalid request headers mutation",
+			config: types.ExternalProcessor{FailOpen: true},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				return &ProcessingResponse{RequestHeaders: &CommonResponse{
+					HeaderMutation: &HeaderMutation{
+						SetHeaders:    []*HeaderValue{{Key: "X-Added", Value: "added"}, {Key: ":path", Value: "relative"}},
+						RemoveHeaders: []string{"X-Removed"},
+					},
+				}}
+			},
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedHeaders:    map[string]string{"X-Added": "", "X-Removed": "removed"},
+			expectedCode:       http.StatusOK,
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:   "response failure open",
+			config: types.ExternalProcessor{FailOpen: true, ProcessResponse: true, SendResponseBody: true},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				if req.ResponseBody != nil {
+					return nil
+				}
+				return passThrough(req)
+			},
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedCode:       http.StatusOK,
+			expectedRespHeader: map[string]string{"X-Backend": "backend"},
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:   "timeout",
+			config: types.ExternalProcessor{Timeout: parse.Duration(50 * time.Millisecond)},
+			handle: func(req *ProcessingRequest) *ProcessingResponse {
+				time.Sleep(500 * time.Millisecond)
+				return passThrough(req)
+			},
+			expectedCode:     http.StatusInternalServerError,
+			expectedRespBody: "Internal Server Error\n",
+		},
+		{
+			desc:             "request body too large",
+			config:           types.ExternalProcessor{SendRequestBody: true, MaxBodyBytes: 4},
+			handle:           passThrough,
+			body:             "payload",
+			expectedCode:     http.StatusRequestEntityTooLarge,
+			expectedRespBody: "Request Entity Too Large\n",
+		},
+		{
+			desc:               "request body too large failing open",
+			config:             types.ExternalProcessor{FailOpen: true, SendRequestBody: true, MaxBodyBytes: 4},
+			handle:             passThrough,
+			body:               "payload",
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedBody:       "payload",
+			expectedCode:       http.StatusOK,
+			expectedRespBody:   "backend",
+		},
+		{
+			desc:               "response body too large",
+			config:             types.ExternalProcessor{ProcessResponse: true, SendResponseBody: true, MaxBodyBytes: 4},
+			handle:             passThrough,
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedCode:       http.StatusInternalServerError,
+			expectedRespBody:   "Internal Server Error\n",
+		},
+		{
+			desc:               "response body too large failing open",
+			config:             types.ExternalProcessor{FailOpen: true, ProcessResponse: true, SendResponseBody: true, MaxBodyBytes: 4},
+			handle:             passThrough,
+			responseBody:       "a longer backend response",
+			expectedNextCalled: true,
+			expectedURI:        "/foo",
+			expectedCode:       http.StatusOK,
+			expectedRespHeader: map[string]string{"X-Backend": "backend"},
+			expectedRespBody:   "a longer backend response",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			address, conns, stop := startProcessor(t, test.handle)
+			defer stop()
+
+			config := test.config
+			config.Address = address
+
+			processor, err := New(&config, conns)
+			require.NoError(t, err)
+			require.NoError(t, processor.PostLoad(nil))
+
+			var body io.Reader
+			if len(test.body) > 0 {
+				body = strings.NewReader(test.body)
+			}
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/foo", body)
+			req.Header.Set("X-Removed", "removed")
+
+			responseBody := test.responseBody
+			if len(responseBody) == 0 {
+				responseBody = "backend"
+			}
+
+			var nextCalled bool
+			recorder := httptest.NewRecorder()
+			processor.ServeHTTP(recorder, req, func(rw http.ResponseWriter, req *http.Request) {
+				nextCalled = true
+
+				assert.Equal(t, test.expectedURI, req.URL.RequestURI())
+				for name, value := range test.expectedHeaders {
+					assert.Equal(t, value, req.Header.Get(name), name)
+				}
+
+				data, err := ioutil.ReadAll(req.Body)
+				require.NoError(t, err)
+				assert.Equal(t, test.expectedBody, string(data))
+				if len(test.expectedBody) > 0 {
+					assert.EqualValues(t, len(test.expectedBody), len(data))
+				}
+
+				rw.Header().Set("X-Backend", "backend")
+				rw.Write([]byte(responseBody))
+			})
+
+			assert.Equal(t, test.expectedNextCalled, nextCalled)
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedRespBody, recorder.Body.String())
+			for name, value := range test.expectedRespHeader {
+				assert.Equal(t, value, recorder.Header().Get(name), name)
+			}
+		})
+	}
+}
+
+func TestExternalProcessorRequestHeaders(t *testing.T) {
+	received := make(chan *HttpHeaders, 1)
+	address, conns, stop := startProcessor(t, func(req *ProcessingRequest) *ProcessingResponse {
+		received <- req.RequestHeaders
+		return passThrough(req)
+	})
+	defer stop()
+
+	processor, err := New(&types.ExternalProcessor{Address: address}, conns)
+	require.NoError(t, err)
+	require.NoError(t, processor.PostLoad(nil))
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/foo?bar=baz", nil)
+	req.Header.Add("X-Foo", "a")
+	req.Header.Add("X-Foo", "b")
+
+	processor.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, req *http.Request) {})
+
+	headers := <-received
+	expected := []*HeaderValue{
+		{Key: ":method", Value: http.MethodGet},
+		{Key: ":scheme", Value: "http"},
+		{Key: ":authority", Value: "localhost"},
+		{Key: ":path", Value: "/foo?bar=baz"},
+		{Key: "X-Foo", Value: "a"},
+		{Key: "X-Foo", Value: "b"},
+	}
+	assert.Equal(t, expected, headers.Headers)
+	assert.True(t, headers.EndOfStream)
+}
+
+func TestConnsPooled(t *testing.T) {
+	conns := NewConns()
+	defer conns.Close()
+
+	first, err := conns.get("127.0.0.1:9999", nil)
+	require.NoError(t, err)
+
+	second, err := conns.get("127.0.0.1:9999", nil)
+	require.NoError(t, err)
+
+	other, err := conns.get("127.0.0.1:9998", nil)
+	require.NoError(t, err)
+
+	assert.True(t, first == second)
+	assert.False(t, first == other)
+}
+
+func TestConnsSweep(t *testing.T) {
+	conns := NewConns()
+	defer conns.Close()
+
+	first, err := conns.get("127.0.0.1:9999", nil)
+	require.NoError(t, err)
+
+	other, err := conns.get("127.0.0.1:9998", nil)
+	require.NoError(t, err)
+
+	// The connections got since the previous sweep are kept.
+	conns.Sweep()
+	assert.Len(t, conns.conns, 2)
+
+	_, err = conns.get("127.0.0.1:9999", nil)
+	require.NoError(t, err)
+
+	conns.Sweep()
+	assert.Len(t, conns.conns, 1)
+	assert.True(t, first == conns.conns["127.0.0.1:9999"])
+	assert.Equal(t, connectivity.Shutdown, other.GetState())
+}
+
+func TestNewFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.ExternalProcessor
+	}{
+		{
+			desc:   "missing address",
+			config: &types.ExternalProcessor{},
+		},
+		{
+			desc:   "negative timeout",
+			config: &types.ExternalProcessor{Address: "127.0.0.1:9999", Timeout: parse.Duration(-time.Second)},
+		},
+		{
+			desc:   "negative max body bytes",
+			config: &types.ExternalProcessor{Address: "127.0.0.1:9999", MaxBodyBytes: -1},
+		},
+		{
+			desc:   "response body without response processing",
+			config: &types.ExternalProcessor{Address: "127.0.0.1:9999", SendResponseBody: true},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(test.config, NewConns())
+			assert.Error(t, err)
+		})
+	}
+}
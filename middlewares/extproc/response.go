@@ -0,0 +1,162 @@
+package extproc
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/containous/traefik/log"
+)
+
+// responseHeadersWriter sends the response headers to the processor once written by the handler,
+// and forwards the body as is.
+type responseHeadersWriter struct {
+	http.ResponseWriter
+
+	process  func(code int) (*ImmediateResponse, int, error)
+	failOpen bool
+
+	wroteHeader bool
+	discard     bool
+}
+
+func (w *responseHeadersWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	// The mutations are applied to the headers in place, the original ones are restored if the processing fails.
+	original := make(http.Header)
+	copyHeader(original, w.Header())
+
+	immediate, processedCode, err := w.process(code)
+	if err != nil {
+		copyHeader(w.Header(), original)
+
+		if !w.failOpen {
+			log.Errorf("Error processing the response headers: %v", err)
+			w.discard = true
+			resetHeader(w.Header())
+			writeFailure(w.ResponseWriter, err)
+			return
+		}
+
+		log.Errorf("Error processing the response headers, forwarded as is: %v", err)
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if immediate != nil {
+		w.discard = true
+		writeImmediateResponse(w.ResponseWriter, immediate)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(processedCode)
+}
+
+func (w *responseHeadersWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	// The body of the replaced response is dropped.
+	if w.discard {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseHeadersWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// bufferedResponseWriter buffers the response for the processor, up to maxBytes.
+// Once the limit is crossed, the response is forwarded as is when failing open, and fails otherwise.
+type bufferedResponseWriter struct {
+	rw       http.ResponseWriter
+	header   http.Header
+	code     int
+	body     bytes.Buffer
+	maxBytes int64
+	failOpen bool
+
+	overflow    bool
+	passThrough bool
+}
+
+func newBufferedResponseWriter(rw http.ResponseWriter, maxBytes int64, failOpen bool) *bufferedResponseWriter {
+	header := make(http.Header)
+	copyHeader(header, rw.Header())
+
+	return &bufferedResponseWriter{
+		rw:       rw,
+		header:   header,
+		maxBytes: maxBytes,
+		failOpen: failOpen,
+	}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	if w.passThrough {
+		return w.rw.Header()
+	}
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if w.passThrough || w.code != 0 {
+		return
+	}
+	w.code = code
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	if w.passThrough {
+		return w.rw.Write(p)
+	}
+
+	if w.overflow {
+		return 0, errBodyTooLarge
+	}
+
+	if int64(w.body.Len()+len(p)) > w.maxBytes {
+		if !w.failOpen {
+			w.overflow = true
+			return 0, errBodyTooLarge
+		}
+
+		w.writeOriginal()
+		w.passThrough = true
+		return w.rw.Write(p)
+	}
+
+	return w.body.Write(p)
+}
+
+// Flush does nothing, the response is written once processed.
+func (w *bufferedResponseWriter) Flush() {}
+
+func (w *bufferedResponseWriter) statusCode() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// writeOriginal writes the buffered response, as written by the handler.
+func (w *bufferedResponseWriter) writeOriginal() {
+	copyHeader(w.rw.Header(), w.header)
+	w.rw.WriteHeader(w.statusCode())
+
+	if _, err := w.rw.Write(w.body.Bytes()); err != nil {
+		log.Error(err)
+	}
+}
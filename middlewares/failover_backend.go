@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/containous/traefik/log"
+)
+
+// FailoverBackend forwards the retries of a backend to a failover backend.
+// Its handler is set once all the backends are built.
+type FailoverBackend struct {
+	BackendName string
+	handler     http.Handler
+}
+
+// NewFailoverBackend creates a new FailoverBackend.
+// The backend name identifies the failover backend handler given to PostLoad.
+func NewFailoverBackend(backendName string) *FailoverBackend {
+	return &FailoverBackend{BackendName: backendName}
+}
+
+// PostLoad sets the handler of the failover backend.
+func (f *FailoverBackend) PostLoad(handler http.Handler) {
+	f.handler = handler
+}
+
+func (f *FailoverBackend) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if f.handler == nil {
+		// Like an empty backend, which stops the retries.
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		if _, err := rw.Write([]byte(http.StatusText(http.StatusServiceUnavailable))); err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	f.handler.ServeHTTP(rw, req)
+}
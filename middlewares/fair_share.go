@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	// maxClientShareLabels is the number of clients reported by their own label in the share metric,
+	// the others are reported together.
+	maxClientShareLabels = 100
+
+	otherClientsLabel = "other"
+)
+
+var (
+	fairShareStores     = make(map[string]*fairShareStore)
+	fairShareStoresLock sync.Mutex
+)
+
+// FairShare limits the share of the capacity of a backend used by each client, when the backend is saturated.
+// The backend is saturated when its in-flight requests reach its capacity: the requests of the clients
+// having their maximum share of the capacity in flight are then rejected.
+// Below the capacity, the clients can use more than their share.
+// The in-flight requests are shared by the fair shares of a backend,
+// so that its frontends share its capacity, and they are kept across the configuration reloads.
+type FairShare struct {
+	next        http.Handler
+	extractor   utils.SourceExtractor
+	capacity    int64
+	maxInFlight int64
+	shareGauge  gokitmetrics.Gauge
+	store       *fairShareStore
+}
+
+// fairShareStore holds the in-flight requests of a backend, in total and by client.
+type fairShareStore struct {
+	lock     sync.Mutex
+	inFlight int64
+	clients  map[string]int64
+	labels   map[string]struct{}
+	others   int64
+}
+
+func getFairShareStore(backendName string) *fairShareStore {
+	fairShareStoresLock.Lock()
+	defer fairShareStoresLock.Unlock()
+
+	s, ok := fairShareStores[backendName]
+	if !ok {
+		s = &fairShareStore{
+			clients: make(map[string]int64),
+			labels:  make(map[string]struct{}),
+		}
+		fairShareStores[backendName] = s
+	}
+	return s
+}
+
+// NewFairShare creates a new FairShare for the given backend.
+// The share gauge is optional.
+func NewFairShare(backendName string, next http.Handler, config *types.FairShare, shareGauge gokitmetrics.Gauge) (*FairShare, error) {
+	if config.Capacity <= 0 {
+		return nil, errors.New("the capacity must be greater than 0")
+	}
+
+	if config.MaxShare <= 0 || config.MaxShare > 1 {
+		return nil, errors.New("the maximum share must be greater than 0, and lower than or equal to 1")
+	}
+
+	extractor, err := utils.NewExtractor(config.ExtractorFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FairShare{
+		next:        next,
+		extractor:   extractor,
+		capacity:    config.Capacity,
+		maxInFlight: int64(math.Max(1, math.Floor(config.MaxShare*float64(config.Capacity)))),
+		shareGauge:  shareGauge,
+		store:       getFairShareStore(backendName),
+	}, nil
+}
+
+func (f *FairShare) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	client, _, err := f.extractor.Extract(req)
+	if err != nil {
+		log.Errorf("Error extracting the client of the fair share: %v", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if !f.acquire(client) {
+		log.Debugf("Client %s exceeds its share of the backend capacity", client)
+		http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	defer f.release(client)
+
+	f.next.ServeHTTP(rw, req)
+}
+
+func (f *FairShare) acquire(client string) bool {
+	f.store.lock.Lock()
+	defer f.store.lock.Unlock()
+
+	if f.store.inFlight >= f.capacity && f.store.clients[client] >= f.maxInFlight {
+		return false
+	}
+
+	f.store.inFlight++
+	f.store.clients[client]++
+	f.report(client, 1)
+
+	return true
+}
+
+func (f *FairShare) release(client string) {
+	f.store.lock.Lock()
+	defer f.store.lock.Unlock()
+
+	f.store.inFlight--
+	f.store.clients[client]--
+	f.report(client, -1)
+
+	if f.store.clients[client] <= 0 {
+		delete(f.store.clients, client)
+	}
+}
+
+// report updates the share of the client, or of the other clients when all the labels are used.
+// It must be called with the lock of the store held.
+func (f *FairShare) report(client string, delta int64) {
+	if f.shareGauge == nil {
+		return
+	}
+
+	if _, ok := f.store.labels[client]; !ok && len(f.store.labels) < maxClientShareLabels {
+		f.store.labels[client] = struct{}{}
+	}
+
+	if _, ok := f.store.labels[client]; ok {
+		f.shareGauge.With("client", client).Set(float64(f.store.clients[client]) / float64(f.capacity))
+		return
+	}
+
+	f.store.others += delta
+	f.shareGauge.With("client", otherClientsLabel).Set(float64(f.store.others) / float64(f.capacity))
+}
@@ -0,0 +1,183 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type labelledGauge struct {
+	values map[string]float64
+	label  string
+}
+
+func (g *labelledGauge) With(labelValues ...string) gokitmetrics.Gauge {
+	return &labelledGauge{values: g.values, label: labelValues[len(labelValues)-1]}
+}
+
+func (g *labelledGauge) Set(value float64) {
+	g.values[g.label] = value
+}
+
+func (g *labelledGauge) Add(delta float64) {
+	g.values[g.label] += delta
+}
+
+// resetFairShareStore forgets the in-flight requests of the backend.
+func resetFairShareStore(backendName string) {
+	fairShareStoresLock.Lock()
+	defer fairShareStoresLock.Unlock()
+
+	delete(fairShareStores, backendName)
+}
+
+func TestNewFairShare(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		config      *types.FairShare
+		expectedMax int64
+		expectedErr bool
+	}{
+		{
+			desc:        "valid",
+			config:      &types.FairShare{Capacity: 10, MaxShare: 0.25, ExtractorFunc: "client.ip"},
+			expectedMax: 2,
+		},
+		{
+			desc:        "share lower than a request",
+			config:      &types.FairShare{Capacity: 10, MaxShare: 0.01, ExtractorFunc: "client.ip"},
+			expectedMax: 1,
+		},
+		{
+			desc:        "no capacity",
+			config:      &types.FairShare{MaxShare: 0.5, ExtractorFunc: "client.ip"},
+			expectedErr: true,
+		},
+		{
+			desc:        "share greater than 1",
+			config:      &types.FairShare{Capacity: 10, MaxShare: 1.5, ExtractorFunc: "client.ip"},
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid extractor",
+			config:      &types.FairShare{Capacity: 10, MaxShare: 0.5, ExtractorFunc: "foo"},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			fairShare, err := NewFairShare("backend", http.NotFoundHandler(), test.config, nil)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedMax, fairShare.maxInFlight)
+		})
+	}
+}
+
+func TestFairShareSaturated(t *testing.T) {
+	gauge := &labelledGauge{values: make(map[string]float64)}
+
+	resetFairShareStore(t.Name())
+
+	fairShare, err := NewFairShare(t.Name(), http.NotFoundHandler(), &types.FairShare{Capacity: 4, MaxShare: 0.5, ExtractorFunc: "request.host"}, gauge)
+	require.NoError(t, err)
+
+	// Below the capacity, a client can use more than its share.
+	for i := 0; i < 3; i++ {
+		assert.True(t, fairShare.acquire("greedy"))
+	}
+	assert.Equal(t, 0.75, gauge.values["greedy"])
+
+	assert.True(t, fairShare.acquire("polite"))
+
+	// The backend is saturated.
+	assert.False(t, fairShare.acquire("greedy"))
+	assert.True(t, fairShare.acquire("polite"))
+	assert.False(t, fairShare.acquire("polite"))
+	assert.True(t, fairShare.acquire("newcomer"))
+
+	fairShare.release("greedy")
+	fairShare.release("greedy")
+	fairShare.release("newcomer")
+	assert.Equal(t, 0.25, gauge.values["greedy"])
+
+	// The backend is not saturated anymore.
+	assert.True(t, fairShare.acquire("polite"))
+}
+
+func TestFairShareServeHTTP(t *testing.T) {
+	resetFairShareStore(t.Name())
+
+	fairShare, err := NewFairShare(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), &types.FairShare{Capacity: 1, MaxShare: 1, ExtractorFunc: "request.host"}, nil)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	fairShare.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// The request of the client is in flight.
+	require.True(t, fairShare.acquire("foo.bar"))
+
+	recorder = httptest.NewRecorder()
+	fairShare.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	fairShare.release("foo.bar")
+	assert.Empty(t, fairShare.store.clients)
+}
+
+func TestFairShareBoundedLabels(t *testing.T) {
+	gauge := &labelledGauge{values: make(map[string]float64)}
+
+	resetFairShareStore(t.Name())
+
+	fairShare, err := NewFairShare(t.Name(), http.NotFoundHandler(), &types.FairShare{Capacity: 1000, MaxShare: 0.1, ExtractorFunc: "request.host"}, gauge)
+	require.NoError(t, err)
+
+	for i := 0; i < maxClientShareLabels+10; i++ {
+		require.True(t, fairShare.acquire(fmt.Sprintf("client%d", i)))
+	}
+
+	assert.Len(t, gauge.values, maxClientShareLabels+1)
+	assert.Equal(t, 0.01, gauge.values[otherClientsLabel])
+}
+
+func TestFairShareSharedByBackend(t *testing.T) {
+	resetFairShareStore(t.Name())
+
+	config := &types.FairShare{Capacity: 2, MaxShare: 0.5, ExtractorFunc: "request.host"}
+
+	fairShare, err := NewFairShare(t.Name(), http.NotFoundHandler(), config, nil)
+	require.NoError(t, err)
+
+	// The fair share of another frontend of the backend, or of the reloaded configuration.
+	other, err := NewFairShare(t.Name(), http.NotFoundHandler(), config, nil)
+	require.NoError(t, err)
+
+	assert.True(t, fairShare.acquire("greedy"))
+	assert.True(t, other.acquire("greedy"))
+
+	// The backend is saturated by the requests of both.
+	assert.False(t, fairShare.acquire("greedy"))
+	assert.False(t, other.acquire("greedy"))
+
+	fairShare.release("greedy")
+	other.release("greedy")
+	assert.Empty(t, other.store.clients)
+}
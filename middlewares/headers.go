@@ -3,11 +3,26 @@ package middlewares
 // Middleware based on https://github.com/unrolled/secure
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/types"
 )
 
+// headerTemplateFuncs are the helper functions available in the header templates.
+var headerTemplateFuncs = template.FuncMap{
+	"now":   time.Now,
+	"first": firstValue,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
 // HeaderOptions is a struct for specifying configuration options for the headers middleware.
 type HeaderOptions struct {
 	// If Custom request headers are set, these will be added to the request
@@ -21,12 +36,34 @@ type HeaderOptions struct {
 type HeaderStruct struct {
 	// Customize headers with a headerOptions struct.
 	opt HeaderOptions
+	// Templates of the custom header values containing template actions, by header name.
+	requestTemplates  map[string]*template.Template
+	responseTemplates map[string]*template.Template
+}
+
+// headerTemplateData is the data given to the header templates.
+type headerTemplateData struct {
+	Method string
+	Host   string
+	Path   string
+	Header http.Header
 }
 
 // NewHeaderFromStruct constructs a new header instance from supplied frontend header struct.
-func NewHeaderFromStruct(headers *types.Headers) *HeaderStruct {
+// The custom header values containing template actions are compiled once, here.
+func NewHeaderFromStruct(headers *types.Headers) (*HeaderStruct, error) {
 	if headers == nil || !headers.HasCustomHeadersDefined() {
-		return nil
+		return nil, nil
+	}
+
+	requestTemplates, err := parseHeaderTemplates(headers.CustomRequestHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom request header: %v", err)
+	}
+
+	responseTemplates, err := parseHeaderTemplates(headers.CustomResponseHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("invalid custom response header: %v", err)
 	}
 
 	return &HeaderStruct{
@@ -34,7 +71,47 @@ func NewHeaderFromStruct(headers *types.Headers) *HeaderStruct {
 			CustomRequestHeaders:  headers.CustomRequestHeaders,
 			CustomResponseHeaders: headers.CustomResponseHeaders,
 		},
+		requestTemplates:  requestTemplates,
+		responseTemplates: responseTemplates,
+	}, nil
+}
+
+// headerTemplateCheckFuncs replace, to check the templates against an empty request, the functions which fail on missing values.
+var headerTemplateCheckFuncs = template.FuncMap{
+	"index": func(item interface{}, indices ...interface{}) string { return "" },
+}
+
+// parseHeaderTemplates compiles the header values containing template actions, the other values are used as is.
+func parseHeaderTemplates(headers map[string]string) (map[string]*template.Template, error) {
+	var templates map[string]*template.Template
+
+	for header, value := range headers {
+		if !strings.Contains(value, "{{") {
+			continue
+		}
+
+		tmpl, err := template.New(header).Funcs(headerTemplateFuncs).Parse(value)
+		if err != nil {
+			return nil, err
+		}
+
+		// The fields and methods are only resolved on execution, an empty request catches the invalid ones.
+		// The index function fails on the missing values of a request, it is only checked for each request.
+		check, err := tmpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if err := check.Funcs(headerTemplateCheckFuncs).Execute(ioutil.Discard, headerTemplateData{Header: http.Header{}}); err != nil {
+			return nil, err
+		}
+
+		if templates == nil {
+			templates = make(map[string]*template.Template)
+		}
+		templates[header] = tmpl
 	}
+
+	return templates, nil
 }
 
 func (s *HeaderStruct) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
@@ -49,6 +126,10 @@ func (s *HeaderStruct) ServeHTTP(w http.ResponseWriter, r *http.Request, next ht
 func (s *HeaderStruct) ModifyRequestHeaders(r *http.Request) {
 	// Loop through Custom request headers
 	for header, value := range s.opt.CustomRequestHeaders {
+		if tmpl, ok := s.requestTemplates[header]; ok {
+			value = executeHeaderTemplate(tmpl, r)
+		}
+
 		if value == "" {
 			r.Header.Del(header)
 		} else {
@@ -61,6 +142,10 @@ func (s *HeaderStruct) ModifyRequestHeaders(r *http.Request) {
 func (s *HeaderStruct) ModifyResponseHeaders(res *http.Response) error {
 	// Loop through Custom response headers
 	for header, value := range s.opt.CustomResponseHeaders {
+		if tmpl, ok := s.responseTemplates[header]; ok && res.Request != nil {
+			value = executeHeaderTemplate(tmpl, res.Request)
+		}
+
 		if value == "" {
 			res.Header.Del(header)
 		} else {
@@ -69,3 +154,24 @@ func (s *HeaderStruct) ModifyResponseHeaders(res *http.Response) error {
 	}
 	return nil
 }
+
+func executeHeaderTemplate(tmpl *template.Template, r *http.Request) string {
+	data := headerTemplateData{
+		Method: r.Method,
+		Host:   r.Host,
+		Path:   r.URL.Path,
+		Header: r.Header,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Debugf("Unable to execute the template of the header %s: %v", tmpl.Name(), err)
+		return ""
+	}
+	return buf.String()
+}
+
+// firstValue returns the first value of a comma separated list, e.g. the client IP of a X-Forwarded-For header.
+func firstValue(value string) string {
+	return strings.TrimSpace(strings.SplitN(value, ",", 2)[0])
+}
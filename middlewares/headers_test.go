@@ -8,7 +8,9 @@ import (
 	"testing"
 
 	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var myHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -116,3 +118,77 @@ func TestCustomRequestHeaderEmptyValue(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.Code, "Status not OK")
 	assert.Equal(t, "", req.Header.Get("X-Custom-Request-Header"), "This header is not expected")
 }
+
+func TestHeaderTemplates(t *testing.T) {
+	header, err := NewHeaderFromStruct(&types.Headers{
+		CustomRequestHeaders: map[string]string{
+			"X-Static":    "{ not a template }",
+			"X-Client-IP": `{{ first (.Header.Get "X-Forwarded-For") }}`,
+			"X-Route":     "{{ .Method }} {{ lower .Host }}{{ .Path }}",
+			"X-Empty":     `{{ .Header.Get "X-Missing" }}`,
+			"X-First-Hop": `{{ index .Header "X-Forwarded-For" 0 }}`,
+			"X-Failed":    `{{ index .Header "X-Missing" 0 }}`,
+		},
+		CustomResponseHeaders: map[string]string{
+			"X-Request-Start": "t={{ now.Unix }}",
+			"X-Request-Host":  "{{ .Host }}",
+		},
+	})
+	require.NoError(t, err)
+
+	req := testhelpers.MustNewRequest(http.MethodGet, "http://Example.com/foo", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+	req.Header.Set("X-Empty", "foo")
+	req.Header.Set("X-Failed", "foo")
+
+	header.ServeHTTP(httptest.NewRecorder(), req, nil)
+
+	assert.Equal(t, "{ not a template }", req.Header.Get("X-Static"))
+	assert.Equal(t, "10.0.0.1", req.Header.Get("X-Client-IP"))
+	assert.Equal(t, "GET example.com/foo", req.Header.Get("X-Route"))
+	assert.NotContains(t, req.Header, "X-Empty")
+	assert.Equal(t, "10.0.0.1, 10.0.0.2", req.Header.Get("X-First-Hop"))
+	// The templates failing to execute remove the header.
+	assert.NotContains(t, req.Header, "X-Failed")
+
+	res := &http.Response{Request: req, Header: make(http.Header)}
+	err = header.ModifyResponseHeaders(res)
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^t=[0-9]+$`, res.Header.Get("X-Request-Start"))
+	assert.Equal(t, "Example.com", res.Header.Get("X-Request-Host"))
+}
+
+func TestNewHeaderFromStructFail(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		headers *types.Headers
+	}{
+		{
+			desc:    "invalid request header template",
+			headers: &types.Headers{CustomRequestHeaders: map[string]string{"X-Foo": "{{ .Method "}},
+		},
+		{
+			desc:    "unknown response header template function",
+			headers: &types.Headers{CustomResponseHeaders: map[string]string{"X-Foo": "{{ unknown .Method }}"}},
+		},
+		{
+			desc:    "unknown response header template field",
+			headers: &types.Headers{CustomResponseHeaders: map[string]string{"X-Foo": "{{ .Unknown }}"}},
+		},
+		{
+			desc:    "unknown request header template field in an index",
+			headers: &types.Headers{CustomRequestHeaders: map[string]string{"X-Foo": `{{ index .Unknwon "X-Forwarded-For" 0 }}`}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewHeaderFromStruct(test.headers)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+)
+
+const connectRetryInterval = 30 * time.Second
+
+// ConnectFunc connects a Kafka producer to the brokers, it is sarama.NewAsyncProducer outside of the tests.
+type ConnectFunc func(brokers []string, config *sarama.Config) (sarama.AsyncProducer, error)
+
+// NewConfig returns the default configuration of the producers, which report their delivery failures.
+func NewConfig() *sarama.Config {
+	config := sarama.NewConfig()
+	config.ClientID = "traefik"
+	config.Producer.Return.Errors = true
+	return config
+}
+
+// Producer is a Kafka producer connected in the background.
+// The messages are dropped while it is not connected to its brokers or is overloaded,
+// so that the requests are never stalled by Kafka.
+type Producer struct {
+	name    string
+	brokers []string
+	config  *sarama.Config
+	connect ConnectFunc
+	fail    func(message *sarama.ProducerMessage)
+
+	stop      chan struct{}
+	delivered chan struct{}
+
+	lock     sync.RWMutex
+	producer sarama.AsyncProducer
+	closed   bool
+}
+
+// NewProducer creates a new Producer, and connects it in the background, retrying until it succeeds or the producer is closed.
+// The name describes the publisher in the logs, and the fail function, called with each dropped or undelivered message, is optional.
+func NewProducer(name string, brokers []string, config *sarama.Config, connect ConnectFunc, fail func(message *sarama.ProducerMessage)) *Producer {
+	producer := &Producer{
+		name:      name,
+		brokers:   brokers,
+		config:    config,
+		connect:   connect,
+		fail:      fail,
+		stop:      make(chan struct{}),
+		delivered: make(chan struct{}),
+	}
+
+	safe.Go(producer.run)
+
+	return producer
+}
+
+// run connects the producer, retrying until it succeeds or the producer is closed, and then reports the delivery failures.
+func (p *Producer) run() {
+	for {
+		producer, err := p.connect(p.brokers, p.config)
+		if err == nil {
+			if p.setProducer(producer) {
+				for err := range producer.Errors() {
+					log.Debugf("Unable to publish a message of the %s to the Kafka topic %s: %v", p.name, err.Msg.Topic, err.Err)
+					p.failed(err.Msg)
+				}
+				close(p.delivered)
+			}
+			return
+		}
+
+		log.Errorf("Unable to connect the %s to the Kafka brokers %v, retrying in %s: %v", p.name, p.brokers, connectRetryInterval, err)
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(connectRetryInterval):
+		}
+	}
+}
+
+// setProducer sets the connected producer, or closes it, and returns false, if the producer has been closed meanwhile.
+func (p *Producer) setProducer(producer sarama.AsyncProducer) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		producer.AsyncClose()
+		return false
+	}
+
+	p.producer = producer
+	return true
+}
+
+// Connected returns whether the producer is connected to its brokers.
+func (p *Producer) Connected() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.producer != nil
+}
+
+// Publish publishes the message without waiting, dropping it if the producer is not connected or is overloaded.
+func (p *Producer) Publish(message *sarama.ProducerMessage) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.producer == nil {
+		p.failed(message)
+		return
+	}
+
+	select {
+	case p.producer.Input() <- message:
+	default:
+		p.failed(message)
+	}
+}
+
+func (p *Producer) failed(message *sarama.ProducerMessage) {
+	if p.fail != nil {
+		p.fail(message)
+	}
+}
+
+// Close closes the producer, and waits for the delivery of its buffered messages if it is connected.
+func (p *Producer) Close() {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.stop)
+
+	producer := p.producer
+	p.producer = nil
+	p.lock.Unlock()
+
+	if producer != nil {
+		producer.AsyncClose()
+		<-p.delivered
+	}
+}
@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProducer struct {
+	input  chan *sarama.ProducerMessage
+	errors chan *sarama.ProducerError
+}
+
+func newFakeProducer(size int) *fakeProducer {
+	return &fakeProducer{
+		input:  make(chan *sarama.ProducerMessage, size),
+		errors: make(chan *sarama.ProducerError, size),
+	}
+}
+
+func (f *fakeProducer) AsyncClose()                               { close(f.errors) }
+func (f *fakeProducer) Close() error                              { f.AsyncClose(); return nil }
+func (f *fakeProducer) Input() chan<- *sarama.ProducerMessage     { return f.input }
+func (f *fakeProducer) Successes() <-chan *sarama.ProducerMessage { return nil }
+func (f *fakeProducer) Errors() <-chan *sarama.ProducerError      { return f.errors }
+
+// waitConnected waits for the producer to be connected.
+func waitConnected(t *testing.T, producer *Producer) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !producer.Connected() {
+		require.True(t, time.Now().Before(deadline), "the producer must be connected")
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestProducer(t *testing.T) {
+	fake := newFakeProducer(1)
+
+	var failed []*sarama.ProducerMessage
+	connected := make(chan struct{})
+	producer := NewProducer("test", []string{"broker1:9092"}, NewConfig(), func(brokers []string, config *sarama.Config) (sarama.AsyncProducer, error) {
+		<-connected
+		return fake, nil
+	}, func(message *sarama.ProducerMessage) {
+		failed = append(failed, message)
+	})
+
+	// The messages are dropped until the producer is connected.
+	notConnected := &sarama.ProducerMessage{Topic: "not-connected"}
+	producer.Publish(notConnected)
+	assert.Equal(t, []*sarama.ProducerMessage{notConnected}, failed)
+
+	close(connected)
+	waitConnected(t, producer)
+
+	// The messages are dropped when the producer is overloaded.
+	delivered := &sarama.ProducerMessage{Topic: "delivered"}
+	producer.Publish(delivered)
+	overloaded := &sarama.ProducerMessage{Topic: "overloaded"}
+	producer.Publish(overloaded)
+	assert.Len(t, fake.input, 1)
+	assert.Equal(t, []*sarama.ProducerMessage{notConnected, overloaded}, failed)
+
+	// The delivery failures are reported until the producer is closed.
+	fake.errors <- &sarama.ProducerError{Msg: <-fake.input, Err: errors.New("delivery failed")}
+	producer.Close()
+	assert.Equal(t, []*sarama.ProducerMessage{notConnected, overloaded, delivered}, failed)
+
+	// The messages are dropped once the producer is closed.
+	closed := &sarama.ProducerMessage{Topic: "closed"}
+	producer.Publish(closed)
+	assert.Equal(t, []*sarama.ProducerMessage{notConnected, overloaded, delivered, closed}, failed)
+}
+
+func TestProducerCloseNotConnected(t *testing.T) {
+	connected := make(chan struct{})
+	producer := NewProducer("test", []string{"broker1:9092"}, NewConfig(), func(brokers []string, config *sarama.Config) (sarama.AsyncProducer, error) {
+		<-connected
+		return nil, errors.New("no brokers available")
+	}, nil)
+
+	// The producer is closed without waiting for the connection, and drops the messages without a fail function.
+	producer.Close()
+	producer.Publish(&sarama.ProducerMessage{Topic: "closed"})
+	assert.False(t, producer.Connected())
+
+	close(connected)
+}
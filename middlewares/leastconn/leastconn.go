@@ -0,0 +1,192 @@
+package leastconn
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/containous/traefik/log"
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+var (
+	stores     = make(map[string]*store)
+	storesLock sync.Mutex
+)
+
+// Balancer is a least connections load balancer:
+// it forwards each request to the server with the fewest active requests, the ties being broken in turn.
+// A request is active until its handler returns, which includes the whole lifetime of an upgraded connection (e.g. WebSocket).
+// The active requests are shared by the balancers of a backend, so they are still counted after a configuration reload.
+type Balancer struct {
+	next          http.Handler
+	stickySession *roundrobin.StickySession
+	active        *store
+
+	mu      sync.RWMutex
+	servers []*url.URL
+	offset  int
+}
+
+// New creates a new Balancer for the given backend.
+func New(backendName string, next http.Handler, stickySession *roundrobin.StickySession) *Balancer {
+	return &Balancer{
+		next:          next,
+		stickySession: stickySession,
+		active:        getStore(backendName),
+	}
+}
+
+func getStore(backendName string) *store {
+	storesLock.Lock()
+	defer storesLock.Unlock()
+
+	s, ok := stores[backendName]
+	if !ok {
+		s = &store{servers: make(map[string]int)}
+		stores[backendName] = s
+	}
+	return s
+}
+
+func (b *Balancer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// Shallow copy of the request, to avoid side effects.
+	newReq := *req
+
+	stuck := false
+	if b.stickySession != nil {
+		cookieURL, present, err := b.stickySession.GetBackend(&newReq, b.Servers())
+		if err != nil {
+			log.Warnf("Error using server from cookie: %v", err)
+		}
+
+		if present {
+			newReq.URL = cookieURL
+			stuck = true
+		}
+	}
+
+	if !stuck {
+		u, err := b.NextServer()
+		if err != nil {
+			utils.DefaultHandler.ServeHTTP(rw, req, err)
+			return
+		}
+
+		if b.stickySession != nil {
+			b.stickySession.StickBackend(u, &rw)
+		}
+		newReq.URL = u
+	}
+
+	server := newReq.URL.String()
+	b.active.acquire(server)
+	// Released even if the handler panics.
+	defer b.active.release(server)
+
+	b.next.ServeHTTP(rw, &newReq)
+}
+
+// NextServer returns the server with the fewest active requests.
+// The selected server is counted as active when the request is served.
+func (b *Balancer) NextServer() (*url.URL, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.servers) == 0 {
+		return nil, errors.New("no servers in the pool")
+	}
+
+	// The servers are scanned from a rotating offset, so the ties are broken in turn.
+	b.offset = (b.offset + 1) % len(b.servers)
+
+	var selected *url.URL
+	min := -1
+	for i := range b.servers {
+		u := b.servers[(b.offset+i)%len(b.servers)]
+		if count := b.active.get(u.String()); min < 0 || count < min {
+			selected = u
+			min = count
+		}
+	}
+
+	return selected, nil
+}
+
+// Servers returns the servers of the pool.
+func (b *Balancer) Servers() []*url.URL {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	servers := make([]*url.URL, len(b.servers))
+	copy(servers, b.servers)
+	return servers
+}
+
+// RemoveServer removes a server from the pool.
+// Its active requests are still counted, in case it comes back.
+func (b *Balancer) RemoveServer(u *url.URL) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, srv := range b.servers {
+		if srv.String() == u.String() {
+			b.servers = append(b.servers[:i:i], b.servers[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("server not found")
+}
+
+// UpsertServer adds a server to the pool.
+// The options, such as the weight, are ignored: the servers are chosen on their active requests.
+func (b *Balancer) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	if u == nil {
+		return errors.New("server URL can't be nil")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, srv := range b.servers {
+		if srv.String() == u.String() {
+			return nil
+		}
+	}
+
+	b.servers = append(b.servers, utils.CopyURL(u))
+	return nil
+}
+
+// store holds the number of active requests of the servers of a backend, by URL.
+// The servers without active requests are not stored.
+type store struct {
+	mu      sync.Mutex
+	servers map[string]int
+}
+
+func (s *store) get(u string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.servers[u]
+}
+
+func (s *store) acquire(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.servers[u]++
+}
+
+func (s *store) release(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.servers[u]--
+	if s.servers[u] <= 0 {
+		delete(s.servers, u)
+	}
+}
@@ -0,0 +1,127 @@
+package leastconn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+func TestBalancerNoServers(t *testing.T) {
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+
+	recorder := httptest.NewRecorder()
+	lb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestBalancerTiesInTurn(t *testing.T) {
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://first")))
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://second")))
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://third")))
+
+	servers := make(map[string]int)
+	for i := 0; i < 9; i++ {
+		u, err := lb.NextServer()
+		require.NoError(t, err)
+		servers[u.Host]++
+	}
+
+	assert.Equal(t, map[string]int{"first": 3, "second": 3, "third": 3}, servers)
+}
+
+func TestBalancerLeastActive(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan string)
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Long-Lived") != "" {
+			started <- req.URL.Host
+			<-release
+		}
+		rw.Write([]byte(req.URL.Host))
+	})
+
+	lb := New(t.Name(), next, nil)
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://first")))
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://second")))
+
+	done := make(chan struct{})
+	go func() {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.Header.Set("Long-Lived", "true")
+		lb.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	busy := <-started
+
+	// The short requests go to the other server while the long-lived one is active.
+	for i := 0; i < 4; i++ {
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.NotEqual(t, busy, recorder.Body.String())
+	}
+
+	close(release)
+	<-done
+	assert.Equal(t, 0, lb.active.get("http://"+busy))
+}
+
+func TestBalancerReleaseOnPanic(t *testing.T) {
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), nil)
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://first")))
+
+	assert.Panics(t, func() {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	})
+
+	assert.Equal(t, 0, lb.active.get("http://first"))
+}
+
+func TestBalancerActiveSurviveReload(t *testing.T) {
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+	lb.active.acquire("http://first")
+
+	reloaded := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+	require.NoError(t, reloaded.UpsertServer(testhelpers.MustParseURL("http://first")))
+	require.NoError(t, reloaded.UpsertServer(testhelpers.MustParseURL("http://second")))
+
+	for i := 0; i < 2; i++ {
+		u, err := reloaded.NextServer()
+		require.NoError(t, err)
+		assert.Equal(t, "second", u.Host)
+	}
+}
+
+func TestBalancerStickySession(t *testing.T) {
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.Host))
+	}), roundrobin.NewStickySession("sticky"))
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://first")))
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://second")))
+
+	// The other server has fewer active requests.
+	lb.active.acquire("http://first")
+	defer lb.active.release("http://first")
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.AddCookie(&http.Cookie{Name: "sticky", Value: "http://first"})
+
+	recorder := httptest.NewRecorder()
+	lb.ServeHTTP(recorder, req)
+	assert.Equal(t, "first", recorder.Body.String())
+
+	// A removed server is not stuck to.
+	require.NoError(t, lb.RemoveServer(testhelpers.MustParseURL("http://first")))
+
+	recorder = httptest.NewRecorder()
+	lb.ServeHTTP(recorder, req)
+	assert.Equal(t, "second", recorder.Body.String())
+}
@@ -0,0 +1,149 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/ip"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/tracing"
+	"github.com/containous/traefik/types"
+)
+
+// Maintenance is a middleware serving a maintenance response instead of forwarding the requests,
+// except for the requests from the allowed source range, or carrying the bypass secret in a header or a cookie.
+// When methods are configured, the requests with the other methods are always forwarded.
+type Maintenance struct {
+	methods      map[string]bool
+	statusCode   int
+	contentType  string
+	body         []byte
+	retryAfter   string
+	checker      *ip.Checker
+	strategy     ip.Strategy
+	bypassHeader string
+	bypassCookie string
+	bypassSecret []byte
+}
+
+// NewMaintenance creates a new Maintenance.
+// The strategy gets the client IP checked against the source range.
+func NewMaintenance(config *types.Maintenance, strategy ip.Strategy) (*Maintenance, error) {
+	if len(config.Body) > 0 && len(config.BodyFile) > 0 {
+		return nil, errors.New("body and bodyFile are mutually exclusive")
+	}
+
+	hasBypass := len(config.BypassHeader) > 0 || len(config.BypassCookie) > 0
+	if hasBypass != (len(config.BypassSecret) > 0) {
+		return nil, errors.New("bypassSecret requires bypassHeader or bypassCookie, and the other way around")
+	}
+
+	m := &Maintenance{
+		statusCode:   http.StatusServiceUnavailable,
+		contentType:  config.ContentType,
+		body:         []byte(config.Body),
+		strategy:     strategy,
+		bypassHeader: config.BypassHeader,
+		bypassCookie: config.BypassCookie,
+		bypassSecret: []byte(config.BypassSecret),
+	}
+
+	if len(config.Methods) > 0 {
+		m.methods = make(map[string]bool)
+		for _, method := range config.Methods {
+			if len(method) == 0 {
+				return nil, errors.New("empty method")
+			}
+			m.methods[strings.ToUpper(method)] = true
+		}
+	}
+
+	if config.StatusCode != 0 {
+		if config.StatusCode < 100 || config.StatusCode > 599 {
+			return nil, fmt.Errorf("invalid status code %d", config.StatusCode)
+		}
+		m.statusCode = config.StatusCode
+	}
+
+	if config.RetryAfter < 0 {
+		return nil, fmt.Errorf("invalid retryAfter %d, must be positive", config.RetryAfter)
+	}
+	if config.RetryAfter > 0 {
+		m.retryAfter = strconv.Itoa(config.RetryAfter)
+	}
+
+	if len(config.BodyFile) > 0 {
+		body, err := ioutil.ReadFile(config.BodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the body file: %v", err)
+		}
+		m.body = body
+	}
+
+	if len(m.body) == 0 {
+		m.body = []byte(http.StatusText(m.statusCode))
+	}
+
+	if len(config.SourceRange) > 0 {
+		checker, err := ip.NewChecker(config.SourceRange)
+		if err != nil {
+			return nil, fmt.Errorf("parsing CIDR source range %s: %v", config.SourceRange, err)
+		}
+		m.checker = checker
+	}
+
+	return m, nil
+}
+
+func (m *Maintenance) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if m.isBypassed(r) {
+		next(rw, r)
+		return
+	}
+
+	tracing.SetErrorAndDebugLog(r, "request %s - serving the maintenance response", r.URL)
+
+	if len(m.contentType) > 0 {
+		rw.Header().Set("Content-Type", m.contentType)
+	}
+	if len(m.retryAfter) > 0 {
+		rw.Header().Set("Retry-After", m.retryAfter)
+	}
+
+	rw.WriteHeader(m.statusCode)
+
+	if _, err := rw.Write(m.body); err != nil {
+		log.Error(err)
+	}
+}
+
+func (m *Maintenance) isBypassed(r *http.Request) bool {
+	if m.methods != nil && !m.methods[r.Method] {
+		return true
+	}
+
+	if m.checker != nil && m.checker.IsAuthorized(m.strategy.GetIP(r)) == nil {
+		return true
+	}
+
+	if len(m.bypassHeader) > 0 && m.isSecret(r.Header.Get(m.bypassHeader)) {
+		return true
+	}
+
+	if len(m.bypassCookie) > 0 {
+		if cookie, err := r.Cookie(m.bypassCookie); err == nil && m.isSecret(cookie.Value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Maintenance) isSecret(value string) bool {
+	return len(m.bypassSecret) > 0 && subtle.ConstantTimeCompare([]byte(value), m.bypassSecret) == 1
+}
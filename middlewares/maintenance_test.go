@@ -0,0 +1,219 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containous/traefik/ip"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenance(t *testing.T) {
+	config := &types.Maintenance{
+		Enabled:      true,
+		ContentType:  "text/html",
+		Body:         "<h1>Maintenance</h1>",
+		RetryAfter:   120,
+		SourceRange:  []string{"10.0.0.0/8"},
+		BypassHeader: "X-Maintenance-Bypass",
+		BypassCookie: "maintenance_bypass",
+		BypassSecret: "s3cr3t",
+	}
+
+	testCases := []struct {
+		desc               string
+		config             *types.Maintenance
+		method             string
+		remoteAddr         string
+		headers            map[string]string
+		expectedNextCalled bool
+		expectedCode       int
+		expectedHeaders    map[string]string
+		expectedBody       string
+	}{
+		{
+			desc:         "maintenance response",
+			config:       config,
+			remoteAddr:   "192.168.1.1:1234",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedHeaders: map[string]string{
+				"Content-Type": "text/html",
+				"Retry-After":  "120",
+			},
+			expectedBody: "<h1>Maintenance</h1>",
+		},
+		{
+			desc:               "allowed source range",
+			config:             config,
+			remoteAddr:         "10.1.2.3:1234",
+			expectedNextCalled: true,
+			expectedCode:       http.StatusOK,
+		},
+		{
+			desc:               "bypass header",
+			config:             config,
+			remoteAddr:         "192.168.1.1:1234",
+			headers:            map[string]string{"X-Maintenance-Bypass": "s3cr3t"},
+			expectedNextCalled: true,
+			expectedCode:       http.StatusOK,
+		},
+		{
+			desc:               "bypass cookie",
+			config:             config,
+			remoteAddr:         "192.168.1.1:1234",
+			headers:            map[string]string{"Cookie": "maintenance_bypass=s3cr3t"},
+			expectedNextCalled: true,
+			expectedCode:       http.StatusOK,
+		},
+		{
+			desc:         "wrong bypass secret",
+			config:       config,
+			remoteAddr:   "192.168.1.1:1234",
+			headers:      map[string]string{"X-Maintenance-Bypass": "guess", "Cookie": "maintenance_bypass=guess"},
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: "<h1>Maintenance</h1>",
+		},
+		{
+			desc:         "default response",
+			config:       &types.Maintenance{Enabled: true},
+			remoteAddr:   "10.1.2.3:1234",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: "Service Unavailable",
+		},
+		{
+			desc:         "custom status code",
+			config:       &types.Maintenance{Enabled: true, StatusCode: http.StatusOK, Body: "maintenance"},
+			remoteAddr:   "10.1.2.3:1234",
+			expectedCode: http.StatusOK,
+			expectedBody: "maintenance",
+		},
+		{
+			desc:         "maintenance method",
+			config:       &types.Maintenance{Enabled: true, Methods: []string{"post", "PUT", "PATCH", "DELETE"}, RetryAfter: 60},
+			method:       http.MethodPost,
+			remoteAddr:   "192.168.1.1:1234",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedHeaders: map[string]string{
+				"Retry-After": "60",
+			},
+			expectedBody: "Service Unavailable",
+		},
+		{
+			desc:               "other method",
+			config:             &types.Maintenance{Enabled: true, Methods: []string{"post", "PUT", "PATCH", "DELETE"}, RetryAfter: 60},
+			method:             http.MethodHead,
+			remoteAddr:         "192.168.1.1:1234",
+			expectedNextCalled: true,
+			expectedCode:       http.StatusOK,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			maintenance, err := NewMaintenance(test.config, &ip.RemoteAddrStrategy{})
+			require.NoError(t, err)
+
+			method := http.MethodGet
+			if len(test.method) > 0 {
+				method = test.method
+			}
+
+			req := httptest.NewRequest(method, "http://localhost", nil)
+			req.RemoteAddr = test.remoteAddr
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			var nextCalled bool
+			recorder := httptest.NewRecorder()
+			maintenance.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+			})
+
+			assert.Equal(t, test.expectedNextCalled, nextCalled)
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedBody, recorder.Body.String())
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, recorder.Header().Get(name), name)
+			}
+		})
+	}
+}
+
+func TestMaintenanceBodyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traefik-maintenance")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bodyFile := filepath.Join(dir, "maintenance.html")
+	err = ioutil.WriteFile(bodyFile, []byte("<h1>Back soon</h1>"), 0644)
+	require.NoError(t, err)
+
+	maintenance, err := NewMaintenance(&types.Maintenance{Enabled: true, BodyFile: bodyFile}, &ip.RemoteAddrStrategy{})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	maintenance.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil), nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "<h1>Back soon</h1>", recorder.Body.String())
+}
+
+func TestNewMaintenanceFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.Maintenance
+	}{
+		{
+			desc:   "body and body file",
+			config: &types.Maintenance{Body: "foo", BodyFile: "/foo.html"},
+		},
+		{
+			desc:   "missing body file",
+			config: &types.Maintenance{BodyFile: "/does/not/exist.html"},
+		},
+		{
+			desc:   "empty method",
+			config: &types.Maintenance{Methods: []string{"POST", ""}},
+		},
+		{
+			desc:   "invalid status code",
+			config: &types.Maintenance{StatusCode: 1000},
+		},
+		{
+			desc:   "negative retry after",
+			config: &types.Maintenance{RetryAfter: -1},
+		},
+		{
+			desc:   "invalid source range",
+			config: &types.Maintenance{SourceRange: []string{"foo"}},
+		},
+		{
+			desc:   "bypass header without secret",
+			config: &types.Maintenance{BypassHeader: "X-Bypass"},
+		},
+		{
+			desc:   "bypass secret without header or cookie",
+			config: &types.Maintenance{BypassSecret: "s3cr3t"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewMaintenance(test.config, &ip.RemoteAddrStrategy{})
+			assert.Error(t, err)
+		})
+	}
+}
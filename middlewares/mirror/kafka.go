@@ -0,0 +1,136 @@
+package mirror
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/kafka"
+	"github.com/containous/traefik/types"
+)
+
+// mirroredRequest is the copy of a request published to Kafka.
+type mirroredRequest struct {
+	Time          time.Time   `json:"time"`
+	Backend       string      `json:"backend"`
+	Method        string      `json:"method"`
+	Host          string      `json:"host"`
+	URI           string      `json:"uri"`
+	RemoteAddr    string      `json:"remoteAddr"`
+	Headers       http.Header `json:"headers"`
+	Body          []byte      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+// KafkaMirror publishes a copy of a sample of the requests of a backend to a Kafka topic, before forwarding them.
+// The copies are published asynchronously, and dropped when they can't be published, so that the requests are never affected.
+type KafkaMirror struct {
+	next        http.Handler
+	backendName string
+	topic       string
+	percent     int
+	maxBodySize int64
+	brokers     []string
+	producers   *KafkaProducers
+	producer    *kafka.Producer
+}
+
+// NewKafkaMirror creates a new KafkaMirror, publishing with the producer of its brokers, got once the configuration is loaded.
+func NewKafkaMirror(next http.Handler, backendName string, config *types.KafkaMirror, producers *KafkaProducers) (*KafkaMirror, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("no Kafka brokers")
+	}
+
+	if len(config.Topic) == 0 {
+		return nil, errors.New("no Kafka topic")
+	}
+
+	if config.Percent < 0 || config.Percent > 100 {
+		return nil, fmt.Errorf("invalid percent %d, must be between 0 and 100", config.Percent)
+	}
+
+	if config.MaxBodySize < 0 {
+		return nil, errors.New("the maximum body size must be positive")
+	}
+
+	m := &KafkaMirror{
+		next:        next,
+		backendName: backendName,
+		topic:       config.Topic,
+		percent:     100,
+		maxBodySize: config.MaxBodySize,
+		brokers:     config.Brokers,
+		producers:   producers,
+	}
+
+	if config.Percent > 0 {
+		m.percent = config.Percent
+	}
+
+	return m, nil
+}
+
+// PostLoad gets the producer of the brokers, connecting it in the background if it is new.
+func (m *KafkaMirror) PostLoad(_ map[string]http.Handler) error {
+	m.producer = m.producers.get(m.brokers)
+	return nil
+}
+
+func (m *KafkaMirror) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if m.percent == 100 || rand.Intn(100) < m.percent {
+		m.mirror(req)
+	}
+
+	m.next.ServeHTTP(rw, req)
+}
+
+func (m *KafkaMirror) mirror(req *http.Request) {
+	mirrored := mirroredRequest{
+		Time:       time.Now().UTC(),
+		Backend:    m.backendName,
+		Method:     req.Method,
+		Host:       req.Host,
+		URI:        req.URL.RequestURI(),
+		RemoteAddr: req.RemoteAddr,
+		Headers:    req.Header,
+	}
+
+	if m.maxBodySize > 0 && req.Body != nil && req.Body != http.NoBody {
+		body, err := ioutil.ReadAll(io.LimitReader(req.Body, m.maxBodySize+1))
+
+		// The read part of the body is given back to the request.
+		req.Body = &replayedBody{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+
+		if err != nil {
+			log.Debugf("Unable to read the body of the mirrored request of the backend %s: %v", m.backendName, err)
+		} else if int64(len(body)) > m.maxBodySize {
+			mirrored.Body = body[:m.maxBodySize]
+			mirrored.BodyTruncated = true
+		} else {
+			mirrored.Body = body
+		}
+	}
+
+	value, err := json.Marshal(mirrored)
+	if err != nil {
+		log.Debugf("Unable to encode the mirrored request of the backend %s: %v", m.backendName, err)
+		m.producers.countFailure(m.backendName)
+		return
+	}
+
+	m.producer.Publish(&sarama.ProducerMessage{Topic: m.topic, Value: sarama.ByteEncoder(value), Metadata: m.backendName})
+}
+
+// replayedBody is a request body whose beginning has been read, and is read again.
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,94 @@
+package mirror
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/containous/traefik/middlewares/kafka"
+	"github.com/containous/traefik/safe"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// KafkaProducers shares the Kafka producers of the mirrors by brokers, so that they outlive the configuration reloads.
+// The producers no longer used by the mirrors of the loaded configuration are closed by Sweep.
+type KafkaProducers struct {
+	failures gokitmetrics.Counter
+	connect  kafka.ConnectFunc
+
+	lock      sync.Mutex
+	producers map[string]*kafka.Producer
+	used      map[string]bool
+}
+
+// NewKafkaProducers creates a new KafkaProducers.
+// The failures counter, labelled with the backend, is optional.
+func NewKafkaProducers(failures gokitmetrics.Counter) *KafkaProducers {
+	return &KafkaProducers{
+		failures:  failures,
+		connect:   sarama.NewAsyncProducer,
+		producers: make(map[string]*kafka.Producer),
+		used:      make(map[string]bool),
+	}
+}
+
+// Close closes the producers, after the delivery of their buffered messages.
+func (k *KafkaProducers) Close() {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	for key, producer := range k.producers {
+		producer.Close()
+		delete(k.producers, key)
+	}
+}
+
+// Sweep closes, in the background, the producers which weren't got since the previous sweep,
+// once a configuration is loaded.
+func (k *KafkaProducers) Sweep() {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	for key, producer := range k.producers {
+		if k.used[key] {
+			continue
+		}
+
+		delete(k.producers, key)
+		safe.Go(producer.Close)
+	}
+	k.used = make(map[string]bool)
+}
+
+// get returns the producer of the brokers, connecting it in the background if it is new.
+func (k *KafkaProducers) get(brokers []string) *kafka.Producer {
+	sorted := append([]string{}, brokers...)
+	sort.Strings(sorted)
+	key := strings.Join(sorted, ",")
+
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.used[key] = true
+	if producer, ok := k.producers[key]; ok {
+		return producer
+	}
+
+	producer := kafka.NewProducer("mirror", sorted, kafka.NewConfig(), k.connect, k.fail)
+	k.producers[key] = producer
+
+	return producer
+}
+
+// fail counts the failure of the mirrored request, whose metadata is the name of its backend.
+func (k *KafkaProducers) fail(message *sarama.ProducerMessage) {
+	backendName, _ := message.Metadata.(string)
+	k.countFailure(backendName)
+}
+
+func (k *KafkaProducers) countFailure(backendName string) {
+	if k.failures != nil {
+		k.failures.With("backend", backendName).Add(1)
+	}
+}
@@ -0,0 +1,224 @@
+package mirror
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProducer struct {
+	input  chan *sarama.ProducerMessage
+	errors chan *sarama.ProducerError
+}
+
+func newFakeProducer(size int) *fakeProducer {
+	return &fakeProducer{
+		input:  make(chan *sarama.ProducerMessage, size),
+		errors: make(chan *sarama.ProducerError, size),
+	}
+}
+
+func (f *fakeProducer) AsyncClose()                               { close(f.errors) }
+func (f *fakeProducer) Close() error                              { f.AsyncClose(); return nil }
+func (f *fakeProducer) Input() chan<- *sarama.ProducerMessage     { return f.input }
+func (f *fakeProducer) Successes() <-chan *sarama.ProducerMessage { return nil }
+func (f *fakeProducer) Errors() <-chan *sarama.ProducerError      { return f.errors }
+
+// newTestMirror creates a mirror publishing with the connected fake producer.
+func newTestMirror(t *testing.T, next http.Handler, config *types.KafkaMirror, fake *fakeProducer, failures *testhelpers.CollectingCounter) *KafkaMirror {
+	t.Helper()
+
+	producers := NewKafkaProducers(failures)
+	producers.connect = func(brokers []string, config *sarama.Config) (sarama.AsyncProducer, error) {
+		return fake, nil
+	}
+
+	mirror, err := NewKafkaMirror(next, "backend1", config, producers)
+	require.NoError(t, err)
+	require.NoError(t, mirror.PostLoad(nil))
+
+	deadline := time.Now().Add(time.Second)
+	for !mirror.producer.Connected() {
+		require.True(t, time.Now().Before(deadline), "the producer must be connected")
+		time.Sleep(time.Millisecond)
+	}
+
+	return mirror
+}
+
+func TestKafkaMirror(t *testing.T) {
+	testCases := []struct {
+		desc                  string
+		maxBodySize           int64
+		body                  string
+		expectedBody          string
+		expectedBodyTruncated bool
+	}{
+		{
+			desc: "without body",
+			body: "0123456789",
+		},
+		{
+			desc:         "with body",
+			maxBodySize:  20,
+			body:         "0123456789",
+			expectedBody: "0123456789",
+		},
+		{
+			desc:         "with body of the maximum size",
+			maxBodySize:  10,
+			body:         "0123456789",
+			expectedBody: "0123456789",
+		},
+		{
+			desc:                  "with truncated body",
+			maxBodySize:           4,
+			body:                  "0123456789",
+			expectedBody:          "0123",
+			expectedBodyTruncated: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var forwardedBody string
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				body, err := ioutil.ReadAll(req.Body)
+				require.NoError(t, err)
+				forwardedBody = string(body)
+			})
+
+			fake := newFakeProducer(1)
+			config := &types.KafkaMirror{Brokers: []string{"broker1:9092"}, Topic: "requests", MaxBodySize: test.maxBodySize}
+			mirror := newTestMirror(t, next, config, fake, &testhelpers.CollectingCounter{})
+
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/foo?bar=baz", strings.NewReader(test.body))
+			req.Header.Set("X-Foo", "bar")
+
+			recorder := httptest.NewRecorder()
+			mirror.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, test.body, forwardedBody)
+
+			require.Len(t, fake.input, 1)
+			message := <-fake.input
+			assert.Equal(t, "requests", message.Topic)
+			assert.Equal(t, "backend1", message.Metadata)
+
+			value, err := message.Value.Encode()
+			require.NoError(t, err)
+
+			var mirrored mirroredRequest
+			require.NoError(t, json.Unmarshal(value, &mirrored))
+			assert.Equal(t, "backend1", mirrored.Backend)
+			assert.Equal(t, http.MethodPost, mirrored.Method)
+			assert.Equal(t, "localhost", mirrored.Host)
+			assert.Equal(t, "/foo?bar=baz", mirrored.URI)
+			assert.Equal(t, "bar", mirrored.Headers.Get("X-Foo"))
+			assert.Equal(t, test.expectedBody, string(mirrored.Body))
+			assert.Equal(t, test.expectedBodyTruncated, mirrored.BodyTruncated)
+		})
+	}
+}
+
+func TestKafkaMirrorFailures(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+
+	failures := &testhelpers.CollectingCounter{}
+	fake := newFakeProducer(1)
+	mirror := newTestMirror(t, next, &types.KafkaMirror{Brokers: []string{"broker1:9092"}, Topic: "requests"}, fake, failures)
+
+	// The second request is dropped, as the producer is overloaded, without affecting the request.
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		mirror.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+	assert.Len(t, fake.input, 1)
+	assert.Equal(t, float64(1), failures.CounterValue)
+	assert.Equal(t, []string{"backend", "backend1"}, failures.LastLabelValues)
+
+	// The delivery failures are counted.
+	fake.errors <- &sarama.ProducerError{Msg: <-fake.input, Err: errors.New("delivery failed")}
+	mirror.producers.Close()
+
+	assert.Equal(t, float64(2), failures.CounterValue)
+	assert.Equal(t, []string{"backend", "backend1"}, failures.LastLabelValues)
+}
+
+func TestNewKafkaMirrorFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.KafkaMirror
+	}{
+		{
+			desc:   "no brokers",
+			config: &types.KafkaMirror{Topic: "requests"},
+		},
+		{
+			desc:   "no topic",
+			config: &types.KafkaMirror{Brokers: []string{"broker1:9092"}},
+		},
+		{
+			desc:   "invalid percent",
+			config: &types.KafkaMirror{Brokers: []string{"broker1:9092"}, Topic: "requests", Percent: 101},
+		},
+		{
+			desc:   "negative maximum body size",
+			config: &types.KafkaMirror{Brokers: []string{"broker1:9092"}, Topic: "requests", MaxBodySize: -1},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewKafkaMirror(http.NotFoundHandler(), "backend1", test.config, NewKafkaProducers(nil))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKafkaProducersSweep(t *testing.T) {
+	producers := NewKafkaProducers(nil)
+	producers.connect = func(brokers []string, config *sarama.Config) (sarama.AsyncProducer, error) {
+		return newFakeProducer(1), nil
+	}
+	defer producers.Close()
+
+	producer1 := producers.get([]string{"broker1:9092"})
+	producer2 := producers.get([]string{"broker2:9092"})
+
+	// The producers got since the previous sweep are kept.
+	producers.Sweep()
+	assert.Len(t, producers.producers, 2)
+
+	assert.Equal(t, producer1, producers.get([]string{"broker1:9092"}))
+
+	producers.Sweep()
+	assert.Len(t, producers.producers, 1)
+	assert.Equal(t, producer1, producers.producers["broker1:9092"])
+
+	// The swept producer is closed in the background.
+	deadline := time.Now().Add(time.Second)
+	for producer2.Connected() || !producer1.Connected() {
+		require.True(t, time.Now().Before(deadline), "the swept producer must be closed")
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/middlewares/tracing"
+)
+
+// MisdirectedRequest is a middleware that prevents the HTTP/2 connection coalescing.
+// Clients reuse a connection for all the hosts covered by its certificate,
+// so the requests whose host differs from the TLS server name of the connection
+// are rejected with a 421 Misdirected Request, which makes the client open a new connection.
+// With StripTrailingDot, a host ending with a dot is the same host as without it.
+type MisdirectedRequest struct {
+	StripTrailingDot bool
+}
+
+func (m *MisdirectedRequest) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.ProtoMajor != 2 || r.TLS == nil || len(r.TLS.ServerName) == 0 {
+		next(rw, r)
+		return
+	}
+
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if m.StripTrailingDot {
+		host = strings.TrimSuffix(host, ".")
+	}
+
+	if !strings.EqualFold(host, r.TLS.ServerName) {
+		tracing.SetErrorAndDebugLog(r, "rejecting request for %s from %s: connection was established for %s", r.Host, r.RemoteAddr, r.TLS.ServerName)
+		http.Error(rw, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+		return
+	}
+
+	next(rw, r)
+}
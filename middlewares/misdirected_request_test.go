@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMisdirectedRequest(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		protoMajor       int
+		serverName       string
+		noTLS            bool
+		host             string
+		stripTrailingDot bool
+		expectedCode     int
+	}{
+		{
+			desc:         "matching host",
+			protoMajor:   2,
+			serverName:   "foo.localhost",
+			host:         "foo.localhost",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "matching host with port and different case",
+			protoMajor:   2,
+			serverName:   "foo.localhost",
+			host:         "FOO.localhost:443",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "host with trailing dot",
+			protoMajor:   2,
+			serverName:   "foo.localhost",
+			host:         "foo.localhost.",
+			expectedCode: http.StatusMisdirectedRequest,
+		},
+		{
+			desc:             "host with trailing dot stripped",
+			protoMajor:       2,
+			serverName:       "foo.localhost",
+			host:             "foo.localhost.:443",
+			stripTrailingDot: true,
+			expectedCode:     http.StatusOK,
+		},
+		{
+			desc:         "coalesced request",
+			protoMajor:   2,
+			serverName:   "foo.localhost",
+			host:         "bar.localhost",
+			expectedCode: http.StatusMisdirectedRequest,
+		},
+		{
+			desc:         "HTTP/1.1 request",
+			protoMajor:   1,
+			serverName:   "foo.localhost",
+			host:         "bar.localhost",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "connection without server name",
+			protoMajor:   2,
+			host:         "bar.localhost",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "connection without TLS",
+			protoMajor:   2,
+			noTLS:        true,
+			host:         "bar.localhost",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "https://"+test.host, nil)
+			req.ProtoMajor = test.protoMajor
+			req.TLS = &tls.ConnectionState{ServerName: test.serverName}
+			if test.noTLS {
+				req.TLS = nil
+			}
+
+			recorder := httptest.NewRecorder()
+			m := &MisdirectedRequest{StripTrailingDot: test.stripTrailingDot}
+			m.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			})
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/containous/traefik/middlewares/tracing"
+)
+
+// MissingHost is a middleware that handles the requests sent without Host header (e.g. by HTTP/1.0 clients).
+// Such requests either get a default Host, so they can be routed by the Host based rules, or are rejected.
+type MissingHost struct {
+	defaultHost string
+	reject      bool
+}
+
+// NewMissingHost creates a new MissingHost.
+// The default host takes precedence over the rejection.
+func NewMissingHost(defaultHost string, reject bool) *MissingHost {
+	return &MissingHost{
+		defaultHost: defaultHost,
+		reject:      reject,
+	}
+}
+
+func (m *MissingHost) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if len(r.Host) > 0 {
+		next(rw, r)
+		return
+	}
+
+	if len(m.defaultHost) > 0 {
+		r.Host = m.defaultHost
+		next(rw, r)
+		return
+	}
+
+	if m.reject {
+		tracing.SetErrorAndDebugLog(r, "rejecting %s request from %s: missing Host header", r.Proto, r.RemoteAddr)
+		http.Error(rw, "missing Host header", http.StatusBadRequest)
+		return
+	}
+
+	next(rw, r)
+}
@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingHost(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		defaultHost  string
+		reject       bool
+		host         string
+		expectedCode int
+		expectedHost string
+	}{
+		{
+			desc:         "request with host is left untouched",
+			defaultHost:  "default.localhost",
+			reject:       true,
+			host:         "foo.localhost",
+			expectedCode: http.StatusOK,
+			expectedHost: "foo.localhost",
+		},
+		{
+			desc:         "default host is set",
+			defaultHost:  "default.localhost",
+			expectedCode: http.StatusOK,
+			expectedHost: "default.localhost",
+		},
+		{
+			desc:         "default host takes precedence over rejection",
+			defaultHost:  "default.localhost",
+			reject:       true,
+			expectedCode: http.StatusOK,
+			expectedHost: "default.localhost",
+		},
+		{
+			desc:         "request without host is rejected",
+			reject:       true,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc:         "request without host is forwarded as is",
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Proto = "HTTP/1.0"
+			req.Host = test.host
+
+			var host string
+			recorder := httptest.NewRecorder()
+			NewMissingHost(test.defaultHost, test.reject).ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				host = r.Host
+				rw.WriteHeader(http.StatusOK)
+			})
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedHost, host)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// OriginalURIKey is the key within the request context used to
+	// store the request URI before any rewriting
+	OriginalURIKey key = "OriginalURI"
+)
+
+// OriginalURI is a middleware used to record the request URI, before the path rewriting middlewares it wraps,
+// in the request context and in a header for the backend
+type OriginalURI struct {
+	Handler http.Handler
+	Header  string
+}
+
+func (o *OriginalURI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	uri := r.RequestURI
+	if len(uri) == 0 {
+		uri = r.URL.RequestURI()
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), OriginalURIKey, uri))
+	// The header sent by the client is overwritten, so that the backend can trust it.
+	r.Header.Set(o.Header, uri)
+	o.Handler.ServeHTTP(w, r)
+}
+
+// SetHandler sets handler
+func (o *OriginalURI) SetHandler(Handler http.Handler) {
+	o.Handler = Handler
+}
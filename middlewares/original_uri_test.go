@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginalURI(t *testing.T) {
+	const header = "X-Original-URI"
+
+	testCases := []struct {
+		desc         string
+		path         string
+		rewriter     func(next http.Handler) http.Handler
+		expectedPath string
+	}{
+		{
+			desc: "strip prefix",
+			path: "/api/users",
+			rewriter: func(next http.Handler) http.Handler {
+				return &StripPrefix{Prefixes: []string{"/api"}, Handler: next}
+			},
+			expectedPath: "/users",
+		},
+		{
+			desc: "strip prefix regex",
+			path: "/v1/users",
+			rewriter: func(next http.Handler) http.Handler {
+				return NewStripPrefixRegex(next, []string{"/{version:v[0-9]}"})
+			},
+			expectedPath: "/users",
+		},
+		{
+			desc: "add prefix",
+			path: "/v1/users",
+			rewriter: func(next http.Handler) http.Handler {
+				return &AddPrefix{Prefix: "/root", Handler: next}
+			},
+			expectedPath: "/root/v1/users",
+		},
+		{
+			desc: "replace path",
+			path: "/v1/users",
+			rewriter: func(next http.Handler) http.Handler {
+				return &ReplacePath{Path: "/replaced", Handler: next}
+			},
+			expectedPath: "/replaced",
+		},
+		{
+			desc: "replace path regex",
+			path: "/v1/users",
+			rewriter: func(next http.Handler) http.Handler {
+				return &ReplacePathRegex{Regexp: regexp.MustCompile("^/v1/(.*)"), Replacement: "/$1", Handler: next}
+			},
+			expectedPath: "/users",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var actualPath, actualHeader string
+			var actualContextValue interface{}
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actualPath = r.URL.Path
+				actualHeader = r.Header.Get(header)
+				actualContextValue = r.Context().Value(OriginalURIKey)
+			})
+
+			handler := &OriginalURI{Header: header, Handler: test.rewriter(next)}
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "http://localhost"+test.path+"?page=2", nil)
+			req.RequestURI = test.path + "?page=2"
+			// The header sent by the client is not trusted.
+			req.Header.Set(header, "/spoofed")
+
+			handler.ServeHTTP(nil, req)
+
+			assert.Equal(t, test.expectedPath, actualPath)
+			assert.Equal(t, test.path+"?page=2", actualHeader)
+			assert.Equal(t, test.path+"?page=2", actualContextValue)
+		})
+	}
+}
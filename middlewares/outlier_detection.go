@@ -0,0 +1,237 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/mailgun/timetools"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+const (
+	defaultOutlierConsecutiveErrors  = 5
+	defaultOutlierInterval           = 10 * time.Second
+	defaultOutlierBaseEjectionTime   = 30 * time.Second
+	defaultOutlierMaxEjectionPercent = 10
+)
+
+// outlierBalancer is the part of the load balancer used by the OutlierDetector.
+type outlierBalancer interface {
+	Servers() []*url.URL
+	RemoveServer(u *url.URL) error
+	UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error
+}
+
+// OutlierDetector ejects from the load balancer the servers returning consecutive server errors,
+// the timeouts and connection errors included, and puts them back once their ejection time is over.
+// The ejection time is the base ejection time multiplied by the number of ejections of the server,
+// which decreases by one for each interval without ejection.
+// It is placed between the load balancer and the forwarder, to know the server of each request.
+type OutlierDetector struct {
+	next               http.Handler
+	consecutiveErrors  int
+	interval           time.Duration
+	baseEjectionTime   time.Duration
+	maxEjectionPercent int
+	servers            map[string]types.Server
+	ejectionsCounter   gokitmetrics.Counter
+	serverUpGauge      gokitmetrics.Gauge
+	clock              timetools.TimeProvider
+
+	mu       sync.Mutex
+	lb       outlierBalancer
+	states   map[string]*outlierServer
+	nbEjects int
+}
+
+type outlierServer struct {
+	url          *url.URL
+	errors       int
+	ejections    int
+	ejectedUntil time.Time
+	reinstatedAt time.Time
+}
+
+// NewOutlierDetector creates a new OutlierDetector.
+// The servers of the backend, by parsed URL, are used to put them back in the load balancer with their weight,
+// and to label the metrics with their configured URL. The metrics, labelled with the backend, are optional.
+func NewOutlierDetector(next http.Handler, config *types.OutlierDetection, servers map[string]types.Server, ejectionsCounter gokitmetrics.Counter, serverUpGauge gokitmetrics.Gauge) (*OutlierDetector, error) {
+	return newOutlierDetector(next, config, servers, ejectionsCounter, serverUpGauge, &timetools.RealTime{})
+}
+
+func newOutlierDetector(next http.Handler, config *types.OutlierDetection, servers map[string]types.Server, ejectionsCounter gokitmetrics.Counter, serverUpGauge gokitmetrics.Gauge, clock timetools.TimeProvider) (*OutlierDetector, error) {
+	if config.ConsecutiveErrors < 0 {
+		return nil, errors.New("the consecutive errors must be greater than or equal to 0")
+	}
+	if config.Interval < 0 || config.BaseEjectionTime < 0 {
+		return nil, errors.New("the interval and the base ejection time must be greater than or equal to 0")
+	}
+	if config.MaxEjectionPercent < 0 || config.MaxEjectionPercent > 100 {
+		return nil, fmt.Errorf("invalid maxEjectionPercent %d, must be in [0, 100]", config.MaxEjectionPercent)
+	}
+
+	od := &OutlierDetector{
+		next:               next,
+		consecutiveErrors:  defaultOutlierConsecutiveErrors,
+		interval:           defaultOutlierInterval,
+		baseEjectionTime:   defaultOutlierBaseEjectionTime,
+		maxEjectionPercent: defaultOutlierMaxEjectionPercent,
+		servers:            servers,
+		ejectionsCounter:   ejectionsCounter,
+		serverUpGauge:      serverUpGauge,
+		clock:              clock,
+		states:             make(map[string]*outlierServer),
+	}
+
+	if config.ConsecutiveErrors > 0 {
+		od.consecutiveErrors = config.ConsecutiveErrors
+	}
+	if config.Interval > 0 {
+		od.interval = time.Duration(config.Interval)
+	}
+	if config.BaseEjectionTime > 0 {
+		od.baseEjectionTime = time.Duration(config.BaseEjectionTime)
+	}
+	if config.MaxEjectionPercent > 0 {
+		od.maxEjectionPercent = config.MaxEjectionPercent
+	}
+
+	return od, nil
+}
+
+// SetBalancer sets the load balancer the servers are ejected from.
+func (od *OutlierDetector) SetBalancer(lb outlierBalancer) {
+	od.mu.Lock()
+	defer od.mu.Unlock()
+
+	od.lb = lb
+}
+
+func (od *OutlierDetector) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	od.reinstate()
+
+	recorder := &responseRecorder{rw, http.StatusOK}
+	od.next.ServeHTTP(recorder, req)
+
+	od.record(req.URL, recorder.statusCode < http.StatusInternalServerError)
+}
+
+// reinstate puts back in the load balancer the servers at the end of their ejection time.
+func (od *OutlierDetector) reinstate() {
+	od.mu.Lock()
+	defer od.mu.Unlock()
+
+	if od.nbEjects == 0 {
+		return
+	}
+
+	now := od.clock.UtcNow()
+	for key, srv := range od.states {
+		if srv.ejectedUntil.IsZero() || now.Before(srv.ejectedUntil) {
+			continue
+		}
+
+		var options []roundrobin.ServerOption
+		if server, ok := od.servers[key]; ok {
+			options = append(options, roundrobin.Weight(server.Weight))
+		}
+
+		if err := od.lb.UpsertServer(srv.url, options...); err != nil {
+			log.Errorf("Error reinstating server %s in the load balancer: %v", key, err)
+			continue
+		}
+
+		log.Infof("Reinstating server %s after its ejection by the outlier detection", key)
+		srv.ejectedUntil = time.Time{}
+		srv.reinstatedAt = now
+		srv.errors = 0
+		od.nbEjects--
+
+		if od.serverUpGauge != nil {
+			od.serverUpGauge.With("url", od.serverURL(key)).Set(1)
+		}
+	}
+}
+
+func (od *OutlierDetector) record(u *url.URL, success bool) {
+	od.mu.Lock()
+	defer od.mu.Unlock()
+
+	if od.lb == nil || u == nil {
+		return
+	}
+
+	key := u.String()
+	srv, ok := od.states[key]
+	if !ok {
+		srv = &outlierServer{url: u}
+		od.states[key] = srv
+	}
+
+	if success {
+		srv.errors = 0
+		return
+	}
+
+	srv.errors++
+	if srv.errors < od.consecutiveErrors || !srv.ejectedUntil.IsZero() || !od.canEject() {
+		return
+	}
+
+	if err := od.lb.RemoveServer(u); err != nil {
+		// The server is not in the load balancer anymore, ejected by the health check for instance.
+		log.Debugf("Error ejecting server %s from the load balancer: %v", key, err)
+		return
+	}
+
+	now := od.clock.UtcNow()
+	if !srv.reinstatedAt.IsZero() && od.interval > 0 {
+		srv.ejections -= int(now.Sub(srv.reinstatedAt) / od.interval)
+		if srv.ejections < 0 {
+			srv.ejections = 0
+		}
+	}
+	srv.ejections++
+	srv.ejectedUntil = now.Add(od.baseEjectionTime * time.Duration(srv.ejections))
+	od.nbEjects++
+
+	log.Warnf("Ejecting server %s for %s after %d consecutive errors", key, srv.ejectedUntil.Sub(now), srv.errors)
+
+	if od.ejectionsCounter != nil {
+		od.ejectionsCounter.With("url", od.serverURL(key)).Add(1)
+	}
+	if od.serverUpGauge != nil {
+		od.serverUpGauge.With("url", od.serverURL(key)).Set(0)
+	}
+}
+
+// serverURL returns the configured URL of the server, as the other server metrics are labelled with it.
+func (od *OutlierDetector) serverURL(key string) string {
+	if server, ok := od.servers[key]; ok {
+		return server.URL
+	}
+	return key
+}
+
+// canEject returns whether one more server can be ejected:
+// the last server of the load balancer is never ejected,
+// and one server can always be ejected whatever the maximum ejection percent.
+func (od *OutlierDetector) canEject() bool {
+	inRotation := len(od.lb.Servers())
+	if inRotation <= 1 {
+		return false
+	}
+
+	if od.nbEjects == 0 {
+		return true
+	}
+
+	return (od.nbEjects+1)*100 <= od.maxEjectionPercent*(inRotation+od.nbEjects)
+}
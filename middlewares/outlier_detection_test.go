@@ -0,0 +1,160 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/mailgun/timetools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+func TestNewOutlierDetectorInvalid(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.OutlierDetection
+	}{
+		{
+			desc:   "negative consecutive errors",
+			config: &types.OutlierDetection{ConsecutiveErrors: -1},
+		},
+		{
+			desc:   "negative base ejection time",
+			config: &types.OutlierDetection{BaseEjectionTime: parse.Duration(-time.Second)},
+		},
+		{
+			desc:   "max ejection percent greater than 100",
+			config: &types.OutlierDetection{MaxEjectionPercent: 101},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewOutlierDetector(http.NotFoundHandler(), test.config, nil, nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOutlierDetector(t *testing.T) {
+	clock := &timetools.FreezedTime{CurrentTime: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	failing := map[string]bool{"http://server1": true}
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if failing[req.URL.String()] {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	config := &types.OutlierDetection{
+		ConsecutiveErrors:  2,
+		Interval:           parse.Duration(time.Minute),
+		BaseEjectionTime:   parse.Duration(10 * time.Second),
+		MaxEjectionPercent: 50,
+	}
+	counter := &testhelpers.CollectingCounter{}
+	gauge := &testhelpers.CollectingGauge{}
+
+	od, err := newOutlierDetector(next, config, map[string]types.Server{"http://server1": {URL: "HTTP://server1", Weight: 3}}, counter, gauge, clock)
+	require.NoError(t, err)
+
+	lb, err := roundrobin.New(od)
+	require.NoError(t, err)
+	for _, u := range []string{"http://server1", "http://server2", "http://server3"} {
+		require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL(u)))
+	}
+	od.SetBalancer(lb)
+
+	serve := func(server string) int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.URL = testhelpers.MustParseURL(server)
+		od.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// A success resets the consecutive errors.
+	serve("http://server1")
+	failing["http://server1"] = false
+	serve("http://server1")
+	failing["http://server1"] = true
+	serve("http://server1")
+	assert.Len(t, lb.Servers(), 3)
+
+	serve("http://server1")
+	assert.Len(t, lb.Servers(), 2)
+	assert.Equal(t, float64(1), counter.CounterValue)
+	assert.Equal(t, float64(0), gauge.GaugeValue)
+	assert.Equal(t, []string{"url", "HTTP://server1"}, gauge.LastLabelValues)
+
+	// The maximum ejection percent is reached.
+	failing["http://server2"] = true
+	serve("http://server2")
+	serve("http://server2")
+	assert.Len(t, lb.Servers(), 2)
+
+	// The server is reinstated with its weight after the base ejection time.
+	clock.Sleep(10 * time.Second)
+	serve("http://server3")
+	assert.Len(t, lb.Servers(), 3)
+	weight, ok := lb.ServerWeight(testhelpers.MustParseURL("http://server1"))
+	assert.True(t, ok)
+	assert.Equal(t, 3, weight)
+	assert.Equal(t, float64(1), gauge.GaugeValue)
+
+	// The ejection time grows on repeated ejections.
+	serve("http://server1")
+	serve("http://server1")
+	assert.Len(t, lb.Servers(), 2)
+
+	clock.Sleep(10 * time.Second)
+	serve("http://server3")
+	assert.Len(t, lb.Servers(), 2)
+
+	clock.Sleep(10 * time.Second)
+	serve("http://server3")
+	assert.Len(t, lb.Servers(), 3)
+
+	// The number of ejections decreases for each interval without ejection.
+	clock.Sleep(2 * time.Minute)
+	serve("http://server1")
+	serve("http://server1")
+	assert.Len(t, lb.Servers(), 2)
+
+	clock.Sleep(10 * time.Second)
+	serve("http://server3")
+	assert.Len(t, lb.Servers(), 3)
+}
+
+func TestOutlierDetectorLastServer(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	od, err := NewOutlierDetector(next, &types.OutlierDetection{ConsecutiveErrors: 1}, nil, nil, nil)
+	require.NoError(t, err)
+
+	lb, err := roundrobin.New(od)
+	require.NoError(t, err)
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://server1")))
+	od.SetBalancer(lb)
+
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	}
+
+	assert.Len(t, lb.Servers(), 1)
+}
@@ -0,0 +1,323 @@
+package p2c
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/vulcand/oxy/roundrobin"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	// decayTime is the time constant of the response time averages:
+	// a sample older than decayTime weighs about a third of a new one.
+	decayTime = 10 * time.Second
+
+	// statsTTL is the idle time after which the statistics of a server are forgotten.
+	statsTTL = 10 * time.Minute
+)
+
+var (
+	stores     = make(map[string]*store)
+	storesLock sync.Mutex
+)
+
+// Balancer is a power of two choices load balancer:
+// it picks two servers at random, and forwards the request to the one with the lowest cost,
+// based on an exponentially weighted moving average of its response times, and on its pending requests.
+// The statistics are shared by the balancers of a backend, so they survive the configuration reloads.
+type Balancer struct {
+	next          http.Handler
+	stickySession *roundrobin.StickySession
+	stats         *store
+
+	mu      sync.RWMutex
+	servers []*url.URL
+
+	randLock sync.Mutex
+	rand     *rand.Rand
+}
+
+// New creates a new Balancer for the given backend.
+func New(backendName string, next http.Handler, stickySession *roundrobin.StickySession) *Balancer {
+	return &Balancer{
+		next:          next,
+		stickySession: stickySession,
+		stats:         getStore(backendName),
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func getStore(backendName string) *store {
+	storesLock.Lock()
+	defer storesLock.Unlock()
+
+	s, ok := stores[backendName]
+	if !ok {
+		s = &store{servers: make(map[string]*serverStats)}
+		stores[backendName] = s
+	}
+
+	s.prune()
+	return s
+}
+
+func (b *Balancer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// Shallow copy of the request, to avoid side effects.
+	newReq := *req
+
+	stuck := false
+	if b.stickySession != nil {
+		cookieURL, present, err := b.stickySession.GetBackend(&newReq, b.Servers())
+		if err != nil {
+			log.Warnf("Error using server from cookie: %v", err)
+		}
+
+		if present {
+			newReq.URL = cookieURL
+			stuck = true
+		}
+	}
+
+	if !stuck {
+		u, err := b.NextServer()
+		if err != nil {
+			utils.DefaultHandler.ServeHTTP(rw, req, err)
+			return
+		}
+
+		if b.stickySession != nil {
+			b.stickySession.StickBackend(u, &rw)
+		}
+		newReq.URL = u
+	}
+
+	stats := b.stats.get(newReq.URL.String())
+	stats.start()
+	start := time.Now()
+
+	defer func() {
+		stats.done(time.Since(start))
+	}()
+
+	b.next.ServeHTTP(rw, &newReq)
+}
+
+// NextServer returns the server to forward the next request to.
+func (b *Balancer) NextServer() (*url.URL, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	switch len(b.servers) {
+	case 0:
+		return nil, errors.New("no servers in the pool")
+	case 1:
+		return b.servers[0], nil
+	}
+
+	i, j := b.pickTwo(len(b.servers))
+	first, second := b.servers[i], b.servers[j]
+
+	now := time.Now()
+	mean := b.stats.mean(b.servers)
+
+	if b.stats.get(second.String()).cost(mean, now) < b.stats.get(first.String()).cost(mean, now) {
+		return second, nil
+	}
+	return first, nil
+}
+
+// pickTwo returns two distinct random indexes lower than n.
+func (b *Balancer) pickTwo(n int) (int, int) {
+	b.randLock.Lock()
+	defer b.randLock.Unlock()
+
+	i := b.rand.Intn(n)
+	j := b.rand.Intn(n - 1)
+	if j >= i {
+		j++
+	}
+	return i, j
+}
+
+// Servers returns the servers of the pool.
+func (b *Balancer) Servers() []*url.URL {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	servers := make([]*url.URL, len(b.servers))
+	copy(servers, b.servers)
+	return servers
+}
+
+// RemoveServer removes a server from the pool.
+// Its statistics are kept, in case it comes back.
+func (b *Balancer) RemoveServer(u *url.URL) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, srv := range b.servers {
+		if srv.String() == u.String() {
+			b.servers = append(b.servers[:i:i], b.servers[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("server not found")
+}
+
+// UpsertServer adds a server to the pool.
+// The options, such as the weight, are ignored: the servers are chosen on their response times.
+func (b *Balancer) UpsertServer(u *url.URL, options ...roundrobin.ServerOption) error {
+	if u == nil {
+		return errors.New("server URL can't be nil")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, srv := range b.servers {
+		if srv.String() == u.String() {
+			return nil
+		}
+	}
+
+	b.servers = append(b.servers, utils.CopyURL(u))
+	return nil
+}
+
+// store holds the statistics of the servers of a backend, by URL.
+type store struct {
+	mu      sync.Mutex
+	servers map[string]*serverStats
+}
+
+// get returns the statistics of a server.
+func (s *store) get(u string) *serverStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.servers[u]
+	if !ok {
+		stats = &serverStats{lastUpdate: time.Now()}
+		s.servers[u] = stats
+	}
+	return stats
+}
+
+// prune forgets the statistics of the servers idle for too long.
+func (s *store) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, stats := range s.servers {
+		if stats.idleSince(now) > statsTTL {
+			delete(s.servers, key)
+		}
+	}
+}
+
+// mean returns the mean of the response time averages of the given servers having some.
+func (s *store) mean(servers []*url.URL) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sum float64
+	var count int
+	for _, u := range servers {
+		stats, ok := s.servers[u.String()]
+		if !ok {
+			continue
+		}
+
+		if ewma, ok := stats.average(); ok {
+			sum += ewma
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// serverStats holds the moving average of the response times of a server, in nanoseconds.
+type serverStats struct {
+	mu         sync.Mutex
+	ewma       float64
+	sampled    bool
+	pending    int
+	lastUpdate time.Time
+}
+
+func (s *serverStats) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending++
+}
+
+func (s *serverStats) done(rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pending--
+
+	if !s.sampled {
+		s.ewma = float64(rtt)
+		s.sampled = true
+	} else {
+		w := decay(now.Sub(s.lastUpdate))
+		s.ewma = s.ewma*w + float64(rtt)*(1-w)
+	}
+	s.lastUpdate = now
+}
+
+func (s *serverStats) average() (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ewma, s.sampled
+}
+
+func (s *serverStats) idleSince(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending > 0 {
+		return 0
+	}
+	return now.Sub(s.lastUpdate)
+}
+
+// cost returns the cost of forwarding a request to the server.
+// The average of a server without recent responses decays towards the mean of the backend,
+// so a server which was slow once is tried again, and a new server starts at the mean.
+func (s *serverStats) cost(mean float64, now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ewma := mean
+	if s.sampled {
+		w := decay(now.Sub(s.lastUpdate))
+		ewma = s.ewma*w + mean*(1-w)
+	}
+
+	return ewma * float64(s.pending+1)
+}
+
+// decay returns the weight of a sample of the given age.
+func decay(age time.Duration) float64 {
+	if age <= 0 {
+		return 1
+	}
+	return math.Exp(-float64(age) / float64(decayTime))
+}
@@ -0,0 +1,155 @@
+package p2c
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+func TestBalancerNoServers(t *testing.T) {
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+
+	recorder := httptest.NewRecorder()
+	lb.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestBalancerPrefersFasterServer(t *testing.T) {
+	fast := testhelpers.MustParseURL("http://fast")
+	slow := testhelpers.MustParseURL("http://slow")
+
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+	require.NoError(t, lb.UpsertServer(fast))
+	require.NoError(t, lb.UpsertServer(slow))
+
+	record(lb.stats.get(fast.String()), 10*time.Millisecond)
+	record(lb.stats.get(slow.String()), time.Second)
+
+	// With two servers, both are always candidates.
+	for i := 0; i < 10; i++ {
+		u, err := lb.NextServer()
+		require.NoError(t, err)
+		assert.Equal(t, fast.String(), u.String())
+	}
+}
+
+func TestBalancerPendingRequests(t *testing.T) {
+	first := testhelpers.MustParseURL("http://first")
+	second := testhelpers.MustParseURL("http://second")
+
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+	require.NoError(t, lb.UpsertServer(first))
+	require.NoError(t, lb.UpsertServer(second))
+
+	record(lb.stats.get(first.String()), 10*time.Millisecond)
+	record(lb.stats.get(second.String()), 15*time.Millisecond)
+
+	// The faster server is overloaded.
+	firstStats := lb.stats.get(first.String())
+	firstStats.start()
+	firstStats.start()
+
+	u, err := lb.NextServer()
+	require.NoError(t, err)
+	assert.Equal(t, second.String(), u.String())
+}
+
+func TestBalancerStatsSurviveReload(t *testing.T) {
+	fast := testhelpers.MustParseURL("http://fast")
+	slow := testhelpers.MustParseURL("http://slow")
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Host == slow.Host {
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	lb := New(t.Name(), next, nil)
+	require.NoError(t, lb.UpsertServer(fast))
+	require.NoError(t, lb.UpsertServer(slow))
+
+	for i := 0; i < 10; i++ {
+		lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+	}
+	record(lb.stats.get(slow.String()), 20*time.Millisecond)
+
+	// A new balancer, with a new server, for the same backend.
+	reloaded := New(t.Name(), next, nil)
+	require.NoError(t, reloaded.UpsertServer(testhelpers.MustParseURL("http://other")))
+	require.NoError(t, reloaded.UpsertServer(slow))
+	require.NoError(t, reloaded.UpsertServer(fast))
+
+	mean := reloaded.stats.mean(reloaded.Servers())
+	fastCost := reloaded.stats.get(fast.String()).cost(mean, time.Now())
+	slowCost := reloaded.stats.get(slow.String()).cost(mean, time.Now())
+	assert.True(t, fastCost < slowCost, "fast cost %f, slow cost %f", fastCost, slowCost)
+}
+
+func TestBalancerStickySession(t *testing.T) {
+	first := testhelpers.MustParseURL("http://first")
+	second := testhelpers.MustParseURL("http://second")
+
+	var forwarded string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		forwarded = req.URL.String()
+	})
+
+	lb := New(t.Name(), next, roundrobin.NewStickySession("test"))
+	require.NoError(t, lb.UpsertServer(first))
+	require.NoError(t, lb.UpsertServer(second))
+
+	// The second server is faster, but the request is stuck to the first one.
+	record(lb.stats.get(first.String()), time.Second)
+	record(lb.stats.get(second.String()), time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.AddCookie(&http.Cookie{Name: "test", Value: first.String()})
+
+	recorder := httptest.NewRecorder()
+	lb.ServeHTTP(recorder, req)
+
+	assert.Equal(t, first.String(), forwarded)
+}
+
+func TestBalancerRemoveServer(t *testing.T) {
+	first := testhelpers.MustParseURL("http://first")
+	second := testhelpers.MustParseURL("http://second")
+
+	lb := New(t.Name(), http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), nil)
+	require.NoError(t, lb.UpsertServer(first))
+	require.NoError(t, lb.UpsertServer(second))
+	require.NoError(t, lb.UpsertServer(second))
+	assert.Len(t, lb.Servers(), 2)
+
+	require.NoError(t, lb.RemoveServer(first))
+	assert.Error(t, lb.RemoveServer(first))
+
+	u, err := lb.NextServer()
+	require.NoError(t, err)
+	assert.Equal(t, second.String(), u.String())
+}
+
+func TestCostDecaysTowardsMean(t *testing.T) {
+	now := time.Now()
+	stats := &serverStats{ewma: float64(time.Second), sampled: true, lastUpdate: now}
+
+	assert.Equal(t, float64(time.Second), stats.cost(float64(time.Millisecond), now))
+
+	later := stats.cost(float64(time.Millisecond), now.Add(time.Minute))
+	assert.True(t, later < float64(10*time.Millisecond), "cost %f", later)
+
+	unsampled := &serverStats{lastUpdate: now}
+	assert.Equal(t, float64(time.Millisecond), unsampled.cost(float64(time.Millisecond), now))
+}
+
+func record(stats *serverStats, rtt time.Duration) {
+	stats.start()
+	stats.done(rtt)
+}
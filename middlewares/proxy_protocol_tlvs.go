@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containous/traefik/log"
+	"golang.org/x/net/http/httpguts"
+)
+
+// Names of the PROXY protocol v2 TLVs which can be forwarded as headers.
+const (
+	ProxyProtocolTLVALPN      = "alpn"
+	ProxyProtocolTLVAuthority = "authority"
+	ProxyProtocolTLVUniqueID  = "unique_id"
+	ProxyProtocolTLVNetNS     = "netns"
+	ProxyProtocolTLVAWSVPCEID = "aws_vpce_id"
+)
+
+// PROXY protocol v2 TLV types.
+const (
+	proxyProtocolTypeALPN      = 0x01
+	proxyProtocolTypeAuthority = 0x02
+	proxyProtocolTypeUniqueID  = 0x05
+	proxyProtocolTypeNetNS     = 0x30
+	proxyProtocolTypeMinCustom = 0xE0
+	proxyProtocolTypeAWS       = 0xEA
+	proxyProtocolTypeMaxCustom = 0xEF
+
+	proxyProtocolSubtypeAWSVPCEID = 0x01
+)
+
+// ProxyProtocolTLV is a Type-Length-Value extension of a PROXY protocol v2 header.
+type ProxyProtocolTLV struct {
+	Type  byte
+	Value []byte
+}
+
+type proxyProtocolTLVHeader struct {
+	name   string
+	header string
+}
+
+// ProxyProtocolTLVs forwards the TLVs of the PROXY protocol v2 headers to the backends, as request headers,
+// e.g. the ID of the AWS VPC endpoint through which the client connected.
+// The TLVs are recorded by the listener of the entrypoint for each connection, with Record and Forget.
+// The headers sent by the clients are always removed, as they could otherwise be forged.
+type ProxyProtocolTLVs struct {
+	headers map[byte]proxyProtocolTLVHeader
+
+	lock  sync.RWMutex
+	conns map[string]http.Header
+}
+
+// NewProxyProtocolTLVs creates a new ProxyProtocolTLVs, with the header names by TLV name.
+// The custom TLV types (0xE0 to 0xEF) are named by their hexadecimal value, e.g. "0xE0",
+// and their values are forwarded hexadecimal encoded.
+func NewProxyProtocolTLVs(tlvHeaders map[string]string) (*ProxyProtocolTLVs, error) {
+	p := &ProxyProtocolTLVs{
+		headers: make(map[byte]proxyProtocolTLVHeader),
+		conns:   make(map[string]http.Header),
+	}
+
+	for name, header := range tlvHeaders {
+		if !httpguts.ValidHeaderFieldName(header) {
+			return nil, fmt.Errorf("invalid header name %q for the PROXY protocol TLV %s", header, name)
+		}
+
+		tlvType, err := proxyProtocolTLVType(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if previous, ok := p.headers[tlvType]; ok {
+			return nil, fmt.Errorf("the PROXY protocol TLVs %s and %s have the same type", previous.name, name)
+		}
+
+		p.headers[tlvType] = proxyProtocolTLVHeader{name: name, header: http.CanonicalHeaderKey(header)}
+	}
+
+	return p, nil
+}
+
+func proxyProtocolTLVType(name string) (byte, error) {
+	switch strings.ToLower(name) {
+	case ProxyProtocolTLVALPN:
+		return proxyProtocolTypeALPN, nil
+	case ProxyProtocolTLVAuthority:
+		return proxyProtocolTypeAuthority, nil
+	case ProxyProtocolTLVUniqueID:
+		return proxyProtocolTypeUniqueID, nil
+	case ProxyProtocolTLVNetNS:
+		return proxyProtocolTypeNetNS, nil
+	case ProxyProtocolTLVAWSVPCEID:
+		return proxyProtocolTypeAWS, nil
+	}
+
+	value, err := strconv.ParseUint(name, 0, 8)
+	if err != nil || value < proxyProtocolTypeMinCustom || value > proxyProtocolTypeMaxCustom || value == proxyProtocolTypeAWS {
+		return 0, fmt.Errorf("unknown PROXY protocol TLV %q", name)
+	}
+	return byte(value), nil
+}
+
+// Record records the forwarded TLVs of the connection of a remote address (ip:port), as in the requests.
+func (p *ProxyProtocolTLVs) Record(remoteAddr string, tlvs []ProxyProtocolTLV) {
+	headers := make(http.Header)
+	for _, tlv := range tlvs {
+		tlvHeader, ok := p.headers[tlv.Type]
+		if !ok {
+			continue
+		}
+
+		value, ok := proxyProtocolTLVValue(tlv)
+		if !ok {
+			log.Debugf("Ignoring the invalid PROXY protocol TLV %s from %s", tlvHeader.name, remoteAddr)
+			continue
+		}
+		headers.Set(tlvHeader.header, value)
+	}
+
+	if len(headers) == 0 {
+		return
+	}
+
+	p.lock.Lock()
+	p.conns[remoteAddr] = headers
+	p.lock.Unlock()
+}
+
+// Forget forgets the TLVs of the connection of a remote address, once it is closed.
+func (p *ProxyProtocolTLVs) Forget(remoteAddr string) {
+	p.lock.Lock()
+	delete(p.conns, remoteAddr)
+	p.lock.Unlock()
+}
+
+func proxyProtocolTLVValue(tlv ProxyProtocolTLV) (string, bool) {
+	switch tlv.Type {
+	case proxyProtocolTypeUniqueID:
+		return hex.EncodeToString(tlv.Value), len(tlv.Value) > 0
+	case proxyProtocolTypeAWS:
+		// The AWS TLV starts with its subtype.
+		if len(tlv.Value) < 2 || tlv.Value[0] != proxyProtocolSubtypeAWSVPCEID {
+			return "", false
+		}
+		return printableTLVValue(tlv.Value[1:])
+	case proxyProtocolTypeALPN, proxyProtocolTypeAuthority, proxyProtocolTypeNetNS:
+		return printableTLVValue(tlv.Value)
+	default:
+		return hex.EncodeToString(tlv.Value), len(tlv.Value) > 0
+	}
+}
+
+func printableTLVValue(value []byte) (string, bool) {
+	if len(value) == 0 {
+		return "", false
+	}
+	for _, b := range value {
+		if b < 0x20 || b > 0x7e {
+			return "", false
+		}
+	}
+	return string(value), true
+}
+
+func (p *ProxyProtocolTLVs) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	for _, tlvHeader := range p.headers {
+		r.Header.Del(tlvHeader.header)
+	}
+
+	p.lock.RLock()
+	headers := p.conns[r.RemoteAddr]
+	p.lock.RUnlock()
+
+	for header, values := range headers {
+		r.Header[header] = append([]string(nil), values...)
+	}
+
+	next(rw, r)
+}
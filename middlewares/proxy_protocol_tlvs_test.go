@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyProtocolTLVs(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		tlvs            []ProxyProtocolTLV
+		requestHeaders  map[string]string
+		expectedHeaders map[string]string
+	}{
+		{
+			desc: "forwarded TLVs",
+			tlvs: []ProxyProtocolTLV{
+				{Type: proxyProtocolTypeAWS, Value: append([]byte{proxyProtocolSubtypeAWSVPCEID}, "vpce-08d2bf15fac5001c9"...)},
+				{Type: proxyProtocolTypeALPN, Value: []byte("h2")},
+				{Type: 0xE0, Value: []byte{0x01, 0xAB}},
+			},
+			expectedHeaders: map[string]string{
+				"X-Amzn-Vpce-Id": "vpce-08d2bf15fac5001c9",
+				"X-Proxy-Alpn":   "h2",
+				"X-Custom":       "01ab",
+			},
+		},
+		{
+			desc: "TLVs not forwarded",
+			tlvs: []ProxyProtocolTLV{
+				{Type: proxyProtocolTypeAuthority, Value: []byte("foo.bar")},
+			},
+		},
+		{
+			desc: "invalid TLVs",
+			tlvs: []ProxyProtocolTLV{
+				{Type: proxyProtocolTypeAWS, Value: append([]byte{0x02}, "vpce-08d2bf15fac5001c9"...)},
+				{Type: proxyProtocolTypeALPN, Value: []byte("h2\r\n")},
+			},
+		},
+		{
+			desc: "forged headers",
+			tlvs: []ProxyProtocolTLV{
+				{Type: proxyProtocolTypeALPN, Value: []byte("h2")},
+			},
+			requestHeaders: map[string]string{
+				"X-Amzn-Vpce-Id": "vpce-forged",
+				"X-Proxy-Alpn":   "http/1.1",
+			},
+			expectedHeaders: map[string]string{
+				"X-Proxy-Alpn": "h2",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			tlvs, err := NewProxyProtocolTLVs(map[string]string{
+				"aws_vpce_id": "X-Amzn-Vpce-Id",
+				"ALPN":        "X-Proxy-Alpn",
+				"0xE0":        "X-Custom",
+			})
+			require.NoError(t, err)
+
+			tlvs.Record("10.0.0.1:34567", test.tlvs)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:34567"
+			for name, value := range test.requestHeaders {
+				req.Header.Set(name, value)
+			}
+
+			headers := make(map[string]string)
+			tlvs.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				for _, name := range []string{"X-Amzn-Vpce-Id", "X-Proxy-Alpn", "X-Custom"} {
+					if value := r.Header.Get(name); len(value) > 0 {
+						headers[name] = value
+					}
+				}
+			})
+
+			if len(test.expectedHeaders) == 0 {
+				assert.Empty(t, headers)
+			} else {
+				assert.Equal(t, test.expectedHeaders, headers)
+			}
+		})
+	}
+}
+
+func TestProxyProtocolTLVsForget(t *testing.T) {
+	tlvs, err := NewProxyProtocolTLVs(map[string]string{"authority": "X-Proxy-Authority"})
+	require.NoError(t, err)
+
+	tlvs.Record("10.0.0.1:34567", []ProxyProtocolTLV{{Type: proxyProtocolTypeAuthority, Value: []byte("foo.bar")}})
+	tlvs.Forget("10.0.0.1:34567")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:34567"
+
+	tlvs.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("X-Proxy-Authority"))
+	})
+}
+
+func TestNewProxyProtocolTLVsFail(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		tlvHeaders map[string]string
+	}{
+		{
+			desc:       "unknown TLV",
+			tlvHeaders: map[string]string{"ssl": "X-Proxy-SSL"},
+		},
+		{
+			desc:       "non custom TLV type",
+			tlvHeaders: map[string]string{"0x20": "X-Proxy-SSL"},
+		},
+		{
+			desc:       "AWS TLV type",
+			tlvHeaders: map[string]string{"0xEA": "X-Proxy-AWS"},
+		},
+		{
+			desc:       "invalid header name",
+			tlvHeaders: map[string]string{"alpn": "X Proxy Alpn"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewProxyProtocolTLVs(test.tlvHeaders)
+			assert.Error(t, err)
+		})
+	}
+}
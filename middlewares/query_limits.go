@@ -0,0 +1,193 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+// Query limit actions.
+const (
+	QueryLimitActionReject = "reject"
+	QueryLimitActionClamp  = "clamp"
+)
+
+type queryLimit struct {
+	name         string
+	min          int64
+	max          int64
+	defaultValue string
+	clamp        bool
+}
+
+// QueryLimiter checks the integer query parameters of the requests, such as the page sizes, against their bounds,
+// to protect the backends from unbounded queries. The out of range values are rejected with a 400, or clamped,
+// and the absent parameters get their default value.
+type QueryLimiter struct {
+	backendName string
+	limits      []queryLimit
+}
+
+// NewQueryLimiter creates a new QueryLimiter, with the limits by parameter name.
+func NewQueryLimiter(backendName string, config map[string]*types.QueryLimit) (*QueryLimiter, error) {
+	limiter := &QueryLimiter{backendName: backendName}
+
+	for name, limitConfig := range config {
+		if limitConfig == nil {
+			continue
+		}
+
+		limit := queryLimit{
+			name: name,
+			min:  limitConfig.Min,
+			max:  limitConfig.Max,
+		}
+
+		switch limitConfig.Action {
+		case "", QueryLimitActionReject:
+		case QueryLimitActionClamp:
+			limit.clamp = true
+		default:
+			return nil, fmt.Errorf("unknown action %q for the query parameter %s", limitConfig.Action, name)
+		}
+
+		if limit.max != 0 && limit.max < limit.min {
+			return nil, fmt.Errorf("the maximum %d of the query parameter %s is lower than its minimum %d", limit.max, name, limit.min)
+		}
+
+		if len(limitConfig.Default) > 0 {
+			value, err := strconv.ParseInt(limitConfig.Default, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default value %q for the query parameter %s: %v", limitConfig.Default, name, err)
+			}
+			if !limit.inRange(value) {
+				return nil, fmt.Errorf("the default value %d of the query parameter %s is out of range", value, name)
+			}
+			limit.defaultValue = limitConfig.Default
+		}
+
+		limiter.limits = append(limiter.limits, limit)
+	}
+
+	sort.Slice(limiter.limits, func(i, j int) bool {
+		return limiter.limits[i].name < limiter.limits[j].name
+	})
+
+	return limiter, nil
+}
+
+func (q *QueryLimiter) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// The raw query is rewritten pair by pair, to leave the order and the escaping of the other parameters untouched.
+	present := make(map[string]bool)
+	updated := false
+
+	var rawQuery strings.Builder
+	remaining := r.URL.RawQuery
+	for len(remaining) > 0 {
+		pair, separator := remaining, ""
+		if i := strings.IndexAny(remaining, "&;"); i >= 0 {
+			pair, separator, remaining = remaining[:i], remaining[i:i+1], remaining[i+1:]
+		} else {
+			remaining = ""
+		}
+
+		limited, err := q.limitPair(pair, present)
+		if err != nil {
+			log.Debugf("Backend %s: rejecting the request to %s: %v", q.backendName, r.URL, err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		updated = updated || limited != pair
+		rawQuery.WriteString(limited)
+		rawQuery.WriteString(separator)
+	}
+
+	for _, limit := range q.limits {
+		if present[limit.name] || len(limit.defaultValue) == 0 {
+			continue
+		}
+
+		if rawQuery.Len() > 0 {
+			rawQuery.WriteString("&")
+		}
+		rawQuery.WriteString(url.QueryEscape(limit.name) + "=" + limit.defaultValue)
+		updated = true
+	}
+
+	if updated {
+		r.URL.RawQuery = rawQuery.String()
+		r.RequestURI = r.URL.RequestURI()
+	}
+
+	next.ServeHTTP(rw, r)
+}
+
+// limitPair checks the value of a key=value pair of the raw query, if it is a limited parameter,
+// and returns the pair with its value clamped if needed.
+func (q *QueryLimiter) limitPair(pair string, present map[string]bool) (string, error) {
+	rawKey, rawValue := pair, ""
+	if i := strings.Index(pair, "="); i >= 0 {
+		rawKey, rawValue = pair[:i], pair[i+1:]
+	}
+
+	key, err := url.QueryUnescape(rawKey)
+	if err != nil {
+		return pair, nil
+	}
+
+	limit, ok := q.limit(key)
+	if !ok {
+		return pair, nil
+	}
+	present[key] = true
+
+	rawValue, err = url.QueryUnescape(rawValue)
+	if err != nil {
+		return "", fmt.Errorf("invalid query parameter %s", key)
+	}
+
+	value, err := strconv.ParseInt(rawValue, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid query parameter %s", key)
+	}
+
+	if limit.inRange(value) {
+		return pair, nil
+	}
+
+	if !limit.clamp {
+		return "", fmt.Errorf("out of range query parameter %s", key)
+	}
+
+	return rawKey + "=" + strconv.FormatInt(limit.clampValue(value), 10), nil
+}
+
+func (q *QueryLimiter) limit(name string) (queryLimit, bool) {
+	for _, limit := range q.limits {
+		if limit.name == name {
+			return limit, true
+		}
+	}
+	return queryLimit{}, false
+}
+
+func (l queryLimit) inRange(value int64) bool {
+	return value >= l.min && (l.max == 0 || value <= l.max)
+}
+
+func (l queryLimit) clampValue(value int64) int64 {
+	if value < l.min {
+		return l.min
+	}
+	if l.max != 0 && value > l.max {
+		return l.max
+	}
+	return value
+}
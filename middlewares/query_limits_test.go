@@ -0,0 +1,164 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLimiter(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		config             map[string]*types.QueryLimit
+		target             string
+		expectedStatus     int
+		expectedRequestURI string
+	}{
+		{
+			desc:               "value in range",
+			config:             map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100}},
+			target:             "/users?limit=50&sort=name",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?limit=50&sort=name",
+		},
+		{
+			desc:           "value above the maximum",
+			config:         map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100}},
+			target:         "/users?limit=1000000",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "value below the minimum",
+			config:         map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100}},
+			target:         "/users?limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "negative value without minimum",
+			config:         map[string]*types.QueryLimit{"offset": {}},
+			target:         "/users?offset=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:           "invalid value",
+			config:         map[string]*types.QueryLimit{"limit": {Max: 100, Action: QueryLimitActionClamp}},
+			target:         "/users?limit=all",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:               "clamped values",
+			config:             map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100, Action: QueryLimitActionClamp}, "page_size": {Min: 10, Action: QueryLimitActionClamp}},
+			target:             "/users?limit=1000000&page_size=5&sort=name",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?limit=100&page_size=10&sort=name",
+		},
+		{
+			desc:               "clamped repeated parameter",
+			config:             map[string]*types.QueryLimit{"limit": {Max: 100, Action: QueryLimitActionClamp}},
+			target:             "/users?limit=10&limit=500",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?limit=10&limit=100",
+		},
+		{
+			desc:               "default value",
+			config:             map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100, Default: "20"}},
+			target:             "/users?sort=name",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?sort=name&limit=20",
+		},
+		{
+			desc:               "default value without query",
+			config:             map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100, Default: "20"}},
+			target:             "/users",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?limit=20",
+		},
+		{
+			desc:               "other parameters untouched",
+			config:             map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100, Action: QueryLimitActionClamp}},
+			target:             "/users?sort=name&q=a%20b+c;x=%2F&limit=500&flag",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?sort=name&q=a%20b+c;x=%2F&limit=100&flag",
+		},
+		{
+			desc:           "parameter after a semicolon",
+			config:         map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100}},
+			target:         "/users?sort=name;limit=500",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			desc:               "escaped parameter name",
+			config:             map[string]*types.QueryLimit{"page size": {Max: 100, Action: QueryLimitActionClamp}},
+			target:             "/users?page%20size=500",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?page%20size=100",
+		},
+		{
+			desc:               "absent parameter without default value",
+			config:             map[string]*types.QueryLimit{"limit": {Min: 1, Max: 100}},
+			target:             "/users?sort=name",
+			expectedStatus:     http.StatusOK,
+			expectedRequestURI: "/users?sort=name",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			limiter, err := NewQueryLimiter("backend1", test.config)
+			require.NoError(t, err)
+
+			var requestURI string
+			next := func(rw http.ResponseWriter, req *http.Request) {
+				requestURI = req.URL.RequestURI()
+				assert.Equal(t, requestURI, req.RequestURI)
+			}
+
+			recorder := httptest.NewRecorder()
+			limiter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.target, nil), next)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedRequestURI, requestURI)
+		})
+	}
+}
+
+func TestNewQueryLimiterFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config map[string]*types.QueryLimit
+	}{
+		{
+			desc:   "unknown action",
+			config: map[string]*types.QueryLimit{"limit": {Action: "drop"}},
+		},
+		{
+			desc:   "maximum below the minimum",
+			config: map[string]*types.QueryLimit{"limit": {Min: 10, Max: 5}},
+		},
+		{
+			desc:   "invalid default value",
+			config: map[string]*types.QueryLimit{"limit": {Default: "ten"}},
+		},
+		{
+			desc:   "out of range default value",
+			config: map[string]*types.QueryLimit{"limit": {Max: 100, Default: "500"}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewQueryLimiter("backend1", test.config)
+			assert.Error(t, err)
+		})
+	}
+}
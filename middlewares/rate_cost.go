@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+// RateCostExtractor is a rate limiting source extractor which makes the requests
+// consume a number of tokens depending on their cost.
+// The cost of a request is the highest cost of the rules it matches, 1 if it matches none.
+type RateCostExtractor struct {
+	extractor utils.SourceExtractor
+	costs     []*types.RateCost
+}
+
+// NewRateCostExtractor creates a new RateCostExtractor on top of the given source extractor.
+// The costs can't exceed maxCost, the smallest burst of the rates, as the requests costing more could never be served.
+func NewRateCostExtractor(extractor utils.SourceExtractor, costs map[string]*types.RateCost, maxCost int64) (*RateCostExtractor, error) {
+	rce := &RateCostExtractor{extractor: extractor}
+
+	for name, cost := range costs {
+		if cost == nil {
+			continue
+		}
+
+		if cost.Cost <= 0 {
+			return nil, fmt.Errorf("invalid cost for %s: %d", name, cost.Cost)
+		}
+
+		if cost.Cost > maxCost {
+			return nil, fmt.Errorf("invalid cost for %s: %d exceeds the smallest burst %d", name, cost.Cost, maxCost)
+		}
+
+		rce.costs = append(rce.costs, cost)
+	}
+
+	return rce, nil
+}
+
+// Extract extracts the source of the request with the amount of tokens it consumes.
+func (r *RateCostExtractor) Extract(req *http.Request) (string, int64, error) {
+	source, amount, err := r.extractor.Extract(req)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var cost int64
+	for _, rateCost := range r.costs {
+		if rateCost.Cost > cost && matchRateCost(rateCost, req) {
+			cost = rateCost.Cost
+		}
+	}
+
+	if cost > 0 {
+		amount = cost
+	}
+
+	return source, amount, nil
+}
+
+func matchRateCost(rateCost *types.RateCost, req *http.Request) bool {
+	if len(rateCost.PathPrefix) > 0 && !strings.HasPrefix(req.URL.Path, rateCost.PathPrefix) {
+		return false
+	}
+
+	if len(rateCost.Methods) > 0 {
+		var found bool
+		for _, method := range rateCost.Methods {
+			if strings.EqualFold(method, req.Method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for name, value := range rateCost.Headers {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+
+	return true
+}
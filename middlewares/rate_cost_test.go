@@ -0,0 +1,132 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/utils"
+)
+
+func TestRateCostExtractor(t *testing.T) {
+	costs := map[string]*types.RateCost{
+		"search": {
+			PathPrefix: "/search",
+			Cost:       5,
+		},
+		"write": {
+			Methods: []string{"post", "PUT"},
+			Cost:    3,
+		},
+		"export": {
+			PathPrefix: "/search",
+			Headers:    map[string]string{"X-Export": "true"},
+			Cost:       10,
+		},
+	}
+
+	testCases := []struct {
+		desc           string
+		method         string
+		path           string
+		headers        map[string]string
+		expectedAmount int64
+	}{
+		{
+			desc:           "no matching rule",
+			method:         http.MethodGet,
+			path:           "/health",
+			expectedAmount: 1,
+		},
+		{
+			desc:           "path prefix",
+			method:         http.MethodGet,
+			path:           "/search/foo",
+			expectedAmount: 5,
+		},
+		{
+			desc:           "method is case insensitive",
+			method:         http.MethodPost,
+			path:           "/items",
+			expectedAmount: 3,
+		},
+		{
+			desc:           "highest cost of the matching rules",
+			method:         http.MethodPost,
+			path:           "/search",
+			expectedAmount: 5,
+		},
+		{
+			desc:           "all the criteria must match",
+			method:         http.MethodGet,
+			path:           "/items",
+			headers:        map[string]string{"X-Export": "true"},
+			expectedAmount: 1,
+		},
+		{
+			desc:           "header",
+			method:         http.MethodGet,
+			path:           "/search",
+			headers:        map[string]string{"X-Export": "true"},
+			expectedAmount: 10,
+		},
+	}
+
+	extractor, err := utils.NewExtractor("request.host")
+	require.NoError(t, err)
+
+	costExtractor, err := NewRateCostExtractor(extractor, costs, 10)
+	require.NoError(t, err)
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(test.method, "http://foo.localhost"+test.path, nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			source, amount, err := costExtractor.Extract(req)
+			require.NoError(t, err)
+
+			assert.Equal(t, "foo.localhost", source)
+			assert.Equal(t, test.expectedAmount, amount)
+		})
+	}
+}
+
+func TestNewRateCostExtractor_invalidCost(t *testing.T) {
+	extractor, err := utils.NewExtractor("client.ip")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc string
+		cost int64
+	}{
+		{
+			desc: "zero cost",
+			cost: 0,
+		},
+		{
+			desc: "cost exceeding the smallest burst",
+			cost: 11,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewRateCostExtractor(extractor, map[string]*types.RateCost{
+				"cost": {PathPrefix: "/search", Cost: test.cost},
+			}, 10)
+			assert.Error(t, err)
+		})
+	}
+}
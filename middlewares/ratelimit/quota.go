@@ -0,0 +1,276 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	// QuotaPeriodDay resets the quotas every day at midnight.
+	QuotaPeriodDay = "day"
+	// QuotaPeriodMonth resets the quotas on the first day of every month at midnight.
+	QuotaPeriodMonth = "month"
+
+	// maxQuotaLocalSources is the maximum number of sources counted in memory in a period.
+	maxQuotaLocalSources = 100000
+)
+
+var (
+	quotaStores     = make(map[string]*quotaStore)
+	quotaStoresLock sync.Mutex
+)
+
+// quotaScript increments the count of a client, and sets its expiration on the first request of the period.
+// KEYS: the count. ARGV: the expiration time (ms).
+// It returns the count.
+const quotaScript = `
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+  redis.call('PEXPIREAT', KEYS[1], ARGV[1])
+end
+return count
+`
+
+var quotaScriptSHA = sha1Hex(quotaScript)
+
+// Quota rejects the requests of the clients which have exhausted their quota of requests for the current period.
+// The counts are shared between the Traefik instances through Redis, if configured.
+// When Redis is unreachable, or without Redis, the requests are counted in memory,
+// in a store shared by the quotas of the same name so that the counts survive the configuration reloads.
+type Quota struct {
+	name         string
+	next         http.Handler
+	extractor    utils.SourceExtractor
+	period       string
+	location     *time.Location
+	defaultLimit int64
+	limits       map[string]int64
+	redis        *types.RateLimitRedis
+	clients      *RedisClients
+	client       *redisClient
+	now          func() time.Time
+	store        *quotaStore
+
+	lock      sync.Mutex
+	downUntil time.Time
+}
+
+// quotaStore holds the in-memory counts of the sources of a quota in the current period.
+type quotaStore struct {
+	lock   sync.Mutex
+	period time.Time
+	counts map[string]int64
+}
+
+func getQuotaStore(name string) *quotaStore {
+	quotaStoresLock.Lock()
+	defer quotaStoresLock.Unlock()
+
+	s, ok := quotaStores[name]
+	if !ok {
+		s = &quotaStore{counts: make(map[string]int64)}
+		quotaStores[name] = s
+	}
+	return s
+}
+
+// NewQuota creates a new Quota, whose Redis client, if any, is got from the clients once the configuration is loaded.
+// The name is used to isolate the counts of the quotas sharing the same Redis server.
+func NewQuota(name string, next http.Handler, extractor utils.SourceExtractor, config *types.Quota, clients *RedisClients) (*Quota, error) {
+	q := &Quota{
+		name:         name,
+		next:         next,
+		extractor:    extractor,
+		period:       config.Period,
+		location:     time.UTC,
+		defaultLimit: config.Default,
+		limits:       config.Keys,
+		redis:        config.Redis,
+		clients:      clients,
+		now:          time.Now,
+		store:        getQuotaStore(name),
+	}
+
+	switch q.period {
+	case "":
+		q.period = QuotaPeriodDay
+	case QuotaPeriodDay, QuotaPeriodMonth:
+	default:
+		return nil, fmt.Errorf("invalid quota period %q, must be %q or %q", config.Period, QuotaPeriodDay, QuotaPeriodMonth)
+	}
+
+	if len(config.TimeZone) > 0 {
+		location, err := time.LoadLocation(config.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota time zone %q: %v", config.TimeZone, err)
+		}
+		q.location = location
+	}
+
+	if q.defaultLimit < 0 {
+		return nil, fmt.Errorf("invalid default quota %d", q.defaultLimit)
+	}
+
+	for key, limit := range q.limits {
+		if limit <= 0 {
+			return nil, fmt.Errorf("invalid quota %d for %q", limit, key)
+		}
+	}
+
+	if config.Redis != nil {
+		if err := checkRedisConfig(config.Redis); err != nil {
+			return nil, err
+		}
+	}
+
+	return q, nil
+}
+
+// PostLoad gets the client of the Redis server, if any, shared by the middlewares using the same Redis server.
+func (q *Quota) PostLoad(_ map[string]http.Handler) error {
+	if q.redis == nil {
+		return nil
+	}
+
+	client, err := q.clients.get(q.redis)
+	if err != nil {
+		return err
+	}
+
+	q.client = client
+	return nil
+}
+
+func (q *Quota) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	source, _, err := q.extractor.Extract(req)
+	if err != nil {
+		utils.DefaultHandler.ServeHTTP(rw, req, err)
+		return
+	}
+
+	limit, ok := q.limits[source]
+	if !ok {
+		limit = q.defaultLimit
+	}
+
+	if limit == 0 {
+		q.next.ServeHTTP(rw, req)
+		return
+	}
+
+	start, reset := q.periodBounds(q.now())
+	count := q.count(source, start, reset)
+
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	rw.Header().Set("X-Quota-Limit", strconv.FormatInt(limit, 10))
+	rw.Header().Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+	rw.Header().Set("X-Quota-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+	if count > limit {
+		log.Debugf("Quota %s: rejecting request %s %s, the quota %d of %q is exhausted until %s", q.name, req.Method, req.URL, limit, source, reset)
+		rw.Header().Set("Retry-After", strconv.FormatInt(int64(reset.Sub(q.now()).Seconds()+1), 10))
+		rw.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintf(rw, "quota exhausted: reset at %s", reset.Format(time.RFC3339))
+		return
+	}
+
+	q.next.ServeHTTP(rw, req)
+}
+
+// count counts the request of the source in the period, and returns the count of the period including it.
+func (q *Quota) count(source string, start, reset time.Time) int64 {
+	if q.client != nil && !q.isRedisDown() {
+		count, err := q.countRedis(source, start, reset)
+		if err == nil {
+			return count
+		}
+
+		log.Warnf("Quota %s: unable to reach Redis, falling back on the in-memory counts for %s: %v", q.name, redisRetryInterval, err)
+		q.setRedisDown()
+	}
+
+	return q.countLocal(source, start)
+}
+
+func (q *Quota) countRedis(source string, start, reset time.Time) (int64, error) {
+	key := strings.Join([]string{"traefik", "quota", q.name, source, strconv.FormatInt(start.Unix(), 10)}, ":")
+
+	// The count is kept a little after the reset, in case of clock skew between the instances.
+	expiration := strconv.FormatInt(reset.Add(time.Hour).UnixNano()/int64(time.Millisecond), 10)
+
+	reply, err := q.client.do("EVALSHA", quotaScriptSHA, "1", key, expiration)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		reply, err = q.client.do("EVAL", quotaScript, "1", key, expiration)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply: %v", reply)
+	}
+
+	return count, nil
+}
+
+// countLocal counts the request of the source in memory.
+// Once maxQuotaLocalSources sources are counted in the period, the requests of the other sources are not counted,
+// so that the memory used by a quota stays bounded.
+func (q *Quota) countLocal(source string, start time.Time) int64 {
+	q.store.lock.Lock()
+	defer q.store.lock.Unlock()
+
+	if !q.store.period.Equal(start) {
+		q.store.period = start
+		q.store.counts = make(map[string]int64)
+	}
+
+	count, ok := q.store.counts[source]
+	if !ok && len(q.store.counts) >= maxQuotaLocalSources {
+		log.Debugf("Quota %s: too many sources counted in memory, not counting %q", q.name, source)
+		return 1
+	}
+
+	q.store.counts[source] = count + 1
+	return count + 1
+}
+
+// periodBounds returns the start of the period of the time, and the start of the next one.
+func (q *Quota) periodBounds(now time.Time) (time.Time, time.Time) {
+	now = now.In(q.location)
+
+	if q.period == QuotaPeriodMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, q.location)
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, q.location)
+	return start, start.AddDate(0, 0, 1)
+}
+
+func (q *Quota) isRedisDown() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.now().Before(q.downUntil)
+}
+
+func (q *Quota) setRedisDown() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.downUntil = q.now().Add(redisRetryInterval)
+}
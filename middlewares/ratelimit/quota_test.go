@@ -0,0 +1,271 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/utils"
+)
+
+func newTestQuota(t *testing.T, name string, config *types.Quota, now time.Time) *Quota {
+	t.Helper()
+
+	// Start from fresh counts.
+	quotaStoresLock.Lock()
+	delete(quotaStores, name)
+	quotaStoresLock.Unlock()
+
+	return newReloadedTestQuota(t, name, config, now)
+}
+
+// newReloadedTestQuota creates a quota keeping the counts of the previous quota of the same name.
+func newReloadedTestQuota(t *testing.T, name string, config *types.Quota, now time.Time) *Quota {
+	t.Helper()
+
+	extractor, err := utils.NewExtractor("request.header.X-Api-Key")
+	require.NoError(t, err)
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	quota, err := NewQuota(name, next, extractor, config, NewRedisClients())
+	require.NoError(t, err)
+	require.NoError(t, quota.PostLoad(nil))
+	quota.now = func() time.Time { return now }
+
+	return quota
+}
+
+func serveQuota(quota *Quota, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.Header.Set("X-Api-Key", key)
+
+	recorder := httptest.NewRecorder()
+	quota.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+func TestQuota(t *testing.T) {
+	now := time.Date(2018, time.October, 15, 10, 30, 0, 0, time.UTC)
+	reset := time.Date(2018, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+	quota := newTestQuota(t, "quota", &types.Quota{Default: 2, Keys: map[string]int64{"premium": 3}}, now)
+
+	testCases := []struct {
+		desc               string
+		key                string
+		expectedStatusCode int
+		expectedRemaining  string
+	}{
+		{desc: "first request", key: "basic", expectedStatusCode: http.StatusOK, expectedRemaining: "1"},
+		{desc: "last request", key: "basic", expectedStatusCode: http.StatusOK, expectedRemaining: "0"},
+		{desc: "quota exhausted", key: "basic", expectedStatusCode: http.StatusTooManyRequests, expectedRemaining: "0"},
+		{desc: "quota of the key", key: "premium", expectedStatusCode: http.StatusOK, expectedRemaining: "2"},
+	}
+
+	// The requests are counted in order, so the test cases are not parallel.
+	for _, test := range testCases {
+		recorder := serveQuota(quota, test.key)
+
+		assert.Equal(t, test.expectedStatusCode, recorder.Code, test.desc)
+		assert.Equal(t, test.expectedRemaining, recorder.Header().Get("X-Quota-Remaining"), test.desc)
+		assert.Equal(t, strconv.FormatInt(reset.Unix(), 10), recorder.Header().Get("X-Quota-Reset"), test.desc)
+	}
+
+	recorder := serveQuota(quota, "basic")
+	assert.Equal(t, "2", recorder.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, strconv.FormatInt(int64(reset.Sub(now).Seconds())+1, 10), recorder.Header().Get("Retry-After"))
+
+	// The counts are reset at the start of the next period.
+	quota.now = func() time.Time { return reset }
+	recorder = serveQuota(quota, "basic")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("X-Quota-Remaining"))
+}
+
+func TestQuota_reload(t *testing.T) {
+	now := time.Now()
+	config := &types.Quota{Default: 2}
+
+	quota := newTestQuota(t, "reload", config, now)
+	assert.Equal(t, http.StatusOK, serveQuota(quota, "basic").Code)
+
+	// The quota of the reloaded configuration keeps the counts.
+	reloaded := newReloadedTestQuota(t, "reload", config, now)
+	assert.Equal(t, http.StatusOK, serveQuota(reloaded, "basic").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveQuota(reloaded, "basic").Code)
+
+	// The quotas of other names have their own counts.
+	other := newTestQuota(t, "reloadOther", config, now)
+	assert.Equal(t, http.StatusOK, serveQuota(other, "basic").Code)
+}
+
+func TestQuota_maxLocalSources(t *testing.T) {
+	now := time.Now()
+	quota := newTestQuota(t, "maxLocalSources", &types.Quota{Default: 1}, now)
+
+	start, _ := quota.periodBounds(now)
+	quota.store.period = start
+	for i := 0; i < maxQuotaLocalSources-1; i++ {
+		quota.store.counts[strconv.Itoa(i)] = 1
+	}
+
+	assert.Equal(t, http.StatusOK, serveQuota(quota, "basic").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveQuota(quota, "basic").Code)
+
+	// The other sources are not counted once the maximum is reached.
+	assert.Equal(t, http.StatusOK, serveQuota(quota, "other").Code)
+	assert.Equal(t, http.StatusOK, serveQuota(quota, "other").Code)
+	assert.Len(t, quota.store.counts, maxQuotaLocalSources)
+}
+
+func TestQuota_noQuota(t *testing.T) {
+	quota := newTestQuota(t, "noQuota", &types.Quota{Keys: map[string]int64{"premium": 1}}, time.Now())
+
+	for i := 0; i < 3; i++ {
+		recorder := serveQuota(quota, "basic")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Empty(t, recorder.Header().Get("X-Quota-Limit"))
+	}
+}
+
+func TestQuota_periodBounds(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc          string
+		period        string
+		timeZone      string
+		now           time.Time
+		expectedStart time.Time
+		expectedReset time.Time
+	}{
+		{
+			desc:          "day",
+			period:        QuotaPeriodDay,
+			now:           time.Date(2018, time.December, 31, 23, 59, 0, 0, time.UTC),
+			expectedStart: time.Date(2018, time.December, 31, 0, 0, 0, 0, time.UTC),
+			expectedReset: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:          "month",
+			period:        QuotaPeriodMonth,
+			now:           time.Date(2018, time.December, 31, 23, 59, 0, 0, time.UTC),
+			expectedStart: time.Date(2018, time.December, 1, 0, 0, 0, 0, time.UTC),
+			expectedReset: time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:          "day in a time zone",
+			period:        QuotaPeriodDay,
+			timeZone:      "Europe/Paris",
+			now:           time.Date(2018, time.October, 15, 23, 30, 0, 0, time.UTC),
+			expectedStart: time.Date(2018, time.October, 16, 0, 0, 0, 0, paris),
+			expectedReset: time.Date(2018, time.October, 17, 0, 0, 0, 0, paris),
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			quota := newTestQuota(t, "periodBounds", &types.Quota{Period: test.period, TimeZone: test.timeZone, Default: 1}, test.now)
+
+			start, reset := quota.periodBounds(test.now)
+			assert.True(t, test.expectedStart.Equal(start), "start %s", start)
+			assert.True(t, test.expectedReset.Equal(reset), "reset %s", reset)
+		})
+	}
+}
+
+func TestQuota_redis(t *testing.T) {
+	server := newFakeRedis(t, "3")
+	defer server.listener.Close()
+
+	now := time.Date(2018, time.October, 15, 10, 30, 0, 0, time.UTC)
+	quota := newTestQuota(t, "frontend1", &types.Quota{
+		Period:  QuotaPeriodMonth,
+		Default: 2,
+		Redis:   &types.RateLimitRedis{Address: server.listener.Addr().String()},
+	}, now)
+
+	recorder := serveQuota(quota, "basic")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "0", recorder.Header().Get("X-Quota-Remaining"))
+
+	commands := server.getCommands()
+	require.Len(t, commands, 2)
+	assert.Equal(t, "EVALSHA", commands[0][0])
+
+	start := time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC)
+	expiration := time.Date(2018, time.November, 1, 1, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"EVAL", quotaScript, "1",
+		"traefik:quota:frontend1:basic:" + strconv.FormatInt(start.Unix(), 10),
+		strconv.FormatInt(expiration.UnixNano()/int64(time.Millisecond), 10),
+	}, commands[1])
+}
+
+func TestQuota_redisFallback(t *testing.T) {
+	// Get a free address, nobody listens on.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	quota := newTestQuota(t, "redisFallback", &types.Quota{Default: 1, Redis: &types.RateLimitRedis{Address: address}}, time.Now())
+
+	assert.Equal(t, http.StatusOK, serveQuota(quota, "basic").Code)
+	assert.True(t, quota.isRedisDown())
+	assert.Equal(t, http.StatusTooManyRequests, serveQuota(quota, "basic").Code)
+}
+
+func TestNewQuota_invalidConfig(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.Quota
+	}{
+		{
+			desc:   "invalid period",
+			config: &types.Quota{Period: "week"},
+		},
+		{
+			desc:   "invalid time zone",
+			config: &types.Quota{TimeZone: "Mars/Olympus"},
+		},
+		{
+			desc:   "negative default quota",
+			config: &types.Quota{Default: -1},
+		},
+		{
+			desc:   "invalid quota of a key",
+			config: &types.Quota{Keys: map[string]int64{"basic": 0}},
+		},
+		{
+			desc:   "missing Redis address",
+			config: &types.Quota{Default: 1, Redis: &types.RateLimitRedis{}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			extractor, err := utils.NewExtractor("client.ip")
+			require.NoError(t, err)
+
+			_, err = NewQuota("frontend1", nil, extractor, test.config, NewRedisClients())
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package ratelimit
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+// redisRetryInterval is the time during which the fallback limiter is used after a Redis failure.
+const redisRetryInterval = 5 * time.Second
+
+// tokenBucketScript consumes tokens from one token bucket per rate, all or nothing.
+// KEYS: the buckets. ARGV: the amount of tokens, then the average, the period (ms) and the burst of each rate.
+// The current time is the one of the Redis server, shared by the Traefik instances,
+// which requires the effects of the script to be replicated rather than the script itself (Redis 3.2 or later).
+// It returns 0 when the tokens are consumed, the delay (ms) before they are available otherwise,
+// or -1 when the amount of tokens exceeds a burst.
+const tokenBucketScript = `
+redis.replicate_commands()
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local amount = tonumber(ARGV[1])
+local delay = 0
+local buckets = {}
+
+for i, key in ipairs(KEYS) do
+  local average = tonumber(ARGV[3 * i - 1])
+  local period = tonumber(ARGV[3 * i])
+  local burst = tonumber(ARGV[3 * i + 1])
+
+  if amount > burst then
+    return -1
+  end
+
+  local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+  local tokens = tonumber(bucket[1]) or burst
+  local ts = tonumber(bucket[2]) or now
+
+  if now > ts then
+    tokens = math.min(burst, tokens + (now - ts) * average / period)
+    ts = now
+  end
+
+  if tokens < amount then
+    delay = math.max(delay, math.ceil((amount - tokens) * period / average))
+  end
+
+  buckets[i] = {tokens, ts, period}
+end
+
+if delay > 0 then
+  return delay
+end
+
+for i, key in ipairs(KEYS) do
+  redis.call('HMSET', key, 'tokens', buckets[i][1] - amount, 'ts', buckets[i][2])
+  redis.call('PEXPIRE', key, buckets[i][3] * 10)
+end
+
+return 0
+`
+
+var tokenBucketScriptSHA = sha1Hex(tokenBucketScript)
+
+type rate struct {
+	period  time.Duration
+	average int64
+	burst   int64
+}
+
+// RedisRateLimiter is a token bucket rate limiter which shares its buckets between
+// the Traefik instances through Redis.
+// When Redis is unreachable, the requests are handled by the fallback (in-memory) limiter.
+type RedisRateLimiter struct {
+	name      string
+	next      http.Handler
+	fallback  http.Handler
+	extractor utils.SourceExtractor
+	rates     []rate
+	redis     *types.RateLimitRedis
+	clients   *RedisClients
+	client    *redisClient
+
+	lock      sync.RWMutex
+	downUntil time.Time
+}
+
+// NewRedisRateLimiter creates a new RedisRateLimiter, whose Redis client is got from the clients once the configuration is loaded.
+// The name is used to isolate the buckets of the rate limiters sharing the same Redis server.
+func NewRedisRateLimiter(name string, next http.Handler, fallback http.Handler, extractor utils.SourceExtractor,
+	rateSet map[string]*types.Rate, config *types.RateLimitRedis, clients *RedisClients) (*RedisRateLimiter, error) {
+	rl := &RedisRateLimiter{
+		name:      name,
+		next:      next,
+		fallback:  fallback,
+		extractor: extractor,
+		redis:     config,
+		clients:   clients,
+	}
+
+	for _, r := range rateSet {
+		if r.Period <= 0 || r.Average <= 0 || r.Burst <= 0 {
+			return nil, fmt.Errorf("invalid rate: period %s, average %d, burst %d", time.Duration(r.Period), r.Average, r.Burst)
+		}
+		rl.rates = append(rl.rates, rate{period: time.Duration(r.Period), average: r.Average, burst: r.Burst})
+	}
+
+	// Keep the script arguments stable.
+	sort.Slice(rl.rates, func(i, j int) bool {
+		return rl.rates[i].period < rl.rates[j].period
+	})
+
+	if err := checkRedisConfig(config); err != nil {
+		return nil, err
+	}
+
+	return rl, nil
+}
+
+// PostLoad gets the client of the Redis server, shared by the middlewares using the same Redis server.
+func (rl *RedisRateLimiter) PostLoad(_ map[string]http.Handler) error {
+	client, err := rl.clients.get(rl.redis)
+	if err != nil {
+		return err
+	}
+
+	rl.client = client
+	return nil
+}
+
+func (rl *RedisRateLimiter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if rl.client == nil || rl.isRedisDown() {
+		rl.fallback.ServeHTTP(rw, req)
+		return
+	}
+
+	source, amount, err := rl.extractor.Extract(req)
+	if err != nil {
+		utils.DefaultHandler.ServeHTTP(rw, req, err)
+		return
+	}
+
+	delay, err := rl.consume(source, amount)
+	if err != nil {
+		log.Warnf("Rate limiter %s: unable to reach Redis, falling back on the in-memory limiter for %s: %v", rl.name, redisRetryInterval, err)
+		rl.setRedisDown()
+		rl.fallback.ServeHTTP(rw, req)
+		return
+	}
+
+	if delay < 0 {
+		log.Debugf("Rate limiter %s: limiting request %s %s, the amount of tokens %d exceeds the burst", rl.name, req.Method, req.URL, amount)
+		http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	if delay > 0 {
+		log.Debugf("Rate limiter %s: limiting request %s %s, retry in %s", rl.name, req.Method, req.URL, delay)
+		rw.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10))
+		rw.Header().Set("X-Retry-In", delay.String())
+		rw.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprintf(rw, "max rate reached: retry-in %v", delay)
+		return
+	}
+
+	rl.next.ServeHTTP(rw, req)
+}
+
+// consume consumes the amount of tokens from the buckets of the source,
+// and returns the delay before they are available if they are not, or a negative delay if they never will be.
+func (rl *RedisRateLimiter) consume(source string, amount int64) (time.Duration, error) {
+	args := []string{"", "", strconv.Itoa(len(rl.rates))}
+
+	for _, r := range rl.rates {
+		args = append(args, strings.Join([]string{"traefik", "ratelimit", rl.name, source, strconv.FormatInt(int64(r.period/time.Millisecond), 10)}, ":"))
+	}
+
+	args = append(args, strconv.FormatInt(amount, 10))
+	for _, r := range rl.rates {
+		args = append(args,
+			strconv.FormatInt(r.average, 10),
+			strconv.FormatInt(int64(r.period/time.Millisecond), 10),
+			strconv.FormatInt(r.burst, 10))
+	}
+
+	args[0], args[1] = "EVALSHA", tokenBucketScriptSHA
+	reply, err := rl.client.do(args...)
+	if err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT") {
+		args[0], args[1] = "EVAL", tokenBucketScript
+		reply, err = rl.client.do(args...)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	delay, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply: %v", reply)
+	}
+
+	return time.Duration(delay) * time.Millisecond, nil
+}
+
+func (rl *RedisRateLimiter) isRedisDown() bool {
+	rl.lock.RLock()
+	defer rl.lock.RUnlock()
+
+	return time.Now().Before(rl.downUntil)
+}
+
+func (rl *RedisRateLimiter) setRedisDown() {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	rl.downUntil = time.Now().Add(redisRetryInterval)
+}
+
+func sha1Hex(value string) string {
+	hash := sha1.Sum([]byte(value))
+	return hex.EncodeToString(hash[:])
+}
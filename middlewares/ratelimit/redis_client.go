@@ -0,0 +1,323 @@
+package ratelimit
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/types"
+)
+
+const (
+	redisTimeout      = 500 * time.Millisecond
+	redisMaxIdleConns = 16
+)
+
+// redisError is an error reply of the Redis server.
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisClient is a minimal Redis client, only able to send commands and read their replies,
+// which keeps a pool of idle connections.
+// The rate limiters and the quotas only send a few commands (AUTH, SELECT, EVAL and EVALSHA)
+// and read integer or array replies, which doesn't warrant vendoring a full Redis client library
+// with its connection management and dependencies.
+type redisClient struct {
+	address   string
+	password  string
+	db        int
+	tlsConfig *tls.Config
+
+	idleConns chan *redisConn
+
+	lock   sync.Mutex
+	closed bool
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newRedisClient(address, password string, db int, tlsConfig *tls.Config) *redisClient {
+	return &redisClient{
+		address:   address,
+		password:  password,
+		db:        db,
+		tlsConfig: tlsConfig,
+		idleConns: make(chan *redisConn, redisMaxIdleConns),
+	}
+}
+
+// checkRedisConfig checks the Redis configuration of a rate limiter or of a quota, without connecting to Redis.
+func checkRedisConfig(config *types.RateLimitRedis) error {
+	if len(config.Address) == 0 {
+		return errors.New("missing Redis address")
+	}
+
+	if config.TLS != nil {
+		if _, err := config.TLS.CreateTLSConfig(); err != nil {
+			return fmt.Errorf("unable to create the Redis TLS configuration: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// RedisClients shares the Redis clients of the rate limiters and of the quotas by Redis server,
+// so that they outlive the configuration reloads.
+// The clients no longer used by the middlewares of the loaded configuration are closed by Sweep.
+type RedisClients struct {
+	lock    sync.Mutex
+	clients map[string]*redisClient
+	used    map[string]bool
+}
+
+// NewRedisClients creates a new RedisClients.
+func NewRedisClients() *RedisClients {
+	return &RedisClients{
+		clients: make(map[string]*redisClient),
+		used:    make(map[string]bool),
+	}
+}
+
+// Close closes the clients.
+func (r *RedisClients) Close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for key, client := range r.clients {
+		client.close()
+		delete(r.clients, key)
+	}
+}
+
+// Sweep closes the clients which weren't got since the previous sweep, once a configuration is loaded.
+func (r *RedisClients) Sweep() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for key, client := range r.clients {
+		if r.used[key] {
+			continue
+		}
+
+		client.close()
+		delete(r.clients, key)
+	}
+	r.used = make(map[string]bool)
+}
+
+// get returns the client of the Redis configuration of a rate limiter or of a quota,
+// shared by the middlewares using the same Redis server.
+func (r *RedisClients) get(config *types.RateLimitRedis) (*redisClient, error) {
+	if len(config.Address) == 0 {
+		return nil, errors.New("missing Redis address")
+	}
+
+	key := fmt.Sprintf("%s|%d|%s", config.Address, config.DB, config.Password)
+	if config.TLS != nil {
+		key = fmt.Sprintf("%s|%+v", key, *config.TLS)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if client, ok := r.clients[key]; ok {
+		r.used[key] = true
+		return client, nil
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS != nil {
+		var err error
+		tlsConfig, err = config.TLS.CreateTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the Redis TLS configuration: %v", err)
+		}
+	}
+
+	client := newRedisClient(config.Address, config.Password, config.DB, tlsConfig)
+	r.clients[key] = client
+	r.used[key] = true
+	return client, nil
+}
+
+// close closes the idle connections, and the connections released afterwards.
+func (c *redisClient) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+	for {
+		select {
+		case conn := <-c.idleConns:
+			conn.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// do sends a command to the Redis server and returns its reply.
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	conn, err := c.getConn()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(args...)
+	if err != nil {
+		if _, ok := err.(redisError); !ok {
+			// The connection state is unknown after a network or protocol error.
+			conn.conn.Close()
+			return nil, err
+		}
+	}
+
+	c.putConn(conn)
+	return reply, err
+}
+
+func (c *redisClient) getConn() (*redisConn, error) {
+	select {
+	case conn := <-c.idleConns:
+		return conn, nil
+	default:
+	}
+
+	dialer := &net.Dialer{Timeout: redisTimeout}
+
+	var netConn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", c.address, c.tlsConfig)
+	} else {
+		netConn, err = dialer.Dial("tcp", c.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if len(c.password) > 0 {
+		if _, err = conn.do("AUTH", c.password); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("unable to authenticate: %v", err)
+		}
+	}
+
+	if c.db != 0 {
+		if _, err = conn.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("unable to select the database %d: %v", c.db, err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *redisClient) putConn(conn *redisConn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		conn.conn.Close()
+		return
+	}
+
+	select {
+	case c.idleConns <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+
+	// Commands are sent as RESP arrays of bulk strings.
+	var cmd strings.Builder
+	cmd.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		cmd.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+
+	if _, err := io.WriteString(c.conn, cmd.String()); err != nil {
+		return nil, err
+	}
+
+	return readReply(c.reader)
+}
+
+// readReply reads a RESP reply: simple strings and bulk strings are returned as string,
+// integers as int64, arrays as []interface{}, and errors as redisError.
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("invalid reply: %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+
+		values := make([]interface{}, size)
+		for i := range values {
+			values[i], err = readReply(reader)
+			if err != nil {
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+				values[i] = err
+			}
+		}
+		return values, nil
+	default:
+		return nil, errors.New("invalid reply type: " + line)
+	}
+}
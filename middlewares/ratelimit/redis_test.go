@@ -0,0 +1,307 @@
+package ratelimit
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/utils"
+)
+
+// fakeRedis is a Redis server which replies to the scripts with the given delay,
+// and records the received commands.
+type fakeRedis struct {
+	listener net.Listener
+	delay    string
+
+	lock     sync.Mutex
+	commands [][]string
+}
+
+func newFakeRedis(t *testing.T, delay string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &fakeRedis{listener: listener, delay: delay}
+	go server.serve()
+
+	return server
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+
+		var cmd []string
+		for _, arg := range reply.([]interface{}) {
+			cmd = append(cmd, arg.(string))
+		}
+
+		f.lock.Lock()
+		f.commands = append(f.commands, cmd)
+		f.lock.Unlock()
+
+		switch cmd[0] {
+		case "EVALSHA":
+			conn.Write([]byte("-NOSCRIPT No matching script.\r\n"))
+		case "EVAL":
+			conn.Write([]byte(":" + f.delay + "\r\n"))
+		default:
+			conn.Write([]byte("+OK\r\n"))
+		}
+	}
+}
+
+func (f *fakeRedis) getCommands() [][]string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.commands
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		delay              string
+		expectedStatusCode int
+		expectedRetryAfter string
+		expectedRetryIn    string
+	}{
+		{
+			desc:               "tokens consumed",
+			delay:              "0",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "tokens not available",
+			delay:              "1500",
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedRetryAfter: "2",
+			expectedRetryIn:    "1.5s",
+		},
+		{
+			desc:               "amount exceeds the burst",
+			delay:              "-1",
+			expectedStatusCode: http.StatusTooManyRequests,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			server := newFakeRedis(t, test.delay)
+			defer server.listener.Close()
+
+			extractor, err := utils.NewExtractor("request.host")
+			require.NoError(t, err)
+
+			rateSet := map[string]*types.Rate{
+				"long":  {Period: parse.Duration(time.Minute), Average: 100, Burst: 200},
+				"short": {Period: parse.Duration(time.Second), Average: 5, Burst: 10},
+			}
+
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			})
+			fallback := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				t.Error("the fallback must not be used")
+			})
+
+			limiter, err := NewRedisRateLimiter("frontend1", next, fallback, extractor, rateSet,
+				&types.RateLimitRedis{Address: server.listener.Addr().String(), DB: 2, Password: "secret"}, NewRedisClients())
+			require.NoError(t, err)
+			require.NoError(t, limiter.PostLoad(nil))
+
+			recorder := httptest.NewRecorder()
+			limiter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://foo.localhost", nil))
+
+			assert.Equal(t, test.expectedStatusCode, recorder.Code)
+			assert.Equal(t, test.expectedRetryAfter, recorder.Header().Get("Retry-After"))
+			assert.Equal(t, test.expectedRetryIn, recorder.Header().Get("X-Retry-In"))
+
+			commands := server.getCommands()
+			require.Len(t, commands, 4)
+			assert.Equal(t, []string{"AUTH", "secret"}, commands[0])
+			assert.Equal(t, []string{"SELECT", "2"}, commands[1])
+			assert.Equal(t, "EVALSHA", commands[2][0])
+
+			eval := commands[3]
+			assert.Equal(t, "EVAL", eval[0])
+			assert.Equal(t, "2", eval[2])
+			assert.Equal(t, "traefik:ratelimit:frontend1:foo.localhost:1000", eval[3])
+			assert.Equal(t, "traefik:ratelimit:frontend1:foo.localhost:60000", eval[4])
+			// amount, then average, period and burst of each rate.
+			assert.Equal(t, []string{"1", "5", "1000", "10", "100", "60000", "200"}, eval[5:])
+		})
+	}
+}
+
+func TestRedisRateLimiter_fallback(t *testing.T) {
+	// Get a free address, nobody listens on.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	extractor, err := utils.NewExtractor("client.ip")
+	require.NoError(t, err)
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		t.Error("the requests must go through the fallback")
+	})
+
+	var fallbackCalls int
+	fallback := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fallbackCalls++
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	limiter, err := NewRedisRateLimiter("frontend1", next, fallback, extractor,
+		map[string]*types.Rate{"rate": {Period: parse.Duration(time.Second), Average: 5, Burst: 10}},
+		&types.RateLimitRedis{Address: address}, NewRedisClients())
+	require.NoError(t, err)
+	require.NoError(t, limiter.PostLoad(nil))
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		limiter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://foo.localhost", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	assert.Equal(t, 2, fallbackCalls)
+	assert.True(t, limiter.isRedisDown())
+}
+
+func TestNewRedisRateLimiter_invalidConfig(t *testing.T) {
+	extractor, err := utils.NewExtractor("client.ip")
+	require.NoError(t, err)
+
+	_, err = NewRedisRateLimiter("frontend1", nil, nil, extractor,
+		map[string]*types.Rate{"rate": {Period: parse.Duration(time.Second), Average: 5, Burst: 10}},
+		&types.RateLimitRedis{}, NewRedisClients())
+	assert.Error(t, err)
+
+	_, err = NewRedisRateLimiter("frontend1", nil, nil, extractor,
+		map[string]*types.Rate{"rate": {Period: parse.Duration(time.Second), Average: 0, Burst: 10}},
+		&types.RateLimitRedis{Address: "127.0.0.1:6379"}, NewRedisClients())
+	assert.Error(t, err)
+}
+
+func TestRedisClients(t *testing.T) {
+	clients := NewRedisClients()
+	defer clients.Close()
+
+	client, err := clients.get(&types.RateLimitRedis{Address: "127.0.0.1:6379", DB: 1})
+	require.NoError(t, err)
+
+	// The client is shared by the middlewares using the same Redis server, across the reloads.
+	sameClient, err := clients.get(&types.RateLimitRedis{Address: "127.0.0.1:6379", DB: 1})
+	require.NoError(t, err)
+	assert.True(t, client == sameClient)
+
+	otherClient, err := clients.get(&types.RateLimitRedis{Address: "127.0.0.1:6379", DB: 2})
+	require.NoError(t, err)
+	assert.False(t, client == otherClient)
+
+	_, err = clients.get(&types.RateLimitRedis{})
+	assert.Error(t, err)
+}
+
+func TestRedisClientsSweep(t *testing.T) {
+	server := newFakeRedis(t, "1")
+	defer server.listener.Close()
+
+	clients := NewRedisClients()
+	defer clients.Close()
+
+	first, err := clients.get(&types.RateLimitRedis{Address: server.listener.Addr().String()})
+	require.NoError(t, err)
+
+	other, err := clients.get(&types.RateLimitRedis{Address: server.listener.Addr().String(), DB: 2})
+	require.NoError(t, err)
+
+	_, err = other.do("PING")
+	require.NoError(t, err)
+	require.Len(t, other.idleConns, 1)
+
+	// The clients got since the previous sweep are kept.
+	clients.Sweep()
+	assert.Len(t, clients.clients, 2)
+
+	_, err = clients.get(&types.RateLimitRedis{Address: server.listener.Addr().String()})
+	require.NoError(t, err)
+
+	clients.Sweep()
+	assert.Len(t, clients.clients, 1)
+	assert.True(t, first == clients.clients[server.listener.Addr().String()+"|0|"])
+
+	// The connections of the swept client are closed, including the ones released afterwards.
+	assert.True(t, other.closed)
+	assert.Len(t, other.idleConns, 0)
+
+	_, err = other.do("PING")
+	require.NoError(t, err)
+	assert.Len(t, other.idleConns, 0)
+}
+
+func TestReadReply(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		reply    string
+		expected interface{}
+		errorMsg string
+	}{
+		{desc: "simple string", reply: "+OK\r\n", expected: "OK"},
+		{desc: "integer", reply: ":-12\r\n", expected: int64(-12)},
+		{desc: "bulk string", reply: "$5\r\nhello\r\n", expected: "hello"},
+		{desc: "nil bulk string", reply: "$-1\r\n", expected: nil},
+		{desc: "array", reply: "*2\r\n:1\r\n$2\r\nab\r\n", expected: []interface{}{int64(1), "ab"}},
+		{desc: "error", reply: "-ERR unknown command\r\n", errorMsg: "ERR unknown command"},
+		{desc: "invalid", reply: "?\r\n", errorMsg: "invalid reply"},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			reply, err := readReply(bufio.NewReader(strings.NewReader(test.reply)))
+			if len(test.errorMsg) > 0 {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errorMsg)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, reply)
+		})
+	}
+}
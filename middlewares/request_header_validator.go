@@ -0,0 +1,170 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"golang.org/x/net/http/httpguts"
+)
+
+// Request header validation actions.
+const (
+	RequestHeaderActionReject   = "reject"
+	RequestHeaderActionSanitize = "sanitize"
+)
+
+// RequestHeaderValidator checks the request headers against malformed encodings and control characters,
+// which break the parsing or the logging of the backends, and rejects the requests with a 400 or sanitizes their headers.
+type RequestHeaderValidator struct {
+	backendName       string
+	headers           map[string]bool
+	utf8Headers       map[string]bool
+	sanitize          bool
+	violationsCounter gokitmetrics.Counter
+}
+
+// NewRequestHeaderValidator creates a new RequestHeaderValidator.
+// All the headers are checked if no headers are configured.
+func NewRequestHeaderValidator(backendName string, config *types.RequestHeaderValidation, violationsCounter gokitmetrics.Counter) (*RequestHeaderValidator, error) {
+	validator := &RequestHeaderValidator{
+		backendName:       backendName,
+		utf8Headers:       make(map[string]bool),
+		violationsCounter: violationsCounter,
+	}
+
+	switch config.Action {
+	case "", RequestHeaderActionReject:
+	case RequestHeaderActionSanitize:
+		validator.sanitize = true
+	default:
+		return nil, fmt.Errorf("unknown request header validation action %q", config.Action)
+	}
+
+	if len(config.Headers) > 0 {
+		validator.headers = make(map[string]bool)
+		for _, header := range config.Headers {
+			validator.headers[http.CanonicalHeaderKey(header)] = true
+		}
+	}
+
+	for _, header := range config.UTF8Headers {
+		validator.utf8Headers[http.CanonicalHeaderKey(header)] = true
+	}
+
+	return validator, nil
+}
+
+func (v *RequestHeaderValidator) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var violation string
+	for name, values := range r.Header {
+		if v.headers != nil && !v.headers[name] {
+			continue
+		}
+
+		if !httpguts.ValidHeaderFieldName(name) {
+			violation = name
+			if !v.sanitize {
+				break
+			}
+
+			r.Header.Del(name)
+			continue
+		}
+
+		for i, value := range values {
+			if v.isValidValue(name, value) {
+				continue
+			}
+
+			violation = name
+			if !v.sanitize {
+				break
+			}
+
+			values[i] = v.sanitizeValue(name, value)
+		}
+
+		if len(violation) > 0 && !v.sanitize {
+			break
+		}
+	}
+
+	if len(violation) == 0 {
+		next.ServeHTTP(rw, r)
+		return
+	}
+
+	if v.violationsCounter != nil {
+		v.violationsCounter.With("backend", v.backendName).Add(1)
+	}
+
+	if v.sanitize {
+		log.Debugf("Backend %s: sanitized the malformed headers of the request to %s", v.backendName, r.URL)
+		next.ServeHTTP(rw, r)
+		return
+	}
+
+	log.Debugf("Backend %s: rejecting the request to %s with the malformed header %q", v.backendName, r.URL, violation)
+	http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+}
+
+// isValidValue returns true if the value is printable ASCII, or valid UTF-8 without control characters for the UTF-8 headers.
+func (v *RequestHeaderValidator) isValidValue(name, value string) bool {
+	if !v.utf8Headers[name] {
+		for i := 0; i < len(value); i++ {
+			if !isPrintableASCII(value[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !utf8.ValidString(value) {
+		return false
+	}
+
+	for _, c := range value {
+		if isControl(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeValue removes the characters of the value which are not printable ASCII,
+// or the invalid UTF-8 sequences and the control characters for the UTF-8 headers.
+func (v *RequestHeaderValidator) sanitizeValue(name, value string) string {
+	var sanitized strings.Builder
+
+	if !v.utf8Headers[name] {
+		for i := 0; i < len(value); i++ {
+			if isPrintableASCII(value[i]) {
+				sanitized.WriteByte(value[i])
+			}
+		}
+		return sanitized.String()
+	}
+
+	for len(value) > 0 {
+		c, size := utf8.DecodeRuneInString(value)
+		if !(c == utf8.RuneError && size == 1) && !isControl(c) {
+			sanitized.WriteString(value[:size])
+		}
+		value = value[size:]
+	}
+	return sanitized.String()
+}
+
+func isPrintableASCII(b byte) bool {
+	return b == '\t' || (b >= 0x20 && b < 0x7f)
+}
+
+func isControl(c rune) bool {
+	return c != '\t' && unicode.IsControl(c)
+}
@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestHeaderValidator(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		config             *types.RequestHeaderValidation
+		headers            http.Header
+		expectedStatus     int
+		expectedHeaders    http.Header
+		expectedViolations float64
+	}{
+		{
+			desc:            "valid headers",
+			config:          &types.RequestHeaderValidation{},
+			headers:         http.Header{"X-Foo": {"bar\tbaz"}},
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: http.Header{"X-Foo": {"bar\tbaz"}},
+		},
+		{
+			desc:               "CR LF",
+			config:             &types.RequestHeaderValidation{},
+			headers:            http.Header{"X-Foo": {"bar\r\nX-Injected: true"}},
+			expectedStatus:     http.StatusBadRequest,
+			expectedViolations: 1,
+		},
+		{
+			desc:               "invalid header name",
+			config:             &types.RequestHeaderValidation{Action: RequestHeaderActionReject},
+			headers:            http.Header{"X Foo": {"bar"}},
+			expectedStatus:     http.StatusBadRequest,
+			expectedViolations: 1,
+		},
+		{
+			desc:               "non ASCII value",
+			config:             &types.RequestHeaderValidation{},
+			headers:            http.Header{"X-Name": {"Zoë"}},
+			expectedStatus:     http.StatusBadRequest,
+			expectedViolations: 1,
+		},
+		{
+			desc:            "UTF-8 value",
+			config:          &types.RequestHeaderValidation{UTF8Headers: []string{"x-name"}},
+			headers:         http.Header{"X-Name": {"Zoë"}},
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: http.Header{"X-Name": {"Zoë"}},
+		},
+		{
+			desc:               "invalid UTF-8 value",
+			config:             &types.RequestHeaderValidation{UTF8Headers: []string{"X-Name"}},
+			headers:            http.Header{"X-Name": {"Zo\xff"}},
+			expectedStatus:     http.StatusBadRequest,
+			expectedViolations: 1,
+		},
+		{
+			desc:            "unchecked header",
+			config:          &types.RequestHeaderValidation{Headers: []string{"user-agent"}},
+			headers:         http.Header{"X-Foo": {"bar\x00"}, "User-Agent": {"curl"}},
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: http.Header{"X-Foo": {"bar\x00"}, "User-Agent": {"curl"}},
+		},
+		{
+			desc:               "sanitized headers",
+			config:             &types.RequestHeaderValidation{UTF8Headers: []string{"X-Name"}, Action: RequestHeaderActionSanitize},
+			headers:            http.Header{"X-Foo": {"bar\r\nbaz", "ok"}, "X-Name": {"Zo\xffë\x1b"}, "X Foo": {"bar"}},
+			expectedStatus:     http.StatusOK,
+			expectedHeaders:    http.Header{"X-Foo": {"barbaz", "ok"}, "X-Name": {"Zoë"}},
+			expectedViolations: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			counter := &testhelpers.CollectingCounter{}
+			validator, err := NewRequestHeaderValidator("backend1", test.config, counter)
+			require.NoError(t, err)
+
+			var forwarded http.Header
+			next := func(rw http.ResponseWriter, req *http.Request) {
+				forwarded = req.Header
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			req.Header = test.headers
+
+			recorder := httptest.NewRecorder()
+			validator.ServeHTTP(recorder, req, next)
+
+			assert.Equal(t, test.expectedStatus, recorder.Code)
+			assert.Equal(t, test.expectedHeaders, forwarded)
+			assert.Equal(t, test.expectedViolations, counter.CounterValue)
+			if test.expectedViolations > 0 {
+				assert.Equal(t, []string{"backend", "backend1"}, counter.LastLabelValues)
+			}
+		})
+	}
+}
+
+func TestNewRequestHeaderValidatorFail(t *testing.T) {
+	_, err := NewRequestHeaderValidator("backend1", &types.RequestHeaderValidation{Action: "drop"}, nil)
+	assert.Error(t, err)
+}
@@ -12,11 +12,17 @@ import (
 var requestHostKey struct{}
 
 // RequestHost is the struct for the middleware that adds the CanonicalDomain of the request Host into a context for later use.
-type RequestHost struct{}
+// With StripTrailingDot, the fully qualified hosts, ending with a dot, are canonized without it.
+type RequestHost struct {
+	StripTrailingDot bool
+}
 
 func (rh *RequestHost) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	if next != nil {
 		host := types.CanonicalDomain(parseHost(r.Host))
+		if rh.StripTrailingDot {
+			host = strings.TrimSuffix(host, ".")
+		}
 		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), requestHostKey, host)))
 	}
 }
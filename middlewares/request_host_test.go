@@ -10,9 +10,10 @@ import (
 
 func TestRequestHost(t *testing.T) {
 	testCases := []struct {
-		desc     string
-		url      string
-		expected string
+		desc             string
+		url              string
+		stripTrailingDot bool
+		expected         string
 	}{
 		{
 			desc:     "host without :",
@@ -34,10 +35,19 @@ func TestRequestHost(t *testing.T) {
 			url:      "http://127.0.0.1:",
 			expected: "127.0.0.1",
 		},
+		{
+			desc:     "host with trailing dot",
+			url:      "http://host.",
+			expected: "host.",
+		},
+		{
+			desc:             "host with trailing dot stripped",
+			url:              "http://host.:80",
+			stripTrailingDot: true,
+			expected:         "host",
+		},
 	}
 
-	rh := &RequestHost{}
-
 	for _, test := range testCases {
 		test := test
 		t.Run(test.desc, func(t *testing.T) {
@@ -45,6 +55,7 @@ func TestRequestHost(t *testing.T) {
 
 			req := testhelpers.MustNewRequest(http.MethodGet, test.url, nil)
 
+			rh := &RequestHost{StripTrailingDot: test.stripTrailingDot}
 			rh.ServeHTTP(nil, req, func(_ http.ResponseWriter, r *http.Request) {
 				host := GetCanonizedHost(r.Context())
 				assert.Equal(t, test.expected, host)
@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/containous/mux"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"golang.org/x/net/http/httpguts"
+)
+
+// templateRequest is the view of the request given to the templates, without its body.
+type templateRequest struct {
+	Method     string
+	Host       string
+	Path       string
+	Query      url.Values
+	Header     http.Header
+	RemoteAddr string
+}
+
+// templateData is the data the request templates are rendered with.
+type templateData struct {
+	Request templateRequest
+	Vars    map[string]string
+}
+
+// RequestTemplate is a middleware setting the headers and the path of the requests from templates,
+// rendered with the attributes of the request and the variables captured by its route.
+type RequestTemplate struct {
+	headers map[string]*template.Template
+	path    *template.Template
+}
+
+// NewRequestTemplate compiles the templates of the configuration, and creates a new RequestTemplate.
+// The templates only have access to the built-in functions of text/template.
+func NewRequestTemplate(config *types.RequestTemplate) (*RequestTemplate, error) {
+	rt := &RequestTemplate{headers: make(map[string]*template.Template)}
+
+	for name, text := range config.Headers {
+		tmpl, err := newRequestTemplate(name, text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of the header %s: %v", name, err)
+		}
+		rt.headers[http.CanonicalHeaderKey(name)] = tmpl
+	}
+
+	if len(config.Path) > 0 {
+		tmpl, err := newRequestTemplate("path", config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of the path: %v", err)
+		}
+		rt.path = tmpl
+	}
+
+	return rt, nil
+}
+
+func newRequestTemplate(name, text string) (*template.Template, error) {
+	// The missing route variables are rendered as empty strings.
+	return template.New(name).Option("missingkey=zero").Parse(text)
+}
+
+func (rt *RequestTemplate) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	data := templateData{
+		Request: templateRequest{
+			Method:     r.Method,
+			Host:       r.Host,
+			Path:       r.URL.Path,
+			Query:      r.URL.Query(),
+			Header:     r.Header,
+			RemoteAddr: r.RemoteAddr,
+		},
+		Vars: mux.Vars(r),
+	}
+
+	// All the templates are rendered from the original request, before it is modified.
+	headers := make(map[string]string, len(rt.headers))
+	for name, tmpl := range rt.headers {
+		value, err := render(tmpl, data)
+		if err == nil && !httpguts.ValidHeaderFieldValue(value) {
+			err = fmt.Errorf("invalid header value %q", value)
+		}
+		if err != nil {
+			log.Errorf("Unable to render the template of the header %s: %v", name, err)
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		headers[name] = value
+	}
+
+	var path string
+	if rt.path != nil {
+		var err error
+		path, err = render(rt.path, data)
+		if err != nil {
+			log.Errorf("Unable to render the template of the path: %v", err)
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		// The rendered path comes from the request, which must not be able to leave the path space of the backend.
+		if err = validateRenderedPath(path); err != nil {
+			log.Debugf("Rejecting the rendered path %q: %v", path, err)
+			http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for name, value := range headers {
+		if len(value) == 0 {
+			r.Header.Del(name)
+		} else {
+			r.Header.Set(name, value)
+		}
+	}
+
+	if rt.path != nil {
+		r = r.WithContext(context.WithValue(r.Context(), ReplacePathKey, r.URL.Path))
+		r.Header.Add(ReplacedPathHeader, r.URL.Path)
+		r.URL.Path = path
+		r.URL.RawPath = ""
+		r.RequestURI = r.URL.RequestURI()
+	}
+
+	next(rw, r)
+}
+
+func render(tmpl *template.Template, data templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// validateRenderedPath returns an error if the path is empty, relative, or has a ".." segment.
+func validateRenderedPath(path string) error {
+	if !strings.HasPrefix(path, "/") {
+		return errors.New("not an absolute path")
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." {
+			return errors.New("dot-dot segment")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/mux"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTemplate(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		config             *types.RequestTemplate
+		requestHeaders     map[string]string
+		expectedStatusCode int
+		expectedHeaders    map[string]string
+		expectedPath       string
+	}{
+		{
+			desc: "header from a request header",
+			config: &types.RequestTemplate{
+				Headers: map[string]string{"X-Tenant-Id": `{{ .Request.Header.Get "X-Tenant" }}-suffix`},
+			},
+			requestHeaders:     map[string]string{"X-Tenant": "acme"},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders:    map[string]string{"X-Tenant-Id": "acme-suffix"},
+			expectedPath:       "/users/42",
+		},
+		{
+			desc: "header from the request attributes",
+			config: &types.RequestTemplate{
+				Headers: map[string]string{"X-Request": `{{ .Request.Method }} {{ .Request.Host }}{{ .Request.Path }}?{{ .Request.Query.Get "page" }}`},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders:    map[string]string{"X-Request": "GET localhost/users/42?2"},
+			expectedPath:       "/users/42",
+		},
+		{
+			desc: "header removed when rendered empty",
+			config: &types.RequestTemplate{
+				Headers: map[string]string{"X-Debug": `{{ .Vars.debug }}`},
+			},
+			requestHeaders:     map[string]string{"X-Debug": "true"},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders:    map[string]string{"X-Debug": ""},
+			expectedPath:       "/users/42",
+		},
+		{
+			desc: "path from the route variables",
+			config: &types.RequestTemplate{
+				Path: `/api/{{ .Vars.resource }}/{{ .Vars.id }}`,
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders:    map[string]string{ReplacedPathHeader: "/users/42"},
+			expectedPath:       "/api/users/42",
+		},
+		{
+			desc: "relative path",
+			config: &types.RequestTemplate{
+				Path: `{{ .Vars.id }}`,
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			desc: "empty path",
+			config: &types.RequestTemplate{
+				Path: `{{ .Vars.unknown }}`,
+			},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			desc: "path with a dot-dot segment",
+			config: &types.RequestTemplate{
+				Path: `/api/{{ .Request.Header.Get "X-Tenant" }}/{{ .Vars.id }}`,
+			},
+			requestHeaders:     map[string]string{"X-Tenant": "../admin"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			desc: "path with dots in a segment",
+			config: &types.RequestTemplate{
+				Path: `/api/{{ .Request.Header.Get "X-Tenant" }}/{{ .Vars.id }}`,
+			},
+			requestHeaders:     map[string]string{"X-Tenant": "acme..v2"},
+			expectedStatusCode: http.StatusOK,
+			expectedPath:       "/api/acme..v2/42",
+		},
+		{
+			desc: "invalid header value",
+			config: &types.RequestTemplate{
+				Headers: map[string]string{"X-Tenant-Id": `{{ .Request.Header.Get "X-Tenant" }}` + "\n"},
+			},
+			requestHeaders:     map[string]string{"X-Tenant": "acme"},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			desc: "rendering error",
+			config: &types.RequestTemplate{
+				Path: `{{ .Request.Unknown }}`,
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			requestTemplate, err := NewRequestTemplate(test.config)
+			require.NoError(t, err)
+
+			var forwarded *http.Request
+			next := func(rw http.ResponseWriter, req *http.Request) {
+				forwarded = req
+			}
+
+			router := mux.NewRouter()
+			router.Path("/{resource}/{id:[0-9]+}").HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				requestTemplate.ServeHTTP(rw, req, next)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/users/42?page=2", nil)
+			for name, value := range test.requestHeaders {
+				req.Header.Set(name, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedStatusCode, recorder.Code)
+			if test.expectedStatusCode != http.StatusOK {
+				assert.Nil(t, forwarded)
+				return
+			}
+
+			require.NotNil(t, forwarded)
+			assert.Equal(t, test.expectedPath, forwarded.URL.Path)
+			if len(test.config.Path) > 0 {
+				assert.Equal(t, forwarded.URL.RequestURI(), forwarded.RequestURI)
+			}
+			for name, value := range test.expectedHeaders {
+				assert.Equal(t, value, forwarded.Header.Get(name), name)
+			}
+		})
+	}
+}
+
+func TestNewRequestTemplate_invalidTemplate(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.RequestTemplate
+	}{
+		{
+			desc:   "invalid header template",
+			config: &types.RequestTemplate{Headers: map[string]string{"X-Tenant": "{{ .Request.Host"}},
+		},
+		{
+			desc:   "invalid path template",
+			config: &types.RequestTemplate{Path: "{{ unknownFunction }}"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewRequestTemplate(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
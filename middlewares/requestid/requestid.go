@@ -0,0 +1,130 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+type contextKey int
+
+const idKey contextKey = iota
+
+// DefaultHeaderName is the header carrying the request ID.
+const DefaultHeaderName = "X-Request-Id"
+
+// Generators of the request IDs.
+const (
+	GeneratorUUID = "uuid"
+	GeneratorULID = "ulid"
+)
+
+// maxIDLength bounds the length of the IDs sent by the clients, which end up in the logs.
+const maxIDLength = 128
+
+// Handler is a middleware giving an ID to each request: the ID of the request header, or a generated one when missing.
+// The ID is forwarded to the backend in the header, set on the response, and stored in the request context
+// for the access logs and the traces.
+type Handler struct {
+	headerName string
+	generate   func() string
+}
+
+// NewHandler creates a new Handler.
+func NewHandler(headerName, generator string) (*Handler, error) {
+	if len(headerName) == 0 {
+		headerName = DefaultHeaderName
+	}
+
+	h := &Handler{headerName: http.CanonicalHeaderKey(headerName)}
+
+	switch generator {
+	case "", GeneratorUUID:
+		h.generate = newUUID
+	case GeneratorULID:
+		h.generate = newULID
+	default:
+		return nil, fmt.Errorf("unknown request ID generator %q, must be %s or %s", generator, GeneratorUUID, GeneratorULID)
+	}
+
+	return h, nil
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id := r.Header.Get(h.headerName)
+	if !isValidID(id) {
+		id = h.generate()
+		r.Header.Set(h.headerName, id)
+	}
+
+	next(newResponseWriter(rw, h.headerName, id), r.WithContext(context.WithValue(r.Context(), idKey, id)))
+}
+
+// GetID returns the request ID of the request context, if any.
+func GetID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(idKey).(string)
+	return id, ok
+}
+
+// isValidID returns false for the missing IDs, and for the ones unsafe to log.
+func isValidID(id string) bool {
+	if len(id) == 0 || len(id) > maxIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newUUID() string {
+	return uuid.NewV4().String()
+}
+
+// newULID returns a ULID: a 48 bits timestamp in milliseconds followed by 80 random bits,
+// encoded in Crockford's base32, so that the IDs sort by creation time.
+func newULID() string {
+	var id [16]byte
+
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(id[:6], ts[2:])
+
+	if _, err := rand.Read(id[6:]); err != nil {
+		// crypto/rand doesn't fail on the supported platforms.
+		panic(err)
+	}
+
+	return encodeULID(id)
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// encodeULID encodes the 128 bits of the ULID, preceded by 2 zero bits, as 26 groups of 5 bits.
+func encodeULID(id [16]byte) string {
+	out := make([]byte, 26)
+	for i := range out {
+		var v byte
+		for j := 0; j < 5; j++ {
+			v <<= 1
+
+			bit := i*5 + j - 2
+			if bit >= 0 && id[bit/8]&(0x80>>uint(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockfordAlphabet[v]
+	}
+
+	return string(out)
+}
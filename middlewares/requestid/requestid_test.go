@@ -0,0 +1,157 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	uuidRegexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidRegexp = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+)
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		headerName     string
+		generator      string
+		incomingID     string
+		expectedID     string
+		expectedRegexp *regexp.Regexp
+	}{
+		{
+			desc:           "generated UUID",
+			expectedRegexp: uuidRegexp,
+		},
+		{
+			desc:           "generated ULID",
+			generator:      GeneratorULID,
+			expectedRegexp: ulidRegexp,
+		},
+		{
+			desc:       "incoming ID",
+			incomingID: "abc-123",
+			expectedID: "abc-123",
+		},
+		{
+			desc:       "custom header name",
+			headerName: "X-Correlation-Id",
+			incomingID: "abc-123",
+			expectedID: "abc-123",
+		},
+		{
+			desc:           "incoming ID unsafe to log",
+			incomingID:     "abc 123",
+			expectedRegexp: uuidRegexp,
+		},
+		{
+			desc:           "incoming ID too long",
+			incomingID:     strings.Repeat("a", maxIDLength+1),
+			expectedRegexp: uuidRegexp,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			handler, err := NewHandler(test.headerName, test.generator)
+			require.NoError(t, err)
+
+			headerName := test.headerName
+			if len(headerName) == 0 {
+				headerName = DefaultHeaderName
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			if len(test.incomingID) > 0 {
+				req.Header.Set(headerName, test.incomingID)
+			}
+
+			var forwardedID, contextID string
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				forwardedID = r.Header.Get(headerName)
+				contextID, _ = GetID(r.Context())
+
+				// The ID echoed by the backend is replaced.
+				rw.Header().Add(headerName, "echoed")
+				rw.Write([]byte("OK"))
+			})
+
+			if test.expectedRegexp != nil {
+				assert.Regexp(t, test.expectedRegexp, forwardedID)
+			} else {
+				assert.Equal(t, test.expectedID, forwardedID)
+			}
+
+			assert.Equal(t, forwardedID, contextID)
+			assert.Equal(t, []string{forwardedID}, recorder.Header()[headerName])
+		})
+	}
+}
+
+func TestNewHandlerUnknownGenerator(t *testing.T) {
+	_, err := NewHandler("", "foo")
+	assert.Error(t, err)
+}
+
+func TestGetIDWithoutHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+
+	_, ok := GetID(req.Context())
+	assert.False(t, ok)
+}
+
+func TestEncodeULID(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		id       [16]byte
+		expected string
+	}{
+		{
+			desc:     "zero",
+			expected: "00000000000000000000000000",
+		},
+		{
+			desc:     "max",
+			id:       [16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			expected: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ",
+		},
+		{
+			desc:     "lowest bit",
+			id:       [16]byte{15: 0x01},
+			expected: "00000000000000000000000001",
+		},
+		{
+			desc:     "timestamp",
+			id:       [16]byte{0x01, 0x56, 0x3d, 0xf3, 0x64, 0x81},
+			expected: "01ARYZ6S410000000000000000",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, encodeULID(test.id))
+		})
+	}
+}
+
+func TestNewULIDSortable(t *testing.T) {
+	first := newULID()
+	second := newULID()
+
+	// The timestamps are the same or increasing, the IDs differ by their random part.
+	assert.True(t, first[:10] <= second[:10])
+	assert.NotEqual(t, first, second)
+}
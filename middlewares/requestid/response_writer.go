@@ -0,0 +1,62 @@
+package requestid
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// responseWriterWithoutCloseNotify sets the request ID on the response headers when they are written,
+// replacing the one the backend may have echoed.
+type responseWriterWithoutCloseNotify struct {
+	http.ResponseWriter
+	headerName  string
+	id          string
+	wroteHeader bool
+}
+
+func (r *responseWriterWithoutCloseNotify) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		r.Header().Set(r.headerName, r.id)
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseWriterWithoutCloseNotify) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Hijack hijacks the connection
+func (r *responseWriterWithoutCloseNotify) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// Flush sends any buffered data to the client.
+func (r *responseWriterWithoutCloseNotify) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+type responseWriterWithCloseNotify struct {
+	*responseWriterWithoutCloseNotify
+}
+
+func (r *responseWriterWithCloseNotify) CloseNotify() <-chan bool {
+	return r.ResponseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+func newResponseWriter(rw http.ResponseWriter, headerName string, id string) http.ResponseWriter {
+	writer := &responseWriterWithoutCloseNotify{ResponseWriter: rw, headerName: headerName, id: id}
+	if _, ok := rw.(http.CloseNotifier); ok {
+		return &responseWriterWithCloseNotify{writer}
+	}
+	return writer
+}
@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// Response header rule actions.
+const (
+	ResponseHeaderActionLog     = "log"
+	ResponseHeaderActionDefault = "default"
+	ResponseHeaderActionReject  = "reject"
+)
+
+type responseHeaderRule struct {
+	name    string
+	header  string
+	pattern *regexp.Regexp
+	action  string
+	value   string
+}
+
+// ResponseHeaderValidator checks the response headers of a backend against a set of rules,
+// to catch the backends breaking their contract.
+type ResponseHeaderValidator struct {
+	backendName       string
+	rules             []responseHeaderRule
+	violationsCounter gokitmetrics.Counter
+}
+
+// NewResponseHeaderValidator creates a new ResponseHeaderValidator, or returns nil if there is no rule.
+func NewResponseHeaderValidator(backendName string, rules map[string]*types.ResponseHeaderRule, violationsCounter gokitmetrics.Counter) (*ResponseHeaderValidator, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	validator := &ResponseHeaderValidator{
+		backendName:       backendName,
+		violationsCounter: violationsCounter,
+	}
+
+	for name, rule := range rules {
+		if rule == nil {
+			return nil, fmt.Errorf("response header rule %s: missing configuration", name)
+		}
+
+		if len(rule.Header) == 0 {
+			return nil, fmt.Errorf("response header rule %s: missing header", name)
+		}
+
+		r := responseHeaderRule{
+			name:   name,
+			header: http.CanonicalHeaderKey(rule.Header),
+			action: rule.Action,
+			value:  rule.Default,
+		}
+
+		if len(r.action) == 0 {
+			r.action = ResponseHeaderActionLog
+		}
+
+		switch r.action {
+		case ResponseHeaderActionLog, ResponseHeaderActionReject:
+		case ResponseHeaderActionDefault:
+			if len(r.value) == 0 {
+				return nil, fmt.Errorf("response header rule %s: missing default value", name)
+			}
+		default:
+			return nil, fmt.Errorf("response header rule %s: unknown action %q", name, rule.Action)
+		}
+
+		if len(rule.Pattern) > 0 {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("response header rule %s: invalid pattern: %v", name, err)
+			}
+			r.pattern = pattern
+		}
+
+		validator.rules = append(validator.rules, r)
+	}
+
+	// Apply the rules in a stable order.
+	sort.Slice(validator.rules, func(i, j int) bool {
+		return validator.rules[i].name < validator.rules[j].name
+	})
+
+	return validator, nil
+}
+
+// ModifyResponseHeaders applies the rules to the response headers.
+// It returns an error, which makes the reverse proxy answer with a 502, if a rejecting rule is violated.
+func (v *ResponseHeaderValidator) ModifyResponseHeaders(res *http.Response) error {
+	for _, rule := range v.rules {
+		values, ok := res.Header[rule.header]
+		if ok && (rule.pattern == nil || rule.pattern.MatchString(values[0])) {
+			continue
+		}
+
+		if v.violationsCounter != nil {
+			v.violationsCounter.With("backend", v.backendName, "header", rule.header).Add(1)
+		}
+
+		var reqURL string
+		if res.Request != nil {
+			reqURL = res.Request.URL.String()
+		}
+
+		switch rule.action {
+		case ResponseHeaderActionDefault:
+			log.Debugf("Backend %s: response to %s violates the header rule %s, setting %s to %q", v.backendName, reqURL, rule.name, rule.header, rule.value)
+			res.Header.Set(rule.header, rule.value)
+		case ResponseHeaderActionReject:
+			log.Warnf("Backend %s: response to %s violates the header rule %s, rejecting it", v.backendName, reqURL, rule.name)
+			return fmt.Errorf("response header %s violates the rule %s", rule.header, rule.name)
+		default:
+			log.Warnf("Backend %s: response to %s violates the header rule %s on %s", v.backendName, reqURL, rule.name, rule.header)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResponseHeaderValidator(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		rules         map[string]*types.ResponseHeaderRule
+		expectedNil   bool
+		expectedError bool
+	}{
+		{
+			desc:        "no rules",
+			expectedNil: true,
+		},
+		{
+			desc: "valid rules",
+			rules: map[string]*types.ResponseHeaderRule{
+				"content-type": {Header: "Content-Type", Pattern: "^application/json", Action: ResponseHeaderActionReject},
+				"cache":        {Header: "Cache-Control", Action: ResponseHeaderActionDefault, Default: "no-store"},
+			},
+		},
+		{
+			desc: "missing header",
+			rules: map[string]*types.ResponseHeaderRule{
+				"foo": {Pattern: "bar"},
+			},
+			expectedError: true,
+		},
+		{
+			desc: "nil rule",
+			rules: map[string]*types.ResponseHeaderRule{
+				"foo": nil,
+			},
+			expectedError: true,
+		},
+		{
+			desc: "invalid pattern",
+			rules: map[string]*types.ResponseHeaderRule{
+				"foo": {Header: "X-Foo", Pattern: "("},
+			},
+			expectedError: true,
+		},
+		{
+			desc: "unknown action",
+			rules: map[string]*types.ResponseHeaderRule{
+				"foo": {Header: "X-Foo", Action: "drop"},
+			},
+			expectedError: true,
+		},
+		{
+			desc: "default action without default value",
+			rules: map[string]*types.ResponseHeaderRule{
+				"foo": {Header: "X-Foo", Action: ResponseHeaderActionDefault},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			validator, err := NewResponseHeaderValidator("backend1", test.rules, nil)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedNil, validator == nil)
+		})
+	}
+}
+
+func TestResponseHeaderValidator(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		rule               *types.ResponseHeaderRule
+		headers            map[string]string
+		expectedError      bool
+		expectedHeader     string
+		expectedViolations float64
+	}{
+		{
+			desc:           "present header without pattern",
+			rule:           &types.ResponseHeaderRule{Header: "cache-control"},
+			headers:        map[string]string{"Cache-Control": "max-age=60"},
+			expectedHeader: "max-age=60",
+		},
+		{
+			desc:           "matching header",
+			rule:           &types.ResponseHeaderRule{Header: "Content-Type", Pattern: "^application/json", Action: ResponseHeaderActionReject},
+			headers:        map[string]string{"Content-Type": "application/json; charset=utf-8"},
+			expectedHeader: "application/json; charset=utf-8",
+		},
+		{
+			desc:               "missing header is logged",
+			rule:               &types.ResponseHeaderRule{Header: "Content-Type"},
+			expectedViolations: 1,
+		},
+		{
+			desc:               "mismatching header is logged",
+			rule:               &types.ResponseHeaderRule{Header: "Content-Type", Pattern: "^application/json"},
+			headers:            map[string]string{"Content-Type": "text/html"},
+			expectedHeader:     "text/html",
+			expectedViolations: 1,
+		},
+		{
+			desc:               "missing header is defaulted",
+			rule:               &types.ResponseHeaderRule{Header: "Cache-Control", Action: ResponseHeaderActionDefault, Default: "no-store"},
+			expectedHeader:     "no-store",
+			expectedViolations: 1,
+		},
+		{
+			desc:               "mismatching header is defaulted",
+			rule:               &types.ResponseHeaderRule{Header: "Cache-Control", Pattern: "^(no-store|private)", Action: ResponseHeaderActionDefault, Default: "no-store"},
+			headers:            map[string]string{"Cache-Control": "public"},
+			expectedHeader:     "no-store",
+			expectedViolations: 1,
+		},
+		{
+			desc:               "mismatching header is rejected",
+			rule:               &types.ResponseHeaderRule{Header: "Content-Type", Pattern: "^application/json", Action: ResponseHeaderActionReject},
+			headers:            map[string]string{"Content-Type": "text/html"},
+			expectedError:      true,
+			expectedHeader:     "text/html",
+			expectedViolations: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			counter := &testhelpers.CollectingCounter{}
+			validator, err := NewResponseHeaderValidator("backend1", map[string]*types.ResponseHeaderRule{"rule": test.rule}, counter)
+			require.NoError(t, err)
+
+			res := &http.Response{
+				Request: httptest.NewRequest(http.MethodGet, "http://localhost", nil),
+				Header:  make(http.Header),
+			}
+			for name, value := range test.headers {
+				res.Header.Set(name, value)
+			}
+
+			err = validator.ModifyResponseHeaders(res)
+			if test.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, test.expectedHeader, res.Header.Get(test.rule.Header))
+			assert.Equal(t, test.expectedViolations, counter.CounterValue)
+			if test.expectedViolations > 0 {
+				assert.Equal(t, []string{"backend", "backend1", "header", http.CanonicalHeaderKey(test.rule.Header)}, counter.LastLabelValues)
+			}
+		})
+	}
+}
@@ -2,11 +2,15 @@ package middlewares
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"math/rand"
 	"net"
 	"net/http"
 	"net/http/httptrace"
+	"time"
 
 	"github.com/containous/traefik/log"
 )
@@ -16,9 +20,51 @@ var _ Stateful = &retryResponseWriterWithCloseNotify{}
 
 // Retry is a middleware that retries requests
 type Retry struct {
-	attempts int
-	next     http.Handler
-	listener RetryListener
+	attempts            int
+	attemptsFunc        func() int
+	responseHeader      string
+	responseHeaderValue string
+	next                http.Handler
+	listener            RetryListener
+	backoff             RetryBackoff
+	failover            []http.Handler
+}
+
+type failoverKey struct{}
+
+// RetryBackoff holds the delay between the attempts of the Retry middleware.
+// The delay before the first retry is InitialInterval, and is multiplied by Multiplier for each following retry,
+// up to MaxInterval. With Jitter, the actual delay is randomized between zero and the computed delay.
+type RetryBackoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          bool
+}
+
+// delay returns the delay before the given retry, starting at 1.
+func (b RetryBackoff) delay(retry int) time.Duration {
+	if b.InitialInterval <= 0 {
+		return 0
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.InitialInterval) * math.Pow(multiplier, float64(retry-1))
+	if b.MaxInterval > 0 && delay > float64(b.MaxInterval) {
+		delay = float64(b.MaxInterval)
+	}
+	if delay > math.MaxInt64 {
+		delay = math.MaxInt64
+	}
+
+	if b.Jitter {
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+	return time.Duration(delay)
 }
 
 // NewRetry returns a new Retry instance
@@ -30,10 +76,61 @@ func NewRetry(attempts int, next http.Handler, listener RetryListener) *Retry {
 	}
 }
 
+// NewResponseHeaderRetry returns a new Retry instance which also retries the requests
+// when the backend response has the given header, with the given value if not empty.
+// Only the requests without body are retried on the response header, as the body was already sent to the backend.
+func NewResponseHeaderRetry(attempts int, responseHeader, responseHeaderValue string, next http.Handler, listener RetryListener) *Retry {
+	return &Retry{
+		attempts:            attempts,
+		responseHeader:      http.CanonicalHeaderKey(responseHeader),
+		responseHeaderValue: responseHeaderValue,
+		next:                next,
+		listener:            listener,
+	}
+}
+
+// SetBackoff sets the delay between the attempts, without delay by default.
+func (retry *Retry) SetBackoff(backoff RetryBackoff) {
+	retry.backoff = backoff
+}
+
+// SetAttemptsFunc makes the number of attempts computed for each request, e.g. from the current servers of a load balancer.
+func (retry *Retry) SetAttemptsFunc(attemptsFunc func() int) {
+	retry.attemptsFunc = attemptsFunc
+}
+
+// SetFailover makes the retries go to the given handlers in order, moving to the next handler after each failed attempt.
+// Once all the handlers are tried, the remaining attempts are made on the last one.
+// The requests already sent to a failover handler are not failed over again, to prevent failover loops.
+func (retry *Retry) SetFailover(handlers []http.Handler) {
+	retry.failover = handlers
+}
+
+// handler returns the handler of the given attempt, starting at 1.
+func (retry *Retry) handler(r *http.Request, attempt int) (http.Handler, *http.Request) {
+	if len(retry.failover) == 0 || attempt == 1 || r.Context().Value(failoverKey{}) != nil {
+		return retry.next, r
+	}
+
+	index := attempt - 2
+	if index >= len(retry.failover) {
+		index = len(retry.failover) - 1
+	}
+
+	return retry.failover[index], r.WithContext(context.WithValue(r.Context(), failoverKey{}, true))
+}
+
 func (retry *Retry) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	retryOnHeader := len(retry.responseHeader) > 0 && r.ContentLength == 0 && (r.Body == nil || r.Body == http.NoBody)
+
+	maxAttempts := retry.attempts
+	if retry.attemptsFunc != nil {
+		maxAttempts = retry.attemptsFunc()
+	}
+
 	// if we might make multiple attempts, swap the body for an ioutil.NopCloser
 	// cf https://github.com/containous/traefik/issues/1008
-	if retry.attempts > 1 {
+	if maxAttempts > 1 {
 		body := r.Body
 		if body == nil {
 			body = http.NoBody
@@ -44,10 +141,13 @@ func (retry *Retry) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 
 	attempts := 1
 	for {
-		attemptsExhausted := attempts >= retry.attempts
+		attemptsExhausted := attempts >= maxAttempts
 
 		shouldRetry := !attemptsExhausted
 		retryResponseWriter := newRetryResponseWriter(rw, shouldRetry)
+		if retryOnHeader && !attemptsExhausted {
+			retryResponseWriter.RetryOnResponse(retry.matchResponseHeader)
+		}
 
 		// Disable retries when the backend already received request data
 		trace := &httptrace.ClientTrace{
@@ -58,19 +158,62 @@ func (retry *Retry) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 				retryResponseWriter.DisableRetries()
 			},
 		}
-		newCtx := httptrace.WithClientTrace(r.Context(), trace)
+		next, req := retry.handler(r, attempts)
+		newCtx := httptrace.WithClientTrace(req.Context(), trace)
 
-		retry.next.ServeHTTP(retryResponseWriter, r.WithContext(newCtx))
+		next.ServeHTTP(retryResponseWriter, req.WithContext(newCtx))
 		if !retryResponseWriter.ShouldRetry() {
 			break
 		}
 
+		if !retry.wait(r, attempts) {
+			log.Debugf("Request canceled while waiting for attempt %d: %v", attempts+1, r.URL)
+			return
+		}
+
 		attempts++
 		log.Debugf("New attempt %d for request: %v", attempts, r.URL)
 		retry.listener.Retried(r, attempts)
 	}
 }
 
+// wait waits for the backoff delay before the given retry,
+// and returns false if the request is canceled in the meantime.
+func (retry *Retry) wait(r *http.Request, retries int) bool {
+	delay := retry.backoff.delay(retries)
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (retry *Retry) matchResponseHeader(header http.Header) bool {
+	values, ok := header[retry.responseHeader]
+	if !ok {
+		return false
+	}
+
+	if len(retry.responseHeaderValue) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		if value == retry.responseHeaderValue {
+			return true
+		}
+	}
+	return false
+}
+
 // RetryListener is used to inform about retry attempts.
 type RetryListener interface {
 	// Retried will be called when a retry happens, with the request attempt passed to it.
@@ -94,6 +237,7 @@ type retryResponseWriter interface {
 	http.Flusher
 	ShouldRetry() bool
 	DisableRetries()
+	RetryOnResponse(match func(http.Header) bool)
 }
 
 func newRetryResponseWriter(rw http.ResponseWriter, shouldRetry bool) retryResponseWriter {
@@ -110,24 +254,48 @@ func newRetryResponseWriter(rw http.ResponseWriter, shouldRetry bool) retryRespo
 type retryResponseWriterWithoutCloseNotify struct {
 	responseWriter http.ResponseWriter
 	shouldRetry    bool
+
+	// The response headers are held until the response is matched,
+	// to be able to discard a response which asks for a retry.
+	matchResponse   func(http.Header) bool
+	header          http.Header
+	wroteHeader     bool
+	retryOnResponse bool
 }
 
 func (rr *retryResponseWriterWithoutCloseNotify) ShouldRetry() bool {
-	return rr.shouldRetry
+	return rr.shouldRetry || rr.retryOnResponse
 }
 
 func (rr *retryResponseWriterWithoutCloseNotify) DisableRetries() {
 	rr.shouldRetry = false
 }
 
+// RetryOnResponse makes the writer ask for a retry when the backend response headers match.
+func (rr *retryResponseWriterWithoutCloseNotify) RetryOnResponse(match func(http.Header) bool) {
+	rr.matchResponse = match
+	rr.header = make(http.Header)
+}
+
+func (rr *retryResponseWriterWithoutCloseNotify) isHoldingResponse() bool {
+	return rr.matchResponse != nil && !rr.wroteHeader
+}
+
 func (rr *retryResponseWriterWithoutCloseNotify) Header() http.Header {
 	if rr.ShouldRetry() {
 		return make(http.Header)
 	}
+	if rr.isHoldingResponse() {
+		return rr.header
+	}
 	return rr.responseWriter.Header()
 }
 
 func (rr *retryResponseWriterWithoutCloseNotify) Write(buf []byte) (int, error) {
+	if !rr.ShouldRetry() && rr.isHoldingResponse() {
+		rr.WriteHeader(http.StatusOK)
+	}
+
 	if rr.ShouldRetry() {
 		return len(buf), nil
 	}
@@ -147,6 +315,20 @@ func (rr *retryResponseWriterWithoutCloseNotify) WriteHeader(code int) {
 	if rr.ShouldRetry() {
 		return
 	}
+
+	if rr.isHoldingResponse() {
+		rr.wroteHeader = true
+		if rr.matchResponse(rr.header) {
+			rr.retryOnResponse = true
+			return
+		}
+
+		header := rr.responseWriter.Header()
+		for name, values := range rr.header {
+			header[name] = values
+		}
+	}
+
 	rr.responseWriter.WriteHeader(code)
 }
 
@@ -159,6 +341,10 @@ func (rr *retryResponseWriterWithoutCloseNotify) Hijack() (net.Conn, *bufio.Read
 }
 
 func (rr *retryResponseWriterWithoutCloseNotify) Flush() {
+	if rr.retryOnResponse || rr.isHoldingResponse() {
+		return
+	}
+
 	if flusher, ok := rr.responseWriter.(http.Flusher); ok {
 		flusher.Flush()
 	}
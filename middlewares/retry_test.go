@@ -1,10 +1,15 @@
 package middlewares
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/containous/traefik/testhelpers"
 	"github.com/gorilla/websocket"
@@ -184,6 +189,105 @@ func TestRetryWebsocket(t *testing.T) {
 	}
 }
 
+func TestRetryOnResponseHeader(t *testing.T) {
+	testCases := []struct {
+		desc                   string
+		maxRequestAttempts     int
+		headerValue            string
+		responsesToRetry       int
+		body                   string
+		expectedRetryAttempts  int
+		expectedResponseStatus int
+		expectedBody           string
+	}{
+		{
+			desc:                   "no retry when the header is absent",
+			maxRequestAttempts:     3,
+			expectedRetryAttempts:  0,
+			expectedResponseStatus: http.StatusOK,
+			expectedBody:           "attempt 1",
+		},
+		{
+			desc:                   "retry until the header is absent",
+			maxRequestAttempts:     3,
+			responsesToRetry:       2,
+			expectedRetryAttempts:  2,
+			expectedResponseStatus: http.StatusOK,
+			expectedBody:           "attempt 3",
+		},
+		{
+			desc:                   "max attempts exhausted delivers the last response",
+			maxRequestAttempts:     2,
+			responsesToRetry:       3,
+			expectedRetryAttempts:  1,
+			expectedResponseStatus: http.StatusAccepted,
+			expectedBody:           "attempt 2",
+		},
+		{
+			desc:                   "header value must match",
+			maxRequestAttempts:     3,
+			headerValue:            "false",
+			responsesToRetry:       2,
+			expectedRetryAttempts:  0,
+			expectedResponseStatus: http.StatusAccepted,
+			expectedBody:           "attempt 1",
+		},
+		{
+			desc:                   "no retry for requests with body",
+			maxRequestAttempts:     3,
+			responsesToRetry:       2,
+			body:                   "data",
+			expectedRetryAttempts:  0,
+			expectedResponseStatus: http.StatusAccepted,
+			expectedBody:           "attempt 1",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			attempts := 0
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				// Behave like the forwarder, once the request is sent to the backend.
+				httptrace.ContextClientTrace(req.Context()).WroteHeaders()
+
+				attempts++
+				rw.Header().Set("X-Attempt", strconv.Itoa(attempts))
+				if attempts <= test.responsesToRetry {
+					rw.Header().Set("X-Should-Retry", "true")
+					rw.WriteHeader(http.StatusAccepted)
+				}
+				rw.Write([]byte("attempt " + strconv.Itoa(attempts)))
+			})
+
+			headerValue := "true"
+			if len(test.headerValue) > 0 {
+				headerValue = test.headerValue
+			}
+
+			retryListener := &countingRetryListener{}
+			retry := NewResponseHeaderRetry(test.maxRequestAttempts, "x-should-retry", headerValue, next, retryListener)
+
+			var body io.Reader
+			if len(test.body) > 0 {
+				body = strings.NewReader(test.body)
+			}
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://localhost:3000/ok", body)
+
+			retry.ServeHTTP(recorder, req)
+
+			assert.Equal(t, test.expectedResponseStatus, recorder.Code)
+			assert.Equal(t, test.expectedRetryAttempts, retryListener.timesCalled)
+			assert.Equal(t, test.expectedBody, recorder.Body.String())
+			assert.Equal(t, strconv.Itoa(test.expectedRetryAttempts+1), recorder.Header().Get("X-Attempt"))
+		})
+	}
+}
+
 func TestRetryEmptyServerList(t *testing.T) {
 	forwarder, err := forward.New()
 	if err != nil {
@@ -258,3 +362,235 @@ func TestRetryWithFlush(t *testing.T) {
 		t.Errorf("Wrong body %q want %q", responseRecorder.Body.String(), "FULL DATA")
 	}
 }
+
+func TestRetryBackoffDelay(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		backoff  RetryBackoff
+		retry    int
+		expected time.Duration
+	}{
+		{
+			desc:     "no delay by default",
+			retry:    3,
+			expected: 0,
+		},
+		{
+			desc:     "first retry",
+			backoff:  RetryBackoff{InitialInterval: 100 * time.Millisecond, Multiplier: 2},
+			retry:    1,
+			expected: 100 * time.Millisecond,
+		},
+		{
+			desc:     "third retry",
+			backoff:  RetryBackoff{InitialInterval: 100 * time.Millisecond, Multiplier: 2},
+			retry:    3,
+			expected: 400 * time.Millisecond,
+		},
+		{
+			desc:     "constant delay without multiplier",
+			backoff:  RetryBackoff{InitialInterval: 100 * time.Millisecond},
+			retry:    3,
+			expected: 100 * time.Millisecond,
+		},
+		{
+			desc:     "capped delay",
+			backoff:  RetryBackoff{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 10},
+			retry:    3,
+			expected: time.Second,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.backoff.delay(test.retry))
+		})
+	}
+}
+
+func TestRetryBackoffJitter(t *testing.T) {
+	backoff := RetryBackoff{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2, Jitter: true}
+
+	for retry := 1; retry <= 10; retry++ {
+		delay := backoff.delay(retry)
+		assert.True(t, delay >= 0 && delay <= time.Second, "delay %s out of range", delay)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+
+	retryListener := &countingRetryListener{}
+	retry := NewRetry(3, next, retryListener)
+	retry.SetBackoff(RetryBackoff{InitialInterval: 20 * time.Millisecond, Multiplier: 2})
+
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	retry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, 2, retryListener.timesCalled)
+	assert.True(t, time.Since(start) >= 60*time.Millisecond, "retries were not delayed")
+}
+
+func TestRetryWithAttemptsFunc(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+
+	attempts := 1
+	retryListener := &countingRetryListener{}
+	retry := NewRetry(1, next, retryListener)
+	retry.SetAttemptsFunc(func() int { return attempts })
+
+	// The attempts are computed for each request.
+	attempts = 3
+	recorder := httptest.NewRecorder()
+	retry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, 2, retryListener.timesCalled)
+}
+
+func TestRetryWithBackoffCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		cancel()
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+
+	retryListener := &countingRetryListener{}
+	retry := NewRetry(3, next, retryListener)
+	retry.SetBackoff(RetryBackoff{InitialInterval: time.Minute})
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil).WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		retry.ServeHTTP(recorder, req)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("retry did not stop on request cancellation")
+	}
+
+	assert.Equal(t, 0, retryListener.timesCalled)
+}
+
+func TestRetryWithFailover(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		attempts         int
+		failoverStatus   []int
+		expectedCode     int
+		expectedServed   []string
+		expectedAttempts int
+	}{
+		{
+			desc:             "first failover succeeds",
+			attempts:         3,
+			failoverStatus:   []int{http.StatusOK, http.StatusOK},
+			expectedCode:     http.StatusOK,
+			expectedServed:   []string{"primary", "failover0"},
+			expectedAttempts: 1,
+		},
+		{
+			desc:             "failover in order",
+			attempts:         3,
+			failoverStatus:   []int{http.StatusBadGateway, http.StatusOK},
+			expectedCode:     http.StatusOK,
+			expectedServed:   []string{"primary", "failover0", "failover1"},
+			expectedAttempts: 2,
+		},
+		{
+			desc:             "attempt budget",
+			attempts:         2,
+			failoverStatus:   []int{http.StatusBadGateway, http.StatusOK},
+			expectedCode:     http.StatusBadGateway,
+			expectedServed:   []string{"primary", "failover0"},
+			expectedAttempts: 1,
+		},
+		{
+			desc:             "remaining attempts on the last failover",
+			attempts:         4,
+			failoverStatus:   []int{http.StatusBadGateway},
+			expectedCode:     http.StatusBadGateway,
+			expectedServed:   []string{"primary", "failover0", "failover0", "failover0"},
+			expectedAttempts: 3,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var served []string
+
+			primary := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				served = append(served, "primary")
+				rw.WriteHeader(http.StatusBadGateway)
+			})
+
+			var failover []http.Handler
+			for i, status := range test.failoverStatus {
+				name := "failover" + strconv.Itoa(i)
+				status := status
+				failover = append(failover, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					served = append(served, name)
+					if status == http.StatusOK {
+						// The backend received the request.
+						httptrace.ContextClientTrace(req.Context()).WroteHeaders()
+					}
+					rw.WriteHeader(status)
+				}))
+			}
+
+			retryListener := &countingRetryListener{}
+			retry := NewRetry(test.attempts, primary, retryListener)
+			retry.SetFailover(failover)
+
+			recorder := httptest.NewRecorder()
+			retry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedServed, served)
+			assert.Equal(t, test.expectedAttempts, retryListener.timesCalled)
+		})
+	}
+}
+
+func TestRetryWithFailoverLoop(t *testing.T) {
+	var served []string
+
+	var backendA, backendB *Retry
+
+	backendA = NewRetry(2, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		served = append(served, "a")
+		rw.WriteHeader(http.StatusBadGateway)
+	}), &countingRetryListener{})
+	backendB = NewRetry(2, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		served = append(served, "b")
+		rw.WriteHeader(http.StatusBadGateway)
+	}), &countingRetryListener{})
+
+	backendA.SetFailover([]http.Handler{backendB})
+	backendB.SetFailover([]http.Handler{backendA})
+
+	recorder := httptest.NewRecorder()
+	backendA.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	// The failover backend retries its own servers, but does not fail over again.
+	assert.Equal(t, []string{"a", "b", "b"}, served)
+}
@@ -0,0 +1,75 @@
+package sampling
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+
+	"github.com/containous/traefik/middlewares/requestid"
+)
+
+type contextKey int
+
+const valueKey contextKey = iota
+
+// Handler is a middleware that takes a single sampling decision per request,
+// shared by all the observability outputs (access logs, tracing).
+// The decision is a value in [0, 1): a request is sampled for a given ratio when its value is lower than the ratio,
+// so the requests sampled for a ratio are always a subset of the ones sampled for a higher ratio.
+type Handler struct{}
+
+// NewHandler creates a new Handler.
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if _, ok := GetValue(r.Context()); ok {
+		next(rw, r)
+		return
+	}
+
+	next(rw, r.WithContext(context.WithValue(r.Context(), valueKey, computeValue(r))))
+}
+
+// GetValue returns the sampling value of the request context, if any.
+func GetValue(ctx context.Context) (float64, bool) {
+	value, ok := ctx.Value(valueKey).(float64)
+	return value, ok
+}
+
+// IsSampled returns true if the request is sampled for the given ratio.
+// A ratio lower than or equal to 0, or greater than or equal to 1, samples every request,
+// as does a request which didn't go through the Handler.
+func IsSampled(ctx context.Context, ratio float64) bool {
+	if ratio <= 0 || ratio >= 1 {
+		return true
+	}
+
+	value, ok := GetValue(ctx)
+	if !ok {
+		return true
+	}
+
+	return value < ratio
+}
+
+// computeValue seeds the sampling value with the incoming trace ID, if any,
+// so that the decision is consistent across the services of a trace,
+// or else with the request ID, so that it is consistent for the retried and mirrored requests.
+func computeValue(r *http.Request) float64 {
+	seed := GetTraceContext(r.Header).TraceID
+	if len(seed) == 0 {
+		seed, _ = requestid.GetID(r.Context())
+	}
+	if len(seed) == 0 {
+		return rand.Float64()
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(seed))
+
+	// Keep 53 bits, the precision of a float64 mantissa.
+	return float64(hash.Sum64()>>11) / (1 << 53)
+}
@@ -0,0 +1,148 @@
+package sampling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/middlewares/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		headers map[string]string
+	}{
+		{
+			desc:    "jaeger",
+			headers: map[string]string{"uber-trace-id": "4bf92f3577b34da6:00f067aa0ba902b7:0:1"},
+		},
+		{
+			desc:    "w3c",
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6-00f067aa0ba902b7-01"},
+		},
+		{
+			desc:    "zipkin",
+			headers: map[string]string{"X-B3-TraceId": "4bf92f3577b34da6"},
+		},
+		{
+			desc:    "datadog",
+			headers: map[string]string{"x-datadog-trace-id": "5475468167946178982"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			var value float64
+			NewHandler().ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				var ok bool
+				value, ok = GetValue(r.Context())
+				require.True(t, ok)
+			})
+
+			// The value only depends on the trace ID.
+			assert.Equal(t, computeValue(req), value)
+			assert.InDelta(t, 0.5, value, 0.5)
+		})
+	}
+}
+
+func TestHandler_keepsExistingValue(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req = req.WithContext(context.WithValue(req.Context(), valueKey, 0.42))
+
+	NewHandler().ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+		value, ok := GetValue(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, 0.42, value)
+	})
+}
+
+func TestHandler_requestID(t *testing.T) {
+	requestID, err := requestid.NewHandler("", "")
+	require.NoError(t, err)
+
+	getValue := func() float64 {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.Header.Set(requestid.DefaultHeaderName, "d4c3b2a1")
+
+		var value float64
+		requestID.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+			NewHandler().ServeHTTP(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+				var ok bool
+				value, ok = GetValue(r.Context())
+				require.True(t, ok)
+			})
+		})
+		return value
+	}
+
+	// Without a trace ID, the value only depends on the request ID.
+	assert.Equal(t, getValue(), getValue())
+}
+
+func TestIsSampled(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    *float64
+		ratio    float64
+		expected bool
+	}{
+		{
+			desc:     "no value",
+			ratio:    0.1,
+			expected: true,
+		},
+		{
+			desc:     "no ratio",
+			value:    float64Ptr(0.5),
+			expected: true,
+		},
+		{
+			desc:     "ratio of 1",
+			value:    float64Ptr(0.99),
+			ratio:    1,
+			expected: true,
+		},
+		{
+			desc:     "value under the ratio",
+			value:    float64Ptr(0.05),
+			ratio:    0.1,
+			expected: true,
+		},
+		{
+			desc:  "value over the ratio",
+			value: float64Ptr(0.5),
+			ratio: 0.1,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if test.value != nil {
+				ctx = context.WithValue(ctx, valueKey, *test.value)
+			}
+
+			assert.Equal(t, test.expected, IsSampled(ctx, test.ratio))
+		})
+	}
+}
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
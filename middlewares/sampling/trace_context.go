@@ -0,0 +1,83 @@
+package sampling
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TraceContext is the context of a trace propagated in the headers of a request,
+// by the Jaeger, W3C Trace Context, Zipkin (B3) or Datadog tracers.
+type TraceContext struct {
+	// TraceID and SpanID are hexadecimal, padded to the 16 and 8 bytes of the OpenTelemetry identifiers,
+	// and empty when missing or invalid.
+	TraceID string
+	SpanID  string
+	// Sampled is true if the trace is sampled by the tracer.
+	Sampled bool
+}
+
+// GetTraceContext returns the trace context propagated in the headers, if any.
+func GetTraceContext(header http.Header) TraceContext {
+	// Jaeger: {trace-id}:{span-id}:{parent-span-id}:{flags}
+	if value := header.Get("Uber-Trace-Id"); len(value) > 0 {
+		if parts := strings.Split(value, ":"); len(parts) == 4 {
+			return TraceContext{TraceID: normalizeID(parts[0], 32), SpanID: normalizeID(parts[1], 16), Sampled: hasSampledFlag(parts[3])}
+		}
+	}
+
+	// W3C Trace Context: {version}-{trace-id}-{parent-id}-{flags}
+	if value := header.Get("Traceparent"); len(value) > 0 {
+		if parts := strings.Split(value, "-"); len(parts) == 4 {
+			return TraceContext{TraceID: normalizeID(parts[1], 32), SpanID: normalizeID(parts[2], 16), Sampled: hasSampledFlag(parts[3])}
+		}
+	}
+
+	// Zipkin (B3): the sampling decision can be propagated without the identifiers.
+	traceID, sampled, flags := header.Get("X-B3-Traceid"), header.Get("X-B3-Sampled"), header.Get("X-B3-Flags")
+	if len(traceID) > 0 || len(sampled) > 0 || len(flags) > 0 {
+		return TraceContext{
+			TraceID: normalizeID(traceID, 32),
+			SpanID:  normalizeID(header.Get("X-B3-Spanid"), 16),
+			Sampled: sampled == "1" || sampled == "true" || len(sampled) == 0 && flags == "1",
+		}
+	}
+
+	// Datadog: decimal identifiers
+	priority, err := strconv.Atoi(header.Get("X-Datadog-Sampling-Priority"))
+	return TraceContext{
+		TraceID: decimalID(header.Get("X-Datadog-Trace-Id"), 32),
+		SpanID:  decimalID(header.Get("X-Datadog-Parent-Id"), 16),
+		Sampled: err == nil && priority > 0,
+	}
+}
+
+func hasSampledFlag(flags string) bool {
+	value, err := strconv.ParseUint(flags, 16, 8)
+	return err == nil && value&1 == 1
+}
+
+// normalizeID returns the lower case hexadecimal identifier left padded with zeros to size,
+// or an empty string when it is invalid.
+func normalizeID(id string, size int) string {
+	id = strings.ToLower(id)
+	if len(id) == 0 || len(id) > size {
+		return ""
+	}
+	if _, err := hex.DecodeString(strings.Repeat("0", len(id)%2) + id); err != nil {
+		return ""
+	}
+	if strings.Trim(id, "0") == "" {
+		return ""
+	}
+	return strings.Repeat("0", size-len(id)) + id
+}
+
+func decimalID(id string, size int) string {
+	value, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return normalizeID(strconv.FormatUint(value, 16), size)
+}
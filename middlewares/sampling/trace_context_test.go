@@ -0,0 +1,146 @@
+package sampling
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTraceContext(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		header          http.Header
+		expectedTraceID string
+		expectedSpanID  string
+	}{
+		{
+			desc: "jaeger",
+			header: http.Header{
+				"Uber-Trace-Id": {"6e0c63257de34c92:bf38c1ed5ce2e6a3:0:1"},
+			},
+			expectedTraceID: "00000000000000006e0c63257de34c92",
+			expectedSpanID:  "bf38c1ed5ce2e6a3",
+		},
+		{
+			desc: "W3C trace context",
+			header: http.Header{
+				"Traceparent": {"00-0AF7651916CD43DD8448EB211C80319C-b7ad6b7169203331-01"},
+			},
+			expectedTraceID: "0af7651916cd43dd8448eb211c80319c",
+			expectedSpanID:  "b7ad6b7169203331",
+		},
+		{
+			desc: "zipkin",
+			header: http.Header{
+				"X-B3-Traceid": {"463ac35c9f6413ad"},
+				"X-B3-Spanid":  {"a2fb4a1d1a96d312"},
+			},
+			expectedTraceID: "0000000000000000463ac35c9f6413ad",
+			expectedSpanID:  "a2fb4a1d1a96d312",
+		},
+		{
+			desc: "datadog",
+			header: http.Header{
+				"X-Datadog-Trace-Id":  {"1234"},
+				"X-Datadog-Parent-Id": {"255"},
+			},
+			expectedTraceID: "000000000000000000000000000004d2",
+			expectedSpanID:  "00000000000000ff",
+		},
+		{
+			desc: "invalid identifiers",
+			header: http.Header{
+				"Traceparent": {"00-00000000000000000000000000000000-zzzzzzzzzzzzzzzz-01"},
+			},
+		},
+		{
+			desc:   "no trace",
+			header: http.Header{},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			traceContext := GetTraceContext(test.header)
+			assert.Equal(t, test.expectedTraceID, traceContext.TraceID)
+			assert.Equal(t, test.expectedSpanID, traceContext.SpanID)
+		})
+	}
+}
+
+func TestGetTraceContext_sampled(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		headers  map[string]string
+		expected bool
+	}{
+		{
+			desc:     "jaeger sampled",
+			headers:  map[string]string{"uber-trace-id": "4bf92f3577b34da6:00f067aa0ba902b7:0:1"},
+			expected: true,
+		},
+		{
+			desc:    "jaeger not sampled",
+			headers: map[string]string{"uber-trace-id": "4bf92f3577b34da6:00f067aa0ba902b7:0:0"},
+		},
+		{
+			desc:     "w3c sampled",
+			headers:  map[string]string{"traceparent": "00-4bf92f3577b34da6-00f067aa0ba902b7-01"},
+			expected: true,
+		},
+		{
+			desc:    "w3c not sampled",
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6-00f067aa0ba902b7-00"},
+		},
+		{
+			desc:     "zipkin sampled",
+			headers:  map[string]string{"X-B3-TraceId": "4bf92f3577b34da6", "X-B3-Sampled": "1"},
+			expected: true,
+		},
+		{
+			desc:     "zipkin sampled without identifiers",
+			headers:  map[string]string{"X-B3-Sampled": "true"},
+			expected: true,
+		},
+		{
+			desc:     "zipkin debug",
+			headers:  map[string]string{"X-B3-TraceId": "4bf92f3577b34da6", "X-B3-Flags": "1"},
+			expected: true,
+		},
+		{
+			desc:    "zipkin not sampled",
+			headers: map[string]string{"X-B3-TraceId": "4bf92f3577b34da6", "X-B3-Sampled": "0"},
+		},
+		{
+			desc:     "datadog sampled",
+			headers:  map[string]string{"x-datadog-trace-id": "1234", "x-datadog-sampling-priority": "1"},
+			expected: true,
+		},
+		{
+			desc:    "datadog rejected",
+			headers: map[string]string{"x-datadog-trace-id": "1234", "x-datadog-sampling-priority": "-1"},
+		},
+		{
+			desc:    "no trace",
+			headers: map[string]string{},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			header := http.Header{}
+			for name, value := range test.headers {
+				header.Set(name, value)
+			}
+
+			assert.Equal(t, test.expected, GetTraceContext(header).Sampled)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	defaultSessionFIFOMaxQueue = 10
+	defaultSessionFIFOTimeout  = 10 * time.Second
+)
+
+var (
+	sessionFIFOStores     = make(map[string]*sessionFIFOStore)
+	sessionFIFOStoresLock sync.Mutex
+)
+
+// SessionFIFO forwards the requests of a session one at a time, in their arrival order,
+// so that a request never overtakes an earlier in-flight request of its session.
+// The requests waiting for their turn are rejected with a 429 when the queue of their session is full,
+// and with a 503 when they wait longer than the timeout.
+// The requests without session are forwarded right away.
+// The sessions are shared by the session FIFOs of a backend, so they stay in order after a configuration reload.
+type SessionFIFO struct {
+	next      http.Handler
+	extractor utils.SourceExtractor
+	maxQueue  int
+	timeout   time.Duration
+	store     *sessionFIFOStore
+}
+
+// sessionFIFOStore holds the sessions with an in-flight request of a backend, by key.
+type sessionFIFOStore struct {
+	lock     sync.Mutex
+	sessions map[string]*fifoSession
+}
+
+func getSessionFIFOStore(backendName string) *sessionFIFOStore {
+	sessionFIFOStoresLock.Lock()
+	defer sessionFIFOStoresLock.Unlock()
+
+	s, ok := sessionFIFOStores[backendName]
+	if !ok {
+		s = &sessionFIFOStore{sessions: make(map[string]*fifoSession)}
+		sessionFIFOStores[backendName] = s
+	}
+	return s
+}
+
+// fifoSession holds the requests of a session waiting for the in-flight one, in their arrival order.
+type fifoSession struct {
+	waiting []chan struct{}
+}
+
+// NewSessionFIFO creates a new SessionFIFO for the given backend.
+func NewSessionFIFO(backendName string, next http.Handler, config *types.SessionFIFO) (*SessionFIFO, error) {
+	if config.MaxQueue < 0 {
+		return nil, errors.New("the maximum queue must be positive")
+	}
+
+	if config.Timeout < 0 {
+		return nil, errors.New("the timeout must be positive")
+	}
+
+	extractor, err := utils.NewExtractor(config.ExtractorFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SessionFIFO{
+		next:      next,
+		extractor: extractor,
+		maxQueue:  defaultSessionFIFOMaxQueue,
+		timeout:   defaultSessionFIFOTimeout,
+		store:     getSessionFIFOStore(backendName),
+	}
+
+	if config.MaxQueue > 0 {
+		s.maxQueue = config.MaxQueue
+	}
+
+	if config.Timeout > 0 {
+		s.timeout = time.Duration(config.Timeout)
+	}
+
+	return s, nil
+}
+
+func (s *SessionFIFO) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	key, _, err := s.extractor.Extract(req)
+	if err != nil {
+		log.Errorf("Error extracting the session of the session FIFO: %v", err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if key == "" {
+		s.next.ServeHTTP(rw, req)
+		return
+	}
+
+	turn, ok := s.enqueue(key)
+	if !ok {
+		log.Debugf("Session %s has too many requests waiting", key)
+		http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	if turn != nil && !s.wait(req, key, turn) {
+		log.Debugf("Request of the session %s timed out waiting for its turn", key)
+		http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	defer s.done(key)
+
+	s.next.ServeHTTP(rw, req)
+}
+
+// enqueue returns nil if the request can be forwarded right away,
+// otherwise the channel closed when it is its turn, or false if the queue of the session is full.
+func (s *SessionFIFO) enqueue(key string) (chan struct{}, bool) {
+	s.store.lock.Lock()
+	defer s.store.lock.Unlock()
+
+	session, ok := s.store.sessions[key]
+	if !ok {
+		s.store.sessions[key] = &fifoSession{}
+		return nil, true
+	}
+
+	if len(session.waiting) >= s.maxQueue {
+		return nil, false
+	}
+
+	turn := make(chan struct{})
+	session.waiting = append(session.waiting, turn)
+	return turn, true
+}
+
+// wait waits for the turn of the request, and returns false if the request timed out, or was canceled, before.
+func (s *SessionFIFO) wait(req *http.Request, key string, turn chan struct{}) bool {
+	timer := time.NewTimer(s.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-turn:
+		return true
+	case <-timer.C:
+	case <-req.Context().Done():
+	}
+
+	// The turn may have been given meanwhile: the request must then be forwarded, to give it to the next one.
+	return !s.cancel(key, turn)
+}
+
+// cancel removes a waiting request from the queue of its session.
+// It returns false if the request is not waiting anymore.
+func (s *SessionFIFO) cancel(key string, turn chan struct{}) bool {
+	s.store.lock.Lock()
+	defer s.store.lock.Unlock()
+
+	session := s.store.sessions[key]
+	for i, waiting := range session.waiting {
+		if waiting == turn {
+			session.waiting = append(session.waiting[:i], session.waiting[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// done gives the turn to the next waiting request of the session, if any.
+func (s *SessionFIFO) done(key string) {
+	s.store.lock.Lock()
+	defer s.store.lock.Unlock()
+
+	session := s.store.sessions[key]
+	if len(session.waiting) == 0 {
+		delete(s.store.sessions, key)
+		return
+	}
+
+	close(session.waiting[0])
+	session.waiting = session.waiting[1:]
+}
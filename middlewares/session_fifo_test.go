@@ -0,0 +1,252 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitSessionQueue waits for the number of requests waiting in the queue of the session, behind its in-flight request.
+func waitSessionQueue(t *testing.T, fifo *SessionFIFO, key string, expected int) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		fifo.store.lock.Lock()
+		waiting := -1
+		if session, ok := fifo.store.sessions[key]; ok {
+			waiting = len(session.waiting)
+		}
+		fifo.store.lock.Unlock()
+
+		if waiting == expected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %d requests waiting for the session %s", expected, key)
+}
+
+func TestSessionFIFOOrder(t *testing.T) {
+	release := make(chan struct{})
+
+	var lock sync.Mutex
+	var served []string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/first" {
+			<-release
+		}
+
+		lock.Lock()
+		served = append(served, req.URL.Path)
+		lock.Unlock()
+	})
+
+	fifo, err := NewSessionFIFO("order", next, &types.SessionFIFO{ExtractorFunc: "request.header.X-Session"})
+	require.NoError(t, err)
+
+	serve := func(path, session string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		req.Header.Set("X-Session", session)
+
+		recorder := httptest.NewRecorder()
+		fifo.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	var wg sync.WaitGroup
+	for i, path := range []string{"/first", "/second", "/third", "/fourth"} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, serve(path, "session1").Code)
+		}(path)
+
+		waitSessionQueue(t, fifo, "session1", i)
+	}
+
+	// The requests of another session are not queued.
+	assert.Equal(t, http.StatusOK, serve("/other", "session2").Code)
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{"/other", "/first", "/second", "/third", "/fourth"}, served)
+	assert.Empty(t, fifo.store.sessions)
+}
+
+func TestSessionFIFORejected(t *testing.T) {
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-release
+	})
+
+	fifo, err := NewSessionFIFO("rejected", next, &types.SessionFIFO{
+		ExtractorFunc: "request.header.X-Session",
+		MaxQueue:      1,
+		Timeout:       parse.Duration(100 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.Header.Set("X-Session", "session1")
+
+		recorder := httptest.NewRecorder()
+		fifo.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Equal(t, http.StatusOK, serve().Code)
+	}()
+	waitSessionQueue(t, fifo, "session1", 0)
+
+	timedOut := make(chan int)
+	go func() {
+		timedOut <- serve().Code
+	}()
+	waitSessionQueue(t, fifo, "session1", 1)
+
+	// The queue is full.
+	assert.Equal(t, http.StatusTooManyRequests, serve().Code)
+
+	// The waiting request times out.
+	assert.Equal(t, http.StatusServiceUnavailable, <-timedOut)
+	waitSessionQueue(t, fifo, "session1", 0)
+
+	close(release)
+	<-done
+
+	assert.Empty(t, fifo.store.sessions)
+}
+
+func TestSessionFIFOWithoutSession(t *testing.T) {
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/blocked" {
+			<-release
+		}
+	})
+
+	fifo, err := NewSessionFIFO("without-session", next, &types.SessionFIFO{
+		ExtractorFunc: "request.header.X-Session",
+		MaxQueue:      1,
+	})
+	require.NoError(t, err)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		fifo.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil))
+		return recorder
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Equal(t, http.StatusOK, serve("/blocked").Code)
+	}()
+
+	// The requests without session are neither queued nor rejected.
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve("/other").Code)
+	}
+
+	close(release)
+	<-done
+
+	assert.Empty(t, fifo.store.sessions)
+}
+
+func TestSessionFIFOReload(t *testing.T) {
+	release := make(chan struct{})
+
+	var lock sync.Mutex
+	var served []string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/first" {
+			<-release
+		}
+
+		lock.Lock()
+		served = append(served, req.URL.Path)
+		lock.Unlock()
+	})
+
+	config := &types.SessionFIFO{ExtractorFunc: "request.header.X-Session"}
+
+	fifo, err := NewSessionFIFO("reload", next, config)
+	require.NoError(t, err)
+
+	// The session FIFO of the reloaded configuration.
+	reloaded, err := NewSessionFIFO("reload", next, config)
+	require.NoError(t, err)
+
+	serve := func(fifo *SessionFIFO, path string) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		req.Header.Set("X-Session", "session1")
+
+		recorder := httptest.NewRecorder()
+		fifo.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		serve(fifo, "/first")
+	}()
+	waitSessionQueue(t, fifo, "session1", 0)
+
+	go func() {
+		defer wg.Done()
+		serve(reloaded, "/second")
+	}()
+	waitSessionQueue(t, reloaded, "session1", 1)
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{"/first", "/second"}, served)
+	assert.Empty(t, reloaded.store.sessions)
+}
+
+func TestNewSessionFIFOFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.SessionFIFO
+	}{
+		{
+			desc:   "invalid extractor",
+			config: &types.SessionFIFO{ExtractorFunc: "foo"},
+		},
+		{
+			desc:   "negative max queue",
+			config: &types.SessionFIFO{ExtractorFunc: "client.ip", MaxQueue: -1},
+		},
+		{
+			desc:   "negative timeout",
+			config: &types.SessionFIFO{ExtractorFunc: "client.ip", Timeout: parse.Duration(-time.Second)},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewSessionFIFO("backend", http.NotFoundHandler(), test.config)
+			assert.Error(t, err)
+		})
+	}
+}
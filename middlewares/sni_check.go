@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/middlewares/tracing"
+	"github.com/containous/traefik/types"
+)
+
+// SNIChecker rejects the TLS requests whose server name (SNI) doesn't match their host, or the allowed server names,
+// so that a client can't present the server name of a domain while sending the host of another one (domain fronting).
+// The requests without TLS are not checked.
+type SNIChecker struct {
+	backendName        string
+	allowedServerNames []string
+	statusCode         int
+}
+
+// NewSNIChecker creates a new SNIChecker.
+func NewSNIChecker(backendName string, config *types.SNICheck) (*SNIChecker, error) {
+	checker := &SNIChecker{
+		backendName: backendName,
+		statusCode:  http.StatusMisdirectedRequest,
+	}
+
+	switch config.StatusCode {
+	case 0:
+	case http.StatusMisdirectedRequest, http.StatusForbidden:
+		checker.statusCode = config.StatusCode
+	default:
+		return nil, fmt.Errorf("invalid status code %d, expected %d or %d", config.StatusCode, http.StatusMisdirectedRequest, http.StatusForbidden)
+	}
+
+	for _, serverName := range config.AllowedServerNames {
+		serverName = types.CanonicalDomain(serverName)
+		if len(serverName) == 0 || strings.Contains(strings.TrimPrefix(serverName, "*."), "*") {
+			return nil, fmt.Errorf("invalid allowed server name %q", serverName)
+		}
+		checker.allowedServerNames = append(checker.allowedServerNames, serverName)
+	}
+
+	return checker, nil
+}
+
+func (s *SNIChecker) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.TLS == nil {
+		next(rw, r)
+		return
+	}
+
+	serverName := types.CanonicalDomain(r.TLS.ServerName)
+
+	if !s.isAllowed(r, serverName) {
+		tracing.SetErrorAndDebugLog(r, "Backend %s: rejecting request for %s from %s: connection was established for %q", s.backendName, r.Host, r.RemoteAddr, r.TLS.ServerName)
+		http.Error(rw, http.StatusText(s.statusCode), s.statusCode)
+		return
+	}
+
+	next(rw, r)
+}
+
+func (s *SNIChecker) isAllowed(r *http.Request, serverName string) bool {
+	if len(serverName) == 0 {
+		return false
+	}
+
+	if len(s.allowedServerNames) == 0 {
+		host := GetCanonizedHost(r.Context())
+		if len(host) == 0 {
+			host = types.CanonicalDomain(parseHost(r.Host))
+		}
+		return serverName == host
+	}
+
+	for _, allowed := range s.allowedServerNames {
+		if matchServerName(allowed, serverName) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchServerName matches a server name against a name, which may be a wildcard on its first label (e.g. *.example.com).
+func matchServerName(name, serverName string) bool {
+	if !strings.HasPrefix(name, "*.") {
+		return name == serverName
+	}
+
+	labels := strings.SplitN(serverName, ".", 2)
+	return len(labels) == 2 && len(labels[0]) > 0 && "*."+labels[1] == name
+}
@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSNIChecker(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		config       *types.SNICheck
+		host         string
+		serverName   string
+		noTLS        bool
+		expectedCode int
+	}{
+		{
+			desc:         "matching host",
+			config:       &types.SNICheck{},
+			host:         "foo.localhost",
+			serverName:   "foo.localhost",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "matching host with port and different case",
+			config:       &types.SNICheck{},
+			host:         "FOO.localhost:443",
+			serverName:   "foo.localhost",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "mismatching host",
+			config:       &types.SNICheck{},
+			host:         "bar.localhost",
+			serverName:   "foo.localhost",
+			expectedCode: http.StatusMisdirectedRequest,
+		},
+		{
+			desc:         "mismatching host with forbidden status code",
+			config:       &types.SNICheck{StatusCode: http.StatusForbidden},
+			host:         "bar.localhost",
+			serverName:   "foo.localhost",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			desc:         "connection without server name",
+			config:       &types.SNICheck{},
+			host:         "bar.localhost",
+			expectedCode: http.StatusMisdirectedRequest,
+		},
+		{
+			desc:         "request without TLS",
+			config:       &types.SNICheck{},
+			host:         "bar.localhost",
+			noTLS:        true,
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "allowed server name",
+			config:       &types.SNICheck{AllowedServerNames: []string{"foo.localhost", "*.example.com"}},
+			host:         "bar.localhost",
+			serverName:   "www.example.com",
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "server name not allowed",
+			config:       &types.SNICheck{AllowedServerNames: []string{"foo.localhost", "*.example.com"}},
+			host:         "foo.localhost",
+			serverName:   "foo.www.example.com",
+			expectedCode: http.StatusMisdirectedRequest,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			checker, err := NewSNIChecker("backend1", test.config)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "https://"+test.host, nil)
+			req.TLS = &tls.ConnectionState{ServerName: test.serverName}
+			if test.noTLS {
+				req.TLS = nil
+			}
+
+			recorder := httptest.NewRecorder()
+			checker.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			})
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestSNICheckerCanonizedHost(t *testing.T) {
+	checker, err := NewSNIChecker("backend1", &types.SNICheck{})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "https://foo.localhost.", nil)
+	req.TLS = &tls.ConnectionState{ServerName: "foo.localhost"}
+
+	recorder := httptest.NewRecorder()
+	rh := &RequestHost{StripTrailingDot: true}
+	rh.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+		checker.ServeHTTP(rw, r, func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+	})
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestNewSNICheckerFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.SNICheck
+	}{
+		{
+			desc:   "invalid status code",
+			config: &types.SNICheck{StatusCode: http.StatusBadRequest},
+		},
+		{
+			desc:   "empty allowed server name",
+			config: &types.SNICheck{AllowedServerNames: []string{""}},
+		},
+		{
+			desc:   "invalid wildcard",
+			config: &types.SNICheck{AllowedServerNames: []string{"foo.*.example.com"}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewSNIChecker("backend1", test.config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package middlewares
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+const stickyCookieSignatureSeparator = "|"
+
+// StickyCookie sets the attributes of the sticky session cookie set by the load balancer,
+// and signs its server URL with an HMAC when a secret is configured.
+// The signature of the request cookie is verified, and removed, before the load balancer reads the cookie:
+// a cookie with an invalid signature is ignored, so that the client gets a new server.
+type StickyCookie struct {
+	next     http.Handler
+	name     string
+	secure   bool
+	httpOnly bool
+	sameSite string
+	domain   string
+	maxAge   int
+	secret   []byte
+}
+
+// NewStickyCookie creates a new StickyCookie for the cookie of the name.
+func NewStickyCookie(next http.Handler, name string, config *types.Stickiness) (*StickyCookie, error) {
+	if config.MaxAge < 0 {
+		return nil, fmt.Errorf("invalid max age %d, must be positive", config.MaxAge)
+	}
+
+	// The same site attribute is written as is, http.SameSite has no none mode before Go 1.13.
+	var sameSite string
+	switch strings.ToLower(config.SameSite) {
+	case "":
+	case "none":
+		if !config.Secure {
+			return nil, errors.New("the none same site mode requires a secure cookie")
+		}
+		sameSite = "None"
+	case "lax":
+		sameSite = "Lax"
+	case "strict":
+		sameSite = "Strict"
+	default:
+		return nil, fmt.Errorf("invalid same site mode %q, must be none, lax or strict", config.SameSite)
+	}
+
+	s := &StickyCookie{
+		next:     next,
+		name:     name,
+		secure:   config.Secure,
+		httpOnly: config.HTTPOnly,
+		sameSite: sameSite,
+		domain:   config.Domain,
+		maxAge:   config.MaxAge,
+	}
+	if len(config.Secret) > 0 {
+		s.secret = []byte(config.Secret)
+	}
+
+	return s, nil
+}
+
+func (s *StickyCookie) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if s.secret != nil {
+		req = s.verifyRequestCookie(req)
+	}
+
+	s.next.ServeHTTP(&stickyCookieResponseWriter{ResponseWriter: rw, stickyCookie: s}, req)
+}
+
+// verifyRequestCookie returns a copy of the request, with the server URL of the signed cookie,
+// or without the cookie if its signature is invalid.
+// Only the name=value pair of the cookie is rewritten in the Cookie headers,
+// the other cookies are forwarded as they were received.
+func (s *StickyCookie) verifyRequestCookie(req *http.Request) *http.Request {
+	var found bool
+	var lines []string
+	for _, line := range req.Header["Cookie"] {
+		rewritten, ok := s.rewriteCookieLine(line)
+		found = found || ok
+		if strings.TrimSpace(rewritten) != "" {
+			lines = append(lines, rewritten)
+		}
+	}
+
+	if !found {
+		return req
+	}
+
+	verified := req.WithContext(req.Context())
+	verified.Header = make(http.Header)
+	utils.CopyHeaders(verified.Header, req.Header)
+	verified.Header.Del("Cookie")
+	for _, line := range lines {
+		verified.Header.Add("Cookie", line)
+	}
+
+	return verified
+}
+
+// rewriteCookieLine replaces the signed value of the cookie by its server URL in a Cookie header value,
+// or removes the cookie if its signature is invalid, and reports whether the cookie was found.
+// The pairs are split the way net/http reads them.
+func (s *StickyCookie) rewriteCookieLine(line string) (string, bool) {
+	pairs := strings.Split(line, ";")
+
+	var found, trimLeft bool
+	kept := pairs[:0]
+	for i, pair := range pairs {
+		trimmed := strings.TrimSpace(pair)
+		name, value := trimmed, ""
+		if index := strings.Index(trimmed, "="); index >= 0 {
+			name, value = trimmed[:index], trimmed[index+1:]
+		}
+
+		if name != s.name {
+			kept = append(kept, pair)
+			continue
+		}
+
+		found = true
+		if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+
+		serverURL, ok := s.verify(value)
+		if !ok {
+			trimLeft = trimLeft || i == 0
+			continue
+		}
+
+		prefix := pair[:len(pair)-len(strings.TrimLeft(pair, " \t"))]
+		kept = append(kept, prefix+name+"="+serverURL)
+	}
+
+	if !found {
+		return line, false
+	}
+
+	rewritten := strings.Join(kept, ";")
+	if trimLeft {
+		rewritten = strings.TrimLeft(rewritten, " \t")
+	}
+	return rewritten, true
+}
+
+func (s *StickyCookie) sign(serverURL string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(serverURL))
+	return serverURL + stickyCookieSignatureSeparator + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *StickyCookie) verify(value string) (string, bool) {
+	index := strings.LastIndex(value, stickyCookieSignatureSeparator)
+	if index < 0 {
+		return "", false
+	}
+
+	serverURL := value[:index]
+	return serverURL, hmac.Equal([]byte(s.sign(serverURL)), []byte(value))
+}
+
+// rewriteSetCookie rewrites the sticky cookie set by the load balancer, if any.
+func (s *StickyCookie) rewriteSetCookie(header http.Header) {
+	setCookies := header["Set-Cookie"]
+	for i, setCookie := range setCookies {
+		if !strings.HasPrefix(setCookie, s.name+"=") {
+			continue
+		}
+
+		cookies := (&http.Response{Header: http.Header{"Set-Cookie": {setCookie}}}).Cookies()
+		if len(cookies) == 0 {
+			continue
+		}
+
+		cookie := cookies[0]
+		if s.secret != nil {
+			cookie.Value = s.sign(cookie.Value)
+		}
+		cookie.Secure = s.secure
+		cookie.HttpOnly = s.httpOnly
+		cookie.SameSite = 0
+		cookie.Domain = s.domain
+		cookie.MaxAge = s.maxAge
+
+		setCookies[i] = cookie.String()
+		if s.sameSite != "" {
+			setCookies[i] += "; SameSite=" + s.sameSite
+		}
+	}
+}
+
+type stickyCookieResponseWriter struct {
+	http.ResponseWriter
+	stickyCookie *StickyCookie
+	wroteHeader  bool
+}
+
+func (w *stickyCookieResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.stickyCookie.rewriteSetCookie(w.Header())
+		w.wroteHeader = code >= http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *stickyCookieResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *stickyCookieResponseWriter) Flush() {
+	if fw, ok := w.ResponseWriter.(http.Flusher); ok {
+		fw.Flush()
+	}
+}
+
+// CloseNotify returns a channel that receives at most a single value (true) when the client connection has gone away.
+func (w *stickyCookieResponseWriter) CloseNotify() <-chan bool {
+	if cn, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return make(<-chan bool)
+}
+
+// Hijack hijacks the connection of the underlying ResponseWriter.
+func (w *stickyCookieResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+}
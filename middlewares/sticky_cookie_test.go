@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+func TestNewStickyCookieInvalid(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.Stickiness
+	}{
+		{
+			desc:   "negative max age",
+			config: &types.Stickiness{MaxAge: -1},
+		},
+		{
+			desc:   "invalid same site",
+			config: &types.Stickiness{SameSite: "foo"},
+		},
+		{
+			desc:   "none same site without secure",
+			config: &types.Stickiness{SameSite: "none"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewStickyCookie(http.NotFoundHandler(), "sticky", test.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStickyCookie(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.Host))
+	})
+
+	lb, err := roundrobin.New(next, roundrobin.EnableStickySession(roundrobin.NewStickySession("sticky")))
+	require.NoError(t, err)
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://server1")))
+	require.NoError(t, lb.UpsertServer(testhelpers.MustParseURL("http://server2")))
+
+	stickyCookie, err := NewStickyCookie(lb, "sticky", &types.Stickiness{
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: "None",
+		Domain:   "example.com",
+		MaxAge:   3600,
+		Secret:   "secret",
+	})
+	require.NoError(t, err)
+
+	serve := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+		req.AddCookie(&http.Cookie{Name: "other", Value: "foo"})
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		stickyCookie.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve(nil)
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	cookie := cookies[0]
+	assert.Equal(t, "sticky", cookie.Name)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Contains(t, recorder.Header().Get("Set-Cookie"), "; SameSite=None")
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.Equal(t, 3600, cookie.MaxAge)
+
+	server := recorder.Body.String()
+	assert.NotEqual(t, "http://"+server, cookie.Value, "the server URL should be signed")
+
+	// The signed cookie sticks to the server.
+	for i := 0; i < 3; i++ {
+		recorder = serve(&http.Cookie{Name: "sticky", Value: cookie.Value})
+		assert.Equal(t, server, recorder.Body.String())
+		assert.Empty(t, recorder.Result().Cookies())
+	}
+
+	// A forged cookie is ignored.
+	forged := "http://server1"
+	if server == "server1" {
+		forged = "http://server2"
+	}
+
+	recorder = serve(&http.Cookie{Name: "sticky", Value: forged})
+	assert.Len(t, recorder.Result().Cookies(), 1)
+
+	recorder = serve(&http.Cookie{Name: "sticky", Value: forged + "|forged"})
+	assert.Len(t, recorder.Result().Cookies(), 1)
+}
+
+func TestStickyCookieKeepsOtherCookies(t *testing.T) {
+	var received []string
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = req.Header["Cookie"]
+	})
+
+	stickyCookie, err := NewStickyCookie(next, "sticky", &types.Stickiness{Secret: "secret"})
+	require.NoError(t, err)
+
+	signed := stickyCookie.sign("http://server1")
+
+	testCases := []struct {
+		desc     string
+		cookies  []string
+		expected []string
+	}{
+		{
+			desc:     "valid signature",
+			cookies:  []string{`json={"a": "b, c"}; sticky=` + signed + `; other="d e"`},
+			expected: []string{`json={"a": "b, c"}; sticky=http://server1; other="d e"`},
+		},
+		{
+			desc:     "quoted valid signature",
+			cookies:  []string{`json={"a": "b, c"};sticky="` + signed + `"`},
+			expected: []string{`json={"a": "b, c"};sticky=http://server1`},
+		},
+		{
+			desc:     "invalid signature",
+			cookies:  []string{`json={"a": "b, c"}; sticky=http://server1|forged; other="d e"`},
+			expected: []string{`json={"a": "b, c"}; other="d e"`},
+		},
+		{
+			desc:     "invalid signature first",
+			cookies:  []string{`sticky=http://server1; json={"a": "b, c"}`, `other="d e"`},
+			expected: []string{`json={"a": "b, c"}`, `other="d e"`},
+		},
+		{
+			desc:     "invalid signature alone",
+			cookies:  []string{`sticky=http://server1`, `json={"a": "b, c"}`},
+			expected: []string{`json={"a": "b, c"}`},
+		},
+		{
+			desc:     "no sticky cookie",
+			cookies:  []string{`json={"a": "b, c"}; other="d e"`},
+			expected: []string{`json={"a": "b, c"}; other="d e"`},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			req.Header["Cookie"] = test.cookies
+
+			received = nil
+			stickyCookie.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, test.expected, received)
+		})
+	}
+}
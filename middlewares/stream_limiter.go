@@ -0,0 +1,75 @@
+package middlewares
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/containous/traefik/middlewares/tracing"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// StreamLimiter is a middleware that limits the number of concurrent HTTP/2 streams
+// a single client connection can have in flight.
+// It is enforced on top of the connection level MaxConcurrentStreams setting of the HTTP/2 transport.
+type StreamLimiter struct {
+	maxStreams      int64
+	rejectedCounter gokitmetrics.Counter
+
+	lock    sync.Mutex
+	streams map[string]int64
+}
+
+// NewStreamLimiter creates a new StreamLimiter.
+func NewStreamLimiter(maxStreams int64, rejectedCounter gokitmetrics.Counter) *StreamLimiter {
+	return &StreamLimiter{
+		maxStreams:      maxStreams,
+		rejectedCounter: rejectedCounter,
+		streams:         make(map[string]int64),
+	}
+}
+
+func (s *StreamLimiter) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	// Streams only exist on HTTP/2 connections, HTTP/1.x requests are always serialized.
+	if r.ProtoMajor != 2 {
+		next(rw, r)
+		return
+	}
+
+	// The remote address (ip:port) identifies the client connection the stream belongs to.
+	connKey := r.RemoteAddr
+
+	if !s.acquire(connKey) {
+		if s.rejectedCounter != nil {
+			s.rejectedCounter.Add(1)
+		}
+
+		tracing.SetErrorAndDebugLog(r, "rejecting stream from %s: more than %d concurrent streams", connKey, s.maxStreams)
+		http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	defer s.release(connKey)
+
+	next(rw, r)
+}
+
+func (s *StreamLimiter) acquire(connKey string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.streams[connKey] >= s.maxStreams {
+		return false
+	}
+
+	s.streams[connKey]++
+	return true
+}
+
+func (s *StreamLimiter) release(connKey string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.streams[connKey]--
+	if s.streams[connKey] <= 0 {
+		delete(s.streams, connKey)
+	}
+}
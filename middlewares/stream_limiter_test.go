@@ -0,0 +1,85 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/testhelpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		protoMajor       int
+		inFlight         int64
+		expectedCode     int
+		expectedRejected float64
+	}{
+		{
+			desc:         "HTTP/1.1 request is never limited",
+			protoMajor:   1,
+			inFlight:     2,
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "HTTP/2 stream under the limit",
+			protoMajor:   2,
+			inFlight:     1,
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:             "HTTP/2 stream over the limit",
+			protoMajor:       2,
+			inFlight:         2,
+			expectedCode:     http.StatusTooManyRequests,
+			expectedRejected: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			counter := &testhelpers.CollectingCounter{}
+			limiter := NewStreamLimiter(2, counter)
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			req.ProtoMajor = test.protoMajor
+			req.RemoteAddr = "10.0.0.1:1234"
+
+			limiter.streams[req.RemoteAddr] = test.inFlight
+
+			recorder := httptest.NewRecorder()
+			limiter.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			})
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedRejected, counter.CounterValue)
+			assert.Equal(t, test.inFlight, limiter.streams[req.RemoteAddr])
+		})
+	}
+}
+
+func TestStreamLimiter_releasesConnection(t *testing.T) {
+	limiter := NewStreamLimiter(1, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	req.ProtoMajor = 2
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	for i := 0; i < 3; i++ {
+		recorder := httptest.NewRecorder()
+		limiter.ServeHTTP(recorder, req, func(rw http.ResponseWriter, r *http.Request) {
+			assert.EqualValues(t, 1, limiter.streams[r.RemoteAddr])
+			rw.WriteHeader(http.StatusOK)
+		})
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	assert.Empty(t, limiter.streams)
+}
@@ -0,0 +1,273 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// BaggageHeader is the header of the W3C baggage.
+const BaggageHeader = "Baggage"
+
+const (
+	// BaggageMaxMembers is the maximum number of members of the propagated baggage.
+	BaggageMaxMembers = 64
+	// BaggageMaxLength is the maximum length of the propagated baggage header.
+	BaggageMaxLength = 8192
+)
+
+type baggageKey struct{}
+
+// Baggage holds the members of the W3C baggage of a request.
+// It is kept in the request context, so that the middlewares can add members to it,
+// and it is propagated to the backends by the forwarder middleware.
+type Baggage struct {
+	lock    sync.RWMutex
+	members []baggageMember
+}
+
+type baggageMember struct {
+	key   string
+	value string
+	// properties are the raw properties of the member, after its first ';'.
+	properties string
+}
+
+// NewBaggage creates a new empty Baggage.
+func NewBaggage() *Baggage {
+	return &Baggage{}
+}
+
+// WithBaggage returns a copy of the context with the baggage.
+func WithBaggage(ctx context.Context, baggage *Baggage) context.Context {
+	return context.WithValue(ctx, baggageKey{}, baggage)
+}
+
+// GetBaggage returns the baggage of the context, or nil if there is none.
+func GetBaggage(ctx context.Context) *Baggage {
+	baggage, _ := ctx.Value(baggageKey{}).(*Baggage)
+	return baggage
+}
+
+// Get returns the value of the member of the baggage.
+func (b *Baggage) Get(key string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for _, member := range b.members {
+		if member.key == key {
+			return member.value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the member of the baggage, replacing its previous value and properties.
+func (b *Baggage) Set(key, value string) {
+	if b == nil || !isBaggageKey(key) {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for i, member := range b.members {
+		if member.key == key {
+			b.members[i] = baggageMember{key: key, value: value}
+			return
+		}
+	}
+	b.members = append(b.members, baggageMember{key: key, value: value})
+}
+
+// Members returns the values of the members of the baggage, by key.
+func (b *Baggage) Members() map[string]string {
+	if b == nil {
+		return nil
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	members := make(map[string]string, len(b.members))
+	for _, member := range b.members {
+		members[member.key] = member.value
+	}
+	return members
+}
+
+// Merge adds the members of the baggage headers which are not in the baggage yet, so that the members set meanwhile are kept.
+// The invalid members are ignored, and the members are added up to the limits of the propagated baggage.
+func (b *Baggage) Merge(headers []string) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	length := b.length()
+	for _, header := range headers {
+		for _, raw := range strings.Split(header, ",") {
+			if len(b.members) >= BaggageMaxMembers {
+				return
+			}
+
+			member, ok := parseBaggageMember(raw)
+			if !ok || b.has(member.key) {
+				continue
+			}
+
+			memberLength := len(member.String())
+			if len(b.members) > 0 {
+				memberLength++
+			}
+			if length+memberLength > BaggageMaxLength {
+				continue
+			}
+
+			length += memberLength
+			b.members = append(b.members, member)
+		}
+	}
+}
+
+// String returns the baggage header, without the members past the limits of the propagated baggage.
+func (b *Baggage) String() string {
+	if b == nil {
+		return ""
+	}
+
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	var header strings.Builder
+	for i, member := range b.members {
+		if i >= BaggageMaxMembers {
+			break
+		}
+
+		value := member.String()
+		if header.Len() > 0 {
+			if header.Len()+1+len(value) > BaggageMaxLength {
+				continue
+			}
+			header.WriteString(",")
+		} else if len(value) > BaggageMaxLength {
+			continue
+		}
+		header.WriteString(value)
+	}
+	return header.String()
+}
+
+// has returns true if the baggage has the member, it must be called with the lock held.
+func (b *Baggage) has(key string) bool {
+	for _, member := range b.members {
+		if member.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// length returns the length of the baggage header, it must be called with the lock held.
+func (b *Baggage) length() int {
+	length := 0
+	for i, member := range b.members {
+		if i > 0 {
+			length++
+		}
+		length += len(member.String())
+	}
+	return length
+}
+
+// String returns the member as in the baggage header.
+func (m baggageMember) String() string {
+	member := m.key + "=" + escapeBaggageValue(m.value)
+	if len(m.properties) > 0 {
+		member += ";" + m.properties
+	}
+	return member
+}
+
+// parseBaggageMember parses a member of the baggage header: key=value;properties.
+func parseBaggageMember(raw string) (baggageMember, bool) {
+	raw = strings.TrimSpace(raw)
+
+	var properties string
+	if i := strings.Index(raw, ";"); i >= 0 {
+		raw, properties = raw[:i], strings.TrimSpace(raw[i+1:])
+	}
+
+	keyValue := strings.SplitN(raw, "=", 2)
+	if len(keyValue) != 2 {
+		return baggageMember{}, false
+	}
+
+	key := strings.TrimSpace(keyValue[0])
+	if !isBaggageKey(key) {
+		return baggageMember{}, false
+	}
+
+	value, err := url.PathUnescape(strings.TrimSpace(keyValue[1]))
+	if err != nil {
+		return baggageMember{}, false
+	}
+
+	return baggageMember{key: key, value: value, properties: properties}, true
+}
+
+// isBaggageKey returns true if the key is a token, as defined by RFC 7230.
+func isBaggageKey(key string) bool {
+	if len(key) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeBaggageValue percent-encodes the characters of the value which are not baggage octets, and the percent sign.
+func escapeBaggageValue(value string) string {
+	const hex = "0123456789ABCDEF"
+
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`",;=\%`, c) >= 0 {
+			escaped.WriteByte('%')
+			escaped.WriteByte(hex[c>>4])
+			escaped.WriteByte(hex[c&0xf])
+			continue
+		}
+		escaped.WriteByte(c)
+	}
+	return escaped.String()
+}
+
+// propagateBaggage sets the baggage header of the request from the baggage of its context, if any.
+func propagateBaggage(r *http.Request) {
+	baggage := GetBaggage(r.Context())
+	if baggage == nil {
+		return
+	}
+
+	if header := baggage.String(); len(header) > 0 {
+		r.Header.Set(BaggageHeader, header)
+	} else {
+		r.Header.Del(BaggageHeader)
+	}
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaggageMerge(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		members  map[string]string
+		headers  []string
+		expected map[string]string
+		header   string
+	}{
+		{
+			desc:     "members and properties",
+			headers:  []string{"tenant=acme, user = alice;ttl=10", "region=eu%20west"},
+			expected: map[string]string{"tenant": "acme", "user": "alice", "region": "eu west"},
+			header:   "tenant=acme,user=alice;ttl=10,region=eu%20west",
+		},
+		{
+			desc:     "invalid members are ignored",
+			headers:  []string{"tenant=acme,novalue,bad key=1,=empty,escape=%zz"},
+			expected: map[string]string{"tenant": "acme"},
+			header:   "tenant=acme",
+		},
+		{
+			desc:     "members set before are not replaced",
+			members:  map[string]string{"tenant": "internal"},
+			headers:  []string{"tenant=acme,user=alice"},
+			expected: map[string]string{"tenant": "internal", "user": "alice"},
+			header:   "tenant=internal,user=alice",
+		},
+		{
+			desc:     "first member of a duplicate key is kept",
+			headers:  []string{"tenant=acme", "tenant=other"},
+			expected: map[string]string{"tenant": "acme"},
+			header:   "tenant=acme",
+		},
+		{
+			desc:     "values are escaped",
+			members:  map[string]string{"query": `a=b,c;"d"%`},
+			expected: map[string]string{"query": `a=b,c;"d"%`},
+			header:   "query=a%3Db%2Cc%3B%22d%22%25",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			baggage := NewBaggage()
+			for key, value := range test.members {
+				baggage.Set(key, value)
+			}
+			baggage.Merge(test.headers)
+
+			assert.Equal(t, test.expected, baggage.Members())
+			assert.Equal(t, test.header, baggage.String())
+
+			parsed := NewBaggage()
+			parsed.Merge([]string{baggage.String()})
+			assert.Equal(t, test.expected, parsed.Members())
+		})
+	}
+}
+
+func TestBaggageLimits(t *testing.T) {
+	var members []string
+	for i := 0; i < BaggageMaxMembers+10; i++ {
+		members = append(members, fmt.Sprintf("key%d=value", i))
+	}
+
+	baggage := NewBaggage()
+	baggage.Merge([]string{strings.Join(members, ",")})
+	assert.Len(t, baggage.Members(), BaggageMaxMembers)
+
+	long := strings.Repeat("v", BaggageMaxLength/2)
+	baggage = NewBaggage()
+	baggage.Merge([]string{"first=" + long, "second=" + long, "third=value"})
+	assert.Equal(t, map[string]string{"first": long, "third": "value"}, baggage.Members())
+	assert.True(t, len(baggage.String()) <= BaggageMaxLength)
+
+	// The members set by the middlewares past the limits are not propagated.
+	baggage.Set("fourth", long)
+	assert.Equal(t, "first="+long+",third=value", baggage.String())
+}
+
+func TestBaggageNil(t *testing.T) {
+	var baggage *Baggage
+
+	baggage.Set("tenant", "acme")
+	baggage.Merge([]string{"tenant=acme"})
+
+	_, ok := baggage.Get("tenant")
+	assert.False(t, ok)
+	assert.Nil(t, baggage.Members())
+	assert.Empty(t, baggage.String())
+}
+
+func TestEntryPointMiddlewareBaggage(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		disableBaggage bool
+		expected       string
+	}{
+		{
+			desc:     "propagated",
+			expected: "tenant=acme,user=alice",
+		},
+		{
+			desc:           "disabled",
+			disableBaggage: true,
+			expected:       "tenant=acme,invalid",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			e := &entryPointMiddleware{
+				entryPoint: "test",
+				Tracing: &Tracing{
+					DisableBaggage: test.disableBaggage,
+					tracer:         &MockTracer{Span: &MockSpan{Tags: make(map[string]interface{})}},
+				},
+			}
+
+			next := func(_ http.ResponseWriter, r *http.Request) {
+				GetBaggage(r.Context()).Set("user", "alice")
+
+				propagateBaggage(r)
+				assert.Equal(t, test.expected, r.Header.Get(BaggageHeader))
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://www.test.com", nil)
+			req.Header.Set(BaggageHeader, "tenant=acme,invalid")
+
+			e.ServeHTTP(httptest.NewRecorder(), req, next)
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares/requestid"
+	"github.com/containous/traefik/middlewares/sampling"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/urfave/negroni"
@@ -30,7 +32,35 @@ func (e *entryPointMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 	LogRequest(span, r)
 	ext.SpanKindRPCServer.Set(span)
 
-	r = r.WithContext(opentracing.ContextWithSpan(r.Context(), span))
+	if id, ok := requestid.GetID(r.Context()); ok {
+		span.SetTag("request.id", id)
+	}
+
+	for header, tag := range e.HeaderTags {
+		if value := r.Header.Get(header); len(value) > 0 {
+			span.SetTag(tag, truncateString(value, HeaderTagMaxLength))
+		}
+	}
+
+	if e.SamplingRatio > 0 {
+		if sampling.IsSampled(r.Context(), e.SamplingRatio) {
+			ext.SamplingPriority.Set(span, 1)
+		} else {
+			ext.SamplingPriority.Set(span, 0)
+		}
+	}
+
+	reqCtx := opentracing.ContextWithSpan(r.Context(), span)
+	if !e.DisableBaggage {
+		// The baggage set by the previous middlewares is kept.
+		baggage := GetBaggage(reqCtx)
+		if baggage == nil {
+			baggage = NewBaggage()
+			reqCtx = WithBaggage(reqCtx, baggage)
+		}
+		baggage.Merge(r.Header[BaggageHeader])
+	}
+	r = r.WithContext(reqCtx)
 
 	recorder := newStatusCodeRecoder(w, 200)
 	next(recorder, r)
@@ -3,6 +3,7 @@ package tracing
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/opentracing/opentracing-go/ext"
@@ -22,6 +23,7 @@ func TestEntryPointMiddlewareServeHTTP(t *testing.T) {
 		desc         string
 		entryPoint   string
 		tracing      *Tracing
+		headers      map[string]string
 		expectedTags map[string]interface{}
 		expectedName string
 	}{
@@ -43,6 +45,27 @@ func TestEntryPointMiddlewareServeHTTP(t *testing.T) {
 			},
 			expectedTags: expectedTags,
 			expectedName: "Entrypoint te... ww... 39b97e58",
+		}, {
+			desc:       "header tags",
+			entryPoint: "test",
+			tracing: &Tracing{
+				HeaderTags: HeaderTags{"X-Tenant-Id": "tenant.id", "X-Api-Version": "api.version", "X-Missing": "missing"},
+				tracer:     &MockTracer{Span: &MockSpan{Tags: make(map[string]interface{})}},
+			},
+			headers: map[string]string{
+				"X-Tenant-Id":   "tenant1",
+				"X-Api-Version": strings.Repeat("v", 300),
+			},
+			expectedTags: map[string]interface{}{
+				"span.kind":   ext.SpanKindRPCServerEnum,
+				"http.method": "GET",
+				"component":   "",
+				"http.url":    "http://www.test.com",
+				"http.host":   "www.test.com",
+				"tenant.id":   "tenant1",
+				"api.version": strings.Repeat("v", HeaderTagMaxLength-3) + "...",
+			},
+			expectedName: "Entrypoint test www.test.com",
 		},
 	}
 
@@ -64,7 +87,12 @@ func TestEntryPointMiddlewareServeHTTP(t *testing.T) {
 				assert.Equal(t, test.expectedName, span.OpName)
 			}
 
-			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://www.test.com", nil), next)
+			req := httptest.NewRequest(http.MethodGet, "http://www.test.com", nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			e.ServeHTTP(httptest.NewRecorder(), req, next)
 		})
 	}
 }
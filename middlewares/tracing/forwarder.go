@@ -37,6 +37,7 @@ func (f *forwarderMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 	span.SetTag("http.host", r.Host)
 
 	InjectRequestHeaders(r)
+	propagateBaggage(r)
 
 	recorder := newStatusCodeRecoder(w, 200)
 
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/middlewares/tracing/datadog"
@@ -23,19 +25,66 @@ const EntryPointMaxLengthNumber = 21
 // TraceNameHashLength defines the number of characters to use from the head of the generated hash.
 const TraceNameHashLength = 8
 
+// HeaderTagMaxLength defines the maximum number of characters of the tags set from the request headers.
+const HeaderTagMaxLength = 256
+
 // Tracing middleware
 type Tracing struct {
-	Backend       string          `description:"Selects the tracking backend ('jaeger','zipkin', 'datadog')." export:"true"`
-	ServiceName   string          `description:"Set the name for this service" export:"true"`
-	SpanNameLimit int             `description:"Set the maximum character limit for Span names (default 0 = no limit)" export:"true"`
-	Jaeger        *jaeger.Config  `description:"Settings for jaeger"`
-	Zipkin        *zipkin.Config  `description:"Settings for zipkin"`
-	DataDog       *datadog.Config `description:"Settings for DataDog"`
+	Backend        string          `description:"Selects the tracking backend ('jaeger','zipkin', 'datadog')." export:"true"`
+	ServiceName    string          `description:"Set the name for this service" export:"true"`
+	SpanNameLimit  int             `description:"Set the maximum character limit for Span names (default 0 = no limit)" export:"true"`
+	SamplingRatio  float64         `description:"Ratio of requests to trace, between 0.0 and 1.0, based on the shared sampling decision (default 0 = decided by the tracer)" export:"true"`
+	HeaderTags     HeaderTags      `description:"Tags of the entry point spans set from the request headers, as header=tag" export:"true"`
+	DisableBaggage bool            `description:"Disable the propagation of the W3C baggage to the backends" export:"true"`
+	Jaeger         *jaeger.Config  `description:"Settings for jaeger"`
+	Zipkin         *zipkin.Config  `description:"Settings for zipkin"`
+	DataDog        *datadog.Config `description:"Settings for DataDog"`
 
 	tracer opentracing.Tracer
 	closer io.Closer
 }
 
+// HeaderTags holds the tags of the entry point spans to set from the request headers, by header name
+type HeaderTags map[string]string
+
+// String is the method to format the flag's value, part of the flag.Value interface.
+// The String method's output will be used in diagnostics.
+func (h *HeaderTags) String() string {
+	return fmt.Sprintf("%+v", *h)
+}
+
+// Get return the HeaderTags map
+func (h *HeaderTags) Get() interface{} {
+	return *h
+}
+
+// Set is the method to set the flag value, part of the flag.Value interface.
+// Set's argument is a string to be parsed to set the flag.
+// It's a space-separated list of header=tag, so we split it.
+func (h *HeaderTags) Set(value string) error {
+	value = strings.Trim(value, "\"")
+
+	if *h == nil {
+		*h = make(HeaderTags)
+	}
+
+	for _, field := range strings.Fields(value) {
+		n := strings.SplitN(field, "=", 2)
+		if len(n) != 2 || len(n[0]) == 0 || len(n[1]) == 0 {
+			return fmt.Errorf("invalid header tag %q, expected header=tag", field)
+		}
+
+		(*h)[n[0]] = n[1]
+	}
+
+	return nil
+}
+
+// SetValue sets the HeaderTags map with val
+func (h *HeaderTags) SetValue(val interface{}) {
+	*h = val.(HeaderTags)
+}
+
 // StartSpan delegates to opentracing.Tracer
 func (t *Tracing) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
 	return t.tracer.StartSpan(operationName, opts...)
@@ -179,6 +228,10 @@ func truncateString(str string, num int) string {
 		if num > 3 {
 			num -= 3
 		}
+		// Not to cut a multi-byte character.
+		for num > 0 && !utf8.RuneStart(str[num]) {
+			num--
+		}
 		text = str[0:num] + "..."
 	}
 	return text
@@ -87,6 +87,12 @@ func TestTruncateString(t *testing.T) {
 			limit:    39,
 			expected: "some-service-100.slug.namespace.envi...",
 		},
+		{
+			desc:     "truncate on a rune boundary",
+			text:     "héhéhéhé",
+			limit:    8,
+			expected: "héh...",
+		},
 	}
 
 	for _, test := range testCases {
@@ -131,3 +137,53 @@ func TestComputeHash(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaderTagsSet(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		value         string
+		expected      *HeaderTags
+		expectedError bool
+	}{
+		{
+			desc:     "One value should return HeaderTags of size 1",
+			value:    "X-Tenant-Id=tenant.id",
+			expected: &HeaderTags{"X-Tenant-Id": "tenant.id"},
+		},
+		{
+			desc:  "Several values separated by space should return HeaderTags of the same size",
+			value: "X-Tenant-Id=tenant.id X-Api-Version=api.version",
+			expected: &HeaderTags{
+				"X-Tenant-Id":   "tenant.id",
+				"X-Api-Version": "api.version",
+			},
+		},
+		{
+			desc:          "Missing tag should return an error",
+			value:         "X-Tenant-Id",
+			expectedError: true,
+		},
+		{
+			desc:          "Empty tag should return an error",
+			value:         "X-Tenant-Id=",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			headerTags := &HeaderTags{}
+			err := headerTags.Set(test.value)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expected, headerTags)
+		})
+	}
+}
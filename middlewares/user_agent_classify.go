@@ -0,0 +1,265 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+)
+
+// User agent classes.
+const (
+	UserAgentClassCrawler = "crawler"
+	UserAgentClassBot     = "bot"
+	UserAgentClassBrowser = "browser"
+	UserAgentClassUnknown = "unknown"
+)
+
+// DefaultUserAgentClassHeader is the header set with the class of the user agent.
+const DefaultUserAgentClassHeader = "X-User-Agent-Class"
+
+const (
+	// userAgentPatternsCheckInterval is the minimum interval between two checks of the patterns file modification.
+	userAgentPatternsCheckInterval = 10 * time.Second
+	// maxUserAgentCacheSize bounds the classification cache, which is reset when full.
+	maxUserAgentCacheSize = 10000
+)
+
+var defaultUserAgentPatterns = map[string][]string{
+	UserAgentClassCrawler: {
+		`googlebot`, `bingbot`, `slurp`, `duckduckbot`, `baiduspider`, `yandexbot`, `applebot`,
+		`facebookexternalhit`, `twitterbot`, `linkedinbot`, `ahrefsbot`, `semrushbot`, `petalbot`,
+		`crawler`, `spider`,
+	},
+	UserAgentClassBot: {
+		`bot\b`, `^curl/`, `^wget/`, `python-requests`, `python-urllib`, `go-http-client`, `^java/`,
+		`okhttp`, `libwww-perl`, `httpclient`, `scrapy`, `headlesschrome`,
+	},
+	UserAgentClassBrowser: {
+		`^mozilla/`, `^opera/`,
+	},
+}
+
+// userAgentClasses is the order in which the classes are matched.
+var userAgentClasses = []string{UserAgentClassCrawler, UserAgentClassBot, UserAgentClassBrowser}
+
+type userAgentPattern struct {
+	class   string
+	pattern *regexp.Regexp
+}
+
+// UserAgentClassifier is a middleware that classifies the requests (crawler, bot, browser or unknown)
+// from their User-Agent header, and sets the class in a request header,
+// which can be used by the frontend rules or as a rate limit extractor.
+type UserAgentClassifier struct {
+	headerName      string
+	patterns        []userAgentPattern
+	defaultPatterns []userAgentPattern
+	patternsFile    string
+
+	lock          sync.RWMutex
+	filePatterns  []userAgentPattern
+	fileModTime   time.Time
+	lastFileCheck time.Time
+	cache         map[string]string
+}
+
+// NewUserAgentClassifier creates a new UserAgentClassifier.
+// The configured patterns, then the patterns of the file, are matched before the built-in ones.
+func NewUserAgentClassifier(headerName string, patterns map[string][]string, patternsFile string) (*UserAgentClassifier, error) {
+	if len(headerName) == 0 {
+		headerName = DefaultUserAgentClassHeader
+	}
+
+	configured, err := compileUserAgentPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := compileUserAgentPatterns(defaultUserAgentPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &UserAgentClassifier{
+		headerName:      headerName,
+		patterns:        configured,
+		defaultPatterns: defaults,
+		patternsFile:    patternsFile,
+		cache:           make(map[string]string),
+	}
+
+	if len(patternsFile) > 0 {
+		if err := c.loadPatternsFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *UserAgentClassifier) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	r.Header.Set(c.headerName, c.Classify(r.UserAgent()))
+	next(rw, r)
+}
+
+// Classify returns the class of the user agent.
+func (c *UserAgentClassifier) Classify(userAgent string) string {
+	if len(userAgent) == 0 {
+		return UserAgentClassUnknown
+	}
+
+	c.reloadPatternsFile()
+
+	c.lock.RLock()
+	class, ok := c.cache[userAgent]
+	filePatterns := c.filePatterns
+	c.lock.RUnlock()
+
+	if ok {
+		return class
+	}
+
+	class = UserAgentClassUnknown
+	for _, patterns := range [][]userAgentPattern{c.patterns, filePatterns, c.defaultPatterns} {
+		if matched, ok := matchUserAgent(userAgent, patterns); ok {
+			class = matched
+			break
+		}
+	}
+
+	c.lock.Lock()
+	if len(c.cache) >= maxUserAgentCacheSize {
+		c.cache = make(map[string]string)
+	}
+	c.cache[userAgent] = class
+	c.lock.Unlock()
+
+	return class
+}
+
+func matchUserAgent(userAgent string, patterns []userAgentPattern) (string, bool) {
+	for _, p := range patterns {
+		if p.pattern.MatchString(userAgent) {
+			return p.class, true
+		}
+	}
+	return "", false
+}
+
+// reloadPatternsFile reloads the patterns file when it is modified.
+func (c *UserAgentClassifier) reloadPatternsFile() {
+	if len(c.patternsFile) == 0 {
+		return
+	}
+
+	c.lock.RLock()
+	skip := time.Since(c.lastFileCheck) < userAgentPatternsCheckInterval
+	c.lock.RUnlock()
+
+	if skip {
+		return
+	}
+
+	if err := c.loadPatternsFile(); err != nil {
+		log.Errorf("Unable to reload the user agent patterns, keeping the previous ones: %v", err)
+	}
+}
+
+func (c *UserAgentClassifier) loadPatternsFile() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.lastFileCheck = time.Now()
+
+	info, err := os.Stat(c.patternsFile)
+	if err != nil {
+		return err
+	}
+
+	if info.ModTime().Equal(c.fileModTime) {
+		return nil
+	}
+
+	patterns, err := readUserAgentPatternsFile(c.patternsFile)
+	if err != nil {
+		return err
+	}
+
+	filePatterns, err := compileUserAgentPatterns(patterns)
+	if err != nil {
+		return fmt.Errorf("%s: %v", c.patternsFile, err)
+	}
+
+	c.filePatterns = filePatterns
+	c.fileModTime = info.ModTime()
+	c.cache = make(map[string]string)
+
+	return nil
+}
+
+// readUserAgentPatternsFile reads a file of "<class> <pattern>" lines, ignoring the blank lines and the comments.
+func readUserAgentPatternsFile(filename string) (map[string][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	patterns := make(map[string][]string)
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 || len(strings.TrimSpace(fields[1])) == 0 {
+			return nil, fmt.Errorf("%s:%d: expected \"<class> <pattern>\"", filename, lineNumber)
+		}
+
+		class := strings.ToLower(fields[0])
+		patterns[class] = append(patterns[class], strings.TrimSpace(fields[1]))
+	}
+
+	return patterns, scanner.Err()
+}
+
+// compileUserAgentPatterns compiles the case insensitive patterns, in the matching order of the classes.
+func compileUserAgentPatterns(patterns map[string][]string) ([]userAgentPattern, error) {
+	for class := range patterns {
+		if !isUserAgentClass(class) {
+			return nil, fmt.Errorf("unknown user agent class %q", class)
+		}
+	}
+
+	var compiled []userAgentPattern
+	for _, class := range userAgentClasses {
+		for _, pattern := range patterns[class] {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s pattern %q: %v", class, pattern, err)
+			}
+			compiled = append(compiled, userAgentPattern{class: class, pattern: re})
+		}
+	}
+
+	return compiled, nil
+}
+
+func isUserAgentClass(class string) bool {
+	for _, c := range userAgentClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAgentClassifier(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		headerName     string
+		patterns       map[string][]string
+		userAgent      string
+		expectedHeader string
+		expectedClass  string
+	}{
+		{
+			desc:           "crawler",
+			userAgent:      "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expectedHeader: DefaultUserAgentClassHeader,
+			expectedClass:  UserAgentClassCrawler,
+		},
+		{
+			desc:           "bot",
+			userAgent:      "curl/7.58.0",
+			expectedHeader: DefaultUserAgentClassHeader,
+			expectedClass:  UserAgentClassBot,
+		},
+		{
+			desc:           "browser",
+			userAgent:      "Mozilla/5.0 (X11; Linux x86_64; rv:68.0) Gecko/20100101 Firefox/68.0",
+			expectedHeader: DefaultUserAgentClassHeader,
+			expectedClass:  UserAgentClassBrowser,
+		},
+		{
+			desc:           "unknown",
+			userAgent:      "MyApp/1.0",
+			expectedHeader: DefaultUserAgentClassHeader,
+			expectedClass:  UserAgentClassUnknown,
+		},
+		{
+			desc:           "missing user agent",
+			expectedHeader: DefaultUserAgentClassHeader,
+			expectedClass:  UserAgentClassUnknown,
+		},
+		{
+			desc:           "configured patterns take precedence",
+			headerName:     "X-UA-Class",
+			patterns:       map[string][]string{UserAgentClassBot: {`^myapp/`}, UserAgentClassBrowser: {`^curl/`}},
+			userAgent:      "MyApp/1.0",
+			expectedHeader: "X-UA-Class",
+			expectedClass:  UserAgentClassBot,
+		},
+		{
+			desc:           "configured patterns override the defaults",
+			patterns:       map[string][]string{UserAgentClassBrowser: {`^curl/`}},
+			userAgent:      "curl/7.58.0",
+			expectedHeader: DefaultUserAgentClassHeader,
+			expectedClass:  UserAgentClassBrowser,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			classifier, err := NewUserAgentClassifier(test.headerName, test.patterns, "")
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			req.Header.Set(test.expectedHeader, "spoofed")
+			if len(test.userAgent) > 0 {
+				req.Header.Set("User-Agent", test.userAgent)
+			}
+
+			var class string
+			classifier.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				class = r.Header.Get(test.expectedHeader)
+			})
+
+			assert.Equal(t, test.expectedClass, class)
+		})
+	}
+}
+
+func TestNewUserAgentClassifierFail(t *testing.T) {
+	_, err := NewUserAgentClassifier("", map[string][]string{"robot": {"foo"}}, "")
+	assert.Error(t, err)
+
+	_, err = NewUserAgentClassifier("", map[string][]string{UserAgentClassBot: {"("}}, "")
+	assert.Error(t, err)
+
+	_, err = NewUserAgentClassifier("", nil, "/missing/patterns.txt")
+	assert.Error(t, err)
+}
+
+func TestUserAgentClassifierPatternsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "traefik-useragent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	patternsFile := filepath.Join(dir, "patterns.txt")
+	err = ioutil.WriteFile(patternsFile, []byte("# internal tools\nbot ^myapp/\n\n"), 0644)
+	require.NoError(t, err)
+
+	classifier, err := NewUserAgentClassifier("", nil, patternsFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, UserAgentClassBot, classifier.Classify("MyApp/1.0"))
+
+	// The file is modified, and reloaded on the next check.
+	err = ioutil.WriteFile(patternsFile, []byte("crawler ^myapp/\n"), 0644)
+	require.NoError(t, err)
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(patternsFile, modTime, modTime))
+
+	assert.Equal(t, UserAgentClassBot, classifier.Classify("MyApp/1.0"))
+
+	classifier.lastFileCheck = time.Time{}
+	assert.Equal(t, UserAgentClassCrawler, classifier.Classify("MyApp/1.0"))
+
+	// An invalid file is ignored, the previous patterns are kept.
+	err = ioutil.WriteFile(patternsFile, []byte("robot ^myapp/\n"), 0644)
+	require.NoError(t, err)
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(patternsFile, modTime, modTime))
+
+	classifier.lastFileCheck = time.Time{}
+	assert.Equal(t, UserAgentClassCrawler, classifier.Classify("MyApp/1.0"))
+}
@@ -0,0 +1,394 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+const (
+	webSocketOpPing = 0x9
+	webSocketOpPong = 0xA
+
+	webSocketFinalBit = 0x80
+	webSocketMaskBit  = 0x80
+
+	// maxWebSocketHandshakeSize is the maximum size of the handshake response, past which the frames are not tracked.
+	maxWebSocketHandshakeSize = 64 * 1024
+)
+
+var webSocketHandshakeEnd = []byte("\r\n\r\n")
+
+// WebSocketKeepAlive sends ping frames to the clients of the websocket connections,
+// and closes the connections whose client doesn't answer with a pong frame within the pong timeout,
+// so that the idle connections are kept open through the NAT gateways, and the dead ones are detected.
+// The pings carry a random payload: the pongs answering them are not forwarded to the backend,
+// while the ping and pong frames of the application, and the subprotocol negotiation, are forwarded untouched.
+type WebSocketKeepAlive struct {
+	backendName  string
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+}
+
+// NewWebSocketKeepAlive creates a new WebSocketKeepAlive.
+func NewWebSocketKeepAlive(backendName string, config *types.WebSocket) (*WebSocketKeepAlive, error) {
+	if config.PingInterval <= 0 {
+		return nil, errors.New("the ping interval must be positive")
+	}
+	if config.PongTimeout < 0 {
+		return nil, errors.New("the pong timeout must be positive")
+	}
+
+	keepAlive := &WebSocketKeepAlive{
+		backendName:  backendName,
+		pingInterval: time.Duration(config.PingInterval),
+		pongTimeout:  time.Duration(config.PongTimeout),
+	}
+
+	if keepAlive.pongTimeout == 0 {
+		keepAlive.pongTimeout = keepAlive.pingInterval
+	}
+
+	return keepAlive, nil
+}
+
+func (w *WebSocketKeepAlive) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if !isWebsocketRequest(req) {
+		next(rw, req)
+		return
+	}
+
+	next(&webSocketKeepAliveResponseWriter{ResponseWriter: rw, keepAlive: w}, req)
+}
+
+// webSocketKeepAliveResponseWriter keeps alive the connection it hijacks.
+type webSocketKeepAliveResponseWriter struct {
+	http.ResponseWriter
+	keepAlive *WebSocketKeepAlive
+}
+
+// Hijack hijacks the connection of the underlying ResponseWriter,
+// and reads it through the returned connection so that the pongs answering the pings are removed.
+func (w *webSocketKeepAliveResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T is not a http.Hijacker", w.ResponseWriter)
+	}
+
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keepAliveConn, err := newWebSocketKeepAliveConn(conn, brw.Reader, w.keepAlive)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return keepAliveConn, bufio.NewReadWriter(bufio.NewReader(keepAliveConn), bufio.NewWriter(keepAliveConn)), nil
+}
+
+// webSocketFrameScanner tracks the boundaries of the WebSocket frames of a stream.
+type webSocketFrameScanner struct {
+	header    [14]byte
+	headerLen int
+	remaining uint64
+}
+
+func (s *webSocketFrameScanner) scan(b []byte) {
+	for len(b) > 0 {
+		if s.remaining > 0 {
+			n := uint64(len(b))
+			if n > s.remaining {
+				n = s.remaining
+			}
+			s.remaining -= n
+			b = b[n:]
+			continue
+		}
+
+		s.header[s.headerLen] = b[0]
+		s.headerLen++
+		b = b[1:]
+
+		if s.headerLen >= 2 && s.headerLen == webSocketHeaderSize(s.header[:2]) {
+			s.remaining = webSocketPayloadLen(s.header[:s.headerLen])
+			s.headerLen = 0
+		}
+	}
+}
+
+func (s *webSocketFrameScanner) atBoundary() bool {
+	return s.headerLen == 0 && s.remaining == 0
+}
+
+// webSocketHeaderSize returns the size of a frame header from its first two bytes.
+func webSocketHeaderSize(b []byte) int {
+	size := 2
+	switch b[1] &^ webSocketMaskBit {
+	case 126:
+		size += 2
+	case 127:
+		size += 8
+	}
+	if b[1]&webSocketMaskBit != 0 {
+		size += 4
+	}
+	return size
+}
+
+// webSocketPayloadLen returns the payload length of a frame from its complete header.
+func webSocketPayloadLen(header []byte) uint64 {
+	switch length := header[1] &^ webSocketMaskBit; length {
+	case 126:
+		return uint64(binary.BigEndian.Uint16(header[2:4]))
+	case 127:
+		return binary.BigEndian.Uint64(header[2:10])
+	default:
+		return uint64(length)
+	}
+}
+
+// webSocketKeepAliveConn sends the ping frames between the frames written to the connection,
+// and removes the pong frames answering them from the frames read from the connection.
+type webSocketKeepAliveConn struct {
+	net.Conn
+	src          io.Reader
+	backendName  string
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+	payload      []byte
+
+	writeLock   sync.Mutex
+	handshake   []byte
+	upgraded    bool
+	passThrough int32
+	written     webSocketFrameScanner
+	pingPending bool
+
+	readBuf       []byte
+	readRemaining uint64
+
+	pongs     chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newWebSocketKeepAliveConn(conn net.Conn, src io.Reader, keepAlive *WebSocketKeepAlive) (*webSocketKeepAliveConn, error) {
+	payload := make([]byte, 8)
+	if _, err := rand.Read(payload); err != nil {
+		return nil, err
+	}
+
+	return &webSocketKeepAliveConn{
+		Conn:         conn,
+		src:          src,
+		backendName:  keepAlive.backendName,
+		pingInterval: keepAlive.pingInterval,
+		pongTimeout:  keepAlive.pongTimeout,
+		payload:      payload,
+		pongs:        make(chan struct{}, 1),
+		closed:       make(chan struct{}),
+	}, nil
+}
+
+func (c *webSocketKeepAliveConn) Write(b []byte) (int, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	n, err := c.Conn.Write(b)
+	c.scanWritten(b[:n])
+
+	if err == nil && c.pingPending && c.written.atBoundary() {
+		c.pingPending = false
+		err = c.writePing()
+	}
+
+	return n, err
+}
+
+// scanWritten tracks the frames written after the handshake response,
+// and starts the keep-alive once the connection is upgraded.
+func (c *webSocketKeepAliveConn) scanWritten(b []byte) {
+	if c.upgraded {
+		c.written.scan(b)
+		return
+	}
+	if atomic.LoadInt32(&c.passThrough) == 1 {
+		return
+	}
+
+	c.handshake = append(c.handshake, b...)
+
+	end := bytes.Index(c.handshake, webSocketHandshakeEnd)
+	if end < 0 {
+		if len(c.handshake) > maxWebSocketHandshakeSize {
+			c.handshake = nil
+			atomic.StoreInt32(&c.passThrough, 1)
+		}
+		return
+	}
+
+	frames := c.handshake[end+len(webSocketHandshakeEnd):]
+	upgraded := bytes.HasPrefix(c.handshake, []byte("HTTP/1.1 101 "))
+	c.handshake = nil
+
+	if !upgraded {
+		atomic.StoreInt32(&c.passThrough, 1)
+		return
+	}
+
+	c.upgraded = true
+	c.written.scan(frames)
+	go c.keepAlive()
+}
+
+func (c *webSocketKeepAliveConn) keepAlive() {
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+		}
+
+		// The pongs of the previous pings are ignored.
+		select {
+		case <-c.pongs:
+		default:
+		}
+
+		if err := c.ping(); err != nil {
+			log.Debugf("Backend %s: error sending websocket ping to %s: %v", c.backendName, c.RemoteAddr(), err)
+			return
+		}
+
+		timer := time.NewTimer(c.pongTimeout)
+		select {
+		case <-c.closed:
+			timer.Stop()
+			return
+		case <-c.pongs:
+			timer.Stop()
+		case <-timer.C:
+			log.Debugf("Backend %s: closing the websocket connection of %s, no pong received within %s", c.backendName, c.RemoteAddr(), c.pongTimeout)
+			c.Close()
+			return
+		}
+	}
+}
+
+// ping sends a ping frame, or defers it until the frame being written is complete.
+func (c *webSocketKeepAliveConn) ping() error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if !c.written.atBoundary() {
+		c.pingPending = true
+		return nil
+	}
+	return c.writePing()
+}
+
+func (c *webSocketKeepAliveConn) writePing() error {
+	frame := append([]byte{webSocketFinalBit | webSocketOpPing, byte(len(c.payload))}, c.payload...)
+	_, err := c.Conn.Write(frame)
+	return err
+}
+
+func (c *webSocketKeepAliveConn) Read(p []byte) (int, error) {
+	if atomic.LoadInt32(&c.passThrough) == 1 && len(c.readBuf) == 0 && c.readRemaining == 0 {
+		return c.src.Read(p)
+	}
+
+	for {
+		if len(c.readBuf) > 0 {
+			n := copy(p, c.readBuf)
+			c.readBuf = c.readBuf[n:]
+			return n, nil
+		}
+
+		if c.readRemaining > 0 {
+			if uint64(len(p)) > c.readRemaining {
+				p = p[:c.readRemaining]
+			}
+			n, err := c.src.Read(p)
+			c.readRemaining -= uint64(n)
+			return n, err
+		}
+
+		if err := c.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// readFrame reads the header of the next frame, and the whole frame when it may be a pong answering a ping.
+// The pongs answering the pings are dropped.
+func (c *webSocketKeepAliveConn) readFrame() error {
+	header := make([]byte, 2, 14)
+	if _, err := io.ReadFull(c.src, header); err != nil {
+		return err
+	}
+
+	header = header[:webSocketHeaderSize(header)]
+	if _, err := io.ReadFull(c.src, header[2:]); err != nil {
+		return err
+	}
+
+	payloadLen := webSocketPayloadLen(header)
+	if header[0] != webSocketFinalBit|webSocketOpPong || payloadLen != uint64(len(c.payload)) {
+		c.readBuf = header
+		c.readRemaining = payloadLen
+		return nil
+	}
+
+	payload := make([]byte, payloadLen)
+	if _, err := io.ReadFull(c.src, payload); err != nil {
+		return err
+	}
+
+	c.readBuf = append(header, payload...)
+
+	if header[1]&webSocketMaskBit != 0 {
+		maskKey := header[len(header)-4:]
+		unmasked := make([]byte, len(payload))
+		for i := range payload {
+			unmasked[i] = payload[i] ^ maskKey[i%4]
+		}
+		payload = unmasked
+	}
+
+	if bytes.Equal(payload, c.payload) {
+		c.readBuf = nil
+
+		select {
+		case c.pongs <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (c *webSocketKeepAliveConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return c.Conn.Close()
+}
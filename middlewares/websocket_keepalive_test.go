@@ -0,0 +1,241 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/types"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webSocketEchoServer echoes the messages of the clients through a WebSocketKeepAlive,
+// and records the pongs it receives.
+type webSocketEchoServer struct {
+	*httptest.Server
+
+	lock  sync.Mutex
+	pongs []string
+	conns chan *websocket.Conn
+}
+
+func newWebSocketEchoServer(t *testing.T, config *types.WebSocket) *webSocketEchoServer {
+	t.Helper()
+
+	keepAlive, err := NewWebSocketKeepAlive("backend1", config)
+	require.NoError(t, err)
+
+	server := &webSocketEchoServer{conns: make(chan *websocket.Conn, 1)}
+
+	upgrader := websocket.Upgrader{Subprotocols: []string{"echo"}}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		keepAlive.ServeHTTP(rw, req, func(rw http.ResponseWriter, req *http.Request) {
+			conn, err := upgrader.Upgrade(rw, req, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			conn.SetPongHandler(func(data string) error {
+				server.lock.Lock()
+				defer server.lock.Unlock()
+				server.pongs = append(server.pongs, data)
+				return nil
+			})
+			server.conns <- conn
+
+			for {
+				messageType, data, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				if err := conn.WriteMessage(messageType, data); err != nil {
+					return
+				}
+			}
+		})
+	}))
+
+	return server
+}
+
+func (s *webSocketEchoServer) receivedPongs() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.pongs...)
+}
+
+func (s *webSocketEchoServer) dial(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: []string{"echo"}}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "echo", resp.Header.Get("Sec-Websocket-Protocol"))
+	return conn
+}
+
+func TestWebSocketKeepAlive(t *testing.T) {
+	server := newWebSocketEchoServer(t, &types.WebSocket{PingInterval: parse.Duration(10 * time.Millisecond)})
+	defer server.Close()
+
+	conn := server.dial(t)
+	defer conn.Close()
+
+	var pings int
+	var pingsLock sync.Mutex
+	conn.SetPingHandler(func(data string) error {
+		pingsLock.Lock()
+		pings++
+		pingsLock.Unlock()
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	serverConn := <-server.conns
+
+	// The application pings are forwarded, and so are their pongs.
+	require.NoError(t, serverConn.WriteControl(websocket.PingMessage, []byte("app"), time.Now().Add(time.Second)))
+
+	// The pings are sent between the frames of the messages, even large ones.
+	messages := [][]byte{[]byte("hello"), bytes.Repeat([]byte("a"), 200), bytes.Repeat([]byte("b"), 70000)}
+	for i := 0; i < 10; i++ {
+		for _, message := range messages {
+			require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, message))
+
+			_, data, err := conn.ReadMessage()
+			require.NoError(t, err)
+			assert.Equal(t, message, data)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	pingsLock.Lock()
+	assert.NotZero(t, pings)
+	pingsLock.Unlock()
+
+	// The pongs answering the pings of the keep-alive are not forwarded.
+	assert.Equal(t, []string{"app"}, server.receivedPongs())
+}
+
+func TestWebSocketKeepAlivePongTimeout(t *testing.T) {
+	server := newWebSocketEchoServer(t, &types.WebSocket{
+		PingInterval: parse.Duration(10 * time.Millisecond),
+		PongTimeout:  parse.Duration(20 * time.Millisecond),
+	})
+	defer server.Close()
+
+	conn := server.dial(t)
+	defer conn.Close()
+
+	// The client doesn't answer the pings.
+	conn.SetPingHandler(func(string) error {
+		return nil
+	})
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, _, err := conn.ReadMessage()
+	require.Error(t, err)
+
+	netErr, ok := err.(interface{ Timeout() bool })
+	assert.False(t, ok && netErr.Timeout(), "the connection must be closed by the keep-alive: %v", err)
+}
+
+func TestWebSocketKeepAliveNotUpgraded(t *testing.T) {
+	keepAlive, err := NewWebSocketKeepAlive("backend1", &types.WebSocket{PingInterval: parse.Duration(time.Millisecond)})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		keepAlive.ServeHTTP(rw, req, func(rw http.ResponseWriter, req *http.Request) {
+			conn, _, err := rw.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			resp := &http.Response{StatusCode: http.StatusForbidden, ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{}}
+			resp.Write(conn)
+			time.Sleep(20 * time.Millisecond)
+		})
+	}))
+	defer server.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestWebSocketFrameScanner(t *testing.T) {
+	frame := func(payloadLen int, masked bool) []byte {
+		var header []byte
+		switch {
+		case payloadLen < 126:
+			header = []byte{0x82, byte(payloadLen)}
+		case payloadLen < 65536:
+			header = []byte{0x82, 126, byte(payloadLen >> 8), byte(payloadLen)}
+		default:
+			header = []byte{0x82, 127, 0, 0, 0, 0, byte(payloadLen >> 24), byte(payloadLen >> 16), byte(payloadLen >> 8), byte(payloadLen)}
+		}
+		if masked {
+			header[1] |= 0x80
+			header = append(header, 1, 2, 3, 4)
+		}
+		return append(header, make([]byte, payloadLen)...)
+	}
+
+	var stream []byte
+	var boundaries []int
+	for _, f := range [][]byte{frame(0, false), frame(5, true), frame(300, false), frame(70000, true)} {
+		stream = append(stream, f...)
+		boundaries = append(boundaries, len(stream))
+	}
+
+	// The stream is scanned byte by byte.
+	scanner := &webSocketFrameScanner{}
+	var atBoundaries []int
+	for i := range stream {
+		scanner.scan(stream[i : i+1])
+		if scanner.atBoundary() {
+			atBoundaries = append(atBoundaries, i+1)
+		}
+	}
+	assert.Equal(t, boundaries, atBoundaries)
+
+	// The stream is scanned at once.
+	scanner = &webSocketFrameScanner{}
+	scanner.scan(stream)
+	assert.True(t, scanner.atBoundary())
+}
+
+func TestNewWebSocketKeepAliveFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *types.WebSocket
+	}{
+		{
+			desc:   "no ping interval",
+			config: &types.WebSocket{},
+		},
+		{
+			desc:   "negative pong timeout",
+			config: &types.WebSocket{PingInterval: parse.Duration(time.Second), PongTimeout: parse.Duration(-time.Second)},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewWebSocketKeepAlive("backend1", test.config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -4,14 +4,49 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/containous/mux"
 )
 
 // Handler expose ping routes
 type Handler struct {
-	EntryPoint  string `description:"Ping entryPoint" export:"true"`
+	EntryPoint  string     `description:"Ping entryPoint" export:"true"`
+	Readiness   *Readiness `description:"Enable the readiness endpoints, reporting whether the frontends have healthy servers" export:"true"`
 	terminating bool
+	// FrontendReadiness returns whether a frontend has at least one healthy server, and whether the frontend exists.
+	FrontendReadiness func(frontendName string) (ready bool, exists bool) `json:"-"`
+}
+
+// Readiness holds the readiness endpoints configuration
+type Readiness struct {
+	Frontends Frontends `description:"Frontends which must have at least one healthy server for traefik to be ready" export:"true"`
+}
+
+// Frontends holds the names of the readiness frontends
+type Frontends []string
+
+// Set adds strings elem into the the parser
+// it splits str on , and ;
+func (f *Frontends) Set(str string) error {
+	fargs := func(c rune) bool {
+		return c == ',' || c == ';'
+	}
+	// get function
+	slice := strings.FieldsFunc(str, fargs)
+	*f = append(*f, slice...)
+	return nil
+}
+
+// Get Frontends
+func (f *Frontends) Get() interface{} { return *f }
+
+// String return slice in a string
+func (f *Frontends) String() string { return fmt.Sprintf("%v", *f) }
+
+// SetValue sets Frontends into the parser
+func (f *Frontends) SetValue(val interface{}) {
+	*f = val.(Frontends)
 }
 
 // WithContext causes the ping endpoint to serve non 200 responses.
@@ -33,4 +68,74 @@ func (h *Handler) AddRoutes(router *mux.Router) {
 			response.WriteHeader(statusCode)
 			fmt.Fprint(response, http.StatusText(statusCode))
 		})
+
+	if h.Readiness != nil {
+		router.Methods(http.MethodGet, http.MethodHead).Path("/ready").HandlerFunc(h.readyHandler)
+		router.Methods(http.MethodGet, http.MethodHead).Path("/ready/{frontend}").HandlerFunc(h.frontendReadyHandler)
+	}
+}
+
+// readyHandler serves a 200 response when all the readiness frontends have at least one healthy server,
+// and a 503 one listing the frontends which do not otherwise.
+func (h *Handler) readyHandler(response http.ResponseWriter, request *http.Request) {
+	if h.terminating {
+		response.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(response, http.StatusText(http.StatusServiceUnavailable))
+		return
+	}
+
+	var reasons []string
+	for _, frontendName := range h.Readiness.Frontends {
+		if reason := h.frontendNotReadyReason(frontendName); len(reason) > 0 {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	if len(reasons) == 0 {
+		response.WriteHeader(http.StatusOK)
+		fmt.Fprint(response, http.StatusText(http.StatusOK))
+		return
+	}
+
+	response.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprintln(response, http.StatusText(http.StatusServiceUnavailable))
+	for _, reason := range reasons {
+		fmt.Fprintln(response, reason)
+	}
+}
+
+// frontendReadyHandler serves a 200 response when the frontend has at least one healthy server,
+// a 404 one when it does not exist, and a 503 one otherwise.
+func (h *Handler) frontendReadyHandler(response http.ResponseWriter, request *http.Request) {
+	frontendName := mux.Vars(request)["frontend"]
+
+	statusCode := http.StatusOK
+	if h.terminating {
+		statusCode = http.StatusServiceUnavailable
+	} else if h.FrontendReadiness != nil {
+		ready, exists := h.FrontendReadiness(frontendName)
+		if !exists {
+			statusCode = http.StatusNotFound
+		} else if !ready {
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
+
+	response.WriteHeader(statusCode)
+	fmt.Fprint(response, http.StatusText(statusCode))
+}
+
+func (h *Handler) frontendNotReadyReason(frontendName string) string {
+	if h.FrontendReadiness == nil {
+		return ""
+	}
+
+	ready, exists := h.FrontendReadiness(frontendName)
+	if !exists {
+		return fmt.Sprintf("%s: frontend not found", frontendName)
+	}
+	if !ready {
+		return fmt.Sprintf("%s: no healthy server", frontendName)
+	}
+	return ""
 }
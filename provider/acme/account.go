@@ -2,9 +2,14 @@ package acme
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
 
 	"github.com/containous/traefik/log"
 	"github.com/xenolf/lego/acme"
@@ -18,6 +23,42 @@ type Account struct {
 	KeyType      acme.KeyType
 }
 
+// EAB contains the External Account Binding credentials, required by some CAs to register an account
+type EAB struct {
+	Kid         string `description:"Key identifier from the CA."`
+	HmacEncoded string `description:"Base64 URL encoded HMAC key from the CA."`
+}
+
+func (e *EAB) validate() error {
+	if len(e.Kid) == 0 || len(e.HmacEncoded) == 0 {
+		return errors.New("the key identifier and the HMAC key of the external account binding are required")
+	}
+
+	if _, err := base64.RawURLEncoding.DecodeString(e.HmacEncoded); err != nil {
+		return fmt.Errorf("invalid HMAC key of the external account binding, must be base64 URL encoded: %v", err)
+	}
+
+	return nil
+}
+
+// Register registers the account of the client to the CA, with the External Account Binding if any
+func Register(client *acme.Client, eab *EAB) (*acme.RegistrationResource, error) {
+	if eab == nil {
+		return client.Register(true)
+	}
+
+	if err := eab.validate(); err != nil {
+		return nil, err
+	}
+
+	reg, err := client.RegisterWithExternalAccountBinding(true, eab.Kid, eab.HmacEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("the CA rejected the external account binding %s: %v", eab.Kid, err)
+	}
+
+	return reg, nil
+}
+
 const (
 	// RegistrationURLPathV1Regexp is a regexp which match ACME registration URL in the V1 format
 	RegistrationURLPathV1Regexp = `^.*/acme/reg/\d+$`
@@ -81,3 +122,31 @@ func GetKeyType(value string) acme.KeyType {
 		return acme.RSA4096
 	}
 }
+
+// parseKeyType returns the key type of the value, or an error if the value is not a supported key type.
+func parseKeyType(value string) (acme.KeyType, error) {
+	switch value {
+	case "EC256", "EC384", "RSA2048", "RSA4096", "RSA8192":
+		return GetKeyType(value), nil
+	default:
+		return "", fmt.Errorf("invalid key type %q, expected EC256, EC384, RSA2048, RSA4096 or RSA8192", value)
+	}
+}
+
+// generatePrivateKey generates a private key of the given type.
+func generatePrivateKey(keyType acme.KeyType) (crypto.PrivateKey, error) {
+	switch keyType {
+	case acme.EC256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case acme.EC384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case acme.RSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case acme.RSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case acme.RSA8192:
+		return rsa.GenerateKey(rand.Reader, 8192)
+	default:
+		return nil, fmt.Errorf("invalid key type: %s", keyType)
+	}
+}
@@ -0,0 +1,32 @@
+package acme
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChallengeDomains holds the domains for which a challenge is used
+type ChallengeDomains []string
+
+// Set adds strings elem into the the parser
+// it splits str on , and ;
+func (d *ChallengeDomains) Set(str string) error {
+	fargs := func(c rune) bool {
+		return c == ',' || c == ';'
+	}
+	// get function
+	slice := strings.FieldsFunc(str, fargs)
+	*d = append(*d, slice...)
+	return nil
+}
+
+// Get ChallengeDomains
+func (d *ChallengeDomains) Get() interface{} { return *d }
+
+// String return slice in a string
+func (d *ChallengeDomains) String() string { return fmt.Sprintf("%v", *d) }
+
+// SetValue sets ChallengeDomains into the parser
+func (d *ChallengeDomains) SetValue(val interface{}) {
+	*d = val.(ChallengeDomains)
+}
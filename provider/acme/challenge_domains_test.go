@@ -0,0 +1,39 @@
+package acme
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChallengeDomainsSet(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		value    string
+		expected ChallengeDomains
+	}{
+		{
+			desc:     "one domain",
+			value:    "traefik.wtf",
+			expected: ChallengeDomains{"traefik.wtf"},
+		},
+		{
+			desc:     "domains separated by comma and semicolon",
+			value:    "traefik.wtf,*.traefik.wtf;foo.traefik.wtf",
+			expected: ChallengeDomains{"traefik.wtf", "*.traefik.wtf", "foo.traefik.wtf"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var domains ChallengeDomains
+			err := domains.Set(test.value)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, domains)
+			assert.Equal(t, test.expected, domains.Get())
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package acme
 
 import (
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -46,6 +47,7 @@ type Configuration struct {
 	DNSChallenge  *DNSChallenge  `description:"Activate DNS-01 Challenge"`
 	HTTPChallenge *HTTPChallenge `description:"Activate HTTP-01 Challenge"`
 	TLSChallenge  *TLSChallenge  `description:"Activate TLS-ALPN-01 Challenge"`
+	EAB           *EAB           `description:"External Account Binding to use."`
 	Domains       []types.Domain `description:"CN and SANs (alternative domains) to each main domain using format: --acme.domains='main.com,san1.com,san2.com' --acme.domains='*.main.net'. No SANs for wildcards domain. Wildcard domains only accepted with DNSChallenge"`
 }
 
@@ -55,7 +57,7 @@ type Provider struct {
 	Store                  Store
 	certificates           []*Certificate
 	account                *Account
-	client                 *acme.Client
+	clients                map[acme.Challenge]*acme.Client
 	certsChan              chan *Certificate
 	configurationChan      chan<- types.ConfigMessage
 	certificateStore       *traefiktls.CertificateStore
@@ -79,17 +81,21 @@ type DNSChallenge struct {
 	DelayBeforeCheck        parse.Duration     `description:"Assume DNS propagates after a delay in seconds rather than finding and querying nameservers."`
 	Resolvers               types.DNSResolvers `description:"Use following DNS servers to resolve the FQDN authority."`
 	DisablePropagationCheck bool               `description:"Disable the DNS propagation checks before notifying ACME that the DNS challenge is ready. [not recommended]"`
+	Domains                 ChallengeDomains   `description:"Domains for which the challenge is used, exact or wildcard (*.example.com)."`
 	preCheckTimeout         time.Duration
 	preCheckInterval        time.Duration
 }
 
 // HTTPChallenge contains HTTP challenge Configuration
 type HTTPChallenge struct {
-	EntryPoint string `description:"HTTP challenge EntryPoint"`
+	EntryPoint string           `description:"HTTP challenge EntryPoint"`
+	Domains    ChallengeDomains `description:"Domains for which the challenge is used, exact or wildcard (*.example.com)."`
 }
 
 // TLSChallenge contains TLS challenge Configuration
-type TLSChallenge struct{}
+type TLSChallenge struct {
+	Domains ChallengeDomains `description:"Domains for which the challenge is used, exact or wildcard (*.example.com)."`
+}
 
 // SetConfigListenerChan initializes the configFromListenerChan
 func (p *Provider) SetConfigListenerChan(configFromListenerChan chan types.Configuration) {
@@ -131,6 +137,21 @@ func (p *Provider) Init(_ types.Constraints) error {
 		return errors.New("no store found for the ACME provider")
 	}
 
+	if p.EAB != nil {
+		if err := p.EAB.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, domain := range p.Domains {
+		if len(domain.KeyType) == 0 {
+			continue
+		}
+		if _, err := parseKeyType(domain.KeyType); err != nil {
+			return fmt.Errorf("domain %s: %v", domain.Main, err)
+		}
+	}
+
 	var err error
 	p.account, err = p.Store.GetAccount()
 	if err != nil {
@@ -207,12 +228,17 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 	return nil
 }
 
-func (p *Provider) getClient() (*acme.Client, error) {
+func (p *Provider) getClient(domains []string) (*acme.Client, error) {
 	p.clientMutex.Lock()
 	defer p.clientMutex.Unlock()
 
-	if p.client != nil {
-		return p.client, nil
+	challenge, err := p.getChallenge(domains)
+	if err != nil {
+		return nil, err
+	}
+
+	if client, ok := p.clients[challenge]; ok {
+		return client, nil
 	}
 
 	account, err := p.initAccount()
@@ -237,7 +263,7 @@ func (p *Provider) getClient() (*acme.Client, error) {
 	if account.GetRegistration() == nil {
 		log.Info("Register...")
 
-		reg, err := client.Register(true)
+		reg, err := Register(client, p.EAB)
 		if err != nil {
 			return nil, err
 		}
@@ -252,7 +278,8 @@ func (p *Provider) getClient() (*acme.Client, error) {
 		return nil, err
 	}
 
-	if p.DNSChallenge != nil && len(p.DNSChallenge.Provider) > 0 {
+	switch challenge {
+	case acme.DNS01:
 		log.Debugf("Using DNS Challenge provider: %s", p.DNSChallenge.Provider)
 
 		SetRecursiveNameServers(p.DNSChallenge.Resolvers)
@@ -285,7 +312,7 @@ func (p *Provider) getClient() (*acme.Client, error) {
 			p.DNSChallenge.preCheckTimeout, p.DNSChallenge.preCheckInterval = challengeProviderTimeout.Timeout()
 		}
 
-	} else if p.HTTPChallenge != nil && len(p.HTTPChallenge.EntryPoint) > 0 {
+	case acme.HTTP01:
 		log.Debug("Using HTTP Challenge provider.")
 
 		client.ExcludeChallenges([]acme.Challenge{acme.DNS01, acme.TLSALPN01})
@@ -294,7 +321,7 @@ func (p *Provider) getClient() (*acme.Client, error) {
 		if err != nil {
 			return nil, err
 		}
-	} else if p.TLSChallenge != nil {
+	case acme.TLSALPN01:
 		log.Debug("Using TLS Challenge provider.")
 
 		client.ExcludeChallenges([]acme.Challenge{acme.HTTP01, acme.DNS01})
@@ -303,12 +330,98 @@ func (p *Provider) getClient() (*acme.Client, error) {
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		return nil, errors.New("ACME challenge not specified, please select TLS or HTTP or DNS Challenge")
 	}
 
-	p.client = client
-	return p.client, nil
+	if p.clients == nil {
+		p.clients = make(map[acme.Challenge]*acme.Client)
+	}
+	p.clients[challenge] = client
+	return client, nil
+}
+
+// getChallenge returns the challenge used to generate the certificate of the domains.
+// The challenge declaring the most specific domain matching the main domain is used,
+// an exact domain being more specific than a wildcard one,
+// otherwise the first challenge declaring no domains, in the DNS, HTTP, TLS order.
+// The wildcard domains can only be generated with the DNS challenge.
+func (p *Provider) getChallenge(domains []string) (acme.Challenge, error) {
+	type challengeDomains struct {
+		challenge acme.Challenge
+		domains   []string
+	}
+
+	var challenges []challengeDomains
+	if p.DNSChallenge != nil && len(p.DNSChallenge.Provider) > 0 {
+		challenges = append(challenges, challengeDomains{challenge: acme.DNS01, domains: p.DNSChallenge.Domains})
+	}
+	if p.HTTPChallenge != nil && len(p.HTTPChallenge.EntryPoint) > 0 {
+		challenges = append(challenges, challengeDomains{challenge: acme.HTTP01, domains: p.HTTPChallenge.Domains})
+	}
+	if p.TLSChallenge != nil {
+		challenges = append(challenges, challengeDomains{challenge: acme.TLSALPN01, domains: p.TLSChallenge.Domains})
+	}
+
+	if len(challenges) == 0 {
+		return "", errors.New("ACME challenge not specified, please select TLS or HTTP or DNS Challenge")
+	}
+
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			if challenges[0].challenge != acme.DNS01 {
+				return "", fmt.Errorf("unable to generate a wildcard certificate for the domains %v: ACME needs a DNSChallenge", domains)
+			}
+			return acme.DNS01, nil
+		}
+	}
+
+	var main string
+	if len(domains) > 0 {
+		main = domains[0]
+	}
+
+	var selected, fallback *challengeDomains
+	var selectedDomain string
+	for i, challenge := range challenges {
+		if len(challenge.domains) == 0 {
+			if fallback == nil {
+				fallback = &challenges[i]
+			}
+			continue
+		}
+
+		for _, domain := range challenge.domains {
+			if !traefiktls.MatchDomain(main, domain) {
+				continue
+			}
+
+			if selected == nil || isMoreSpecificDomain(domain, selectedDomain) {
+				selected = &challenges[i]
+				selectedDomain = domain
+			}
+		}
+	}
+
+	if selected != nil {
+		return selected.challenge, nil
+	}
+
+	if fallback != nil {
+		return fallback.challenge, nil
+	}
+
+	return challenges[0].challenge, nil
+}
+
+// isMoreSpecificDomain returns true if the domain is more specific than the other one:
+// an exact domain is more specific than a wildcard one, and the longest domain is the most specific.
+func isMoreSpecificDomain(domain, other string) bool {
+	wildcard := strings.HasPrefix(domain, "*.")
+	otherWildcard := strings.HasPrefix(other, "*.")
+	if wildcard != otherWildcard {
+		return otherWildcard
+	}
+
+	return len(domain) > len(other)
 }
 
 func (p *Provider) initAccount() (*Account, error) {
@@ -400,17 +513,30 @@ func (p *Provider) resolveCertificate(domain types.Domain, domainFromConfigurati
 
 	log.Debugf("Loading ACME certificates %+v...", uncheckedDomains)
 
-	client, err := p.getClient()
+	client, err := p.getClient(uncheckedDomains)
 	if err != nil {
 		return nil, fmt.Errorf("cannot get ACME client %v", err)
 	}
 
+	// Without a domain key type, the client generates a key of the global key type.
+	var privateKey crypto.PrivateKey
+	if len(domain.KeyType) > 0 {
+		var keyType acme.KeyType
+		keyType, err = parseKeyType(domain.KeyType)
+		if err == nil {
+			privateKey, err = generatePrivateKey(keyType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate a private key for the domains %v: %v", uncheckedDomains, err)
+		}
+	}
+
 	var certificate *acme.CertificateResource
 	bundle := true
 	if p.useCertificateWithRetry(uncheckedDomains) {
-		certificate, err = obtainCertificateWithRetry(domains, client, p.DNSChallenge.preCheckTimeout, p.DNSChallenge.preCheckInterval, bundle)
+		certificate, err = obtainCertificateWithRetry(domains, client, p.DNSChallenge.preCheckTimeout, p.DNSChallenge.preCheckInterval, bundle, privateKey)
 	} else {
-		certificate, err = client.ObtainCertificate(domains, bundle, nil, OSCPMustStaple)
+		certificate, err = client.ObtainCertificate(domains, bundle, privateKey, OSCPMustStaple)
 	}
 
 	if err != nil {
@@ -426,9 +552,9 @@ func (p *Provider) resolveCertificate(domain types.Domain, domainFromConfigurati
 	log.Debugf("Certificates obtained for domains %+v", uncheckedDomains)
 
 	if len(uncheckedDomains) > 1 {
-		domain = types.Domain{Main: uncheckedDomains[0], SANs: uncheckedDomains[1:]}
+		domain = types.Domain{Main: uncheckedDomains[0], SANs: uncheckedDomains[1:], KeyType: domain.KeyType}
 	} else {
-		domain = types.Domain{Main: uncheckedDomains[0]}
+		domain = types.Domain{Main: uncheckedDomains[0], KeyType: domain.KeyType}
 	}
 	p.addCertificateForDomain(domain, certificate.Certificate, certificate.PrivateKey)
 
@@ -479,12 +605,12 @@ func (p *Provider) useCertificateWithRetry(domains []string) bool {
 	return false
 }
 
-func obtainCertificateWithRetry(domains []string, client *acme.Client, timeout, interval time.Duration, bundle bool) (*acme.CertificateResource, error) {
+func obtainCertificateWithRetry(domains []string, client *acme.Client, timeout, interval time.Duration, bundle bool, privateKey crypto.PrivateKey) (*acme.CertificateResource, error) {
 	var certificate *acme.CertificateResource
 	var err error
 
 	operation := func() error {
-		certificate, err = client.ObtainCertificate(domains, bundle, nil, OSCPMustStaple)
+		certificate, err = client.ObtainCertificate(domains, bundle, privateKey, OSCPMustStaple)
 		return err
 	}
 
@@ -647,7 +773,7 @@ func (p *Provider) renewCertificates() {
 		// If there's an error, we assume the cert is broken, and needs update
 		// <= 30 days left, renew certificate
 		if err != nil || crt == nil || crt.NotAfter.Before(time.Now().Add(24*30*time.Hour)) {
-			client, err := p.getClient()
+			client, err := p.getClient(certificate.Domain.ToStrArray())
 			if err != nil {
 				log.Infof("Error renewing certificate from LE : %+v, %v", certificate.Domain, err)
 				continue
@@ -655,11 +781,31 @@ func (p *Provider) renewCertificates() {
 
 			log.Infof("Renewing certificate from LE : %+v", certificate.Domain)
 
-			renewedCert, err := client.RenewCertificate(acme.CertificateResource{
-				Domain:      certificate.Domain.Main,
-				PrivateKey:  certificate.Key,
-				Certificate: certificate.Certificate,
-			}, true, OSCPMustStaple)
+			domain := certificate.Domain
+			domain.KeyType = p.getDomainKeyType(certificate.Domain)
+
+			var renewedCert *acme.CertificateResource
+			if len(domain.KeyType) > 0 {
+				// A new key is generated, as the key type of the domain may have changed since the last renewal.
+				var keyType acme.KeyType
+				var privateKey crypto.PrivateKey
+				keyType, err = parseKeyType(domain.KeyType)
+				if err == nil {
+					privateKey, err = generatePrivateKey(keyType)
+				}
+				if err != nil {
+					log.Errorf("Error renewing certificate from LE: %v, %v", certificate.Domain, err)
+					continue
+				}
+
+				renewedCert, err = client.ObtainCertificate(domain.ToStrArray(), true, privateKey, OSCPMustStaple)
+			} else {
+				renewedCert, err = client.RenewCertificate(acme.CertificateResource{
+					Domain:      certificate.Domain.Main,
+					PrivateKey:  certificate.Key,
+					Certificate: certificate.Certificate,
+				}, true, OSCPMustStaple)
+			}
 
 			if err != nil {
 				log.Errorf("Error renewing certificate from LE: %v, %v", certificate.Domain, err)
@@ -671,9 +817,21 @@ func (p *Provider) renewCertificates() {
 				continue
 			}
 
-			p.addCertificateForDomain(certificate.Domain, renewedCert.Certificate, renewedCert.PrivateKey)
+			p.addCertificateForDomain(domain, renewedCert.Certificate, renewedCert.PrivateKey)
+		}
+	}
+}
+
+// getDomainKeyType returns the key type of the domain in the configuration,
+// or the key type the certificate was obtained with if the domain is not in the configuration anymore.
+func (p *Provider) getDomainKeyType(domain types.Domain) string {
+	for _, configDomain := range p.Domains {
+		if configDomain.Main == domain.Main {
+			return configDomain.KeyType
 		}
 	}
+
+	return domain.KeyType
 }
 
 // Get provided certificate which check a domains list (Main and SANs)
@@ -1,6 +1,9 @@
 package acme
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"crypto/tls"
 	"testing"
 
@@ -8,6 +11,7 @@ import (
 	traefiktls "github.com/containous/traefik/tls"
 	"github.com/containous/traefik/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xenolf/lego/acme"
 )
 
@@ -604,6 +608,100 @@ func TestUseBackOffToObtainCertificate(t *testing.T) {
 	}
 }
 
+func TestGetChallenge(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		domains           []string
+		dnsChallenge      *DNSChallenge
+		httpChallenge     *HTTPChallenge
+		tlsChallenge      *TLSChallenge
+		expectedChallenge acme.Challenge
+		expectedErr       bool
+	}{
+		{
+			desc:        "no challenge",
+			domains:     []string{"acme.wtf"},
+			expectedErr: true,
+		},
+		{
+			desc:              "single challenge",
+			domains:           []string{"acme.wtf"},
+			httpChallenge:     &HTTPChallenge{EntryPoint: "http"},
+			expectedChallenge: acme.HTTP01,
+		},
+		{
+			desc:              "challenge declaring the domain",
+			domains:           []string{"acme.wtf"},
+			dnsChallenge:      &DNSChallenge{Provider: "manual", Domains: []string{"*.acme.wtf"}},
+			httpChallenge:     &HTTPChallenge{EntryPoint: "http", Domains: []string{"acme.wtf"}},
+			expectedChallenge: acme.HTTP01,
+		},
+		{
+			desc:              "challenge declaring a wildcard domain",
+			domains:           []string{"foo.acme.wtf", "bar.acme.wtf"},
+			dnsChallenge:      &DNSChallenge{Provider: "manual", Domains: []string{"*.acme.wtf"}},
+			httpChallenge:     &HTTPChallenge{EntryPoint: "http", Domains: []string{"acme.wtf"}},
+			expectedChallenge: acme.DNS01,
+		},
+		{
+			desc:              "exact domain preferred to a wildcard domain",
+			domains:           []string{"foo.acme.wtf"},
+			dnsChallenge:      &DNSChallenge{Provider: "manual", Domains: []string{"*.acme.wtf"}},
+			tlsChallenge:      &TLSChallenge{Domains: []string{"foo.acme.wtf"}},
+			expectedChallenge: acme.TLSALPN01,
+		},
+		{
+			desc:              "fallback to the challenge without domains",
+			domains:           []string{"foo.bar"},
+			dnsChallenge:      &DNSChallenge{Provider: "manual", Domains: []string{"*.acme.wtf"}},
+			httpChallenge:     &HTTPChallenge{EntryPoint: "http"},
+			expectedChallenge: acme.HTTP01,
+		},
+		{
+			desc:              "fallback to the first challenge",
+			domains:           []string{"foo.bar"},
+			dnsChallenge:      &DNSChallenge{Provider: "manual", Domains: []string{"*.acme.wtf"}},
+			httpChallenge:     &HTTPChallenge{EntryPoint: "http", Domains: []string{"acme.wtf"}},
+			expectedChallenge: acme.DNS01,
+		},
+		{
+			desc:              "wildcard domain",
+			domains:           []string{"*.acme.wtf", "acme.wtf"},
+			dnsChallenge:      &DNSChallenge{Provider: "manual", Domains: []string{"foo.bar"}},
+			httpChallenge:     &HTTPChallenge{EntryPoint: "http", Domains: []string{"acme.wtf"}},
+			expectedChallenge: acme.DNS01,
+		},
+		{
+			desc:          "wildcard domain without DNS challenge",
+			domains:       []string{"*.acme.wtf"},
+			httpChallenge: &HTTPChallenge{EntryPoint: "http"},
+			expectedErr:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			acmeProvider := Provider{Configuration: &Configuration{
+				DNSChallenge:  test.dnsChallenge,
+				HTTPChallenge: test.httpChallenge,
+				TLSChallenge:  test.tlsChallenge,
+			}}
+
+			challenge, err := acmeProvider.getChallenge(test.domains)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedChallenge, challenge)
+		})
+	}
+}
+
 func TestInitAccount(t *testing.T) {
 	testCases := []struct {
 		desc            string
@@ -682,3 +780,189 @@ func TestInitAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestEABValidate(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		eab         *EAB
+		expectedErr bool
+	}{
+		{
+			desc: "valid",
+			eab:  &EAB{Kid: "kid", HmacEncoded: "c2VjcmV0"},
+		},
+		{
+			desc:        "no key identifier",
+			eab:         &EAB{HmacEncoded: "c2VjcmV0"},
+			expectedErr: true,
+		},
+		{
+			desc:        "no HMAC key",
+			eab:         &EAB{Kid: "kid"},
+			expectedErr: true,
+		},
+		{
+			desc:        "HMAC key not base64 URL encoded",
+			eab:         &EAB{Kid: "kid", HmacEncoded: "c2VjcmV0+/=="},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.eab.validate()
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetDomainKeyType(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		domains  []types.Domain
+		domain   types.Domain
+		expected string
+	}{
+		{
+			desc:     "domain not in the configuration",
+			domain:   types.Domain{Main: "legacy.traefik.wtf", KeyType: "RSA2048"},
+			expected: "RSA2048",
+		},
+		{
+			desc:     "domain in the configuration",
+			domains:  []types.Domain{{Main: "legacy.traefik.wtf", KeyType: "RSA4096"}},
+			domain:   types.Domain{Main: "legacy.traefik.wtf", KeyType: "RSA2048"},
+			expected: "RSA4096",
+		},
+		{
+			desc:     "domain key type removed from the configuration",
+			domains:  []types.Domain{{Main: "legacy.traefik.wtf"}},
+			domain:   types.Domain{Main: "legacy.traefik.wtf", KeyType: "RSA2048"},
+			expected: "",
+		},
+		{
+			desc:     "other domain in the configuration",
+			domains:  []types.Domain{{Main: "traefik.wtf", KeyType: "EC384"}},
+			domain:   types.Domain{Main: "legacy.traefik.wtf"},
+			expected: "",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			acmeProvider := Provider{Configuration: &Configuration{Domains: test.domains}}
+
+			assert.Equal(t, test.expected, acmeProvider.getDomainKeyType(test.domain))
+		})
+	}
+}
+
+func TestGeneratePrivateKey(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		keyType       acme.KeyType
+		expectedCurve elliptic.Curve
+		expectedBits  int
+		expectedError bool
+	}{
+		{
+			desc:          "EC256",
+			keyType:       acme.EC256,
+			expectedCurve: elliptic.P256(),
+		},
+		{
+			desc:          "EC384",
+			keyType:       acme.EC384,
+			expectedCurve: elliptic.P384(),
+		},
+		{
+			desc:         "RSA2048",
+			keyType:      acme.RSA2048,
+			expectedBits: 2048,
+		},
+		{
+			desc:          "invalid key type",
+			keyType:       acme.KeyType("DSA"),
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			privateKey, err := generatePrivateKey(test.keyType)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			switch key := privateKey.(type) {
+			case *ecdsa.PrivateKey:
+				assert.Equal(t, test.expectedCurve, key.Curve)
+			case *rsa.PrivateKey:
+				assert.Equal(t, test.expectedBits, key.N.BitLen())
+			default:
+				t.Fatalf("unexpected private key type %T", privateKey)
+			}
+		})
+	}
+}
+
+func TestParseKeyType(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		value         string
+		expected      acme.KeyType
+		expectedError bool
+	}{
+		{
+			desc:     "EC384",
+			value:    "EC384",
+			expected: acme.EC384,
+		},
+		{
+			desc:     "RSA2048",
+			value:    "RSA2048",
+			expected: acme.RSA2048,
+		},
+		{
+			desc:          "invalid key type",
+			value:         "RSA1024",
+			expectedError: true,
+		},
+		{
+			desc:          "lowercase key type",
+			value:         "ec256",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			keyType, err := parseKeyType(test.value)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, keyType)
+		})
+	}
+}
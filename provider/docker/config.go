@@ -191,8 +191,8 @@ func (p *Provider) getFrontendRule(container dockerData, segmentLabels map[strin
 		domain = "." + domain
 	}
 
-	if values, err := label.GetStringMultipleStrict(container.Labels, labelDockerComposeProject, labelDockerComposeService); err == nil {
-		return "Host:" + getSubDomain(values[labelDockerComposeService]+"."+values[labelDockerComposeProject]) + domain
+	if service, project, ok := getComposeServiceProject(container.Labels); ok {
+		return "Host:" + getSubDomain(service+"."+project) + domain
 	}
 
 	if len(domain) > 0 {
@@ -295,8 +295,8 @@ func getDefaultBackendName(container dockerData) string {
 func getServiceName(container dockerData) string {
 	serviceName := container.ServiceName
 
-	if values, err := label.GetStringMultipleStrict(container.Labels, labelDockerComposeProject, labelDockerComposeService); err == nil {
-		serviceName = values[labelDockerComposeService] + "_" + values[labelDockerComposeProject]
+	if service, project, ok := getComposeServiceProject(container.Labels); ok {
+		serviceName = service + "_" + project
 	}
 
 	return serviceName
@@ -343,6 +343,20 @@ func (p *Provider) getIPPort(container dockerData) (string, string, error) {
 	var ip, port string
 	usedBound := false
 
+	if p.Podman && isRootlessNetwork(container.NetworkSettings.NetworkMode) {
+		// Rootless containers are only reachable through their published ports on the host.
+		portBinding, err := p.getPortBinding(container)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to find a published port for the rootless container %q: the server is ignored", container.Name)
+		}
+
+		ip = portBinding.HostIP
+		if len(ip) == 0 || ip == "0.0.0.0" {
+			ip = "127.0.0.1"
+		}
+		return ip, portBinding.HostPort, nil
+	}
+
 	if p.UseBindPortIP {
 		portBinding, err := p.getPortBinding(container)
 		if err != nil {
@@ -1228,6 +1228,13 @@ func TestDockerGetFrontendRule(t *testing.T) {
 			})),
 			expected: "Host:bar.foo.docker.localhost",
 		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"io.podman.compose.project": "foo",
+				"io.podman.compose.service": "bar",
+			})),
+			expected: "Host:bar.foo.docker.localhost",
+		},
 		{
 			container: containerJSON(labels(map[string]string{
 				label.TraefikFrontendRule: "Path:/test",
@@ -1281,6 +1288,13 @@ func TestDockerGetBackendName(t *testing.T) {
 			})),
 			expected: "bar-foo",
 		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"io.podman.compose.project": "foo",
+				"io.podman.compose.service": "bar",
+			})),
+			expected: "bar-foo",
+		},
 		{
 			container: containerJSON(labels(map[string]string{
 				"com.docker.compose.project": "foo",
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/cenk/backoff"
+	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/job"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/provider"
@@ -28,6 +30,8 @@ import (
 )
 
 const (
+	// DefaultEndpoint is the default Docker daemon endpoint
+	DefaultEndpoint = "unix:///var/run/docker.sock"
 	// SwarmAPIVersion is a constant holding the version of the Provider API traefik will use
 	SwarmAPIVersion = "1.24"
 	// SwarmDefaultWatchTime is the duration of the interval when polling docker
@@ -46,10 +50,28 @@ type Provider struct {
 	UseBindPortIP         bool             `description:"Use the ip address from the bound port, rather than from the inner network" export:"true"`
 	SwarmMode             bool             `description:"Use Docker on Swarm Mode" export:"true"`
 	Network               string           `description:"Default Docker network used" export:"true"`
+	Podman                bool             `description:"Use the Podman socket instead of the Docker daemon" export:"true"`
+	RefreshSeconds        int              `description:"Polling interval for swarm mode (in seconds)" export:"true"`
+	WatchSwarmConfigs     bool             `description:"Watch the updates of the swarm services, and of the configs and secrets they reference" export:"true"`
+	Debounce              parse.Duration   `description:"Wait for the swarm events to stop for this duration before reloading the configuration" export:"true"`
 }
 
 // Init the provider
 func (p *Provider) Init(constraints types.Constraints) error {
+	if p.Podman {
+		if p.SwarmMode {
+			return errors.New("swarm mode is not supported by Podman")
+		}
+
+		if len(p.Endpoint) == 0 || p.Endpoint == DefaultEndpoint {
+			p.Endpoint = podmanEndpoint()
+		}
+	}
+
+	if p.WatchSwarmConfigs && !p.SwarmMode {
+		return errors.New("watching the swarm configs requires the swarm mode")
+	}
+
 	return p.BaseProvider.Init(constraints)
 }
 
@@ -111,8 +133,12 @@ func (p *Provider) createClient() (client.APIClient, error) {
 	}
 
 	var apiVersion string
-	if p.SwarmMode {
+	if p.SwarmMode && p.WatchSwarmConfigs {
+		apiVersion = SwarmEventsAPIVersion
+	} else if p.SwarmMode {
 		apiVersion = SwarmAPIVersion
+	} else if p.Podman {
+		apiVersion = PodmanAPIVersion
 	} else {
 		apiVersion = DockerAPIVersion
 	}
@@ -162,80 +188,49 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 			}
 			if p.Watch {
 				if p.SwarmMode {
-					errChan := make(chan error)
-					// TODO: This need to be change. Linked to Swarm events docker/docker#23827
-					ticker := time.NewTicker(SwarmDefaultWatchTime)
-					pool.GoCtx(func(ctx context.Context) {
-						defer close(errChan)
-						for {
-							select {
-							case <-ticker.C:
-								services, err := listServices(ctx, dockerClient)
-								if err != nil {
-									log.Errorf("Failed to list services for docker, error %s", err)
-									errChan <- err
-									return
-								}
-								configuration := p.buildConfiguration(services)
-								if configuration != nil {
-									configurationChan <- types.ConfigMessage{
-										ProviderName:  "docker",
-										Configuration: configuration,
-									}
-								}
-
-							case <-ctx.Done():
-								ticker.Stop()
-								return
-							}
-						}
-					})
-					if err, ok := <-errChan; ok {
-						return err
-					}
-					// channel closed
+					return p.watchSwarm(ctx, dockerClient, configurationChan)
+				}
 
-				} else {
-					f := filters.NewArgs()
-					f.Add("type", "container")
-					options := dockertypes.EventsOptions{
-						Filters: f,
-					}
+				f := filters.NewArgs()
+				f.Add("type", "container")
+				options := dockertypes.EventsOptions{
+					Filters: f,
+				}
 
-					startStopHandle := func(m eventtypes.Message) {
-						log.Debugf("Provider event received %+v", m)
-						containers, err := listContainers(ctx, dockerClient)
-						if err != nil {
-							log.Errorf("Failed to list containers for docker, error %s", err)
-							// Call cancel to get out of the monitor
-							return
-						}
-						configuration := p.buildConfiguration(containers)
-						if configuration != nil {
-							configurationChan <- types.ConfigMessage{
-								ProviderName:  "docker",
-								Configuration: configuration,
-							}
+				startStopHandle := func(m eventtypes.Message) {
+					log.Debugf("Provider event received %+v", m)
+					containers, err := listContainers(ctx, dockerClient)
+					if err != nil {
+						log.Errorf("Failed to list containers for docker, error %s", err)
+						// Call cancel to get out of the monitor
+						return
+					}
+					configuration := p.buildConfiguration(containers)
+					if configuration != nil {
+						configurationChan <- types.ConfigMessage{
+							ProviderName:  "docker",
+							Configuration: configuration,
 						}
 					}
+				}
 
-					eventsc, errc := dockerClient.Events(ctx, options)
-					for {
-						select {
-						case event := <-eventsc:
-							if event.Action == "start" ||
-								event.Action == "die" ||
-								strings.HasPrefix(event.Action, "health_status") {
-								startStopHandle(event)
-							}
-						case err := <-errc:
-							if err == io.EOF {
-								log.Debug("Provider event stream closed")
-							}
-							return err
-						case <-ctx.Done():
-							return nil
+				eventsc, errc := dockerClient.Events(ctx, options)
+				for {
+					select {
+					case event := <-eventsc:
+						if event.Action == "start" ||
+							event.Action == "die" ||
+							p.Podman && isPodmanEvent(event.Action) ||
+							strings.HasPrefix(event.Action, "health_status") {
+							startStopHandle(event)
 						}
+					case err := <-errc:
+						if err == io.EOF {
+							log.Debug("Provider event stream closed")
+						}
+						return err
+					case <-ctx.Done():
+						return nil
 					}
 				}
 			}
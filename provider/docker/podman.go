@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containous/traefik/provider/label"
+	dockercontainertypes "github.com/docker/docker/api/types/container"
+)
+
+const (
+	// PodmanAPIVersion is the minimal Docker API version served by the Podman compatibility socket
+	PodmanAPIVersion = "1.24"
+	// PodmanDefaultEndpoint is the endpoint of the rootful Podman socket
+	PodmanDefaultEndpoint = "unix:///run/podman/podman.sock"
+
+	labelPodmanComposeProject = "io.podman.compose.project"
+	labelPodmanComposeService = "io.podman.compose.service"
+)
+
+// podmanEndpoint returns the Podman socket endpoint of the current user.
+// Rootless Podman serves its socket from the user runtime directory.
+func podmanEndpoint() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); len(runtimeDir) > 0 && os.Geteuid() != 0 {
+		return "unix://" + filepath.Join(runtimeDir, "podman", "podman.sock")
+	}
+
+	return PodmanDefaultEndpoint
+}
+
+// isPodmanEvent returns true for the Podman native "died" action,
+// which is the counterpart of the Docker "die" action.
+func isPodmanEvent(action string) bool {
+	return action == "died"
+}
+
+// isRootlessNetwork returns true if the container uses the user mode networking of rootless Podman.
+// Such containers do not get an IP address reachable from the host, only their published ports are.
+func isRootlessNetwork(mode dockercontainertypes.NetworkMode) bool {
+	for _, name := range []string{"slirp4netns", "pasta"} {
+		if string(mode) == name || strings.HasPrefix(string(mode), name+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// getComposeServiceProject returns the compose service and project of the container,
+// looking at the Docker Compose labels first and then at the podman-compose ones.
+func getComposeServiceProject(labels map[string]string) (string, string, bool) {
+	if values, err := label.GetStringMultipleStrict(labels, labelDockerComposeProject, labelDockerComposeService); err == nil {
+		return values[labelDockerComposeService], values[labelDockerComposeProject], true
+	}
+
+	if values, err := label.GetStringMultipleStrict(labels, labelPodmanComposeProject, labelPodmanComposeService); err == nil {
+		return values[labelPodmanComposeService], values[labelPodmanComposeProject], true
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/provider/label"
+	"github.com/containous/traefik/types"
+	docker "github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPodmanInit(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		provider         *Provider
+		expectedEndpoint string
+		expectsError     bool
+	}{
+		{
+			desc:             "custom endpoint is kept",
+			provider:         &Provider{Podman: true, Endpoint: "tcp://127.0.0.1:8080"},
+			expectedEndpoint: "tcp://127.0.0.1:8080",
+		},
+		{
+			desc:             "docker default endpoint is replaced by the podman one",
+			provider:         &Provider{Podman: true, Endpoint: DefaultEndpoint},
+			expectedEndpoint: podmanEndpoint(),
+		},
+		{
+			desc:             "docker endpoint is kept without podman",
+			provider:         &Provider{Endpoint: DefaultEndpoint},
+			expectedEndpoint: DefaultEndpoint,
+		},
+		{
+			desc:         "swarm mode is rejected",
+			provider:     &Provider{Podman: true, SwarmMode: true, Endpoint: DefaultEndpoint},
+			expectsError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.provider.Init(types.Constraints{})
+			if test.expectsError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedEndpoint, test.provider.Endpoint)
+		})
+	}
+}
+
+func TestPodmanGetIPPort(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		container    docker.ContainerJSON
+		ip, port     string
+		expectsError bool
+	}{
+		{
+			desc: "bridge network uses the container IP",
+			container: containerJSON(
+				networkMode("bridge"),
+				ports(nat.PortMap{
+					"80/tcp": []nat.PortBinding{{HostPort: "8080"}},
+				}),
+				withNetwork("podman", ipv4("10.88.0.2"))),
+			ip:   "10.88.0.2",
+			port: "80",
+		},
+		{
+			desc: "slirp4netns uses the published port on the loopback",
+			container: containerJSON(
+				networkMode("slirp4netns"),
+				ports(nat.PortMap{
+					"80/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "8080"}},
+				})),
+			ip:   "127.0.0.1",
+			port: "8080",
+		},
+		{
+			desc: "pasta with options uses the published host IP",
+			container: containerJSON(
+				networkMode("pasta:--ipv4-only"),
+				ports(nat.PortMap{
+					"80/tcp": []nat.PortBinding{{HostIP: "192.168.1.10", HostPort: "8080"}},
+				})),
+			ip:   "192.168.1.10",
+			port: "8080",
+		},
+		{
+			desc: "slirp4netns without published port is ignored",
+			container: containerJSON(
+				networkMode("slirp4netns"),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				})),
+			expectsError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			dData := parseContainer(test.container)
+			segmentProperties := label.ExtractTraefikLabels(dData.Labels)
+			dData.SegmentLabels = segmentProperties[""]
+
+			provider := &Provider{Podman: true}
+
+			actualIP, actualPort, actualError := provider.getIPPort(dData)
+			if test.expectsError {
+				require.Error(t, actualError)
+			} else {
+				require.NoError(t, actualError)
+			}
+			assert.Equal(t, test.ip, actualIP)
+			assert.Equal(t, test.port, actualPort)
+		})
+	}
+}
@@ -2,13 +2,18 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/types"
 	"github.com/davecgh/go-spew/spew"
 	docker "github.com/docker/docker/api/types"
 	dockertypes "github.com/docker/docker/api/types"
+	eventtypes "github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/swarm"
 	dockerclient "github.com/docker/docker/client"
 	"github.com/stretchr/testify/assert"
@@ -395,3 +400,155 @@ func TestSwarmTaskParsing(t *testing.T) {
 		})
 	}
 }
+
+type fakeSwarmEventsClient struct {
+	fakeServicesClient
+	events        chan eventtypes.Message
+	errs          chan error
+	subscriptions int32
+}
+
+func (c *fakeSwarmEventsClient) Events(ctx context.Context, options dockertypes.EventsOptions) (<-chan eventtypes.Message, <-chan error) {
+	atomic.AddInt32(&c.subscriptions, 1)
+	return c.events, c.errs
+}
+
+func withContainerSpec(spec *swarm.ContainerSpec) func(*swarm.Service) {
+	return func(service *swarm.Service) {
+		service.Spec.TaskTemplate.ContainerSpec = spec
+	}
+}
+
+func TestIsSwarmReference(t *testing.T) {
+	containerSpec := &swarm.ContainerSpec{
+		Configs: []*swarm.ConfigReference{{ConfigID: "config1"}},
+		Secrets: []*swarm.SecretReference{{SecretID: "secret1"}},
+	}
+
+	testCases := []struct {
+		desc     string
+		service  swarm.Service
+		id       string
+		expected bool
+	}{
+		{
+			desc:     "referenced config",
+			service:  swarmService(serviceLabels(map[string]string{labelDockerNetwork: "barnet"}), withContainerSpec(containerSpec)),
+			id:       "config1",
+			expected: true,
+		},
+		{
+			desc:     "referenced secret",
+			service:  swarmService(serviceLabels(map[string]string{labelDockerNetwork: "barnet"}), withContainerSpec(containerSpec)),
+			id:       "secret1",
+			expected: true,
+		},
+		{
+			desc:    "not referenced",
+			service: swarmService(serviceLabels(map[string]string{labelDockerNetwork: "barnet"}), withContainerSpec(containerSpec)),
+			id:      "config2",
+		},
+		{
+			desc:    "service without Traefik labels",
+			service: swarmService(serviceLabels(map[string]string{"com.example.foo": "bar"}), withContainerSpec(containerSpec)),
+			id:      "config1",
+		},
+		{
+			desc:    "service without container",
+			service: swarmService(serviceLabels(map[string]string{labelDockerNetwork: "barnet"})),
+			id:      "config1",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, isSwarmReference([]swarm.Service{test.service}, test.id))
+		})
+	}
+}
+
+func TestWatchSwarmEvents(t *testing.T) {
+	dockerClient := &fakeSwarmEventsClient{
+		fakeServicesClient: fakeServicesClient{
+			dockerVersion: "1.30",
+			services: []swarm.Service{
+				swarmService(
+					serviceLabels(map[string]string{labelDockerNetwork: "barnet"}),
+					withContainerSpec(&swarm.ContainerSpec{Configs: []*swarm.ConfigReference{{ConfigID: "config1"}}}),
+				),
+			},
+		},
+		events: make(chan eventtypes.Message),
+		errs:   make(chan error),
+	}
+
+	provider := &Provider{SwarmMode: true, WatchSwarmConfigs: true, RefreshSeconds: 3600}
+	configurationChan := make(chan types.ConfigMessage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- provider.watchSwarm(ctx, dockerClient, configurationChan)
+	}()
+
+	dockerClient.events <- eventtypes.Message{Type: eventtypes.ConfigEventType, Action: "update", Actor: eventtypes.Actor{ID: "config1"}}
+	select {
+	case <-configurationChan:
+	case <-time.After(time.Second):
+		t.Fatal("timeout while waiting for config")
+	}
+
+	// The configs which aren't referenced don't trigger a reload.
+	dockerClient.events <- eventtypes.Message{Type: eventtypes.ConfigEventType, Action: "update", Actor: eventtypes.Actor{ID: "config2"}}
+	select {
+	case <-configurationChan:
+		t.Fatal("unexpected config")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The stream errors don't stop the watch.
+	dockerClient.errs <- errors.New("stream closed")
+	select {
+	case err := <-done:
+		t.Fatalf("unexpected end of the watch: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dockerClient.subscriptions))
+}
+
+func TestWatchSwarmDebounce(t *testing.T) {
+	dockerClient := &fakeSwarmEventsClient{
+		fakeServicesClient: fakeServicesClient{dockerVersion: "1.30"},
+		events:             make(chan eventtypes.Message),
+		errs:               make(chan error),
+	}
+
+	provider := &Provider{SwarmMode: true, WatchSwarmConfigs: true, RefreshSeconds: 3600, Debounce: parse.Duration(100 * time.Millisecond)}
+	configurationChan := make(chan types.ConfigMessage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go provider.watchSwarm(ctx, dockerClient, configurationChan)
+
+	for i := 0; i < 3; i++ {
+		dockerClient.events <- eventtypes.Message{Type: eventtypes.ServiceEventType, Action: "update", Actor: eventtypes.Actor{ID: "serviceID"}}
+	}
+
+	select {
+	case <-configurationChan:
+	case <-time.After(time.Second):
+		t.Fatal("timeout while waiting for config")
+	}
+
+	select {
+	case <-configurationChan:
+		t.Fatal("the burst of events must produce a single reload")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
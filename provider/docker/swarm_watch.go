@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/provider/label"
+	"github.com/containous/traefik/types"
+	dockertypes "github.com/docker/docker/api/types"
+	eventtypes "github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	swarmtypes "github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+)
+
+// SwarmEventsAPIVersion is the version of the Docker API used to receive the events of the services, configs and secrets.
+const SwarmEventsAPIVersion = "1.30"
+
+// watchSwarm polls the swarm services, and, when watching the swarm events, reloads the configuration
+// after the updates of the services and of the configs and secrets they reference.
+// The event stream only speeds up the polling: its errors don't stop the watch,
+// and it is subscribed again at the next poll.
+func (p *Provider) watchSwarm(ctx context.Context, dockerClient client.APIClient, configurationChan chan<- types.ConfigMessage) error {
+	refresh := SwarmDefaultWatchTime
+	if p.RefreshSeconds > 0 {
+		refresh = time.Duration(p.RefreshSeconds) * time.Second
+	}
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	var eventsc <-chan eventtypes.Message
+	var errc <-chan error
+	subscribe := func() {
+		if p.WatchSwarmConfigs {
+			eventsc, errc = dockerClient.Events(ctx, dockertypes.EventsOptions{Filters: swarmEventsFilters()})
+		}
+	}
+	subscribe()
+
+	var debounce *time.Timer
+	var debounceC <-chan time.Time
+	defer func() {
+		if debounce != nil {
+			debounce.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := p.reloadSwarm(ctx, dockerClient, configurationChan); err != nil {
+				return err
+			}
+			if eventsc == nil {
+				subscribe()
+			}
+		case event := <-eventsc:
+			if !isSwarmUpdate(ctx, dockerClient, event) {
+				continue
+			}
+
+			log.Debugf("Provider event received %+v", event)
+			if p.Debounce <= 0 {
+				if err := p.reloadSwarm(ctx, dockerClient, configurationChan); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if debounce != nil {
+				debounce.Stop()
+			}
+			debounce = time.NewTimer(time.Duration(p.Debounce))
+			debounceC = debounce.C
+		case <-debounceC:
+			debounceC = nil
+			if err := p.reloadSwarm(ctx, dockerClient, configurationChan); err != nil {
+				return err
+			}
+		case err := <-errc:
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Warnf("Swarm event stream error, polling the services until it is subscribed again: %v", err)
+			eventsc, errc = nil, nil
+		}
+	}
+}
+
+func (p *Provider) reloadSwarm(ctx context.Context, dockerClient client.APIClient, configurationChan chan<- types.ConfigMessage) error {
+	services, err := listServices(ctx, dockerClient)
+	if err != nil {
+		log.Errorf("Failed to list services for docker, error %s", err)
+		return err
+	}
+
+	configuration := p.buildConfiguration(services)
+	if configuration != nil {
+		configurationChan <- types.ConfigMessage{
+			ProviderName:  "docker",
+			Configuration: configuration,
+		}
+	}
+	return nil
+}
+
+func swarmEventsFilters() filters.Args {
+	f := filters.NewArgs()
+	f.Add("type", eventtypes.ServiceEventType)
+	f.Add("type", eventtypes.ConfigEventType)
+	f.Add("type", eventtypes.SecretEventType)
+	return f
+}
+
+// isSwarmUpdate returns whether the event updates a service, or a config or a secret referenced by a labeled service.
+func isSwarmUpdate(ctx context.Context, dockerClient client.APIClient, event eventtypes.Message) bool {
+	switch event.Type {
+	case eventtypes.ServiceEventType:
+		return true
+	case eventtypes.ConfigEventType, eventtypes.SecretEventType:
+		services, err := dockerClient.ServiceList(ctx, dockertypes.ServiceListOptions{})
+		if err != nil {
+			log.Debugf("Failed to list services for docker, error %s", err)
+			return true
+		}
+		return isSwarmReference(services, event.Actor.ID)
+	default:
+		return false
+	}
+}
+
+// isSwarmReference returns whether a config or a secret is referenced by a service with Traefik labels.
+func isSwarmReference(services []swarmtypes.Service, id string) bool {
+	for _, service := range services {
+		containerSpec := service.Spec.TaskTemplate.ContainerSpec
+		if containerSpec == nil || !hasTraefikLabel(service.Spec.Labels) {
+			continue
+		}
+
+		for _, config := range containerSpec.Configs {
+			if config != nil && config.ConfigID == id {
+				return true
+			}
+		}
+
+		for _, secret := range containerSpec.Secrets {
+			if secret != nil && secret.SecretID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasTraefikLabel(labels map[string]string) bool {
+	for key := range labels {
+		if strings.HasPrefix(key, label.Prefix) {
+			return true
+		}
+	}
+	return false
+}
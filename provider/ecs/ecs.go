@@ -2,7 +2,10 @@ package ecs
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -37,6 +40,8 @@ type Provider struct {
 	Region               string   `description:"The AWS region to use for requests" export:"true"`
 	AccessKeyID          string   `description:"The AWS credentials access key to use for making requests"`
 	SecretAccessKey      string   `description:"The AWS credentials access key to use for making requests"`
+	Tags                 bool     `description:"Read the configuration from the ECS tags of the tasks and services" export:"true"`
+	TagsOverLabels       bool     `description:"Give precedence to the ECS tags over the Docker labels" export:"true"`
 }
 
 type ecsInstance struct {
@@ -76,14 +81,12 @@ func (p *Provider) createClient() (*awsClient, error) {
 		return nil, err
 	}
 
-	ec2meta := ec2metadata.New(sess)
 	if p.Region == "" {
-		log.Infoln("No EC2 region provided, querying instance metadata endpoint...")
-		identity, err := ec2meta.GetInstanceIdentityDocument()
+		region, err := detectRegion(sess)
 		if err != nil {
 			return nil, err
 		}
-		p.Region = identity.Region
+		p.Region = region
 	}
 
 	cfg := &aws.Config{
@@ -114,6 +117,68 @@ func (p *Provider) createClient() (*awsClient, error) {
 	}, nil
 }
 
+// detectRegion returns the region of the environment, of the ECS task metadata in Fargate,
+// or else of the EC2 instance metadata.
+func detectRegion(sess *session.Session) (string, error) {
+	if region := aws.StringValue(sess.Config.Region); len(region) > 0 {
+		return region, nil
+	}
+
+	if uri := taskMetadataURI(); len(uri) > 0 {
+		log.Infoln("No region provided, querying the task metadata endpoint...")
+		region, err := taskMetadataRegion(uri)
+		if err == nil {
+			return region, nil
+		}
+		log.Warnf("Unable to get the region from the task metadata endpoint: %v", err)
+	}
+
+	log.Infoln("No EC2 region provided, querying instance metadata endpoint...")
+	identity, err := ec2metadata.New(sess).GetInstanceIdentityDocument()
+	if err != nil {
+		return "", err
+	}
+	return identity.Region, nil
+}
+
+// taskMetadataRegion returns the region of the cluster of the task, from the ECS task metadata endpoint,
+// which is available in Fargate, where the EC2 instance metadata is not.
+func taskMetadataRegion(metadataURI string) (string, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	resp, err := client.Get(strings.TrimSuffix(metadataURI, "/") + "/task")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from the task metadata endpoint", resp.StatusCode)
+	}
+
+	var metadata struct {
+		Cluster string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return "", err
+	}
+
+	// The cluster ARN is arn:aws:ecs:<region>:<account>:cluster/<name>.
+	parts := strings.Split(metadata.Cluster, ":")
+	if len(parts) < 6 || parts[0] != "arn" || len(parts[3]) == 0 {
+		return "", fmt.Errorf("no region in the cluster ARN %q", metadata.Cluster)
+	}
+	return parts[3], nil
+}
+
+// taskMetadataURI returns the URI of the ECS task metadata endpoint, when running in an ECS task.
+func taskMetadataURI() string {
+	if uri := os.Getenv("ECS_CONTAINER_METADATA_URI_V4"); len(uri) > 0 {
+		return uri
+	}
+	return os.Getenv("ECS_CONTAINER_METADATA_URI")
+}
+
 // Provide allows the ecs provider to provide configurations to traefik
 // using the given configuration channel.
 func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
@@ -262,7 +327,24 @@ func (p *Provider) listInstances(ctx context.Context, client *awsClient) ([]ecsI
 			return nil, err
 		}
 
+		var taskTags, serviceTags map[string]map[string]string
+		if p.Tags {
+			taskTags, err = p.lookupTaskTags(ctx, client, &c, tasks)
+			if err != nil {
+				return nil, err
+			}
+
+			serviceTags, err = p.lookupServiceTags(ctx, client, &c, tasks)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		for key, task := range tasks {
+			if aws.StringValue(task.LastStatus) != ecs.DesiredStatusRunning {
+				log.Debugf("Skipping task %s in the %s state", key, aws.StringValue(task.LastStatus))
+				continue
+			}
 
 			containerInstance := ec2Instances[aws.StringValue(task.ContainerInstanceArn)]
 			taskDef := taskDefinitions[key]
@@ -283,22 +365,14 @@ func (p *Provider) listInstances(ctx context.Context, client *awsClient) ([]ecsI
 				}
 
 				var mach *machine
-				if aws.StringValue(task.LaunchType) == ecs.LaunchTypeFargate {
-					var ports []portMapping
-					for _, mapping := range containerDefinition.PortMappings {
-						if mapping != nil {
-							ports = append(ports, portMapping{
-								hostPort:      aws.Int64Value(mapping.HostPort),
-								containerPort: aws.Int64Value(mapping.ContainerPort),
-							})
-						}
-					}
-					mach = &machine{
-						privateIP: aws.StringValue(container.NetworkInterfaces[0].PrivateIpv4Address),
-						ports:     ports,
-						state:     aws.StringValue(task.LastStatus),
-					}
+				if aws.StringValue(task.LaunchType) == ecs.LaunchTypeFargate || aws.StringValue(taskDef.NetworkMode) == ecs.NetworkModeAwsvpc {
+					mach = awsvpcMachine(task, container, containerDefinition)
 				} else {
+					if containerInstance == nil {
+						log.Debugf("Unable to find container instance for %s", aws.StringValue(container.Name))
+						continue
+					}
+
 					var ports []portMapping
 					for _, mapping := range container.NetworkBindings {
 						if mapping != nil {
@@ -315,12 +389,17 @@ func (p *Provider) listInstances(ctx context.Context, client *awsClient) ([]ecsI
 					}
 				}
 
+				traefikLabels := aws.StringValueMap(containerDefinition.DockerLabels)
+				if p.Tags {
+					traefikLabels = mergeLabels(traefikLabels, taskTags[key], serviceTags[serviceName(task)], p.TagsOverLabels)
+				}
+
 				instances = append(instances, ecsInstance{
 					Name:                fmt.Sprintf("%s-%s", strings.Replace(aws.StringValue(task.Group), ":", "-", 1), *container.Name),
 					ID:                  key[len(key)-12:],
 					containerDefinition: containerDefinition,
 					machine:             mach,
-					TraefikLabels:       aws.StringValueMap(containerDefinition.DockerLabels),
+					TraefikLabels:       traefikLabels,
 				})
 			}
 		}
@@ -329,6 +408,53 @@ func (p *Provider) listInstances(ctx context.Context, client *awsClient) ([]ecsI
 	return instances, nil
 }
 
+// awsvpcMachine returns the machine of a container in the awsvpc network mode, used by Fargate:
+// the container has the private IP of the network interface of its task, and its ports are not mapped.
+func awsvpcMachine(task *ecs.Task, container *ecs.Container, containerDefinition *ecs.ContainerDefinition) *machine {
+	var ports []portMapping
+	for _, mapping := range containerDefinition.PortMappings {
+		if mapping == nil {
+			continue
+		}
+
+		hostPort := aws.Int64Value(mapping.HostPort)
+		if hostPort == 0 {
+			hostPort = aws.Int64Value(mapping.ContainerPort)
+		}
+		ports = append(ports, portMapping{
+			hostPort:      hostPort,
+			containerPort: aws.Int64Value(mapping.ContainerPort),
+		})
+	}
+
+	var privateIP string
+	for _, networkInterface := range container.NetworkInterfaces {
+		if ip := aws.StringValue(networkInterface.PrivateIpv4Address); len(ip) > 0 {
+			privateIP = ip
+			break
+		}
+	}
+
+	if len(privateIP) == 0 {
+		for _, attachment := range task.Attachments {
+			if aws.StringValue(attachment.Type) != "ElasticNetworkInterface" {
+				continue
+			}
+			for _, detail := range attachment.Details {
+				if aws.StringValue(detail.Name) == "privateIPv4Address" {
+					privateIP = aws.StringValue(detail.Value)
+				}
+			}
+		}
+	}
+
+	return &machine{
+		privateIP: privateIP,
+		ports:     ports,
+		state:     aws.StringValue(task.LastStatus),
+	}
+}
+
 func (p *Provider) lookupEc2Instances(ctx context.Context, client *awsClient, clusterName *string, ecsDatas map[string]*ecs.Task) (map[string]*ec2.Instance, error) {
 
 	instanceIds := make(map[string]string)
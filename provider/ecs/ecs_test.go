@@ -1,10 +1,19 @@
 package ecs
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestChunkIDs(t *testing.T) {
@@ -86,3 +95,234 @@ func TestChunkIDs(t *testing.T) {
 		})
 	}
 }
+
+func TestAwsvpcMachine(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		task            *ecs.Task
+		container       *ecs.Container
+		expectedMachine *machine
+	}{
+		{
+			desc: "private IP of the container network interface",
+			task: &ecs.Task{LastStatus: aws.String(ecs.DesiredStatusRunning)},
+			container: &ecs.Container{
+				NetworkInterfaces: []*ecs.NetworkInterface{{PrivateIpv4Address: aws.String("10.0.0.4")}},
+			},
+			expectedMachine: &machine{
+				state:     ecs.DesiredStatusRunning,
+				privateIP: "10.0.0.4",
+				ports:     []portMapping{{containerPort: 80, hostPort: 80}, {containerPort: 8080, hostPort: 8080}},
+			},
+		},
+		{
+			desc: "private IP of the task attachment",
+			task: &ecs.Task{
+				LastStatus: aws.String(ecs.DesiredStatusRunning),
+				Attachments: []*ecs.Attachment{{
+					Type: aws.String("ElasticNetworkInterface"),
+					Details: []*ecs.KeyValuePair{
+						{Name: aws.String("subnetId"), Value: aws.String("subnet-1234")},
+						{Name: aws.String("privateIPv4Address"), Value: aws.String("10.0.0.5")},
+					},
+				}},
+			},
+			container: &ecs.Container{},
+			expectedMachine: &machine{
+				state:     ecs.DesiredStatusRunning,
+				privateIP: "10.0.0.5",
+				ports:     []portMapping{{containerPort: 80, hostPort: 80}, {containerPort: 8080, hostPort: 8080}},
+			},
+		},
+		{
+			desc:      "without network interface",
+			task:      &ecs.Task{LastStatus: aws.String("PROVISIONING")},
+			container: &ecs.Container{},
+			expectedMachine: &machine{
+				state: "PROVISIONING",
+				ports: []portMapping{{containerPort: 80, hostPort: 80}, {containerPort: 8080, hostPort: 8080}},
+			},
+		},
+	}
+
+	containerDefinition := &ecs.ContainerDefinition{
+		PortMappings: []*ecs.PortMapping{
+			{ContainerPort: aws.Int64(80)},
+			{ContainerPort: aws.Int64(8080), HostPort: aws.Int64(8080)},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expectedMachine, awsvpcMachine(test.task, test.container, containerDefinition))
+		})
+	}
+}
+
+func TestMergeLabels(t *testing.T) {
+	labels := map[string]string{"traefik.port": "80", "traefik.frontend.rule": "Host:label.example.com"}
+	taskTags := map[string]string{"traefik.frontend.rule": "Host:task.example.com", "traefik.weight": "5"}
+	serviceTags := map[string]string{"traefik.frontend.rule": "Host:service.example.com", "traefik.weight": "1", "traefik.protocol": "https"}
+
+	testCases := []struct {
+		desc      string
+		tagsFirst bool
+		expected  map[string]string
+	}{
+		{
+			desc: "labels first",
+			expected: map[string]string{
+				"traefik.port":          "80",
+				"traefik.frontend.rule": "Host:label.example.com",
+				"traefik.weight":        "5",
+				"traefik.protocol":      "https",
+			},
+		},
+		{
+			desc:      "tags first",
+			tagsFirst: true,
+			expected: map[string]string{
+				"traefik.port":          "80",
+				"traefik.frontend.rule": "Host:task.example.com",
+				"traefik.weight":        "5",
+				"traefik.protocol":      "https",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, mergeLabels(labels, taskTags, serviceTags, test.tagsFirst))
+		})
+	}
+}
+
+func newTestAWSClient(t *testing.T, url string) *awsClient {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:    aws.String(url),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	require.NoError(t, err)
+
+	return &awsClient{ecs: ecs.New(sess)}
+}
+
+func TestLookupTaskTags(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		assert.Equal(t, "AmazonEC2ContainerServiceV20141113.DescribeTasks", req.Header.Get("X-Amz-Target"))
+
+		var input struct {
+			Cluster string   `json:"cluster"`
+			Include []string `json:"include"`
+			Tasks   []string `json:"tasks"`
+		}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&input))
+		assert.Equal(t, "cluster", input.Cluster)
+		assert.Equal(t, []string{"TAGS"}, input.Include)
+		assert.Len(t, input.Tasks, 2)
+
+		rw.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprint(rw, `{"tasks":[
+			{"taskArn":"arn:aws:ecs:us-east-1:123456789012:task/1","tags":[{"key":"traefik.frontend.rule","value":"Host:foo.bar"},{"key":"team","value":"api"}]},
+			{"taskArn":"arn:aws:ecs:us-east-1:123456789012:task/2"}
+		]}`)
+	}))
+	defer ts.Close()
+
+	tasks := map[string]*ecs.Task{
+		"arn:aws:ecs:us-east-1:123456789012:task/1": {},
+		"arn:aws:ecs:us-east-1:123456789012:task/2": {},
+	}
+
+	provider := &Provider{}
+	tags, err := provider.lookupTaskTags(context.Background(), newTestAWSClient(t, ts.URL), aws.String("cluster"), tasks)
+	require.NoError(t, err)
+
+	expected := map[string]map[string]string{
+		"arn:aws:ecs:us-east-1:123456789012:task/1": {"traefik.frontend.rule": "Host:foo.bar"},
+		"arn:aws:ecs:us-east-1:123456789012:task/2": {},
+	}
+	assert.Equal(t, expected, tags)
+	assert.Equal(t, 1, calls)
+}
+
+func TestLookupServiceTags(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "AmazonEC2ContainerServiceV20141113.DescribeServices", req.Header.Get("X-Amz-Target"))
+
+		var input struct {
+			Include  []string `json:"include"`
+			Services []string `json:"services"`
+		}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&input))
+		assert.Equal(t, []string{"TAGS"}, input.Include)
+		assert.Equal(t, []string{"web"}, input.Services)
+
+		rw.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprint(rw, `{"services":[{"serviceName":"web","tags":[{"key":"traefik.weight","value":"5"}]}]}`)
+	}))
+	defer ts.Close()
+
+	tasks := map[string]*ecs.Task{
+		"arn:aws:ecs:us-east-1:123456789012:task/1": {Group: aws.String("service:web")},
+		"arn:aws:ecs:us-east-1:123456789012:task/2": {Group: aws.String("service:web")},
+		"arn:aws:ecs:us-east-1:123456789012:task/3": {Group: aws.String("family:batch")},
+	}
+
+	provider := &Provider{}
+	tags, err := provider.lookupServiceTags(context.Background(), newTestAWSClient(t, ts.URL), aws.String("cluster"), tasks)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]map[string]string{"web": {"traefik.weight": "5"}}, tags)
+}
+
+func TestTaskMetadataRegion(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		metadata       string
+		expectedRegion string
+		expectedErr    bool
+	}{
+		{
+			desc:           "cluster ARN",
+			metadata:       `{"Cluster":"arn:aws:ecs:eu-west-3:123456789012:cluster/default","TaskARN":"arn:aws:ecs:eu-west-3:123456789012:task/1234"}`,
+			expectedRegion: "eu-west-3",
+		},
+		{
+			desc:        "cluster name",
+			metadata:    `{"Cluster":"default"}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/v3/task", req.URL.Path)
+				fmt.Fprint(rw, test.metadata)
+			}))
+			defer ts.Close()
+
+			region, err := taskMetadataRegion(ts.URL + "/v3")
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedRegion, region)
+		})
+	}
+}
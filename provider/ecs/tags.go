@@ -0,0 +1,187 @@
+package ecs
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/provider/label"
+)
+
+// describeServicesMaxItems is the maximum number of services described by a call.
+const describeServicesMaxItems = 10
+
+// includeTags asks the describe operations of the ECS API for the tags of the resources.
+var includeTags = []*string{aws.String("TAGS")}
+
+// The inputs and outputs of the DescribeTasks and DescribeServices operations of the ECS API,
+// with the tags of the resources which are more recent than the vendored SDK.
+type describeTaggedTasksInput struct {
+	_ struct{} `type:"structure"`
+
+	Cluster *string   `locationName:"cluster" type:"string"`
+	Include []*string `locationName:"include" type:"list"`
+	Tasks   []*string `locationName:"tasks" type:"list" required:"true"`
+}
+
+type describeTaggedTasksOutput struct {
+	_ struct{} `type:"structure"`
+
+	Tasks []*taggedTask `locationName:"tasks" type:"list"`
+}
+
+type taggedTask struct {
+	_ struct{} `type:"structure"`
+
+	TaskArn *string        `locationName:"taskArn" type:"string"`
+	Tags    []*resourceTag `locationName:"tags" type:"list"`
+}
+
+type describeTaggedServicesInput struct {
+	_ struct{} `type:"structure"`
+
+	Cluster  *string   `locationName:"cluster" type:"string"`
+	Include  []*string `locationName:"include" type:"list"`
+	Services []*string `locationName:"services" type:"list" required:"true"`
+}
+
+type describeTaggedServicesOutput struct {
+	_ struct{} `type:"structure"`
+
+	Services []*taggedService `locationName:"services" type:"list"`
+}
+
+type taggedService struct {
+	_ struct{} `type:"structure"`
+
+	ServiceName *string        `locationName:"serviceName" type:"string"`
+	Tags        []*resourceTag `locationName:"tags" type:"list"`
+}
+
+type resourceTag struct {
+	_ struct{} `type:"structure"`
+
+	Key   *string `locationName:"key" type:"string"`
+	Value *string `locationName:"value" type:"string"`
+}
+
+// describeTagged calls a describe operation of the ECS API, including the tags of the resources.
+func describeTagged(ctx context.Context, client *ecs.ECS, name string, input, output interface{}) error {
+	op := &request.Operation{
+		Name:       name,
+		HTTPMethod: http.MethodPost,
+		HTTPPath:   "/",
+	}
+
+	req := client.NewRequest(op, input, output)
+	req.SetContext(ctx)
+	return req.Send()
+}
+
+// traefikTags returns the Traefik tags among the tags of an ECS resource.
+func traefikTags(resourceTags []*resourceTag) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range resourceTags {
+		if key := aws.StringValue(tag.Key); strings.HasPrefix(key, label.Prefix) {
+			tags[key] = aws.StringValue(tag.Value)
+		}
+	}
+	return tags
+}
+
+// lookupTaskTags returns the Traefik tags of the tasks, by task ARN, described by batches.
+func (p *Provider) lookupTaskTags(ctx context.Context, client *awsClient, clusterName *string, tasks map[string]*ecs.Task) (map[string]map[string]string, error) {
+	var arns []*string
+	for arn := range tasks {
+		arns = append(arns, aws.String(arn))
+	}
+
+	taskTags := make(map[string]map[string]string)
+	for _, chunk := range p.chunkIDs(arns) {
+		output := &describeTaggedTasksOutput{}
+		err := describeTagged(ctx, client.ecs, "DescribeTasks", &describeTaggedTasksInput{
+			Cluster: clusterName,
+			Include: includeTags,
+			Tasks:   chunk,
+		}, output)
+		if err != nil {
+			log.Errorf("Unable to describe the tags of the tasks: %v", err)
+			return nil, err
+		}
+
+		for _, task := range output.Tasks {
+			taskTags[aws.StringValue(task.TaskArn)] = traefikTags(task.Tags)
+		}
+	}
+
+	return taskTags, nil
+}
+
+// lookupServiceTags returns the Traefik tags of the services of the tasks, by service name, described by batches.
+func (p *Provider) lookupServiceTags(ctx context.Context, client *awsClient, clusterName *string, tasks map[string]*ecs.Task) (map[string]map[string]string, error) {
+	var names []*string
+	known := make(map[string]bool)
+	for _, task := range tasks {
+		name := serviceName(task)
+		if len(name) > 0 && !known[name] {
+			known[name] = true
+			names = append(names, aws.String(name))
+		}
+	}
+
+	serviceTags := make(map[string]map[string]string)
+	for i := 0; i < len(names); i += describeServicesMaxItems {
+		end := i + describeServicesMaxItems
+		if end > len(names) {
+			end = len(names)
+		}
+
+		output := &describeTaggedServicesOutput{}
+		err := describeTagged(ctx, client.ecs, "DescribeServices", &describeTaggedServicesInput{
+			Cluster:  clusterName,
+			Include:  includeTags,
+			Services: names[i:end],
+		}, output)
+		if err != nil {
+			log.Errorf("Unable to describe services: %v", err)
+			return nil, err
+		}
+
+		for _, service := range output.Services {
+			serviceTags[aws.StringValue(service.ServiceName)] = traefikTags(service.Tags)
+		}
+	}
+
+	return serviceTags, nil
+}
+
+// serviceName returns the name of the service which started the task, if any.
+func serviceName(task *ecs.Task) string {
+	group := aws.StringValue(task.Group)
+	if !strings.HasPrefix(group, "service:") {
+		return ""
+	}
+	return strings.TrimPrefix(group, "service:")
+}
+
+// mergeLabels merges the Docker labels of a container with the Traefik tags of its task and service,
+// the tags of the task taking precedence over the ones of the service.
+// The labels take precedence over the tags, unless tagsFirst is set.
+func mergeLabels(labels, taskTags, serviceTags map[string]string, tagsFirst bool) map[string]string {
+	sources := []map[string]string{serviceTags, taskTags, labels}
+	if tagsFirst {
+		sources = []map[string]string{labels, serviceTags, taskTags}
+	}
+
+	merged := make(map[string]string)
+	for _, source := range sources {
+		for key, value := range source {
+			merged[key] = value
+		}
+	}
+	return merged
+}
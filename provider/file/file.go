@@ -8,7 +8,9 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/provider"
 	"github.com/containous/traefik/safe"
@@ -18,12 +20,17 @@ import (
 	"gopkg.in/fsnotify.v1"
 )
 
+// watchRefreshInterval is the interval at which the watched paths are resolved again,
+// to follow the symlink changes which aren't notified, and to recover the recreated directories.
+const watchRefreshInterval = time.Second
+
 var _ provider.Provider = (*Provider)(nil)
 
 // Provider holds configurations of the provider.
 type Provider struct {
 	provider.BaseProvider `mapstructure:",squash" export:"true"`
-	Directory             string `description:"Load configuration from one or more .toml files in a directory" export:"true"`
+	Directory             string         `description:"Load configuration from one or more .toml files in a directory" export:"true"`
+	Debounce              parse.Duration `description:"Wait for the file events to stop for this duration before reloading the configuration" export:"true"`
 	TraefikFile           string
 }
 
@@ -65,7 +72,7 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 // and returns a 'Configuration' object
 func (p *Provider) BuildConfiguration() (*types.Configuration, error) {
 	if len(p.Directory) > 0 {
-		return p.loadFileConfigFromDirectory(p.Directory, nil)
+		return p.loadFileConfigFromDirectory(p.Directory, nil, make(map[string]struct{}))
 	}
 
 	if len(p.Filename) > 0 {
@@ -80,48 +87,195 @@ func (p *Provider) BuildConfiguration() (*types.Configuration, error) {
 }
 
 func (p *Provider) addWatcher(pool *safe.Pool, directory string, configurationChan chan<- types.ConfigMessage, callback func(chan<- types.ConfigMessage, fsnotify.Event)) error {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return fmt.Errorf("error creating file watcher: %s", err)
+	if _, err := os.Stat(directory); err != nil {
+		return fmt.Errorf("error adding file watcher: %s", err)
 	}
 
-	err = watcher.Add(directory)
+	targets := p.watchTargets(directory)
+	watcher, err := newWatcher(targets)
 	if err != nil {
 		return fmt.Errorf("error adding file watcher: %s", err)
 	}
 
+	// rewatch creates a new watcher when the watched paths lead to other files,
+	// after a symlink change or the recreation of a directory.
+	// The removal of a watched path forces it, as the recreated directory can get the same inode.
+	rewatch := func(evt *fsnotify.Event) bool {
+		newTargets := p.watchTargets(directory)
+		if sameTargets(targets, newTargets) && !isTargetRemoval(targets, evt) {
+			return false
+		}
+
+		newWatcher, err := newWatcher(newTargets)
+		if err != nil {
+			log.Errorf("Error watching %s: %v", directory, err)
+			return false
+		}
+
+		log.Debugf("Watched files of %s changed, watching them again", directory)
+		watcher.Close()
+		watcher = newWatcher
+		targets = newTargets
+		return true
+	}
+
 	// Process events
 	pool.Go(func(stop chan bool) {
-		defer watcher.Close()
+		refresh := time.NewTicker(watchRefreshInterval)
+
+		var debounce *time.Timer
+		var debounceC <-chan time.Time
+		var lastEvent fsnotify.Event
+
+		defer func() {
+			refresh.Stop()
+			if debounce != nil {
+				debounce.Stop()
+			}
+			watcher.Close()
+		}()
+
+		notify := func(evt fsnotify.Event) {
+			if p.Debounce <= 0 {
+				callback(configurationChan, evt)
+				return
+			}
+
+			lastEvent = evt
+			if debounce != nil {
+				debounce.Stop()
+			}
+			debounce = time.NewTimer(time.Duration(p.Debounce))
+			debounceC = debounce.C
+		}
+
 		for {
 			select {
 			case <-stop:
 				return
 			case evt := <-watcher.Events:
-				if p.Directory == "" {
-					var filename string
-					if len(p.Filename) > 0 {
-						filename = p.Filename
-					} else {
-						filename = p.TraefikFile
-					}
-
-					_, evtFileName := filepath.Split(evt.Name)
-					_, confFileName := filepath.Split(filename)
-					if evtFileName == confFileName {
-						callback(configurationChan, evt)
-					}
-				} else {
-					callback(configurationChan, evt)
+				if rewatch(&evt) || p.isWatchedEvent(evt) {
+					notify(evt)
 				}
 			case err := <-watcher.Errors:
 				log.Errorf("Watcher event error: %s", err)
+			case <-refresh.C:
+				if rewatch(nil) {
+					notify(fsnotify.Event{Name: directory, Op: fsnotify.Create})
+				}
+			case <-debounceC:
+				debounceC = nil
+				callback(configurationChan, lastEvent)
 			}
 		}
 	})
 	return nil
 }
 
+// watchTargets returns the files of the paths to watch, by path.
+// Along with the directory, the directory of the target of the configuration file is watched,
+// so that the changes behind its symlinks are notified too.
+func (p *Provider) watchTargets(directory string) map[string]os.FileInfo {
+	paths := []string{directory}
+	if len(p.Directory) == 0 {
+		if target, err := filepath.EvalSymlinks(p.watchedFile()); err == nil {
+			paths = append(paths, filepath.Dir(target))
+		}
+	}
+
+	targets := make(map[string]os.FileInfo)
+	resolved := make(map[string]struct{})
+	for _, path := range paths {
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := resolved[target]; ok {
+			continue
+		}
+
+		info, err := os.Stat(target)
+		if err != nil {
+			continue
+		}
+
+		resolved[target] = struct{}{}
+		targets[path] = info
+	}
+
+	return targets
+}
+
+func newWatcher(targets map[string]os.FileInfo) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error creating file watcher: %s", err)
+	}
+
+	for path := range targets {
+		if err := watcher.Add(path); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	return watcher, nil
+}
+
+func sameTargets(a, b map[string]os.FileInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for path, info := range a {
+		other, ok := b[path]
+		if !ok || !os.SameFile(info, other) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTargetRemoval(targets map[string]os.FileInfo, evt *fsnotify.Event) bool {
+	if evt == nil || evt.Op&(fsnotify.Remove|fsnotify.Rename) == 0 {
+		return false
+	}
+
+	for path := range targets {
+		if filepath.Clean(path) == evt.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// isWatchedEvent returns whether the event concerns the configuration.
+// In the single file mode, only the events of the file, or of the target of its symlink, are considered.
+func (p *Provider) isWatchedEvent(evt fsnotify.Event) bool {
+	if len(p.Directory) > 0 {
+		return true
+	}
+
+	filename := p.watchedFile()
+
+	_, evtFileName := filepath.Split(evt.Name)
+	if evtFileName == filepath.Base(filename) {
+		return true
+	}
+
+	target, err := filepath.EvalSymlinks(filename)
+	return err == nil && evtFileName == filepath.Base(target)
+}
+
+func (p *Provider) watchedFile() string {
+	if len(p.Filename) > 0 {
+		return p.Filename
+	}
+	return p.TraefikFile
+}
+
 func (p *Provider) watcherCallback(configurationChan chan<- types.ConfigMessage, event fsnotify.Event) {
 	watchItem := p.TraefikFile
 	if len(p.Directory) > 0 {
@@ -188,7 +342,20 @@ func (p *Provider) loadFileConfig(filename string, parseTemplate bool) (*types.C
 	return configuration, err
 }
 
-func (p *Provider) loadFileConfigFromDirectory(directory string, configuration *types.Configuration) (*types.Configuration, error) {
+// loadFileConfigFromDirectory loads the configuration files of the directory and of its subdirectories.
+// The directories already loaded, through the symlinks, are skipped.
+func (p *Provider) loadFileConfigFromDirectory(directory string, configuration *types.Configuration, loaded map[string]struct{}) (*types.Configuration, error) {
+	resolvedDirectory, err := filepath.EvalSymlinks(directory)
+	if err != nil {
+		return configuration, fmt.Errorf("unable to read directory %s: %v", directory, err)
+	}
+
+	if _, ok := loaded[resolvedDirectory]; ok {
+		log.Warnf("Directory %s already loaded, skipping", directory)
+		return configuration, nil
+	}
+	loaded[resolvedDirectory] = struct{}{}
+
 	fileList, err := ioutil.ReadDir(directory)
 
 	if err != nil {
@@ -204,9 +371,22 @@ func (p *Provider) loadFileConfigFromDirectory(directory string, configuration *
 
 	configTLSMaps := make(map[*tls.Configuration]struct{})
 	for _, item := range fileList {
+		// Kubernetes mounts the ConfigMaps with hidden "..data" directories, which the other files link to.
+		if strings.HasPrefix(item.Name(), "..") {
+			continue
+		}
 
-		if item.IsDir() {
-			configuration, err = p.loadFileConfigFromDirectory(filepath.Join(directory, item.Name()), configuration)
+		isDir := item.IsDir()
+		if item.Mode()&os.ModeSymlink != 0 {
+			isDir, err = isSymlinkToDirectory(directory, item.Name())
+			if err != nil {
+				log.Warnf("Unable to follow symlink %s in %s: %v", item.Name(), directory, err)
+				continue
+			}
+		}
+
+		if isDir {
+			configuration, err = p.loadFileConfigFromDirectory(filepath.Join(directory, item.Name()), configuration, loaded)
 			if err != nil {
 				return configuration, fmt.Errorf("unable to load content configuration from subdirectory %s: %v", item, err)
 			}
@@ -252,3 +432,12 @@ func (p *Provider) loadFileConfigFromDirectory(directory string, configuration *
 	}
 	return configuration, nil
 }
+
+// isSymlinkToDirectory returns whether the symlink of the directory leads to a directory.
+func isSymlinkToDirectory(directory string, name string) (bool, error) {
+	info, err := os.Stat(filepath.Join(directory, name))
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
@@ -9,9 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/containous/flaeg/parse"
 	"github.com/containous/traefik/safe"
 	"github.com/containous/traefik/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // createRandomFile Helper
@@ -336,3 +338,164 @@ func createProvider(t *testing.T, test ProvideTestCase, watch bool) (*Provider,
 		os.Remove(tempDir)
 	}
 }
+
+// receiveConfig Helper
+func receiveConfig(t *testing.T, configChan chan types.ConfigMessage, timeout time.Duration) *types.Configuration {
+	t.Helper()
+
+	select {
+	case config := <-configChan:
+		return config.Configuration
+	case <-time.After(timeout):
+		t.Fatal("timeout while waiting for config")
+		return nil
+	}
+}
+
+// swapConfigMapData Helper, writing the files of a new version of a Kubernetes ConfigMap volume.
+func swapConfigMapData(t *testing.T, tempDir string, version string, content string) {
+	t.Helper()
+
+	dataDir := path.Join(tempDir, "..data_"+version)
+	if err := os.Mkdir(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	createFile(t, dataDir, "rules.toml", content)
+
+	if err := os.Symlink(dataDir, path.Join(tempDir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(path.Join(tempDir, "..data_tmp"), path.Join(tempDir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProvideWithWatchSymlinkSwap(t *testing.T) {
+	tempDir := createTempDir(t, "testdir")
+	defer os.RemoveAll(tempDir)
+
+	swapConfigMapData(t, tempDir, "1", createFrontendConfiguration(1))
+	if err := os.Symlink(path.Join("..data", "rules.toml"), path.Join(tempDir, "rules.toml")); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &Provider{}
+	provider.Watch = true
+	provider.Filename = path.Join(tempDir, "rules.toml")
+
+	configChan := make(chan types.ConfigMessage)
+	go func() {
+		err := provider.Provide(configChan, safe.NewPool(context.Background()))
+		assert.NoError(t, err)
+	}()
+
+	config := receiveConfig(t, configChan, time.Second)
+	assert.Len(t, config.Frontends, 1)
+
+	swapConfigMapData(t, tempDir, "2", createFrontendConfiguration(2))
+	require.NoError(t, os.RemoveAll(path.Join(tempDir, "..data_1")))
+
+	config = receiveConfig(t, configChan, 3*time.Second)
+	assert.Len(t, config.Frontends, 2)
+
+	// The target of the new symlink is watched.
+	createFile(t, path.Join(tempDir, "..data_2"), "rules.toml", createFrontendConfiguration(3))
+
+	for len(config.Frontends) != 3 {
+		config = receiveConfig(t, configChan, 3*time.Second)
+	}
+}
+
+func TestProvideWithWatchDebounce(t *testing.T) {
+	tempDir := createTempDir(t, "testdir")
+	defer os.RemoveAll(tempDir)
+
+	provider := &Provider{Directory: tempDir, Debounce: parse.Duration(200 * time.Millisecond)}
+	provider.Watch = true
+
+	configChan := make(chan types.ConfigMessage)
+	go func() {
+		err := provider.Provide(configChan, safe.NewPool(context.Background()))
+		assert.NoError(t, err)
+	}()
+
+	config := receiveConfig(t, configChan, time.Second)
+	assert.Len(t, config.Frontends, 0)
+
+	for i := 1; i <= 5; i++ {
+		createFile(t, tempDir, fmt.Sprintf("frontend%d.toml", i), fmt.Sprintf("[frontends.frontend%d]\nbackend = \"backend\"\n", i))
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	config = receiveConfig(t, configChan, 2*time.Second)
+	assert.Len(t, config.Frontends, 5)
+
+	select {
+	case <-configChan:
+		t.Fatal("the burst of file events must produce a single reload")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestProvideWithWatchRecreatedDirectory(t *testing.T) {
+	tempDir := createTempDir(t, "testdir")
+	defer os.RemoveAll(tempDir)
+
+	directory := path.Join(tempDir, "conf")
+	require.NoError(t, os.Mkdir(directory, 0755))
+
+	provider := &Provider{Directory: directory}
+	provider.Watch = true
+
+	configChan := make(chan types.ConfigMessage)
+	go func() {
+		err := provider.Provide(configChan, safe.NewPool(context.Background()))
+		assert.NoError(t, err)
+	}()
+
+	config := receiveConfig(t, configChan, time.Second)
+	assert.Len(t, config.Frontends, 0)
+
+	require.NoError(t, os.RemoveAll(directory))
+	require.NoError(t, os.Mkdir(directory, 0755))
+	createFile(t, directory, "frontends.toml", createFrontendConfiguration(2))
+
+	for len(config.Frontends) != 2 {
+		config = receiveConfig(t, configChan, 3*time.Second)
+	}
+
+	// The new directory is watched.
+	createFile(t, directory, "frontends.toml", createFrontendConfiguration(3))
+
+	for len(config.Frontends) != 3 {
+		config = receiveConfig(t, configChan, 3*time.Second)
+	}
+}
+
+func TestLoadFileConfigFromDirectorySymlinks(t *testing.T) {
+	tempDir := createTempDir(t, "testdir")
+	defer os.RemoveAll(tempDir)
+
+	targetDir := createTempDir(t, "targetdir")
+	defer os.RemoveAll(targetDir)
+
+	createFile(t, targetDir, "frontends.toml", createFrontendConfiguration(2))
+	require.NoError(t, os.Symlink(targetDir, path.Join(tempDir, "linked")))
+
+	// Symlink loop.
+	require.NoError(t, os.Symlink(tempDir, path.Join(targetDir, "parent")))
+
+	// Kubernetes ConfigMap volume.
+	swapConfigMapData(t, tempDir, "1", createBackendConfiguration(3))
+	require.NoError(t, os.Symlink(path.Join("..data", "rules.toml"), path.Join(tempDir, "rules.toml")))
+
+	provider := &Provider{Directory: tempDir}
+
+	config, err := provider.BuildConfiguration()
+	require.NoError(t, err)
+
+	assert.Len(t, config.Frontends, 2)
+	assert.Len(t, config.Backends, 3)
+}
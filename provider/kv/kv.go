@@ -71,11 +71,28 @@ func (p *Provider) watchKv(configurationChan chan<- types.ConfigMessage, prefix
 			select {
 			case <-stop:
 				return nil
-			case _, ok := <-events:
+			case pairs, ok := <-events:
 				if !ok {
 					return errors.New("watchtree channel closed")
 				}
-				configuration := p.buildConfiguration()
+
+				// Coalesces the changes received while building the previous configuration.
+				for pending := true; pending; {
+					select {
+					case latest, ok := <-events:
+						if !ok {
+							return errors.New("watchtree channel closed")
+						}
+						pairs = latest
+					default:
+						pending = false
+					}
+				}
+
+				configuration, err := p.loadConfiguration(pairs)
+				if err != nil {
+					return err
+				}
 				if configuration != nil {
 					configurationChan <- types.ConfigMessage{
 						ProviderName:  string(p.storeType),
@@ -96,12 +113,48 @@ func (p *Provider) watchKv(configurationChan chan<- types.ConfigMessage, prefix
 	return nil
 }
 
+// loadConfiguration builds the configuration.
+// With Consul, it is built from a snapshot of the prefix: the watched pairs if any, or the ones listed at once,
+// as a recursive list is a single read at a given index of the store.
+func (p *Provider) loadConfiguration(pairs []*store.KVPair) (*types.Configuration, error) {
+	if p.storeType != store.CONSUL {
+		return p.buildConfiguration(), nil
+	}
+
+	if pairs == nil {
+		var err error
+		pairs, err = p.kvClient.List(p.Prefix, nil)
+		if err != nil && err != store.ErrKeyNotFound {
+			return nil, fmt.Errorf("failed to list KV prefix %s: %v", p.Prefix, err)
+		}
+	}
+	kvSnapshot := newSnapshot(p.kvClient, p.Prefix, pairs)
+
+	// The alias prefix is read at once too.
+	if alias, err := kvSnapshot.Get(p.Prefix+pathAlias, nil); err == nil && len(alias.Value) > 0 {
+		aliasPrefix := strings.TrimSuffix(string(alias.Value), pathSeparator)
+		aliasPairs, err := p.kvClient.List(aliasPrefix, nil)
+		if err != nil && err != store.ErrKeyNotFound {
+			return nil, fmt.Errorf("failed to list KV prefix %s: %v", aliasPrefix, err)
+		}
+		kvSnapshot = newSnapshot(kvSnapshot, aliasPrefix, aliasPairs)
+	}
+
+	snapshotProvider := *p
+	snapshotProvider.kvClient = kvSnapshot
+	return snapshotProvider.buildConfiguration(), nil
+}
+
 // Provide provides the configuration to traefik via the configuration channel
 func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool) error {
 	operation := func() error {
 		if _, err := p.kvClient.Exists(p.Prefix+"/qmslkjdfmqlskdjfmqlksjazçueznbvbwzlkajzebvkwjdcqmlsfj", nil); err != nil {
 			return fmt.Errorf("failed to test KV store connection: %v", err)
 		}
+		configuration, err := p.loadConfiguration(nil)
+		if err != nil {
+			return err
+		}
 		if p.Watch {
 			pool.Go(func(stop chan bool) {
 				err := p.watchKv(configurationChan, p.Prefix, stop)
@@ -110,7 +163,6 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 				}
 			})
 		}
-		configuration := p.buildConfiguration()
 		configurationChan <- types.ConfigMessage{
 			ProviderName:  string(p.storeType),
 			Configuration: configuration,
@@ -7,6 +7,8 @@ import (
 	"github.com/abronan/valkeyrie/store"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestKvWatchTree(t *testing.T) {
@@ -51,3 +53,35 @@ func TestKvWatchTree(t *testing.T) {
 	default:
 	}
 }
+
+func TestLoadConfigurationSnapshot(t *testing.T) {
+	// The store is half-updated: the frontend uses a backend without server yet.
+	kvClient := &Mock{
+		KVPairs: filler("traefik",
+			frontend("frontend1",
+				withPair(pathFrontendBackend, "backend2")),
+			backend("backend1",
+				withPair("servers/server1/url", "http://172.17.0.2:80")),
+		),
+	}
+
+	provider := &Provider{
+		Prefix:    "traefik",
+		storeType: store.CONSUL,
+		kvClient:  kvClient,
+	}
+
+	pairs := filler("traefik",
+		frontend("frontend1",
+			withPair(pathFrontendBackend, "backend1")),
+		backend("backend1",
+			withPair("servers/server1/url", "http://172.17.0.2:80")),
+	)
+
+	configuration, err := provider.loadConfiguration(pairs)
+	require.NoError(t, err)
+
+	require.Contains(t, configuration.Frontends, "frontend1")
+	assert.Equal(t, "backend1", configuration.Frontends["frontend1"].Backend)
+	assert.Contains(t, configuration.Backends, "backend1")
+}
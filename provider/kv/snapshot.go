@@ -0,0 +1,90 @@
+package kv
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/abronan/valkeyrie/store"
+)
+
+// snapshot is a read-only view of the key-value pairs under a prefix, read at once from the store,
+// so that a configuration is built from a consistent state, and not from a half-applied multi-key update.
+// The keys outside of the prefix are read from the underlying store.
+type snapshot struct {
+	store.Store
+	prefix string
+	pairs  map[string]*store.KVPair
+}
+
+func newSnapshot(kvStore store.Store, prefix string, pairs []*store.KVPair) *snapshot {
+	s := &snapshot{
+		Store:  kvStore,
+		prefix: prefix,
+		pairs:  make(map[string]*store.KVPair),
+	}
+
+	for _, pair := range pairs {
+		s.pairs[pair.Key] = pair
+	}
+
+	return s
+}
+
+func (s *snapshot) contains(key string) bool {
+	return key == s.prefix || strings.HasPrefix(key, s.prefix+pathSeparator)
+}
+
+// Get returns the value of the key from the snapshot.
+func (s *snapshot) Get(key string, options *store.ReadOptions) (*store.KVPair, error) {
+	if !s.contains(key) {
+		return s.Store.Get(key, options)
+	}
+
+	pair, ok := s.pairs[key]
+	if !ok {
+		return nil, store.ErrKeyNotFound
+	}
+	return pair, nil
+}
+
+// Exists returns whether the key exists in the snapshot.
+func (s *snapshot) Exists(key string, options *store.ReadOptions) (bool, error) {
+	if !s.contains(key) {
+		return s.Store.Exists(key, options)
+	}
+
+	_, err := s.Get(key, options)
+	if err == store.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List returns the pairs of the keys starting with the directory from the snapshot, recursively, as the Consul store does.
+func (s *snapshot) List(directory string, options *store.ReadOptions) ([]*store.KVPair, error) {
+	if !s.contains(directory) {
+		return s.Store.List(directory, options)
+	}
+
+	var found bool
+	var pairs []*store.KVPair
+	for key, pair := range s.pairs {
+		if !strings.HasPrefix(key, directory) {
+			continue
+		}
+
+		found = true
+		if pair.Key != directory {
+			pairs = append(pairs, pair)
+		}
+	}
+
+	if !found {
+		return nil, store.ErrKeyNotFound
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key < pairs[j].Key
+	})
+	return pairs, nil
+}
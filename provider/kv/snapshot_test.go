@@ -0,0 +1,59 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/abronan/valkeyrie/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotGet(t *testing.T) {
+	kvClient := newKvClientMock([]*store.KVPair{
+		aKVPair("traefik/frontends/frontend1/backend", "backend2"),
+		aKVPair("other/key", "value"),
+	}, nil)
+
+	kvSnapshot := newSnapshot(kvClient, "traefik", []*store.KVPair{
+		aKVPair("traefik/frontends/frontend1/backend", "backend1"),
+	})
+
+	pair, err := kvSnapshot.Get("traefik/frontends/frontend1/backend", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "backend1", string(pair.Value))
+
+	_, err = kvSnapshot.Get("traefik/frontends/frontend1/priority", nil)
+	assert.Equal(t, store.ErrKeyNotFound, err)
+
+	exists, err := kvSnapshot.Exists("traefik/frontends/frontend1/priority", nil)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// The keys outside of the prefix are read from the store.
+	pair, err = kvSnapshot.Get("other/key", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(pair.Value))
+}
+
+func TestSnapshotList(t *testing.T) {
+	kvSnapshot := newSnapshot(newKvClientMock(nil, nil), "traefik", []*store.KVPair{
+		aKVPair("traefik/backends/backend1/servers/server2/url", "http://172.17.0.3:80"),
+		aKVPair("traefik/backends/backend1/servers/server1/url", "http://172.17.0.2:80"),
+		aKVPair("traefik/frontends/frontend1/backend", "backend1"),
+	})
+
+	pairs, err := kvSnapshot.List("traefik/backends/", nil)
+	require.NoError(t, err)
+
+	var keys []string
+	for _, pair := range pairs {
+		keys = append(keys, pair.Key)
+	}
+	assert.Equal(t, []string{
+		"traefik/backends/backend1/servers/server1/url",
+		"traefik/backends/backend1/servers/server2/url",
+	}, keys)
+
+	_, err = kvSnapshot.List("traefik/tls/", nil)
+	assert.Equal(t, store.ErrKeyNotFound, err)
+}
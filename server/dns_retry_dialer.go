@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/log"
+)
+
+// maxLastKnownAddrs is the maximum number of last known addresses kept by host name.
+const maxLastKnownAddrs = 8
+
+type dialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+type lastKnownAddrs struct {
+	ips          []string
+	failingSince time.Time
+}
+
+// dnsLastKnownAddrs keeps the addresses of the successful connections by host name,
+// shared by the dialers of the transports of a server.
+type dnsLastKnownAddrs struct {
+	gracePeriod time.Duration
+	now         func() time.Time
+
+	lock  sync.Mutex
+	hosts map[string]*lastKnownAddrs
+}
+
+func newDNSLastKnownAddrs(gracePeriod time.Duration) *dnsLastKnownAddrs {
+	return &dnsLastKnownAddrs{
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+		hosts:       make(map[string]*lastKnownAddrs),
+	}
+}
+
+// dnsRetryDialer dials the backend servers, retrying the failed resolutions of their host names with an exponential backoff.
+// While the resolution of a host name fails, its last known addresses are dialed, until the failure lasts longer than the grace period.
+// A host name which doesn't exist (NXDOMAIN) has no last known addresses.
+type dnsRetryDialer struct {
+	dial          dialContextFunc
+	retries       int
+	retryInterval time.Duration
+	addrs         *dnsLastKnownAddrs
+}
+
+func newDNSRetryDialer(dial dialContextFunc, config *configuration.ForwardingDNS, addrs *dnsLastKnownAddrs) *dnsRetryDialer {
+	d := &dnsRetryDialer{
+		dial:          dial,
+		retries:       config.Retries,
+		retryInterval: time.Duration(config.RetryInterval),
+		addrs:         addrs,
+	}
+
+	if d.retryInterval <= 0 {
+		d.retryInterval = configuration.DefaultDNSRetryInterval
+	}
+
+	return d
+}
+
+func (d *dnsRetryDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dial(ctx, network, addr)
+	}
+
+	interval := d.retryInterval
+	for attempt := 0; ; attempt++ {
+		conn, err := d.dial(ctx, network, addr)
+		if err == nil {
+			d.addrs.connected(host, conn.RemoteAddr())
+			return conn, nil
+		}
+
+		if !isDNSError(err) {
+			return nil, err
+		}
+
+		if attempt >= d.retries {
+			return d.dialLastKnownAddrs(ctx, network, host, port, err)
+		}
+
+		log.Debugf("Failed to resolve %s, retrying in %s: %v", host, interval, err)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		interval *= 2
+	}
+}
+
+// dialLastKnownAddrs dials the last known addresses of a host name, during the grace period of its resolution failure.
+func (d *dnsRetryDialer) dialLastKnownAddrs(ctx context.Context, network, host, port string, resolveErr error) (net.Conn, error) {
+	ips := d.addrs.get(host, resolveErr)
+	if len(ips) == 0 {
+		return nil, resolveErr
+	}
+
+	err := resolveErr
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = d.dial(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// connected records the address of a successful connection to a host name.
+func (a *dnsLastKnownAddrs) connected(host string, remoteAddr net.Addr) {
+	tcpAddr, ok := remoteAddr.(*net.TCPAddr)
+	if !ok || a.gracePeriod <= 0 {
+		return
+	}
+	ip := tcpAddr.IP.String()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	known, ok := a.hosts[host]
+	if !ok {
+		known = &lastKnownAddrs{}
+		a.hosts[host] = known
+	}
+
+	known.failingSince = time.Time{}
+
+	// The most recent address first.
+	ips := []string{ip}
+	for _, knownIP := range known.ips {
+		if knownIP != ip && len(ips) < maxLastKnownAddrs {
+			ips = append(ips, knownIP)
+		}
+	}
+	known.ips = ips
+}
+
+// get returns the last known addresses of a host name which resolution fails, during the grace period.
+func (a *dnsLastKnownAddrs) get(host string, resolveErr error) []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	known, ok := a.hosts[host]
+	if !ok {
+		return nil
+	}
+
+	if isNotFoundError(resolveErr) {
+		log.Errorf("Host %s not found, forgetting its last known addresses: %v", host, resolveErr)
+		delete(a.hosts, host)
+		return nil
+	}
+
+	now := a.now()
+	if known.failingSince.IsZero() {
+		log.Warnf("Failed to resolve %s, using its last known addresses %v for %s: %v", host, known.ips, a.gracePeriod, resolveErr)
+		known.failingSince = now
+	}
+
+	if now.Sub(known.failingSince) > a.gracePeriod {
+		log.Errorf("Failed to resolve %s for more than %s, forgetting its last known addresses: %v", host, a.gracePeriod, resolveErr)
+		delete(a.hosts, host)
+		return nil
+	}
+
+	return append([]string(nil), known.ips...)
+}
+
+func isDNSError(err error) bool {
+	_, ok := dnsError(err)
+	return ok
+}
+
+// isNotFoundError returns whether the error is an NXDOMAIN answer, which message is the only way to tell it apart.
+func isNotFoundError(err error) bool {
+	dnsErr, ok := dnsError(err)
+	return ok && dnsErr.Err == "no such host"
+}
+
+func dnsError(err error) (*net.DNSError, bool) {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	dnsErr, ok := err.(*net.DNSError)
+	return dnsErr, ok
+}
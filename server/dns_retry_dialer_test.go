@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/configuration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type addrConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// fakeDialer resolves the host names of its records, and fails to dial the addresses of its down IPs.
+// The resolution of the other host names fails, with an NXDOMAIN answer for the not found ones.
+type fakeDialer struct {
+	records  map[string]string
+	notFound map[string]bool
+	downIPs  map[string]bool
+	dials    []string
+}
+
+func (f *fakeDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	f.dials = append(f.dials, addr)
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := host
+	if net.ParseIP(host) == nil {
+		var ok bool
+		if f.notFound[host] {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: host}}
+		}
+		if ip, ok = f.records[host]; !ok {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "server misbehaving", Name: host, IsTemporary: true}}
+		}
+	}
+
+	if f.downIPs[ip] {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused")}
+	}
+
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	return addrConn{remoteAddr: &net.TCPAddr{IP: net.ParseIP(ip), Port: portNumber}}, nil
+}
+
+func TestDNSRetryDialerRetries(t *testing.T) {
+	fake := &fakeDialer{}
+	dialer := newDNSRetryDialer(fake.dial, &configuration.ForwardingDNS{Retries: 2, RetryInterval: parse.Duration(time.Millisecond)}, newDNSLastKnownAddrs(0))
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.Error(t, err)
+	assert.True(t, isDNSError(err))
+	assert.Equal(t, []string{"backend.local:80", "backend.local:80", "backend.local:80"}, fake.dials)
+
+	// The other errors are not retried.
+	fake.records = map[string]string{"backend.local": "10.0.0.1"}
+	fake.downIPs = map[string]bool{"10.0.0.1": true}
+	fake.dials = nil
+
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.Error(t, err)
+	assert.False(t, isDNSError(err))
+	assert.Equal(t, []string{"backend.local:80"}, fake.dials)
+}
+
+func TestDNSRetryDialerRetriesCanceled(t *testing.T) {
+	fake := &fakeDialer{}
+	dialer := newDNSRetryDialer(fake.dial, &configuration.ForwardingDNS{Retries: 2, RetryInterval: parse.Duration(time.Hour)}, newDNSLastKnownAddrs(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := dialer.DialContext(ctx, "tcp", "backend.local:80")
+	require.Error(t, err)
+	assert.Equal(t, []string{"backend.local:80"}, fake.dials)
+}
+
+func TestDNSRetryDialerGracePeriod(t *testing.T) {
+	fake := &fakeDialer{records: map[string]string{"backend.local": "10.0.0.1"}}
+	dialer := newDNSRetryDialer(fake.dial, &configuration.ForwardingDNS{GracePeriod: parse.Duration(time.Minute)}, newDNSLastKnownAddrs(time.Minute))
+
+	now := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	dialer.addrs.now = func() time.Time { return now }
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	fake.records["backend.local"] = "10.0.0.2"
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	// The resolution fails: the last known addresses are dialed, the most recent one first.
+	delete(fake.records, "backend.local")
+	fake.downIPs = map[string]bool{"10.0.0.2": true}
+	fake.dials = nil
+
+	conn, err := dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:80", conn.RemoteAddr().String())
+	assert.Equal(t, []string{"backend.local:80", "10.0.0.2:80", "10.0.0.1:80"}, fake.dials)
+
+	now = now.Add(time.Minute)
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	// The failure lasts longer than the grace period: the last known addresses are forgotten.
+	now = now.Add(time.Second)
+	fake.dials = nil
+
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.Error(t, err)
+	assert.True(t, isDNSError(err))
+	assert.Equal(t, []string{"backend.local:80"}, fake.dials)
+	assert.Empty(t, dialer.addrs.hosts)
+}
+
+func TestDNSRetryDialerGracePeriodReset(t *testing.T) {
+	fake := &fakeDialer{records: map[string]string{"backend.local": "10.0.0.1"}}
+	dialer := newDNSRetryDialer(fake.dial, &configuration.ForwardingDNS{GracePeriod: parse.Duration(time.Minute)}, newDNSLastKnownAddrs(time.Minute))
+
+	now := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	dialer.addrs.now = func() time.Time { return now }
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	delete(fake.records, "backend.local")
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	// A successful resolution ends the failure.
+	now = now.Add(50 * time.Second)
+	fake.records["backend.local"] = "10.0.0.1"
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	now = now.Add(50 * time.Second)
+	delete(fake.records, "backend.local")
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+}
+
+func TestDNSRetryDialerIPAddress(t *testing.T) {
+	fake := &fakeDialer{downIPs: map[string]bool{"10.0.0.1": true}}
+	dialer := newDNSRetryDialer(fake.dial, &configuration.ForwardingDNS{Retries: 2, GracePeriod: parse.Duration(time.Minute)}, newDNSLastKnownAddrs(time.Minute))
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "10.0.0.1:80")
+	require.Error(t, err)
+	assert.Equal(t, []string{"10.0.0.1:80"}, fake.dials)
+}
+
+func TestDNSRetryDialerSharedLastKnownAddrs(t *testing.T) {
+	addrs := newDNSLastKnownAddrs(time.Minute)
+
+	fake := &fakeDialer{records: map[string]string{"backend.local": "10.0.0.1"}}
+	dialer := newDNSRetryDialer(fake.dial, &configuration.ForwardingDNS{GracePeriod: parse.Duration(time.Minute)}, addrs)
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	// The dialer of another transport of the server uses the addresses known by the first one.
+	other := &fakeDialer{}
+	otherDialer := newDNSRetryDialer(other.dial, &configuration.ForwardingDNS{GracePeriod: parse.Duration(time.Minute)}, addrs)
+
+	conn, err := otherDialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1:80", conn.RemoteAddr().String())
+	assert.Equal(t, []string{"backend.local:80", "10.0.0.1:80"}, other.dials)
+}
+
+func TestDNSRetryDialerNotFound(t *testing.T) {
+	fake := &fakeDialer{records: map[string]string{"backend.local": "10.0.0.1"}}
+	dialer := newDNSRetryDialer(fake.dial, &configuration.ForwardingDNS{GracePeriod: parse.Duration(time.Minute)}, newDNSLastKnownAddrs(time.Minute))
+
+	_, err := dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.NoError(t, err)
+
+	// The host name doesn't exist anymore: its last known addresses are not dialed, and forgotten.
+	fake.notFound = map[string]bool{"backend.local": true}
+	fake.dials = nil
+
+	_, err = dialer.DialContext(context.Background(), "tcp", "backend.local:80")
+	require.Error(t, err)
+	assert.True(t, isNotFoundError(err))
+	assert.Equal(t, []string{"backend.local:80"}, fake.dials)
+	assert.Empty(t, dialer.addrs.hosts)
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+var errBodyNotReplayable = errors.New("request body too large to be replayed")
+
+// refusedRequestsRoundTripper makes the request bodies replayable,
+// for the HTTP/2 transport to retry on a new connection the requests refused by a backend server:
+// the streams above the last stream ID of a GOAWAY, and the REFUSED_STREAM resets, were not processed by the server.
+// The transport retries these requests by itself when they have no body, or when their body can be read again with GetBody,
+// whatever their method.
+type refusedRequestsRoundTripper struct {
+	next         http.RoundTripper
+	maxBodyBytes int64
+}
+
+func newRefusedRequestsRoundTripper(next http.RoundTripper, maxBodyBytes int64) *refusedRequestsRoundTripper {
+	return &refusedRequestsRoundTripper{
+		next:         next,
+		maxBodyBytes: maxBodyBytes,
+	}
+}
+
+func (r *refusedRequestsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil || req.ContentLength > r.maxBodyBytes {
+		return r.next.RoundTrip(req)
+	}
+
+	state := &replayState{src: req.Body, maxBytes: r.maxBodyBytes}
+
+	outReq := new(http.Request)
+	*outReq = *req
+	outReq.Body = &replayBody{state: state}
+	outReq.GetBody = state.replay
+
+	return r.next.RoundTrip(outReq)
+}
+
+// replayState records the bytes read from a request body, up to a maximum size, to replay them.
+// The bodies given to the transport share the source, and read it in turn:
+// the bytes read by the body of a refused request are read from the record by the body of its retry.
+type replayState struct {
+	src      io.Reader
+	maxBytes int64
+
+	// readLock serializes the reads of the source, without blocking GetBody.
+	readLock sync.Mutex
+
+	lock     sync.Mutex
+	buf      []byte
+	consumed int64
+	overflow bool
+}
+
+func (s *replayState) replay() (io.ReadCloser, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.overflow {
+		return nil, errBodyNotReplayable
+	}
+	return &replayBody{state: s}, nil
+}
+
+// readRecorded reads the recorded bytes from the given offset.
+// It returns false when the source has to be read.
+func (s *replayState) readRecorded(offset int64, p []byte) (int, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if offset < int64(len(s.buf)) {
+		return copy(p, s.buf[offset:]), true, nil
+	}
+
+	if offset != s.consumed {
+		// The bytes were read by another body, and not recorded.
+		return 0, true, errBodyNotReplayable
+	}
+
+	return 0, false, nil
+}
+
+func (s *replayState) read(offset int64, p []byte) (int, error) {
+	for {
+		if n, ok, err := s.readRecorded(offset, p); ok {
+			return n, err
+		}
+
+		s.readLock.Lock()
+
+		// Another body may have read the source in the meantime.
+		if _, ok, _ := s.readRecorded(offset, nil); ok {
+			s.readLock.Unlock()
+			continue
+		}
+
+		n, err := s.src.Read(p)
+		s.record(p[:n])
+
+		s.readLock.Unlock()
+		return n, err
+	}
+}
+
+func (s *replayState) record(p []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.consumed += int64(len(p))
+	if s.overflow {
+		return
+	}
+
+	if s.consumed > s.maxBytes {
+		s.overflow = true
+		s.buf = nil
+		return
+	}
+
+	s.buf = append(s.buf, p...)
+}
+
+type replayBody struct {
+	state  *replayState
+	offset int64
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	n, err := b.state.read(b.offset, p)
+	b.offset += int64(n)
+	return n, err
+}
+
+// Close doesn't close the source, which may still be read by the body of a retry.
+// It is closed by the server once the request is handled.
+func (b *replayBody) Close() error {
+	return nil
+}
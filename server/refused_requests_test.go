@@ -0,0 +1,180 @@
+package server
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+// startGoAwayServer starts an h2c server which refuses the request of the first connection,
+// with a GOAWAY sent once its body is received, and serves the following connections.
+func startGoAwayServer(t *testing.T) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Write(body)
+	})
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go refuseWithGoAway(conn)
+
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+
+	return "http://" + listener.Addr().String(), func() { listener.Close() }
+}
+
+func refuseWithGoAway(conn net.Conn) {
+	defer conn.Close()
+
+	preface := make([]byte, len(http2.ClientPreface))
+	if _, err := io.ReadFull(conn, preface); err != nil {
+		return
+	}
+
+	framer := http2.NewFramer(conn, conn)
+	if err := framer.WriteSettings(); err != nil {
+		return
+	}
+
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return
+		}
+
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.DataFrame:
+			if f.StreamEnded() {
+				// No stream was processed.
+				framer.WriteGoAway(0, http2.ErrCodeNo, nil)
+			}
+		}
+	}
+}
+
+func TestRefusedRequestsRoundTripper(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		retryRefused  bool
+		maxBodyBytes  int64
+		expectedError bool
+	}{
+		{
+			desc:          "without retry",
+			expectedError: true,
+		},
+		{
+			desc:         "with retry",
+			retryRefused: true,
+			maxBodyBytes: 1024,
+		},
+		{
+			desc:          "with retry and a body too large to be replayed",
+			retryRefused:  true,
+			maxBodyBytes:  4,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			serverURL, closeServer := startGoAwayServer(t)
+			defer closeServer()
+
+			var roundTripper http.RoundTripper = &http2.Transport{
+				AllowHTTP: true,
+				DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+					return net.Dial(network, addr)
+				},
+			}
+			if test.retryRefused {
+				roundTripper = newRefusedRequestsRoundTripper(roundTripper, test.maxBodyBytes)
+			}
+
+			// The body can't be read again by the transport itself, as for the forwarded requests.
+			req, err := http.NewRequest(http.MethodPost, serverURL, ioutil.NopCloser(strings.NewReader("payload")))
+			require.NoError(t, err)
+
+			resp, err := roundTripper.RoundTrip(req)
+			if test.expectedError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "payload", string(body))
+		})
+	}
+}
+
+func TestReplayBody(t *testing.T) {
+	state := &replayState{src: strings.NewReader("0123456789"), maxBytes: 10}
+
+	first := &replayBody{state: state}
+	buf := make([]byte, 4)
+	n, err := first.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "0123", string(buf[:n]))
+
+	// The replayed body reads the recorded bytes, then the rest of the source.
+	replayed, err := state.replay()
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadAll(replayed)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	// The source was read by the replayed body, the first body reads the recorded bytes.
+	data, err = ioutil.ReadAll(first)
+	require.NoError(t, err)
+	assert.Equal(t, "456789", string(data))
+}
+
+func TestReplayBodyOverflow(t *testing.T) {
+	state := &replayState{src: strings.NewReader("0123456789"), maxBytes: 4}
+
+	first := &replayBody{state: state}
+	data, err := ioutil.ReadAll(first)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	_, err = state.replay()
+	assert.Equal(t, errBodyNotReplayable, err)
+}
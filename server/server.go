@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	stdlog "log"
 	"net"
@@ -13,6 +14,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,21 +23,33 @@ import (
 	"github.com/containous/traefik/cluster"
 	"github.com/containous/traefik/configuration"
 	"github.com/containous/traefik/h2c"
+	"github.com/containous/traefik/healthcheck"
 	"github.com/containous/traefik/ip"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/metrics"
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/middlewares/accesslog"
+	"github.com/containous/traefik/middlewares/extproc"
+	"github.com/containous/traefik/middlewares/mirror"
+	mratelimit "github.com/containous/traefik/middlewares/ratelimit"
 	"github.com/containous/traefik/middlewares/tracing"
 	"github.com/containous/traefik/provider"
 	"github.com/containous/traefik/safe"
 	traefiktls "github.com/containous/traefik/tls"
 	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
 	"github.com/xenolf/lego/acme"
 )
 
+const (
+	defaultKeepAliveIdle = 3 * time.Minute
+
+	// certificatesExpiryRefreshInterval is the interval at which the expiry of the served certificates is measured.
+	certificatesExpiryRefreshInterval = time.Minute
+)
+
 var httpServerLogger = stdlog.New(log.WriterLevel(logrus.DebugLevel), "", 0)
 
 func newHijackConnectionTracker() *hijackConnectionTracker {
@@ -99,6 +113,8 @@ type Server struct {
 	signals                       chan os.Signal
 	stopChan                      chan bool
 	currentConfigurations         safe.Safe
+	middlewareChains              safe.Safe
+	frontendBalancers             safe.Safe
 	providerConfigUpdateMap       map[string]chan types.ConfigMessage
 	globalConfiguration           configuration.GlobalConfiguration
 	accessLoggerMiddleware        *accesslog.LogHandler
@@ -106,11 +122,19 @@ type Server struct {
 	routinesPool                  *safe.Pool
 	leadership                    *cluster.Leadership
 	defaultForwardingRoundTripper http.RoundTripper
+	defaultForwardingTLSConfig    *tls.Config
+	dnsLastKnownAddrs             *dnsLastKnownAddrs
 	metricsRegistry               metrics.Registry
 	provider                      provider.Provider
 	configurationListeners        []func(types.Configuration)
 	entryPoints                   map[string]EntryPoint
 	bufferPool                    httputil.BufferPool
+	ocspStapler                   *traefiktls.OCSPStapler
+	kafkaProducers                *mirror.KafkaProducers
+	processorConns                *extproc.Conns
+	redisClients                  *mratelimit.RedisClients
+	dnsDiscoveries                dnsDiscoveries
+	backendRampOverrides          *middlewares.BackendRampOverrides
 }
 
 // EntryPoint entryPoint information (configuration + internalRouter)
@@ -132,6 +156,13 @@ type serverEntryPoint struct {
 	onDemandListener        func(string) (*tls.Certificate, error)
 	tlsALPNGetter           func(string) (*tls.Certificate, error)
 	hijackConnectionTracker *hijackConnectionTracker
+	proxyProtocolTLVs       *middlewares.ProxyProtocolTLVs
+	// middlewareChain holds the middlewares of the entry point, in their execution order.
+	middlewareChain middlewareChain
+	// frontendChains holds the middlewares of the frontends, after the ones of the entry point, by provider and frontend.
+	frontendChains map[string]map[string]middlewareChain
+	// frontendBalancers holds the load balancers of the backends of the frontends, by provider and frontend.
+	frontendBalancers map[string]map[string]healthcheck.BalancerHandler
 }
 
 func (s serverEntryPoint) Shutdown(ctx context.Context) {
@@ -172,6 +203,7 @@ func (s serverEntryPoint) Shutdown(ctx context.Context) {
 // connections.
 type tcpKeepAliveListener struct {
 	*net.TCPListener
+	keepAlive *configuration.TCPKeepAlive
 }
 
 func (ln tcpKeepAliveListener) Accept() (net.Conn, error) {
@@ -180,7 +212,19 @@ func (ln tcpKeepAliveListener) Accept() (net.Conn, error) {
 		return nil, err
 	}
 	tc.SetKeepAlive(true)
-	tc.SetKeepAlivePeriod(3 * time.Minute)
+
+	idle := defaultKeepAliveIdle
+	if ln.keepAlive != nil && ln.keepAlive.Idle > 0 {
+		idle = time.Duration(ln.keepAlive.Idle)
+	}
+	tc.SetKeepAlivePeriod(idle)
+
+	if ln.keepAlive != nil && (ln.keepAlive.Interval > 0 || ln.keepAlive.Count > 0) {
+		if err := setKeepAliveProbes(tc, time.Duration(ln.keepAlive.Interval), ln.keepAlive.Count); err != nil {
+			log.Debugf("Unable to set the keep-alive probes of %s: %v", tc.RemoteAddr(), err)
+		}
+	}
+
 	return tc, nil
 }
 
@@ -199,22 +243,33 @@ func NewServer(globalConfiguration configuration.GlobalConfiguration, provider p
 	server.configureSignals()
 	currentConfigurations := make(types.Configurations)
 	server.currentConfigurations.Set(currentConfigurations)
+	server.middlewareChains.Set(make(types.MiddlewareChains))
+	server.frontendBalancers.Set(make(map[string]map[string][]healthcheck.BalancerHandler))
 	server.providerConfigUpdateMap = make(map[string]chan types.ConfigMessage)
 
 	if server.globalConfiguration.API != nil {
 		server.globalConfiguration.API.CurrentConfigurations = &server.currentConfigurations
+		server.globalConfiguration.API.MiddlewareChains = &server.middlewareChains
 	}
 
 	server.bufferPool = newBufferPool()
 
+	server.ocspStapler = traefiktls.NewOCSPStapler()
+
 	server.routinesPool = safe.NewPool(context.Background())
 
-	transport, err := createHTTPTransport(globalConfiguration)
+	if globalConfiguration.ForwardingDNS != nil {
+		server.dnsLastKnownAddrs = newDNSLastKnownAddrs(time.Duration(globalConfiguration.ForwardingDNS.GracePeriod))
+	}
+
+	transport, err := createHTTPTransport(globalConfiguration, server.dnsLastKnownAddrs)
 	if err != nil {
 		log.Errorf("failed to create HTTP transport: %v", err)
+	} else {
+		server.defaultForwardingTLSConfig = transport.TLSClientConfig
 	}
 
-	server.defaultForwardingRoundTripper = transport
+	server.defaultForwardingRoundTripper = wrapForwardingRoundTripper(transport, globalConfiguration)
 
 	server.tracingMiddleware = globalConfiguration.Tracing
 	if server.tracingMiddleware != nil && server.tracingMiddleware.Backend != "" {
@@ -223,6 +278,26 @@ func NewServer(globalConfiguration configuration.GlobalConfiguration, provider p
 
 	server.metricsRegistry = registerMetricClients(globalConfiguration.Metrics)
 
+	var mirrorFailuresCounter gokitmetrics.Counter
+	if server.metricsRegistry.IsEnabled() {
+		mirrorFailuresCounter = server.metricsRegistry.BackendMirrorFailuresCounter()
+	}
+	server.kafkaProducers = mirror.NewKafkaProducers(mirrorFailuresCounter)
+	server.processorConns = extproc.NewConns()
+	server.redisClients = mratelimit.NewRedisClients()
+
+	server.backendRampOverrides = middlewares.NewBackendRampOverrides()
+
+	if server.globalConfiguration.API != nil {
+		server.globalConfiguration.API.HealthCheck = healthcheck.GetHealthCheck(server.metricsRegistry)
+		server.globalConfiguration.API.BackendRampOverrides = server.backendRampOverrides
+		server.globalConfiguration.API.ConfigurationValidator = server.validateConfiguration
+	}
+
+	if server.globalConfiguration.Ping != nil {
+		server.globalConfiguration.Ping.FrontendReadiness = server.frontendReadiness
+	}
+
 	if globalConfiguration.Cluster != nil {
 		// leadership creation if cluster mode
 		server.leadership = cluster.NewLeadership(server.routinesPool.Ctx(), globalConfiguration.Cluster)
@@ -252,6 +327,9 @@ func (s *Server) Start() {
 	s.routinesPool.Go(func(stop chan bool) {
 		s.listenSignals(stop)
 	})
+	s.routinesPool.Go(func(stop chan bool) {
+		s.refreshCertificatesExpiry(stop)
+	})
 }
 
 // StartWithContext starts the server and Stop/Close it when context is Done
@@ -308,6 +386,15 @@ func (s *Server) Close() {
 		}
 	}(ctx)
 	stopMetricsClients()
+	if s.kafkaProducers != nil {
+		s.kafkaProducers.Close()
+	}
+	if s.processorConns != nil {
+		s.processorConns.Close()
+	}
+	if s.redisClients != nil {
+		s.redisClients.Close()
+	}
 	s.stopLeadership()
 	s.routinesPool.Cleanup()
 	close(s.configurationChan)
@@ -369,6 +456,9 @@ func (s *Server) AddListener(listener func(types.Configuration)) {
 // getCertificate allows to customize tlsConfig.GetCertificate behavior to get the certificates inserted dynamically
 func (s *serverEntryPoint) getCertificate(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 	domainToCheck := types.CanonicalDomain(clientHello.ServerName)
+	if s.certs.StripTrailingDot {
+		domainToCheck = strings.TrimSuffix(domainToCheck, ".")
+	}
 
 	if s.tlsALPNGetter != nil {
 		cert, err := s.tlsALPNGetter(domainToCheck)
@@ -396,7 +486,49 @@ func (s *serverEntryPoint) getCertificate(clientHello *tls.ClientHelloInfo) (*tl
 	}
 
 	log.Debugf("Serving default cert for request: %q", domainToCheck)
-	return s.certs.DefaultCertificate, nil
+	return s.certs.GetDefaultCertificate(domainToCheck), nil
+}
+
+// refreshCertificatesExpiry measures the expiry of the served certificates periodically,
+// the certificates renewed by ACME in the cluster mode being only known by its account.
+func (s *Server) refreshCertificatesExpiry(stop chan bool) {
+	s.updateCertificatesExpiry()
+
+	ticker := time.NewTicker(certificatesExpiryRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.updateCertificatesExpiry()
+		}
+	}
+}
+
+// updateCertificatesExpiry sets the expiry gauge of the certificates served by the TLS entry points.
+func (s *Server) updateCertificatesExpiry() {
+	if !s.metricsRegistry.IsEnabled() {
+		return
+	}
+
+	now := time.Now()
+	domains := make(map[string][]string)
+	for entryPointName, serverEntryPoint := range s.serverEntryPoints {
+		if s.entryPoints[entryPointName].Configuration.TLS == nil || serverEntryPoint.certs == nil {
+			continue
+		}
+
+		certs := serverEntryPoint.certs.GetAllCertificates()
+		if s.globalConfiguration.ACME != nil && entryPointName == s.globalConfiguration.ACME.EntryPoint {
+			certs = append(certs, s.globalConfiguration.ACME.GetCertificates()...)
+		}
+
+		domains[entryPointName] = traefiktls.SetCertificatesExpiry(s.metricsRegistry.EntrypointTLSCertificateExpiryGauge(), entryPointName, certs, now)
+	}
+
+	metrics.OnCertificatesUpdate(domains)
 }
 
 func (s *Server) startProvider() {
@@ -444,11 +576,35 @@ func (s *Server) createTLSConfig(entryPointName string, tlsOption *traefiktls.TL
 			}
 		}
 		config.ClientCAs = pool
-		if tlsOption.ClientCA.Optional {
-			config.ClientAuth = tls.VerifyClientCertIfGiven
-		} else {
-			config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	config.ClientAuth, err = tlsOption.ClientCA.GetClientAuthType()
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsOption.SessionTickets != nil {
+		sessionTicketKeys, err := traefiktls.NewSessionTicketKeys(tlsOption.SessionTickets)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create the session ticket keys: %v", err)
+		}
+
+		s.routinesPool.GoCtx(sessionTicketKeys.Rotate)
+		sessionTicketKeys.Apply(config)
+	}
+
+	if tlsOption.ClientCA.CRL != nil {
+		if config.ClientAuth != tls.VerifyClientCertIfGiven && config.ClientAuth != tls.RequireAndVerifyClientCert {
+			return nil, errors.New("the client CRLs require the verification of the client certificates")
+		}
+
+		crlChecker, err := traefiktls.NewCRLChecker(tlsOption.ClientCA.CRL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client CRLs: %v", err)
 		}
+
+		s.routinesPool.GoCtx(crlChecker.Refresh)
+		config.VerifyPeerCertificate = crlChecker.VerifyPeerCertificate
 	}
 
 	if s.globalConfiguration.ACME != nil && entryPointName == s.globalConfiguration.ACME.EntryPoint {
@@ -479,6 +635,11 @@ func (s *Server) createTLSConfig(entryPointName string, tlsOption *traefiktls.TL
 		config.Certificates = []tls.Certificate{}
 	}
 
+	// The OCSP responses are stapled to the ACME certificates as well as to the static and dynamic ones.
+	if !tlsOption.DisableOCSPStapling && s.ocspStapler != nil {
+		config.GetCertificate = s.ocspStapler.GetCertificateFunc(config.GetCertificate)
+	}
+
 	// Set the minimum TLS version if set in the config TOML
 	if minConst, exists := traefiktls.MinVersion[s.entryPoints[entryPointName].Configuration.TLS.MinVersion]; exists {
 		config.PreferServerCipherSuites = true
@@ -507,7 +668,9 @@ func (s *Server) startServer(serverEntryPoint *serverEntryPoint) {
 
 	var err error
 	if serverEntryPoint.httpServer.TLSConfig != nil {
-		err = serverEntryPoint.httpServer.ServeTLS(serverEntryPoint.listener, "", "")
+		// The TLS configuration is served as is, rather than cloned by ServeTLS, so that its session ticket keys can be rotated.
+		// The h2c wrapper is bypassed, h2c is not allowed over TLS.
+		err = serverEntryPoint.httpServer.Server.Serve(tls.NewListener(serverEntryPoint.listener, serverEntryPoint.httpServer.TLSConfig))
 	} else {
 		err = serverEntryPoint.httpServer.Serve(serverEntryPoint.listener)
 	}
@@ -518,11 +681,29 @@ func (s *Server) startServer(serverEntryPoint *serverEntryPoint) {
 }
 
 func (s *Server) setupServerEntryPoint(newServerEntryPointName string, newServerEntryPoint *serverEntryPoint) *serverEntryPoint {
-	serverMiddlewares, err := s.buildServerEntryPointMiddlewares(newServerEntryPointName)
+	// The PROXY protocol TLVs are recorded by the listener, and forwarded by the entrypoint middlewares.
+	if config := s.entryPoints[newServerEntryPointName].Configuration.ProxyProtocol; config != nil && len(config.TLVHeaders) > 0 {
+		proxyProtocolTLVs, err := middlewares.NewProxyProtocolTLVs(config.TLVHeaders)
+		if err != nil {
+			log.Fatal("Error preparing server: ", err)
+		}
+		newServerEntryPoint.proxyProtocolTLVs = proxyProtocolTLVs
+	}
+
+	var chain middlewareChain
+	serverMiddlewares, err := s.buildServerEntryPointMiddlewares(newServerEntryPointName, &chain)
 	if err != nil {
 		log.Fatal("Error preparing server: ", err)
 	}
 
+	// The connection age is tracked with the connection state hook of the server.
+	var connectionAge *middlewares.ConnectionAge
+	if config := s.entryPoints[newServerEntryPointName].Configuration.ConnectionAge; config != nil && config.MaxAge > 0 {
+		connectionAge = middlewares.NewConnectionAge(time.Duration(config.MaxAge))
+		serverMiddlewares = append(serverMiddlewares, connectionAge)
+		chain.add(chainLevelEntryPoint, "Connection age")
+	}
+
 	newSrv, listener, err := s.prepareServer(newServerEntryPointName, s.entryPoints[newServerEntryPointName].Configuration, newServerEntryPoint.httpRouter, serverMiddlewares)
 	if err != nil {
 		log.Fatal("Error preparing server: ", err)
@@ -531,6 +712,13 @@ func (s *Server) setupServerEntryPoint(newServerEntryPointName string, newServer
 	serverEntryPoint := s.serverEntryPoints[newServerEntryPointName]
 	serverEntryPoint.httpServer = newSrv
 	serverEntryPoint.listener = listener
+	serverEntryPoint.middlewareChain = chain
+
+	// The TLS handshakes are made by the server, they are counted with its connection state hook.
+	var handshakeMetrics *traefiktls.HandshakeMetrics
+	if s.entryPoints[newServerEntryPointName].Configuration.TLS != nil && s.metricsRegistry.IsEnabled() {
+		handshakeMetrics = traefiktls.NewHandshakeMetrics(newServerEntryPointName, s.metricsRegistry.EntrypointTLSHandshakesCounter(), s.metricsRegistry.EntrypointTLSHandshakeErrorsCounter())
+	}
 
 	serverEntryPoint.hijackConnectionTracker = newHijackConnectionTracker()
 	serverEntryPoint.httpServer.ConnState = func(conn net.Conn, state http.ConnState) {
@@ -540,6 +728,14 @@ func (s *Server) setupServerEntryPoint(newServerEntryPointName string, newServer
 		case http.StateClosed:
 			serverEntryPoint.hijackConnectionTracker.RemoveHijackedConnection(conn)
 		}
+
+		if connectionAge != nil {
+			connectionAge.ConnState(conn, state)
+		}
+
+		if handshakeMetrics != nil {
+			handshakeMetrics.ConnState(conn, state)
+		}
 	}
 
 	return serverEntryPoint
@@ -564,20 +760,56 @@ func (s *Server) prepareServer(entryPointName string, entryPoint *configuration.
 		return nil, nil, fmt.Errorf("error creating TLS config: %v", err)
 	}
 
-	listener, err := net.Listen("tcp", entryPoint.Address)
+	listener, err := listen(entryPoint)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error opening listener: %v", err)
 	}
 
-	listener = tcpKeepAliveListener{listener.(*net.TCPListener)}
+	keepAliveListener := tcpKeepAliveListener{TCPListener: listener.(*net.TCPListener)}
+	if entryPoint.Transport != nil {
+		keepAliveListener.keepAlive = entryPoint.Transport.KeepAlive
+	}
+	listener = keepAliveListener
+
+	if entryPoint.Transport != nil && entryPoint.Transport.MaxConnections > 0 {
+		var connsGauge, maxConnsGauge gokitmetrics.Gauge
+		var rejectedCounter gokitmetrics.Counter
+		if s.metricsRegistry.IsEnabled() {
+			connsGauge = s.metricsRegistry.EntrypointTCPConnsGauge()
+			maxConnsGauge = s.metricsRegistry.EntrypointMaxTCPConnsGauge()
+			rejectedCounter = s.metricsRegistry.EntrypointRejectedTCPConnsCounter()
+		}
+
+		// The connections are limited under the proxy protocol and the TLS layers.
+		listener, err = newConnLimitListener(listener, entryPointName, entryPoint.Transport, connsGauge, maxConnsGauge, rejectedCounter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating connection limit listener: %v", err)
+		}
+	}
 
 	if entryPoint.ProxyProtocol != nil {
-		listener, err = buildProxyProtocolListener(entryPoint, listener)
+		listener, err = buildProxyProtocolListener(entryPoint, listener, s.proxyProtocolTLVs(entryPointName))
 		if err != nil {
 			return nil, nil, fmt.Errorf("error creating proxy protocol listener: %v", err)
 		}
 	}
 
+	if tlsConfig != nil && entryPoint.TLS.Handshakes != nil {
+		var limitedCounter gokitmetrics.Counter
+		if s.metricsRegistry.IsEnabled() {
+			limitedCounter = s.metricsRegistry.EntrypointLimitedTLSHandshakesCounter()
+		}
+
+		handshakeLimiter, err := traefiktls.NewHandshakeLimiter(entryPointName, entryPoint.TLS.Handshakes, limitedCounter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error creating TLS handshake limiter: %v", err)
+		}
+
+		// The handshakes are tracked on the connections, under the TLS layer.
+		listener = handshakeLimiter.Listener(listener)
+		handshakeLimiter.Apply(tlsConfig)
+	}
+
 	return &h2c.Server{
 			Server: &http.Server{
 				Addr:         entryPoint.Address,
@@ -593,7 +825,7 @@ func (s *Server) prepareServer(entryPointName string, entryPoint *configuration.
 		nil
 }
 
-func buildProxyProtocolListener(entryPoint *configuration.EntryPoint, listener net.Listener) (net.Listener, error) {
+func buildProxyProtocolListener(entryPoint *configuration.EntryPoint, listener net.Listener, tlvs *middlewares.ProxyProtocolTLVs) (net.Listener, error) {
 	var sourceCheck func(addr net.Addr) (bool, error)
 	if entryPoint.ProxyProtocol.Insecure {
 		sourceCheck = func(_ net.Addr) (bool, error) {
@@ -617,8 +849,13 @@ func buildProxyProtocolListener(entryPoint *configuration.EntryPoint, listener n
 
 	log.Infof("Enabling ProxyProtocol for trusted IPs %v", entryPoint.ProxyProtocol.TrustedIPs)
 
+	// The v2 headers are read under the v1 listener.
 	return &proxyproto.Listener{
-		Listener:    listener,
+		Listener: &proxyProtocolV2Listener{
+			Listener:    listener,
+			sourceCheck: sourceCheck,
+			tlvs:        tlvs,
+		},
 		SourceCheck: sourceCheck,
 	}, nil
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/containous/traefik/tls/generate"
 	"github.com/containous/traefik/types"
 	"github.com/eapache/channels"
+	gokitmetrics "github.com/go-kit/kit/metrics"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
 	"github.com/vulcand/oxy/forward"
@@ -61,7 +62,13 @@ func (s *Server) loadConfiguration(configMsg types.ConfigMessage) {
 		log.Infof("Server configuration reloaded on %s", s.serverEntryPoints[newServerEntryPointName].httpServer.Addr)
 	}
 
+	// The backend ramps switched over through the API follow the reloaded configuration again,
+	// once it is applied: until then, the requests are still served by the switched over ramps.
+	s.backendRampOverrides.Reset(configMsg.ProviderName)
+
 	s.currentConfigurations.Set(newConfigurations)
+	s.middlewareChains.Set(s.buildMiddlewareChains(newServerEntryPoints))
+	s.frontendBalancers.Set(buildFrontendBalancers(newServerEntryPoints))
 
 	for _, listener := range s.configurationListeners {
 		listener(*configMsg.Configuration)
@@ -78,6 +85,8 @@ func (s *Server) loadConfig(configurations types.Configurations, globalConfigura
 
 	backendsHandlers := map[string]http.Handler{}
 	backendsHealthCheck := map[string]*healthcheck.BackendConfig{}
+	backendsBalancers := map[string]healthcheck.BalancerHandler{}
+	backendsChains := map[string]middlewareChain{}
 
 	var postConfigs []handlerPostConfig
 
@@ -87,7 +96,7 @@ func (s *Server) loadConfig(configurations types.Configurations, globalConfigura
 		for _, frontendName := range frontendNames {
 			frontendPostConfigs, err := s.loadFrontendConfig(providerName, frontendName, config,
 				serverEntryPoints,
-				backendsHandlers, backendsHealthCheck)
+				backendsHandlers, backendsHealthCheck, backendsBalancers, backendsChains)
 			if err != nil {
 				log.Errorf("%v. Skipping frontend %s...", err, frontendName)
 			}
@@ -106,6 +115,7 @@ func (s *Server) loadConfig(configurations types.Configurations, globalConfigura
 	}
 
 	healthcheck.GetHealthCheck(s.metricsRegistry).SetBackendsConfiguration(s.routinesPool.Ctx(), backendsHealthCheck)
+	s.dnsDiscoveries.start(s.routinesPool.Ctx())
 
 	// Get new certificates list sorted per entrypoints
 	// Update certificates
@@ -126,19 +136,17 @@ func (s *Server) loadFrontendConfig(
 	providerName string, frontendName string, config *types.Configuration,
 	serverEntryPoints map[string]*serverEntryPoint,
 	backendsHandlers map[string]http.Handler, backendsHealthCheck map[string]*healthcheck.BackendConfig,
+	backendsBalancers map[string]healthcheck.BalancerHandler, backendsChains map[string]middlewareChain,
 ) ([]handlerPostConfig, error) {
 
 	frontend := config.Frontends[frontendName]
 	hostResolver := buildHostResolver(s.globalConfiguration)
 
-	if len(frontend.EntryPoints) == 0 {
-		return nil, fmt.Errorf("no entrypoint defined for frontend %s", frontendName)
+	if err := checkFrontend(frontendName, frontend, config.Backends); err != nil {
+		return nil, err
 	}
 
 	backend := config.Backends[frontend.Backend]
-	if backend == nil {
-		return nil, fmt.Errorf("undefined backend '%s' for frontend %s", frontend.Backend, frontendName)
-	}
 
 	frontendHash, err := frontend.Hash()
 	if err != nil {
@@ -155,7 +163,9 @@ func (s *Server) loadFrontendConfig(
 		if backendsHandlers[entryPointName+providerName+frontendHash] == nil {
 			log.Debugf("Creating backend %s", frontend.Backend)
 
-			handlers, responseModifier, postConfig, err := s.buildMiddlewares(frontendName, frontend, config.Backends, entryPointName, providerName)
+			var chain middlewareChain
+
+			handlers, responseModifier, postConfig, err := s.buildMiddlewares(frontendName, frontend, config.Backends, entryPointName, providerName, &chain)
 			if err != nil {
 				return nil, err
 			}
@@ -169,11 +179,15 @@ func (s *Server) loadFrontendConfig(
 				return nil, fmt.Errorf("failed to create the forwarder for frontend %s: %v", frontendName, err)
 			}
 
-			lb, healthCheckConfig, err := s.buildBalancerMiddlewares(frontendName, frontend, backend, fwd)
+			lb, balancer, healthCheckConfig, lbPostConfig, err := s.buildBalancerMiddlewares(entryPointName, providerName, frontendName, frontend, backend, fwd, &chain)
 			if err != nil {
 				return nil, err
 			}
 
+			if lbPostConfig != nil {
+				postConfigs = append(postConfigs, lbPostConfig)
+			}
+
 			// Handler used by error pages
 			if backendsHandlers[entryPointName+providerName+frontend.Backend] == nil {
 				backendsHandlers[entryPointName+providerName+frontend.Backend] = lb
@@ -183,6 +197,28 @@ func (s *Server) loadFrontendConfig(
 				backendsHealthCheck[entryPointName+providerName+frontendHash] = healthCheckConfig
 			}
 
+			// The other backends the middlewares forward some of the requests of the frontend to are built for the frontend itself.
+			for _, backendName := range frontendOtherBackends(frontend) {
+				key := frontendBackendsPrefix(entryPointName, providerName, frontendName) + backendName
+				if backendsHandlers[key] != nil {
+					continue
+				}
+
+				otherLB, otherHealthCheckConfig, otherPostConfig, err := s.buildFrontendBackend(entryPointName, providerName, frontendName, frontend,
+					backendName, config.Backends[backendName], responseModifier)
+				if err != nil {
+					return nil, err
+				}
+
+				if otherPostConfig != nil {
+					postConfigs = append(postConfigs, otherPostConfig)
+				}
+				if otherHealthCheckConfig != nil {
+					backendsHealthCheck[key] = otherHealthCheckConfig
+				}
+				backendsHandlers[key] = otherLB
+			}
+
 			n := negroni.New()
 
 			for _, handler := range handlers {
@@ -192,6 +228,8 @@ func (s *Server) loadFrontendConfig(
 			n.UseHandler(lb)
 
 			backendsHandlers[entryPointName+providerName+frontendHash] = n
+			backendsBalancers[entryPointName+providerName+frontendHash] = balancer
+			backendsChains[entryPointName+providerName+frontendHash] = chain
 		} else {
 			log.Debugf("Reusing backend %s [%s - %s - %s - %s]",
 				frontend.Backend, entryPointName, providerName, frontendName, frontendHash)
@@ -202,9 +240,14 @@ func (s *Server) loadFrontendConfig(
 			return nil, err
 		}
 
-		handler := buildMatcherMiddlewares(serverRoute, backendsHandlers[entryPointName+providerName+frontendHash])
+		var chain middlewareChain
+		handler := buildMatcherMiddlewares(serverRoute, frontend.OriginalURIHeader, backendsHandlers[entryPointName+providerName+frontendHash], &chain)
 		serverRoute.Route.Handler(handler)
 
+		chain = append(chain, backendsChains[entryPointName+providerName+frontendHash]...)
+		serverEntryPoints[entryPointName].addFrontendChain(providerName, frontendName, chain)
+		serverEntryPoints[entryPointName].addFrontendBalancer(providerName, frontendName, backendsBalancers[entryPointName+providerName+frontendHash])
+
 		err = serverRoute.Route.GetError()
 		if err != nil {
 			// FIXME error management
@@ -215,15 +258,82 @@ func (s *Server) loadFrontendConfig(
 	return postConfigs, nil
 }
 
+// frontendOtherBackends returns the backends, other than its own, the middlewares of the frontend forward some of its requests to.
+func frontendOtherBackends(frontend *types.Frontend) []string {
+	var backendNames []string
+	if frontend.BackendSchedule != nil {
+		for _, window := range frontend.BackendSchedule.Windows {
+			if window != nil {
+				backendNames = append(backendNames, window.Backend)
+			}
+		}
+	}
+	if frontend.BackendRamp != nil {
+		backendNames = append(backendNames, frontend.BackendRamp.Backend)
+	}
+
+	sort.Strings(backendNames)
+	return backendNames
+}
+
+// frontendBackendsPrefix returns the prefix of the names of the handlers of the other backends built for the frontend.
+func frontendBackendsPrefix(entryPointName, providerName, frontendName string) string {
+	return entryPointName + providerName + "/" + frontendName + "/"
+}
+
+// buildFrontendBackend builds the load balancer of another backend than the one of the frontend,
+// as if the frontend forwarded its requests to it.
+func (s *Server) buildFrontendBackend(entryPointName string, providerName string, frontendName string, frontend *types.Frontend,
+	backendName string, backend *types.Backend, responseModifier modifyResponse) (http.Handler, *healthcheck.BackendConfig, handlerPostConfig, error) {
+	log.Debugf("Creating backend %s for frontend %s", backendName, frontendName)
+
+	backendFrontend := *frontend
+	backendFrontend.Backend = backendName
+
+	entryPoint := s.entryPoints[entryPointName].Configuration
+	fwd, err := s.buildForwarder(entryPointName, entryPoint, frontendName, &backendFrontend, responseModifier, backend)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create the forwarder of backend %s for frontend %s: %v", backendName, frontendName, err)
+	}
+
+	var chain middlewareChain
+	lb, _, healthCheckConfig, postConfig, err := s.buildBalancerMiddlewares(entryPointName, providerName, frontendName, &backendFrontend, backend, fwd, &chain)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return lb, healthCheckConfig, postConfig, nil
+}
+
 func (s *Server) buildForwarder(entryPointName string, entryPoint *configuration.EntryPoint,
 	frontendName string, frontend *types.Frontend,
 	responseModifier modifyResponse, backend *types.Backend) (http.Handler, error) {
 
-	roundTripper, err := s.getRoundTripper(entryPointName, frontend.PassTLSCert, entryPoint.TLS)
+	var pinnedPublicKeys *traefiktls.PinnedPublicKeys
+	if len(backend.PinnedPublicKeys) > 0 {
+		var pinFailuresCounter gokitmetrics.Counter
+		if s.metricsRegistry.IsEnabled() {
+			pinFailuresCounter = s.metricsRegistry.BackendTLSPinFailuresCounter().With("backend", frontend.Backend)
+		}
+
+		var err error
+		pinnedPublicKeys, err = traefiktls.NewPinnedPublicKeys(backend.PinnedPublicKeys, pinFailuresCounter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pinned public keys of the backend %s: %v", frontend.Backend, err)
+		}
+	}
+
+	roundTripper, websocketTLSConfig, err := s.getRoundTripper(entryPointName, frontend.PassTLSCert, entryPoint.TLS, pinnedPublicKeys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RoundTripper for frontend %s: %v", frontendName, err)
 	}
 
+	if s.metricsRegistry.IsEnabled() {
+		roundTripper = middlewares.NewConnPoolMetricsRoundTripper(roundTripper,
+			s.metricsRegistry.BackendConnWaitHistogram().With("backend", frontend.Backend),
+			s.metricsRegistry.BackendConnWaitingGauge().With("backend", frontend.Backend))
+	}
+
 	var flushInterval parse.Duration
 	if backend.ResponseForwarding != nil {
 		err := flushInterval.Set(backend.ResponseForwarding.FlushInterval)
@@ -237,6 +347,7 @@ func (s *Server) buildForwarder(entryPointName string, entryPoint *configuration
 		forward.Stream(true),
 		forward.PassHostHeader(frontend.PassHostHeader),
 		forward.RoundTripper(roundTripper),
+		forward.WebsocketTLSClientConfig(websocketTLSConfig),
 		forward.ResponseModifier(responseModifier),
 		forward.BufferPool(s.bufferPool),
 		forward.StreamingFlushInterval(time.Duration(flushInterval)),
@@ -304,7 +415,7 @@ func (s *Server) preLoadConfiguration(configMsg types.ConfigMessage) {
 		log.Debugf("Configuration received from provider %s: %s", configMsg.ProviderName, string(jsonConf))
 	}
 
-	if configMsg.Configuration == nil || configMsg.Configuration.Backends == nil && configMsg.Configuration.Frontends == nil && configMsg.Configuration.TLS == nil {
+	if isEmptyConfiguration(configMsg.Configuration) {
 		log.Infof("Skipping empty Configuration for provider %s", configMsg.ProviderName)
 		return
 	}
@@ -326,6 +437,10 @@ func (s *Server) preLoadConfiguration(configMsg types.ConfigMessage) {
 	providerConfigUpdateCh <- configMsg
 }
 
+func isEmptyConfiguration(configuration *types.Configuration) bool {
+	return configuration == nil || configuration.Backends == nil && configuration.Frontends == nil && configuration.TLS == nil
+}
+
 func (s *Server) defaultConfigurationValues(configuration *types.Configuration) {
 	if configuration == nil || configuration.Frontends == nil {
 		return
@@ -442,7 +557,10 @@ func (s *Server) throttleProviderConfigReload(throttle time.Duration, publish ch
 	}
 }
 
-func buildMatcherMiddlewares(serverRoute *types.ServerRoute, handler http.Handler) http.Handler {
+func buildMatcherMiddlewares(serverRoute *types.ServerRoute, originalURIHeader string, handler http.Handler, chain *middlewareChain) http.Handler {
+	// The middlewares wrap the handler one after the other, the last one runs first.
+	var routeChain middlewareChain
+
 	// path replace - This needs to always be the very last on the handler chain (first in the order in this function)
 	// -- Replacing Path should happen at the very end of the Modifier chain, after all the Matcher+Modifiers ran
 	if len(serverRoute.ReplacePath) > 0 {
@@ -450,12 +568,14 @@ func buildMatcherMiddlewares(serverRoute *types.ServerRoute, handler http.Handle
 			Path:    serverRoute.ReplacePath,
 			Handler: handler,
 		}
+		routeChain.add(chainLevelRoute, "Replace path")
 	}
 
 	if len(serverRoute.ReplacePathRegex) > 0 {
 		sp := strings.Split(serverRoute.ReplacePathRegex, " ")
 		if len(sp) == 2 {
 			handler = middlewares.NewReplacePathRegexHandler(sp[0], sp[1], handler)
+			routeChain.add(chainLevelRoute, "Replace path regex")
 		} else {
 			log.Warnf("Invalid syntax for ReplacePathRegex: %s. Separate the regular expression and the replacement by a space.", serverRoute.ReplacePathRegex)
 		}
@@ -468,6 +588,7 @@ func buildMatcherMiddlewares(serverRoute *types.ServerRoute, handler http.Handle
 			Prefix:  serverRoute.AddPrefix,
 			Handler: handler,
 		}
+		routeChain.add(chainLevelRoute, "Add prefix")
 	}
 
 	// strip prefix
@@ -476,20 +597,49 @@ func buildMatcherMiddlewares(serverRoute *types.ServerRoute, handler http.Handle
 			Prefixes: serverRoute.StripPrefixes,
 			Handler:  handler,
 		}
+		routeChain.add(chainLevelRoute, "Strip prefix")
 	}
 
 	// strip prefix with regex
 	if len(serverRoute.StripPrefixesRegex) > 0 {
 		handler = middlewares.NewStripPrefixRegex(handler, serverRoute.StripPrefixesRegex)
+		routeChain.add(chainLevelRoute, "Strip prefix regex")
+	}
+
+	// original URI - This needs to always be the very first on the handler chain (last in the order in this function)
+	// -- Recording the URI should happen before any Modifier rewrites it
+	if len(originalURIHeader) > 0 {
+		handler = &middlewares.OriginalURI{
+			Header:  originalURIHeader,
+			Handler: handler,
+		}
+		routeChain.add(chainLevelRoute, "Original URI")
+	}
+
+	if chain != nil {
+		*chain = append(*chain, routeChain.reversed()...)
 	}
 
 	return handler
 }
 
 func (s *Server) postLoadConfiguration() {
+	// The Kafka producers, the processor connections and the Redis clients are only used by the middlewares,
+	// their handlers are switched by now.
+	if s.kafkaProducers != nil {
+		s.kafkaProducers.Sweep()
+	}
+	if s.processorConns != nil {
+		s.processorConns.Sweep()
+	}
+	if s.redisClients != nil {
+		s.redisClients.Sweep()
+	}
+
 	if s.metricsRegistry.IsEnabled() {
 		activeConfig := s.currentConfigurations.Get().(types.Configurations)
 		metrics.OnConfigurationUpdate(activeConfig)
+		s.updateCertificatesExpiry()
 	}
 
 	if s.globalConfiguration.ACME == nil || s.leadership == nil || !s.leadership.IsLeader() {
@@ -556,6 +706,8 @@ func (s *Server) buildServerEntryPoints() map[string]*serverEntryPoint {
 			serverEntryPoints[entryPointName].certs = traefiktls.NewCertificateStore()
 		}
 
+		serverEntryPoints[entryPointName].certs.StripTrailingDot = entryPoint.Configuration.StripHostTrailingDot
+
 		if entryPoint.Configuration.TLS != nil {
 			serverEntryPoints[entryPointName].certs.SniStrict = entryPoint.Configuration.TLS.SniStrict
 
@@ -574,6 +726,24 @@ func (s *Server) buildServerEntryPoints() map[string]*serverEntryPoint {
 				}
 				serverEntryPoints[entryPointName].certs.DefaultCertificate = cert
 			}
+
+			sniDefaultCertificates := make(map[string]*tls.Certificate)
+			for _, sniDefaultCertificate := range entryPoint.Configuration.TLS.SNIDefaultCertificates {
+				pattern := strings.ToLower(strings.TrimSpace(sniDefaultCertificate.SNI))
+				if len(pattern) == 0 || pattern == "*." {
+					log.Errorf("Invalid SNI pattern %q for a default certificate of the entry point %s", sniDefaultCertificate.SNI, entryPointName)
+					continue
+				}
+
+				cert, err := buildDefaultCertificate(&traefiktls.Certificate{CertFile: sniDefaultCertificate.CertFile, KeyFile: sniDefaultCertificate.KeyFile})
+				if err != nil {
+					log.Errorf("Unable to load the default certificate of the SNI pattern %s: %v", pattern, err)
+					continue
+				}
+				sniDefaultCertificates[pattern] = cert
+			}
+			serverEntryPoints[entryPointName].certs.SNIDefaultCertificates = sniDefaultCertificates
+
 			if len(entryPoint.Configuration.TLS.Certificates) > 0 {
 				config, _ := entryPoint.Configuration.TLS.Certificates.CreateTLSConfig(entryPointName)
 				certMap := s.buildNameOrIPToCertificate(config.Certificates)
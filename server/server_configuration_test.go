@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
 	"testing"
 	"time"
 
@@ -86,7 +87,7 @@ func TestServerLoadConfigHealthCheckOptions(t *testing.T) {
 		},
 	}
 
-	for _, lbMethod := range []string{"Wrr", "Drr"} {
+	for _, lbMethod := range []string{"Wrr", "Drr", "P2c", "LeastConn", "least-conn"} {
 		for _, healthCheck := range healthChecks {
 			t.Run(fmt.Sprintf("%s/hc=%t", lbMethod, healthCheck != nil), func(t *testing.T) {
 				globalConfig := configuration.GlobalConfiguration{
@@ -281,6 +282,118 @@ func TestReuseBackend(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, responseRecorderUnauthorized.Result().StatusCode, "status code")
 }
 
+func TestServerBackendRampWithUnusedBackend(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("backend"))
+	}))
+	defer backendServer.Close()
+
+	rampServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("ramp"))
+	}))
+	defer rampServer.Close()
+
+	globalConfig := configuration.GlobalConfiguration{
+		DefaultEntryPoints: []string{"http"},
+	}
+
+	entryPoints := map[string]EntryPoint{
+		"http": {Configuration: &configuration.EntryPoint{
+			ForwardedHeaders: &configuration.ForwardedHeaders{Insecure: true},
+		}},
+	}
+
+	// The ramp backend isn't the backend of any frontend.
+	dynamicConfigs := types.Configurations{
+		"config": th.BuildConfiguration(
+			th.WithFrontends(
+				th.WithFrontend("backend",
+					th.WithFrontendName("frontend0"),
+					th.WithEntryPoints("http"),
+					th.WithRoutes(th.WithRoute("/ok", "Path: /ok")),
+					func(f *types.Frontend) {
+						f.BackendRamp = &types.BackendRamp{
+							Backend: "ramp",
+							Start:   "2018-10-01T00:00:00Z",
+							End:     "2018-10-08T00:00:00Z",
+						}
+					}),
+			),
+			th.WithBackends(
+				th.WithBackendNew("backend", th.WithLBMethod("wrr"), th.WithServersNew(th.WithServerNew(backendServer.URL))),
+				th.WithBackendNew("ramp", th.WithLBMethod("wrr"), th.WithServersNew(th.WithServerNew(rampServer.URL))),
+			),
+		),
+	}
+
+	srv := NewServer(globalConfig, nil, entryPoints)
+
+	serverEntryPoints := srv.loadConfig(dynamicConfigs, globalConfig)
+
+	// The ramp is over, all the requests are forwarded to the ramp backend.
+	recorder := httptest.NewRecorder()
+	serverEntryPoints["http"].httpRouter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, backendServer.URL+"/ok", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ramp", recorder.Body.String())
+}
+
+func TestServerBackendScheduleWithUnusedBackend(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("backend"))
+	}))
+	defer backendServer.Close()
+
+	windowServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("window"))
+	}))
+	defer windowServer.Close()
+
+	globalConfig := configuration.GlobalConfiguration{
+		DefaultEntryPoints: []string{"http"},
+	}
+
+	entryPoints := map[string]EntryPoint{
+		"http": {Configuration: &configuration.EntryPoint{
+			ForwardedHeaders: &configuration.ForwardedHeaders{Insecure: true},
+		}},
+	}
+
+	// The backend of the window isn't the backend of any frontend.
+	dynamicConfigs := types.Configurations{
+		"config": th.BuildConfiguration(
+			th.WithFrontends(
+				th.WithFrontend("backend",
+					th.WithFrontendName("frontend0"),
+					th.WithEntryPoints("http"),
+					th.WithRoutes(th.WithRoute("/ok", "Path: /ok")),
+					func(f *types.Frontend) {
+						f.BackendSchedule = &types.BackendSchedule{
+							Windows: map[string]*types.ScheduleWindow{
+								"always": {Backend: "window"},
+							},
+						}
+					}),
+			),
+			th.WithBackends(
+				th.WithBackendNew("backend", th.WithLBMethod("wrr"), th.WithServersNew(th.WithServerNew(backendServer.URL))),
+				th.WithBackendNew("window", th.WithLBMethod("wrr"), th.WithServersNew(th.WithServerNew(windowServer.URL))),
+			),
+		),
+	}
+
+	srv := NewServer(globalConfig, nil, entryPoints)
+
+	serverEntryPoints := srv.loadConfig(dynamicConfigs, globalConfig)
+
+	// The window lasts all day, every day.
+	recorder := httptest.NewRecorder()
+	serverEntryPoints["http"].httpRouter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, backendServer.URL+"/ok", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "window", recorder.Body.String())
+}
+
 func TestThrottleProviderConfigReload(t *testing.T) {
 	throttleDuration := 30 * time.Millisecond
 	publishConfig := make(chan types.ConfigMessage)
@@ -411,7 +524,7 @@ func TestServerMultipleFrontendRules(t *testing.T) {
 				require.Equal(t, test.expectedURL, r.URL.String(), "URL")
 			})
 
-			hd := buildMatcherMiddlewares(serverRoute, handler)
+			hd := buildMatcherMiddlewares(serverRoute, "", handler, nil)
 			serverRoute.Route.Handler(hd)
 
 			serverRoute.Route.GetHandler().ServeHTTP(nil, request)
@@ -522,6 +635,67 @@ func TestServerBuildHealthCheckOptions(t *testing.T) {
 				LB:       lb,
 			},
 		},
+		{
+			desc: "gRPC mode without path",
+			hc: &types.HealthCheck{
+				Mode:    "gRPC",
+				Service: "foo.Bar",
+			},
+			expectedOpts: &healthcheck.Options{
+				Mode:     healthcheck.ModeGRPC,
+				Service:  "foo.Bar",
+				Interval: globalInterval,
+				Timeout:  globalTimeout,
+				LB:       lb,
+			},
+		},
+		{
+			desc: "HTTP mode",
+			hc: &types.HealthCheck{
+				Mode: "http",
+				Path: "/path",
+			},
+			expectedOpts: &healthcheck.Options{
+				Path:     "/path",
+				Interval: globalInterval,
+				Timeout:  globalTimeout,
+				LB:       lb,
+			},
+		},
+		{
+			desc: "unknown mode",
+			hc: &types.HealthCheck{
+				Mode: "tcp",
+				Path: "/path",
+			},
+			expectedOpts: nil,
+		},
+		{
+			desc: "expected body and headers",
+			hc: &types.HealthCheck{
+				Path:              "/path",
+				ExpectedBody:      "ok",
+				ExpectedBodyRegex: `"status":\s*"up"`,
+				ExpectedHeaders:   map[string]string{"X-Health": "up"},
+			},
+			expectedOpts: &healthcheck.Options{
+				Path:              "/path",
+				Interval:          globalInterval,
+				Timeout:           globalTimeout,
+				LB:                lb,
+				ExpectedBody:      "ok",
+				ExpectedBodyRegex: regexp.MustCompile(`"status":\s*"up"`),
+				ExpectedHeaders:   map[string]string{"X-Health": "up"},
+			},
+		},
+		{
+			desc: "invalid expected body regex",
+			hc: &types.HealthCheck{
+				Path:              "/path",
+				ExpectedBodyRegex: "(",
+			},
+			expectedOpts: nil,
+		},
 	}
 
 	for _, test := range testCases {
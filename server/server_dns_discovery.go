@@ -0,0 +1,515 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/miekg/dns"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+const (
+	defaultDNSDiscoveryWeight  = 10
+	defaultDNSDiscoveryRefresh = 30 * time.Second
+	minDNSDiscoveryRefresh     = time.Second
+	dnsDiscoveryRetryInterval  = 5 * time.Second
+	dnsDiscoveryTimeout        = 5 * time.Second
+	dnsDiscoveryRampSteps      = 10
+	resolvConfPath             = "/etc/resolv.conf"
+)
+
+// dnsResolver resolves the IPs of the A and AAAA records of a name.
+type dnsResolver interface {
+	// lookup returns the IPs and the lowest TTL of their records, zero when it is unknown.
+	lookup(ctx context.Context, name string) ([]net.IP, time.Duration, error)
+}
+
+// systemResolver queries the name servers of the resolver configuration file, to know the TTL of the records,
+// and falls back to the system resolver, e.g. for the names of the hosts file.
+type systemResolver struct {
+	resolvConf string
+}
+
+func (r systemResolver) lookup(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	ips, ttl, err := r.lookupRecords(ctx, name)
+	if err == nil && len(ips) > 0 {
+		return ips, ttl, nil
+	}
+	if err != nil {
+		log.Debugf("Failed to query the name servers for %s, using the system resolver: %v", name, err)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ips = nil
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, 0, nil
+}
+
+func (r systemResolver) lookupRecords(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	config, err := dns.ClientConfigFromFile(r.resolvConf)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid resolver configuration file %s: %v", r.resolvConf, err)
+	}
+
+	client := &dns.Client{}
+
+	for _, fqdn := range config.NameList(name) {
+		var ips []net.IP
+		var ttl uint32
+
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			msg := &dns.Msg{}
+			msg.SetQuestion(fqdn, qtype)
+
+			resp, err := exchange(ctx, client, msg, config)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			for _, rr := range resp.Answer {
+				switch record := rr.(type) {
+				case *dns.A:
+					ips = append(ips, record.A)
+				case *dns.AAAA:
+					ips = append(ips, record.AAAA)
+				default:
+					continue
+				}
+
+				if len(ips) == 1 || rr.Header().Ttl < ttl {
+					ttl = rr.Header().Ttl
+				}
+			}
+		}
+
+		if len(ips) > 0 {
+			return ips, time.Duration(ttl) * time.Second, nil
+		}
+	}
+
+	return nil, 0, nil
+}
+
+// exchange sends the message to the name servers in turn, until one of them answers.
+func exchange(ctx context.Context, client *dns.Client, msg *dns.Msg, config *dns.ClientConfig) (*dns.Msg, error) {
+	err := errors.New("no name server")
+	for _, server := range config.Servers {
+		var resp *dns.Msg
+		resp, _, err = client.ExchangeContext(ctx, msg, net.JoinHostPort(server, config.Port))
+		if err == nil {
+			return resp, nil
+		}
+	}
+	return nil, err
+}
+
+// dnsDiscovery keeps the servers of load balancers in line with the IPs resolved from a DNS name.
+// The new servers join with a weight growing during the ramp duration, when the load balancers have weighted servers,
+// and the removed ones no longer receive requests, but complete their in-flight ones.
+// When the resolution fails, the servers are kept, until the failure lasts longer than the grace period, if any.
+// The discoveries of the load balancers of a backend are merged into one, which resolves the name for all of them.
+type dnsDiscovery struct {
+	backendName   string
+	name          string
+	scheme        string
+	port          string
+	weight        int
+	refresh       time.Duration
+	rampDuration  time.Duration
+	gracePeriod   time.Duration
+	lbs           []healthcheck.BalancerHandler
+	resolver      dnsResolver
+	serverUpGauge gokitmetrics.Gauge
+
+	lock        sync.Mutex
+	mergedInto  *dnsDiscovery
+	resolved    bool
+	servers     map[string]*url.URL
+	rampStarts  map[string]time.Time
+	nextRefresh time.Duration
+
+	failingSince time.Time
+}
+
+func newDNSDiscovery(backendName string, config *types.DNSDiscovery, lb healthcheck.BalancerHandler, resolver dnsResolver, serverUpGauge gokitmetrics.Gauge) (*dnsDiscovery, error) {
+	if config.Name == "" {
+		return nil, errors.New("the DNS name is required")
+	}
+
+	d := &dnsDiscovery{
+		backendName:   backendName,
+		name:          config.Name,
+		scheme:        config.Scheme,
+		weight:        defaultDNSDiscoveryWeight,
+		refresh:       time.Duration(config.RefreshInterval),
+		rampDuration:  time.Duration(config.RampDuration),
+		lbs:           []healthcheck.BalancerHandler{lb},
+		resolver:      resolver,
+		serverUpGauge: serverUpGauge,
+		servers:       make(map[string]*url.URL),
+		rampStarts:    make(map[string]time.Time),
+	}
+
+	switch config.Scheme {
+	case "":
+		d.scheme = "http"
+	case "http", "https", "h2c":
+	default:
+		return nil, fmt.Errorf("invalid scheme %q", config.Scheme)
+	}
+
+	if config.Port < 0 || config.Port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", config.Port)
+	}
+	if config.Port > 0 {
+		d.port = strconv.Itoa(config.Port)
+	}
+
+	if config.Weight < 0 || config.RefreshInterval < 0 || config.RampDuration < 0 {
+		return nil, errors.New("the weight, refresh interval and ramp duration must be positive")
+	}
+	if config.Weight > 0 {
+		d.weight = config.Weight
+	}
+	if d.refresh > 0 && d.refresh < minDNSDiscoveryRefresh {
+		d.refresh = minDNSDiscoveryRefresh
+	}
+
+	// The weights only ramp for the weighted round robin: the other load balancers ignore them,
+	// or adjust them by themselves.
+	if _, ok := lb.(*roundrobin.RoundRobin); !ok {
+		d.rampDuration = 0
+	}
+
+	return d, nil
+}
+
+// resolve updates the servers with the resolved IPs, and returns the delay before the next resolution:
+// the refresh interval if any, or else the TTL of the records.
+// When the resolution fails, the servers are kept during the grace period, and the resolution is retried shortly.
+func (d *dnsDiscovery) resolve(ctx context.Context) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, dnsDiscoveryTimeout)
+	defer cancel()
+
+	now := time.Now()
+
+	ips, ttl, err := d.resolver.lookup(ctx, d.name)
+	if err == nil && len(ips) == 0 {
+		err = errors.New("no A or AAAA record")
+	}
+	if err != nil {
+		if d.failingSince.IsZero() {
+			d.failingSince = now
+		}
+
+		if d.gracePeriod > 0 && now.Sub(d.failingSince) > d.gracePeriod {
+			if d.hasServers() {
+				log.Errorf("Failed to resolve %s for backend %s for more than %s, removing the servers: %v", d.name, d.backendName, d.gracePeriod, err)
+				d.update(nil, now)
+			}
+			return dnsDiscoveryRetryInterval
+		}
+
+		log.Errorf("Failed to resolve %s for backend %s, keeping the current servers: %v", d.name, d.backendName, err)
+		return dnsDiscoveryRetryInterval
+	}
+
+	d.failingSince = time.Time{}
+	d.update(ips, now)
+
+	switch {
+	case d.refresh > 0:
+		return d.refresh
+	case ttl <= 0:
+		return defaultDNSDiscoveryRefresh
+	case ttl < minDNSDiscoveryRefresh:
+		return minDNSDiscoveryRefresh
+	default:
+		return ttl
+	}
+}
+
+// update adds the servers of the new IPs, and removes the servers of the IPs no longer resolved.
+// The servers of the first resolution get their full weight right away.
+func (d *dnsDiscovery) update(ips []net.IP, now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	resolved := make(map[string]*url.URL)
+	for _, ip := range ips {
+		u := d.serverURL(ip)
+		resolved[u.String()] = u
+	}
+
+	var added []string
+	for key := range resolved {
+		if _, ok := d.servers[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+
+	for _, key := range added {
+		u := resolved[key]
+
+		weight := d.weight
+		if d.resolved && d.rampDuration > 0 {
+			weight = 1
+			d.rampStarts[key] = now
+		}
+
+		log.Debugf("Adding server %s to backend %s with weight %d", u, d.backendName, weight)
+		for _, lb := range d.lbs {
+			if err := lb.UpsertServer(u, roundrobin.Weight(weight)); err != nil {
+				log.Errorf("Error adding server %s to backend %s: %v", u, d.backendName, err)
+			}
+		}
+
+		d.servers[key] = u
+		if d.serverUpGauge != nil {
+			d.serverUpGauge.With("backend", d.backendName, "url", key).Set(1)
+		}
+	}
+
+	for key, u := range d.servers {
+		if _, ok := resolved[key]; ok {
+			continue
+		}
+
+		log.Debugf("Removing server %s from backend %s, its record was removed", u, d.backendName)
+		delete(d.servers, key)
+		delete(d.rampStarts, key)
+
+		for _, lb := range d.lbs {
+			// The server may already be disabled by the health check.
+			if containsURL(lb.Servers(), u) {
+				if err := lb.RemoveServer(u); err != nil {
+					log.Errorf("Error removing server %s from backend %s: %v", u, d.backendName, err)
+				}
+			}
+		}
+
+		if d.serverUpGauge != nil {
+			d.serverUpGauge.With("backend", d.backendName, "url", key).Set(0)
+		}
+	}
+
+	// The servers returned to the load balancers by the health checks get their weight back.
+	for _, lb := range d.lbs {
+		rr, ok := lb.(*roundrobin.RoundRobin)
+		if !ok {
+			continue
+		}
+
+		for key, u := range d.servers {
+			if _, ramping := d.rampStarts[key]; ramping {
+				continue
+			}
+
+			if weight, ok := rr.ServerWeight(u); ok && weight != d.weight {
+				if err := rr.UpsertServer(u, roundrobin.Weight(d.weight)); err != nil {
+					log.Errorf("Error updating the weight of server %s of backend %s: %v", u, d.backendName, err)
+				}
+			}
+		}
+	}
+
+	d.resolved = true
+}
+
+// ramp raises the weight of the new servers, in proportion to the time elapsed since they were added.
+// The servers disabled by the health check keep ramping, and get the current weight once enabled again.
+func (d *dnsDiscovery) ramp(now time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.rampStarts) == 0 {
+		return
+	}
+
+	for key, start := range d.rampStarts {
+		weight := d.weight
+		if elapsed := now.Sub(start); elapsed < d.rampDuration {
+			weight = int(int64(d.weight) * int64(elapsed) / int64(d.rampDuration))
+			if weight < 1 {
+				weight = 1
+			}
+		} else {
+			delete(d.rampStarts, key)
+		}
+
+		u := d.servers[key]
+		for _, lb := range d.lbs {
+			if !containsURL(lb.Servers(), u) {
+				continue
+			}
+
+			if err := lb.UpsertServer(u, roundrobin.Weight(weight)); err != nil {
+				log.Errorf("Error updating the weight of server %s of backend %s: %v", u, d.backendName, err)
+			}
+		}
+	}
+}
+
+func (d *dnsDiscovery) hasServers() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return len(d.servers) > 0
+}
+
+// removed returns whether the server is no longer resolved.
+func (d *dnsDiscovery) removed(u *url.URL) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.mergedInto != nil {
+		return d.mergedInto.removed(u)
+	}
+
+	_, ok := d.servers[u.String()]
+	return !ok
+}
+
+// merge makes the discovery update the load balancers of the other discovery too,
+// and the other discovery defer to it.
+func (d *dnsDiscovery) merge(other *dnsDiscovery) {
+	d.lock.Lock()
+	d.lbs = append(d.lbs, other.lbs...)
+	d.lock.Unlock()
+
+	other.lock.Lock()
+	other.mergedInto = d
+	other.lock.Unlock()
+}
+
+func (d *dnsDiscovery) serverURL(ip net.IP) *url.URL {
+	host := ip.String()
+	if d.port != "" {
+		host = net.JoinHostPort(host, d.port)
+	} else if ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+
+	return &url.URL{Scheme: d.scheme, Host: host}
+}
+
+func (d *dnsDiscovery) run(ctx context.Context) {
+	refresh := time.NewTimer(d.nextRefresh)
+	defer refresh.Stop()
+
+	var rampC <-chan time.Time
+	if d.rampDuration > 0 {
+		ticker := time.NewTicker(d.rampDuration / dnsDiscoveryRampSteps)
+		defer ticker.Stop()
+		rampC = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debugf("Stopping the DNS discovery of backend %s", d.backendName)
+			return
+		case <-refresh.C:
+			refresh.Reset(d.resolve(ctx))
+		case now := <-rampC:
+			d.ramp(now)
+		}
+	}
+}
+
+func containsURL(urls []*url.URL, u *url.URL) bool {
+	for _, other := range urls {
+		if other.String() == u.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// dnsDiscoveries runs the DNS discoveries of the load balancers of the current configuration.
+type dnsDiscoveries struct {
+	lock    sync.Mutex
+	pending map[string]*dnsDiscovery
+	cancel  context.CancelFunc
+}
+
+// add registers a discovery, run once its configuration is loaded.
+// The discoveries added with the same key, those of the same backend, are merged into the first one.
+func (d *dnsDiscoveries) add(key string, discovery *dnsDiscovery) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.pending == nil {
+		d.pending = make(map[string]*dnsDiscovery)
+	}
+
+	if first, ok := d.pending[key]; ok {
+		first.merge(discovery)
+		return
+	}
+	d.pending[key] = discovery
+}
+
+// start stops the discoveries of the previous configuration, and runs the registered ones,
+// once their servers are resolved.
+func (d *dnsDiscoveries) start(parentCtx context.Context) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.cancel != nil {
+		d.cancel()
+	}
+	ctx, cancel := context.WithCancel(parentCtx)
+	d.cancel = cancel
+
+	for _, discovery := range d.pending {
+		discovery.nextRefresh = discovery.resolve(ctx)
+
+		currentDiscovery := discovery
+		safe.Go(func() {
+			currentDiscovery.run(ctx)
+		})
+	}
+	d.pending = nil
+}
+
+// buildDNSDiscovery creates the discovery of the servers of the load balancer of a backend,
+// which resolves them once the configuration is loaded.
+func (s *Server) buildDNSDiscovery(backendName string, backend *types.Backend, lb healthcheck.BalancerHandler) (*dnsDiscovery, error) {
+	if len(backend.Servers) > 0 {
+		return nil, errors.New("a backend with a DNS discovery can't have servers")
+	}
+
+	discovery, err := newDNSDiscovery(backendName, backend.DNSDiscovery, lb, systemResolver{resolvConf: resolvConfPath},
+		s.metricsRegistry.BackendServerUpGauge())
+	if err != nil {
+		return nil, err
+	}
+
+	if dnsConfig := s.globalConfiguration.ForwardingDNS; dnsConfig != nil {
+		discovery.gracePeriod = time.Duration(dnsConfig.GracePeriod)
+	}
+
+	log.Debugf("Creating DNS discovery of %s for backend %s", backend.DNSDiscovery.Name, backendName)
+
+	return discovery, nil
+}
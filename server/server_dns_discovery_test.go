@@ -0,0 +1,348 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/middlewares/leastconn"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vulcand/oxy/roundrobin"
+)
+
+type fakeResolver struct {
+	ips     []net.IP
+	ttl     time.Duration
+	err     error
+	lookups int
+}
+
+func (r *fakeResolver) lookup(ctx context.Context, name string) ([]net.IP, time.Duration, error) {
+	r.lookups++
+	return r.ips, r.ttl, r.err
+}
+
+func newTestRoundRobin(t *testing.T) *roundrobin.RoundRobin {
+	rr, err := roundrobin.New(http.NotFoundHandler())
+	require.NoError(t, err)
+	return rr
+}
+
+func serverWeights(rr *roundrobin.RoundRobin) map[string]int {
+	weights := make(map[string]int)
+	for _, u := range rr.Servers() {
+		weights[u.String()], _ = rr.ServerWeight(u)
+	}
+	return weights
+}
+
+func TestNewDNSDiscovery(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		config      *types.DNSDiscovery
+		expectedURL string
+		expectedErr bool
+	}{
+		{
+			desc:        "defaults",
+			config:      &types.DNSDiscovery{Name: "foo.bar"},
+			expectedURL: "http://10.0.0.1",
+		},
+		{
+			desc:        "scheme and port",
+			config:      &types.DNSDiscovery{Name: "foo.bar", Scheme: "https", Port: 8443},
+			expectedURL: "https://10.0.0.1:8443",
+		},
+		{
+			desc:        "missing name",
+			config:      &types.DNSDiscovery{},
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid scheme",
+			config:      &types.DNSDiscovery{Name: "foo.bar", Scheme: "ftp"},
+			expectedErr: true,
+		},
+		{
+			desc:        "invalid port",
+			config:      &types.DNSDiscovery{Name: "foo.bar", Port: 70000},
+			expectedErr: true,
+		},
+		{
+			desc:        "negative weight",
+			config:      &types.DNSDiscovery{Name: "foo.bar", Weight: -1},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			discovery, err := newDNSDiscovery("backend", test.config, newTestRoundRobin(t), &fakeResolver{}, nil)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedURL, discovery.serverURL(net.ParseIP("10.0.0.1")).String())
+		})
+	}
+}
+
+func TestDNSDiscoveryServerURLIPv6(t *testing.T) {
+	discovery, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar"}, newTestRoundRobin(t), &fakeResolver{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "http://[::1]", discovery.serverURL(net.ParseIP("::1")).String())
+
+	discovery.port = "8080"
+	assert.Equal(t, "http://[::1]:8080", discovery.serverURL(net.ParseIP("::1")).String())
+}
+
+func TestDNSDiscoveryResolve(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		config          *types.DNSDiscovery
+		resolver        *fakeResolver
+		expectedRefresh time.Duration
+		expectedServers []string
+	}{
+		{
+			desc:            "refresh on the TTL",
+			config:          &types.DNSDiscovery{Name: "foo.bar"},
+			resolver:        &fakeResolver{ips: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, ttl: 10 * time.Second},
+			expectedRefresh: 10 * time.Second,
+			expectedServers: []string{"http://10.0.0.1", "http://10.0.0.2"},
+		},
+		{
+			desc:            "refresh interval",
+			config:          &types.DNSDiscovery{Name: "foo.bar", RefreshInterval: parse.Duration(time.Minute)},
+			resolver:        &fakeResolver{ips: []net.IP{net.ParseIP("10.0.0.1")}, ttl: 10 * time.Second},
+			expectedRefresh: time.Minute,
+			expectedServers: []string{"http://10.0.0.1"},
+		},
+		{
+			desc:            "unknown TTL",
+			config:          &types.DNSDiscovery{Name: "foo.bar"},
+			resolver:        &fakeResolver{ips: []net.IP{net.ParseIP("10.0.0.1")}},
+			expectedRefresh: defaultDNSDiscoveryRefresh,
+			expectedServers: []string{"http://10.0.0.1"},
+		},
+		{
+			desc:            "TTL below the minimum",
+			config:          &types.DNSDiscovery{Name: "foo.bar"},
+			resolver:        &fakeResolver{ips: []net.IP{net.ParseIP("10.0.0.1")}, ttl: time.Millisecond},
+			expectedRefresh: minDNSDiscoveryRefresh,
+			expectedServers: []string{"http://10.0.0.1"},
+		},
+		{
+			desc:            "resolution error",
+			config:          &types.DNSDiscovery{Name: "foo.bar"},
+			resolver:        &fakeResolver{err: errors.New("no such host")},
+			expectedRefresh: dnsDiscoveryRetryInterval,
+		},
+		{
+			desc:            "no records",
+			config:          &types.DNSDiscovery{Name: "foo.bar"},
+			resolver:        &fakeResolver{},
+			expectedRefresh: dnsDiscoveryRetryInterval,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rr := newTestRoundRobin(t)
+			gauge := &testhelpers.CollectingGauge{}
+
+			discovery, err := newDNSDiscovery("backend", test.config, rr, test.resolver, gauge)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedRefresh, discovery.resolve(context.Background()))
+
+			var servers []string
+			for _, u := range rr.Servers() {
+				servers = append(servers, u.String())
+			}
+			sort.Strings(servers)
+			assert.Equal(t, test.expectedServers, servers)
+
+			if len(test.expectedServers) > 0 {
+				assert.Equal(t, float64(1), gauge.GaugeValue)
+			}
+		})
+	}
+}
+
+func TestDNSDiscoveryGracePeriod(t *testing.T) {
+	rr := newTestRoundRobin(t)
+	resolver := &fakeResolver{ips: []net.IP{net.ParseIP("10.0.0.1")}}
+
+	discovery, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar"}, rr, resolver, nil)
+	require.NoError(t, err)
+	discovery.gracePeriod = time.Minute
+
+	discovery.resolve(context.Background())
+	require.Len(t, rr.Servers(), 1)
+
+	// The servers are kept during the grace period of the resolution failure.
+	resolver.err = errors.New("server misbehaving")
+	assert.Equal(t, dnsDiscoveryRetryInterval, discovery.resolve(context.Background()))
+	assert.Len(t, rr.Servers(), 1)
+
+	// The servers are removed once the failure lasts longer than the grace period.
+	discovery.failingSince = time.Now().Add(-2 * time.Minute)
+	assert.Equal(t, dnsDiscoveryRetryInterval, discovery.resolve(context.Background()))
+	assert.Empty(t, rr.Servers())
+	assert.True(t, discovery.removed(testhelpers.MustParseURL("http://10.0.0.1")))
+
+	// The servers are back with the resolution.
+	resolver.err = nil
+	discovery.resolve(context.Background())
+	assert.Len(t, rr.Servers(), 1)
+	assert.True(t, discovery.failingSince.IsZero())
+}
+
+func TestDNSDiscoveryUpdate(t *testing.T) {
+	rr := newTestRoundRobin(t)
+	discovery, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar", RampDuration: parse.Duration(10 * time.Second)}, rr, &fakeResolver{}, nil)
+	require.NoError(t, err)
+
+	now := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// The servers of the first resolution get their full weight.
+	discovery.update([]net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, now)
+	assert.Equal(t, map[string]int{"http://10.0.0.1": 10, "http://10.0.0.2": 10}, serverWeights(rr))
+
+	// A new server ramps in, and a removed one leaves the load balancer.
+	discovery.update([]net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")}, now)
+	assert.Equal(t, map[string]int{"http://10.0.0.2": 10, "http://10.0.0.3": 1}, serverWeights(rr))
+	assert.True(t, discovery.removed(testhelpers.MustParseURL("http://10.0.0.1")))
+	assert.False(t, discovery.removed(testhelpers.MustParseURL("http://10.0.0.3")))
+
+	discovery.ramp(now.Add(2 * time.Second))
+	assert.Equal(t, map[string]int{"http://10.0.0.2": 10, "http://10.0.0.3": 2}, serverWeights(rr))
+
+	discovery.ramp(now.Add(7 * time.Second))
+	assert.Equal(t, map[string]int{"http://10.0.0.2": 10, "http://10.0.0.3": 7}, serverWeights(rr))
+
+	discovery.ramp(now.Add(10 * time.Second))
+	assert.Equal(t, map[string]int{"http://10.0.0.2": 10, "http://10.0.0.3": 10}, serverWeights(rr))
+	assert.Empty(t, discovery.rampStarts)
+}
+
+func TestDNSDiscoveryHealthCheckedServers(t *testing.T) {
+	rr := newTestRoundRobin(t)
+	discovery, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar", RampDuration: parse.Duration(10 * time.Second)}, rr, &fakeResolver{}, nil)
+	require.NoError(t, err)
+
+	now := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	discovery.update([]net.IP{net.ParseIP("10.0.0.1")}, now)
+	discovery.update([]net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, now)
+
+	// The health check disables the servers.
+	require.NoError(t, rr.RemoveServer(testhelpers.MustParseURL("http://10.0.0.1")))
+	require.NoError(t, rr.RemoveServer(testhelpers.MustParseURL("http://10.0.0.2")))
+
+	// The disabled servers are not returned to the load balancer by the discovery.
+	discovery.ramp(now.Add(5 * time.Second))
+	discovery.update([]net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, now.Add(5*time.Second))
+	assert.Empty(t, rr.Servers())
+
+	// The health check returns the servers with a weight of 1, and the discovery restores their weight.
+	require.NoError(t, rr.UpsertServer(testhelpers.MustParseURL("http://10.0.0.1"), roundrobin.Weight(1)))
+	require.NoError(t, rr.UpsertServer(testhelpers.MustParseURL("http://10.0.0.2"), roundrobin.Weight(1)))
+
+	discovery.ramp(now.Add(5 * time.Second))
+	discovery.update([]net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, now.Add(5*time.Second))
+	assert.Equal(t, map[string]int{"http://10.0.0.1": 10, "http://10.0.0.2": 5}, serverWeights(rr))
+}
+
+func TestDNSDiscoveryWithoutWeights(t *testing.T) {
+	lb := leastconn.New("backend", http.NotFoundHandler(), nil)
+	discovery, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar", RampDuration: parse.Duration(10 * time.Second)}, lb, &fakeResolver{}, nil)
+	require.NoError(t, err)
+
+	now := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	discovery.update([]net.IP{net.ParseIP("10.0.0.1")}, now)
+	discovery.update([]net.IP{net.ParseIP("10.0.0.2")}, now)
+
+	// Without weights, the new servers don't ramp.
+	assert.Empty(t, discovery.rampStarts)
+	assert.Equal(t, []*url.URL{testhelpers.MustParseURL("http://10.0.0.2")}, lb.Servers())
+}
+
+func TestDNSDiscoveriesStart(t *testing.T) {
+	resolver := &fakeResolver{ips: []net.IP{net.ParseIP("10.0.0.1")}}
+
+	var discoveries dnsDiscoveries
+
+	discovery, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar"}, newTestRoundRobin(t), resolver, nil)
+	require.NoError(t, err)
+
+	discoveries.add("backend", discovery)
+	discoveries.start(context.Background())
+	assert.Empty(t, discoveries.pending)
+	assert.Equal(t, 1, resolver.lookups)
+
+	previousCancel := discoveries.cancel
+	require.NotNil(t, previousCancel)
+
+	// Starting the discoveries of a new configuration stops the previous ones.
+	discoveries.start(context.Background())
+	assert.NotNil(t, discoveries.cancel)
+	discoveries.cancel()
+}
+
+func TestDNSDiscoveriesMerge(t *testing.T) {
+	resolver := &fakeResolver{ips: []net.IP{net.ParseIP("10.0.0.1")}}
+
+	var discoveries dnsDiscoveries
+
+	rr1 := newTestRoundRobin(t)
+	discovery1, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar"}, rr1, resolver, nil)
+	require.NoError(t, err)
+
+	rr2 := newTestRoundRobin(t)
+	discovery2, err := newDNSDiscovery("backend", &types.DNSDiscovery{Name: "foo.bar"}, rr2, resolver, nil)
+	require.NoError(t, err)
+
+	discoveries.add("backend", discovery1)
+	discoveries.add("backend", discovery2)
+	discoveries.start(context.Background())
+	defer discoveries.cancel()
+
+	// The name is resolved once for the load balancers of both discoveries.
+	assert.Equal(t, 1, resolver.lookups)
+	assert.Equal(t, []*url.URL{testhelpers.MustParseURL("http://10.0.0.1")}, rr1.Servers())
+	assert.Equal(t, []*url.URL{testhelpers.MustParseURL("http://10.0.0.1")}, rr2.Servers())
+
+	assert.False(t, discovery2.removed(testhelpers.MustParseURL("http://10.0.0.1")))
+	assert.True(t, discovery2.removed(testhelpers.MustParseURL("http://10.0.0.2")))
+}
+
+func TestBuildDNSDiscoveryWithServers(t *testing.T) {
+	srv := NewServer(configuration.GlobalConfiguration{}, nil, nil)
+
+	backend := &types.Backend{
+		Servers:      map[string]types.Server{"server": {URL: "http://10.0.0.1"}},
+		DNSDiscovery: &types.DNSDiscovery{Name: "foo.bar"},
+	}
+
+	_, err := srv.buildDNSDiscovery("backend", backend, newTestRoundRobin(t))
+	assert.Error(t, err)
+}
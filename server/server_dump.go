@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+// DumpConfigurations starts the providers and returns their initial configurations, with the default values set by the server when it loads them.
+// It returns once every provider, or only the given one if any, has provided its configuration, or once the timeout is elapsed.
+// The configurations are not applied.
+func DumpConfigurations(globalConfiguration configuration.GlobalConfiguration, providerAggregator configuration.ProviderAggregator, providerName string, timeout time.Duration) (types.Configurations, error) {
+	entryPoints := make(map[string]EntryPoint)
+	for entryPointName, entryPoint := range globalConfiguration.EntryPoints {
+		entryPoints[entryPointName] = EntryPoint{Configuration: entryPoint}
+	}
+	s := &Server{globalConfiguration: globalConfiguration, entryPoints: entryPoints}
+
+	configurationChan := make(chan types.ConfigMessage, 100)
+	pool := safe.NewPool(context.Background())
+	defer func() {
+		// The providers may still be sending configurations while they are stopped.
+		stopped := make(chan bool)
+		safe.Go(func() {
+			for {
+				select {
+				case <-stopped:
+					return
+				case <-configurationChan:
+				}
+			}
+		})
+		pool.Cleanup()
+		close(stopped)
+	}()
+
+	if err := providerAggregator.Provide(configurationChan, pool); err != nil {
+		return nil, err
+	}
+
+	configurations := make(types.Configurations)
+	provided := make(map[string]bool)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		if len(providerName) > 0 && provided[providerName] || len(providerName) == 0 && len(provided) >= providerAggregator.Len() {
+			return configurations, nil
+		}
+
+		select {
+		case <-timer.C:
+			log.Warnf("Not all the providers provided their configuration after %s", timeout)
+			return configurations, nil
+		case configMsg := <-configurationChan:
+			if provided[configMsg.ProviderName] {
+				continue
+			}
+			provided[configMsg.ProviderName] = true
+
+			if len(providerName) > 0 && configMsg.ProviderName != providerName {
+				continue
+			}
+
+			s.defaultConfigurationValues(configMsg.Configuration)
+			if isEmptyConfiguration(configMsg.Configuration) {
+				log.Infof("Skipping empty Configuration for provider %s", configMsg.ProviderName)
+				continue
+			}
+			configurations[configMsg.ProviderName] = configMsg.Configuration
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProvider struct {
+	name          string
+	configuration *types.Configuration
+}
+
+func (p *staticProvider) Init(_ types.Constraints) error {
+	return nil
+}
+
+func (p *staticProvider) Provide(configurationChan chan<- types.ConfigMessage, _ *safe.Pool) error {
+	if len(p.name) > 0 {
+		configurationChan <- types.ConfigMessage{ProviderName: p.name, Configuration: p.configuration}
+	}
+	return nil
+}
+
+func TestDumpConfigurations(t *testing.T) {
+	buildConfiguration := func() *types.Configuration {
+		return &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend": {Backend: "backend", EntryPoints: []string{"http", "https"}},
+			},
+			Backends: map[string]*types.Backend{
+				"backend": {Servers: map[string]types.Server{"server": {URL: "http://127.0.0.1:8080"}}},
+			},
+		}
+	}
+
+	expectedConfiguration := &types.Configuration{
+		Frontends: map[string]*types.Frontend{
+			"frontend": {Backend: "backend", EntryPoints: []string{"http"}},
+		},
+		Backends: map[string]*types.Backend{
+			"backend": {
+				Servers:      map[string]types.Server{"server": {URL: "http://127.0.0.1:8080"}},
+				LoadBalancer: &types.LoadBalancer{Method: "wrr"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		desc         string
+		providers    []*staticProvider
+		providerName string
+		expected     types.Configurations
+	}{
+		{
+			desc: "all providers",
+			providers: []*staticProvider{
+				{name: "file", configuration: buildConfiguration()},
+				{name: "docker", configuration: &types.Configuration{}},
+			},
+			expected: types.Configurations{"file": expectedConfiguration},
+		},
+		{
+			desc: "given provider",
+			providers: []*staticProvider{
+				{name: "file", configuration: buildConfiguration()},
+				{name: "docker", configuration: buildConfiguration()},
+			},
+			providerName: "docker",
+			expected:     types.Configurations{"docker": expectedConfiguration},
+		},
+		{
+			desc: "timeout",
+			providers: []*staticProvider{
+				{name: "file", configuration: buildConfiguration()},
+				{},
+			},
+			expected: types.Configurations{"file": expectedConfiguration},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			globalConfig := configuration.GlobalConfiguration{
+				EntryPoints: configuration.EntryPoints{
+					"http": &configuration.EntryPoint{},
+				},
+			}
+
+			providerAggregator := configuration.ProviderAggregator{}
+			for _, provider := range test.providers {
+				require.NoError(t, providerAggregator.AddProvider(provider))
+			}
+
+			configurations, err := DumpConfigurations(globalConfig, providerAggregator, test.providerName, 100*time.Millisecond)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, configurations)
+		})
+	}
+}
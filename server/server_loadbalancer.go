@@ -8,6 +8,8 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/containous/traefik/configuration"
@@ -15,10 +17,16 @@ import (
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/middlewares/accesslog"
+	"github.com/containous/traefik/middlewares/leastconn"
+	"github.com/containous/traefik/middlewares/mirror"
+	"github.com/containous/traefik/middlewares/p2c"
+	mratelimit "github.com/containous/traefik/middlewares/ratelimit"
 	"github.com/containous/traefik/server/cookie"
 	traefiktls "github.com/containous/traefik/tls"
 	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
 	"github.com/vulcand/oxy/buffer"
+	"github.com/vulcand/oxy/cbreaker"
 	"github.com/vulcand/oxy/connlimit"
 	"github.com/vulcand/oxy/ratelimit"
 	"github.com/vulcand/oxy/roundrobin"
@@ -35,10 +43,20 @@ func (t *h2cTransportWrapper) RoundTrip(req *http.Request) (*http.Response, erro
 	return t.Transport.RoundTrip(req)
 }
 
-func (s *Server) buildBalancerMiddlewares(frontendName string, frontend *types.Frontend, backend *types.Backend, fwd http.Handler) (http.Handler, *healthcheck.BackendConfig, error) {
+func (s *Server) buildBalancerMiddlewares(entryPointName string, providerName string, frontendName string, frontend *types.Frontend,
+	backend *types.Backend, fwd http.Handler, chain *middlewareChain) (http.Handler, healthcheck.BalancerHandler, *healthcheck.BackendConfig, handlerPostConfig, error) {
 	balancer, err := s.buildLoadBalancer(frontendName, frontend.Backend, backend, fwd)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, nil, err
+	}
+
+	// DNS discovery of the servers
+	var discovery *dnsDiscovery
+	if backend.DNSDiscovery != nil {
+		discovery, err = s.buildDNSDiscovery(frontend.Backend, backend, balancer)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("error creating DNS discovery for frontend %s: %v", frontendName, err)
+		}
 	}
 
 	// Health Check
@@ -47,23 +65,63 @@ func (s *Server) buildBalancerMiddlewares(frontendName string, frontend *types.F
 		log.Debugf("Setting up backend health check %s", *hcOpts)
 
 		hcOpts.Transport = s.defaultForwardingRoundTripper
+		if hcOpts.Mode == healthcheck.ModeGRPC {
+			hcOpts.TLSConfig = createHealthCheckTLSConfig(s.globalConfiguration)
+		}
+		if discovery != nil {
+			hcOpts.Removed = discovery.removed
+		}
 		backendHealthCheck = healthcheck.NewBackendConfig(*hcOpts, frontend.Backend)
 	}
 
 	// Empty (backend with no servers)
 	var lb http.Handler = middlewares.NewEmptyBackendHandler(balancer)
 
+	// The middlewares wrap the load balancer one after the other, the last one runs first.
+	var backendChain middlewareChain
+	var postConfigs []handlerPostConfig
+
+	// Sticky cookie
+	if backend.LoadBalancer != nil && backend.LoadBalancer.Stickiness != nil {
+		stickiness := backend.LoadBalancer.Stickiness
+		handler, err := middlewares.NewStickyCookie(lb, cookie.GetName(stickiness.CookieName, frontend.Backend), stickiness)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("error creating sticky cookie: %v", err)
+		}
+		lb = handler
+		backendChain.add(chainLevelBackend, "Sticky cookie")
+	}
+
 	// Rate Limit
 	if frontend.RateLimit != nil && len(frontend.RateLimit.RateSet) > 0 {
-		handler, err := buildRateLimiter(lb, frontend.RateLimit)
+		handler, postConfig, err := buildRateLimiter(lb, frontendName, frontend.RateLimit, s.redisClients)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error creating rate limiter: %v", err)
+			return nil, nil, nil, nil, fmt.Errorf("error creating rate limiter: %v", err)
+		}
+		if postConfig != nil {
+			postConfigs = append(postConfigs, postConfig)
 		}
 
 		lb = s.wrapHTTPHandlerWithAccessLog(
 			s.tracingMiddleware.NewHTTPHandlerWrapper("Rate limit", handler, false),
 			fmt.Sprintf("rate limit for %s", frontendName),
 		)
+		backendChain.add(chainLevelBackend, "Rate limit")
+	}
+
+	// Quota
+	if frontend.Quota != nil {
+		handler, err := buildQuota(lb, frontendName, frontend.Quota, s.redisClients)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("error creating quota: %v", err)
+		}
+		postConfigs = append(postConfigs, handler.PostLoad)
+
+		lb = s.wrapHTTPHandlerWithAccessLog(
+			s.tracingMiddleware.NewHTTPHandlerWrapper("Quota", handler, false),
+			fmt.Sprintf("quota for %s", frontendName),
+		)
+		backendChain.add(chainLevelBackend, "Quota")
 	}
 
 	// Max Connections
@@ -72,26 +130,121 @@ func (s *Server) buildBalancerMiddlewares(frontendName string, frontend *types.F
 
 		handler, err := buildMaxConn(lb, backend.MaxConn)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, nil, err
 		}
 		lb = s.wrapHTTPHandlerWithAccessLog(handler, fmt.Sprintf("connection limit for %s", frontendName))
+		backendChain.add(chainLevelBackend, "Max connections")
+	}
+
+	// Fair Share
+	if backend.FairShare != nil {
+		log.Debugf("Creating fair share for %s", frontendName)
+
+		var shareGauge gokitmetrics.Gauge
+		if s.metricsRegistry.IsEnabled() {
+			shareGauge = s.metricsRegistry.BackendClientShareGauge().With("backend", frontend.Backend)
+		}
+
+		handler, err := middlewares.NewFairShare(providerName+frontend.Backend, lb, backend.FairShare, shareGauge)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("error creating fair share: %v", err)
+		}
+		lb = s.wrapHTTPHandlerWithAccessLog(
+			s.tracingMiddleware.NewHTTPHandlerWrapper("Fair share", handler, false),
+			fmt.Sprintf("fair share for %s", frontendName),
+		)
+		backendChain.add(chainLevelBackend, "Fair share")
+	}
+
+	// Adaptive Concurrency
+	if backend.AdaptiveConcurrency != nil {
+		log.Debugf("Creating adaptive concurrency for %s", frontendName)
+
+		var limitGauge gokitmetrics.Gauge
+		if s.metricsRegistry.IsEnabled() {
+			limitGauge = s.metricsRegistry.BackendConcurrencyLimitGauge().With("backend", frontend.Backend)
+		}
+
+		handler, err := middlewares.NewAdaptiveConcurrency(providerName+frontend.Backend, lb, backend.AdaptiveConcurrency, limitGauge)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("error creating adaptive concurrency: %v", err)
+		}
+		lb = s.wrapHTTPHandlerWithAccessLog(
+			s.tracingMiddleware.NewHTTPHandlerWrapper("Adaptive concurrency", handler, false),
+			fmt.Sprintf("adaptive concurrency for %s", frontendName),
+		)
+		backendChain.add(chainLevelBackend, "Adaptive concurrency")
+	}
+
+	// The servers are resolved, and the DNS discovery is run, once the configuration is loaded.
+	if discovery != nil {
+		postConfigs = append(postConfigs, dnsDiscoveryPostConfig(&s.dnsDiscoveries, providerName+frontend.Backend, discovery))
 	}
 
 	// Retry
-	if s.globalConfiguration.Retry != nil {
-		handler := s.buildRetryMiddleware(lb, s.globalConfiguration.Retry, len(backend.Servers), frontend.Backend)
+	retryConfig := s.globalConfiguration.Retry
+	if retryConfig == nil && len(backend.FailoverBackends) > 0 {
+		// Without retry configuration, the failover backends are tried once each.
+		retryConfig = &configuration.Retry{}
+	}
+
+	if retryConfig != nil {
+		// The default attempts must reach all the failover backends.
+		minAttempts := len(backend.FailoverBackends) + 1
+		countAttempts := len(backend.Servers)
+		if minAttempts > countAttempts {
+			countAttempts = minAttempts
+		}
+
+		handler := s.buildRetryMiddleware(lb, retryConfig, countAttempts, frontend.Backend)
+
+		// The servers of a DNS discovery are only known at request time.
+		if discovery != nil && retryConfig.Attempts <= 0 {
+			handler.SetAttemptsFunc(func() int {
+				if countServers := len(balancer.Servers()); countServers > minAttempts {
+					return countServers
+				}
+				return minAttempts
+			})
+		}
+
+		if len(backend.FailoverBackends) > 0 {
+			postConfig, err := buildFailover(handler, frontend.Backend, backend.FailoverBackends, entryPointName, providerName)
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("error creating failover: %v", err)
+			}
+			postConfigs = append(postConfigs, postConfig)
+		}
+
 		lb = s.tracingMiddleware.NewHTTPHandlerWrapper("Retry", handler, false)
+		backendChain.add(chainLevelBackend, "Retry")
+	}
+
+	// Session FIFO, outside of the retries, so that the requests of a session are not interleaved with the retries
+	if backend.SessionFIFO != nil {
+		log.Debugf("Creating session FIFO for %s", frontendName)
+
+		handler, err := middlewares.NewSessionFIFO(providerName+frontend.Backend, lb, backend.SessionFIFO)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("error creating session FIFO: %v", err)
+		}
+		lb = s.wrapHTTPHandlerWithAccessLog(
+			s.tracingMiddleware.NewHTTPHandlerWrapper("Session FIFO", handler, false),
+			fmt.Sprintf("session FIFO for %s", frontendName),
+		)
+		backendChain.add(chainLevelBackend, "Session FIFO")
 	}
 
 	// Buffering
 	if backend.Buffering != nil {
 		handler, err := buildBufferingMiddleware(lb, backend.Buffering)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error setting up buffering middleware: %s", err)
+			return nil, nil, nil, nil, fmt.Errorf("error setting up buffering middleware: %s", err)
 		}
 
 		// TODO refactor ?
 		lb = handler
+		backendChain.add(chainLevelBackend, "Buffering")
 	}
 
 	// Circuit Breaker
@@ -99,18 +252,167 @@ func (s *Server) buildBalancerMiddlewares(frontendName string, frontend *types.F
 		log.Debugf("Creating circuit breaker %s", backend.CircuitBreaker.Expression)
 
 		expression := backend.CircuitBreaker.Expression
-		circuitBreaker, err := middlewares.NewCircuitBreaker(lb, expression, middlewares.NewCircuitBreakerOptions(expression))
+		option := middlewares.NewCircuitBreakerOptions(expression)
+
+		var sloFallback http.Handler
+		if backend.CircuitBreaker.Fallback != nil {
+			fallback, err := buildCircuitBreakerFallback(expression, backend.CircuitBreaker.Fallback, frontend.Backend, entryPointName, providerName)
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("error creating circuit breaker fallback: %v", err)
+			}
+
+			if len(fallback.BackendName) > 0 {
+				postConfigs = append(postConfigs, circuitBreakerFallbackPostConfig(fallback))
+			}
+
+			option = cbreaker.Fallback(fallback)
+			sloFallback = fallback
+		}
+
+		if slo := backend.CircuitBreaker.SLO; slo != nil {
+			var complianceGauge gokitmetrics.Gauge
+			if s.metricsRegistry.IsEnabled() {
+				complianceGauge = s.metricsRegistry.BackendSLOComplianceGauge().With("backend", frontend.Backend)
+			}
+
+			circuitBreaker, err := middlewares.NewSLOCircuitBreaker(lb, slo, sloFallback, complianceGauge)
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("error creating SLO circuit breaker: %v", err)
+			}
+
+			lb = s.tracingMiddleware.NewHTTPHandlerWrapper("SLO circuit breaker", circuitBreaker, false)
+			backendChain.add(chainLevelBackend, "SLO circuit breaker")
+		}
+
+		// The expression is optional only with a SLO.
+		if len(expression) > 0 || backend.CircuitBreaker.SLO == nil {
+			circuitBreaker, err := middlewares.NewCircuitBreaker(lb, expression, option)
+			if err != nil {
+				return nil, nil, nil, nil, fmt.Errorf("error creating circuit breaker: %v", err)
+			}
+
+			lb = s.tracingMiddleware.NewHTTPHandlerWrapper("Circuit breaker", circuitBreaker, false)
+			backendChain.add(chainLevelBackend, "Circuit breaker")
+		}
+	}
+
+	// Kafka mirror, outside of the other middlewares, so that all the requests of the backend can be mirrored
+	if backend.KafkaMirror != nil {
+		log.Debugf("Creating Kafka mirror for %s", frontendName)
+
+		handler, err := mirror.NewKafkaMirror(lb, frontend.Backend, backend.KafkaMirror, s.kafkaProducers)
 		if err != nil {
-			return nil, nil, fmt.Errorf("error creating circuit breaker: %v", err)
+			return nil, nil, nil, nil, fmt.Errorf("error creating Kafka mirror: %v", err)
+		}
+		postConfigs = append(postConfigs, handler.PostLoad)
+		lb = s.tracingMiddleware.NewHTTPHandlerWrapper("Kafka mirror", handler, false)
+		backendChain.add(chainLevelBackend, "Kafka mirror")
+	}
+
+	if chain != nil {
+		*chain = append(*chain, backendChain.reversed()...)
+	}
+
+	return lb, balancer, backendHealthCheck, mergePostConfigs(postConfigs), nil
+}
+
+// mergePostConfigs runs all the post configurations, even after a failure, and returns the first error.
+func mergePostConfigs(postConfigs []handlerPostConfig) handlerPostConfig {
+	if len(postConfigs) == 0 {
+		return nil
+	}
+
+	return func(backendsHandlers map[string]http.Handler) error {
+		var firstErr error
+		for _, postConfig := range postConfigs {
+			if err := postConfig(backendsHandlers); err != nil && firstErr == nil {
+				firstErr = err
+			}
 		}
+		return firstErr
+	}
+}
 
-		lb = s.tracingMiddleware.NewHTTPHandlerWrapper("Circuit breaker", circuitBreaker, false)
+func buildFailover(retry *middlewares.Retry, backendName string, failoverBackendNames []string,
+	entryPointName string, providerName string) (handlerPostConfig, error) {
+	var failoverBackends []*middlewares.FailoverBackend
+	var handlers []http.Handler
+
+	for _, failoverBackendName := range failoverBackendNames {
+		if failoverBackendName == backendName {
+			return nil, fmt.Errorf("the failover backend %q is the backend itself", failoverBackendName)
+		}
+
+		failoverBackend := middlewares.NewFailoverBackend(entryPointName + providerName + failoverBackendName)
+		failoverBackends = append(failoverBackends, failoverBackend)
+		handlers = append(handlers, failoverBackend)
 	}
 
-	return lb, backendHealthCheck, nil
+	retry.SetFailover(handlers)
+
+	return func(backendsHandlers map[string]http.Handler) error {
+		for _, failoverBackend := range failoverBackends {
+			handler, ok := backendsHandlers[failoverBackend.BackendName]
+			if !ok {
+				return fmt.Errorf("failover backend %s not found", failoverBackend.BackendName)
+			}
+
+			failoverBackend.PostLoad(handler)
+		}
+		return nil
+	}, nil
+}
+
+func buildCircuitBreakerFallback(expression string, config *types.CircuitBreakerFallback, backendName string,
+	entryPointName string, providerName string) (*middlewares.CircuitBreakerFallback, error) {
+	if config.Backend == backendName {
+		return nil, fmt.Errorf("the fallback backend %q is the circuit breaker backend", config.Backend)
+	}
+
+	return middlewares.NewCircuitBreakerFallback(expression, config, entryPointName+providerName+config.Backend)
+}
+
+func dnsDiscoveryPostConfig(discoveries *dnsDiscoveries, key string, discovery *dnsDiscovery) handlerPostConfig {
+	return func(_ map[string]http.Handler) error {
+		discoveries.add(key, discovery)
+		return nil
+	}
+}
+
+func circuitBreakerFallbackPostConfig(fallback *middlewares.CircuitBreakerFallback) handlerPostConfig {
+	return func(backendsHandlers map[string]http.Handler) error {
+		handler, ok := backendsHandlers[fallback.BackendName]
+		if !ok {
+			return fmt.Errorf("circuit breaker fallback backend %s not found, serving the fallback response instead", fallback.BackendName)
+		}
+
+		fallback.PostLoad(handler)
+		return nil
+	}
 }
 
 func (s *Server) buildLoadBalancer(frontendName string, backendName string, backend *types.Backend, fwd http.Handler) (healthcheck.BalancerHandler, error) {
+	var outlierDetector *middlewares.OutlierDetector
+	if backend.OutlierDetection != nil {
+		log.Debugf("Creating outlier detection for %s", backendName)
+
+		servers := make(map[string]types.Server)
+		for _, srv := range backend.Servers {
+			if u, err := url.Parse(srv.URL); err == nil {
+				servers[u.String()] = srv
+			}
+		}
+
+		var err error
+		outlierDetector, err = middlewares.NewOutlierDetector(fwd, backend.OutlierDetection, servers,
+			s.metricsRegistry.BackendServerEjectionsCounter().With("backend", backendName),
+			s.metricsRegistry.BackendServerUpGauge().With("backend", backendName))
+		if err != nil {
+			return nil, fmt.Errorf("error creating outlier detection for frontend %s: %v", frontendName, err)
+		}
+		fwd = outlierDetector
+	}
+
 	var rr *roundrobin.RoundRobin
 	var saveFrontend http.Handler
 
@@ -174,6 +476,30 @@ func (s *Server) buildLoadBalancer(frontendName string, backendName string, back
 		} else {
 			lb = rr
 		}
+	case types.P2c:
+		log.Debug("Creating load-balancer p2c")
+
+		if stickySession != nil {
+			log.Debugf("Sticky session with cookie %v", cookieName)
+		}
+
+		if s.accessLoggerMiddleware != nil {
+			lb = p2c.New(backendName, saveFrontend, stickySession)
+		} else {
+			lb = p2c.New(backendName, fwd, stickySession)
+		}
+	case types.LeastConn:
+		log.Debug("Creating load-balancer least connections")
+
+		if stickySession != nil {
+			log.Debugf("Sticky session with cookie %v", cookieName)
+		}
+
+		if s.accessLoggerMiddleware != nil {
+			lb = leastconn.New(backendName, saveFrontend, stickySession)
+		} else {
+			lb = leastconn.New(backendName, fwd, stickySession)
+		}
 	default:
 		return nil, fmt.Errorf("invalid load-balancing method %q", lbMethod)
 	}
@@ -182,6 +508,10 @@ func (s *Server) buildLoadBalancer(frontendName string, backendName string, back
 		return nil, fmt.Errorf("error configuring load balancer for frontend %s: %v", frontendName, err)
 	}
 
+	if outlierDetector != nil {
+		outlierDetector.SetBalancer(lb)
+	}
+
 	return lb, nil
 }
 
@@ -204,24 +534,33 @@ func (s *Server) configureLBServers(lb healthcheck.BalancerHandler, backend *typ
 }
 
 // getRoundTripper will either use server.defaultForwardingRoundTripper or create a new one
-// given a custom TLS configuration is passed and the passTLSCert option is set to true.
-func (s *Server) getRoundTripper(entryPointName string, passTLSCert bool, tls *traefiktls.TLS) (http.RoundTripper, error) {
-	if passTLSCert {
-		tlsConfig, err := createClientTLSConfig(entryPointName, tls)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create TLSClientConfig: %v", err)
-		}
+// given a custom TLS configuration is passed and the passTLSCert option is set to true,
+// or the public keys of the backend servers are pinned.
+// It also returns the TLS configuration of the transport, which the round trippers wrapping it hide.
+func (s *Server) getRoundTripper(entryPointName string, passTLSCert bool, tlsOption *traefiktls.TLS, pinnedPublicKeys *traefiktls.PinnedPublicKeys) (http.RoundTripper, *tls.Config, error) {
+	if !passTLSCert && pinnedPublicKeys == nil {
+		return s.defaultForwardingRoundTripper, s.defaultForwardingTLSConfig, nil
+	}
+
+	transport, err := createHTTPTransport(s.globalConfiguration, s.dnsLastKnownAddrs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create HTTP transport: %v", err)
+	}
 
-		transport, err := createHTTPTransport(s.globalConfiguration)
+	if passTLSCert {
+		tlsConfig, err := createClientTLSConfig(entryPointName, tlsOption)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create HTTP transport: %v", err)
+			return nil, nil, fmt.Errorf("failed to create TLSClientConfig: %v", err)
 		}
 
 		transport.TLSClientConfig = tlsConfig
-		return transport, nil
 	}
 
-	return s.defaultForwardingRoundTripper, nil
+	if pinnedPublicKeys != nil {
+		transport.TLSClientConfig.VerifyPeerCertificate = pinnedPublicKeys.VerifyPeerCertificate
+	}
+
+	return wrapForwardingRoundTripper(transport, s.globalConfiguration), transport.TLSClientConfig, nil
 }
 
 // createHTTPTransport creates an http.Transport configured with the GlobalConfiguration settings.
@@ -229,7 +568,8 @@ func (s *Server) getRoundTripper(entryPointName string, passTLSCert bool, tls *t
 // An exception to this is the MaxIdleConns setting as we only provide the option MaxIdleConnsPerHost
 // in Traefik at this point in time. Setting this value to the default of 100 could lead to confusing
 // behavior and backwards compatibility issues.
-func createHTTPTransport(globalConfiguration configuration.GlobalConfiguration) (*http.Transport, error) {
+// The last known addresses of the backend servers host names are shared by the transports of the server.
+func createHTTPTransport(globalConfiguration configuration.GlobalConfiguration, dnsAddrs *dnsLastKnownAddrs) (*http.Transport, error) {
 	dialer := &net.Dialer{
 		Timeout:   configuration.DefaultDialTimeout,
 		KeepAlive: 30 * time.Second,
@@ -258,6 +598,10 @@ func createHTTPTransport(globalConfiguration configuration.GlobalConfiguration)
 		},
 	})
 
+	if dnsConfig := globalConfiguration.ForwardingDNS; dnsConfig != nil && (dnsConfig.Retries > 0 || dnsConfig.GracePeriod > 0) {
+		transport.DialContext = newDNSRetryDialer(dialer.DialContext, dnsConfig, dnsAddrs).DialContext
+	}
+
 	if globalConfiguration.ForwardingTimeouts != nil {
 		transport.ResponseHeaderTimeout = time.Duration(globalConfiguration.ForwardingTimeouts.ResponseHeaderTimeout)
 	}
@@ -280,6 +624,21 @@ func createHTTPTransport(globalConfiguration configuration.GlobalConfiguration)
 	return transport, nil
 }
 
+// wrapForwardingRoundTripper wraps the transport with the round trippers enabled by the GlobalConfiguration.
+func wrapForwardingRoundTripper(transport http.RoundTripper, globalConfiguration configuration.GlobalConfiguration) http.RoundTripper {
+	http2Config := globalConfiguration.ForwardingHTTP2
+	if http2Config == nil || !http2Config.RetryRefusedRequests {
+		return transport
+	}
+
+	maxBodyBytes := http2Config.MaxRetryBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = configuration.DefaultMaxRetryBodyBytes
+	}
+
+	return newRefusedRequestsRoundTripper(transport, maxBodyBytes)
+}
+
 func createRootCACertPool(rootCAs traefiktls.FilesOrContents) *x509.CertPool {
 	roots := x509.NewCertPool()
 
@@ -325,7 +684,7 @@ func createClientTLSConfig(entryPointName string, tlsOption *traefiktls.TLS) (*t
 	return config, nil
 }
 
-func (s *Server) buildRetryMiddleware(handler http.Handler, retry *configuration.Retry, countServers int, backendName string) http.Handler {
+func (s *Server) buildRetryMiddleware(handler http.Handler, retry *configuration.Retry, countServers int, backendName string) *middlewares.Retry {
 	retryListeners := middlewares.RetryListeners{}
 	if s.metricsRegistry.IsEnabled() {
 		retryListeners = append(retryListeners, middlewares.NewMetricsRetryListener(s.metricsRegistry, backendName))
@@ -339,27 +698,87 @@ func (s *Server) buildRetryMiddleware(handler http.Handler, retry *configuration
 		retryAttempts = retry.Attempts
 	}
 
-	log.Debugf("Creating retries max attempts %d", retryAttempts)
+	var retryMiddleware *middlewares.Retry
+	if len(retry.ResponseHeader) > 0 {
+		log.Debugf("Creating retries max attempts %d, on response header %s", retryAttempts, retry.ResponseHeader)
 
-	return middlewares.NewRetry(retryAttempts, handler, retryListeners)
+		retryMiddleware = middlewares.NewResponseHeaderRetry(retryAttempts, retry.ResponseHeader, retry.ResponseHeaderValue, handler, retryListeners)
+	} else {
+		log.Debugf("Creating retries max attempts %d", retryAttempts)
+
+		retryMiddleware = middlewares.NewRetry(retryAttempts, handler, retryListeners)
+	}
+
+	retryMiddleware.SetBackoff(middlewares.RetryBackoff{
+		InitialInterval: time.Duration(retry.InitialInterval),
+		MaxInterval:     time.Duration(retry.MaxInterval),
+		Multiplier:      retry.Multiplier,
+		Jitter:          retry.Jitter,
+	})
+
+	return retryMiddleware
 }
 
-func buildRateLimiter(handler http.Handler, rlConfig *types.RateLimit) (http.Handler, error) {
+func buildRateLimiter(handler http.Handler, frontendName string, rlConfig *types.RateLimit, redisClients *mratelimit.RedisClients) (http.Handler, handlerPostConfig, error) {
 	extractFunc, err := utils.NewExtractor(rlConfig.ExtractorFunc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	log.Debugf("Creating load-balancer rate limiter")
 
 	rateSet := ratelimit.NewRateSet()
+	var minBurst int64
 	for _, rate := range rlConfig.RateSet {
 		if err := rateSet.Add(time.Duration(rate.Period), rate.Average, rate.Burst); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		if minBurst == 0 || rate.Burst < minBurst {
+			minBurst = rate.Burst
+		}
+	}
+
+	if len(rlConfig.Costs) > 0 {
+		extractFunc, err = middlewares.NewRateCostExtractor(extractFunc, rlConfig.Costs, minBurst)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
-	return ratelimit.New(handler, extractFunc, rateSet)
+	limiter, err := ratelimit.New(handler, extractFunc, rateSet)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if rlConfig.Redis == nil {
+		return limiter, nil, nil
+	}
+
+	log.Debugf("Sharing the rate limiter through Redis %s", rlConfig.Redis.Address)
+
+	// The in-memory limiter is used as a fallback when Redis is unreachable.
+	redisLimiter, err := mratelimit.NewRedisRateLimiter(frontendName, handler, limiter, extractFunc, rlConfig.RateSet, rlConfig.Redis, redisClients)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return redisLimiter, redisLimiter.PostLoad, nil
+}
+
+func buildQuota(handler http.Handler, frontendName string, config *types.Quota, redisClients *mratelimit.RedisClients) (*mratelimit.Quota, error) {
+	extractFunc, err := utils.NewExtractor(config.ExtractorFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Creating load-balancer quota")
+
+	if config.Redis != nil {
+		log.Debugf("Sharing the quota through Redis %s", config.Redis.Address)
+	}
+
+	return mratelimit.NewQuota(frontendName, handler, extractFunc, config, redisClients)
 }
 
 func buildBufferingMiddleware(handler http.Handler, config *types.Buffering) (http.Handler, error) {
@@ -367,14 +786,16 @@ func buildBufferingMiddleware(handler http.Handler, config *types.Buffering) (ht
 		config.MemRequestBodyBytes, config.MaxRequestBodyBytes, config.MemResponseBodyBytes,
 		config.MaxResponseBodyBytes, config.RetryExpression)
 
-	return buffer.New(
-		handler,
-		buffer.MemRequestBodyBytes(config.MemRequestBodyBytes),
-		buffer.MaxRequestBodyBytes(config.MaxRequestBodyBytes),
-		buffer.MemResponseBodyBytes(config.MemResponseBodyBytes),
-		buffer.MaxResponseBodyBytes(config.MaxResponseBodyBytes),
-		buffer.CondSetter(len(config.RetryExpression) > 0, buffer.Retry(config.RetryExpression)),
-	)
+	return middlewares.NewContentTypeBuffering(handler, config.BufferContentTypes, config.StreamContentTypes, func(next http.Handler) (http.Handler, error) {
+		return buffer.New(
+			next,
+			buffer.MemRequestBodyBytes(config.MemRequestBodyBytes),
+			buffer.MaxRequestBodyBytes(config.MaxRequestBodyBytes),
+			buffer.MemResponseBodyBytes(config.MemResponseBodyBytes),
+			buffer.MaxResponseBodyBytes(config.MaxResponseBodyBytes),
+			buffer.CondSetter(len(config.RetryExpression) > 0, buffer.Retry(config.RetryExpression)),
+		)
+	})
 }
 
 func buildMaxConn(lb http.Handler, maxConns *types.MaxConn) (http.Handler, error) {
@@ -394,7 +815,20 @@ func buildMaxConn(lb http.Handler, maxConns *types.MaxConn) (http.Handler, error
 }
 
 func buildHealthCheckOptions(lb healthcheck.BalancerHandler, backend string, hc *types.HealthCheck, hcConfig *configuration.HealthCheckConfig) *healthcheck.Options {
-	if hc == nil || hc.Path == "" || hcConfig == nil {
+	if hc == nil || hcConfig == nil {
+		return nil
+	}
+
+	mode := strings.ToLower(hc.Mode)
+	switch mode {
+	case "", healthcheck.ModeHTTP:
+		if hc.Path == "" {
+			return nil
+		}
+		mode = ""
+	case healthcheck.ModeGRPC:
+	default:
+		log.Errorf("Illegal health check mode %q for backend '%s'", hc.Mode, backend)
 		return nil
 	}
 
@@ -422,6 +856,16 @@ func buildHealthCheckOptions(lb healthcheck.BalancerHandler, backend string, hc
 		}
 	}
 
+	var expectedBodyRegex *regexp.Regexp
+	if hc.ExpectedBodyRegex != "" {
+		var err error
+		expectedBodyRegex, err = regexp.Compile(hc.ExpectedBodyRegex)
+		if err != nil {
+			log.Errorf("Illegal health check expected body regex for backend '%s': %s", backend, err)
+			return nil
+		}
+	}
+
 	if timeout >= interval {
 		log.Warnf("Health check timeout for backend '%s' should be lower than the health check interval. Interval set to timeout + 1 second (%s).", backend)
 	}
@@ -435,5 +879,20 @@ func buildHealthCheckOptions(lb healthcheck.BalancerHandler, backend string, hc
 		LB:       lb,
 		Hostname: hc.Hostname,
 		Headers:  hc.Headers,
+		Mode:     mode,
+		Service:  hc.Service,
+
+		ExpectedBody:      hc.ExpectedBody,
+		ExpectedBodyRegex: expectedBodyRegex,
+		ExpectedHeaders:   hc.ExpectedHeaders,
+	}
+}
+
+// createHealthCheckTLSConfig returns the TLS configuration of the gRPC health checks,
+// which is the one of the forwarding transport.
+func createHealthCheckTLSConfig(globalConfiguration configuration.GlobalConfiguration) *tls.Config {
+	if len(globalConfiguration.RootCAs) > 0 {
+		return &tls.Config{RootCAs: createRootCACertPool(globalConfiguration.RootCAs)}
 	}
+	return &tls.Config{InsecureSkipVerify: globalConfiguration.InsecureSkipVerify}
 }
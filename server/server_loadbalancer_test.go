@@ -1,10 +1,14 @@
 package server
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfigureBackends(t *testing.T) {
@@ -79,3 +83,31 @@ func TestConfigureBackends(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildFailover(t *testing.T) {
+	primary := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := buildFailover(middlewares.NewRetry(2, primary, middlewares.RetryListeners{}), "backend1", []string{"backend1"}, "http", "file")
+	assert.Error(t, err)
+
+	retry := middlewares.NewRetry(2, primary, middlewares.RetryListeners{})
+	postConfig, err := buildFailover(retry, "backend1", []string{"backend2"}, "http", "file")
+	require.NoError(t, err)
+
+	err = postConfig(map[string]http.Handler{})
+	assert.Error(t, err)
+
+	err = postConfig(map[string]http.Handler{
+		"httpfilebackend2": http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusTeapot)
+		}),
+	})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	retry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://localhost", nil))
+
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/containous/traefik/types"
+)
+
+// Levels of the middlewares of the chains.
+const (
+	chainLevelEntryPoint = "entrypoint"
+	chainLevelRoute      = "route"
+	chainLevelFrontend   = "frontend"
+	chainLevelBackend    = "backend"
+)
+
+// middlewareChain records the middlewares of a chain, as they are built.
+type middlewareChain []types.ChainMiddleware
+
+// add adds a middleware at the end of the chain, it does nothing on a nil chain.
+func (c *middlewareChain) add(level, name string) {
+	if c == nil {
+		return
+	}
+	*c = append(*c, types.ChainMiddleware{Name: name, Level: level})
+}
+
+// reversed returns the middlewares of the chain in the reverse order,
+// for the middlewares built from the innermost one, which run in the reverse order.
+func (c middlewareChain) reversed() middlewareChain {
+	reversed := make(middlewareChain, 0, len(c))
+	for i := len(c) - 1; i >= 0; i-- {
+		reversed = append(reversed, c[i])
+	}
+	return reversed
+}
+
+// addFrontendChain sets the middleware chain of a frontend of the entry point.
+func (s *serverEntryPoint) addFrontendChain(providerName, frontendName string, chain middlewareChain) {
+	if s.frontendChains == nil {
+		s.frontendChains = make(map[string]map[string]middlewareChain)
+	}
+	if s.frontendChains[providerName] == nil {
+		s.frontendChains[providerName] = make(map[string]middlewareChain)
+	}
+	s.frontendChains[providerName][frontendName] = chain
+}
+
+// buildMiddlewareChains returns the middleware chains of the frontends of the entry points:
+// the middlewares of the running entry point, followed by the ones of the frontend, with the duplicates noted.
+func (s *Server) buildMiddlewareChains(serverEntryPoints map[string]*serverEntryPoint) types.MiddlewareChains {
+	chains := make(types.MiddlewareChains)
+
+	var entryPointNames []string
+	for entryPointName := range serverEntryPoints {
+		entryPointNames = append(entryPointNames, entryPointName)
+	}
+	sort.Strings(entryPointNames)
+
+	for _, entryPointName := range entryPointNames {
+		var entryPointChain middlewareChain
+		if running, ok := s.serverEntryPoints[entryPointName]; ok {
+			entryPointChain = running.middlewareChain
+		}
+
+		for providerName, frontends := range serverEntryPoints[entryPointName].frontendChains {
+			if chains[providerName] == nil {
+				chains[providerName] = make(map[string][]types.MiddlewareChain)
+			}
+
+			for frontendName, frontendChain := range frontends {
+				chain := types.MiddlewareChain{EntryPoint: entryPointName}
+
+				seen := make(map[string]bool)
+				for _, middlewares := range []middlewareChain{entryPointChain, frontendChain} {
+					for _, middleware := range middlewares {
+						middleware.Duplicate = seen[middleware.Name]
+						seen[middleware.Name] = true
+						chain.Middlewares = append(chain.Middlewares, middleware)
+					}
+				}
+
+				chains[providerName][frontendName] = append(chains[providerName][frontendName], chain)
+			}
+		}
+	}
+
+	return chains
+}
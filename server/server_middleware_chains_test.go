@@ -0,0 +1,73 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/configuration"
+	th "github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerMiddlewareChains(t *testing.T) {
+	globalConfig := configuration.GlobalConfiguration{
+		DefaultEntryPoints: []string{"http"},
+		Retry:              &configuration.Retry{},
+	}
+
+	entryPoints := map[string]EntryPoint{
+		"http": {Configuration: &configuration.EntryPoint{
+			Auth: &types.Auth{Basic: &types.Basic{Users: []string{"foo:bar"}}},
+		}},
+	}
+
+	dynamicConfigs := types.Configurations{
+		"file": th.BuildConfiguration(
+			th.WithFrontends(
+				th.WithFrontend("backend",
+					th.WithFrontendName("frontend"),
+					th.WithEntryPoints("http"),
+					th.WithRoutes(th.WithRoute("api", "PathPrefixStrip:/api")),
+					th.WithFrontEndAuth(&types.Auth{Basic: &types.Basic{Users: []string{"foo:bar"}}})),
+			),
+			th.WithBackends(th.WithBackendNew("backend",
+				th.WithLBMethod("wrr"),
+				th.WithServersNew(th.WithServerNew("http://127.0.0.1:8080")),
+				func(backend *types.Backend) {
+					backend.CircuitBreaker = &types.CircuitBreaker{Expression: "NetworkErrorRatio() > 0.5"}
+				}),
+			),
+		),
+	}
+
+	srv := NewServer(globalConfig, nil, entryPoints)
+
+	var entryPointChain middlewareChain
+	_, err := srv.buildServerEntryPointMiddlewares("http", &entryPointChain)
+	require.NoError(t, err)
+	srv.serverEntryPoints["http"] = &serverEntryPoint{middlewareChain: entryPointChain}
+
+	serverEntryPoints := srv.loadConfig(dynamicConfigs, globalConfig)
+
+	expected := types.MiddlewareChains{
+		"file": {
+			"frontend": {
+				{
+					EntryPoint: "http",
+					Middlewares: []types.ChainMiddleware{
+						{Name: "Recover", Level: "entrypoint"},
+						{Name: "Auth", Level: "entrypoint"},
+						{Name: "Request host", Level: "entrypoint"},
+						{Name: "Strip prefix", Level: "route"},
+						{Name: "Auth", Level: "frontend", Duplicate: true},
+						{Name: "TLSClientHeaders", Level: "frontend"},
+						{Name: "Circuit breaker", Level: "backend"},
+						{Name: "Retry", Level: "backend"},
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, srv.buildMiddlewareChains(serverEntryPoints))
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 
@@ -8,10 +9,16 @@ import (
 	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/middlewares/accesslog"
 	mauth "github.com/containous/traefik/middlewares/auth"
+	"github.com/containous/traefik/middlewares/bodylimit"
+	"github.com/containous/traefik/middlewares/cors"
 	"github.com/containous/traefik/middlewares/errorpages"
+	"github.com/containous/traefik/middlewares/extproc"
 	"github.com/containous/traefik/middlewares/forwardedheaders"
 	"github.com/containous/traefik/middlewares/redirect"
+	"github.com/containous/traefik/middlewares/requestid"
+	"github.com/containous/traefik/middlewares/sampling"
 	"github.com/containous/traefik/types"
+	gokitmetrics "github.com/go-kit/kit/metrics"
 	thoas_stats "github.com/thoas/stats"
 	"github.com/unrolled/secure"
 	"github.com/urfave/negroni"
@@ -22,7 +29,7 @@ type handlerPostConfig func(backendsHandlers map[string]http.Handler) error
 type modifyResponse func(*http.Response) error
 
 func (s *Server) buildMiddlewares(frontendName string, frontend *types.Frontend,
-	backends map[string]*types.Backend, entryPointName string, providerName string) ([]negroni.Handler, modifyResponse, handlerPostConfig, error) {
+	backends map[string]*types.Backend, entryPointName string, providerName string, chain *middlewareChain) ([]negroni.Handler, modifyResponse, handlerPostConfig, error) {
 
 	var middle []negroni.Handler
 	var postConfig handlerPostConfig
@@ -39,12 +46,63 @@ func (s *Server) buildMiddlewares(frontendName string, frontend *types.Frontend,
 		for _, handler := range handlers {
 			middle = append(middle, handler)
 		}
+		if len(handlers) > 0 {
+			chain.add(chainLevelFrontend, "Error pages")
+		}
 	}
 
 	// Metrics
 	if s.metricsRegistry.IsEnabled() {
 		handler := middlewares.NewBackendMetricsMiddleware(s.metricsRegistry, frontend.Backend)
 		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Backend metrics")
+	}
+
+	// SNI check
+	if frontend.SNICheck != nil {
+		sniChecker, err := middlewares.NewSNIChecker(frontend.Backend, frontend.SNICheck)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating SNI check for frontend %s: %v", frontendName, err)
+		}
+
+		log.Debugf("Adding SNI check for frontend %s", frontendName)
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("SNI check", sniChecker, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "SNI check")
+	}
+
+	// Request header validation
+	if frontend.RequestHeaderValidation != nil {
+		var violationsCounter gokitmetrics.Counter
+		if s.metricsRegistry.IsEnabled() {
+			violationsCounter = s.metricsRegistry.BackendRequestHeaderViolationsCounter()
+		}
+
+		headerValidator, err := middlewares.NewRequestHeaderValidator(frontend.Backend, frontend.RequestHeaderValidation, violationsCounter)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating request header validation for frontend %s: %v", frontendName, err)
+		}
+
+		log.Debugf("Adding request header validation for frontend %s", frontendName)
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Request header validation", headerValidator, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Request header validation")
+	}
+
+	// Query limits
+	if len(frontend.QueryLimits) > 0 {
+		queryLimiter, err := middlewares.NewQueryLimiter(frontend.Backend, frontend.QueryLimits)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating query limits for frontend %s: %v", frontendName, err)
+		}
+
+		log.Debugf("Adding query limits for frontend %s", frontendName)
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Query limits", queryLimiter, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Query limits")
 	}
 
 	// Whitelist
@@ -60,6 +118,24 @@ func (s *Server) buildMiddlewares(frontendName string, frontend *types.Frontend,
 			s.wrapNegroniHandlerWithAccessLog(ipWhitelistMiddleware, fmt.Sprintf("ipwhitelister for %s", frontendName)),
 			false)
 		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "IP whitelist")
+	}
+
+	// Maintenance
+	// The configuration is validated even when the maintenance is disabled, not to fail only once it is enabled.
+	if frontend.Maintenance != nil {
+		maintenanceMiddleware, err := buildMaintenance(frontend.Maintenance, s.entryPoints[entryPointName].Configuration.ClientIPStrategy)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating maintenance middleware for frontend %s: %v", frontendName, err)
+		}
+
+		if frontend.Maintenance.Enabled {
+			log.Debugf("Adding maintenance middleware for frontend %s", frontendName)
+
+			handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Maintenance", maintenanceMiddleware, false)
+			middle = append(middle, handler)
+			chain.add(chainLevelFrontend, "Maintenance")
+		}
 	}
 
 	// Redirect
@@ -71,17 +147,61 @@ func (s *Server) buildMiddlewares(frontendName string, frontend *types.Frontend,
 
 		handler := s.wrapNegroniHandlerWithAccessLog(rewrite, fmt.Sprintf("frontend redirect for %s", frontendName))
 		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Redirect")
 
 		log.Debugf("Frontend %s redirect created", frontendName)
 	}
 
+	// Body limit
+	if frontend.BodyLimit != nil {
+		bodyLimit, err := bodylimit.New(frontend.BodyLimit)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating body limit for frontend %s: %v", frontendName, err)
+		}
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Body limit", bodyLimit, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Body limit")
+	}
+
+	// WebSocket keep-alive
+	if frontend.WebSocket != nil {
+		webSocketKeepAlive, err := middlewares.NewWebSocketKeepAlive(frontend.Backend, frontend.WebSocket)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating websocket keep-alive for frontend %s: %v", frontendName, err)
+		}
+
+		log.Debugf("Adding websocket keep-alive for frontend %s", frontendName)
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("WebSocket keep-alive", webSocketKeepAlive, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "WebSocket keep-alive")
+	}
+
+	// CORS, before the authentication as the preflight requests have no credentials
+	var corsMiddleware *cors.CORS
+	if frontend.CORS != nil {
+		corsMiddleware, err = cors.New(frontend.CORS)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating CORS middleware for frontend %s: %v", frontendName, err)
+		}
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("CORS", corsMiddleware, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "CORS")
+	}
+
 	// Header
-	headerMiddleware := middlewares.NewHeaderFromStruct(frontend.Headers)
+	headerMiddleware, err := middlewares.NewHeaderFromStruct(frontend.Headers)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error creating header middleware for frontend %s: %v", frontendName, err)
+	}
 	if headerMiddleware != nil {
 		log.Debugf("Adding header middleware for frontend %s", frontendName)
 
 		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Header", headerMiddleware, false)
 		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Header")
 	}
 
 	// Secure
@@ -91,6 +211,7 @@ func (s *Server) buildMiddlewares(frontendName string, frontend *types.Frontend,
 
 		handler := negroni.HandlerFunc(secureMiddleware.HandlerFuncWithNextForRequestOnly)
 		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Secure")
 	}
 
 	// Authentication
@@ -102,6 +223,7 @@ func (s *Server) buildMiddlewares(frontendName string, frontend *types.Frontend,
 
 		handler := s.wrapNegroniHandlerWithAccessLog(authMiddleware, fmt.Sprintf("Auth for %s", frontendName))
 		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Auth")
 	}
 
 	// TLSClientHeaders
@@ -111,24 +233,219 @@ func (s *Server) buildMiddlewares(frontendName string, frontend *types.Frontend,
 
 		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("TLSClientHeaders", tlsClientHeadersMiddleware, false)
 		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "TLSClientHeaders")
+	}
+
+	// Backend compression
+	if frontend.BackendCompression != nil {
+		backendCompression, err := middlewares.NewBackendCompression(frontendName, frontend.BackendCompression)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating backend compression for frontend %s: %v", frontendName, err)
+		}
+
+		log.Debugf("Adding backend compression %s for frontend %s", frontend.BackendCompression.Policy, frontendName)
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Backend compression", backendCompression, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Backend compression")
+	}
+
+	// Request template
+	if frontend.RequestTemplate != nil {
+		requestTemplate, err := middlewares.NewRequestTemplate(frontend.RequestTemplate)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating request template for frontend %s: %v", frontendName, err)
+		}
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Request template", requestTemplate, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Request template")
 	}
 
-	return middle, buildModifyResponse(secureMiddleware, headerMiddleware), postConfig, nil
+	// External processor, last to process the request as forwarded to the backend
+	if frontend.ExternalProcessor != nil {
+		processor, err := extproc.New(frontend.ExternalProcessor, s.processorConns)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating external processor for frontend %s: %v", frontendName, err)
+		}
+
+		log.Debugf("Adding external processor %s for frontend %s", frontend.ExternalProcessor.Address, frontendName)
+
+		if postConfig != nil {
+			postConfig = mergePostConfigs([]handlerPostConfig{postConfig, processor.PostLoad})
+		} else {
+			postConfig = processor.PostLoad
+		}
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("External processor", processor, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "External processor")
+	}
+
+	// Backend schedule, to select the backend of the request
+	if frontend.BackendSchedule != nil {
+		if err := checkBackendSchedule(frontendName, frontend.BackendSchedule, backends); err != nil {
+			return nil, nil, nil, err
+		}
+
+		schedule, err := middlewares.NewBackendSchedule(frontend.BackendSchedule, frontendBackendsPrefix(entryPointName, providerName, frontendName))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating backend schedule for frontend %s: %v", frontendName, err)
+		}
+
+		log.Debugf("Adding backend schedule for frontend %s", frontendName)
+
+		if postConfig != nil {
+			postConfig = mergePostConfigs([]handlerPostConfig{postConfig, schedule.PostLoad})
+		} else {
+			postConfig = schedule.PostLoad
+		}
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Backend schedule", schedule, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Backend schedule")
+	}
+
+	// Backend ramp, after the backend schedule which selects the backend during its windows
+	if frontend.BackendRamp != nil {
+		if err := checkBackendRamp(frontendName, frontend.BackendRamp, backends); err != nil {
+			return nil, nil, nil, err
+		}
+
+		var weightGauge gokitmetrics.Gauge
+		if s.metricsRegistry.IsEnabled() {
+			weightGauge = s.metricsRegistry.BackendRampWeightGauge()
+		}
+
+		ramp, err := middlewares.NewBackendRamp(frontend.BackendRamp, frontendName, frontendBackendsPrefix(entryPointName, providerName, frontendName), weightGauge)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating backend ramp for frontend %s: %v", frontendName, err)
+		}
+		ramp.UseOverrides(s.backendRampOverrides, providerName)
+
+		log.Debugf("Adding backend ramp to %s for frontend %s", frontend.BackendRamp.Backend, frontendName)
+
+		if postConfig != nil {
+			postConfig = mergePostConfigs([]handlerPostConfig{postConfig, ramp.PostLoad})
+		} else {
+			postConfig = ramp.PostLoad
+		}
+
+		handler := s.tracingMiddleware.NewNegroniHandlerWrapper("Backend ramp", ramp, false)
+		middle = append(middle, handler)
+		chain.add(chainLevelFrontend, "Backend ramp")
+	}
+
+	// Response header rules
+	var violationsCounter gokitmetrics.Counter
+	if s.metricsRegistry.IsEnabled() {
+		violationsCounter = s.metricsRegistry.BackendResponseHeaderViolationsCounter()
+	}
+
+	headerValidator, err := middlewares.NewResponseHeaderValidator(frontend.Backend, frontend.ResponseHeaderRules, violationsCounter)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error creating response header rules for frontend %s: %v", frontendName, err)
+	}
+
+	// Backend version
+	var versionRecorder *middlewares.BackendVersionRecorder
+	if frontend.BackendVersion != nil {
+		var versionReqsCounter gokitmetrics.Counter
+		if s.metricsRegistry.IsEnabled() {
+			versionReqsCounter = s.metricsRegistry.BackendVersionReqsCounter()
+		}
+
+		var listener middlewares.BackendVersionListener
+		if s.accessLoggerMiddleware != nil {
+			listener = &accesslog.SaveBackendVersion{}
+		}
+
+		versionRecorder, err = middlewares.NewBackendVersionRecorder(frontend.Backend, frontend.BackendVersion, listener, versionReqsCounter)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error creating backend version for frontend %s: %v", frontendName, err)
+		}
+	}
+
+	return middle, buildModifyResponse(secureMiddleware, headerMiddleware, versionRecorder, headerValidator, corsMiddleware), postConfig, nil
 }
 
-func (s *Server) buildServerEntryPointMiddlewares(serverEntryPointName string) ([]negroni.Handler, error) {
+func (s *Server) buildServerEntryPointMiddlewares(serverEntryPointName string, chain *middlewareChain) ([]negroni.Handler, error) {
 	serverMiddlewares := []negroni.Handler{middlewares.NegroniRecoverHandler()}
+	chain.add(chainLevelEntryPoint, "Recover")
+
+	// The PROXY protocol TLVs are forwarded before the access logs, and the authentication.
+	if proxyProtocolTLVs := s.proxyProtocolTLVs(serverEntryPointName); proxyProtocolTLVs != nil {
+		serverMiddlewares = append(serverMiddlewares, proxyProtocolTLVs)
+		chain.add(chainLevelEntryPoint, "PROXY protocol TLVs")
+	}
+
+	// The request ID is set before the access logs and the tracing.
+	if requestID := s.entryPoints[serverEntryPointName].Configuration.RequestID; requestID != nil {
+		requestIDMiddleware, err := requestid.NewHandler(requestID.HeaderName, requestID.Generator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request ID middleware: %v", err)
+		}
+		serverMiddlewares = append(serverMiddlewares, requestIDMiddleware)
+		chain.add(chainLevelEntryPoint, "Request ID")
+	}
+
+	if s.isSharedSamplingEnabled() {
+		serverMiddlewares = append(serverMiddlewares, sampling.NewHandler())
+		chain.add(chainLevelEntryPoint, "Sampling")
+	}
 
 	if s.tracingMiddleware.IsEnabled() {
 		serverMiddlewares = append(serverMiddlewares, s.tracingMiddleware.NewEntryPoint(serverEntryPointName))
+		chain.add(chainLevelEntryPoint, "Tracing")
 	}
 
 	if s.accessLoggerMiddleware != nil {
 		serverMiddlewares = append(serverMiddlewares, s.accessLoggerMiddleware)
+		chain.add(chainLevelEntryPoint, "Access log")
 	}
 
 	if s.metricsRegistry.IsEnabled() {
 		serverMiddlewares = append(serverMiddlewares, middlewares.NewEntryPointMetricsMiddleware(s.metricsRegistry, serverEntryPointName))
+		chain.add(chainLevelEntryPoint, "Entry point metrics")
+	}
+
+	if http2 := s.entryPoints[serverEntryPointName].Configuration.HTTP2; http2 != nil && http2.MaxConcurrentStreamsPerConn > 0 {
+		rejectedCounter := s.metricsRegistry.EntrypointRejectedStreamsCounter().With("entrypoint", serverEntryPointName)
+		serverMiddlewares = append(serverMiddlewares, middlewares.NewStreamLimiter(http2.MaxConcurrentStreamsPerConn, rejectedCounter))
+		chain.add(chainLevelEntryPoint, "Stream limit")
+	}
+
+	if http2 := s.entryPoints[serverEntryPointName].Configuration.HTTP2; http2 != nil && http2.DisableCoalescing {
+		serverMiddlewares = append(serverMiddlewares, &middlewares.MisdirectedRequest{
+			StripTrailingDot: s.entryPoints[serverEntryPointName].Configuration.StripHostTrailingDot,
+		})
+		chain.add(chainLevelEntryPoint, "Misdirected request")
+	}
+
+	if entryPointTLS := s.entryPoints[serverEntryPointName].Configuration.TLS; entryPointTLS != nil {
+		if clientAuthType, err := entryPointTLS.ClientCA.GetClientAuthType(); err == nil && clientAuthType != tls.NoClientCert {
+			serverMiddlewares = append(serverMiddlewares, &middlewares.ClientCertStatus{})
+			chain.add(chainLevelEntryPoint, "Client certificate status")
+		}
+	}
+
+	if missingHost := s.entryPoints[serverEntryPointName].Configuration.MissingHost; missingHost != nil {
+		serverMiddlewares = append(serverMiddlewares, middlewares.NewMissingHost(missingHost.DefaultHost, missingHost.Reject))
+		chain.add(chainLevelEntryPoint, "Missing host")
+	}
+
+	if uaClassify := s.entryPoints[serverEntryPointName].Configuration.UserAgentClassify; uaClassify != nil {
+		patterns := map[string][]string{
+			middlewares.UserAgentClassCrawler: uaClassify.Crawlers,
+			middlewares.UserAgentClassBot:     uaClassify.Bots,
+			middlewares.UserAgentClassBrowser: uaClassify.Browsers,
+		}
+		classifier, err := middlewares.NewUserAgentClassifier(uaClassify.HeaderName, patterns, uaClassify.PatternsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user agent classify middleware: %v", err)
+		}
+		serverMiddlewares = append(serverMiddlewares, classifier)
+		chain.add(chainLevelEntryPoint, "User agent classify")
 	}
 
 	if s.globalConfiguration.API != nil {
@@ -136,11 +453,13 @@ func (s *Server) buildServerEntryPointMiddlewares(serverEntryPointName string) (
 			s.globalConfiguration.API.Stats = thoas_stats.New()
 		}
 		serverMiddlewares = append(serverMiddlewares, s.globalConfiguration.API.Stats)
+		chain.add(chainLevelEntryPoint, "Stats")
 		if s.globalConfiguration.API.Statistics != nil {
 			if s.globalConfiguration.API.StatsRecorder == nil {
 				s.globalConfiguration.API.StatsRecorder = middlewares.NewStatsRecorder(s.globalConfiguration.API.Statistics.RecentErrors)
 			}
 			serverMiddlewares = append(serverMiddlewares, s.globalConfiguration.API.StatsRecorder)
+			chain.add(chainLevelEntryPoint, "Stats recorder")
 		}
 	}
 
@@ -150,6 +469,7 @@ func (s *Server) buildServerEntryPointMiddlewares(serverEntryPointName string) (
 			return nil, fmt.Errorf("failed to create redirect middleware: %v", err)
 		}
 		serverMiddlewares = append(serverMiddlewares, redirectHandlers[serverEntryPointName])
+		chain.add(chainLevelEntryPoint, "Redirect")
 	}
 
 	if s.entryPoints[serverEntryPointName].Configuration.Auth != nil {
@@ -158,10 +478,16 @@ func (s *Server) buildServerEntryPointMiddlewares(serverEntryPointName string) (
 			return nil, fmt.Errorf("failed to create authentication middleware: %v", err)
 		}
 		serverMiddlewares = append(serverMiddlewares, s.wrapNegroniHandlerWithAccessLog(authMiddleware, fmt.Sprintf("Auth for entrypoint %s", serverEntryPointName)))
+		chain.add(chainLevelEntryPoint, "Auth")
 	}
 
-	if s.entryPoints[serverEntryPointName].Configuration.Compress != nil {
-		serverMiddlewares = append(serverMiddlewares, &middlewares.Compress{})
+	if compress := s.entryPoints[serverEntryPointName].Configuration.Compress; compress != nil {
+		compressMiddleware, err := middlewares.NewCompress(compress.Encodings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create compress middleware: %v", err)
+		}
+		serverMiddlewares = append(serverMiddlewares, compressMiddleware)
+		chain.add(chainLevelEntryPoint, "Compress")
 	}
 
 	if s.entryPoints[serverEntryPointName].Configuration.ForwardedHeaders != nil {
@@ -173,6 +499,7 @@ func (s *Server) buildServerEntryPointMiddlewares(serverEntryPointName string) (
 			return nil, fmt.Errorf("failed to create xforwarded headers middleware: %v", err)
 		}
 		serverMiddlewares = append(serverMiddlewares, xForwardedMiddleware)
+		chain.add(chainLevelEntryPoint, "Forwarded headers")
 	}
 
 	ipWhitelistMiddleware, err := buildIPWhiteLister(s.entryPoints[serverEntryPointName].Configuration.WhiteList, s.entryPoints[serverEntryPointName].Configuration.ClientIPStrategy)
@@ -181,14 +508,31 @@ func (s *Server) buildServerEntryPointMiddlewares(serverEntryPointName string) (
 	}
 	if ipWhitelistMiddleware != nil {
 		serverMiddlewares = append(serverMiddlewares, s.wrapNegroniHandlerWithAccessLog(ipWhitelistMiddleware, fmt.Sprintf("ipwhitelister for entrypoint %s", serverEntryPointName)))
+		chain.add(chainLevelEntryPoint, "IP whitelist")
 	}
 
 	// RequestHost Cannonizer
-	serverMiddlewares = append(serverMiddlewares, &middlewares.RequestHost{})
+	serverMiddlewares = append(serverMiddlewares, &middlewares.RequestHost{
+		StripTrailingDot: s.entryPoints[serverEntryPointName].Configuration.StripHostTrailingDot,
+	})
+	chain.add(chainLevelEntryPoint, "Request host")
 
 	return serverMiddlewares, nil
 }
 
+func (s *Server) isSharedSamplingEnabled() bool {
+	accessLog := s.globalConfiguration.AccessLog
+	if accessLog != nil && (accessLog.SamplingRatio > 0 || len(accessLog.StatusSamplingRatios) > 0) {
+		return true
+	}
+
+	if accessLog != nil && accessLog.Sampling != nil && accessLog.Sampling.Rate > 0 {
+		return true
+	}
+
+	return s.tracingMiddleware.IsEnabled() && s.tracingMiddleware.SamplingRatio > 0
+}
+
 func errorPagesPostConfig(epHandlers []*errorpages.Handler) handlerPostConfig {
 	return func(backendsHandlers map[string]http.Handler) error {
 		for _, errorPageHandler := range epHandlers {
@@ -212,12 +556,8 @@ func buildErrorPagesMiddleware(frontendName string, frontend *types.Frontend, ba
 	var errorPageHandlers []*errorpages.Handler
 
 	for errorPageName, errorPage := range frontend.Errors {
-		if frontend.Backend == errorPage.Backend {
-			log.Errorf("Error when creating error page %q for frontend %q: error pages backend %q is the same as backend for the frontend (infinite call risk).",
-				errorPageName, frontendName, errorPage.Backend)
-		} else if backends[errorPage.Backend] == nil {
-			log.Errorf("Error when creating error page %q for frontend %q: the backend %q doesn't exist.",
-				errorPageName, frontendName, errorPage.Backend)
+		if err := checkErrorPage(frontendName, frontend, errorPageName, errorPage, backends); err != nil {
+			log.Error(err)
 		} else {
 			errorPagesHandler, err := errorpages.NewHandler(errorPage, entryPointName+providerName+errorPage.Backend)
 			if err != nil {
@@ -312,6 +652,19 @@ func buildIPWhiteLister(whiteList *types.WhiteList, ipStrategy *types.IPStrategy
 	return middlewares.NewIPWhiteLister(whiteList.SourceRange, strategy)
 }
 
+func buildMaintenance(maintenance *types.Maintenance, ipStrategy *types.IPStrategy) (*middlewares.Maintenance, error) {
+	if maintenance.IPStrategy != nil {
+		ipStrategy = maintenance.IPStrategy
+	}
+
+	strategy, err := ipStrategy.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	return middlewares.NewMaintenance(maintenance, strategy)
+}
+
 func (s *Server) wrapNegroniHandlerWithAccessLog(handler negroni.Handler, frontendName string) negroni.Handler {
 	if s.accessLoggerMiddleware != nil {
 		saveUsername := accesslog.NewSaveNegroniUsername(handler)
@@ -332,8 +685,29 @@ func (s *Server) wrapHTTPHandlerWithAccessLog(handler http.Handler, frontendName
 	return handler
 }
 
-func buildModifyResponse(secure *secure.Secure, header *middlewares.HeaderStruct) func(res *http.Response) error {
+func buildModifyResponse(secure *secure.Secure, header *middlewares.HeaderStruct, versionRecorder *middlewares.BackendVersionRecorder,
+	headerValidator *middlewares.ResponseHeaderValidator, corsMiddleware *cors.CORS) func(res *http.Response) error {
 	return func(res *http.Response) error {
+		// The version is the one sent by the backend.
+		if versionRecorder != nil {
+			if err := versionRecorder.ModifyResponseHeaders(res); err != nil {
+				return err
+			}
+		}
+
+		// The rules apply to the headers sent by the backend.
+		if headerValidator != nil {
+			if err := headerValidator.ModifyResponseHeaders(res); err != nil {
+				return err
+			}
+		}
+
+		if corsMiddleware != nil {
+			if err := corsMiddleware.ModifyResponseHeaders(res); err != nil {
+				return err
+			}
+		}
+
 		if secure != nil {
 			if err := secure.ModifyResponseHeaders(res); err != nil {
 				return err
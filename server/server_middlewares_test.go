@@ -268,3 +268,51 @@ func TestServerGenericFrontendAuthFail(t *testing.T) {
 
 	_ = srv.loadConfig(dynamicConfigs, globalConfig)
 }
+
+func TestServerDisabledMaintenanceValidated(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("backend"))
+	}))
+	defer backendServer.Close()
+
+	globalConfig := configuration.GlobalConfiguration{
+		DefaultEntryPoints: []string{"http"},
+	}
+
+	entryPoints := map[string]EntryPoint{
+		"http": {Configuration: &configuration.EntryPoint{
+			ForwardedHeaders: &configuration.ForwardedHeaders{Insecure: true},
+		}},
+	}
+
+	dynamicConfigs := types.Configurations{
+		"config": th.BuildConfiguration(
+			th.WithFrontends(
+				th.WithFrontend("backend",
+					th.WithFrontendName("frontend0"),
+					th.WithEntryPoints("http"),
+					th.WithRoutes(th.WithRoute("/ok", "Path: /ok")),
+					func(f *types.Frontend) {
+						f.Maintenance = &types.Maintenance{
+							Enabled:  false,
+							Body:     "maintenance",
+							BodyFile: "maintenance.html",
+						}
+					}),
+			),
+			th.WithBackends(
+				th.WithBackendNew("backend", th.WithLBMethod("wrr"), th.WithServersNew(th.WithServerNew(backendServer.URL))),
+			),
+		),
+	}
+
+	srv := NewServer(globalConfig, nil, entryPoints)
+
+	serverEntryPoints := srv.loadConfig(dynamicConfigs, globalConfig)
+
+	// The frontend with an invalid maintenance configuration isn't loaded, even though the maintenance is disabled.
+	recorder := httptest.NewRecorder()
+	serverEntryPoints["http"].httpRouter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, backendServer.URL+"/ok", nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
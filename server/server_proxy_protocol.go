@@ -0,0 +1,214 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/armon/go-proxyproto"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares"
+)
+
+// proxyProtocolV2Signature starts the binary headers of the PROXY protocol v2.
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyProtocolV2HeaderLength = 16
+
+	proxyProtocolV2CommandLocal = 0x0
+	proxyProtocolV2CommandProxy = 0x1
+
+	proxyProtocolV2FamilyInet  = 0x1
+	proxyProtocolV2FamilyInet6 = 0x2
+	proxyProtocolV2FamilyUnix  = 0x3
+
+	proxyProtocolV2TransportStream = 0x1
+)
+
+// proxyProtocolTLVs returns the PROXY protocol TLVs forwarded by an entrypoint, if any.
+func (s *Server) proxyProtocolTLVs(entryPointName string) *middlewares.ProxyProtocolTLVs {
+	if serverEntryPoint, ok := s.serverEntryPoints[entryPointName]; ok {
+		return serverEntryPoint.proxyProtocolTLVs
+	}
+	return nil
+}
+
+// proxyProtocolV2Listener reads the PROXY protocol v2 headers of the connections,
+// under the listener of the vendored PROXY protocol library which only reads the v1 headers.
+// As with the v1 headers, the headers of the untrusted sources are discarded.
+type proxyProtocolV2Listener struct {
+	net.Listener
+	sourceCheck proxyproto.SourceChecker
+	tlvs        *middlewares.ProxyProtocolTLVs
+}
+
+func (l *proxyProtocolV2Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	// The errors of the source check are returned by the v1 listener, on top of this one.
+	trusted, err := l.sourceCheck(conn.RemoteAddr())
+
+	return &proxyProtocolV2Conn{
+		Conn:    conn,
+		reader:  bufio.NewReader(conn),
+		trusted: trusted && err == nil,
+		tlvs:    l.tlvs,
+	}, nil
+}
+
+// proxyProtocolV2Conn reads the PROXY protocol v2 header of the connection, if any, on the first read.
+// Contrary to the v1 library, RemoteAddr doesn't read the header, as it is called by the v1 listener on accept.
+type proxyProtocolV2Conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	trusted bool
+	tlvs    *middlewares.ProxyProtocolTLVs
+
+	once     sync.Once
+	err      error
+	lock     sync.Mutex
+	srcAddr  net.Addr
+	recorded bool
+}
+
+func (c *proxyProtocolV2Conn) Read(b []byte) (int, error) {
+	c.once.Do(func() { c.err = c.readHeader() })
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *proxyProtocolV2Conn) RemoteAddr() net.Addr {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.srcAddr != nil {
+		return c.srcAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtocolV2Conn) Close() error {
+	c.lock.Lock()
+	if c.recorded {
+		c.tlvs.Forget(c.srcAddr.String())
+		c.recorded = false
+	}
+	c.lock.Unlock()
+
+	return c.Conn.Close()
+}
+
+func (c *proxyProtocolV2Conn) readHeader() error {
+	// The signature is checked incrementally, not to wait for more bytes than the clients without header send.
+	for i := 1; i <= len(proxyProtocolV2Signature); i++ {
+		peek, err := c.reader.Peek(i)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(peek, proxyProtocolV2Signature[:i]) {
+			return nil
+		}
+	}
+
+	header := make([]byte, proxyProtocolV2HeaderLength)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return err
+	}
+
+	if version := header[12] >> 4; version != 2 {
+		return fmt.Errorf("unsupported PROXY protocol version %d", version)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return err
+	}
+
+	switch command := header[12] & 0x0F; command {
+	case proxyProtocolV2CommandLocal:
+		// Connection established by the proxy itself, e.g. for its health checks.
+		return nil
+	case proxyProtocolV2CommandProxy:
+	default:
+		return fmt.Errorf("unsupported PROXY protocol command %d", command)
+	}
+
+	family, transport := header[13]>>4, header[13]&0x0F
+
+	var addressesLength int
+	switch family {
+	case proxyProtocolV2FamilyInet:
+		addressesLength = 2*net.IPv4len + 4
+	case proxyProtocolV2FamilyInet6:
+		addressesLength = 2*net.IPv6len + 4
+	case proxyProtocolV2FamilyUnix:
+		addressesLength = 216
+	}
+	if len(payload) < addressesLength {
+		return fmt.Errorf("PROXY protocol header too short for the address family %d", family)
+	}
+
+	if !c.trusted || transport != proxyProtocolV2TransportStream {
+		return nil
+	}
+
+	var srcAddr *net.TCPAddr
+	switch family {
+	case proxyProtocolV2FamilyInet:
+		srcAddr = &net.TCPAddr{IP: net.IP(payload[:net.IPv4len]), Port: int(binary.BigEndian.Uint16(payload[2*net.IPv4len:]))}
+	case proxyProtocolV2FamilyInet6:
+		srcAddr = &net.TCPAddr{IP: net.IP(payload[:net.IPv6len]), Port: int(binary.BigEndian.Uint16(payload[2*net.IPv6len:]))}
+	default:
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.srcAddr = srcAddr
+
+	if c.tlvs == nil {
+		return nil
+	}
+
+	// The malformed TLVs are ignored, they don't prevent the connection from being served.
+	tlvs, err := parseProxyProtocolTLVs(payload[addressesLength:])
+	if err != nil {
+		log.Debugf("Ignoring the PROXY protocol TLVs of %s: %v", srcAddr, err)
+		return nil
+	}
+
+	c.tlvs.Record(srcAddr.String(), tlvs)
+	c.recorded = true
+
+	return nil
+}
+
+func parseProxyProtocolTLVs(data []byte) ([]middlewares.ProxyProtocolTLV, error) {
+	var tlvs []middlewares.ProxyProtocolTLV
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, errors.New("truncated TLV")
+		}
+
+		length := int(binary.BigEndian.Uint16(data[1:3]))
+		if len(data) < 3+length {
+			return nil, fmt.Errorf("truncated TLV of type 0x%02X", data[0])
+		}
+
+		tlvs = append(tlvs, middlewares.ProxyProtocolTLV{Type: data[0], Value: data[3 : 3+length]})
+		data = data[3+length:]
+	}
+	return tlvs, nil
+}
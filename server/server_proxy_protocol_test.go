@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/middlewares"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proxyProtocolV2Header(command byte, tlvs []byte) []byte {
+	addresses := []byte{
+		10, 0, 0, 1, // source address
+		10, 0, 0, 2, // destination address
+		0x87, 0x07, // source port 34567
+		0x00, 0x50, // destination port 80
+	}
+
+	header := append([]byte{}, proxyProtocolV2Signature...)
+	header = append(header, 0x20|command, 0x11, 0, 0)
+	binary.BigEndian.PutUint16(header[14:16], uint16(len(addresses)+len(tlvs)))
+	header = append(header, addresses...)
+	return append(header, tlvs...)
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		trustedIPs         []string
+		header             []byte
+		expectedRemoteAddr string
+		expectedHeader     string
+		expectedErr        bool
+	}{
+		{
+			desc:               "without header",
+			trustedIPs:         []string{"127.0.0.1"},
+			expectedRemoteAddr: "127.0.0.1",
+		},
+		{
+			desc:               "v1 header",
+			trustedIPs:         []string{"127.0.0.1"},
+			header:             []byte("PROXY TCP4 10.0.0.1 10.0.0.2 34567 80\r\n"),
+			expectedRemoteAddr: "10.0.0.1:34567",
+		},
+		{
+			desc:               "v2 header",
+			trustedIPs:         []string{"127.0.0.1"},
+			header:             proxyProtocolV2Header(proxyProtocolV2CommandProxy, nil),
+			expectedRemoteAddr: "10.0.0.1:34567",
+		},
+		{
+			desc:               "v2 header with TLVs",
+			trustedIPs:         []string{"127.0.0.1"},
+			header:             proxyProtocolV2Header(proxyProtocolV2CommandProxy, []byte{0x04, 0x00, 0x00, 0xEA, 0x00, 0x05, 0x01, 'v', 'p', 'c', 'e'}),
+			expectedRemoteAddr: "10.0.0.1:34567",
+			expectedHeader:     "vpce",
+		},
+		{
+			desc:               "v2 header with malformed TLVs",
+			trustedIPs:         []string{"127.0.0.1"},
+			header:             proxyProtocolV2Header(proxyProtocolV2CommandProxy, []byte{0xEA, 0x00, 0x10, 0x01, 'v', 'p', 'c', 'e'}),
+			expectedRemoteAddr: "10.0.0.1:34567",
+		},
+		{
+			desc:               "v2 header from the proxy itself",
+			trustedIPs:         []string{"127.0.0.1"},
+			header:             proxyProtocolV2Header(proxyProtocolV2CommandLocal, nil),
+			expectedRemoteAddr: "127.0.0.1",
+		},
+		{
+			desc:               "v2 header from an untrusted source",
+			trustedIPs:         []string{"10.0.0.0/8"},
+			header:             proxyProtocolV2Header(proxyProtocolV2CommandProxy, []byte{0xEA, 0x00, 0x05, 0x01, 'v', 'p', 'c', 'e'}),
+			expectedRemoteAddr: "127.0.0.1",
+		},
+		{
+			desc:        "v2 header with an unsupported version",
+			trustedIPs:  []string{"127.0.0.1"},
+			header:      append(append([]byte{}, proxyProtocolV2Signature...), 0x31, 0x11, 0x00, 0x00),
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			tlvs, err := middlewares.NewProxyProtocolTLVs(map[string]string{"aws_vpce_id": "X-Amzn-Vpce-Id"})
+			require.NoError(t, err)
+
+			tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer tcpListener.Close()
+
+			entryPoint := &configuration.EntryPoint{
+				ProxyProtocol: &configuration.ProxyProtocol{TrustedIPs: test.trustedIPs},
+			}
+			listener, err := buildProxyProtocolListener(entryPoint, tcpListener, tlvs)
+			require.NoError(t, err)
+
+			clientConn, err := net.Dial("tcp", tcpListener.Addr().String())
+			require.NoError(t, err)
+			defer clientConn.Close()
+
+			_, err = clientConn.Write(append(test.header, "hello"...))
+			require.NoError(t, err)
+			require.NoError(t, clientConn.(*net.TCPConn).CloseWrite())
+
+			conn, err := listener.Accept()
+			require.NoError(t, err)
+
+			remoteAddr := conn.RemoteAddr().String()
+			data, err := ioutil.ReadAll(conn)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(data))
+
+			if host, _, err := net.SplitHostPort(remoteAddr); err == nil && host == test.expectedRemoteAddr {
+				remoteAddr = host
+			}
+			assert.Equal(t, test.expectedRemoteAddr, remoteAddr)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = remoteAddr
+			tlvs.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.expectedHeader, r.Header.Get("X-Amzn-Vpce-Id"))
+			})
+
+			// The TLVs of the connection are forgotten once it is closed.
+			require.NoError(t, conn.Close())
+			tlvs.ServeHTTP(httptest.NewRecorder(), req, func(rw http.ResponseWriter, r *http.Request) {
+				assert.Empty(t, r.Header.Get("X-Amzn-Vpce-Id"))
+			})
+		})
+	}
+}
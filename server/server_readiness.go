@@ -0,0 +1,83 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/types"
+)
+
+// addFrontendBalancer sets the load balancer of the backend of a frontend of the entry point.
+func (s *serverEntryPoint) addFrontendBalancer(providerName, frontendName string, balancer healthcheck.BalancerHandler) {
+	if balancer == nil {
+		return
+	}
+	if s.frontendBalancers == nil {
+		s.frontendBalancers = make(map[string]map[string]healthcheck.BalancerHandler)
+	}
+	if s.frontendBalancers[providerName] == nil {
+		s.frontendBalancers[providerName] = make(map[string]healthcheck.BalancerHandler)
+	}
+	s.frontendBalancers[providerName][frontendName] = balancer
+}
+
+// buildFrontendBalancers returns the load balancers of the backends of the frontends of the entry points,
+// by provider and frontend.
+func buildFrontendBalancers(serverEntryPoints map[string]*serverEntryPoint) map[string]map[string][]healthcheck.BalancerHandler {
+	balancers := make(map[string]map[string][]healthcheck.BalancerHandler)
+
+	var entryPointNames []string
+	for entryPointName := range serverEntryPoints {
+		entryPointNames = append(entryPointNames, entryPointName)
+	}
+	sort.Strings(entryPointNames)
+
+	for _, entryPointName := range entryPointNames {
+		for providerName, frontends := range serverEntryPoints[entryPointName].frontendBalancers {
+			if balancers[providerName] == nil {
+				balancers[providerName] = make(map[string][]healthcheck.BalancerHandler)
+			}
+
+			for frontendName, balancer := range frontends {
+				balancers[providerName][frontendName] = append(balancers[providerName][frontendName], balancer)
+			}
+		}
+	}
+
+	return balancers
+}
+
+// frontendReadiness returns whether the frontend has at least one healthy server, and whether the frontend exists.
+func (s *Server) frontendReadiness(frontendName string) (bool, bool) {
+	currentConfigurations := s.currentConfigurations.Get().(types.Configurations)
+	balancers := s.frontendBalancers.Get().(map[string]map[string][]healthcheck.BalancerHandler)
+
+	return frontendReady(currentConfigurations, balancers, frontendName)
+}
+
+// frontendReady returns whether a load balancer of the backend of the frontend has at least one server, and whether the frontend exists.
+// The servers are the running ones of the load balancers, which the health checks remove the failing servers from,
+// and the DNS discoveries add the resolved servers to.
+func frontendReady(configurations types.Configurations, balancers map[string]map[string][]healthcheck.BalancerHandler, frontendName string) (bool, bool) {
+	var providerNames []string
+	for providerName := range configurations {
+		providerNames = append(providerNames, providerName)
+	}
+	sort.Strings(providerNames)
+
+	for _, providerName := range providerNames {
+		config := configurations[providerName]
+		if config == nil || config.Frontends[frontendName] == nil {
+			continue
+		}
+
+		for _, balancer := range balancers[providerName][frontendName] {
+			if len(balancer.Servers()) > 0 {
+				return true, true
+			}
+		}
+		return false, true
+	}
+
+	return false, false
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/testhelpers"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontendReady(t *testing.T) {
+	configurations := types.Configurations{
+		"file": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"healthy":      {Backend: "healthy"},
+				"unhealthy":    {Backend: "unhealthy"},
+				"discovered":   {Backend: "discovered"},
+				"no-server":    {Backend: "no-server"},
+				"missing":      {Backend: "missing"},
+				"shared-front": {Backend: "healthy"},
+			},
+			Backends: map[string]*types.Backend{
+				"healthy":    {Servers: map[string]types.Server{"server": {URL: "http://127.0.0.1:8080"}}},
+				"unhealthy":  {Servers: map[string]types.Server{"server": {URL: "http://127.0.0.1:8081"}}},
+				"discovered": {DNSDiscovery: &types.DNSDiscovery{Name: "foo.bar"}},
+				"no-server":  {},
+			},
+		},
+		"rest": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"other-provider": {Backend: "unhealthy"},
+			},
+			Backends: map[string]*types.Backend{
+				"unhealthy": {Servers: map[string]types.Server{"server": {URL: "http://127.0.0.1:8083"}}},
+			},
+		},
+	}
+
+	healthy := newTestRoundRobin(t)
+	require.NoError(t, healthy.UpsertServer(testhelpers.MustParseURL("http://127.0.0.1:8080")))
+
+	// The health check removed the server of the load balancer.
+	unhealthy := newTestRoundRobin(t)
+
+	discovered := newTestRoundRobin(t)
+	require.NoError(t, discovered.UpsertServer(testhelpers.MustParseURL("http://10.0.0.1")))
+
+	otherProvider := newTestRoundRobin(t)
+	require.NoError(t, otherProvider.UpsertServer(testhelpers.MustParseURL("http://127.0.0.1:8083")))
+
+	balancers := map[string]map[string][]healthcheck.BalancerHandler{
+		"file": {
+			"healthy":      {healthy},
+			"unhealthy":    {unhealthy},
+			"discovered":   {discovered},
+			"no-server":    {newTestRoundRobin(t)},
+			"shared-front": {unhealthy, healthy},
+		},
+		"rest": {
+			"other-provider": {otherProvider},
+		},
+	}
+
+	testCases := []struct {
+		desc           string
+		frontendName   string
+		expectedReady  bool
+		expectedExists bool
+	}{
+		{
+			desc:           "healthy server",
+			frontendName:   "healthy",
+			expectedReady:  true,
+			expectedExists: true,
+		},
+		{
+			desc:           "healthy server on one of the entry points",
+			frontendName:   "shared-front",
+			expectedReady:  true,
+			expectedExists: true,
+		},
+		{
+			desc:           "all servers disabled by the health check",
+			frontendName:   "unhealthy",
+			expectedExists: true,
+		},
+		{
+			desc:           "servers of a DNS discovery",
+			frontendName:   "discovered",
+			expectedReady:  true,
+			expectedExists: true,
+		},
+		{
+			desc:           "backend of the same name in another provider",
+			frontendName:   "other-provider",
+			expectedReady:  true,
+			expectedExists: true,
+		},
+		{
+			desc:           "backend without server",
+			frontendName:   "no-server",
+			expectedExists: true,
+		},
+		{
+			desc:           "undefined backend",
+			frontendName:   "missing",
+			expectedExists: true,
+		},
+		{
+			desc:         "undefined frontend",
+			frontendName: "undefined",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ready, exists := frontendReady(configurations, balancers, test.frontendName)
+			assert.Equal(t, test.expectedReady, ready)
+			assert.Equal(t, test.expectedExists, exists)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package server
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// controlReusePort fails, SO_REUSEPORT is not supported on this platform.
+func controlReusePort(_, _ string, _ syscall.RawConn) error {
+	return fmt.Errorf("reusePort is not supported on %s", runtime.GOOS)
+}
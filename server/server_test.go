@@ -1,18 +1,24 @@
 package server
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/containous/flaeg/parse"
 	"github.com/containous/mux"
 	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/h2c"
 	"github.com/containous/traefik/middlewares"
 	th "github.com/containous/traefik/testhelpers"
 	"github.com/containous/traefik/types"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/unrolled/secure"
@@ -72,6 +78,47 @@ func TestPrepareServerTimeouts(t *testing.T) {
 	}
 }
 
+func TestStartServerTLSWithoutH2C(t *testing.T) {
+	cert, err := tls.X509KeyPair([]byte(localhostCert), []byte(localhostKey))
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	httpServer := &h2c.Server{
+		Server: &http.Server{
+			Handler: http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			}),
+			TLSConfig: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				NextProtos:   []string{"h2", "http/1.1"},
+			},
+		},
+	}
+	defer httpServer.Close()
+
+	srv := NewServer(configuration.GlobalConfiguration{}, nil, nil)
+	go srv.startServer(&serverEntryPoint{httpServer: httpServer, listener: listener})
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req, err := http.NewRequest(http.MethodGet, "https://127.0.0.1/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+	req.Header.Set("Upgrade", "h2c")
+	req.Header.Set("HTTP2-Settings", "AAMAAABkAARAAAAAAAIAAAAA")
+	require.NoError(t, req.Write(conn))
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the request should not be upgraded to h2c over TLS")
+}
+
 func TestListenProvidersSkipsEmptyConfigs(t *testing.T) {
 	server, stop, invokeStopChan := setupListenProvider(10 * time.Millisecond)
 	defer invokeStopChan()
@@ -324,6 +371,57 @@ func TestServerResponseEmptyBackend(t *testing.T) {
 	}
 }
 
+func TestServerWebsocketTLSBackendWithRetryRefusedRequests(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(rw, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(messageType, message)
+	}))
+	defer backend.Close()
+
+	globalConfig := configuration.GlobalConfiguration{
+		InsecureSkipVerify: true,
+		ForwardingHTTP2:    &configuration.ForwardingHTTP2{RetryRefusedRequests: true},
+	}
+	entryPointsConfig := map[string]EntryPoint{
+		"http": {Configuration: &configuration.EntryPoint{ForwardedHeaders: &configuration.ForwardedHeaders{Insecure: true}}},
+	}
+	dynamicConfigs := types.Configurations{"config": th.BuildConfiguration(
+		th.WithFrontends(th.WithFrontend("backend",
+			th.WithEntryPoints("http"),
+			th.WithRoutes(th.WithRoute("/ws", "Path:/ws"))),
+		),
+		th.WithBackends(th.WithBackendNew("backend",
+			th.WithLBMethod("wrr"),
+			th.WithServersNew(th.WithServerNew(backend.URL))),
+		),
+	)}
+
+	srv := NewServer(globalConfig, nil, entryPointsConfig)
+	entryPoints := srv.loadConfig(dynamicConfigs, globalConfig)
+
+	frontend := httptest.NewServer(entryPoints["http"].httpRouter)
+	defer frontend.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(frontend.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("OK")))
+
+	_, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "OK", string(message))
+}
+
 type mockContext struct {
 	headers http.Header
 }
@@ -349,14 +447,14 @@ func (c mockContext) Value(key interface{}) interface{} {
 func TestNewServerWithResponseModifiers(t *testing.T) {
 	testCases := []struct {
 		desc             string
-		headerMiddleware *middlewares.HeaderStruct
+		headers          *types.Headers
 		secureMiddleware *secure.Secure
 		ctx              context.Context
 		expected         map[string]string
 	}{
 		{
 			desc:             "header and secure nil",
-			headerMiddleware: nil,
+			headers:          nil,
 			secureMiddleware: nil,
 			ctx:              mockContext{},
 			expected: map[string]string{
@@ -366,11 +464,11 @@ func TestNewServerWithResponseModifiers(t *testing.T) {
 		},
 		{
 			desc: "header middleware not nil",
-			headerMiddleware: middlewares.NewHeaderFromStruct(&types.Headers{
+			headers: &types.Headers{
 				CustomResponseHeaders: map[string]string{
 					"X-Default": "powpow",
 				},
-			}),
+			},
 			secureMiddleware: nil,
 			ctx:              mockContext{},
 			expected: map[string]string{
@@ -379,8 +477,8 @@ func TestNewServerWithResponseModifiers(t *testing.T) {
 			},
 		},
 		{
-			desc:             "secure middleware not nil",
-			headerMiddleware: nil,
+			desc:    "secure middleware not nil",
+			headers: nil,
 			secureMiddleware: middlewares.NewSecure(&types.Headers{
 				ReferrerPolicy: "no-referrer",
 			}),
@@ -394,11 +492,11 @@ func TestNewServerWithResponseModifiers(t *testing.T) {
 		},
 		{
 			desc: "header and secure middleware not nil",
-			headerMiddleware: middlewares.NewHeaderFromStruct(&types.Headers{
+			headers: &types.Headers{
 				CustomResponseHeaders: map[string]string{
 					"Referrer-Policy": "powpow",
 				},
-			}),
+			},
 			secureMiddleware: middlewares.NewSecure(&types.Headers{
 				ReferrerPolicy: "no-referrer",
 			}),
@@ -428,8 +526,11 @@ func TestNewServerWithResponseModifiers(t *testing.T) {
 				Header:  headers,
 			}
 
-			responseModifier := buildModifyResponse(test.secureMiddleware, test.headerMiddleware)
-			err := responseModifier(res)
+			headerMiddleware, err := middlewares.NewHeaderFromStruct(test.headers)
+			require.NoError(t, err)
+
+			responseModifier := buildModifyResponse(test.secureMiddleware, headerMiddleware, nil, nil, nil)
+			err = responseModifier(res)
 
 			assert.NoError(t, err)
 			assert.Equal(t, len(test.expected), len(res.Header))
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/log"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// listen opens the listener of an entry point.
+func listen(entryPoint *configuration.EntryPoint) (net.Listener, error) {
+	listenConfig := net.ListenConfig{}
+	if entryPoint.Transport != nil && entryPoint.Transport.ReusePort {
+		listenConfig.Control = controlReusePort
+	}
+
+	return listenConfig.Listen(context.Background(), "tcp", entryPoint.Address)
+}
+
+// connLimitListener limits the number of simultaneous connections of an entry point.
+// The connections beyond the limit wait up to the queue timeout for a slot, before they are closed.
+// While a connection waits, the next ones are left in the backlog of the listener.
+type connLimitListener struct {
+	net.Listener
+	entryPointName string
+	slots          chan struct{}
+	queueTimeout   time.Duration
+	connsGauge     gokitmetrics.Gauge
+	rejected       gokitmetrics.Counter
+}
+
+// newConnLimitListener creates a new connLimitListener.
+// The gauges of the current and the maximum connections, and the counter of the rejected connections, are optional.
+func newConnLimitListener(listener net.Listener, entryPointName string, transport *configuration.Transport, connsGauge, maxConnsGauge gokitmetrics.Gauge, rejected gokitmetrics.Counter) (*connLimitListener, error) {
+	if transport.MaxConnections <= 0 {
+		return nil, errors.New("the maximum number of connections must be positive")
+	}
+	if transport.QueueTimeout < 0 {
+		return nil, errors.New("the connection queue timeout must be positive")
+	}
+
+	l := &connLimitListener{
+		Listener:       listener,
+		entryPointName: entryPointName,
+		slots:          make(chan struct{}, transport.MaxConnections),
+		queueTimeout:   time.Duration(transport.QueueTimeout),
+	}
+
+	if connsGauge != nil {
+		l.connsGauge = connsGauge.With("entrypoint", entryPointName)
+		l.connsGauge.Set(0)
+	}
+	if maxConnsGauge != nil {
+		maxConnsGauge.With("entrypoint", entryPointName).Set(float64(transport.MaxConnections))
+	}
+	if rejected != nil {
+		l.rejected = rejected.With("entrypoint", entryPointName)
+	}
+
+	return l, nil
+}
+
+func (l *connLimitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if l.acquire() {
+			return &connLimitConn{Conn: conn, release: l.release}, nil
+		}
+
+		log.Debugf("Closing the connection of %s on entry point %s: too many connections", conn.RemoteAddr(), l.entryPointName)
+		if l.rejected != nil {
+			l.rejected.Add(1)
+		}
+		conn.Close()
+	}
+}
+
+func (l *connLimitListener) acquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		l.updateGauge()
+		return true
+	default:
+	}
+
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		l.updateGauge()
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (l *connLimitListener) release() {
+	<-l.slots
+	l.updateGauge()
+}
+
+func (l *connLimitListener) updateGauge() {
+	if l.connsGauge != nil {
+		l.connsGauge.Set(float64(len(l.slots)))
+	}
+}
+
+// connLimitConn releases its slot when it is closed.
+type connLimitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *connLimitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
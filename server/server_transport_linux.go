@@ -0,0 +1,34 @@
+// +build linux
+
+package server
+
+import (
+	"net"
+	"syscall"
+	"time"
+)
+
+// setKeepAliveProbes sets the interval and the number of the keep-alive probes of a connection.
+func setKeepAliveProbes(conn *net.TCPConn, interval time.Duration, count int) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		if interval > 0 {
+			secs := int((interval + time.Second - 1) / time.Second)
+			if sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, secs); sockErr != nil {
+				return
+			}
+		}
+		if count > 0 {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, count)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// setKeepAliveProbes is only supported on Linux.
+func setKeepAliveProbes(_ *net.TCPConn, _ time.Duration, _ int) error {
+	return errors.New("the keep-alive interval and count are only supported on Linux")
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/configuration"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// entryPointMetric records the value of a gauge or a counter, by entry point.
+type entryPointMetric struct {
+	lock   *sync.Mutex
+	values map[string]float64
+	label  string
+}
+
+func newEntryPointMetric() *entryPointMetric {
+	return &entryPointMetric{lock: &sync.Mutex{}, values: make(map[string]float64)}
+}
+
+func (m *entryPointMetric) With(labelValues ...string) gokitmetrics.Gauge {
+	return &entryPointMetric{lock: m.lock, values: m.values, label: labelValues[len(labelValues)-1]}
+}
+
+func (m *entryPointMetric) Set(value float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[m.label] = value
+}
+
+func (m *entryPointMetric) Add(delta float64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[m.label] += delta
+}
+
+func (m *entryPointMetric) value(entryPointName string) float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.values[entryPointName]
+}
+
+// entryPointCounter adapts an entryPointMetric to a counter.
+type entryPointCounter struct {
+	*entryPointMetric
+}
+
+func (c entryPointCounter) With(labelValues ...string) gokitmetrics.Counter {
+	return entryPointCounter{c.entryPointMetric.With(labelValues...).(*entryPointMetric)}
+}
+
+func TestConnLimitListener(t *testing.T) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcpListener.Close()
+
+	connsGauge := newEntryPointMetric()
+	maxConnsGauge := newEntryPointMetric()
+	rejected := newEntryPointMetric()
+
+	listener, err := newConnLimitListener(tcpListener, "http", &configuration.Transport{MaxConnections: 1}, connsGauge, maxConnsGauge, entryPointCounter{rejected})
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), maxConnsGauge.value("http"))
+
+	clientConn, err := net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), connsGauge.value("http"))
+
+	// The second connection is closed, and the third one takes the slot released by the first one.
+	secondConn, err := net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer secondConn.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	require.NoError(t, secondConn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = secondConn.Read(make([]byte, 1))
+	require.Error(t, err)
+	assert.False(t, isTimeout(err))
+	assert.Equal(t, float64(1), rejected.value("http"))
+
+	require.NoError(t, conn.Close())
+	conn.Close()
+	assert.Equal(t, float64(0), connsGauge.value("http"))
+
+	thirdConn, err := net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer thirdConn.Close()
+
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+		assert.Equal(t, thirdConn.LocalAddr().String(), conn.RemoteAddr().String())
+		assert.Equal(t, float64(1), connsGauge.value("http"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the third connection was not accepted")
+	}
+}
+
+func TestConnLimitListenerQueueTimeout(t *testing.T) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer tcpListener.Close()
+
+	transport := &configuration.Transport{MaxConnections: 1, QueueTimeout: parse.Duration(5 * time.Second)}
+	listener, err := newConnLimitListener(tcpListener, "http", transport, nil, nil, nil)
+	require.NoError(t, err)
+
+	clientConn, err := net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+
+	secondConn, err := net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer secondConn.Close()
+
+	// The second connection waits for the slot of the first one.
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, conn.Close())
+
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+		assert.Equal(t, secondConn.LocalAddr().String(), conn.RemoteAddr().String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("the second connection was not accepted")
+	}
+}
+
+func TestListenReusePort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SO_REUSEPORT is not supported on windows")
+	}
+
+	entryPoint := &configuration.EntryPoint{
+		Address:   "127.0.0.1:0",
+		Transport: &configuration.Transport{ReusePort: true},
+	}
+
+	listener, err := listen(entryPoint)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Another listener can listen on the same port, only with reusePort.
+	entryPoint.Address = listener.Addr().String()
+
+	otherListener, err := listen(entryPoint)
+	require.NoError(t, err)
+	defer otherListener.Close()
+
+	_, err = listen(&configuration.EntryPoint{Address: entryPoint.Address})
+	assert.Error(t, err)
+}
+
+func TestNewConnLimitListenerFail(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		transport *configuration.Transport
+	}{
+		{
+			desc:      "no maximum number of connections",
+			transport: &configuration.Transport{},
+		},
+		{
+			desc:      "negative queue timeout",
+			transport: &configuration.Transport{MaxConnections: 1, QueueTimeout: parse.Duration(-time.Second)},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newConnLimitListener(nil, "http", test.transport, nil, nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/types"
+)
+
+// validateConfiguration validates a dynamic configuration without applying it,
+// with the checks made when the configurations are loaded, and returns its errors.
+// The configuration is not modified, neither is the running configuration.
+func (s *Server) validateConfiguration(config *types.Configuration) []types.ConfigurationError {
+	var errs []types.ConfigurationError
+	if config == nil {
+		return errs
+	}
+
+	hostResolver := buildHostResolver(s.globalConfiguration)
+	serverEntryPoint := &serverEntryPoint{httpRouter: middlewares.NewHandlerSwitcher(s.buildDefaultHTTPRouter())}
+
+	// The load balancers are defaulted as in configureBackends, on copies of the backends.
+	backends := make(map[string]*types.Backend, len(config.Backends))
+	for backendName, backend := range config.Backends {
+		if backend != nil {
+			backendCopy := *backend
+			backends[backendName] = &backendCopy
+		}
+	}
+	configureBackends(backends)
+
+	// served holds the backends of the valid frontends by entry point,
+	// as the failover and fallback backends are resolved among them.
+	served := make(map[string]bool)
+	var frontends []*types.Frontend
+	var frontendNames []string
+
+	for _, frontendName := range sortedFrontendNamesForConfig(config) {
+		addError := func(err error) {
+			errs = append(errs, types.ConfigurationError{Frontend: frontendName, Message: err.Error()})
+		}
+
+		if config.Frontends[frontendName] == nil {
+			addError(fmt.Errorf("empty frontend %s", frontendName))
+			continue
+		}
+
+		// The entry points are defaulted and filtered as in defaultConfigurationValues, on a copy of the frontend.
+		frontend := *config.Frontends[frontendName]
+		if len(frontend.EntryPoints) == 0 {
+			frontend.EntryPoints = s.globalConfiguration.DefaultEntryPoints
+		}
+
+		var undefinedEntryPoints []string
+		frontend.EntryPoints, undefinedEntryPoints = s.filterEntryPoints(frontend.EntryPoints)
+		if len(undefinedEntryPoints) > 0 {
+			addError(fmt.Errorf("undefined entry point(s) '%s' for frontend %s", strings.Join(undefinedEntryPoints, ","), frontendName))
+		}
+
+		if err := checkFrontend(frontendName, &frontend, config.Backends); err != nil {
+			addError(err)
+			continue
+		}
+
+		if _, err := frontend.Hash(); err != nil {
+			addError(fmt.Errorf("error calculating hash value for frontend %s: %v", frontendName, err))
+			continue
+		}
+
+		valid := true
+		for _, err := range checkFrontendReferences(frontendName, &frontend, config.Backends) {
+			addError(err)
+			valid = false
+		}
+
+		serverRoute, err := buildServerRoute(serverEntryPoint, frontendName, &frontend, hostResolver)
+		if err == nil {
+			err = serverRoute.Route.GetError()
+		}
+		if err != nil {
+			addError(err)
+			valid = false
+		}
+
+		if !valid {
+			continue
+		}
+
+		if err := s.checkFrontendHandlers(frontendName, &frontend, backends); err != nil {
+			addError(err)
+			continue
+		}
+
+		for _, entryPointName := range frontend.EntryPoints {
+			served[entryPointName+frontend.Backend] = true
+		}
+		frontends = append(frontends, &frontend)
+		frontendNames = append(frontendNames, frontendName)
+	}
+
+	for i, frontend := range frontends {
+		for _, err := range checkResolvedBackends(frontend, config.Backends[frontend.Backend], served) {
+			errs = append(errs, types.ConfigurationError{Frontend: frontendNames[i], Backend: frontend.Backend, Message: err.Error()})
+		}
+	}
+
+	var backendNames []string
+	for backendName := range config.Backends {
+		backendNames = append(backendNames, backendName)
+	}
+	sort.Strings(backendNames)
+
+	for _, backendName := range backendNames {
+		backend := config.Backends[backendName]
+		if backend == nil {
+			errs = append(errs, types.ConfigurationError{Backend: backendName, Message: fmt.Sprintf("empty backend %s", backendName)})
+			continue
+		}
+
+		var serverNames []string
+		for serverName := range backend.Servers {
+			serverNames = append(serverNames, serverName)
+		}
+		sort.Strings(serverNames)
+
+		for _, serverName := range serverNames {
+			srv := backend.Servers[serverName]
+			if _, err := url.Parse(srv.URL); err != nil {
+				errs = append(errs, types.ConfigurationError{Backend: backendName, Message: fmt.Sprintf("error parsing server URL %s: %v", srv.URL, err)})
+			}
+		}
+	}
+
+	return errs
+}
+
+// checkFrontendHandlers builds the middlewares, the forwarder and the load balancer of a frontend for each of its entry points,
+// as when the configuration is loaded, and returns the first error.
+// The post configurations aren't run: the shared Kafka producers, processor connections and Redis clients aren't got,
+// the servers of the DNS discoveries aren't resolved, and nothing is connected.
+func (s *Server) checkFrontendHandlers(frontendName string, frontend *types.Frontend, backends map[string]*types.Backend) error {
+	backend := backends[frontend.Backend]
+
+	for _, entryPointName := range frontend.EntryPoints {
+		var chain middlewareChain
+
+		_, responseModifier, _, err := s.buildMiddlewares(frontendName, frontend, backends, entryPointName, "", &chain)
+		if err != nil {
+			return err
+		}
+
+		fwd, err := s.buildForwarder(entryPointName, s.entryPoints[entryPointName].Configuration, frontendName, frontend, responseModifier, backend)
+		if err != nil {
+			return fmt.Errorf("failed to create the forwarder for frontend %s: %v", frontendName, err)
+		}
+
+		if _, _, _, _, err := s.buildBalancerMiddlewares(entryPointName, "", frontendName, frontend, backend, fwd, &chain); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkFrontend checks the entry points and the backend of a frontend, without which it is skipped.
+func checkFrontend(frontendName string, frontend *types.Frontend, backends map[string]*types.Backend) error {
+	if len(frontend.EntryPoints) == 0 {
+		return fmt.Errorf("no entrypoint defined for frontend %s", frontendName)
+	}
+
+	if backends[frontend.Backend] == nil {
+		return fmt.Errorf("undefined backend '%s' for frontend %s", frontend.Backend, frontendName)
+	}
+
+	return nil
+}
+
+// checkFrontendReferences checks the backends referenced by the middlewares of a frontend.
+func checkFrontendReferences(frontendName string, frontend *types.Frontend, backends map[string]*types.Backend) []error {
+	var errs []error
+
+	var errorPageNames []string
+	for errorPageName := range frontend.Errors {
+		errorPageNames = append(errorPageNames, errorPageName)
+	}
+	sort.Strings(errorPageNames)
+
+	for _, errorPageName := range errorPageNames {
+		if err := checkErrorPage(frontendName, frontend, errorPageName, frontend.Errors[errorPageName], backends); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if frontend.BackendSchedule != nil {
+		if err := checkBackendSchedule(frontendName, frontend.BackendSchedule, backends); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if frontend.BackendRamp != nil {
+		if err := checkBackendRamp(frontendName, frontend.BackendRamp, backends); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// checkResolvedBackends checks the failover and circuit breaker fallback backends of the backend of a frontend,
+// which must be the backends of valid frontends of the same entry points.
+func checkResolvedBackends(frontend *types.Frontend, backend *types.Backend, served map[string]bool) []error {
+	var errs []error
+
+	for _, failoverBackendName := range backend.FailoverBackends {
+		if failoverBackendName == frontend.Backend {
+			errs = append(errs, fmt.Errorf("the failover backend %q is the backend itself", failoverBackendName))
+			continue
+		}
+
+		for _, entryPointName := range frontend.EntryPoints {
+			if !served[entryPointName+failoverBackendName] {
+				errs = append(errs, fmt.Errorf("failover backend %s not found on entry point %s", failoverBackendName, entryPointName))
+			}
+		}
+	}
+
+	if backend.CircuitBreaker != nil && backend.CircuitBreaker.Fallback != nil && len(backend.CircuitBreaker.Fallback.Backend) > 0 {
+		fallbackBackendName := backend.CircuitBreaker.Fallback.Backend
+		if fallbackBackendName == frontend.Backend {
+			errs = append(errs, fmt.Errorf("the fallback backend %q is the circuit breaker backend", fallbackBackendName))
+		} else {
+			for _, entryPointName := range frontend.EntryPoints {
+				if !served[entryPointName+fallbackBackendName] {
+					errs = append(errs, fmt.Errorf("circuit breaker fallback backend %s not found on entry point %s", fallbackBackendName, entryPointName))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+func checkErrorPage(frontendName string, frontend *types.Frontend, errorPageName string, errorPage *types.ErrorPage, backends map[string]*types.Backend) error {
+	if frontend.Backend == errorPage.Backend {
+		return fmt.Errorf("error when creating error page %q for frontend %q: error pages backend %q is the same as backend for the frontend (infinite call risk)",
+			errorPageName, frontendName, errorPage.Backend)
+	}
+
+	if backends[errorPage.Backend] == nil {
+		return fmt.Errorf("error when creating error page %q for frontend %q: the backend %q doesn't exist",
+			errorPageName, frontendName, errorPage.Backend)
+	}
+
+	return nil
+}
+
+func checkBackendSchedule(frontendName string, schedule *types.BackendSchedule, backends map[string]*types.Backend) error {
+	var windowNames []string
+	for windowName := range schedule.Windows {
+		windowNames = append(windowNames, windowName)
+	}
+	sort.Strings(windowNames)
+
+	for _, windowName := range windowNames {
+		window := schedule.Windows[windowName]
+		if window != nil && backends[window.Backend] == nil {
+			return fmt.Errorf("error creating backend schedule for frontend %s: the backend %q of window %s doesn't exist", frontendName, window.Backend, windowName)
+		}
+	}
+
+	return nil
+}
+
+func checkBackendRamp(frontendName string, ramp *types.BackendRamp, backends map[string]*types.Backend) error {
+	if backends[ramp.Backend] == nil {
+		return fmt.Errorf("error creating backend ramp for frontend %s: the backend %q doesn't exist", frontendName, ramp.Backend)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/containous/traefik/configuration"
+	"github.com/containous/traefik/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerValidateConfiguration(t *testing.T) {
+	_, urlErr := url.Parse("http://[::1")
+
+	testCases := []struct {
+		desc     string
+		config   *types.Configuration
+		expected []types.ConfigurationError
+	}{
+		{
+			desc: "valid configuration",
+			config: &types.Configuration{
+				Frontends: map[string]*types.Frontend{
+					"frontend": {
+						Backend: "backend",
+						Routes:  map[string]types.Route{"route": {Rule: "Host:example.com"}},
+					},
+					"fallback": {
+						Backend:     "fallback",
+						EntryPoints: []string{"http"},
+					},
+				},
+				Backends: map[string]*types.Backend{
+					"backend": {
+						Servers:          map[string]types.Server{"server": {URL: "http://127.0.0.1:8080"}},
+						FailoverBackends: []string{"fallback"},
+					},
+					"fallback": {
+						Servers: map[string]types.Server{"server": {URL: "http://127.0.0.1:8081"}},
+					},
+				},
+			},
+		},
+		{
+			desc:   "empty configuration",
+			config: &types.Configuration{},
+		},
+		{
+			desc: "undefined references",
+			config: &types.Configuration{
+				Frontends: map[string]*types.Frontend{
+					"missing-backend": {Backend: "missing"},
+					"missing-entrypoint": {
+						Backend:     "backend",
+						EntryPoints: []string{"http", "https"},
+					},
+					"missing-references": {
+						Backend:     "backend",
+						Errors:      map[string]*types.ErrorPage{"5xx": {Backend: "errors", Status: []string{"500-599"}}},
+						BackendRamp: &types.BackendRamp{Backend: "canary"},
+					},
+					"self-fallback": {Backend: "self-fallback"},
+				},
+				Backends: map[string]*types.Backend{
+					"backend": {
+						FailoverBackends: []string{"unserved"},
+					},
+					"self-fallback": {
+						CircuitBreaker: &types.CircuitBreaker{
+							Expression: "NetworkErrorRatio() > 0.5",
+							Fallback:   &types.CircuitBreakerFallback{Backend: "self-fallback"},
+						},
+					},
+					"unserved": {},
+				},
+			},
+			expected: []types.ConfigurationError{
+				{Frontend: "missing-backend", Message: "undefined backend 'missing' for frontend missing-backend"},
+				{Frontend: "missing-entrypoint", Message: "undefined entry point(s) 'https' for frontend missing-entrypoint"},
+				{Frontend: "missing-references", Message: `error when creating error page "5xx" for frontend "missing-references": the backend "errors" doesn't exist`},
+				{Frontend: "missing-references", Message: `error creating backend ramp for frontend missing-references: the backend "canary" doesn't exist`},
+				{Frontend: "self-fallback", Message: `error creating circuit breaker fallback: the fallback backend "self-fallback" is the circuit breaker backend`},
+				{Frontend: "missing-entrypoint", Backend: "backend", Message: "failover backend unserved not found on entry point http"},
+			},
+		},
+		{
+			desc: "invalid whitelist",
+			config: &types.Configuration{
+				Frontends: map[string]*types.Frontend{
+					"frontend": {
+						Backend:   "backend",
+						WhiteList: &types.WhiteList{SourceRange: []string{"10.0.0.0/33"}},
+					},
+				},
+				Backends: map[string]*types.Backend{
+					"backend": {
+						Servers: map[string]types.Server{"server": {URL: "http://127.0.0.1:8080"}},
+					},
+				},
+			},
+			expected: []types.ConfigurationError{
+				{Frontend: "frontend", Message: "error creating IP Whitelister: parsing CIDR whitelist [10.0.0.0/33]: parsing CIDR trusted IPs <nil>: invalid CIDR address: 10.0.0.0/33"},
+			},
+		},
+		{
+			desc: "invalid rule and server URL",
+			config: &types.Configuration{
+				Frontends: map[string]*types.Frontend{
+					"frontend": {
+						Backend: "backend",
+						Routes:  map[string]types.Route{"route": {Rule: "Hots:example.com"}},
+					},
+				},
+				Backends: map[string]*types.Backend{
+					"backend": {
+						Servers: map[string]types.Server{"server": {URL: "http://[::1"}},
+					},
+					"empty": nil,
+				},
+			},
+			expected: []types.ConfigurationError{
+				{Frontend: "frontend", Message: "error creating route for frontend frontend: error parsing rule: error parsing rule: 'Hots:example.com'. Unknown function: 'Hots'"},
+				{Backend: "backend", Message: "error parsing server URL http://[::1: " + urlErr.Error()},
+				{Backend: "empty", Message: "empty backend empty"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			globalConfig := configuration.GlobalConfiguration{
+				DefaultEntryPoints: []string{"http"},
+			}
+			entryPoints := map[string]EntryPoint{
+				"http": {Configuration: &configuration.EntryPoint{}},
+			}
+
+			srv := NewServer(globalConfig, nil, entryPoints)
+
+			errs := srv.validateConfiguration(test.config)
+			assert.Equal(t, test.expected, errs)
+
+			// The validated configuration is not modified.
+			for _, frontend := range test.config.Frontends {
+				if frontend.Backend == "backend" && frontend.Routes != nil {
+					assert.Empty(t, frontend.EntryPoints)
+				}
+			}
+		})
+	}
+}
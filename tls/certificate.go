@@ -21,6 +21,15 @@ var (
 		`VersionTLS12`: tls.VersionTLS12,
 	}
 
+	// ClientAuthTypes Map of the client authentication types from crypto/tls
+	ClientAuthTypes = map[string]tls.ClientAuthType{
+		`NoClientCert`:               tls.NoClientCert,
+		`RequestClientCert`:          tls.RequestClientCert,
+		`RequireAnyClientCert`:       tls.RequireAnyClientCert,
+		`VerifyClientCertIfGiven`:    tls.VerifyClientCertIfGiven,
+		`RequireAndVerifyClientCert`: tls.RequireAndVerifyClientCert,
+	}
+
 	// CipherSuites Map of TLS CipherSuites from crypto/tls
 	// Available CipherSuites defined at https://golang.org/pkg/crypto/tls/#pkg-constants
 	CipherSuites = map[string]uint16{
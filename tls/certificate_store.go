@@ -17,8 +17,12 @@ type CertificateStore struct {
 	DynamicCerts       *safe.Safe
 	StaticCerts        *safe.Safe
 	DefaultCertificate *tls.Certificate
-	CertCache          *cache.Cache
-	SniStrict          bool
+	// SNIDefaultCertificates are the default certificates by SNI pattern.
+	SNIDefaultCertificates map[string]*tls.Certificate
+	CertCache              *cache.Cache
+	SniStrict              bool
+	// StripTrailingDot selects the certificates of the server names ending with a dot as without it.
+	StripTrailingDot bool
 }
 
 // NewCertificateStore create a store for dynamic and static certificates
@@ -50,9 +54,44 @@ func (c CertificateStore) GetAllDomains() []string {
 	return allCerts
 }
 
+// GetAllCertificates returns all the certificates the store serves, the default ones included.
+func (c CertificateStore) GetAllCertificates() []*tls.Certificate {
+	var allCerts []*tls.Certificate
+	seen := make(map[*tls.Certificate]bool)
+
+	add := func(cert *tls.Certificate) {
+		if cert != nil && !seen[cert] {
+			seen[cert] = true
+			allCerts = append(allCerts, cert)
+		}
+	}
+
+	if c.StaticCerts != nil && c.StaticCerts.Get() != nil {
+		for _, cert := range c.StaticCerts.Get().(map[string]*tls.Certificate) {
+			add(cert)
+		}
+	}
+
+	if c.DynamicCerts != nil && c.DynamicCerts.Get() != nil {
+		for _, cert := range c.DynamicCerts.Get().(map[string]*tls.Certificate) {
+			add(cert)
+		}
+	}
+
+	for _, cert := range c.SNIDefaultCertificates {
+		add(cert)
+	}
+	add(c.DefaultCertificate)
+
+	return allCerts
+}
+
 // GetBestCertificate returns the best match certificate, and caches the response
 func (c CertificateStore) GetBestCertificate(clientHello *tls.ClientHelloInfo) *tls.Certificate {
 	domainToCheck := strings.ToLower(strings.TrimSpace(clientHello.ServerName))
+	if c.StripTrailingDot {
+		domainToCheck = strings.TrimSuffix(domainToCheck, ".")
+	}
 	if len(domainToCheck) == 0 {
 		// If no ServerName is provided, Check for local IP address matches
 		host, _, err := net.SplitHostPort(clientHello.Conn.LocalAddr().String())
@@ -103,6 +142,33 @@ func (c CertificateStore) GetBestCertificate(clientHello *tls.ClientHelloInfo) *
 	return nil
 }
 
+// GetDefaultCertificate returns the default certificate of the domain:
+// the one with the most specific SNI pattern matching the domain, or the global default certificate.
+func (c CertificateStore) GetDefaultCertificate(domain string) *tls.Certificate {
+	var bestPattern string
+	for pattern := range c.SNIDefaultCertificates {
+		if MatchSNIPattern(domain, pattern) && len(pattern) > len(bestPattern) {
+			bestPattern = pattern
+		}
+	}
+
+	if len(bestPattern) > 0 {
+		return c.SNIDefaultCertificates[bestPattern]
+	}
+
+	return c.DefaultCertificate
+}
+
+// MatchSNIPattern returns true if the domain matches the SNI pattern:
+// the exact domain, or a wildcard (*.example.com) matching all the subdomains of a domain.
+func MatchSNIPattern(domain string, pattern string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(domain, pattern[1:])
+	}
+
+	return domain == pattern
+}
+
 // ContainsCertificates checks if there are any certs in the store
 func (c CertificateStore) ContainsCertificates() bool {
 	return c.StaticCerts.Get() != nil || c.DynamicCerts.Get() != nil
@@ -15,11 +15,12 @@ import (
 
 func TestGetBestCertificate(t *testing.T) {
 	testCases := []struct {
-		desc          string
-		domainToCheck string
-		staticCert    string
-		dynamicCert   string
-		expectedCert  string
+		desc             string
+		domainToCheck    string
+		staticCert       string
+		dynamicCert      string
+		stripTrailingDot bool
+		expectedCert     string
 	}{
 		{
 			desc:          "Empty Store, returns no certs",
@@ -84,6 +85,29 @@ func TestGetBestCertificate(t *testing.T) {
 			dynamicCert:   "*.snitest.com",
 			expectedCert:  "*.www.snitest.com",
 		},
+		{
+			desc:          "Server name with trailing dot",
+			domainToCheck: "snitest.com.",
+			staticCert:    "snitest.com",
+			dynamicCert:   "",
+			expectedCert:  "",
+		},
+		{
+			desc:             "Server name with trailing dot stripped",
+			domainToCheck:    "snitest.com.",
+			staticCert:       "snitest.com",
+			dynamicCert:      "",
+			stripTrailingDot: true,
+			expectedCert:     "snitest.com",
+		},
+		{
+			desc:             "Server name with trailing dot stripped and wildcard",
+			domainToCheck:    "www.snitest.com.",
+			staticCert:       "",
+			dynamicCert:      "*.snitest.com",
+			stripTrailingDot: true,
+			expectedCert:     "*.snitest.com",
+		},
 	}
 
 	for _, test := range testCases {
@@ -106,9 +130,10 @@ func TestGetBestCertificate(t *testing.T) {
 			}
 
 			store := &CertificateStore{
-				DynamicCerts: safe.New(dynamicMap),
-				StaticCerts:  safe.New(staticMap),
-				CertCache:    cache.New(1*time.Hour, 10*time.Minute),
+				DynamicCerts:     safe.New(dynamicMap),
+				StaticCerts:      safe.New(staticMap),
+				CertCache:        cache.New(1*time.Hour, 10*time.Minute),
+				StripTrailingDot: test.stripTrailingDot,
 			}
 
 			var expected *tls.Certificate
@@ -128,6 +153,102 @@ func TestGetBestCertificate(t *testing.T) {
 	}
 }
 
+func TestGetDefaultCertificate(t *testing.T) {
+	defaultCert := &tls.Certificate{}
+	tenantACert := &tls.Certificate{}
+	tenantBCert := &tls.Certificate{}
+	tenantBAdminCert := &tls.Certificate{}
+	exactCert := &tls.Certificate{}
+
+	store := &CertificateStore{
+		DefaultCertificate: defaultCert,
+		SNIDefaultCertificates: map[string]*tls.Certificate{
+			"*.tenant-a.com":       tenantACert,
+			"*.tenant-b.com":       tenantBCert,
+			"*.admin.tenant-b.com": tenantBAdminCert,
+			"tenant-c.com":         exactCert,
+		},
+	}
+
+	testCases := []struct {
+		desc         string
+		domain       string
+		expectedCert *tls.Certificate
+	}{
+		{
+			desc:         "wildcard pattern",
+			domain:       "www.tenant-a.com",
+			expectedCert: tenantACert,
+		},
+		{
+			desc:         "wildcard pattern with several labels",
+			domain:       "foo.www.tenant-b.com",
+			expectedCert: tenantBCert,
+		},
+		{
+			desc:         "most specific wildcard pattern",
+			domain:       "foo.admin.tenant-b.com",
+			expectedCert: tenantBAdminCert,
+		},
+		{
+			desc:         "wildcard pattern does not match the domain itself",
+			domain:       "tenant-a.com",
+			expectedCert: defaultCert,
+		},
+		{
+			desc:         "exact pattern",
+			domain:       "tenant-c.com",
+			expectedCert: exactCert,
+		},
+		{
+			desc:         "exact pattern does not match the subdomains",
+			domain:       "www.tenant-c.com",
+			expectedCert: defaultCert,
+		},
+		{
+			desc:         "no matching pattern",
+			domain:       "www.other-tenant-a.com",
+			expectedCert: defaultCert,
+		},
+		{
+			desc:         "no domain",
+			expectedCert: defaultCert,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.True(t, test.expectedCert == store.GetDefaultCertificate(test.domain))
+		})
+	}
+}
+
+func TestGetAllCertificates(t *testing.T) {
+	staticCert := &tls.Certificate{}
+	dynamicCert := &tls.Certificate{}
+	sniDefaultCert := &tls.Certificate{}
+	defaultCert := &tls.Certificate{}
+
+	store := NewCertificateStore()
+	store.StaticCerts.Set(map[string]*tls.Certificate{"static.com": staticCert})
+	// A certificate with several domains is returned once.
+	store.DynamicCerts.Set(map[string]*tls.Certificate{"dynamic.com": dynamicCert, "www.dynamic.com": dynamicCert})
+	store.SNIDefaultCertificates = map[string]*tls.Certificate{"*.tenant.com": sniDefaultCert}
+	store.DefaultCertificate = defaultCert
+
+	certs := store.GetAllCertificates()
+	require.Len(t, certs, 4)
+	assert.True(t, certs[0] == staticCert)
+	assert.True(t, certs[1] == dynamicCert)
+	assert.True(t, certs[2] == sniDefaultCert)
+	assert.True(t, certs[3] == defaultCert)
+
+	assert.Empty(t, NewCertificateStore().GetAllCertificates())
+}
+
 func loadTestCert(certName string) (*tls.Certificate, error) {
 	staticCert, err := tls.LoadX509KeyPair(
 		fmt.Sprintf("../integration/fixtures/https/%s.cert", strings.Replace(certName, "*", "wildcard", -1)),
@@ -0,0 +1,241 @@
+package tls
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/log"
+)
+
+const (
+	defaultCRLRefreshInterval = time.Hour
+	crlMaxBytes               = 10 * 1024 * 1024
+	crlRequestTimeout         = 30 * time.Second
+)
+
+// CRL configures the certificate revocation lists checked for the client certificates,
+// from files (or contents) and from distribution points (URLs), refreshed every RefreshInterval (1 hour by default).
+// When the CRL of an issuer is expired, or could not be loaded, the client certificates are rejected, unless FailOpen is set.
+type CRL struct {
+	Files           FilesOrContents
+	URLs            []string
+	RefreshInterval parse.Duration `export:"true"`
+	FailOpen        bool           `export:"true"`
+}
+
+// revocationList is a loaded certificate revocation list, with the serial numbers of its revoked certificates.
+type revocationList struct {
+	list      *pkix.CertificateList
+	rawIssuer []byte
+	revoked   map[string]struct{}
+}
+
+// tbsCertListIssuer is the beginning of a TBSCertList, up to its issuer, kept DER encoded to be compared to the subject of the certificates.
+type tbsCertListIssuer struct {
+	Version   int `asn1:"optional,default:0"`
+	Signature pkix.AlgorithmIdentifier
+	Issuer    asn1.RawValue
+}
+
+// CRLChecker rejects the verified client certificates revoked by their issuer CRL.
+type CRLChecker struct {
+	sources         []string
+	files           map[string]bool
+	refreshInterval time.Duration
+	failOpen        bool
+	client          *http.Client
+	now             func() time.Time
+
+	lock  sync.RWMutex
+	lists map[string]*revocationList
+}
+
+// NewCRLChecker creates a new CRLChecker, and loads its CRLs.
+// The CRL files must be valid, whereas the distribution points failures are only logged, and retried on the next refresh.
+func NewCRLChecker(config *CRL) (*CRLChecker, error) {
+	if len(config.Files) == 0 && len(config.URLs) == 0 {
+		return nil, errors.New("no CRL files nor URLs")
+	}
+
+	c := &CRLChecker{
+		files:           make(map[string]bool),
+		refreshInterval: defaultCRLRefreshInterval,
+		failOpen:        config.FailOpen,
+		client:          &http.Client{Timeout: crlRequestTimeout},
+		now:             time.Now,
+		lists:           make(map[string]*revocationList),
+	}
+
+	if config.RefreshInterval > 0 {
+		c.refreshInterval = time.Duration(config.RefreshInterval)
+	}
+
+	for _, file := range config.Files {
+		c.sources = append(c.sources, file.String())
+		c.files[file.String()] = true
+	}
+	c.sources = append(c.sources, config.URLs...)
+
+	for _, source := range c.sources {
+		err := c.load(source)
+		if err != nil && c.files[source] {
+			return nil, err
+		}
+		if err != nil {
+			log.Warnf("Unable to load the CRL from %s: %v", source, err)
+		}
+	}
+
+	return c, nil
+}
+
+// Refresh reloads the CRLs periodically, until the context is done.
+// A CRL failing to reload is kept until the next refresh.
+func (c *CRLChecker) Refresh(ctx context.Context) {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, source := range c.sources {
+				if err := c.load(source); err != nil {
+					log.Warnf("Unable to refresh the CRL from %s: %v", source, err)
+				}
+			}
+		}
+	}
+}
+
+// VerifyPeerCertificate rejects the client certificates, and their intermediates, revoked by the CRL of their issuer.
+// It is meant to be used as the VerifyPeerCertificate function of a TLS configuration verifying the client certificates.
+func (c *CRLChecker) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	now := c.now()
+
+	for _, chain := range verifiedChains {
+		for i := 0; i < len(chain)-1; i++ {
+			if err := c.check(chain[i], chain[i+1], now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *CRLChecker) check(cert, issuer *x509.Certificate, now time.Time) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var found, current bool
+	for _, list := range c.lists {
+		if !bytes.Equal(list.rawIssuer, issuer.RawSubject) || issuer.CheckCRLSignature(list.list) != nil {
+			continue
+		}
+
+		found = true
+		if _, ok := list.revoked[cert.SerialNumber.String()]; ok {
+			return fmt.Errorf("the certificate %q with the serial number %s is revoked", cert.Subject.CommonName, cert.SerialNumber)
+		}
+
+		if list.list.TBSCertList.NextUpdate.IsZero() || now.Before(list.list.TBSCertList.NextUpdate) {
+			current = true
+		}
+	}
+
+	// Without any CRL of the issuer, while all the CRLs are loaded, the issuer doesn't revoke certificates.
+	if current || (!found && len(c.lists) == len(c.sources)) {
+		return nil
+	}
+
+	if c.failOpen {
+		log.Warnf("No current CRL for the issuer %q of the certificate %q, accepting it", issuer.Subject.CommonName, cert.Subject.CommonName)
+		return nil
+	}
+
+	log.Warnf("No current CRL for the issuer %q of the certificate %q, rejecting it", issuer.Subject.CommonName, cert.Subject.CommonName)
+	return fmt.Errorf("no current CRL for the issuer %q of the certificate %q", issuer.Subject.CommonName, cert.Subject.CommonName)
+}
+
+func (c *CRLChecker) load(source string) error {
+	var data []byte
+	var err error
+	if c.files[source] {
+		data, err = FileOrContent(source).Read()
+	} else {
+		data, err = c.fetch(source)
+	}
+	if err != nil {
+		return err
+	}
+
+	list, err := parseRevocationList(data)
+	if err != nil {
+		return err
+	}
+
+	if !list.list.TBSCertList.NextUpdate.IsZero() && !c.now().Before(list.list.TBSCertList.NextUpdate) {
+		log.Warnf("The CRL from %s is expired since %s", source, list.list.TBSCertList.NextUpdate)
+	}
+
+	c.lock.Lock()
+	c.lists[source] = list
+	c.lock.Unlock()
+
+	return nil
+}
+
+func (c *CRLChecker) fetch(url string) ([]byte, error) {
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, crlMaxBytes))
+}
+
+// parseRevocationList parses a CRL, PEM or DER encoded.
+func parseRevocationList(data []byte) (*revocationList, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("unexpected PEM block %q, expected X509 CRL", block.Type)
+		}
+		data = block.Bytes
+	}
+
+	list, err := x509.ParseDERCRL(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CRL: %v", err)
+	}
+
+	var tbsCertList tbsCertListIssuer
+	if _, err := asn1.Unmarshal(list.TBSCertList.Raw, &tbsCertList); err != nil {
+		return nil, fmt.Errorf("invalid CRL issuer: %v", err)
+	}
+
+	revoked := make(map[string]struct{}, len(list.TBSCertList.RevokedCertificates))
+	for _, entry := range list.TBSCertList.RevokedCertificates {
+		revoked[entry.SerialNumber.String()] = struct{}{}
+	}
+
+	return &revocationList{list: list, rawIssuer: tbsCertList.Issuer.FullBytes, revoked: revoked}, nil
+}
@@ -0,0 +1,225 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// crl returns the PEM encoded CRL of the CA, revoking the serial numbers, and valid until the next update.
+func (ca *testCA) crl(t *testing.T, nextUpdate time.Time, serials ...int64) string {
+	t.Helper()
+
+	var revoked []pkix.RevokedCertificate
+	for _, serial := range serials {
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Hour),
+		})
+	}
+
+	der, err := ca.cert.CreateCRL(rand.Reader, ca.key, revoked, nextUpdate.Add(-48*time.Hour), nextUpdate)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}))
+}
+
+func TestCRLChecker(t *testing.T) {
+	ca := newTestCA(t, "CA")
+	otherCA := newTestCA(t, "Other CA")
+
+	current := ca.crl(t, time.Now().Add(24*time.Hour), 3)
+	expired := ca.crl(t, time.Now().Add(-time.Hour), 3)
+
+	testCases := []struct {
+		desc        string
+		config      *CRL
+		ca          *testCA
+		serial      int64
+		expectedErr bool
+	}{
+		{
+			desc:   "not revoked",
+			config: &CRL{Files: FilesOrContents{FileOrContent(current)}},
+			ca:     ca,
+			serial: 2,
+		},
+		{
+			desc:        "revoked",
+			config:      &CRL{Files: FilesOrContents{FileOrContent(current)}},
+			ca:          ca,
+			serial:      3,
+			expectedErr: true,
+		},
+		{
+			desc:   "issuer without CRL",
+			config: &CRL{Files: FilesOrContents{FileOrContent(current)}},
+			ca:     otherCA,
+			serial: 3,
+		},
+		{
+			desc:        "expired CRL",
+			config:      &CRL{Files: FilesOrContents{FileOrContent(expired)}},
+			ca:          ca,
+			serial:      2,
+			expectedErr: true,
+		},
+		{
+			desc:   "expired CRL with fail open",
+			config: &CRL{Files: FilesOrContents{FileOrContent(expired)}, FailOpen: true},
+			ca:     ca,
+			serial: 2,
+		},
+		{
+			desc:        "revoked by an expired CRL with fail open",
+			config:      &CRL{Files: FilesOrContents{FileOrContent(expired)}, FailOpen: true},
+			ca:          ca,
+			serial:      3,
+			expectedErr: true,
+		},
+		{
+			desc:        "unreachable distribution point",
+			config:      &CRL{URLs: []string{"http://127.0.0.1:0/ca.crl"}},
+			ca:          otherCA,
+			serial:      2,
+			expectedErr: true,
+		},
+		{
+			desc:   "unreachable distribution point with fail open",
+			config: &CRL{URLs: []string{"http://127.0.0.1:0/ca.crl"}, FailOpen: true},
+			ca:     otherCA,
+			serial: 2,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			checker, err := NewCRLChecker(test.config)
+			require.NoError(t, err)
+
+			chain := []*x509.Certificate{test.ca.issue(t, test.serial), test.ca.cert}
+
+			err = checker.VerifyPeerCertificate(nil, [][]*x509.Certificate{chain})
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCRLCheckerDistributionPoint(t *testing.T) {
+	ca := newTestCA(t, "CA")
+
+	var crl atomic.Value
+	crl.Store(ca.crl(t, time.Now().Add(24*time.Hour)))
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(crl.Load().(string)))
+	}))
+	defer server.Close()
+
+	checker, err := NewCRLChecker(&CRL{URLs: []string{server.URL}})
+	require.NoError(t, err)
+
+	chain := []*x509.Certificate{ca.issue(t, 3), ca.cert}
+	require.NoError(t, checker.VerifyPeerCertificate(nil, [][]*x509.Certificate{chain}))
+
+	// The refreshed CRL revokes the certificate.
+	crl.Store(ca.crl(t, time.Now().Add(24*time.Hour), 3))
+	require.NoError(t, checker.load(server.URL))
+
+	assert.Error(t, checker.VerifyPeerCertificate(nil, [][]*x509.Certificate{chain}))
+}
+
+func TestNewCRLCheckerFail(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config *CRL
+	}{
+		{
+			desc:   "no CRL",
+			config: &CRL{},
+		},
+		{
+			desc:   "invalid CRL file",
+			config: &CRL{Files: FilesOrContents{"not a CRL"}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewCRLChecker(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
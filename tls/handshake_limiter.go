@@ -0,0 +1,214 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/log"
+	gokitmetrics "github.com/go-kit/kit/metrics"
+	"golang.org/x/time/rate"
+)
+
+const defaultHandshakesQueueTimeout = time.Second
+
+var errHandshakeLimited = errors.New("too many TLS handshakes")
+
+// Handshakes defines the limits of the TLS handshakes of an entry point.
+// The handshakes beyond the limits wait up to QueueTimeout (1s by default), before their connection is closed.
+// MaxConcurrent defaults to 8 handshakes per CPU, and Rate (per second) is unlimited by default.
+type Handshakes struct {
+	MaxConcurrent int            `description:"Maximum number of simultaneous TLS handshakes" export:"true"`
+	Rate          float64        `description:"Maximum number of TLS handshakes per second" export:"true"`
+	Burst         int            `description:"Maximum number of TLS handshakes beyond the rate" export:"true"`
+	QueueTimeout  parse.Duration `description:"Maximum time a TLS handshake waits for the limits before its connection is closed" export:"true"`
+}
+
+// HandshakeLimiter limits the rate and the concurrency of the TLS handshakes of an entry point.
+// A handshake holds its slot from the reception of its ClientHello, until the server sends its response to it,
+// which is when the costly cryptographic operations of the server are done.
+type HandshakeLimiter struct {
+	entryPointName string
+	slots          chan struct{}
+	limiter        *rate.Limiter
+	queueTimeout   time.Duration
+	limited        gokitmetrics.Counter
+}
+
+// NewHandshakeLimiter creates a new HandshakeLimiter.
+// The counter of the queued and rejected handshakes, labelled with the entry point and the result, is optional.
+func NewHandshakeLimiter(entryPointName string, config *Handshakes, limited gokitmetrics.Counter) (*HandshakeLimiter, error) {
+	if config.MaxConcurrent < 0 || config.Rate < 0 || config.Burst < 0 || config.QueueTimeout < 0 {
+		return nil, errors.New("the TLS handshake limits must be positive")
+	}
+
+	maxConcurrent := config.MaxConcurrent
+	if maxConcurrent == 0 {
+		maxConcurrent = 8 * runtime.NumCPU()
+	}
+
+	h := &HandshakeLimiter{
+		entryPointName: entryPointName,
+		slots:          make(chan struct{}, maxConcurrent),
+		queueTimeout:   time.Duration(config.QueueTimeout),
+		limited:        limited,
+	}
+
+	if h.queueTimeout == 0 {
+		h.queueTimeout = defaultHandshakesQueueTimeout
+	}
+
+	if config.Rate > 0 {
+		burst := config.Burst
+		if burst == 0 {
+			burst = int(config.Rate)
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		h.limiter = rate.NewLimiter(rate.Limit(config.Rate), burst)
+	}
+
+	return h, nil
+}
+
+// Listener wraps the listener of the entry point, so that the handshakes of its connections release their slot.
+func (h *HandshakeLimiter) Listener(listener net.Listener) net.Listener {
+	return &handshakeListener{Listener: listener}
+}
+
+// Apply makes the handshakes of the TLS configuration wait for the limits.
+// The configuration must be served on a listener wrapped with Listener.
+func (h *HandshakeLimiter) Apply(config *tls.Config) {
+	getConfigForClient := config.GetConfigForClient
+
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if err := h.acquire(hello); err != nil {
+			return nil, err
+		}
+
+		if getConfigForClient != nil {
+			return getConfigForClient(hello)
+		}
+		return nil, nil
+	}
+}
+
+// acquire waits for the rate and for a slot, and gives the slot to the connection of the handshake.
+func (h *HandshakeLimiter) acquire(hello *tls.ClientHelloInfo) error {
+	// The handshakes have no context before Go 1.17, the queue timeout bounds the wait.
+	ctx, cancel := context.WithTimeout(context.Background(), h.queueTimeout)
+	defer cancel()
+
+	queued := false
+
+	if h.limiter != nil && !h.limiter.Allow() {
+		queued = true
+		h.count("queued")
+
+		// Wait fails right away when the rate can't be honored within the queue timeout.
+		if err := h.limiter.Wait(ctx); err != nil {
+			return h.reject(hello)
+		}
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	default:
+		if !queued {
+			h.count("queued")
+		}
+
+		select {
+		case h.slots <- struct{}{}:
+		case <-ctx.Done():
+			return h.reject(hello)
+		}
+	}
+
+	if conn, ok := hello.Conn.(*handshakeConn); ok {
+		conn.hold(h.release)
+	} else {
+		h.release()
+	}
+
+	return nil
+}
+
+func (h *HandshakeLimiter) release() {
+	<-h.slots
+}
+
+func (h *HandshakeLimiter) reject(hello *tls.ClientHelloInfo) error {
+	h.count("rejected")
+	log.Debugf("Rejecting the TLS handshake of %s on entry point %s: %v", hello.Conn.RemoteAddr(), h.entryPointName, errHandshakeLimited)
+	return fmt.Errorf("%v on entry point %s", errHandshakeLimited, h.entryPointName)
+}
+
+func (h *HandshakeLimiter) count(result string) {
+	if h.limited != nil {
+		h.limited.With("entrypoint", h.entryPointName, "result", result).Add(1)
+	}
+}
+
+type handshakeListener struct {
+	net.Listener
+}
+
+func (l *handshakeListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &handshakeConn{Conn: conn}, nil
+}
+
+// handshakeConn releases the handshake slot it holds when it is first written to, or closed.
+type handshakeConn struct {
+	net.Conn
+
+	holding int32
+	lock    sync.Mutex
+	release func()
+}
+
+func (c *handshakeConn) hold(release func()) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.release = release
+	atomic.StoreInt32(&c.holding, 1)
+}
+
+func (c *handshakeConn) releaseSlot() {
+	if atomic.LoadInt32(&c.holding) == 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.release != nil {
+		c.release()
+		c.release = nil
+		atomic.StoreInt32(&c.holding, 0)
+	}
+}
+
+func (c *handshakeConn) Write(b []byte) (int, error) {
+	c.releaseSlot()
+	return c.Conn.Write(b)
+}
+
+func (c *handshakeConn) Close() error {
+	c.releaseSlot()
+	return c.Conn.Close()
+}
@@ -0,0 +1,185 @@
+package tls
+
+import (
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// labelledCounter records the counts by label values.
+type labelledCounter struct {
+	counts map[string]float64
+	labels string
+}
+
+func newLabelledCounter() labelledCounter {
+	return labelledCounter{counts: make(map[string]float64)}
+}
+
+func (c labelledCounter) With(labelValues ...string) metrics.Counter {
+	return labelledCounter{counts: c.counts, labels: strings.Join(labelValues, ",")}
+}
+
+func (c labelledCounter) Add(delta float64) {
+	c.counts[c.labels] += delta
+}
+
+// limitedHandshake makes a handshake with a server limited by the handshake limiter, and returns the error of the client.
+func limitedHandshake(t *testing.T, limiter *HandshakeLimiter, cert tls.Certificate) error {
+	t.Helper()
+
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+	limiter.Apply(serverConfig)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn := tls.Server(&handshakeConn{Conn: serverConn}, serverConfig)
+		conn.Handshake()
+		conn.Close()
+	}()
+
+	err := tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true, ServerName: "localhost"}).Handshake()
+	clientConn.Close()
+	<-done
+
+	return err
+}
+
+func TestHandshakeLimiterConcurrency(t *testing.T) {
+	cert := newSessionTicketsCertificate(t)
+	limited := newLabelledCounter()
+
+	limiter, err := NewHandshakeLimiter("https", &Handshakes{MaxConcurrent: 1, QueueTimeout: parse.Duration(50 * time.Millisecond)}, limited)
+	require.NoError(t, err)
+
+	require.NoError(t, limitedHandshake(t, limiter, cert))
+	assert.Len(t, limiter.slots, 0, "the slot must be released after the handshake")
+
+	// The handshake waits for the slot held by another handshake, then its connection is closed.
+	limiter.slots <- struct{}{}
+	assert.Error(t, limitedHandshake(t, limiter, cert))
+	assert.Equal(t, map[string]float64{
+		"entrypoint,https,result,queued":   1,
+		"entrypoint,https,result,rejected": 1,
+	}, limited.counts)
+
+	// The handshake gets the slot released meanwhile.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		limiter.release()
+	}()
+	assert.NoError(t, limitedHandshake(t, limiter, cert))
+	assert.Equal(t, float64(2), limited.counts["entrypoint,https,result,queued"])
+	assert.Len(t, limiter.slots, 0)
+}
+
+func TestHandshakeLimiterRate(t *testing.T) {
+	cert := newSessionTicketsCertificate(t)
+	limited := newLabelledCounter()
+
+	limiter, err := NewHandshakeLimiter("https", &Handshakes{Rate: 1, QueueTimeout: parse.Duration(10 * time.Millisecond)}, limited)
+	require.NoError(t, err)
+
+	require.NoError(t, limitedHandshake(t, limiter, cert))
+
+	// The next handshake can't be allowed within the queue timeout.
+	assert.Error(t, limitedHandshake(t, limiter, cert))
+	assert.Equal(t, map[string]float64{
+		"entrypoint,https,result,queued":   1,
+		"entrypoint,https,result,rejected": 1,
+	}, limited.counts)
+	assert.Len(t, limiter.slots, 0)
+}
+
+func TestHandshakeConnRelease(t *testing.T) {
+	released := 0
+	release := func() { released++ }
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	conn := &handshakeConn{Conn: serverConn}
+	conn.hold(release)
+
+	go clientConn.Read(make([]byte, 1))
+	_, err := conn.Write([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, released)
+
+	// The slot is only released once.
+	require.NoError(t, conn.Close())
+	assert.Equal(t, 1, released)
+}
+
+func TestNewHandshakeLimiter(t *testing.T) {
+	testCases := []struct {
+		desc                 string
+		config               *Handshakes
+		expectedQueueTimeout time.Duration
+		expectedBurst        int
+		expectError          bool
+	}{
+		{
+			desc:                 "defaults",
+			config:               &Handshakes{},
+			expectedQueueTimeout: defaultHandshakesQueueTimeout,
+		},
+		{
+			desc:                 "rate with default burst",
+			config:               &Handshakes{Rate: 50, QueueTimeout: parse.Duration(time.Minute)},
+			expectedQueueTimeout: time.Minute,
+			expectedBurst:        50,
+		},
+		{
+			desc:                 "rate below one per second",
+			config:               &Handshakes{Rate: 0.5},
+			expectedQueueTimeout: defaultHandshakesQueueTimeout,
+			expectedBurst:        1,
+		},
+		{
+			desc:        "negative maximum",
+			config:      &Handshakes{MaxConcurrent: -1},
+			expectError: true,
+		},
+		{
+			desc:        "negative rate",
+			config:      &Handshakes{Rate: -1},
+			expectError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			limiter, err := NewHandshakeLimiter("https", test.config, nil)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, cap(limiter.slots) > 0)
+			assert.Equal(t, test.expectedQueueTimeout, limiter.queueTimeout)
+
+			if test.expectedBurst == 0 {
+				assert.Nil(t, limiter.limiter)
+			} else {
+				require.NotNil(t, limiter.limiter)
+				assert.Equal(t, test.expectedBurst, limiter.limiter.Burst())
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// versionTLS13 is tls.VersionTLS13, which is not defined before Go 1.12.
+const versionTLS13 = 0x0304
+
+// HandshakeMetrics counts the TLS handshakes of the connections of an entry point,
+// from the connection state hook of its server, as the handshakes are made by the server.
+type HandshakeMetrics struct {
+	entryPointName string
+	handshakes     gokitmetrics.Counter
+	errors         gokitmetrics.Counter
+
+	lock sync.Mutex
+	// counted holds the open connections whose handshake has been counted.
+	counted map[net.Conn]struct{}
+}
+
+// NewHandshakeMetrics creates a new HandshakeMetrics.
+// The handshakes are labelled with the entry point, the TLS version and whether the session was resumed,
+// and the handshake errors with the entry point.
+func NewHandshakeMetrics(entryPointName string, handshakes gokitmetrics.Counter, errors gokitmetrics.Counter) *HandshakeMetrics {
+	return &HandshakeMetrics{
+		entryPointName: entryPointName,
+		handshakes:     handshakes,
+		errors:         errors,
+		counted:        make(map[net.Conn]struct{}),
+	}
+}
+
+// ConnState counts the handshake of a TLS connection once it is completed, that is when the connection is first active or closed.
+// The connections closed before completing their handshake are counted as handshake errors.
+// The hijacked connections, e.g. the websockets, are not tracked by the server any more, their state is the last one.
+func (m *HandshakeMetrics) ConnState(conn net.Conn, state http.ConnState) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok || state == http.StateNew {
+		return
+	}
+
+	last := state == http.StateClosed || state == http.StateHijacked
+
+	m.lock.Lock()
+	_, counted := m.counted[conn]
+	if last {
+		delete(m.counted, conn)
+	}
+	m.lock.Unlock()
+
+	if counted {
+		return
+	}
+
+	connState := tlsConn.ConnectionState()
+	if !connState.HandshakeComplete {
+		if state == http.StateClosed {
+			m.errors.With("entrypoint", m.entryPointName).Add(1)
+		}
+		return
+	}
+
+	if !last {
+		m.lock.Lock()
+		m.counted[conn] = struct{}{}
+		m.lock.Unlock()
+	}
+
+	m.handshakes.With("entrypoint", m.entryPointName, "tls_version", versionName(connState.Version), "resumed", strconv.FormatBool(connState.DidResume)).Add(1)
+}
+
+func versionName(version uint16) string {
+	switch version {
+	case tls.VersionSSL30:
+		return "SSL3.0"
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case versionTLS13:
+		return "1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
+
+// SetCertificatesExpiry sets the gauge, labelled with the entry point and the domain of the certificates,
+// to the number of seconds until each certificate expires, and returns the domains of the certificates.
+// The domain of a certificate is its first DNS name, or its common name.
+func SetCertificatesExpiry(gauge gokitmetrics.Gauge, entryPointName string, certs []*tls.Certificate, now time.Time) []string {
+	var domains []string
+	for _, cert := range certs {
+		leaf, err := certificateLeaf(cert)
+		if err != nil {
+			continue
+		}
+
+		domain := leaf.Subject.CommonName
+		if len(leaf.DNSNames) > 0 {
+			domain = leaf.DNSNames[0]
+		}
+
+		gauge.With("entrypoint", entryPointName, "domain", domain).Set(leaf.NotAfter.Sub(now).Seconds())
+		domains = append(domains, domain)
+	}
+
+	return domains
+}
+
+func certificateLeaf(cert *tls.Certificate) (*x509.Certificate, error) {
+	if cert.Leaf != nil {
+		return cert.Leaf, nil
+	}
+
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("empty certificate")
+	}
+
+	return x509.ParseCertificate(cert.Certificate[0])
+}
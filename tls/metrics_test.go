@@ -0,0 +1,144 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// labelledGauge records the values by label values.
+type labelledGauge struct {
+	values map[string]float64
+	labels string
+}
+
+func newLabelledGauge() labelledGauge {
+	return labelledGauge{values: make(map[string]float64)}
+}
+
+func (g labelledGauge) With(labelValues ...string) metrics.Gauge {
+	return labelledGauge{values: g.values, labels: strings.Join(labelValues, ",")}
+}
+
+func (g labelledGauge) Set(value float64) {
+	g.values[g.labels] = value
+}
+
+func (g labelledGauge) Add(delta float64) {
+	g.values[g.labels] += delta
+}
+
+// serverHandshake makes a handshake between a client and a server, and returns the connection of the server.
+func serverHandshake(t *testing.T, serverConfig *tls.Config, clientConfig *tls.Config) *tls.Conn {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	conn := tls.Server(serverConn, serverConfig)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.Handshake()
+	}()
+
+	tls.Client(clientConn, clientConfig).Handshake()
+	clientConn.Close()
+	<-done
+
+	return conn
+}
+
+func TestHandshakeMetrics(t *testing.T) {
+	handshakes := newLabelledCounter()
+	errors := newLabelledCounter()
+	handshakeMetrics := NewHandshakeMetrics("https", handshakes, errors)
+
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{newSessionTicketsCertificate(t)}, MaxVersion: tls.VersionTLS12}
+	clientConfig := &tls.Config{InsecureSkipVerify: true, ServerName: "localhost", ClientSessionCache: tls.NewLRUClientSessionCache(1)}
+
+	// The handshake is counted once, when the connection is first active.
+	conn := serverHandshake(t, serverConfig, clientConfig)
+	for _, state := range []http.ConnState{http.StateNew, http.StateActive, http.StateIdle, http.StateActive, http.StateClosed} {
+		handshakeMetrics.ConnState(conn, state)
+	}
+
+	// The handshake of a connection closed without request is counted when it is closed.
+	conn = serverHandshake(t, serverConfig, clientConfig)
+	handshakeMetrics.ConnState(conn, http.StateNew)
+	handshakeMetrics.ConnState(conn, http.StateClosed)
+
+	// The handshake of a hijacked connection, e.g. a websocket, is counted once, and the connection forgotten.
+	conn = serverHandshake(t, serverConfig, clientConfig)
+	for _, state := range []http.ConnState{http.StateNew, http.StateActive, http.StateHijacked} {
+		handshakeMetrics.ConnState(conn, state)
+	}
+
+	// The connections closed before completing their handshake are errors.
+	conn = serverHandshake(t, serverConfig, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_RSA_WITH_RC4_128_SHA}})
+	handshakeMetrics.ConnState(conn, http.StateNew)
+	handshakeMetrics.ConnState(conn, http.StateClosed)
+
+	// The connections without TLS are ignored.
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	handshakeMetrics.ConnState(serverConn, http.StateActive)
+	handshakeMetrics.ConnState(serverConn, http.StateClosed)
+
+	assert.Equal(t, map[string]float64{
+		"entrypoint,https,tls_version,1.2,resumed,false": 1,
+		"entrypoint,https,tls_version,1.2,resumed,true":  2,
+	}, handshakes.counts)
+	assert.Equal(t, map[string]float64{"entrypoint,https": 1}, errors.counts)
+	assert.Empty(t, handshakeMetrics.counted, "the closed and hijacked connections must be forgotten")
+}
+
+func newExpiringCertificate(t *testing.T, commonName string, dnsNames []string, notAfter time.Time) *tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestSetCertificatesExpiry(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	certs := []*tls.Certificate{
+		newExpiringCertificate(t, "example.com", []string{"www.example.com", "example.com"}, now.Add(time.Hour)),
+		newExpiringCertificate(t, "example.org", nil, now.Add(-time.Minute)),
+		{},
+	}
+
+	gauge := newLabelledGauge()
+	domains := SetCertificatesExpiry(gauge, "https", certs, now)
+
+	assert.Equal(t, []string{"www.example.com", "example.org"}, domains)
+	assert.Equal(t, map[string]float64{
+		"entrypoint,https,domain,www.example.com": 3600,
+		"entrypoint,https,domain,example.org":     -60,
+	}, gauge.values)
+}
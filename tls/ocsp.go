@@ -0,0 +1,235 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	ocspMinBackoff       = time.Minute
+	ocspMaxBackoff       = time.Hour
+	ocspEntryTTL         = 24 * time.Hour
+	ocspMaxResponseBytes = 1024 * 1024
+	ocspRequestTimeout   = 10 * time.Second
+)
+
+// errOCSPUnsupported is returned for the certificates without an OCSP responder or without their issuer in the chain.
+var errOCSPUnsupported = errors.New("no OCSP responder or issuer for the certificate")
+
+// OCSPStapler staples the OCSP responses of the certificates in the TLS handshakes.
+// The responses are fetched from the responder of the certificate issuer in the background, cached,
+// and refreshed halfway to their next update, so that a handshake never waits for, nor fails because of, the responder:
+// the certificates are served without a staple until a response is fetched,
+// and the fetch failures are retried with an exponential backoff, the cached response being stapled until its next update.
+type OCSPStapler struct {
+	client *http.Client
+	now    func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*ocspEntry
+}
+
+// ocspEntry holds the OCSP response of a certificate.
+type ocspEntry struct {
+	staple     []byte
+	nextUpdate time.Time
+	refreshAt  time.Time
+	failures   int
+	fetching   bool
+	lastUsed   time.Time
+}
+
+// NewOCSPStapler creates a new OCSPStapler.
+func NewOCSPStapler() *OCSPStapler {
+	return &OCSPStapler{
+		client:  &http.Client{Timeout: ocspRequestTimeout},
+		now:     time.Now,
+		entries: make(map[string]*ocspEntry),
+	}
+}
+
+// GetCertificateFunc returns a GetCertificate function stapling the OCSP responses to the certificates returned by getCertificate.
+func (o *OCSPStapler) GetCertificateFunc(getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := getCertificate(clientHello)
+		if err != nil || cert == nil || len(cert.OCSPStaple) > 0 {
+			return cert, err
+		}
+
+		return o.staple(cert), nil
+	}
+}
+
+// staple returns a copy of the certificate with its cached OCSP response, if any, and starts a refresh when it is due.
+func (o *OCSPStapler) staple(cert *tls.Certificate) *tls.Certificate {
+	if len(cert.Certificate) == 0 {
+		return cert
+	}
+
+	key := fmt.Sprintf("%x", sha256.Sum256(cert.Certificate[0]))
+	now := o.now()
+
+	o.lock.Lock()
+	entry, ok := o.entries[key]
+	if !ok {
+		o.sweep(now)
+		entry = &ocspEntry{}
+		o.entries[key] = entry
+	}
+	entry.lastUsed = now
+
+	if !entry.fetching && !now.Before(entry.refreshAt) {
+		entry.fetching = true
+		safe.Go(func() {
+			o.refresh(key, cert)
+		})
+	}
+
+	staple := entry.staple
+	if !entry.nextUpdate.IsZero() && !now.Before(entry.nextUpdate) {
+		staple = nil
+	}
+	o.lock.Unlock()
+
+	if len(staple) == 0 {
+		return cert
+	}
+
+	stapled := *cert
+	stapled.OCSPStaple = staple
+	return &stapled
+}
+
+// sweep removes the entries of the certificates not served for a while, e.g. replaced by a renewal.
+func (o *OCSPStapler) sweep(now time.Time) {
+	for key, entry := range o.entries {
+		if !entry.fetching && now.Sub(entry.lastUsed) > ocspEntryTTL {
+			delete(o.entries, key)
+		}
+	}
+}
+
+func (o *OCSPStapler) refresh(key string, cert *tls.Certificate) {
+	staple, resp, err := o.fetch(cert)
+	now := o.now()
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	entry, ok := o.entries[key]
+	if !ok {
+		return
+	}
+	entry.fetching = false
+
+	if err == errOCSPUnsupported {
+		entry.refreshAt = now.Add(ocspEntryTTL)
+		return
+	}
+
+	if err == nil && !resp.NextUpdate.IsZero() && !now.Before(resp.NextUpdate) {
+		err = fmt.Errorf("expired OCSP response, next update was at %s", resp.NextUpdate)
+	}
+
+	if err != nil {
+		backoff := ocspMinBackoff
+		for i := 0; i < entry.failures && backoff < ocspMaxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > ocspMaxBackoff {
+			backoff = ocspMaxBackoff
+		}
+
+		entry.failures++
+		entry.refreshAt = now.Add(backoff)
+		log.Warnf("Unable to get the OCSP response, retrying in %s: %v", backoff, err)
+		return
+	}
+
+	if resp.Status == ocsp.Revoked {
+		log.Warnf("The certificate with the serial number %s is revoked, as of %s", resp.SerialNumber, resp.RevokedAt)
+	}
+
+	entry.failures = 0
+	entry.staple = staple
+	entry.nextUpdate = resp.NextUpdate
+	entry.refreshAt = now.Add(ocspMaxBackoff)
+	if !resp.NextUpdate.IsZero() {
+		entry.refreshAt = resp.ThisUpdate.Add(resp.NextUpdate.Sub(resp.ThisUpdate) / 2)
+	}
+}
+
+// fetch gets the OCSP response of the certificate from the responder of its issuer, the second certificate of the chain.
+func (o *OCSPStapler) fetch(cert *tls.Certificate) ([]byte, *ocsp.Response, error) {
+	if len(cert.Certificate) < 2 {
+		return nil, nil, errOCSPUnsupported
+	}
+
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(leaf.OCSPServer) == 0 {
+		return nil, nil, errOCSPUnsupported
+	}
+
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ocspReq, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(ocspReq))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status code %d from the OCSP responder %s", res.StatusCode, leaf.OCSPServer[0])
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, ocspMaxResponseBytes))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.Status == ocsp.Unknown {
+		return nil, nil, fmt.Errorf("unknown OCSP status of the certificate with the serial number %s", leaf.SerialNumber)
+	}
+
+	return body, resp, nil
+}
@@ -0,0 +1,231 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+type ocspResponder struct {
+	issuer *x509.Certificate
+	key    *ecdsa.PrivateKey
+
+	lock     sync.Mutex
+	status   int
+	requests int
+}
+
+func (r *ocspResponder) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.requests++
+	if r.status != http.StatusOK {
+		rw.WriteHeader(r.status)
+		return
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ocspReq, err := ocsp.ParseRequest(body)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	resp, err := ocsp.CreateResponse(r.issuer, r.issuer, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: ocspReq.SerialNumber,
+		ThisUpdate:   now.Add(-time.Hour),
+		NextUpdate:   now.Add(47 * time.Hour),
+	}, r.key)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.Write(resp)
+}
+
+func (r *ocspResponder) setStatus(status int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.status = status
+}
+
+func (r *ocspResponder) getRequests() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.requests
+}
+
+// createOCSPCertificate creates a certificate, with its issuer in the chain, and returns the issuer and its key.
+func createOCSPCertificate(t *testing.T, ocspServer string) (*tls.Certificate, *x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	if len(ocspServer) > 0 {
+		template.OCSPServer = []string{ocspServer}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	cert := &tls.Certificate{
+		Certificate: [][]byte{der, caDER},
+		PrivateKey:  key,
+	}
+
+	return cert, ca, caKey
+}
+
+func waitOCSPFetch(t *testing.T, stapler *OCSPStapler) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		stapler.lock.Lock()
+		fetching := false
+		for _, entry := range stapler.entries {
+			fetching = fetching || entry.fetching
+		}
+		stapler.lock.Unlock()
+
+		if !fetching {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("the OCSP response was not fetched")
+}
+
+func TestOCSPStapler(t *testing.T) {
+	responder := &ocspResponder{status: http.StatusOK}
+	server := httptest.NewServer(responder)
+	defer server.Close()
+
+	cert, issuer, key := createOCSPCertificate(t, server.URL)
+	responder.issuer = issuer
+	responder.key = key
+
+	now := time.Now()
+	stapler := NewOCSPStapler()
+	stapler.now = func() time.Time { return now }
+
+	getCertificate := stapler.GetCertificateFunc(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return cert, nil
+	})
+
+	// The first handshake doesn't wait for the responder.
+	served, err := getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Empty(t, served.OCSPStaple)
+
+	waitOCSPFetch(t, stapler)
+	assert.Equal(t, 1, responder.getRequests())
+
+	served, err = getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	require.NotEmpty(t, served.OCSPStaple)
+	assert.Empty(t, cert.OCSPStaple, "the certificate should not be modified")
+
+	resp, err := ocsp.ParseResponse(served.OCSPStaple, issuer)
+	require.NoError(t, err)
+	assert.Equal(t, ocsp.Good, resp.Status)
+
+	// The response is cached until halfway to its next update.
+	served, err = getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, served.OCSPStaple)
+	waitOCSPFetch(t, stapler)
+	assert.Equal(t, 1, responder.getRequests())
+
+	// Then, the failed refreshes keep the cached response, and are retried with a backoff.
+	responder.setStatus(http.StatusInternalServerError)
+	now = now.Add(24 * time.Hour)
+
+	served, err = getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, served.OCSPStaple)
+	waitOCSPFetch(t, stapler)
+	assert.Equal(t, 2, responder.getRequests())
+
+	now = now.Add(30 * time.Second)
+	_, err = getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	waitOCSPFetch(t, stapler)
+	assert.Equal(t, 2, responder.getRequests())
+
+	now = now.Add(time.Minute)
+	_, err = getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	waitOCSPFetch(t, stapler)
+	assert.Equal(t, 3, responder.getRequests())
+
+	// Past its next update, the response is not stapled anymore.
+	now = now.Add(24 * time.Hour)
+	served, err = getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Empty(t, served.OCSPStaple)
+	waitOCSPFetch(t, stapler)
+}
+
+func TestOCSPStaplerUnsupported(t *testing.T) {
+	cert, _, _ := createOCSPCertificate(t, "")
+
+	stapler := NewOCSPStapler()
+	getCertificate := stapler.GetCertificateFunc(func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return cert, nil
+	})
+
+	served, err := getCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	waitOCSPFetch(t, stapler)
+
+	assert.Equal(t, cert, served)
+	assert.Empty(t, served.OCSPStaple)
+}
@@ -0,0 +1,72 @@
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	gokitmetrics "github.com/go-kit/kit/metrics"
+)
+
+// PinnedPublicKeys verifies that the certificate of a server has one of the pinned public keys,
+// as the base64 encoded SHA-256 hash of the certificate SubjectPublicKeyInfo (SPKI),
+// so that several keys can be pinned during their rotation.
+type PinnedPublicKeys struct {
+	pins       map[[sha256.Size]byte]bool
+	mismatches gokitmetrics.Counter
+}
+
+// NewPinnedPublicKeys creates a new PinnedPublicKeys.
+// The mismatches counter is optional.
+func NewPinnedPublicKeys(pins []string, mismatches gokitmetrics.Counter) (*PinnedPublicKeys, error) {
+	if len(pins) == 0 {
+		return nil, errors.New("no pinned public keys")
+	}
+
+	p := &PinnedPublicKeys{
+		pins:       make(map[[sha256.Size]byte]bool),
+		mismatches: mismatches,
+	}
+
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid pinned public key %q, must be a base64 encoded SHA-256 hash", pin)
+		}
+
+		var key [sha256.Size]byte
+		copy(key[:], hash)
+		p.pins[key] = true
+	}
+
+	return p, nil
+}
+
+// VerifyPeerCertificate rejects the connections to a server whose certificate doesn't have a pinned public key.
+// It is meant to be used as the VerifyPeerCertificate function of a client TLS configuration,
+// and is called after the verification of the certificate chain, if any.
+func (p *PinnedPublicKeys) VerifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return p.mismatch(errors.New("public key pinning failed: no server certificate"))
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return p.mismatch(fmt.Errorf("public key pinning failed: invalid server certificate: %v", err))
+	}
+
+	if p.pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+		return nil
+	}
+
+	return p.mismatch(fmt.Errorf("public key pinning failed: the public key of the certificate %q is not pinned", cert.Subject.CommonName))
+}
+
+func (p *PinnedPublicKeys) mismatch(err error) error {
+	if p.mismatches != nil {
+		p.mismatches.Add(1)
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedPublicKeys(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	hash := sha256.Sum256(server.Certificate().RawSubjectPublicKeyInfo)
+	serverPin := base64.StdEncoding.EncodeToString(hash[:])
+
+	otherHash := sha256.Sum256([]byte("other"))
+	otherPin := base64.StdEncoding.EncodeToString(otherHash[:])
+
+	testCases := []struct {
+		desc               string
+		pins               []string
+		expectedErr        bool
+		expectedMismatches float64
+	}{
+		{
+			desc: "pinned",
+			pins: []string{serverPin},
+		},
+		{
+			desc: "pinned during a rotation",
+			pins: []string{otherPin, serverPin},
+		},
+		{
+			desc:               "not pinned",
+			pins:               []string{otherPin},
+			expectedErr:        true,
+			expectedMismatches: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			counter := generic.NewCounter("pin_failures")
+			pinned, err := NewPinnedPublicKeys(test.pins, counter)
+			require.NoError(t, err)
+
+			rootCAs := x509.NewCertPool()
+			rootCAs.AddCert(server.Certificate())
+
+			transport := &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:               rootCAs,
+					VerifyPeerCertificate: pinned.VerifyPeerCertificate,
+				},
+			}
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if test.expectedErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				resp.Body.Close()
+			}
+
+			assert.Equal(t, test.expectedMismatches, counter.Value())
+		})
+	}
+}
+
+func TestNewPinnedPublicKeysFail(t *testing.T) {
+	testCases := []struct {
+		desc string
+		pins []string
+	}{
+		{
+			desc: "no pins",
+		},
+		{
+			desc: "not base64",
+			pins: []string{"not base64!"},
+		},
+		{
+			desc: "not a SHA-256 hash",
+			pins: []string{base64.StdEncoding.EncodeToString([]byte("short"))},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewPinnedPublicKeys(test.pins, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPinnedPublicKeysWithoutCertificate(t *testing.T) {
+	hash := sha256.Sum256([]byte("key"))
+	pinned, err := NewPinnedPublicKeys([]string{base64.StdEncoding.EncodeToString(hash[:])}, nil)
+	require.NoError(t, err)
+
+	assert.Error(t, pinned.VerifyPeerCertificate(nil, nil))
+}
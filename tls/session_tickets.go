@@ -0,0 +1,153 @@
+package tls
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/containous/flaeg/parse"
+	"github.com/containous/traefik/log"
+)
+
+const (
+	defaultSessionTicketRotation = 12 * time.Hour
+	sessionTicketKeysRingSize    = 3
+	sessionTicketKeySize         = 32
+)
+
+// SessionTickets configures the keys encrypting the TLS session tickets, rotated every Rotation (12 hours by default).
+// KeyFile, when set, holds the keys shared by several instances, one base64 encoded 32 bytes key per line, the first one being the current key:
+// the keys are then reloaded from the file, instead of generated, every Rotation.
+type SessionTickets struct {
+	Rotation parse.Duration `export:"true"`
+	KeyFile  string
+}
+
+// SessionTicketKeys rotates the keys encrypting the TLS session tickets of an entry point.
+// The tickets are encrypted with the current key, and decrypted with any key of a small ring of the most recent keys,
+// so that the sessions resumed during a rotation are not broken.
+type SessionTicketKeys struct {
+	rotation time.Duration
+	keyFile  string
+
+	lock    sync.Mutex
+	keys    [][sessionTicketKeySize]byte
+	configs []*tls.Config
+}
+
+// NewSessionTicketKeys creates a new SessionTicketKeys, with a generated key, or with the keys of the key file.
+func NewSessionTicketKeys(config *SessionTickets) (*SessionTicketKeys, error) {
+	if config.Rotation < 0 {
+		return nil, errors.New("the session ticket keys rotation must be positive")
+	}
+
+	k := &SessionTicketKeys{
+		rotation: defaultSessionTicketRotation,
+		keyFile:  config.KeyFile,
+	}
+
+	if config.Rotation > 0 {
+		k.rotation = time.Duration(config.Rotation)
+	}
+
+	if err := k.rotate(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Rotate rotates the keys periodically, until the context is done.
+// Keys failing to reload from the key file are kept until the next rotation.
+func (k *SessionTicketKeys) Rotate(ctx context.Context) {
+	ticker := time.NewTicker(k.rotation)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.rotate(); err != nil {
+				log.Warnf("Unable to rotate the session ticket keys: %v", err)
+			}
+		}
+	}
+}
+
+// Apply makes the TLS configuration encrypt and decrypt its session tickets with the rotated keys.
+// The configuration must be the one served, and not a copy of it, for the rotated keys to be used.
+func (k *SessionTicketKeys) Apply(config *tls.Config) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.configs = append(k.configs, config)
+	config.SetSessionTicketKeys(k.keys)
+}
+
+func (k *SessionTicketKeys) rotate() error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	if len(k.keyFile) > 0 {
+		keys, err := readSessionTicketKeys(k.keyFile)
+		if err != nil {
+			return err
+		}
+		k.keys = keys
+	} else {
+		var key [sessionTicketKeySize]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return fmt.Errorf("unable to generate a session ticket key: %v", err)
+		}
+
+		k.keys = append([][sessionTicketKeySize]byte{key}, k.keys...)
+		if len(k.keys) > sessionTicketKeysRingSize {
+			k.keys = k.keys[:sessionTicketKeysRingSize]
+		}
+	}
+
+	for _, config := range k.configs {
+		config.SetSessionTicketKeys(k.keys)
+	}
+	return nil
+}
+
+func readSessionTicketKeys(keyFile string) ([][sessionTicketKeySize]byte, error) {
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the session ticket keys file %s: %v", keyFile, err)
+	}
+
+	var keys [][sessionTicketKeySize]byte
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil || len(decoded) != sessionTicketKeySize {
+			return nil, fmt.Errorf("invalid session ticket key in %s, must be a base64 encoded %d bytes key", keyFile, sessionTicketKeySize)
+		}
+
+		var key [sessionTicketKeySize]byte
+		copy(key[:], decoded)
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no session ticket keys in %s", keyFile)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,152 @@
+package tls
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resumed makes a handshake with a server using the session ticket keys, and returns true if the session of the client was resumed.
+func resumed(t *testing.T, keys *SessionTicketKeys, cert tls.Certificate, cache tls.ClientSessionCache) bool {
+	t.Helper()
+
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MaxVersion: tls.VersionTLS12}
+	keys.Apply(serverConfig)
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tls.Server(serverConn, serverConfig).Handshake()
+	}()
+
+	client := tls.Client(clientConn, &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         "localhost",
+		ClientSessionCache: cache,
+	})
+	require.NoError(t, client.Handshake())
+	require.NoError(t, <-errCh)
+
+	return client.ConnectionState().DidResume
+}
+
+func newSessionTicketsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	ca := newTestCA(t, "localhost")
+	return tls.Certificate{Certificate: [][]byte{ca.cert.Raw}, PrivateKey: ca.key}
+}
+
+func TestSessionTicketKeysRotation(t *testing.T) {
+	cert := newSessionTicketsCertificate(t)
+
+	keys, err := NewSessionTicketKeys(&SessionTickets{})
+	require.NoError(t, err)
+
+	cache := tls.NewLRUClientSessionCache(1)
+	assert.False(t, resumed(t, keys, cert, cache))
+	assert.True(t, resumed(t, keys, cert, cache))
+
+	// The tickets of the previous keys are still accepted.
+	require.NoError(t, keys.rotate())
+	assert.True(t, resumed(t, keys, cert, cache))
+
+	// The tickets of the keys out of the ring are rejected.
+	for i := 0; i < sessionTicketKeysRingSize; i++ {
+		require.NoError(t, keys.rotate())
+	}
+	assert.False(t, resumed(t, keys, cert, cache))
+}
+
+func TestSessionTicketKeysFile(t *testing.T) {
+	cert := newSessionTicketsCertificate(t)
+
+	dir, err := ioutil.TempDir("", "session-tickets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	newKey := func() string {
+		key := make([]byte, sessionTicketKeySize)
+		_, err := rand.Read(key)
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(key)
+	}
+
+	keyFile := filepath.Join(dir, "tickets.key")
+	current, previous := newKey(), newKey()
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(current+"\n"+previous+"\n"), 0600))
+
+	instance1, err := NewSessionTicketKeys(&SessionTickets{KeyFile: keyFile})
+	require.NoError(t, err)
+	instance2, err := NewSessionTicketKeys(&SessionTickets{KeyFile: keyFile})
+	require.NoError(t, err)
+
+	// The instances sharing the keys resume the sessions of each other.
+	cache := tls.NewLRUClientSessionCache(1)
+	assert.False(t, resumed(t, instance1, cert, cache))
+	assert.True(t, resumed(t, instance2, cert, cache))
+
+	// The tickets of the keys removed from the file are rejected once reloaded.
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(newKey()), 0600))
+	require.NoError(t, instance1.rotate())
+	assert.False(t, resumed(t, instance1, cert, cache))
+
+	// The keys are kept when the file is invalid.
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("not a key"), 0600))
+	assert.Error(t, instance1.rotate())
+	assert.True(t, resumed(t, instance1, cert, cache))
+}
+
+func TestNewSessionTicketKeysFail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session-tickets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	shortKey := filepath.Join(dir, "short.key")
+	require.NoError(t, ioutil.WriteFile(shortKey, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0600))
+
+	emptyFile := filepath.Join(dir, "empty.key")
+	require.NoError(t, ioutil.WriteFile(emptyFile, []byte(strings.Repeat("\n", 2)), 0600))
+
+	testCases := []struct {
+		desc   string
+		config *SessionTickets
+	}{
+		{
+			desc:   "negative rotation",
+			config: &SessionTickets{Rotation: -1},
+		},
+		{
+			desc:   "missing key file",
+			config: &SessionTickets{KeyFile: filepath.Join(dir, "missing.key")},
+		},
+		{
+			desc:   "invalid key",
+			config: &SessionTickets{KeyFile: shortKey},
+		},
+		{
+			desc:   "no keys",
+			config: &SessionTickets{KeyFile: emptyFile},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := NewSessionTicketKeys(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -14,20 +14,66 @@ const (
 )
 
 // ClientCA defines traefik CA files for a entryPoint
-// and it indicates if they are mandatory or have just to be analyzed if provided
+// and it indicates if they are mandatory or have just to be analyzed if provided.
+// ClientAuthType, when set, defines how the client certificates are requested and verified, instead of Optional
+// CRL, when set, rejects the verified client certificates revoked by their issuer.
 type ClientCA struct {
-	Files    FilesOrContents
-	Optional bool
+	Files          FilesOrContents
+	Optional       bool
+	ClientAuthType string
+	CRL            *CRL
+}
+
+// GetClientAuthType returns the client authentication type of the entrypoint:
+// the configured one, or, by default, the verification of the client certificates with the CA files, optional or not.
+func (c *ClientCA) GetClientAuthType() (tls.ClientAuthType, error) {
+	if len(c.ClientAuthType) == 0 {
+		switch {
+		case len(c.Files) == 0:
+			return tls.NoClientCert, nil
+		case c.Optional:
+			return tls.VerifyClientCertIfGiven, nil
+		default:
+			return tls.RequireAndVerifyClientCert, nil
+		}
+	}
+
+	clientAuthType, ok := ClientAuthTypes[c.ClientAuthType]
+	if !ok {
+		return tls.NoClientCert, fmt.Errorf("invalid ClientAuthType: %s", c.ClientAuthType)
+	}
+
+	if len(c.Files) == 0 && (clientAuthType == tls.VerifyClientCertIfGiven || clientAuthType == tls.RequireAndVerifyClientCert) {
+		return tls.NoClientCert, fmt.Errorf("ClientAuthType %s requires the CA files to verify the client certificates", c.ClientAuthType)
+	}
+
+	return clientAuthType, nil
 }
 
 // TLS configures TLS for an entry point
+// The OCSP responses of the certificates are stapled in the handshakes, unless DisableOCSPStapling is set.
+// SessionTickets, when set, rotates the keys of the session tickets.
+// SNIDefaultCertificates are served, instead of DefaultCertificate, to the server names matching their SNI pattern.
+// Handshakes, when set, limits the rate and the concurrency of the handshakes.
 type TLS struct {
-	MinVersion         string `export:"true"`
-	CipherSuites       []string
-	Certificates       Certificates
-	ClientCA           ClientCA
-	DefaultCertificate *Certificate
-	SniStrict          bool `export:"true"`
+	MinVersion             string `export:"true"`
+	CipherSuites           []string
+	Certificates           Certificates
+	ClientCA               ClientCA
+	DefaultCertificate     *Certificate
+	SNIDefaultCertificates []SNIDefaultCertificate
+	SniStrict              bool `export:"true"`
+	DisableOCSPStapling    bool `export:"true"`
+	SessionTickets         *SessionTickets
+	Handshakes             *Handshakes
+}
+
+// SNIDefaultCertificate is a default certificate served to the server names matching its SNI pattern,
+// an exact server name, or a wildcard (*.example.com) matching all the subdomains of a domain.
+type SNIDefaultCertificate struct {
+	SNI      string
+	CertFile FileOrContent
+	KeyFile  FileOrContent
 }
 
 // FilesOrContents hold the CA we want to have in root
@@ -0,0 +1,85 @@
+package tls
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCAGetClientAuthType(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		clientCA ClientCA
+		expected tls.ClientAuthType
+	}{
+		{
+			desc:     "no client CA",
+			expected: tls.NoClientCert,
+		},
+		{
+			desc:     "CA files",
+			clientCA: ClientCA{Files: FilesOrContents{"ca.crt"}},
+			expected: tls.RequireAndVerifyClientCert,
+		},
+		{
+			desc:     "optional CA files",
+			clientCA: ClientCA{Files: FilesOrContents{"ca.crt"}, Optional: true},
+			expected: tls.VerifyClientCertIfGiven,
+		},
+		{
+			desc:     "request without CA files",
+			clientCA: ClientCA{ClientAuthType: "RequestClientCert"},
+			expected: tls.RequestClientCert,
+		},
+		{
+			desc:     "require any without CA files",
+			clientCA: ClientCA{ClientAuthType: "RequireAnyClientCert"},
+			expected: tls.RequireAnyClientCert,
+		},
+		{
+			desc:     "client auth type over optional",
+			clientCA: ClientCA{Files: FilesOrContents{"ca.crt"}, Optional: true, ClientAuthType: "RequireAndVerifyClientCert"},
+			expected: tls.RequireAndVerifyClientCert,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			clientAuthType, err := test.clientCA.GetClientAuthType()
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, clientAuthType)
+		})
+	}
+}
+
+func TestClientCAGetClientAuthTypeFail(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		clientCA ClientCA
+	}{
+		{
+			desc:     "unknown client auth type",
+			clientCA: ClientCA{ClientAuthType: "Foo"},
+		},
+		{
+			desc:     "verification without CA files",
+			clientCA: ClientCA{ClientAuthType: "VerifyClientCertIfGiven"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := test.clientCA.GetClientAuthType()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"strings"
 )
 
-// Domain holds a domain name with SANs
+// Domain holds a domain name with SANs.
+// KeyType optionally overrides the key type of the ACME certificate of the domain.
 type Domain struct {
-	Main string
-	SANs []string
+	Main    string
+	SANs    []string
+	KeyType string `json:",omitempty"`
 }
 
 // ToStrArray convert a domain into an array of strings
@@ -1,7 +1,9 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/containous/flaeg/parse"
@@ -18,17 +20,145 @@ const (
 
 // TraefikLog holds the configuration settings for the traefik logger.
 type TraefikLog struct {
-	FilePath string `json:"file,omitempty" description:"Traefik log file path. Stdout is used when omitted or empty"`
-	Format   string `json:"format,omitempty" description:"Traefik log format: json | common"`
+	FilePath string    `json:"file,omitempty" description:"Traefik log file path. Stdout is used when omitted or empty"`
+	Format   string    `json:"format,omitempty" description:"Traefik log format: json | common"`
+	OTLP     *OTLPLogs `json:"otlp,omitempty" description:"Export the Traefik logs to an OpenTelemetry collector (OTLP/HTTP JSON only, no gRPC)" export:"true"`
 }
 
 // AccessLog holds the configuration settings for the access logger (middlewares/accesslog).
 type AccessLog struct {
-	FilePath      string            `json:"file,omitempty" description:"Access log file path. Stdout is used when omitted or empty" export:"true"`
-	Format        string            `json:"format,omitempty" description:"Access log format: json | common" export:"true"`
-	Filters       *AccessLogFilters `json:"filters,omitempty" description:"Access log filters, used to keep only specific access logs" export:"true"`
-	Fields        *AccessLogFields  `json:"fields,omitempty" description:"AccessLogFields" export:"true"`
-	BufferingSize int64             `json:"bufferingSize,omitempty" description:"Number of access log lines to process in a buffered way. Default 0." export:"true"`
+	FilePath             string               `json:"file,omitempty" description:"Access log file path. Stdout is used when omitted or empty" export:"true"`
+	Format               string               `json:"format,omitempty" description:"Access log format: json | common" export:"true"`
+	Filters              *AccessLogFilters    `json:"filters,omitempty" description:"Access log filters, used to keep only specific access logs" export:"true"`
+	Fields               *AccessLogFields     `json:"fields,omitempty" description:"AccessLogFields" export:"true"`
+	BufferingSize        int64                `json:"bufferingSize,omitempty" description:"Number of access log lines to process in a buffered way. Default 0." export:"true"`
+	SamplingRatio        float64              `json:"samplingRatio,omitempty" description:"Ratio of requests to log, between 0.0 and 1.0, based on the shared sampling decision. Default 0 (every request)." export:"true"`
+	StatusSamplingRatios StatusSamplingRatios `json:"statusSamplingRatios,omitempty" description:"Ratio of requests to log per status code range, overriding samplingRatio for the matching responses" export:"true"`
+	Sampling             *AccessLogSampling   `json:"sampling,omitempty" description:"Access log sampling, logging a ratio of the requests and always the notable ones" export:"true"`
+	OTLP                 *OTLPLogs            `json:"otlp,omitempty" description:"Export the access logs to an OpenTelemetry collector (OTLP/HTTP JSON only, no gRPC)" export:"true"`
+	Kafka                *AccessLogKafka      `json:"kafka,omitempty" description:"Publish the access logs to a Kafka topic" export:"true"`
+}
+
+// AccessLogKafka holds the configuration of the publication of the access logs to a Kafka topic.
+// The access log lines are published asynchronously, in batches, and dropped when the buffer is full.
+type AccessLogKafka struct {
+	Brokers       KafkaBrokers   `json:"brokers,omitempty" description:"Kafka brokers" export:"true"`
+	Topic         string         `json:"topic,omitempty" description:"Kafka topic" export:"true"`
+	SASL          *KafkaSASL     `json:"sasl,omitempty" description:"SASL/PLAIN authentication" export:"true"`
+	TLS           *ClientTLS     `json:"tls,omitempty" description:"Enable TLS support" export:"true"`
+	BufferSize    int            `json:"bufferSize,omitempty" description:"Maximum number of access log lines waiting to be published, past which they are dropped. Default 1024." export:"true"`
+	FlushInterval parse.Duration `json:"flushInterval,omitempty" description:"Interval between the publications of the batches. Default 500ms." export:"true"`
+}
+
+// KafkaBrokers holds the addresses of the Kafka brokers
+type KafkaBrokers []string
+
+// Set adds strings elem into the the parser
+// it splits str on , and ;
+func (b *KafkaBrokers) Set(str string) error {
+	fargs := func(c rune) bool {
+		return c == ',' || c == ';'
+	}
+	// get function
+	slice := strings.FieldsFunc(str, fargs)
+	*b = append(*b, slice...)
+	return nil
+}
+
+// Get KafkaBrokers
+func (b *KafkaBrokers) Get() interface{} { return *b }
+
+// String return slice in a string
+func (b *KafkaBrokers) String() string { return fmt.Sprintf("%v", *b) }
+
+// SetValue sets KafkaBrokers into the parser
+func (b *KafkaBrokers) SetValue(val interface{}) {
+	*b = val.(KafkaBrokers)
+}
+
+// KafkaSASL holds the SASL/PLAIN credentials of a Kafka client.
+type KafkaSASL struct {
+	User     string `json:"user,omitempty" description:"SASL user"`
+	Password string `json:"password,omitempty" description:"SASL password"`
+}
+
+// MarshalJSON returns the JSON encoding of the credentials, with the password redacted, so that it is not logged.
+func (k KafkaSASL) MarshalJSON() ([]byte, error) {
+	type kafkaSASL KafkaSASL
+	redacted := kafkaSASL(k)
+	if len(redacted.Password) > 0 {
+		redacted.Password = redactedValue
+	}
+	return json.Marshal(redacted)
+}
+
+// AccessLogSampling holds the access log sampling configuration.
+// The requests are sampled with the rate, except the traced requests and the ones matching the always rules, which are always logged.
+type AccessLogSampling struct {
+	Rate              float64        `json:"rate,omitempty" description:"Ratio of requests to log, between 0.0 and 1.0, based on the shared sampling decision. Default 0 (every request)." export:"true"`
+	AlwaysStatusCodes StatusCodes    `json:"alwaysStatusCodes,omitempty" description:"Always log the requests with status codes in the specified range" export:"true"`
+	MinDuration       parse.Duration `json:"minDuration,omitempty" description:"Always log the requests which took longer than the specified duration" export:"true"`
+}
+
+// OTLPLogs holds the configuration of the export of the logs to an OpenTelemetry collector (OTLP/HTTP with JSON encoding).
+type OTLPLogs struct {
+	Endpoint      string         `json:"endpoint,omitempty" description:"URL of the OTLP/HTTP logs endpoint of the collector (e.g. http://localhost:4318/v1/logs)" export:"true"`
+	Headers       OTLPHeaders    `json:"headers,omitempty" description:"Headers sent with the export requests (e.g. authentication)"`
+	ServiceName   string         `json:"serviceName,omitempty" description:"Value of the service.name resource attribute. Defaults to the tracing service name" export:"true"`
+	BatchSize     int            `json:"batchSize,omitempty" description:"Maximum number of log records sent in an export request. Default 512." export:"true"`
+	QueueSize     int            `json:"queueSize,omitempty" description:"Maximum number of log records waiting to be exported, past which they are dropped. Default 2048." export:"true"`
+	FlushInterval parse.Duration `json:"flushInterval,omitempty" description:"Interval between the export requests. Default 5s." export:"true"`
+	Timeout       parse.Duration `json:"timeout,omitempty" description:"Timeout of the export requests. Default 10s." export:"true"`
+}
+
+// OTLPHeaders holds the headers sent with the OTLP export requests
+type OTLPHeaders map[string]string
+
+// String is the method to format the flag's value, part of the flag.Value interface.
+// The String method's output will be used in diagnostics.
+func (h *OTLPHeaders) String() string {
+	return fmt.Sprintf("%+v", *h)
+}
+
+// Get return the OTLPHeaders map
+func (h *OTLPHeaders) Get() interface{} {
+	return *h
+}
+
+// Set is the method to set the flag value, part of the flag.Value interface.
+// Set's argument is a string to be parsed to set the flag.
+// It's a space-separated list of name=value, so we split it.
+func (h *OTLPHeaders) Set(value string) error {
+	value = strings.Trim(value, "\"")
+
+	if *h == nil {
+		*h = make(OTLPHeaders)
+	}
+
+	for _, field := range strings.Fields(value) {
+		n := strings.SplitN(field, "=", 2)
+		if len(n) != 2 {
+			return fmt.Errorf("invalid OTLP header %q, expected name=value", field)
+		}
+
+		(*h)[n[0]] = n[1]
+	}
+
+	return nil
+}
+
+// SetValue sets the OTLPHeaders map with val
+func (h *OTLPHeaders) SetValue(val interface{}) {
+	*h = val.(OTLPHeaders)
+}
+
+// MarshalJSON returns the JSON encoding of the headers, with their values redacted, so that the credentials are not logged.
+func (h OTLPHeaders) MarshalJSON() ([]byte, error) {
+	redacted := make(map[string]string, len(h))
+	for name := range h {
+		redacted[name] = redactedValue
+	}
+	return json.Marshal(redacted)
 }
 
 // AccessLogFilters holds filters configuration
@@ -70,6 +200,52 @@ func (s *StatusCodes) SetValue(val interface{}) {
 	*s = val.(StatusCodes)
 }
 
+// StatusSamplingRatios holds the access log sampling ratios per status code range (e.g. "500-599" or "404")
+type StatusSamplingRatios map[string]float64
+
+// String is the method to format the flag's value, part of the flag.Value interface.
+// The String method's output will be used in diagnostics.
+func (s *StatusSamplingRatios) String() string {
+	return fmt.Sprintf("%+v", *s)
+}
+
+// Get return the StatusSamplingRatios map
+func (s *StatusSamplingRatios) Get() interface{} {
+	return *s
+}
+
+// Set is the method to set the flag value, part of the flag.Value interface.
+// Set's argument is a string to be parsed to set the flag.
+// It's a space-separated list of range=ratio, so we split it.
+func (s *StatusSamplingRatios) Set(value string) error {
+	value = strings.Trim(value, "\"")
+
+	if *s == nil {
+		*s = make(StatusSamplingRatios)
+	}
+
+	for _, field := range strings.Fields(value) {
+		n := strings.SplitN(field, "=", 2)
+		if len(n) != 2 {
+			return fmt.Errorf("invalid status sampling ratio %q, expected range=ratio", field)
+		}
+
+		ratio, err := strconv.ParseFloat(n[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid status sampling ratio %q: %v", field, err)
+		}
+
+		(*s)[n[0]] = ratio
+	}
+
+	return nil
+}
+
+// SetValue sets the StatusSamplingRatios map with val
+func (s *StatusSamplingRatios) SetValue(val interface{}) {
+	*s = val.(StatusSamplingRatios)
+}
+
 // FieldNames holds maps of fields with specific mode
 type FieldNames map[string]string
 
@@ -153,6 +329,8 @@ type AccessLogFields struct {
 	DefaultMode string        `json:"defaultMode,omitempty" description:"Default mode for fields: keep | drop" export:"true"`
 	Names       FieldNames    `json:"names,omitempty" description:"Override mode for fields" export:"true"`
 	Headers     *FieldHeaders `json:"headers,omitempty" description:"Headers to keep, drop or redact" export:"true"`
+	Rename      FieldNames    `json:"rename,omitempty" description:"New names of the fields in the JSON format" export:"true"`
+	Extra       FieldNames    `json:"extra,omitempty" description:"Extra fields of the JSON format, their values can be templates" export:"true"`
 }
 
 // Keep check if the field need to be kept or dropped
@@ -1,9 +1,11 @@
 package types
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStatusCodesSet(t *testing.T) {
@@ -150,6 +152,106 @@ func TestStatusCodesSetValue(t *testing.T) {
 	}
 }
 
+func TestStatusSamplingRatiosSet(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		value         string
+		expected      *StatusSamplingRatios
+		expectedError bool
+	}{
+		{
+			desc:  "One value should return StatusSamplingRatios of size 1",
+			value: "500-599=1",
+			expected: &StatusSamplingRatios{
+				"500-599": 1,
+			},
+		},
+		{
+			desc:  "Several values separated by space should return StatusSamplingRatios of the same size",
+			value: "500-599=1 400-499=0.1 200-299=0.01",
+			expected: &StatusSamplingRatios{
+				"500-599": 1,
+				"400-499": 0.1,
+				"200-299": 0.01,
+			},
+		},
+		{
+			desc:          "Missing ratio should return an error",
+			value:         "500-599",
+			expectedError: true,
+		},
+		{
+			desc:          "Invalid ratio should return an error",
+			value:         "500-599=all",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ratios := &StatusSamplingRatios{}
+			err := ratios.Set(test.value)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expected, ratios)
+		})
+	}
+}
+
+func TestOTLPHeadersSet(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		value         string
+		expected      *OTLPHeaders
+		expectedError bool
+	}{
+		{
+			desc:  "One value should return OTLPHeaders of size 1",
+			value: "Authorization=Basic",
+			expected: &OTLPHeaders{
+				"Authorization": "Basic",
+			},
+		},
+		{
+			desc:  "Several values separated by space should return OTLPHeaders of the same size",
+			value: "X-Scope-OrgID=tenant1 X-Api-Key=a=b",
+			expected: &OTLPHeaders{
+				"X-Scope-OrgID": "tenant1",
+				"X-Api-Key":     "a=b",
+			},
+		},
+		{
+			desc:          "Missing value should return an error",
+			value:         "Authorization",
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			headers := &OTLPHeaders{}
+			err := headers.Set(test.value)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expected, headers)
+		})
+	}
+}
+
 func TestFieldsNamesSet(t *testing.T) {
 	testCases := []struct {
 		desc     string
@@ -417,3 +519,23 @@ func TestFieldsHeadersNamesSetValue(t *testing.T) {
 		})
 	}
 }
+
+func TestKafkaSASLMarshalJSON(t *testing.T) {
+	sasl := &KafkaSASL{User: "traefik", Password: "secret"}
+
+	data, err := json.Marshal(&AccessLogKafka{SASL: sasl})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"sasl":{"user":"traefik","password":"xxxx"}}`, string(data))
+	assert.Equal(t, "secret", sasl.Password)
+}
+
+func TestOTLPHeadersMarshalJSON(t *testing.T) {
+	headers := OTLPHeaders{"Authorization": "Bearer token"}
+
+	data, err := json.Marshal(&OTLPLogs{Endpoint: "http://localhost:4318/v1/logs", Headers: headers})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"endpoint":"http://localhost:4318/v1/logs","headers":{"Authorization":"xxxx"}}`, string(data))
+	assert.Equal(t, "Bearer token", headers["Authorization"])
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -21,15 +22,26 @@ import (
 	"github.com/ryanuber/go-glob"
 )
 
+// redactedValue replaces the secrets in the JSON encoding of the configuration.
+const redactedValue = "xxxx"
+
 // Backend holds backend configuration.
 type Backend struct {
-	Servers            map[string]Server   `json:"servers,omitempty"`
-	CircuitBreaker     *CircuitBreaker     `json:"circuitBreaker,omitempty"`
-	LoadBalancer       *LoadBalancer       `json:"loadBalancer,omitempty"`
-	MaxConn            *MaxConn            `json:"maxConn,omitempty"`
-	HealthCheck        *HealthCheck        `json:"healthCheck,omitempty"`
-	Buffering          *Buffering          `json:"buffering,omitempty"`
-	ResponseForwarding *ResponseForwarding `json:"forwardingResponse,omitempty"`
+	Servers             map[string]Server    `json:"servers,omitempty"`
+	CircuitBreaker      *CircuitBreaker      `json:"circuitBreaker,omitempty"`
+	LoadBalancer        *LoadBalancer        `json:"loadBalancer,omitempty"`
+	MaxConn             *MaxConn             `json:"maxConn,omitempty"`
+	FairShare           *FairShare           `json:"fairShare,omitempty"`
+	AdaptiveConcurrency *AdaptiveConcurrency `json:"adaptiveConcurrency,omitempty"`
+	SessionFIFO         *SessionFIFO         `json:"sessionFIFO,omitempty"`
+	OutlierDetection    *OutlierDetection    `json:"outlierDetection,omitempty"`
+	HealthCheck         *HealthCheck         `json:"healthCheck,omitempty"`
+	Buffering           *Buffering           `json:"buffering,omitempty"`
+	ResponseForwarding  *ResponseForwarding  `json:"forwardingResponse,omitempty"`
+	FailoverBackends    []string             `json:"failoverBackends,omitempty"`
+	PinnedPublicKeys    []string             `json:"pinnedPublicKeys,omitempty"`
+	KafkaMirror         *KafkaMirror         `json:"kafkaMirror,omitempty"`
+	DNSDiscovery        *DNSDiscovery        `json:"dnsDiscovery,omitempty"`
 }
 
 // ResponseForwarding holds configuration for the forward of the response
@@ -43,6 +55,67 @@ type MaxConn struct {
 	ExtractorFunc string `json:"extractorFunc,omitempty"`
 }
 
+// FairShare holds the fair share configuration: the maximum share of the backend capacity
+// used by the in-flight requests of a client, enforced when the backend is saturated.
+type FairShare struct {
+	Capacity      int64   `json:"capacity,omitempty"`
+	MaxShare      float64 `json:"maxShare,omitempty"`
+	ExtractorFunc string  `json:"extractorFunc,omitempty"`
+}
+
+// AdaptiveConcurrency holds the adaptive concurrency limit of a backend: the limit of its in-flight requests,
+// between MinLimit and MaxLimit, grows while their latency stays stable, shrinks when it rises,
+// and is multiplied by BackoffRatio on the server errors.
+// The requests beyond the limit get StatusCode (429 or 503) responses.
+type AdaptiveConcurrency struct {
+	InitialLimit  int            `json:"initialLimit,omitempty"`
+	MinLimit      int            `json:"minLimit,omitempty"`
+	MaxLimit      int            `json:"maxLimit,omitempty"`
+	BackoffRatio  float64        `json:"backoffRatio,omitempty"`
+	ProbeInterval parse.Duration `json:"probeInterval,omitempty"`
+	StatusCode    int            `json:"statusCode,omitempty"`
+}
+
+// DNSDiscovery holds the DNS discovery of the servers of a backend: each IP resolved from the A and AAAA records
+// of Name is a server, reached with Scheme on Port.
+// The records are resolved again at the end of their TTL, or every RefreshInterval when set.
+// The weight of the new servers grows up to Weight during RampDuration, and the removed servers
+// no longer receive requests, but complete their in-flight ones.
+type DNSDiscovery struct {
+	Name            string         `json:"name,omitempty"`
+	Port            int            `json:"port,omitempty"`
+	Scheme          string         `json:"scheme,omitempty"`
+	Weight          int            `json:"weight,omitempty"`
+	RefreshInterval parse.Duration `json:"refreshInterval,omitempty"`
+	RampDuration    parse.Duration `json:"rampDuration,omitempty"`
+}
+
+// SessionFIFO holds the session FIFO configuration: the requests of a session, categorized with ExtractorFunc,
+// are forwarded one at a time, in their arrival order, at most MaxQueue of them waiting for at most Timeout.
+type SessionFIFO struct {
+	ExtractorFunc string         `json:"extractorFunc,omitempty"`
+	MaxQueue      int            `json:"maxQueue,omitempty"`
+	Timeout       parse.Duration `json:"timeout,omitempty"`
+}
+
+// KafkaMirror holds the publication of a copy of the requests to a Kafka topic, for analytics or replay:
+// Percent of the requests (100 by default) are published, with their body up to MaxBodySize bytes (not published by default).
+type KafkaMirror struct {
+	Brokers     []string `json:"brokers,omitempty"`
+	Topic       string   `json:"topic,omitempty"`
+	Percent     int      `json:"percent,omitempty"`
+	MaxBodySize int64    `json:"maxBodySize,omitempty"`
+}
+
+// OutlierDetection holds the passive outlier detection configuration:
+// the servers returning consecutive server errors are ejected from the load balancer for a while.
+type OutlierDetection struct {
+	ConsecutiveErrors  int            `json:"consecutiveErrors,omitempty"`
+	Interval           parse.Duration `json:"interval,omitempty"`
+	BaseEjectionTime   parse.Duration `json:"baseEjectionTime,omitempty"`
+	MaxEjectionPercent int            `json:"maxEjectionPercent,omitempty"`
+}
+
 // LoadBalancer holds load balancing configuration.
 type LoadBalancer struct {
 	Method     string      `json:"method,omitempty"`
@@ -52,11 +125,52 @@ type LoadBalancer struct {
 // Stickiness holds sticky session configuration.
 type Stickiness struct {
 	CookieName string `json:"cookieName,omitempty"`
+	Secure     bool   `json:"secure,omitempty"`
+	HTTPOnly   bool   `json:"httpOnly,omitempty"`
+	SameSite   string `json:"sameSite,omitempty"`
+	Domain     string `json:"domain,omitempty"`
+	MaxAge     int    `json:"maxAge,omitempty"`
+	Secret     string `json:"secret,omitempty"`
+}
+
+// MarshalJSON returns the JSON encoding of the stickiness, with its secret redacted,
+// so that it is neither served by the API nor logged.
+func (s Stickiness) MarshalJSON() ([]byte, error) {
+	type stickiness Stickiness
+	redacted := stickiness(s)
+	if len(redacted.Secret) > 0 {
+		redacted.Secret = redactedValue
+	}
+	return json.Marshal(redacted)
 }
 
 // CircuitBreaker holds circuit breaker configuration.
 type CircuitBreaker struct {
-	Expression string `json:"expression,omitempty"`
+	Expression string                  `json:"expression,omitempty"`
+	Fallback   *CircuitBreakerFallback `json:"fallback,omitempty"`
+	SLO        *CircuitBreakerSLO      `json:"slo,omitempty"`
+}
+
+// CircuitBreakerSLO holds the latency SLO tripping the circuit breaker:
+// a response is compliant when it is not a server error and is served within Latency,
+// and the circuit breaker opens when the compliance ratio over Window drops below MinSuccessRatio.
+type CircuitBreakerSLO struct {
+	Latency          parse.Duration `json:"latency,omitempty"`
+	MinSuccessRatio  float64        `json:"minSuccessRatio,omitempty"`
+	Window           parse.Duration `json:"window,omitempty"`
+	MinRequests      int            `json:"minRequests,omitempty"`
+	FallbackDuration parse.Duration `json:"fallbackDuration,omitempty"`
+}
+
+// CircuitBreakerFallback holds the response served while the circuit breaker is open.
+// When Backend is set, the requests are forwarded to this backend instead.
+type CircuitBreakerFallback struct {
+	StatusCode  int               `json:"statusCode,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	BodyFile    string            `json:"bodyFile,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Backend     string            `json:"backend,omitempty"`
 }
 
 // Buffering holds request/response buffering configuration/
@@ -66,6 +180,105 @@ type Buffering struct {
 	MaxResponseBodyBytes int64  `json:"maxResponseBodyBytes,omitempty"`
 	MemResponseBodyBytes int64  `json:"memResponseBodyBytes,omitempty"`
 	RetryExpression      string `json:"retryExpression,omitempty"`
+
+	BufferContentTypes []string `json:"bufferContentTypes,omitempty"`
+	StreamContentTypes []string `json:"streamContentTypes,omitempty"`
+}
+
+// BodyLimit holds the request body size limit configuration.
+type BodyLimit struct {
+	MaxBytes           int64 `json:"maxBytes,omitempty"`
+	TrustContentLength bool  `json:"trustContentLength,omitempty"`
+}
+
+// CORS holds the Cross-Origin Resource Sharing configuration.
+type CORS struct {
+	AllowOrigins      []string `json:"allowOrigins,omitempty"`
+	AllowOriginsRegex []string `json:"allowOriginsRegex,omitempty"`
+	AllowMethods      []string `json:"allowMethods,omitempty"`
+	AllowHeaders      []string `json:"allowHeaders,omitempty"`
+	ExposeHeaders     []string `json:"exposeHeaders,omitempty"`
+	AllowCredentials  bool     `json:"allowCredentials,omitempty"`
+	MaxAge            int64    `json:"maxAge,omitempty"`
+}
+
+// Maintenance holds the maintenance mode configuration.
+// While enabled, the requests get the maintenance response, except the ones from the source range
+// or carrying the bypass secret in the bypass header or cookie.
+// When Methods are set, only the requests with these methods get the maintenance response (e.g. a read-only maintenance).
+type Maintenance struct {
+	Enabled      bool        `json:"enabled,omitempty"`
+	Methods      []string    `json:"methods,omitempty"`
+	StatusCode   int         `json:"statusCode,omitempty"`
+	ContentType  string      `json:"contentType,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyFile     string      `json:"bodyFile,omitempty"`
+	RetryAfter   int         `json:"retryAfter,omitempty"`
+	SourceRange  []string    `json:"sourceRange,omitempty"`
+	IPStrategy   *IPStrategy `json:"ipStrategy,omitempty"`
+	BypassHeader string      `json:"bypassHeader,omitempty"`
+	BypassCookie string      `json:"bypassCookie,omitempty"`
+	BypassSecret string      `json:"bypassSecret,omitempty"`
+}
+
+// BackendSchedule holds the recurring time windows during which the requests of a frontend
+// are forwarded to other backends than the frontend backend, in the time zone (local by default).
+type BackendSchedule struct {
+	Timezone string                     `json:"timezone,omitempty"`
+	Windows  map[string]*ScheduleWindow `json:"windows,omitempty"`
+}
+
+// ScheduleWindow holds a recurring time window, from Start to End (HH:MM) on the Days (every day by default).
+// When End is not after Start, the window ends the next day.
+type ScheduleWindow struct {
+	Backend string   `json:"backend,omitempty"`
+	Days    []string `json:"days,omitempty"`
+	Start   string   `json:"start,omitempty"`
+	End     string   `json:"end,omitempty"`
+}
+
+// BackendRamp holds a gradual migration of the requests of a frontend from the frontend backend to another backend,
+// whose share of the requests grows linearly from none at Start to all at End (RFC 3339 times).
+type BackendRamp struct {
+	Backend string `json:"backend,omitempty"`
+	Start   string `json:"start,omitempty"`
+	End     string `json:"end,omitempty"`
+}
+
+// BackendCompression holds the compression expected from the backend responses.
+// With the require policy, the responses the backend does not compress are compressed with the encodings,
+// and with the forbid policy, the responses the backend compresses are decompressed, up to the maximum size of their decoded body.
+type BackendCompression struct {
+	Policy              string   `json:"policy,omitempty"`
+	Encodings           []string `json:"encodings,omitempty"`
+	MaxDecodedBodyBytes int64    `json:"maxDecodedBodyBytes,omitempty"`
+}
+
+// BackendVersion holds the backend response header holding the version of the backend,
+// and the versions labelled in the metrics.
+type BackendVersion struct {
+	Header       string   `json:"header,omitempty"`
+	MetricValues []string `json:"metricValues,omitempty"`
+}
+
+// RequestTemplate holds the Go templates, rendered for each request from its attributes and its route variables,
+// of the headers to set on the request and of its new path.
+type RequestTemplate struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Path    string            `json:"path,omitempty"`
+}
+
+// ExternalProcessor holds the configuration of the external gRPC processing service,
+// transforming the requests, and optionally the responses, of a frontend.
+type ExternalProcessor struct {
+	Address          string         `json:"address,omitempty"`
+	TLS              *ClientTLS     `json:"tls,omitempty"`
+	Timeout          parse.Duration `json:"timeout,omitempty"`
+	FailOpen         bool           `json:"failOpen,omitempty"`
+	SendRequestBody  bool           `json:"sendRequestBody,omitempty"`
+	ProcessResponse  bool           `json:"processResponse,omitempty"`
+	SendResponseBody bool           `json:"sendResponseBody,omitempty"`
+	MaxBodyBytes     int64          `json:"maxBodyBytes,omitempty"`
 }
 
 // WhiteList contains white list configuration.
@@ -83,6 +296,12 @@ type HealthCheck struct {
 	Timeout  string            `json:"timeout,omitempty"`
 	Hostname string            `json:"hostname,omitempty"`
 	Headers  map[string]string `json:"headers,omitempty"`
+	Mode     string            `json:"mode,omitempty"`
+	Service  string            `json:"service,omitempty"`
+
+	ExpectedBody      string            `json:"expectedBody,omitempty"`
+	ExpectedBodyRegex string            `json:"expectedBodyRegex,omitempty"`
+	ExpectedHeaders   map[string]string `json:"expectedHeaders,omitempty"`
 }
 
 // Server holds server configuration.
@@ -113,6 +332,49 @@ type ErrorPage struct {
 	Query   string   `json:"query,omitempty"`
 }
 
+// ResponseHeaderRule holds a rule the response header of a backend must satisfy.
+// Action is one of "log" (default), "default" which sets the Default value, or "reject" which returns a 502.
+type ResponseHeaderRule struct {
+	Header  string `json:"header,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Action  string `json:"action,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+// SNICheck holds the check of the TLS server name (SNI) of the requests against their host, or against the AllowedServerNames when set,
+// so that a connection established for a host can't be used to reach the frontend of another host.
+// The mismatching requests are rejected with the StatusCode, 421 Misdirected Request (default) or 403 Forbidden.
+type SNICheck struct {
+	AllowedServerNames []string `json:"allowedServerNames,omitempty"`
+	StatusCode         int      `json:"statusCode,omitempty"`
+}
+
+// WebSocket holds the keep-alive of the websocket connections: a ping frame is sent to the clients every PingInterval,
+// and the connections whose client doesn't answer with a pong frame within PongTimeout (PingInterval by default) are closed.
+type WebSocket struct {
+	PingInterval parse.Duration `json:"pingInterval,omitempty"`
+	PongTimeout  parse.Duration `json:"pongTimeout,omitempty"`
+}
+
+// RequestHeaderValidation holds the validation of the request headers against malformed encodings and control characters.
+// The values must be printable ASCII, or valid UTF-8 without control characters for the UTF8Headers.
+// Action is one of "reject" (default) which returns a 400, or "sanitize" which removes the invalid characters.
+type RequestHeaderValidation struct {
+	Headers     []string `json:"headers,omitempty"`
+	UTF8Headers []string `json:"utf8Headers,omitempty"`
+	Action      string   `json:"action,omitempty"`
+}
+
+// QueryLimit holds the bounds of an integer query parameter of the requests, such as a page size.
+// The values must be integers from Min, and up to Max when it is set, and Default is set when the parameter is absent.
+// Action is one of "reject" (default) which returns a 400, or "clamp" which brings the values within the bounds.
+type QueryLimit struct {
+	Min     int64  `json:"min,omitempty"`
+	Max     int64  `json:"max,omitempty"`
+	Default string `json:"default,omitempty"`
+	Action  string `json:"action,omitempty"`
+}
+
 // Rate holds a rate limiting configuration for a specific time period
 type Rate struct {
 	Period  parse.Duration `json:"period,omitempty"`
@@ -120,10 +382,40 @@ type Rate struct {
 	Burst   int64          `json:"burst,omitempty"`
 }
 
+// RateCost holds the number of tokens consumed by the requests matching all its criteria
+type RateCost struct {
+	PathPrefix string            `json:"pathPrefix,omitempty"`
+	Methods    []string          `json:"methods,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Cost       int64             `json:"cost,omitempty"`
+}
+
 // RateLimit holds a rate limiting configuration for a given frontend
 type RateLimit struct {
-	RateSet       map[string]*Rate `json:"rateset,omitempty"`
+	RateSet       map[string]*Rate     `json:"rateset,omitempty"`
+	ExtractorFunc string               `json:"extractorFunc,omitempty"`
+	Costs         map[string]*RateCost `json:"costs,omitempty"`
+	Redis         *RateLimitRedis      `json:"redis,omitempty"`
+}
+
+// RateLimitRedis holds the configuration of the Redis server used to share the rate limits between Traefik instances
+type RateLimitRedis struct {
+	Address  string     `json:"address,omitempty"`
+	DB       int        `json:"db,omitempty"`
+	Password string     `json:"password,omitempty"`
+	TLS      *ClientTLS `json:"tls,omitempty"`
+}
+
+// Quota holds the quotas of the requests of the clients of a frontend, categorized with ExtractorFunc.
+// The counts are reset at the start of each Period ("day" or "month") in TimeZone (UTC by default).
+// The quota of a client is its entry in Keys if any, Default otherwise (no quota if zero).
+type Quota struct {
 	ExtractorFunc string           `json:"extractorFunc,omitempty"`
+	Period        string           `json:"period,omitempty"`
+	TimeZone      string           `json:"timeZone,omitempty"`
+	Default       int64            `json:"default,omitempty"`
+	Keys          map[string]int64 `json:"keys,omitempty"`
+	Redis         *RateLimitRedis  `json:"redis,omitempty"`
 }
 
 // Headers holds the custom header configuration
@@ -185,19 +477,35 @@ func (h *Headers) HasSecureHeadersDefined() bool {
 
 // Frontend holds frontend configuration.
 type Frontend struct {
-	EntryPoints       []string              `json:"entryPoints,omitempty" hash:"ignore"`
-	Backend           string                `json:"backend,omitempty"`
-	Routes            map[string]Route      `json:"routes,omitempty" hash:"ignore"`
-	PassHostHeader    bool                  `json:"passHostHeader,omitempty"`
-	PassTLSCert       bool                  `json:"passTLSCert,omitempty"` // Deprecated use PassTLSClientCert instead
-	PassTLSClientCert *TLSClientHeaders     `json:"passTLSClientCert,omitempty"`
-	Priority          int                   `json:"priority"`
-	WhiteList         *WhiteList            `json:"whiteList,omitempty"`
-	Headers           *Headers              `json:"headers,omitempty"`
-	Errors            map[string]*ErrorPage `json:"errors,omitempty"`
-	RateLimit         *RateLimit            `json:"ratelimit,omitempty"`
-	Redirect          *Redirect             `json:"redirect,omitempty"`
-	Auth              *Auth                 `json:"auth,omitempty"`
+	EntryPoints             []string                       `json:"entryPoints,omitempty" hash:"ignore"`
+	Backend                 string                         `json:"backend,omitempty"`
+	Routes                  map[string]Route               `json:"routes,omitempty" hash:"ignore"`
+	PassHostHeader          bool                           `json:"passHostHeader,omitempty"`
+	PassTLSCert             bool                           `json:"passTLSCert,omitempty"` // Deprecated use PassTLSClientCert instead
+	PassTLSClientCert       *TLSClientHeaders              `json:"passTLSClientCert,omitempty"`
+	OriginalURIHeader       string                         `json:"originalURIHeader,omitempty"`
+	Priority                int                            `json:"priority"`
+	WhiteList               *WhiteList                     `json:"whiteList,omitempty"`
+	SNICheck                *SNICheck                      `json:"sniCheck,omitempty"`
+	Headers                 *Headers                       `json:"headers,omitempty"`
+	Errors                  map[string]*ErrorPage          `json:"errors,omitempty"`
+	ResponseHeaderRules     map[string]*ResponseHeaderRule `json:"responseHeaderRules,omitempty"`
+	RequestHeaderValidation *RequestHeaderValidation       `json:"requestHeaderValidation,omitempty"`
+	QueryLimits             map[string]*QueryLimit         `json:"queryLimits,omitempty"`
+	RateLimit               *RateLimit                     `json:"ratelimit,omitempty"`
+	Quota                   *Quota                         `json:"quota,omitempty"`
+	Redirect                *Redirect                      `json:"redirect,omitempty"`
+	Auth                    *Auth                          `json:"auth,omitempty"`
+	BodyLimit               *BodyLimit                     `json:"bodyLimit,omitempty"`
+	CORS                    *CORS                          `json:"cors,omitempty"`
+	Maintenance             *Maintenance                   `json:"maintenance,omitempty"`
+	RequestTemplate         *RequestTemplate               `json:"requestTemplate,omitempty"`
+	ExternalProcessor       *ExternalProcessor             `json:"externalProcessor,omitempty"`
+	BackendCompression      *BackendCompression            `json:"backendCompression,omitempty"`
+	BackendSchedule         *BackendSchedule               `json:"backendSchedule,omitempty"`
+	BackendRamp             *BackendRamp                   `json:"backendRamp,omitempty"`
+	BackendVersion          *BackendVersion                `json:"backendVersion,omitempty"`
+	WebSocket               *WebSocket                     `json:"websocket,omitempty"`
 }
 
 // Hash returns the hash value of a Frontend struct.
@@ -227,11 +535,17 @@ const (
 	Wrr LoadBalancerMethod = iota
 	// Drr = Dynamic Round Robin
 	Drr
+	// P2c = Power of two choices, on the response times
+	P2c
+	// LeastConn = Least connections, on the active requests
+	LeastConn
 )
 
 var loadBalancerMethodNames = []string{
 	"Wrr",
 	"Drr",
+	"P2c",
+	"LeastConn",
 }
 
 // NewLoadBalancerMethod create a new LoadBalancerMethod from a given LoadBalancer.
@@ -244,8 +558,10 @@ func NewLoadBalancerMethod(loadBalancer *LoadBalancer) (LoadBalancerMethod, erro
 		return Wrr, errors.New("no load-balancing method defined, fallback to 'wrr' method")
 	}
 
+	// The dashes are ignored, e.g. least-conn for LeastConn.
+	method := strings.Replace(loadBalancer.Method, "-", "", -1)
 	for i, name := range loadBalancerMethodNames {
-		if strings.EqualFold(name, loadBalancer.Method) {
+		if strings.EqualFold(name, method) {
 			return LoadBalancerMethod(i), nil
 		}
 	}
@@ -263,6 +579,30 @@ type Configuration struct {
 	TLS       []*traefiktls.Configuration `json:"-"`
 }
 
+// ConfigurationError is an error of a dynamic configuration, as reported by its validation.
+type ConfigurationError struct {
+	Frontend string `json:"frontend,omitempty"`
+	Backend  string `json:"backend,omitempty"`
+	Message  string `json:"message"`
+}
+
+// MiddlewareChains holds the middleware chains of the frontends, as built by the server, by provider and frontend.
+type MiddlewareChains map[string]map[string][]MiddlewareChain
+
+// MiddlewareChain is the list of the middlewares of a frontend on an entry point, in their execution order.
+type MiddlewareChain struct {
+	EntryPoint  string            `json:"entryPoint"`
+	Middlewares []ChainMiddleware `json:"middlewares"`
+}
+
+// ChainMiddleware is a middleware of a MiddlewareChain, with the level it is configured at: entrypoint, route, frontend or backend.
+// Duplicate is true when a middleware of the same name runs earlier in the chain.
+type ChainMiddleware struct {
+	Name      string `json:"name"`
+	Level     string `json:"level"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+}
+
 // ConfigMessage hold configuration information exchanged between parts of traefik.
 type ConfigMessage struct {
 	ProviderName  string
@@ -399,6 +739,7 @@ type Auth struct {
 	Basic       *Basic   `json:"basic,omitempty" export:"true"`
 	Digest      *Digest  `json:"digest,omitempty" export:"true"`
 	Forward     *Forward `json:"forward,omitempty" export:"true"`
+	JWT         *JWT     `json:"jwt,omitempty" export:"true"`
 	HeaderField string   `json:"headerField,omitempty" export:"true"`
 }
 
@@ -422,10 +763,28 @@ type Digest struct {
 
 // Forward authentication
 type Forward struct {
-	Address             string     `description:"Authentication server address" json:"address,omitempty"`
-	TLS                 *ClientTLS `description:"Enable TLS support" json:"tls,omitempty" export:"true"`
-	TrustForwardHeader  bool       `description:"Trust X-Forwarded-* headers" json:"trustForwardHeader,omitempty" export:"true"`
-	AuthResponseHeaders []string   `description:"Headers to be forwarded from auth response" json:"authResponseHeaders,omitempty"`
+	Address             string        `description:"Authentication server address" json:"address,omitempty"`
+	TLS                 *ClientTLS    `description:"Enable TLS support" json:"tls,omitempty" export:"true"`
+	TrustForwardHeader  bool          `description:"Trust X-Forwarded-* headers" json:"trustForwardHeader,omitempty" export:"true"`
+	AuthResponseHeaders []string      `description:"Headers to be forwarded from auth response" json:"authResponseHeaders,omitempty"`
+	Cache               *ForwardCache `description:"Cache the responses of the authentication server" json:"cache,omitempty" export:"true"`
+}
+
+// ForwardCache holds the cache of the forward authentication responses, keyed by a request header
+type ForwardCache struct {
+	Key        string         `description:"Request header used as cache key" json:"key,omitempty" export:"true"`
+	TTL        parse.Duration `description:"Time to live of the cached responses" json:"ttl,omitempty" export:"true"`
+	MaxEntries int            `description:"Maximum number of cached responses" json:"maxEntries,omitempty" export:"true"`
+}
+
+// JWT authentication, which validates the bearer tokens against a JSON Web Key Set
+type JWT struct {
+	JWKSURL         string            `description:"JSON Web Key Set URL" json:"jwksUrl,omitempty"`
+	Issuer          string            `description:"Expected issuer of the tokens" json:"issuer,omitempty" export:"true"`
+	Audiences       []string          `description:"Accepted audiences of the tokens" json:"audiences,omitempty" export:"true"`
+	ClaimsHeaders   map[string]string `description:"Claims to be forwarded as request headers" json:"claimsHeaders,omitempty" export:"true"`
+	ForwardHeaders  bool              `description:"Forward the Authorization header to the backend" json:"forwardHeaders,omitempty" export:"true"`
+	RefreshInterval parse.Duration    `description:"Interval between two refreshes of the JSON Web Key Set" json:"refreshInterval,omitempty" export:"true"`
 }
 
 // CanonicalDomain returns a lower case domain with trim space
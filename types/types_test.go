@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -184,3 +185,36 @@ func TestIPStrategy_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestStickiness_MarshalJSON(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		sticky   *Stickiness
+		expected string
+	}{
+		{
+			desc:     "without secret",
+			sticky:   &Stickiness{CookieName: "foo"},
+			expected: `{"cookieName":"foo"}`,
+		},
+		{
+			desc:     "with secret",
+			sticky:   &Stickiness{CookieName: "foo", Secret: "my_secret"},
+			expected: `{"cookieName":"foo","secret":"xxxx"}`,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := json.Marshal(&LoadBalancer{Stickiness: test.sticky})
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"stickiness":`+test.expected+`}`, string(data))
+
+			// The secret is kept by the configuration.
+			assert.NotEqual(t, redactedValue, test.sticky.Secret)
+		})
+	}
+}
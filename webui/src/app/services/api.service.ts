@@ -1,6 +1,7 @@
 import { HttpClient, HttpErrorResponse, HttpHeaders } from '@angular/common/http';
 import { Injectable } from '@angular/core';
 import 'rxjs/add/observable/empty';
+import 'rxjs/add/observable/forkJoin';
 import 'rxjs/add/observable/of';
 import 'rxjs/add/operator/catch';
 import 'rxjs/add/operator/map';
@@ -43,16 +44,27 @@ export class ApiService {
   }
 
   fetchProviders(): Observable<any> {
-    return this.http.get('../api/providers', {headers: this.headers})
+    const providers = this.http.get('../api/providers', {headers: this.headers})
       .retry(2)
       .catch((err: HttpErrorResponse) => {
         console.error(`[providers] returned code ${err.status}, body was: ${err.error}`);
         return Observable.of<any>({});
-      })
-      .map((data: any): ProviderType => this.parseProviders(data));
+      });
+
+    return Observable.forkJoin(providers, this.fetchBackendRampOverrides())
+      .map(([data, overrides]): ProviderType => this.parseProviders(data, overrides));
   }
 
-  parseProviders(data: any): ProviderType {
+  fetchBackendRampOverrides(): Observable<any> {
+    return this.http.get('../api/backendramps/overrides', {headers: this.headers})
+      .retry(2)
+      .catch((err: HttpErrorResponse) => {
+        console.error(`[backendramps] returned code ${err.status}, body was: ${err.error}`);
+        return Observable.of<any>({});
+      });
+  }
+
+  parseProviders(data: any, overrides: any = {}): ProviderType {
     return Object.keys(data)
       .filter(value => value !== 'acme' && value !== 'ACME')
       .reduce((acc, curr) => {
@@ -70,6 +82,9 @@ export class ApiService {
             if (frontend.ratelimit && frontend.ratelimit.rateset) {
               frontend.ratelimit.rateset = this.toArray(frontend.ratelimit.rateset, 'id');
             }
+            if (frontend.backendRamp) {
+              frontend.backendRamp.override = (overrides[curr] || {})[frontend.id];
+            }
             return frontend;
           });
 